@@ -0,0 +1,708 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/argon2id"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"golang.org/x/crypto/ssh"
+)
+
+// Admin: manage users, namespaces and repositories through the database directly, used to bootstrap the server
+// before any administrator exists. Hooks and webhooks are not triggered, changes are recorded in the audit log.
+type Admin struct {
+	User       AdminUser       `cmd:"user" help:"Manage users"`
+	Key        AdminKey        `cmd:"key" help:"Manage SSH keys of users"`
+	Namespace  AdminNamespace  `cmd:"namespace" help:"Manage namespaces"`
+	Member     AdminMember     `cmd:"member" help:"Manage members of groups and repositories"`
+	Repo       AdminRepo       `cmd:"repo" help:"Manage repositories, use the management API to create repositories"`
+	DeployKey  AdminDeployKey  `cmd:"deploy-key" help:"Manage deploy keys of repositories"`
+	Branch     AdminBranch     `cmd:"branch" help:"Manage branches of repositories"`
+	Tag        AdminTag        `cmd:"tag" help:"Manage tags of repositories"`
+	Protection AdminProtection `cmd:"protection" help:"Manage protection rules of repositories"`
+	Audit      AdminAudit      `cmd:"audit" help:"Query the audit log of reference updates and management actions"`
+}
+
+type adminConfig struct {
+	Config   string `short:"c" name:"config" help:"Location of server config file, httpd or sshd" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+	Operator string `name:"operator" help:"Username of the operator recorded in the audit log, default: the login name of the current user" env:"ZETA_SERVE_OPERATOR"`
+	// operator: user of the operator, the first user created on an empty server is its own operator
+	operator  *database.User
+	bootstrap bool
+}
+
+func (c *adminConfig) open(globals *Globals) (database.DB, *databaseConfig, error) {
+	var dc databaseConfig
+	if err := dc.Decode(c.Config, globals.ExpandEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "load config error: %v\n", err)
+		return nil, nil, err
+	}
+	driver, dsn, err := dc.DB.DSN()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "database config error: %v\n", err)
+		return nil, nil, err
+	}
+	db, err := database.NewDB(driver, dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open database error: %v\n", err)
+		return nil, nil, err
+	}
+	if err := c.resolveOperator(context.Background(), db); err != nil {
+		_ = db.Close()
+		fmt.Fprintf(os.Stderr, "zeta-serve admin: %v\n", err)
+		return nil, nil, err
+	}
+	return db, &dc, nil
+}
+
+// resolveOperator: the operator must be a user of the server, actions are recorded in the audit log with its uid.
+func (c *adminConfig) resolveOperator(ctx context.Context, db database.DB) error {
+	operatorName := c.Operator
+	if len(operatorName) == 0 {
+		// the login name of the user who runs sudo
+		if operatorName = os.Getenv("SUDO_USER"); len(operatorName) == 0 {
+			current, err := user.Current()
+			if err != nil {
+				return fmt.Errorf("resolve current user error: %w, use '--operator' to set the operator", err)
+			}
+			operatorName = current.Username
+		}
+	}
+	u, err := db.SearchUser(ctx, operatorName)
+	if err == nil {
+		c.operator = u
+		return nil
+	}
+	if !database.IsNotFound(err) {
+		return err
+	}
+	if c.bootstrap {
+		users, _, err := db.ListUsers(ctx, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("operator '%s' not found, use '--operator' to set the user recorded in the audit log", operatorName)
+}
+
+// record: append the succeeded command to the audit log as 'cli' by the operator
+func (c *adminConfig) record(ctx context.Context, db database.DB, action string, rid int64, target string) error {
+	var uid int64
+	if c.operator != nil {
+		uid = c.operator.ID
+	}
+	return db.NewAuditEvent(ctx, &database.AuditEvent{RID: rid, UID: uid, Action: action, Target: target, Protocol: "cli"})
+}
+
+type adminPage struct {
+	Cursor int64 `name:"cursor" help:"Cursor of the page, the next of the previous page" default:"0"`
+	Limit  int   `name:"limit" help:"Number of items per page, range: 1-1000" default:"100"`
+}
+
+func (p *adminPage) check() error {
+	if p.Cursor < 0 {
+		return fmt.Errorf("bad cursor '%d'", p.Cursor)
+	}
+	if p.Limit <= 0 || p.Limit > database.MaxListLimit {
+		return fmt.Errorf("bad limit '%d', range: 1-%d", p.Limit, database.MaxListLimit)
+	}
+	return nil
+}
+
+// adminOutput: print v in JSON format and report err
+func adminOutput(v any, err error) error {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta-serve admin: %v\n", err)
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func adminFindUser(ctx context.Context, db database.DB, userName string) (*database.User, error) {
+	u, err := db.SearchUser(ctx, userName)
+	if database.IsNotFound(err) {
+		return nil, fmt.Errorf("user '%s' not found", userName)
+	}
+	return u, err
+}
+
+func adminFindNamespace(ctx context.Context, db database.DB, namespacePath string) (*database.Namespace, error) {
+	n, err := db.FindNamespaceByPath(ctx, namespacePath)
+	if database.IsNotFound(err) {
+		return nil, fmt.Errorf("namespace '%s' not found", namespacePath)
+	}
+	return n, err
+}
+
+func adminGrantOwner(ctx context.Context, db database.DB, sourceType database.MemberType, sourceID int64, uid int64) error {
+	m := &database.Member{UID: uid, SourceID: sourceID, SourceType: sourceType, AccessLevel: database.OwnerAccess}
+	if err := db.UpdateMember(ctx, m); !database.IsNotFound(err) {
+		return err
+	}
+	return db.AddMember(ctx, m)
+}
+
+type AdminUser struct {
+	List   ListUser   `cmd:"list" aliases:"ls" help:"List users"`
+	Show   ShowUser   `cmd:"show" help:"Show a user"`
+	Create CreateUser `cmd:"create" help:"Create a user with the personal namespace"`
+	Update UpdateUser `cmd:"update" help:"Update a user"`
+	Delete DeleteUser `cmd:"delete" aliases:"rm" help:"Delete a user with the personal namespace, keys and memberships"`
+}
+
+type ListUser struct {
+	adminConfig
+	adminPage
+}
+
+func (c *ListUser) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	users, next, err := db.ListUsers(context.Background(), c.Cursor, c.Limit)
+	for _, u := range users {
+		u.Guard()
+	}
+	return adminOutput(map[string]any{"users": users, "next": next}, err)
+}
+
+type ShowUser struct {
+	adminConfig
+	UserName string `arg:"" name:"username" help:"Username or email of the user"`
+}
+
+func (c *ShowUser) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	u, err := adminFindUser(context.Background(), db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	u.Guard()
+	return adminOutput(u, nil)
+}
+
+type CreateUser struct {
+	adminConfig
+	UserName      string `arg:"" name:"username" help:"Username of the user"`
+	Name          string `name:"name" help:"Display name of the user, default: username"`
+	Email         string `name:"email" help:"Email of the user"`
+	Password      string `name:"password" help:"Password of the user" env:"ZETA_SERVE_PASSWORD" required:""`
+	Administrator bool   `name:"admin" help:"Grant administrator"`
+}
+
+func (c *CreateUser) Run(globals *Globals) error {
+	passwd, err := argon2id.CreateHash(c.Password, argon2id.DefaultParams)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	c.bootstrap = true
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := db.NewUser(ctx, &database.User{
+		UserName:       c.UserName,
+		Name:           c.Name,
+		Administrator:  c.Administrator,
+		Email:          c.Email,
+		Password:       passwd,
+		SignatureToken: strengthen.NewRID(),
+	})
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	u.Guard()
+	if c.operator == nil {
+		c.operator = u
+	}
+	return adminOutput(u, c.record(ctx, db, "user.create", 0, "users/"+u.UserName))
+}
+
+type UpdateUser struct {
+	adminConfig
+	UserName      string `arg:"" name:"username" help:"Username or email of the user"`
+	Name          string `name:"name" help:"Display name of the user"`
+	Email         string `name:"email" help:"Email of the user"`
+	Password      string `name:"password" help:"Password of the user" env:"ZETA_SERVE_PASSWORD"`
+	Administrator bool   `name:"admin" help:"Grant administrator" xor:"admin"`
+	Revoke        bool   `name:"no-admin" help:"Revoke administrator" xor:"admin"`
+	Lock          bool   `name:"lock" help:"Lock the user" xor:"lock"`
+	Unlock        bool   `name:"unlock" help:"Unlock the user" xor:"lock"`
+}
+
+func (c *UpdateUser) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if len(c.Name) != 0 {
+		u.Name = c.Name
+	}
+	if len(c.Email) != 0 {
+		u.Email = c.Email
+	}
+	if len(c.Password) != 0 {
+		if u.Password, err = argon2id.CreateHash(c.Password, argon2id.DefaultParams); err != nil {
+			return adminOutput(nil, err)
+		}
+	}
+	switch {
+	case c.Administrator:
+		u.Administrator = true
+	case c.Revoke:
+		u.Administrator = false
+	}
+	switch {
+	case c.Lock && u.LockedAt.IsZero():
+		u.LockedAt = time.Now()
+	case c.Unlock:
+		u.LockedAt = time.Time{}
+	}
+	if err := db.UpdateUser(ctx, u); err != nil {
+		return adminOutput(nil, err)
+	}
+	u.Guard()
+	return adminOutput(u, c.record(ctx, db, "user.update", 0, "users/"+u.UserName))
+}
+
+type DeleteUser struct {
+	adminConfig
+	UserName string `arg:"" name:"username" help:"Username or email of the user"`
+}
+
+func (c *DeleteUser) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := db.DeleteUser(ctx, u.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.record(ctx, db, "user.delete", 0, "users/"+u.UserName))
+}
+
+type AdminKey struct {
+	List   ListKey   `cmd:"list" aliases:"ls" help:"List SSH and deploy keys added by the user"`
+	Add    AddKey    `cmd:"add" help:"Add an SSH key to the user"`
+	Delete DeleteKey `cmd:"delete" aliases:"rm" help:"Delete a key, deploy keys are disabled for all repositories"`
+}
+
+type ListKey struct {
+	adminConfig
+	adminPage
+	UserName string `arg:"" name:"username" help:"Username or email of the user"`
+}
+
+func (c *ListKey) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	keys, next, err := db.ListKeys(ctx, u.ID, c.Cursor, c.Limit)
+	return adminOutput(map[string]any{"keys": keys, "next": next}, err)
+}
+
+type AddKey struct {
+	adminConfig
+	UserName string `arg:"" name:"username" help:"Username or email of the user"`
+	Key      string `arg:"" name:"key" help:"File of the public key in the authorized_keys format" type:"existingfile"`
+	Title    string `name:"title" help:"Title of the key, default: comment of the key"`
+}
+
+// parseKeyFile: parse the public key in the authorized_keys format
+func parseKeyFile(keyFile, title string, keyType database.KeyType) (*database.Key, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pk, comment, _, _, err := ssh.ParseAuthorizedKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("bad public key: %w", err)
+	}
+	if len(title) == 0 {
+		title = comment
+	}
+	if len(title) == 0 || len(title) > 255 {
+		return nil, errors.New("title is empty or longer than 255 bytes")
+	}
+	return &database.Key{
+		Content:     strings.TrimSpace(string(content)),
+		Title:       title,
+		Type:        keyType,
+		Fingerprint: ssh.FingerprintSHA256(pk),
+	}, nil
+}
+
+func (c *AddKey) Run(globals *Globals) error {
+	k, err := parseKeyFile(c.Key, c.Title, database.BasicKey)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	k.UID = u.ID
+	if k, err = db.AddKey(ctx, k); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(k, c.record(ctx, db, "user.key.create", 0, fmt.Sprintf("users/%s/keys/%d", u.UserName, k.ID)))
+}
+
+type DeleteKey struct {
+	adminConfig
+	ID int64 `arg:"" name:"id" help:"ID of the key"`
+}
+
+func (c *DeleteKey) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := db.DeleteKey(ctx, c.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.record(ctx, db, "user.key.delete", 0, fmt.Sprintf("keys/%d", c.ID)))
+}
+
+type AdminNamespace struct {
+	List   ListNamespace   `cmd:"list" aliases:"ls" help:"List namespaces"`
+	Show   ShowNamespace   `cmd:"show" help:"Show a namespace"`
+	Create CreateNamespace `cmd:"create" help:"Create a group"`
+	Update UpdateNamespace `cmd:"update" help:"Update a namespace"`
+	Delete DeleteNamespace `cmd:"delete" aliases:"rm" help:"Delete a group without repositories"`
+}
+
+type ListNamespace struct {
+	adminConfig
+	adminPage
+	Owner string `name:"owner" help:"Username of the owner, default: all namespaces"`
+}
+
+func (c *ListNamespace) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	var owner int64
+	if len(c.Owner) != 0 {
+		u, err := adminFindUser(ctx, db, c.Owner)
+		if err != nil {
+			return adminOutput(nil, err)
+		}
+		owner = u.ID
+	}
+	namespaces, next, err := db.ListNamespaces(ctx, owner, c.Cursor, c.Limit)
+	return adminOutput(map[string]any{"namespaces": namespaces, "next": next}, err)
+}
+
+type ShowNamespace struct {
+	adminConfig
+	Path string `arg:"" name:"namespace" help:"Path of the namespace"`
+}
+
+func (c *ShowNamespace) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return adminOutput(adminFindNamespace(context.Background(), db, c.Path))
+}
+
+type CreateNamespace struct {
+	adminConfig
+	Path        string `arg:"" name:"namespace" help:"Path of the group"`
+	Owner       string `name:"owner" help:"Username of the owner" required:""`
+	Name        string `name:"name" help:"Display name of the group, default: path"`
+	Description string `name:"description" help:"Description of the group"`
+}
+
+func (c *CreateNamespace) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	u, err := adminFindUser(ctx, db, c.Owner)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	n, err := db.NewGroupNamespace(ctx, &database.Namespace{
+		Path:        c.Path,
+		Name:        c.Name,
+		Description: c.Description,
+		Owner:       u.ID,
+	})
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := adminGrantOwner(ctx, db, database.GroupMember, n.ID, u.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(n, c.record(ctx, db, "namespace.create", 0, "namespaces/"+n.Path))
+}
+
+type UpdateNamespace struct {
+	adminConfig
+	Path        string `arg:"" name:"namespace" help:"Path of the namespace"`
+	Owner       string `name:"owner" help:"Username of the new owner, groups only"`
+	Name        string `name:"name" help:"Display name of the namespace"`
+	Description string `name:"description" help:"Description of the namespace"`
+}
+
+func (c *UpdateNamespace) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	n, err := adminFindNamespace(ctx, db, c.Path)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if len(c.Name) != 0 {
+		n.Name = c.Name
+	}
+	if len(c.Description) != 0 {
+		n.Description = c.Description
+	}
+	if len(c.Owner) != 0 {
+		if n.Type != database.GroupNamespace {
+			return adminOutput(nil, errors.New("the owner of personal namespaces cannot be changed"))
+		}
+		u, err := adminFindUser(ctx, db, c.Owner)
+		if err != nil {
+			return adminOutput(nil, err)
+		}
+		n.Owner = u.ID
+	}
+	if err := db.UpdateNamespace(ctx, n); err != nil {
+		return adminOutput(nil, err)
+	}
+	if n.Type == database.GroupNamespace {
+		if err := adminGrantOwner(ctx, db, database.GroupMember, n.ID, n.Owner); err != nil {
+			return adminOutput(nil, err)
+		}
+	}
+	return adminOutput(n, c.record(ctx, db, "namespace.update", 0, "namespaces/"+n.Path))
+}
+
+type DeleteNamespace struct {
+	adminConfig
+	Path string `arg:"" name:"namespace" help:"Path of the group"`
+}
+
+func (c *DeleteNamespace) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	n, err := adminFindNamespace(ctx, db, c.Path)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if n.Type != database.GroupNamespace {
+		return adminOutput(nil, errors.New("personal namespaces are deleted with the user"))
+	}
+	if err := db.DeleteNamespace(ctx, n.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.record(ctx, db, "namespace.delete", 0, "namespaces/"+n.Path))
+}
+
+type AdminMember struct {
+	List   ListMember   `cmd:"list" aliases:"ls" help:"List members"`
+	Set    SetMember    `cmd:"set" help:"Add a member or change its access level"`
+	Remove RemoveMember `cmd:"remove" aliases:"rm" help:"Remove a member"`
+}
+
+// memberSource: a group or a repository
+type memberSource struct {
+	Namespace string `name:"namespace" help:"Path of the group" xor:"source" required:""`
+	Repo      string `name:"repo" help:"Path of the repository, eg: group/repo" xor:"source" required:""`
+}
+
+func (s *memberSource) resolve(ctx context.Context, db database.DB) (database.MemberType, int64, int64, error) {
+	if len(s.Repo) != 0 {
+		n, r, err := adminFindRepo(ctx, db, s.Repo)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return database.ProjectMember, r.ID, n.Owner, nil
+	}
+	n, err := adminFindNamespace(ctx, db, s.Namespace)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if n.Type != database.GroupNamespace {
+		return 0, 0, 0, fmt.Errorf("'%s' is not a group", n.Path)
+	}
+	return database.GroupMember, n.ID, n.Owner, nil
+}
+
+// recordMember: append the action on the member to the audit log, eg: member.set is recorded as repo.member.set for
+// repositories
+func (s *memberSource) recordMember(ctx context.Context, db database.DB, c *adminConfig, action string, sourceID int64, userName string) error {
+	if len(s.Repo) != 0 {
+		return c.record(ctx, db, "repo."+action, sourceID, "repos/"+s.Repo+"/members/"+userName)
+	}
+	return c.record(ctx, db, "namespace."+action, 0, "namespaces/"+s.Namespace+"/members/"+userName)
+}
+
+type ListMember struct {
+	adminConfig
+	adminPage
+	memberSource
+}
+
+func (c *ListMember) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	sourceType, sourceID, _, err := c.resolve(ctx, db)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	members, next, err := db.ListMembers(ctx, sourceType, sourceID, c.Cursor, c.Limit)
+	return adminOutput(map[string]any{"members": members, "next": next}, err)
+}
+
+type SetMember struct {
+	adminConfig
+	memberSource
+	UserName    string    `arg:"" name:"username" help:"Username or email of the member"`
+	AccessLevel int       `name:"access-level" help:"Access level, 20: reporter, 30: developer, 40: master, 50: owner" required:""`
+	ExpiresAt   time.Time `name:"expires-at" help:"Expiration time in RFC 3339 format, default: never"`
+}
+
+func (c *SetMember) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	sourceType, sourceID, _, err := c.resolve(ctx, db)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	m := &database.Member{UID: u.ID, SourceID: sourceID, SourceType: sourceType, AccessLevel: database.AccessLevel(c.AccessLevel), ExpiresAt: c.ExpiresAt}
+	err = db.UpdateMember(ctx, m)
+	if database.IsNotFound(err) {
+		err = db.AddMember(ctx, m)
+	}
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	m.UserName = u.UserName
+	return adminOutput(m, c.recordMember(ctx, db, &c.adminConfig, "member.set", sourceID, u.UserName))
+}
+
+type RemoveMember struct {
+	adminConfig
+	memberSource
+	UserName string `arg:"" name:"username" help:"Username or email of the member"`
+}
+
+func (c *RemoveMember) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	sourceType, sourceID, owner, err := c.resolve(ctx, db)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	u, err := adminFindUser(ctx, db, c.UserName)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if sourceType == database.GroupMember && u.ID == owner {
+		return adminOutput(nil, errors.New("the owner of the group cannot be removed"))
+	}
+	if err := db.RemoveMember(ctx, sourceType, sourceID, u.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.recordMember(ctx, db, &c.adminConfig, "member.remove", sourceID, u.UserName))
+}
@@ -0,0 +1,63 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+type AdminAudit struct {
+	List ListAudit `cmd:"list" aliases:"ls" help:"List events of the audit log, newest first"`
+}
+
+type ListAudit struct {
+	adminConfig
+	adminPage
+	Repo     string    `name:"repo" help:"Path of the repository, eg: group/repo"`
+	Ref      string    `name:"ref" help:"Full reference name or branch name"`
+	UserName string    `name:"user" help:"Username or email of the user"`
+	Action   string    `name:"action" help:"Action, or prefix of actions ending with '.', eg: reference."`
+	Since    time.Time `name:"since" help:"Events created at or after the time in RFC 3339 format"`
+	Until    time.Time `name:"until" help:"Events created before the time in RFC 3339 format"`
+}
+
+func (c *ListAudit) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	f := &database.AuditFilter{Action: c.Action, Since: c.Since, Until: c.Until}
+	if len(c.Repo) != 0 {
+		_, r, err := adminFindRepo(ctx, db, c.Repo)
+		if err != nil {
+			return adminOutput(nil, err)
+		}
+		f.RID = r.ID
+	}
+	if len(c.Ref) != 0 {
+		f.ReferenceName = plumbing.ReferenceName(c.Ref)
+		if !strings.HasPrefix(c.Ref, plumbing.ReferencePrefix) {
+			f.ReferenceName = plumbing.NewBranchReferenceName(c.Ref)
+		}
+	}
+	if len(c.UserName) != 0 {
+		u, err := adminFindUser(ctx, db, c.UserName)
+		if err != nil {
+			return adminOutput(nil, err)
+		}
+		f.UID = u.ID
+	}
+	events, next, err := db.ListAuditEvents(ctx, f, c.Cursor, c.Limit)
+	return adminOutput(map[string]any{"events": events, "next": next}, err)
+}
@@ -0,0 +1,475 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+func adminFindRepo(ctx context.Context, db database.DB, fullPath string) (*database.Namespace, *database.Repository, error) {
+	namespacePath, repoPath, ok := strings.Cut(fullPath, "/")
+	if !ok || len(namespacePath) == 0 || len(repoPath) == 0 {
+		return nil, nil, fmt.Errorf("bad repository path '%s', eg: group/repo", fullPath)
+	}
+	n, r, err := db.FindRepositoryByPath(ctx, namespacePath, repoPath)
+	if database.IsNotFound(err) {
+		return nil, nil, fmt.Errorf("repository '%s' not found", fullPath)
+	}
+	return n, r, err
+}
+
+type AdminRepo struct {
+	List   ListRepo   `cmd:"list" aliases:"ls" help:"List repositories of a namespace"`
+	Show   ShowRepo   `cmd:"show" help:"Show a repository"`
+	Update UpdateRepo `cmd:"update" help:"Update a repository"`
+	Delete DeleteRepo `cmd:"delete" aliases:"rm" help:"Delete a repository, large objects are kept in the bucket"`
+}
+
+type ListRepo struct {
+	adminConfig
+	adminPage
+	Namespace string `arg:"" name:"namespace" help:"Path of the namespace"`
+}
+
+func (c *ListRepo) Run(globals *Globals) error {
+	if err := c.check(); err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	n, err := adminFindNamespace(ctx, db, c.Namespace)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	repos, next, err := db.ListRepositories(ctx, n.ID, c.Cursor, c.Limit)
+	return adminOutput(map[string]any{"repositories": repos, "next": next}, err)
+}
+
+type ShowRepo struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+}
+
+func (c *ShowRepo) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, r, err := adminFindRepo(context.Background(), db, c.Repo)
+	return adminOutput(r, err)
+}
+
+type UpdateRepo struct {
+	adminConfig
+	Repo          string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	Name          string `name:"name" help:"Display name of the repository"`
+	Description   string `name:"description" help:"Description of the repository"`
+	VisibleLevel  int    `name:"visible-level" help:"Visible level, 0: private, 10: internal, 20: public, 30: anonymous" default:"-1"`
+	DefaultBranch string `name:"default-branch" help:"Default branch of the repository, the branch must exist"`
+}
+
+func (c *UpdateRepo) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if len(c.Name) != 0 {
+		r.Name = c.Name
+	}
+	if len(c.Description) != 0 {
+		r.Description = c.Description
+	}
+	if c.VisibleLevel >= 0 {
+		r.VisibleLevel = c.VisibleLevel
+	}
+	if len(c.DefaultBranch) != 0 {
+		if _, err := db.FindBranch(ctx, r.ID, c.DefaultBranch); err != nil {
+			if database.IsNotFound(err) {
+				err = fmt.Errorf("branch '%s' not found", c.DefaultBranch)
+			}
+			return adminOutput(nil, err)
+		}
+		r.DefaultBranch = c.DefaultBranch
+	}
+	if err := db.UpdateRepository(ctx, r); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(r, c.record(ctx, db, "repo.update", r.ID, "repos/"+c.Repo))
+}
+
+type DeleteRepo struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+}
+
+func (c *DeleteRepo) Run(globals *Globals) error {
+	db, dc, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := db.DeleteRepository(ctx, r.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := c.record(ctx, db, "repo.delete", r.ID, "repos/"+c.Repo); err != nil {
+		return adminOutput(nil, err)
+	}
+	if len(dc.Repositories) == 0 {
+		fmt.Fprintf(os.Stderr, "repositories is not configured, remove %03d/%d.zeta manually\n", r.ID%1000, r.ID)
+		return nil
+	}
+	return adminOutput(nil, os.RemoveAll(filepath.Join(dc.Repositories, fmt.Sprintf("%03d/%d.zeta", r.ID%1000, r.ID))))
+}
+
+type AdminDeployKey struct {
+	List   ListDeployKey   `cmd:"list" aliases:"ls" help:"List deploy keys enabled for a repository"`
+	Add    AddDeployKey    `cmd:"add" help:"Enable a deploy key, the key is added when it does not exist"`
+	Remove RemoveDeployKey `cmd:"remove" aliases:"rm" help:"Disable a deploy key, the key is deleted when no repository uses it"`
+}
+
+type ListDeployKey struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+}
+
+func (c *ListDeployKey) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(db.ListDeployKeys(ctx, r.ID))
+}
+
+type AddDeployKey struct {
+	adminConfig
+	Repo  string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	Key   string `arg:"" name:"key" help:"File of the public key in the authorized_keys format" type:"existingfile"`
+	Title string `name:"title" help:"Title of the key, default: comment of the key"`
+}
+
+func (c *AddDeployKey) Run(globals *Globals) error {
+	k, err := parseKeyFile(c.Key, c.Title, database.DeployKey)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	n, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	existing, err := db.SearchKey(ctx, k.Fingerprint)
+	switch {
+	case err == nil:
+		if existing.Type != database.DeployKey {
+			return adminOutput(nil, errors.New("key is already used by a user"))
+		}
+		k = existing
+	case database.IsNotFound(err):
+		k.UID = n.Owner
+		if k, err = db.AddKey(ctx, k); err != nil {
+			return adminOutput(nil, err)
+		}
+	default:
+		return adminOutput(nil, err)
+	}
+	if err := db.EnableDeployKey(ctx, r.ID, k.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(k, c.record(ctx, db, "repo.deploy_key.create", r.ID, fmt.Sprintf("repos/%s/deploy-keys/%d", c.Repo, k.ID)))
+}
+
+type RemoveDeployKey struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	ID   int64  `arg:"" name:"id" help:"ID of the key"`
+}
+
+func (c *RemoveDeployKey) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := db.DisableDeployKey(ctx, r.ID, c.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.record(ctx, db, "repo.deploy_key.delete", r.ID, fmt.Sprintf("repos/%s/deploy-keys/%d", c.Repo, c.ID)))
+}
+
+type AdminBranch struct {
+	List   ListBranch   `cmd:"list" aliases:"ls" help:"List branches"`
+	Delete DeleteBranch `cmd:"delete" aliases:"rm" help:"Delete a branch, protection rules are not checked"`
+}
+
+type AdminTag struct {
+	List   ListTag   `cmd:"list" aliases:"ls" help:"List tags"`
+	Delete DeleteTag `cmd:"delete" aliases:"rm" help:"Delete a tag, protection rules are not checked"`
+}
+
+type listRefs struct {
+	adminConfig
+	Repo   string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	Prefix string `name:"prefix" help:"Prefix of the names"`
+	Cursor string `name:"cursor" help:"Cursor of the page, the next of the previous page"`
+	Limit  int    `name:"limit" help:"Number of items per page, range: 1-1000" default:"100"`
+}
+
+func (c *listRefs) run(globals *Globals, refPrefix string) error {
+	if c.Limit <= 0 || c.Limit > database.MaxListLimit {
+		return adminOutput(nil, fmt.Errorf("bad limit '%d', range: 1-%d", c.Limit, database.MaxListLimit))
+	}
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	var cursor string
+	if len(c.Cursor) != 0 {
+		cursor = refPrefix + c.Cursor
+	}
+	refs, next, err := db.ListReferences(ctx, r.ID, refPrefix+c.Prefix, cursor, c.Limit)
+	return adminOutput(map[string]any{"references": refs, "next": strings.TrimPrefix(next, refPrefix)}, err)
+}
+
+type deleteRef struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	Name string `arg:"" name:"name" help:"Short name of the reference"`
+}
+
+func (c *deleteRef) run(globals *Globals, refname plumbing.ReferenceName) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if refname == plumbing.NewBranchReferenceName(r.DefaultBranch) {
+		return adminOutput(nil, fmt.Errorf("'%s' is the default branch, it cannot be deleted", r.DefaultBranch))
+	}
+	refs, _, err := db.ListReferences(ctx, r.ID, string(refname), "", 1)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if len(refs) == 0 || refs[0].Name != refname {
+		return adminOutput(nil, fmt.Errorf("reference '%s' not found", refname))
+	}
+	_, err = db.DoReferenceUpdate(ctx, &database.Command{
+		ReferenceName: refname,
+		OldRev:        refs[0].Hash,
+		NewRev:        plumbing.ZERO_OID,
+		RID:           r.ID,
+		UID:           c.operator.ID,
+		Protocol:      "cli",
+	})
+	return adminOutput(nil, err)
+}
+
+type ListBranch struct {
+	listRefs
+}
+
+func (c *ListBranch) Run(globals *Globals) error {
+	return c.run(globals, plumbing.ReferencePrefix+"heads/")
+}
+
+type DeleteBranch struct {
+	deleteRef
+}
+
+func (c *DeleteBranch) Run(globals *Globals) error {
+	return c.run(globals, plumbing.NewBranchReferenceName(c.Name))
+}
+
+type ListTag struct {
+	listRefs
+}
+
+func (c *ListTag) Run(globals *Globals) error {
+	return c.run(globals, plumbing.ReferencePrefix+"tags/")
+}
+
+type DeleteTag struct {
+	deleteRef
+}
+
+func (c *DeleteTag) Run(globals *Globals) error {
+	return c.run(globals, plumbing.NewTagReferenceName(c.Name))
+}
+
+type AdminProtection struct {
+	List   ListProtection   `cmd:"list" aliases:"ls" help:"List protection rules"`
+	Add    AddProtection    `cmd:"add" help:"Add a protection rule"`
+	Update UpdateProtection `cmd:"update" help:"Replace a protection rule, settings not given are reset to defaults"`
+	Delete DeleteProtection `cmd:"delete" aliases:"rm" help:"Delete a protection rule"`
+}
+
+type ListProtection struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+}
+
+func (c *ListProtection) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(db.ListProtectionRules(ctx, r.ID))
+}
+
+type protectionFlags struct {
+	Pattern                string   `name:"pattern" help:"Full reference name, shell patterns are supported, eg: refs/heads/release/*" required:""`
+	PushAccessLevel        int      `name:"push-access-level" help:"Minimum access level to push, 60 forbids everyone" default:"30"`
+	ForcePushAccessLevel   int      `name:"force-push-access-level" help:"Minimum access level to force push" default:"60"`
+	DeleteAccessLevel      int      `name:"delete-access-level" help:"Minimum access level to delete" default:"60"`
+	RequireLinearHistory   bool     `name:"require-linear-history" help:"Reject merge commits"`
+	RequireSignatureHeader bool     `name:"require-signature-header" help:"Reject commits without a signature header, the signature is not verified"`
+	MaxBlobSize            int64    `name:"max-blob-size" help:"Maximum size of new blobs in bytes, 0: unlimited" default:"0"`
+	ForbiddenPaths         []string `name:"forbidden-path" help:"Paths which cannot be modified, repeatable"`
+}
+
+func (p *protectionFlags) rule(rid int64) *database.ProtectionRule {
+	return &database.ProtectionRule{
+		RID:                    rid,
+		Pattern:                p.Pattern,
+		PushAccessLevel:        database.AccessLevel(p.PushAccessLevel),
+		ForcePushAccessLevel:   database.AccessLevel(p.ForcePushAccessLevel),
+		DeleteAccessLevel:      database.AccessLevel(p.DeleteAccessLevel),
+		RequireLinearHistory:   p.RequireLinearHistory,
+		RequireSignatureHeader: p.RequireSignatureHeader,
+		MaxBlobSize:            p.MaxBlobSize,
+		ForbiddenPaths:         p.ForbiddenPaths,
+	}
+}
+
+type AddProtection struct {
+	adminConfig
+	protectionFlags
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+}
+
+func (c *AddProtection) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	p, err := db.NewProtectionRule(ctx, c.rule(r.ID))
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(p, c.record(ctx, db, "repo.protection.create", r.ID, fmt.Sprintf("repos/%s/protections/%d", c.Repo, p.ID)))
+}
+
+type UpdateProtection struct {
+	adminConfig
+	protectionFlags
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	ID   int64  `arg:"" name:"id" help:"ID of the protection rule"`
+}
+
+func (c *UpdateProtection) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	p := c.rule(r.ID)
+	p.ID = c.ID
+	if err := db.UpdateProtectionRule(ctx, p); err != nil {
+		if database.IsNotFound(err) {
+			err = fmt.Errorf("protection rule '%d' not found", c.ID)
+		}
+		return adminOutput(nil, err)
+	}
+	return adminOutput(p, c.record(ctx, db, "repo.protection.update", r.ID, fmt.Sprintf("repos/%s/protections/%d", c.Repo, p.ID)))
+}
+
+type DeleteProtection struct {
+	adminConfig
+	Repo string `arg:"" name:"repo" help:"Path of the repository, eg: group/repo"`
+	ID   int64  `arg:"" name:"id" help:"ID of the protection rule"`
+}
+
+func (c *DeleteProtection) Run(globals *Globals) error {
+	db, _, err := c.open(globals)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_, r, err := adminFindRepo(ctx, db, c.Repo)
+	if err != nil {
+		return adminOutput(nil, err)
+	}
+	if err := db.DeleteProtectionRule(ctx, r.ID, c.ID); err != nil {
+		return adminOutput(nil, err)
+	}
+	return adminOutput(nil, c.record(ctx, db, "repo.protection.delete", r.ID, fmt.Sprintf("repos/%s/protections/%d", c.Repo, c.ID)))
+}
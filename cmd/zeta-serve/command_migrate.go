@@ -0,0 +1,86 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+// databaseConfig: the database section and the repositories root of httpd and sshd config
+type databaseConfig struct {
+	DecryptedKey string          `toml:"decrypted_key,omitempty"`
+	Repositories string          `toml:"repositories,omitempty"`
+	DB           *serve.Database `toml:"database,omitempty"`
+}
+
+func (dc *databaseConfig) Decode(cfg string, expandEnv bool) error {
+	r, err := serve.NewExpandReader(cfg, expandEnv)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := toml.NewDecoder(r).Decode(dc); err != nil {
+		return err
+	}
+	if dc.DB == nil {
+		return errors.New("database is not configured")
+	}
+	dc.DB.Decrypt(dc.DecryptedKey)
+	return nil
+}
+
+type MigrateDB struct {
+	Config string `short:"c" name:"config" help:"Location of server config file, httpd or sshd" default:"~/config/zeta-serve-httpd.toml" type:"path"`
+	DryRun bool   `short:"n" name:"dry-run" help:"Show pending migrations without applying them"`
+}
+
+func (c *MigrateDB) Run(globals *Globals) error {
+	var dc databaseConfig
+	if err := dc.Decode(c.Config, globals.ExpandEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "load config error: %v\n", err)
+		return err
+	}
+	driver, dsn, err := dc.DB.DSN()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "database config error: %v\n", err)
+		return err
+	}
+	conn, err := database.OpenConn(driver, dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open database error: %v\n", err)
+		return err
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	if c.DryRun {
+		pending, err := database.PendingMigrations(ctx, conn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "check migrations error: %v\n", err)
+			return err
+		}
+		for _, m := range pending {
+			fmt.Fprintf(os.Stdout, "pending %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+	}
+	applied, err := database.Migrate(ctx, conn)
+	for _, m := range applied {
+		fmt.Fprintf(os.Stdout, "applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s database error: %v\n", driver, err)
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(os.Stdout, "database is up to date")
+	}
+	return nil
+}
@@ -13,10 +13,12 @@ import (
 
 type App struct {
 	Globals
-	HTTPD   HTTPD   `cmd:"httpd" help:"start zeta-serve httpd server"`
-	SSHD    SSHD    `cmd:"sshd" help:"start zeta-serve sshd server"`
-	Keygen  Keygen  `cmd:"keygen" help:"Generates a random private key"`
-	Encrypt Encrypt `cmd:"encrypt" help:"Encrypting Data Using RSA Key"`
+	HTTPD     HTTPD     `cmd:"httpd" help:"start zeta-serve httpd server"`
+	SSHD      SSHD      `cmd:"sshd" help:"start zeta-serve sshd server"`
+	Keygen    Keygen    `cmd:"keygen" help:"Generates a random private key"`
+	Encrypt   Encrypt   `cmd:"encrypt" help:"Encrypting Data Using RSA Key"`
+	MigrateDB MigrateDB `cmd:"migrate-db" help:"Create or upgrade the schema of the database"`
+	Admin     Admin     `cmd:"admin" help:"Manage users, namespaces and repositories through the database"`
 }
 
 func main() {
@@ -37,22 +37,32 @@ type App struct {
 	CatFile     command.Cat         `cmd:"cat-file" aliases:"cat" help:"Provide contents or details of repository objects"`
 	Log         command.Log         `cmd:"log" help:"Show commit logs"`
 	GC          command.GC          `cmd:"gc" help:"Cleanup unnecessary files and optimize the local repository"`
+	Cache       command.Cache       `cmd:"cache" help:"Manage the blob cache shared by repositories"`
+	Hydrate     command.Hydrate     `cmd:"hydrate" help:"Download and checkout contents of dehydrated files"`
+	Dehydrate   command.Dehydrate   `cmd:"dehydrate" help:"Replace clean files with placeholders and drop their local blobs"`
 	Reset       command.Reset       `cmd:"reset" help:"Reset current HEAD to the specified state"`
 	Diff        command.Diff        `cmd:"diff" help:"Show changes between commits, commit and working tree, etc"`
 	Clean       command.Clean       `cmd:"clean" help:"Remove untracked files from the working tree"`
 	LsTree      command.LsTree      `cmd:"ls-tree" help:"List the contents of a tree object"`
+	Du          command.Du          `cmd:"du" help:"Summarize sizes of directories at a revision"`
+	SizeReport  command.SizeReport  `cmd:"size-report" help:"Report the largest blobs in a revision or in history"`
 	MergeTree   command.MergeTree   `cmd:"merge-tree" help:"Perform merge without touching index or working tree"`
 	RM          command.Remove      `cmd:"rm" help:"Remove files from the working tree and from the index"`
 	Stash       command.Stash       `cmd:"stash" help:"Stash the changes in a dirty working directory away"`
 	RevParse    command.RevParse    `cmd:"rev-parse" help:"Pick out and massage parameters"`
 	ForEachRef  command.ForEachRef  `cmd:"for-each-ref" help:"Output information on each ref"`
 	Remote      command.Remote      `cmd:"remote" help:"Manage of tracked repository"`
+	LsRemote    command.LsRemote    `cmd:"ls-remote" help:"List references in the remote repository"`
 	CheckIgnore command.CheckIgnore `cmd:"check-ignore" help:"Debug zetaignore / exclude files"`
 	Init        command.Init        `cmd:"init" help:"Create an empty zeta repository"`
 	MergeBase   command.MergeBase   `cmd:"merge-base" help:"Find optimal common ancestors for merge"`
 	LsFiles     command.LsFiles     `cmd:"ls-files" help:"Show information about files in the index and the working tree"`
 	HashObject  command.HashObject  `cmd:"hash-object" help:"Compute hash or create object"`
+	Credential  command.Credential  `cmd:"credential" help:"Manage stored credentials"`
+	Bundle      command.Bundle      `cmd:"bundle" help:"Create, verify and push bundle files for offline transfer"`
 	Version     command.Version     `cmd:"version" help:"Display version information"`
+	CredCache   command.CredCache   `cmd:"" name:"credential-cache" hidden:"" help:"Helper to temporarily store passwords in memory"`
+	CredDaemon  command.CredDaemon  `cmd:"" name:"credential-cache--daemon" hidden:"" help:"Daemon of the credential cache helper"`
 	Debug       bool                `name:"debug" help:"Enable debug mode; analyze timing"`
 }
 
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/bits"
 )
 
 // Bitmap is an EWAH-encoded bitmap.
@@ -197,7 +198,7 @@ func (b *Bitmap) Set(pos int64) error {
 		if k == 0 && lastrlw.l()+1 <= maxUint31 {
 			lastrlw.setl(lastrlw.l() + 1)
 			b.w[b.lastrlw] = uint64(lastrlw)
-		} else if k > 0 && int64(lastrlw.k())+k <= math.MaxUint32 && lastrlw.l() == 0 {
+		} else if k > 0 && !lastrlw.b() && int64(lastrlw.k())+k <= math.MaxUint32 && lastrlw.l() == 0 {
 			// increment k only if k does not overflow
 			lastrlw.setk(lastrlw.k() + uint32(k))
 			lastrlw.setl(lastrlw.l() + 1)
@@ -222,42 +223,29 @@ func (b *Bitmap) Get(pos int64) bool {
 		return false
 	}
 
-	if b.lastpos > pos {
-		b.lastpos = -1
+	// restart from the first word when seeking backwards
+	if pos < b.acc || b.cursor >= len(b.w) {
 		b.cursor = 0
 		b.acc = 0
-	} else if b.cursor >= len(b.w) {
-		b.cursor = b.lastrlw
 	}
 
-	for ; b.cursor < len(b.w); b.cursor++ {
-		acc := b.acc
+	for b.cursor < len(b.w) {
 		word := rlw(b.w[b.cursor])
 		kb := int64(word.k()) * 64
+		l := int64(word.l())
+		b.lastpos = pos
 		if pos < b.acc+kb {
-			b.lastpos = pos
 			return word.b()
 		}
 
-		acc += kb
-		l := int64(word.l())
-
-		if l > 0 && pos < acc+l*64 {
-			for j := 1; j <= int(word.l()); j++ {
-				if pos < acc+64 {
-					w := b.w[b.cursor+j]
-					mask := uint64(1) << (63 - uint64(pos-acc))
-					return w&mask != 0
-				}
-
-				acc += 64
-			}
-		} else {
-			acc += l * 64
+		if pos < b.acc+kb+l*64 {
+			w := b.w[b.cursor+1+int((pos-b.acc-kb)/64)]
+			mask := uint64(1) << (63 - uint64(pos%64))
+			return w&mask != 0
 		}
 
-		b.cursor += int(l)
-		b.acc = acc
+		b.acc += kb + l*64
+		b.cursor += 1 + int(l)
 	}
 
 	return false
@@ -291,6 +279,198 @@ func (b *Bitmap) Reset() {
 	b.lastrlw = -1
 }
 
+// runIter iterates the compressed words of a bitmap as runs of fill words followed by literal words, the words
+// after the end of the bitmap are zero fills.
+type runIter struct {
+	w     []uint64
+	next  int    // index of the next rlw
+	fill  bool   // bit of the fill words
+	fills uint64 // fill words left in the current rlw
+	lit   int    // index of the next literal word
+	lits  int    // literal words left in the current rlw
+}
+
+func newRunIter(b *Bitmap) *runIter {
+	return &runIter{w: b.w}
+}
+
+// load moves to the next rlw when the current one is consumed, returns false at the end of the bitmap.
+func (it *runIter) load() bool {
+	for it.fills == 0 && it.lits == 0 {
+		if it.next >= len(it.w) {
+			return false
+		}
+		word := rlw(it.w[it.next])
+		it.fill, it.fills = word.b(), uint64(word.k())
+		it.lit, it.lits = it.next+1, int(word.l())
+		it.next += 1 + int(word.l())
+	}
+	return true
+}
+
+// run returns the fill word and the number of fill words available at the current position, 0 if the current word
+// is a literal.
+func (it *runIter) run() (uint64, uint64) {
+	if !it.load() {
+		return 0, math.MaxUint64
+	}
+	if it.fills == 0 {
+		return 0, 0
+	}
+	if it.fill {
+		return allones, it.fills
+	}
+	return 0, it.fills
+}
+
+// skip consumes n fill words.
+func (it *runIter) skip(n uint64) {
+	if it.load() {
+		it.fills -= n
+	}
+}
+
+// word consumes and returns one word.
+func (it *runIter) word() uint64 {
+	if !it.load() {
+		return 0
+	}
+	if it.fills > 0 {
+		it.fills--
+		if it.fill {
+			return allones
+		}
+		return 0
+	}
+	it.lits--
+	it.lit++
+	return it.w[it.lit-1]
+}
+
+// builder appends words to a bitmap in compressed form.
+type builder struct {
+	b   *Bitmap
+	cur rlw
+}
+
+func (bd *builder) newRlw() {
+	bd.b.w = append(bd.b.w, 0)
+	bd.b.lastrlw = len(bd.b.w) - 1
+	bd.cur = 0
+}
+
+func (bd *builder) addFill(one bool, n uint64) {
+	for n > 0 {
+		if bd.b.lastrlw < 0 || bd.cur.l() != 0 || (bd.cur.k() != 0 && bd.cur.b() != one) || bd.cur.k() == math.MaxUint32 {
+			bd.newRlw()
+		}
+		add := min(n, uint64(math.MaxUint32-bd.cur.k()))
+		bd.cur = newRlw(one, bd.cur.k()+uint32(add), 0)
+		bd.b.w[bd.b.lastrlw] = uint64(bd.cur)
+		n -= add
+	}
+}
+
+func (bd *builder) addLiteral(word uint64) {
+	if bd.b.lastrlw < 0 || bd.cur.l() >= maxUint31 {
+		bd.newRlw()
+	}
+	bd.cur.setl(bd.cur.l() + 1)
+	bd.b.w[bd.b.lastrlw] = uint64(bd.cur)
+	bd.b.w = append(bd.b.w, word)
+}
+
+// combine applies fn to the words of a and o without decompressing runs of fill words. The word holding the last
+// bit is always kept as a literal so that Set keeps working.
+func combine(a, o *Bitmap, n int64, fn func(x, y uint64) uint64) *Bitmap {
+	bd := &builder{b: &Bitmap{n: n, lastrlw: -1}}
+	remaining := uint64((n + 63) / 64)
+	var tail uint64
+	if n%64 != 0 {
+		tail = 1
+	}
+	ai, oi := newRunIter(a), newRunIter(o)
+	for remaining > 0 {
+		x, xn := ai.run()
+		y, yn := oi.run()
+		if fills := min(xn, yn, remaining-tail); fills > 0 {
+			bd.addFill(fn(x, y) == allones, fills)
+			ai.skip(fills)
+			oi.skip(fills)
+			remaining -= fills
+			continue
+		}
+		word := fn(ai.word(), oi.word())
+		remaining--
+		if remaining > 0 || tail == 0 {
+			if word == 0 || word == allones {
+				bd.addFill(word == allones, 1)
+				continue
+			}
+		}
+		bd.addLiteral(word)
+	}
+	return bd.b
+}
+
+// Or returns a new bitmap with the bits set in b or o.
+func (b *Bitmap) Or(o *Bitmap) *Bitmap {
+	return combine(b, o, max(b.n, o.n), func(x, y uint64) uint64 { return x | y })
+}
+
+// And returns a new bitmap with the bits set in both b and o.
+func (b *Bitmap) And(o *Bitmap) *Bitmap {
+	return combine(b, o, min(b.n, o.n), func(x, y uint64) uint64 { return x & y })
+}
+
+// AndNot returns a new bitmap with the bits set in b but not in o.
+func (b *Bitmap) AndNot(o *Bitmap) *Bitmap {
+	return combine(b, o, b.n, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// ForEach calls fn for every bit set to 1, in ascending order. Iteration
+// stops when fn returns false.
+func (b *Bitmap) ForEach(fn func(pos int64) bool) {
+	it := newRunIter(b)
+	for i := int64(0); i*64 < b.n; i++ {
+		if fill, fills := it.run(); fill == 0 && fills > 0 {
+			// skip runs of zeros
+			skip := min(fills, uint64((b.n-i*64+63)/64))
+			it.skip(skip)
+			i += int64(skip) - 1
+			continue
+		}
+		word := it.word()
+		for word != 0 {
+			idx := bits.LeadingZeros64(word)
+			pos := i*64 + int64(idx)
+			if pos >= b.n {
+				return
+			}
+			if !fn(pos) {
+				return
+			}
+			word &^= uint64(1) << (63 - idx)
+		}
+	}
+}
+
+// Count returns the number of bits set to 1.
+func (b *Bitmap) Count() int64 {
+	var count int64
+	for i := 0; i < len(b.w); i++ {
+		word := rlw(b.w[i])
+		if word.b() {
+			count += int64(word.k()) * 64
+		}
+		for _, lit := range b.w[i+1 : i+1+int(word.l())] {
+			count += int64(bits.OnesCount64(lit))
+		}
+		i += int(word.l())
+	}
+	return count
+}
+
 // setbit sets to 1 the bit in the given idx.
 func setbit(word *uint64, idx uint64) {
 	*word |= (uint64(1) << (64 - idx - 1))
@@ -232,6 +232,94 @@ func TestBug1(t *testing.T) {
 	}
 }
 
+func TestBitmapSetOps(t *testing.T) {
+	require := require.New(t)
+
+	build := func(positions ...int64) *Bitmap {
+		b := New()
+		for _, p := range positions {
+			require.NoError(b.Set(p))
+		}
+		return b
+	}
+	collect := func(b *Bitmap) []int64 {
+		var out []int64
+		b.ForEach(func(pos int64) bool {
+			out = append(out, pos)
+			return true
+		})
+		return out
+	}
+
+	var full []int64
+	for i := int64(64); i < 256; i++ {
+		full = append(full, i)
+	}
+	a := build(append([]int64{1, 3}, append(full, 300, 1000)...)...)
+	b := build(3, 70, 200, 1000, 1001)
+
+	or := a.Or(b)
+	require.Equal(append([]int64{1, 3}, append(full, 300, 1000, 1001)...), collect(or))
+	require.Equal(int64(len(full)+5), or.Count())
+	require.Equal([]int64{3, 70, 200, 1000}, collect(a.And(b)))
+	require.Equal([]int64{1001}, collect(b.AndNot(a)))
+
+	// results must stay appendable
+	and := a.And(b)
+	require.NoError(and.Set(1000 + 5))
+	require.True(and.Get(1005))
+	require.False(and.Get(1004))
+
+	buf := bytes.NewBuffer(nil)
+	_, err := or.Write(buf, binary.BigEndian)
+	require.NoError(err)
+	or2, err := FromBytes(buf.Bytes(), binary.BigEndian)
+	require.NoError(err)
+	require.Equal(collect(or), collect(or2))
+}
+
+func TestBitmapSetOpsRuns(t *testing.T) {
+	require := require.New(t)
+
+	// long runs of ones and zeros, compared with a plain set
+	ranges := func(rs ...[2]int64) (*Bitmap, map[int64]bool) {
+		b, set := New(), make(map[int64]bool)
+		for _, r := range rs {
+			for i := r[0]; i < r[1]; i++ {
+				require.NoError(b.Set(i))
+				set[i] = true
+			}
+		}
+		return b, set
+	}
+	a, aSet := ranges([2]int64{0, 64 * 100}, [2]int64{64*300 + 7, 64*500 + 3}, [2]int64{64 * 900, 64*900 + 1})
+	o, oSet := ranges([2]int64{64 * 50, 64 * 400}, [2]int64{64*1000 + 63, 64*1000 + 64})
+	check := func(b *Bitmap, n int64, fn func(x, y bool) bool) {
+		var expected, actual []int64
+		for i := int64(0); i < n; i++ {
+			if fn(aSet[i], oSet[i]) {
+				expected = append(expected, i)
+			}
+		}
+		b.ForEach(func(pos int64) bool {
+			actual = append(actual, pos)
+			return true
+		})
+		require.Equal(expected, actual)
+		require.Equal(int64(len(expected)), b.Count())
+		require.Less(len(b.w), 16, "runs must stay compressed")
+	}
+	check(a.Or(o), 64*1000+64, func(x, y bool) bool { return x || y })
+	check(a.And(o), 64*900+1, func(x, y bool) bool { return x && y })
+	check(a.AndNot(o), 64*900+1, func(x, y bool) bool { return x && !y })
+	check(o.AndNot(a), 64*1000+64, func(x, y bool) bool { return y && !x })
+
+	or := a.Or(o)
+	require.NoError(or.Set(64*2000 + 1))
+	require.True(or.Get(64*2000 + 1))
+	require.False(or.Get(64 * 2000))
+}
+
 func BenchmarkBitmapGet(b *testing.B) {
 	bitmap := newBitmap()
 	for i := 0; i < b.N; i++ {
@@ -3,12 +3,54 @@ package keyring
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ENV_ZETA_KEYRING_BACKEND: keyring backend, overrides credential.keyring
+	ENV_ZETA_KEYRING_BACKEND = "ZETA_KEYRING_BACKEND"
+	// BackendSystem: OS keyring, Secret Service/Keychain/Credential Manager
+	BackendSystem = "system"
+	// BackendFile: encrypted file keyring, for headless machines without OS keyring
+	BackendFile = "file"
 )
 
 // provider set in the init function by the relevant os file e.g.:
 // keyring_unix.go
 var provider Keyring = fallbackServiceProvider{}
 
+// backend: keyring selected by UseBackend, nil means the OS keyring
+var backend Keyring
+
+func current() Keyring {
+	if backend != nil {
+		return backend
+	}
+	return provider
+}
+
+// UseBackend: select the keyring backend, 'system' (default) or 'file'.
+func UseBackend(name string) error {
+	switch strings.ToLower(name) {
+	case "", BackendSystem:
+		backend = nil
+	case BackendFile:
+		backend = NewFileKeyring(DefaultFileKeyringDir())
+	default:
+		return fmt.Errorf("unsupported keyring backend '%s'", name)
+	}
+	return nil
+}
+
+// Backend: name of the selected keyring backend
+func Backend() string {
+	if _, ok := backend.(*fileKeyring); ok {
+		return BackendFile
+	}
+	return BackendSystem
+}
+
 var (
 	// ErrNotFound is the expected error if the secret isn't found in the
 	// keyring.
@@ -35,17 +77,30 @@ type Keyring interface {
 	Discard(ctx context.Context, targetName string) error
 }
 
+// Lister: keyrings able to enumerate the stored targets
+type Lister interface {
+	List(ctx context.Context) ([]string, error)
+}
+
 // Find cred in keyring for target.
 func Find(ctx context.Context, targetName string) (*Cred, error) {
-	return provider.Find(ctx, targetName)
+	return current().Find(ctx, targetName)
 }
 
 // Store target cred
 func Store(ctx context.Context, targetName string, c *Cred) error {
-	return provider.Store(ctx, targetName, c)
+	return current().Store(ctx, targetName, c)
 }
 
 // Discard cred
 func Discard(ctx context.Context, targetName string) error {
-	return provider.Discard(ctx, targetName)
+	return current().Discard(ctx, targetName)
+}
+
+// List targets stored in the keyring, only the file keyring supports listing.
+func List(ctx context.Context) ([]string, error) {
+	if l, ok := current().(Lister); ok {
+		return l.List(ctx)
+	}
+	return nil, fmt.Errorf("keyring backend '%s' does not support listing credentials", Backend())
 }
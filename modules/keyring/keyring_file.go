@@ -0,0 +1,278 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package keyring
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// ENV_ZETA_KEYRING_PASSPHRASE: passphrase of the file keyring, the machine-bound secret file is used when unset
+	ENV_ZETA_KEYRING_PASSPHRASE = "ZETA_KEYRING_PASSPHRASE"
+	fileKeyringVersion          = 1
+	fileKeyringKDF              = "scrypt"
+	lockTimeout                 = 10 * time.Second
+	staleLockTimeout            = 60 * time.Second
+)
+
+var (
+	ErrBadPassphrase = errors.New("unable to decrypt file keyring: wrong passphrase or machine secret")
+)
+
+// DefaultFileKeyringDir: ~/.zeta/keyring
+func DefaultFileKeyringDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".zeta", "keyring")
+	}
+	return filepath.Join(homeDir, ".zeta", "keyring")
+}
+
+// fileKeyring: credentials encrypted at rest with AES-256-GCM, the key is derived by scrypt from
+// ZETA_KEYRING_PASSPHRASE or from a random secret file bound to the machine.
+//
+//	<dir>/credentials  encrypted credentials
+//	<dir>/secret       machine-bound secret, created on first use
+type fileKeyring struct {
+	dir string
+}
+
+type fileKeyringData struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func NewFileKeyring(dir string) Keyring {
+	return &fileKeyring{dir: dir}
+}
+
+func (k *fileKeyring) credentialsPath() string {
+	return filepath.Join(k.dir, "credentials")
+}
+
+func machineID() []byte {
+	for _, p := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if b, err := os.ReadFile(p); err == nil {
+			return []byte(strings.TrimSpace(string(b)))
+		}
+	}
+	hostname, _ := os.Hostname()
+	return []byte(hostname)
+}
+
+// secret: passphrase or machine-bound secret
+func (k *fileKeyring) secret() ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(ENV_ZETA_KEYRING_PASSPHRASE); ok && len(passphrase) != 0 {
+		return []byte(passphrase), nil
+	}
+	secretPath := filepath.Join(k.dir, "secret")
+	secret, err := os.ReadFile(secretPath)
+	if os.IsNotExist(err) {
+		secret = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(secretPath, secret, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, _ = h.Write(secret)
+	_, _ = h.Write(machineID())
+	return h.Sum(nil), nil
+}
+
+func (k *fileKeyring) newAEAD(salt []byte) (cipher.AEAD, error) {
+	secret, err := k.secret()
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(secret, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (k *fileKeyring) load() (map[string]*Cred, error) {
+	creds := make(map[string]*Cred)
+	b, err := os.ReadFile(k.credentialsPath())
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d fileKeyringData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("decode file keyring: %w", err)
+	}
+	if d.Version != fileKeyringVersion || d.KDF != fileKeyringKDF {
+		return nil, fmt.Errorf("unsupported file keyring version %d kdf '%s'", d.Version, d.KDF)
+	}
+	aead, err := k.newAEAD(d.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, d.Nonce, d.Data, nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("decode file keyring: %w", err)
+	}
+	return creds, nil
+}
+
+func (k *fileKeyring) save(creds map[string]*Cred) error {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	d := &fileKeyringData{Version: fileKeyringVersion, KDF: fileKeyringKDF, Salt: make([]byte, 16)}
+	if _, err := io.ReadFull(rand.Reader, d.Salt); err != nil {
+		return err
+	}
+	aead, err := k.newAEAD(d.Salt)
+	if err != nil {
+		return err
+	}
+	d.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, d.Nonce); err != nil {
+		return err
+	}
+	d.Data = aead.Seal(nil, d.Nonce, plaintext, nil)
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	tmpPath := k.credentialsPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, k.credentialsPath())
+}
+
+// lock: exclusive lock file, concurrent zeta processes wait for each other, stale locks are removed.
+func (k *fileKeyring) lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return nil, err
+	}
+	lockPath := k.credentialsPath() + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		fd, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = fd.Close()
+			return func() {
+				_ = os.Remove(lockPath)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if si, err := os.Stat(lockPath); err == nil && time.Since(si.ModTime()) > staleLockTimeout {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("file keyring is locked by '%s'", lockPath)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (k *fileKeyring) Find(ctx context.Context, targetName string) (*Cred, error) {
+	unlock, err := k.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	creds, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := creds[targetName]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+func (k *fileKeyring) Store(ctx context.Context, targetName string, c *Cred) error {
+	unlock, err := k.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	creds, err := k.load()
+	if err != nil {
+		return err
+	}
+	creds[targetName] = &Cred{UserName: c.UserName, Password: c.Password}
+	return k.save(creds)
+}
+
+func (k *fileKeyring) Discard(ctx context.Context, targetName string) error {
+	unlock, err := k.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	creds, err := k.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[targetName]; !ok {
+		return ErrNotFound
+	}
+	delete(creds, targetName)
+	return k.save(creds)
+}
+
+func (k *fileKeyring) List(ctx context.Context) ([]string, error) {
+	unlock, err := k.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	creds, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0, len(creds))
+	for t := range creds {
+		targets = append(targets, t)
+	}
+	slices.Sort(targets)
+	return targets, nil
+}
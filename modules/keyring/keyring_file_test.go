@@ -0,0 +1,66 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestFileKeyring(t *testing.T) {
+	t.Setenv(ENV_ZETA_KEYRING_PASSPHRASE, "")
+	k := NewFileKeyring(t.TempDir())
+	ctx := context.Background()
+	if _, err := k.Find(ctx, "https://zeta.io"); err != ErrNotFound {
+		t.Fatalf("find in empty keyring: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := k.Store(ctx, fmt.Sprintf("https://%d.zeta.io", i), &Cred{UserName: "bob", Password: fmt.Sprintf("secret-%d", i)}); err != nil {
+				t.Errorf("store error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	targets, err := k.(Lister).List(ctx)
+	if err != nil || len(targets) != 4 {
+		t.Fatalf("list: %v %v", targets, err)
+	}
+	c, err := k.Find(ctx, "https://3.zeta.io")
+	if err != nil || c.UserName != "bob" || c.Password != "secret-3" {
+		t.Fatalf("find: %v %v", c, err)
+	}
+	if err := k.Discard(ctx, "https://3.zeta.io"); err != nil {
+		t.Fatalf("discard: %v", err)
+	}
+	if _, err := k.Find(ctx, "https://3.zeta.io"); err != ErrNotFound {
+		t.Fatalf("find discarded: %v", err)
+	}
+}
+
+func TestFileKeyringPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	t.Setenv(ENV_ZETA_KEYRING_PASSPHRASE, "correct horse")
+	if err := NewFileKeyring(dir).Store(ctx, "https://zeta.io", &Cred{UserName: "zeta-user-bob", Password: "s3cr3t-password"}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	b, err := os.ReadFile(dir + "/credentials")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for _, s := range []string{"zeta-user-bob", "s3cr3t-password", "https://zeta.io"} {
+		if bytes.Contains(b, []byte(s)) {
+			t.Errorf("'%s' is stored in plaintext: %s", s, b)
+		}
+	}
+	t.Setenv(ENV_ZETA_KEYRING_PASSPHRASE, "battery staple")
+	if _, err := NewFileKeyring(dir).Find(ctx, "https://zeta.io"); err != ErrBadPassphrase {
+		t.Fatalf("find with wrong passphrase: %v", err)
+	}
+}
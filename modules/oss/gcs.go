@@ -5,10 +5,15 @@ package oss
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -99,6 +104,106 @@ func (b *gscBucket) LinearUpload(ctx context.Context, resourcePath string, r io.
 	return nil
 }
 
+// GCS has no multipart upload session, parts are staged as objects under '<resourcePath>.uploads/<uploadID>/' and composed
+// into the target object when the session is completed.
+const (
+	gcsMaxComposeSources = 32
+)
+
+func gcsUploadPrefix(resourcePath, uploadID string) string {
+	return resourcePath + ".uploads/" + uploadID + "/"
+}
+
+func (b *gscBucket) InitiateUpload(ctx context.Context, resourcePath string, mime string) (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+func (b *gscBucket) UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) error {
+	if err := checkPartNumber(partNumber, size); err != nil {
+		return err
+	}
+	// cancel the context to discard a partially written part
+	newCtx, cancelCtx := context.WithCancel(ctx)
+	defer cancelCtx()
+	w := b.bucket.Object(fmt.Sprintf("%s%05d", gcsUploadPrefix(resourcePath, uploadID), partNumber)).NewWriter(newCtx)
+	if _, err := io.CopyN(w, r, size); err != nil {
+		cancelCtx()
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (b *gscBucket) ListParts(ctx context.Context, resourcePath, uploadID string) ([]*Part, error) {
+	prefix := gcsUploadPrefix(resourcePath, uploadID)
+	parts := make([]*Part, 0, 100)
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		o, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(o.Name, prefix))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, &Part{Number: number, ETag: o.Etag, Size: o.Size})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return parts, nil
+}
+
+func (b *gscBucket) compose(ctx context.Context, dst *storage.ObjectHandle, srcs []*storage.ObjectHandle) error {
+	_, err := dst.ComposerFrom(srcs...).Run(ctx)
+	return err
+}
+
+func (b *gscBucket) CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*Part) error {
+	prefix := gcsUploadPrefix(resourcePath, uploadID)
+	srcs := make([]*storage.ObjectHandle, 0, len(parts))
+	for _, p := range parts {
+		srcs = append(srcs, b.bucket.Object(fmt.Sprintf("%s%05d", prefix, p.Number)))
+	}
+	// compose accepts at most 32 sources, merge the parts level by level
+	for level := 0; len(srcs) > gcsMaxComposeSources; level++ {
+		merged := make([]*storage.ObjectHandle, 0, len(srcs)/gcsMaxComposeSources+1)
+		for i := 0; i < len(srcs); i += gcsMaxComposeSources {
+			dst := b.bucket.Object(fmt.Sprintf("%scompose-%d-%05d", prefix, level, i/gcsMaxComposeSources))
+			if err := b.compose(ctx, dst, srcs[i:min(i+gcsMaxComposeSources, len(srcs))]); err != nil {
+				return err
+			}
+			merged = append(merged, dst)
+		}
+		srcs = merged
+	}
+	if err := b.compose(ctx, b.bucket.Object(resourcePath), srcs); err != nil {
+		return err
+	}
+	return b.AbortUpload(ctx, resourcePath, uploadID)
+}
+
+func (b *gscBucket) AbortUpload(ctx context.Context, resourcePath, uploadID string) error {
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: gcsUploadPrefix(resourcePath, uploadID)})
+	for {
+		o, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		_ = b.bucket.Object(o.Name).Delete(ctx)
+	}
+	return nil
+}
+
 func (b *gscBucket) DeleteMultipleObjects(ctx context.Context, objectKeys []string) error {
 	for _, o := range objectKeys {
 		_ = b.bucket.Object(o).Delete(ctx)
@@ -0,0 +1,463 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oss
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LocalDownloadPrefix: route of the signed URLs returned by the Sharing of the local bucket, the server which
+	// shares the local bucket serves it, see LocalBucket.OpenShared.
+	LocalDownloadPrefix = "/_oss/"
+	localTempDir        = ".tmp"
+	localUploadsDir     = ".uploads"
+)
+
+var (
+	ErrBadResourcePath   = errors.New("bad resource path")
+	ErrSignatureMismatch = errors.New("signature mismatch")
+	ErrSignatureExpired  = errors.New("signature expired")
+	// ErrMissingSecret: signed URLs are verified by every server sharing the bucket, a random key differs per process.
+	ErrMissingSecret         = errors.New("local bucket requires access_key_secret to sign URLs")
+	ErrMissingSharedEndpoint = errors.New("local bucket requires shared_endpoint to serve signed URLs")
+)
+
+// LocalBucket: bucket on the local filesystem. Objects are stored as files named by their keys under the root, keys of
+// zeta objects are content-addressed and sharded by the hash prefix, eg: zeta/001/1001/ab/cd/abcd..., so every
+// directory stays small. Temporary files and multipart upload sessions are kept in '.tmp' and '.uploads' of the root.
+type LocalBucket struct {
+	root           string
+	sharedEndpoint string
+	secret         []byte
+}
+
+var (
+	_ Bucket = &LocalBucket{}
+)
+
+type NewLocalBucketOptions struct {
+	Root           string // root directory of the bucket
+	SharedEndpoint string // URL of the server which serves the signed URLs, eg: https://zeta.example.io
+	Secret         string // HMAC key of the signed URLs, shared by httpd and sshd
+}
+
+func NewLocalBucket(opts *NewLocalBucketOptions) (*LocalBucket, error) {
+	if len(opts.Secret) == 0 {
+		return nil, ErrMissingSecret
+	}
+	if len(opts.SharedEndpoint) == 0 {
+		return nil, ErrMissingSharedEndpoint
+	}
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range []string{localTempDir, localUploadsDir} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &LocalBucket{root: root, sharedEndpoint: strings.TrimSuffix(opts.SharedEndpoint, "/"), secret: []byte(opts.Secret)}, nil
+}
+
+// join: file path of the object, keys are relative slash-separated paths and must not escape the root.
+func (b *LocalBucket) join(resourcePath string) (string, error) {
+	if len(resourcePath) == 0 || strings.HasPrefix(resourcePath, "/") || strings.HasPrefix(resourcePath, ".") ||
+		strings.HasSuffix(resourcePath, "/") || path.Clean(resourcePath) != resourcePath || strings.Contains(resourcePath, "\\") {
+		return "", ErrBadResourcePath
+	}
+	for _, s := range strings.Split(resourcePath, "/") {
+		if s == ".." {
+			return "", ErrBadResourcePath
+		}
+	}
+	return filepath.Join(b.root, filepath.FromSlash(resourcePath)), nil
+}
+
+func notExist(err error) error {
+	if os.IsNotExist(err) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+func localETag(si fs.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", si.ModTime().UnixNano(), si.Size())
+}
+
+func (b *LocalBucket) Stat(ctx context.Context, resourcePath string) (*Stat, error) {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return nil, err
+	}
+	si, err := os.Stat(p)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	if !si.Mode().IsRegular() {
+		return nil, os.ErrNotExist
+	}
+	return &Stat{Size: si.Size()}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Open: start < 0 reads the last -start bytes, otherwise reads length bytes from start, or to the end when length is 0.
+func (b *LocalBucket) Open(ctx context.Context, resourcePath string, start, length int64) (RangeReader, error) {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(p)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	si, err := fd.Stat()
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	size := si.Size()
+	switch {
+	case start < 0:
+		start = max(size+start, 0)
+		length = size - start
+	case start >= 0 && length > 0:
+		length = min(length, size-start)
+	case start > 0:
+		length = size - start
+	default: // NO RANGE
+		return NewRangeReader(fd, size, ""), nil
+	}
+	if start >= size || length <= 0 {
+		_ = fd.Close()
+		return nil, fmt.Errorf("range %d-%d not satisfiable, size %d", start, start+length-1, size)
+	}
+	if _, err := fd.Seek(start, io.SeekStart); err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	rangeHdr := fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size)
+	return NewRangeReader(&readCloser{Reader: io.LimitReader(fd, length), Closer: fd}, size, rangeHdr), nil
+}
+
+// removeEmptyParents: remove empty directories between the object and the root
+func (b *LocalBucket) removeEmptyParents(p string) {
+	for dir := filepath.Dir(p); dir != b.root && strings.HasPrefix(dir, b.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+func (b *LocalBucket) Delete(ctx context.Context, resourcePath string) error {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	b.removeEmptyParents(p)
+	return nil
+}
+
+// writeFile: write r to the file atomically, size < 0 reads r to the end.
+func (b *LocalBucket) writeFile(p string, r io.Reader, size int64) error {
+	fd, err := os.CreateTemp(filepath.Join(b.root, localTempDir), "object-")
+	if err != nil {
+		return err
+	}
+	tempPath := fd.Name()
+	defer os.Remove(tempPath) // nolint
+	if size < 0 {
+		_, err = io.Copy(fd, r)
+	} else {
+		_, err = io.CopyN(fd, r, size)
+	}
+	if err != nil {
+		_ = fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	// empty parents removed by a concurrent Delete are created again
+	for i := 0; ; i++ {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(tempPath, p); err == nil || i == 2 || !os.IsNotExist(err) {
+			return err
+		}
+	}
+}
+
+func (b *LocalBucket) Put(ctx context.Context, resourcePath string, r io.Reader, mime string) error {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return err
+	}
+	return b.writeFile(p, r, -1)
+}
+
+func (b *LocalBucket) StartUpload(ctx context.Context, resourcePath, filePath string, mime string) error {
+	fd, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return b.Put(ctx, resourcePath, fd, mime)
+}
+
+func (b *LocalBucket) LinearUpload(ctx context.Context, resourcePath string, r io.Reader, size int64, mime string) error {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return err
+	}
+	return b.writeFile(p, r, size)
+}
+
+// uploadDir: directory of the multipart upload session, parts are named by the zero padded part number.
+func (b *LocalBucket) uploadDir(uploadID string) (string, error) {
+	if len(uploadID) != 32 {
+		return "", os.ErrNotExist
+	}
+	if _, err := hex.DecodeString(uploadID); err != nil {
+		return "", os.ErrNotExist
+	}
+	return filepath.Join(b.root, localUploadsDir, uploadID), nil
+}
+
+func (b *LocalBucket) InitiateUpload(ctx context.Context, resourcePath string, mime string) (string, error) {
+	if _, err := b.join(resourcePath); err != nil {
+		return "", err
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id[:])
+	if err := os.Mkdir(filepath.Join(b.root, localUploadsDir, uploadID), 0755); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (b *LocalBucket) UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) error {
+	if err := checkPartNumber(partNumber, size); err != nil {
+		return err
+	}
+	dir, err := b.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return notExist(err)
+	}
+	return b.writeFile(filepath.Join(dir, fmt.Sprintf("%05d", partNumber)), r, size)
+}
+
+func (b *LocalBucket) ListParts(ctx context.Context, resourcePath, uploadID string) ([]*Part, error) {
+	dir, err := b.uploadDir(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	parts := make([]*Part, 0, len(entries))
+	for _, e := range entries {
+		number, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		si, err := e.Info()
+		if err != nil {
+			continue
+		}
+		parts = append(parts, &Part{Number: number, ETag: localETag(si), Size: si.Size()})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return parts, nil
+}
+
+func (b *LocalBucket) CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*Part) error {
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return err
+	}
+	dir, err := b.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		fd, err := os.Open(filepath.Join(dir, fmt.Sprintf("%05d", part.Number)))
+		if err != nil {
+			return notExist(err)
+		}
+		defer fd.Close() // nolint
+		readers = append(readers, fd)
+	}
+	if err := b.writeFile(p, io.MultiReader(readers...), -1); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (b *LocalBucket) AbortUpload(ctx context.Context, resourcePath, uploadID string) error {
+	dir, err := b.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (b *LocalBucket) DeleteMultipleObjects(ctx context.Context, objectKeys []string) error {
+	for _, k := range objectKeys {
+		if err := b.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// comparePath: order of keys in the directory walk, keys are compared component by component.
+func comparePath(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
+
+var (
+	errListFull = errors.New("list full")
+)
+
+// ListObjects: objects are listed in the order of the directory walk, the continuation token is the last key of the page.
+func (b *LocalBucket) ListObjects(ctx context.Context, prefix, continuationToken string) ([]*Object, string, error) {
+	startDir := b.root
+	if dir := path.Dir(prefix + "x"); dir != "." {
+		startDir = filepath.Join(b.root, filepath.FromSlash(dir))
+	}
+	objects := make([]*Object, 0, 100)
+	err := filepath.WalkDir(startDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if p == b.root {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if strings.HasPrefix(key, ".") {
+				return fs.SkipDir
+			}
+			if dirKey := key + "/"; !strings.HasPrefix(dirKey, prefix) && !strings.HasPrefix(prefix, dirKey) {
+				return fs.SkipDir
+			}
+			// directories before the continuation token which do not contain it have been listed
+			if len(continuationToken) != 0 && comparePath(key, continuationToken) < 0 && !strings.HasPrefix(continuationToken, key+"/") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasPrefix(key, prefix) || strings.HasPrefix(key, ".") {
+			return nil
+		}
+		if len(continuationToken) != 0 && comparePath(key, continuationToken) <= 0 {
+			return nil
+		}
+		si, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		objects = append(objects, &Object{Key: key, Size: si.Size(), ETag: localETag(si)})
+		if len(objects) == MaxKeys {
+			return errListFull
+		}
+		return ctx.Err()
+	})
+	if err == errListFull {
+		return objects, objects[len(objects)-1].Key, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return objects, "", nil
+}
+
+func (b *LocalBucket) sign(resourcePath string, expiresAt int64) string {
+	h := hmac.New(sha256.New, b.secret)
+	_, _ = h.Write([]byte(resourcePath))
+	_, _ = h.Write([]byte{'\n'})
+	_, _ = h.Write([]byte(strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sharing: URL signed by HMAC-SHA256 of the key and the expiration time, the server serves it with OpenShared.
+func (b *LocalBucket) Sharing(ctx context.Context, resourcePath string, expiresAt int64) string {
+	if expiresAt <= 0 {
+		expiresAt = time.Now().Add(time.Hour).Unix()
+	}
+	q := make(url.Values)
+	q.Set("expires", strconv.FormatInt(expiresAt, 10))
+	q.Set("signature", b.sign(resourcePath, expiresAt))
+	return b.sharedEndpoint + LocalDownloadPrefix + resourcePath + "?" + q.Encode()
+}
+
+// OpenShared: verify the signed URL returned by Sharing and open the object.
+func (b *LocalBucket) OpenShared(resourcePath string, expires, signature string) (*os.File, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrSignatureMismatch
+	}
+	if !hmac.Equal([]byte(b.sign(resourcePath, expiresAt)), []byte(signature)) {
+		return nil, ErrSignatureMismatch
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, ErrSignatureExpired
+	}
+	p, err := b.join(resourcePath)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(p)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return fd, nil
+}
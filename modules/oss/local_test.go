@@ -0,0 +1,182 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalBucket(t *testing.T) *LocalBucket {
+	b, err := NewLocalBucket(&NewLocalBucketOptions{Root: t.TempDir(), SharedEndpoint: "http://zeta.example.io", Secret: "secret"})
+	require.NoError(t, err)
+	return b
+}
+
+func TestNewLocalBucketOptions(t *testing.T) {
+	_, err := NewLocalBucket(&NewLocalBucketOptions{Root: t.TempDir(), SharedEndpoint: "http://zeta.example.io"})
+	require.ErrorIs(t, err, ErrMissingSecret)
+	_, err = NewLocalBucket(&NewLocalBucketOptions{Root: t.TempDir(), Secret: "secret"})
+	require.ErrorIs(t, err, ErrMissingSharedEndpoint)
+}
+
+func TestLocalBucketPutOpen(t *testing.T) {
+	ctx := context.Background()
+	b := newTestLocalBucket(t)
+	key := "zeta/001/1/ab/cd/abcdef"
+	require.NoError(t, b.Put(ctx, key, strings.NewReader("0123456789"), "application/octet-stream"))
+	st, err := b.Stat(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), st.Size)
+
+	readAll := func(start, length int64) (string, string) {
+		rr, err := b.Open(ctx, key, start, length)
+		require.NoError(t, err)
+		defer rr.Close()
+		require.Equal(t, int64(10), rr.Size())
+		data, err := io.ReadAll(rr)
+		require.NoError(t, err)
+		return string(data), rr.Range()
+	}
+	data, hdr := readAll(0, 0)
+	require.Equal(t, "0123456789", data)
+	require.Empty(t, hdr)
+	data, hdr = readAll(2, 3)
+	require.Equal(t, "234", data)
+	require.Equal(t, "bytes 2-4/10", hdr)
+	data, hdr = readAll(7, 0)
+	require.Equal(t, "789", data)
+	require.Equal(t, "bytes 7-9/10", hdr)
+	data, hdr = readAll(-4, 0)
+	require.Equal(t, "6789", data)
+	require.Equal(t, "bytes 6-9/10", hdr)
+	_, err = b.Open(ctx, key, 10, 0)
+	require.Error(t, err)
+
+	_, err = b.Stat(ctx, "zeta/001/1/ab/cd/none")
+	require.Equal(t, os.ErrNotExist, err)
+	require.NoError(t, b.Delete(ctx, key))
+	require.NoError(t, b.Delete(ctx, key))
+	_, err = b.Open(ctx, key, 0, 0)
+	require.Equal(t, os.ErrNotExist, err)
+}
+
+func TestLocalBucketBadKey(t *testing.T) {
+	ctx := context.Background()
+	b := newTestLocalBucket(t)
+	for _, key := range []string{"", "../x", "a/../../x", "/a", ".uploads/x", "a//b"} {
+		require.ErrorIs(t, b.Put(ctx, key, strings.NewReader("x"), ""), ErrBadResourcePath, key)
+	}
+}
+
+func TestLocalBucketMultipart(t *testing.T) {
+	ctx := context.Background()
+	b := newTestLocalBucket(t)
+	key := "zeta/001/1/ab/cd/multipart"
+	uploadID, err := b.InitiateUpload(ctx, key, "")
+	require.NoError(t, err)
+	require.NoError(t, b.UploadPart(ctx, key, uploadID, 2, strings.NewReader("world"), 5))
+	require.NoError(t, b.UploadPart(ctx, key, uploadID, 1, strings.NewReader("hello "), 6))
+	parts, err := b.ListParts(ctx, key, uploadID)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	require.Equal(t, 1, parts[0].Number)
+	require.Equal(t, int64(5), parts[1].Size)
+	require.NoError(t, b.CompleteUpload(ctx, key, uploadID, parts))
+	rr, err := b.Open(ctx, key, 0, 0)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rr)
+	require.NoError(t, err)
+	require.NoError(t, rr.Close())
+	require.Equal(t, "hello world", string(data))
+	_, err = b.ListParts(ctx, key, uploadID)
+	require.Equal(t, os.ErrNotExist, err)
+
+	uploadID, err = b.InitiateUpload(ctx, key, "")
+	require.NoError(t, err)
+	require.NoError(t, b.AbortUpload(ctx, key, uploadID))
+	require.Error(t, b.UploadPart(ctx, key, uploadID, 1, strings.NewReader("x"), 1))
+}
+
+func TestLocalBucketListObjects(t *testing.T) {
+	ctx := context.Background()
+	b := newTestLocalBucket(t)
+	keys := make([]string, 0, MaxKeys+10)
+	for i := range MaxKeys + 10 {
+		key := fmt.Sprintf("zeta/001/1/%02x/%02x/%06d", i%7, i%5, i)
+		keys = append(keys, key)
+		require.NoError(t, b.LinearUpload(ctx, key, bytes.NewReader([]byte{'x'}), 1, ""))
+	}
+	require.NoError(t, b.Put(ctx, "zeta/002/1/00/00/other", strings.NewReader("x"), ""))
+	uploadID, err := b.InitiateUpload(ctx, "zeta/001/1/00/00/pending", "")
+	require.NoError(t, err)
+	require.NoError(t, b.UploadPart(ctx, "zeta/001/1/00/00/pending", uploadID, 1, strings.NewReader("x"), 1))
+
+	seen := make(map[string]bool)
+	var token string
+	pages := 0
+	for {
+		objects, next, err := b.ListObjects(ctx, "zeta/001/", token)
+		require.NoError(t, err)
+		pages++
+		for _, o := range objects {
+			require.True(t, strings.HasPrefix(o.Key, "zeta/001/"), o.Key)
+			require.False(t, seen[o.Key], o.Key)
+			seen[o.Key] = true
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Equal(t, 2, pages)
+	require.Len(t, seen, len(keys))
+
+	objects, _, err := b.ListObjects(ctx, "zeta/001/1/03/0", "")
+	require.NoError(t, err)
+	for _, o := range objects {
+		require.True(t, strings.HasPrefix(o.Key, "zeta/001/1/03/0"), o.Key)
+	}
+	require.NotEmpty(t, objects)
+
+	require.NoError(t, b.DeleteMultipleObjects(ctx, keys))
+	objects, _, err = b.ListObjects(ctx, "zeta/001/", "")
+	require.NoError(t, err)
+	require.Empty(t, objects)
+}
+
+func TestLocalBucketSharing(t *testing.T) {
+	ctx := context.Background()
+	b := newTestLocalBucket(t)
+	key := "zeta/001/1/ab/cd/shared"
+	require.NoError(t, b.Put(ctx, key, strings.NewReader("shared"), ""))
+
+	u, err := url.Parse(b.Sharing(ctx, key, time.Now().Add(time.Hour).Unix()))
+	require.NoError(t, err)
+	require.Equal(t, LocalDownloadPrefix+key, u.Path)
+	q := u.Query()
+	fd, err := b.OpenShared(key, q.Get("expires"), q.Get("signature"))
+	require.NoError(t, err)
+	data, err := io.ReadAll(fd)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	require.Equal(t, "shared", string(data))
+
+	_, err = b.OpenShared("zeta/001/1/ab/cd/other", q.Get("expires"), q.Get("signature"))
+	require.ErrorIs(t, err, ErrSignatureMismatch)
+	_, err = b.OpenShared(key, q.Get("expires")+"0", q.Get("signature"))
+	require.ErrorIs(t, err, ErrSignatureMismatch)
+
+	u, err = url.Parse(b.Sharing(ctx, key, time.Now().Add(-time.Minute).Unix()))
+	require.NoError(t, err)
+	q = u.Query()
+	_, err = b.OpenShared(key, q.Get("expires"), q.Get("signature"))
+	require.ErrorIs(t, err, ErrSignatureExpired)
+}
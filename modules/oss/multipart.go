@@ -39,6 +39,7 @@ const (
 	minPartSize     = 100 * 1024
 	maxPartSize     = 5 * GiByte
 	defaultPartSize = GiByte
+	maxParts        = 10000
 	// MaxPartSize                 = 5 * 1024 * 1024 * 1024 // Max part size, 5GB
 	// MinPartSize                 = 100 * 1024             // Min part size, 100KB
 )
@@ -51,6 +52,26 @@ type InitiateMultipartUploadResult struct {
 	UploadID string   `xml:"UploadId"` // Generated UploadId
 }
 
+// Part defines the uploaded part of the multipart upload session
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// ListPartsResult defines result of ListParts request
+type ListPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	UploadID             string   `xml:"UploadId"`
+	NextPartNumberMarker int      `xml:"NextPartNumberMarker"`
+	IsTruncated          bool     `xml:"IsTruncated"`
+	Parts                []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+		Size       int64  `xml:"Size"`
+	} `xml:"Part"`
+}
+
 // UploadPart defines the upload/copy part
 type UploadPart struct {
 	XMLName    xml.Name `xml:"Part"`
@@ -181,7 +202,8 @@ func (b *bucket) abortMultipartUpload(resourcePath string, mur *InitiateMultipar
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
-		return readOssError(resp)
+		// NoSuchUpload: the session is completed, aborted or expired
+		return os.ErrNotExist
 	}
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return errors.New(resp.Status)
@@ -242,6 +264,10 @@ func (b *bucket) uploadPart(ctx context.Context, resourcePath string, reader io.
 	if err != nil {
 		return result, err
 	}
+	if k.size > 0 {
+		// a short body fails the request instead of storing a truncated part
+		req.ContentLength = k.size
+	}
 	resource := b.getResourceV2(resourcePath, q)
 	b.signature(req, resource)
 	resp, err := b.Do(req)
@@ -286,3 +312,94 @@ func (b *bucket) LinearUpload(ctx context.Context, resourcePath string, r io.Rea
 	}
 	return nil
 }
+
+// https://www.alibabacloud.com/help/en/object-storage-service/latest/listparts
+func (b *bucket) listParts(ctx context.Context, resourcePath string, uploadID string, marker int) (*ListPartsResult, error) {
+	q := fmt.Sprintf("max-parts=1000&part-number-marker=%d&uploadId=%s", marker, uploadID)
+	u := &url.URL{
+		Scheme:   b.scheme,
+		Host:     b.bucketEndpoint,
+		Path:     resourcePath,
+		RawQuery: q,
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resource := b.getResourceV2(resourcePath, q)
+	b.signature(req, resource)
+	resp, err := b.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		// NoSuchUpload: the session is completed, aborted or expired
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, readOssError(resp)
+	}
+	var result ListPartsResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func checkPartNumber(partNumber int, size int64) error {
+	if partNumber < 1 || partNumber > maxParts {
+		return fmt.Errorf("part number %d out of range [1, %d]", partNumber, maxParts)
+	}
+	if size <= 0 || size > maxPartSize {
+		return fmt.Errorf("part size %d out of range (0, %d]", size, maxPartSize)
+	}
+	return nil
+}
+
+func (b *bucket) InitiateUpload(ctx context.Context, resourcePath string, mime string) (string, error) {
+	mur, err := b.initiateMultipartUpload(ctx, resourcePath, mime)
+	if err != nil {
+		return "", err
+	}
+	return mur.UploadID, nil
+}
+
+func (b *bucket) UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) error {
+	if err := checkPartNumber(partNumber, size); err != nil {
+		return err
+	}
+	_, err := b.uploadPart(ctx, resourcePath, io.LimitReader(r, size), &InitiateMultipartUploadResult{UploadID: uploadID}, chunk{number: partNumber, size: size})
+	return err
+}
+
+func (b *bucket) ListParts(ctx context.Context, resourcePath, uploadID string) ([]*Part, error) {
+	parts := make([]*Part, 0, 100)
+	var marker int
+	for {
+		result, err := b.listParts(ctx, resourcePath, uploadID, marker)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range result.Parts {
+			parts = append(parts, &Part{Number: p.PartNumber, ETag: p.ETag, Size: p.Size})
+		}
+		if !result.IsTruncated || result.NextPartNumberMarker <= marker {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	return parts, nil
+}
+
+func (b *bucket) CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*Part) error {
+	uploadParts := make([]UploadPart, 0, len(parts))
+	for _, p := range parts {
+		uploadParts = append(uploadParts, UploadPart{PartNumber: p.Number, ETag: p.ETag})
+	}
+	return b.completeMultipartUpload(ctx, resourcePath, &InitiateMultipartUploadResult{UploadID: uploadID}, uploadParts)
+}
+
+func (b *bucket) AbortUpload(ctx context.Context, resourcePath, uploadID string) error {
+	return b.abortMultipartUpload(resourcePath, &InitiateMultipartUploadResult{UploadID: uploadID})
+}
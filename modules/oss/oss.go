@@ -33,6 +33,14 @@ type Bucket interface {
 	// LinearUpload: Aliyun oss currently has a 5GB file upload limit, so when the OSS object exceeds 5GB, we use the MultipartUpload mechanism to upload. However,
 	// please note that due to network failures or other problems, large file uploads are prone to failure, and LFS is currently not working well. scheme to solve this problem.
 	LinearUpload(ctx context.Context, resourcePath string, r io.Reader, size int64, mime string) error
+	// InitiateUpload/UploadPart/ListParts/CompleteUpload/AbortUpload: multipart upload sessions that outlive a single request,
+	// parts are numbered from 1, uploading the same part number again replaces it. Abandoned sessions are aborted by the
+	// caller, ListParts and AbortUpload return os.ErrNotExist if the session is gone.
+	InitiateUpload(ctx context.Context, resourcePath string, mime string) (string, error)
+	UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) error
+	ListParts(ctx context.Context, resourcePath, uploadID string) ([]*Part, error)
+	CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*Part) error
+	AbortUpload(ctx context.Context, resourcePath, uploadID string) error
 	DeleteMultipleObjects(ctx context.Context, objectKeys []string) error
 	ListObjects(ctx context.Context, prefix, continuationToken string) ([]*Object, string, error)
 	Sharing(ctx context.Context, resourcePath string, expiresAt int64) string
@@ -5,6 +5,7 @@ package oss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -142,12 +143,17 @@ func (b *s3Bucket) uploadFilePart(ctx context.Context, resourcePath string, file
 
 func (b *s3Bucket) uploadPart(ctx context.Context, resourcePath string, reader io.Reader, mur *s3.CreateMultipartUploadOutput, k chunk) (types.CompletedPart, error) {
 	result := types.CompletedPart{PartNumber: aws.Int32(int32(k.number))}
-	o, err := b.client.UploadPart(ctx, &s3.UploadPartInput{
-		Bucket:   aws.String(b.bucketName),
-		Key:      aws.String(resourcePath),
-		UploadId: mur.UploadId,
-		Body:     reader,
-	})
+	input := &s3.UploadPartInput{
+		Bucket:     aws.String(b.bucketName),
+		Key:        aws.String(resourcePath),
+		UploadId:   mur.UploadId,
+		PartNumber: aws.Int32(int32(k.number)),
+		Body:       reader,
+	}
+	if k.size > 0 {
+		input.ContentLength = aws.Int64(k.size)
+	}
+	o, err := b.client.UploadPart(ctx, input)
 	if err != nil {
 		return result, err
 	}
@@ -277,6 +283,78 @@ func (b *s3Bucket) LinearUpload(ctx context.Context, resourcePath string, r io.R
 	return nil
 }
 
+func (b *s3Bucket) InitiateUpload(ctx context.Context, resourcePath string, mime string) (string, error) {
+	mur, err := b.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(b.bucketName),
+		Key:         aws.String(resourcePath),
+		ContentType: aws.String(mime),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(mur.UploadId), nil
+}
+
+func (b *s3Bucket) UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) error {
+	if err := checkPartNumber(partNumber, size); err != nil {
+		return err
+	}
+	_, err := b.uploadPart(ctx, resourcePath, io.LimitReader(r, size), &s3.CreateMultipartUploadOutput{UploadId: aws.String(uploadID)}, chunk{number: partNumber, size: size})
+	return err
+}
+
+func (b *s3Bucket) ListParts(ctx context.Context, resourcePath, uploadID string) ([]*Part, error) {
+	parts := make([]*Part, 0, 100)
+	p := s3.NewListPartsPaginator(b.client, &s3.ListPartsInput{
+		Bucket:   aws.String(b.bucketName),
+		Key:      aws.String(resourcePath),
+		UploadId: aws.String(uploadID),
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			var nsu *types.NoSuchUpload
+			if errors.As(err, &nsu) {
+				return nil, os.ErrNotExist
+			}
+			return nil, err
+		}
+		for _, o := range out.Parts {
+			parts = append(parts, &Part{Number: int(aws.ToInt32(o.PartNumber)), ETag: aws.ToString(o.ETag), Size: aws.ToInt64(o.Size)})
+		}
+	}
+	return parts, nil
+}
+
+func (b *s3Bucket) CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*Part) error {
+	completedParts := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completedParts = append(completedParts, types.CompletedPart{PartNumber: aws.Int32(int32(p.Number)), ETag: aws.String(p.ETag)})
+	}
+	_, err := b.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket: aws.String(b.bucketName),
+		Key:    aws.String(resourcePath),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+func (b *s3Bucket) AbortUpload(ctx context.Context, resourcePath, uploadID string) error {
+	_, err := b.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.bucketName),
+		Key:      aws.String(resourcePath),
+		UploadId: aws.String(uploadID),
+	})
+	var nsu *types.NoSuchUpload
+	if errors.As(err, &nsu) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
 func (b *s3Bucket) DeleteMultipleObjects(ctx context.Context, objectKeys []string) error {
 	d := &types.Delete{}
 	for _, o := range objectKeys {
@@ -0,0 +1,505 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package backend
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+const (
+	cacheLockName      = "cache.flock"
+	cacheAccessName    = "access.idx"
+	cacheCheckoutsName = "checkouts"
+	// cacheAccessRecordSize: raw oid + unix timestamp (BigEndian)
+	cacheAccessRecordSize = plumbing.HASH_DIGEST_SIZE + 8
+	cacheLockTimeout      = 30 * time.Second
+	// CacheGracePeriod: blobs accessed within the grace period are never evicted, they may belong to a
+	// checkout that is still running and has not recorded its HEAD or index yet.
+	CacheGracePeriod = time.Hour
+)
+
+// SharingCache: the blob directory shared by several repositories through core.sharingRoot.
+//
+//	<sharingRoot>/blob        loose blobs and packs
+//	<sharingRoot>/incoming    temporary files
+//	<sharingRoot>/access.idx  append-only access index: oid + last access time
+//	<sharingRoot>/checkouts   zeta dirs of the registered checkouts, one per line
+//	<sharingRoot>/cache.flock lock file, flock (LockFileEx on Windows) held while the index, the registry or the blobs
+//	                          are modified. The file is never removed, the lock of a crashed process is released
+//	                          by the system.
+type SharingCache struct {
+	root string
+}
+
+func NewSharingCache(root string) *SharingCache {
+	return &SharingCache{root: root}
+}
+
+func (c *SharingCache) Root() string {
+	return c.root
+}
+
+func (c *SharingCache) blobRoot() string {
+	return filepath.Join(c.root, "blob")
+}
+
+func (c *SharingCache) lock(ctx context.Context, wait bool) (func(), error) {
+	if err := os.MkdirAll(c.root, 0755); err != nil {
+		return nil, err
+	}
+	fd, err := os.OpenFile(filepath.Join(c.root, cacheLockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(cacheLockTimeout)
+	for {
+		locked, err := tryLockFile(fd)
+		if err != nil {
+			_ = fd.Close()
+			return nil, err
+		}
+		if locked {
+			return func() {
+				_ = unlockFile(fd)
+				_ = fd.Close()
+			}, nil
+		}
+		if !wait || time.Now().After(deadline) {
+			_ = fd.Close()
+			return nil, plumbing.NewErrResourceLocked("cache", plumbing.ReferenceName(c.root))
+		}
+		select {
+		case <-ctx.Done():
+			_ = fd.Close()
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Lock: acquire the cache lock, wait for other zeta processes to release it.
+func (c *SharingCache) Lock(ctx context.Context) (func(), error) {
+	return c.lock(ctx, true)
+}
+
+// TryLock: acquire the cache lock, return ErrResourceLocked if another zeta process holds it.
+func (c *SharingCache) TryLock(ctx context.Context) (func(), error) {
+	return c.lock(ctx, false)
+}
+
+func encodeAccessRecords(w io.Writer, accessed map[plumbing.Hash]int64) error {
+	var record [cacheAccessRecordSize]byte
+	for oid, t := range accessed {
+		copy(record[:], oid[:])
+		binary.BigEndian.PutUint64(record[plumbing.HASH_DIGEST_SIZE:], uint64(t))
+		if _, err := w.Write(record[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Touch: record the access time of blobs.
+func (c *SharingCache) Touch(ctx context.Context, accessed map[plumbing.Hash]int64) error {
+	if len(accessed) == 0 {
+		return nil
+	}
+	unlock, err := c.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	fd, err := os.OpenFile(filepath.Join(c.root, cacheAccessName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(fd)
+	if err := encodeAccessRecords(bw, accessed); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// AccessTimes: last access time of blobs recorded in the access index.
+func (c *SharingCache) AccessTimes() (map[plumbing.Hash]int64, error) {
+	accessed := make(map[plumbing.Hash]int64)
+	fd, err := os.Open(filepath.Join(c.root, cacheAccessName))
+	if os.IsNotExist(err) {
+		return accessed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	br := bufio.NewReader(fd)
+	var record [cacheAccessRecordSize]byte
+	for {
+		if _, err := io.ReadFull(br, record[:]); err != nil {
+			// a truncated trailing record is written by an interrupted process, ignore it
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return nil, err
+		}
+		var oid plumbing.Hash
+		copy(oid[:], record[:plumbing.HASH_DIGEST_SIZE])
+		t := int64(binary.BigEndian.Uint64(record[plumbing.HASH_DIGEST_SIZE:]))
+		if t > accessed[oid] {
+			accessed[oid] = t
+		}
+	}
+	return accessed, nil
+}
+
+func (c *SharingCache) rewriteAccessTimes(accessed map[plumbing.Hash]int64) error {
+	fd, err := os.CreateTemp(c.root, "access")
+	if err != nil {
+		return err
+	}
+	tempName := fd.Name()
+	bw := bufio.NewWriter(fd)
+	if err := encodeAccessRecords(bw, accessed); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := os.Rename(tempName, filepath.Join(c.root, cacheAccessName)); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+func (c *SharingCache) readCheckouts() ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(c.root, cacheCheckoutsName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkouts := make([]string, 0, 10)
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); len(line) != 0 {
+			checkouts = append(checkouts, line)
+		}
+	}
+	return checkouts, nil
+}
+
+func (c *SharingCache) writeCheckouts(checkouts []string) error {
+	var b strings.Builder
+	for _, p := range checkouts {
+		_, _ = b.WriteString(p)
+		_ = b.WriteByte('\n')
+	}
+	fd, err := os.CreateTemp(c.root, "checkouts")
+	if err != nil {
+		return err
+	}
+	tempName := fd.Name()
+	if _, err := fd.WriteString(b.String()); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := os.Rename(tempName, filepath.Join(c.root, cacheCheckoutsName)); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+// Checkouts: zeta dirs of the checkouts using the cache.
+func (c *SharingCache) Checkouts() ([]string, error) {
+	return c.readCheckouts()
+}
+
+// Register: register a checkout, blobs referenced by its HEAD or index are never evicted.
+func (c *SharingCache) Register(ctx context.Context, zetaDir string) error {
+	zetaDir, err := filepath.Abs(zetaDir)
+	if err != nil {
+		return err
+	}
+	checkouts, err := c.readCheckouts()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(checkouts, zetaDir) {
+		return nil
+	}
+	unlock, err := c.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// re-read the registry under lock
+	if checkouts, err = c.readCheckouts(); err != nil {
+		return err
+	}
+	if slices.Contains(checkouts, zetaDir) {
+		return nil
+	}
+	return c.writeCheckouts(append(checkouts, zetaDir))
+}
+
+// cleanupCheckouts: remove checkouts that no longer exist from the registry, the caller must hold the lock.
+func (c *SharingCache) cleanupCheckouts() ([]string, error) {
+	checkouts, err := c.readCheckouts()
+	if err != nil {
+		return nil, err
+	}
+	removed := make([]string, 0, 4)
+	alive := make([]string, 0, len(checkouts))
+	for _, p := range checkouts {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			removed = append(removed, p)
+			continue
+		}
+		alive = append(alive, p)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, c.writeCheckouts(alive)
+}
+
+type CacheUsage struct {
+	Objects    LooseObjects // loose blobs, can be evicted
+	LooseSize  int64
+	PackedSize int64 // packed blobs (zeta gc), cannot be evicted one by one
+}
+
+func (u *CacheUsage) Size() int64 {
+	return u.LooseSize + u.PackedSize
+}
+
+// Usage: scan the blob directory.
+func (c *SharingCache) Usage(ctx context.Context) (*CacheUsage, error) {
+	so := newFileStorer(c.blobRoot(), filepath.Join(c.root, "incoming"), DefaultCompressionALGO)
+	objects, err := so.looseObjects(math.MaxInt64)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	u := &CacheUsage{Objects: objects}
+	for _, o := range objects {
+		u.LooseSize += o.Size
+	}
+	err = filepath.WalkDir(filepath.Join(c.blobRoot(), "pack"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		si, err := d.Info()
+		if err != nil {
+			return err
+		}
+		u.PackedSize += si.Size()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return u, nil
+}
+
+type CachePruneOptions struct {
+	MaxSize int64                  // evict until the cache size is less than or equal to MaxSize
+	Pinned  map[plumbing.Hash]bool // blobs referenced by registered checkouts
+	DryRun  bool
+}
+
+type CachePruneResult struct {
+	Evicted           []plumbing.Hash
+	EvictedSize       int64
+	Size              int64 // cache size after eviction
+	RemovedCheckouts  []string
+	PinnedUnevictable int64 // size of pinned blobs or blobs in the grace period that prevented reaching MaxSize
+}
+
+// Prune: evict least recently used blobs not pinned by any checkout until the cache fits MaxSize.
+func (c *SharingCache) Prune(ctx context.Context, opts *CachePruneOptions) (*CachePruneResult, error) {
+	unlock, err := c.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	result := &CachePruneResult{}
+	if !opts.DryRun {
+		if result.RemovedCheckouts, err = c.cleanupCheckouts(); err != nil {
+			return nil, err
+		}
+	}
+	u, err := c.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	accessed, err := c.AccessTimes()
+	if err != nil {
+		return nil, err
+	}
+	lastAccess := func(o *LooseObject) int64 {
+		if t, ok := accessed[o.Hash]; ok && t > o.Modification {
+			return t
+		}
+		return o.Modification
+	}
+	size := u.Size()
+	if size > opts.MaxSize {
+		graceline := time.Now().Add(-CacheGracePeriod).Unix()
+		candidates := make(LooseObjects, 0, len(u.Objects))
+		for _, o := range u.Objects {
+			if opts.Pinned[o.Hash] || lastAccess(o) > graceline {
+				result.PinnedUnevictable += o.Size
+				continue
+			}
+			candidates = append(candidates, o)
+		}
+		slices.SortFunc(candidates, func(a, b *LooseObject) int {
+			return cmp.Compare(lastAccess(a), lastAccess(b))
+		})
+		for _, o := range candidates {
+			if size <= opts.MaxSize {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if !opts.DryRun {
+				if err := os.Remove(Join(c.blobRoot(), o.Hash)); err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+			}
+			result.Evicted = append(result.Evicted, o.Hash)
+			result.EvictedSize += o.Size
+			size -= o.Size
+		}
+	}
+	result.Size = size
+	if opts.DryRun {
+		return result, nil
+	}
+	// compact the access index: drop records of blobs no longer present
+	evicted := make(map[plumbing.Hash]bool, len(result.Evicted))
+	for _, oid := range result.Evicted {
+		evicted[oid] = true
+	}
+	compacted := make(map[plumbing.Hash]int64, len(u.Objects))
+	for _, o := range u.Objects {
+		if evicted[o.Hash] {
+			continue
+		}
+		if t, ok := accessed[o.Hash]; ok {
+			compacted[o.Hash] = t
+		}
+	}
+	if err := c.rewriteAccessTimes(compacted); err != nil {
+		return nil, err
+	}
+	so := newFileStorer(c.blobRoot(), filepath.Join(c.root, "incoming"), DefaultCompressionALGO)
+	_, _ = so.Prune(ctx)
+	return result, nil
+}
+
+var (
+	ErrCorruptedBlob = errors.New("corrupted blob")
+)
+
+func validateBlob(p string, oid plumbing.Hash) error {
+	fd, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	b, err := object.NewBlob(fd)
+	if err != nil {
+		return err
+	}
+	h := plumbing.NewHasher()
+	if _, err := io.Copy(h, b.Contents); err != nil {
+		return err
+	}
+	if h.Sum() != oid {
+		return ErrCorruptedBlob
+	}
+	return nil
+}
+
+type CacheVerifyResult struct {
+	Checked   int
+	Corrupted []plumbing.Hash
+	Removed   int
+}
+
+// Verify: check the hash of every loose blob, corrupted blobs are removed when fix is set, they will be
+// downloaded again on demand.
+func (c *SharingCache) Verify(ctx context.Context, fix bool) (*CacheVerifyResult, error) {
+	u, err := c.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &CacheVerifyResult{}
+	for _, o := range u.Objects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Checked++
+		if err := validateBlob(Join(c.blobRoot(), o.Hash), o.Hash); err != nil {
+			if os.IsNotExist(err) {
+				continue // evicted by another process
+			}
+			result.Corrupted = append(result.Corrupted, o.Hash)
+		}
+	}
+	if !fix || len(result.Corrupted) == 0 {
+		return result, nil
+	}
+	unlock, err := c.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	for _, oid := range result.Corrupted {
+		if err := os.Remove(Join(c.blobRoot(), oid)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		result.Removed++
+	}
+	return result, nil
+}
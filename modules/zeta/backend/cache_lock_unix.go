@@ -0,0 +1,23 @@
+//go:build !windows
+
+package backend
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile: exclusive flock without waiting, the kernel releases it when the process exits.
+func tryLockFile(fd *os.File) (bool, error) {
+	err := unix.Flock(int(fd.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) || errors.Is(err, unix.EINTR) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(fd *os.File) error {
+	return unix.Flock(int(fd.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+func TestSharingCachePrune(t *testing.T) {
+	ctx := context.Background()
+	sharingRoot := t.TempDir()
+	zetaDir := filepath.Join(t.TempDir(), ".zeta")
+	db, err := NewDatabase(zetaDir, WithSharingRoot(sharingRoot))
+	if err != nil {
+		t.Fatalf("new database error: %v", err)
+	}
+	oids := make([]plumbing.Hash, 0, 3)
+	for _, s := range []string{"oldest blob", "older blob", "newest blob"} {
+		content := strings.Repeat(s, 100)
+		oid, err := db.HashTo(ctx, strings.NewReader(content), int64(len(content)))
+		if err != nil {
+			t.Fatalf("hash-to error: %v", err)
+		}
+		oids = append(oids, oid)
+	}
+	if !db.Grown() {
+		t.Fatalf("database should be grown")
+	}
+	_ = db.Close()
+
+	c := NewSharingCache(sharingRoot)
+	checkouts, err := c.Checkouts()
+	if err != nil || len(checkouts) != 1 || checkouts[0] != zetaDir {
+		t.Fatalf("bad checkouts %v error: %v", checkouts, err)
+	}
+	accessed, err := c.AccessTimes()
+	if err != nil || len(accessed) != 3 {
+		t.Fatalf("bad access index %v error: %v", accessed, err)
+	}
+	// move all blobs out of the grace period
+	past := time.Now().Add(-3 * CacheGracePeriod)
+	for i, oid := range oids {
+		when := past.Add(time.Duration(i) * time.Minute)
+		_ = os.Chtimes(Join(c.blobRoot(), oid), when, when)
+		accessed[oid] = when.Unix()
+	}
+	if err := c.rewriteAccessTimes(accessed); err != nil {
+		t.Fatalf("rewrite access index error: %v", err)
+	}
+	u, err := c.Usage(ctx)
+	if err != nil {
+		t.Fatalf("usage error: %v", err)
+	}
+	// the oldest blob is pinned, evict one blob: the older one
+	result, err := c.Prune(ctx, &CachePruneOptions{
+		MaxSize: u.Size() - 1,
+		Pinned:  map[plumbing.Hash]bool{oids[0]: true},
+	})
+	if err != nil {
+		t.Fatalf("prune error: %v", err)
+	}
+	if len(result.Evicted) != 1 || result.Evicted[0] != oids[1] {
+		t.Fatalf("bad evicted %v", result.Evicted)
+	}
+	for i, oid := range oids {
+		_, err := os.Stat(Join(c.blobRoot(), oid))
+		if exists := err == nil; exists != (i != 1) {
+			t.Errorf("blob %s exists: %v", oid, exists)
+		}
+	}
+	if accessed, err = c.AccessTimes(); err != nil || len(accessed) != 2 {
+		t.Fatalf("bad compacted access index %v error: %v", accessed, err)
+	}
+	vr, err := c.Verify(ctx, false)
+	if err != nil || vr.Checked != 2 || len(vr.Corrupted) != 0 {
+		t.Fatalf("bad verify result %v error: %v", vr, err)
+	}
+}
+
+func TestSharingCacheLock(t *testing.T) {
+	ctx := context.Background()
+	c := NewSharingCache(filepath.Join(t.TempDir(), "sharing"))
+	unlock, err := c.Lock(ctx)
+	if err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+	if _, err := c.TryLock(ctx); !plumbing.IsErrResourceLocked(err) {
+		t.Fatalf("cache should be locked, error: %v", err)
+	}
+	unlock()
+	// the lock file is kept, only the lock is released
+	if _, err := os.Stat(filepath.Join(c.Root(), cacheLockName)); err != nil {
+		t.Fatalf("stat lock file error: %v", err)
+	}
+	unlock, err = c.TryLock(ctx)
+	if err != nil {
+		t.Fatalf("try lock error: %v", err)
+	}
+	unlock()
+}
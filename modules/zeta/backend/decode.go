@@ -181,7 +181,9 @@ func (d *Database) Blob(_ context.Context, oid plumbing.Hash) (blob *object.Blob
 	}
 	if blob, err = object.NewBlob(rc); err != nil {
 		_ = rc.Close()
+		return
 	}
+	d.touch(oid, false)
 	return
 }
 
@@ -278,6 +280,7 @@ func (d *Database) SizeReader(oid plumbing.Hash, meta bool) (SizeReader, error)
 	if err != nil {
 		return nil, err
 	}
+	d.touch(oid, false)
 	switch v := rc.(type) {
 	case *os.File:
 		si, err := v.Stat()
@@ -310,7 +313,11 @@ func (d *Database) OpenReader(oid plumbing.Hash, meta bool) (io.ReadCloser, erro
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	if !meta {
-		return d.ro.Open(oid)
+		rc, err := d.ro.Open(oid)
+		if err == nil {
+			d.touch(oid, false)
+		}
+		return rc, err
 	}
 	rc, err := d.metaRO.Open(oid)
 	if err != nil {
@@ -31,13 +31,20 @@ func (d *Database) HashTo(ctx context.Context, r io.Reader, size int64) (oid plu
 	}
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.rw.HashTo(ctx, r, size)
+	if oid, err = d.rw.HashTo(ctx, r, size); err == nil {
+		d.touch(oid, true)
+	}
+	return
 }
 
 func (d *Database) WriteTo(ctx context.Context, oid plumbing.Hash, r io.Reader) error {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.rw.Unpack(oid, r)
+	if err := d.rw.Unpack(oid, r); err != nil {
+		return err
+	}
+	d.touch(oid, true)
+	return nil
 }
 
 func (d *Database) JoinPart(oid plumbing.Hash) string {
@@ -117,5 +124,6 @@ func (d *Database) ValidateFD(fd *os.File, oid plumbing.Hash) error {
 		_ = os.Remove(name)
 		return err
 	}
+	d.touch(oid, true)
 	return nil
 }
@@ -4,13 +4,16 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/backend/pack"
 	"github.com/antgroup/hugescm/modules/zeta/backend/storage"
 	"github.com/antgroup/hugescm/modules/zeta/object"
@@ -39,6 +42,11 @@ type Database struct {
 	mu        sync.RWMutex
 	backend   object.Backend
 	enableLRU bool
+	// cache is not nil when blobs are stored in the sharing root
+	cache    *SharingCache
+	accessMu sync.Mutex
+	accessed map[plumbing.Hash]int64
+	grown    bool
 }
 
 type Option func(*Database)
@@ -99,9 +107,40 @@ func NewDatabase(root string, opts ...Option) (*Database, error) {
 	if d.backend == nil {
 		d.backend = d
 	}
+	if len(d.sharingRoot) != 0 {
+		d.cache = NewSharingCache(d.sharingRoot)
+		d.accessed = make(map[plumbing.Hash]int64)
+		// registration failure only weakens pinning, the repository remains usable
+		_ = d.cache.Register(context.Background(), root)
+	}
 	return d, nil
 }
 
+// touch: record blob access, flushed to the sharing cache access index on Close.
+func (d *Database) touch(oid plumbing.Hash, created bool) {
+	if d.cache == nil {
+		return
+	}
+	d.accessMu.Lock()
+	d.accessed[oid] = time.Now().Unix()
+	if created {
+		d.grown = true
+	}
+	d.accessMu.Unlock()
+}
+
+// SharingCache: returns the sharing cache, nil if core.sharingRoot is not set.
+func (d *Database) SharingCache() *SharingCache {
+	return d.cache
+}
+
+// Grown: new blobs were added to the sharing cache.
+func (d *Database) Grown() bool {
+	d.accessMu.Lock()
+	defer d.accessMu.Unlock()
+	return d.grown
+}
+
 func (d *Database) initializeBlobStorage() error {
 	if d.ro != nil {
 		_ = d.ro.Close()
@@ -188,6 +227,11 @@ func (d *Database) Close() error {
 	if !atomic.CompareAndSwapUint32(&d.closed, 0, 1) {
 		return fmt.Errorf("zeta: *Database already closed")
 	}
+	if d.cache != nil {
+		d.accessMu.Lock()
+		_ = d.cache.Touch(context.Background(), d.accessed)
+		d.accessMu.Unlock()
+	}
 	return closeSafe(d.ro, d.metaRO, d.rw, d.metaRW)
 }
 
@@ -6,6 +6,7 @@ package pack
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -130,6 +131,34 @@ func (e *Encoder) Write(oid plumbing.Hash, size uint32, r io.Reader, modificatio
 	return
 }
 
+// Truncate: keep the first n objects, the rest and a partially written object are discarded.
+// Only packs created with unknown entries can be truncated, their hash is computed by WriteTrailer.
+func (e *Encoder) Truncate(n int) error {
+	if e.hasher.Hash != nil {
+		return errors.New("pack with known entries cannot be truncated")
+	}
+	if n < 0 || n > len(e.objects) {
+		return fmt.Errorf("truncate pack to %d objects out of range [0, %d]", n, len(e.objects))
+	}
+	if n < len(e.objects) {
+		e.offset = e.objects[n].Offset
+		e.objects = e.objects[:n]
+	}
+	if err := e.bw.Flush(); err != nil {
+		return err
+	}
+	if err := e.fd.Truncate(int64(e.offset)); err != nil {
+		return err
+	}
+	_, err := e.fd.Seek(int64(e.offset), io.SeekStart)
+	return err
+}
+
+// Count: number of objects written
+func (e *Encoder) Count() int {
+	return len(e.objects)
+}
+
 func (e *Encoder) Name() string {
 	return e.sum.String()
 }
@@ -257,6 +286,14 @@ func (w *Writer) Write(oid plumbing.Hash, size uint32, r io.Reader, modification
 	return w.e.Write(oid, size, r, modification)
 }
 
+func (w *Writer) Truncate(n int) error {
+	return w.e.Truncate(n)
+}
+
+func (w *Writer) Count() int {
+	return w.e.Count()
+}
+
 func (w *Writer) WriteTrailer() error {
 	if err := w.e.WriteTrailer(); err != nil {
 		return err
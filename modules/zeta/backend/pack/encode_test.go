@@ -0,0 +1,68 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func testOID(n int) plumbing.Hash {
+	return plumbing.NewHash(fmt.Sprintf("%064x", n))
+}
+
+func TestWriterTruncate(t *testing.T) {
+	root := t.TempDir()
+	packDir := filepath.Join(root, "pack")
+	require.NoError(t, os.MkdirAll(packDir, 0755))
+	w, err := NewWriter(packDir, 0)
+	require.NoError(t, err)
+	defer w.Close()
+
+	contents := map[plumbing.Hash][]byte{
+		testOID(1): []byte("hello"),
+		testOID(2): []byte("world"),
+		testOID(3): []byte("dropped"),
+		testOID(5): []byte("again"),
+	}
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, w.Write(testOID(i), uint32(len(contents[testOID(i)])), bytes.NewReader(contents[testOID(i)]), 0))
+	}
+	// interrupted in the middle of an object
+	require.Error(t, w.Write(testOID(4), 100, io.MultiReader(bytes.NewReader([]byte("abc")), failingReader{}), 0))
+	require.Equal(t, 3, w.Count())
+
+	require.NoError(t, w.Truncate(2))
+	require.Equal(t, 2, w.Count())
+	require.Error(t, w.Truncate(3))
+
+	require.NoError(t, w.Write(testOID(5), 5, bytes.NewReader(contents[testOID(5)]), 0))
+	require.NoError(t, w.WriteTrailer())
+
+	s, err := NewStorage(root)
+	require.NoError(t, err)
+	defer s.Close()
+	for _, i := range []int{1, 2, 5} {
+		oid := testOID(i)
+		rc, err := s.Open(oid)
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		_ = rc.Close()
+		require.NoError(t, err)
+		require.Equal(t, contents[oid], b)
+	}
+	require.Error(t, s.Exists(testOID(3)))
+	require.Error(t, s.Exists(testOID(4)))
+}
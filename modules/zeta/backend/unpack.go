@@ -120,6 +120,9 @@ func (d *Database) NewUnpackerEx(entries uint32, metadata bool, method CompressM
 	case len(d.sharingRoot) != 0:
 		root = filepath.Join(d.sharingRoot, "blob")
 		incoming = filepath.Join(d.sharingRoot, "incoming")
+		d.accessMu.Lock()
+		d.grown = true
+		d.accessMu.Unlock()
 	default:
 		root = filepath.Join(d.root, "blob")
 		incoming = filepath.Join(d.root, "incoming")
@@ -0,0 +1,242 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundle implements the zeta bundle file, metadata and blobs of references in one self-describing,
+// checksummed file for offline transfer.
+//
+// Layout of the bundle file:
+//
+//	magic      'Z','K','\0','\1'
+//	version    uint32
+//	reserved   16 bytes
+//	header     uint32 length + JSON header
+//	metadata   metadata stream, same as the metadata transport: 'Z','M','\0','\1' ... crc64
+//	blobs      entries: uint64 length (64 + size) + 64 bytes hash + content + uint32 crc32, ended by uint64 0
+//	trailer    uint64 metadata offset, uint64 blobs offset, uint64 metadata count, uint64 blobs count,
+//	           32 bytes sha256 of all preceding bytes
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+const (
+	Version uint32 = 1
+	// Extension: conventional extension of bundle files
+	Extension = ".zb"
+)
+
+var (
+	Magic         = [4]byte{'Z', 'K', '\x00', '\x01'}
+	metadataMagic = [4]byte{'Z', 'M', '\x00', '\x01'}
+	reserved      [16]byte
+)
+
+const (
+	prefixSize    = 4 + 4 + 16
+	trailerSize   = 8*4 + sha256.Size
+	maxHeaderSize = 16 << 20
+)
+
+var (
+	ErrNotBundle = errors.New("not a zeta bundle file")
+)
+
+type Reference struct {
+	Name plumbing.ReferenceName `json:"name"`
+	Hash string                 `json:"hash"`
+	// Old: the value of the reference expected by the receiver of 'zeta bundle push', empty if the reference is created
+	Old string `json:"old,omitempty"`
+}
+
+// Header: describes what the bundle contains, written before the objects.
+type Header struct {
+	Version uint32 `json:"version"`
+	// HEAD: the reference checked out by 'zeta co bundle://file'
+	HEAD       plumbing.ReferenceName `json:"head"`
+	References []*Reference           `json:"references"`
+	// Prerequisites: commits that the receiver must already have, the history before them is not included
+	Prerequisites []string `json:"prerequisites,omitempty"`
+	// Sparses: only the trees and blobs of these directories are included
+	Sparses []string `json:"sparses,omitempty"`
+	// Blobs: blobs of the references are included, limited by MaxBlobSize, 0 means no limit
+	Blobs           bool      `json:"blobs"`
+	MaxBlobSize     int64     `json:"max_blob_size,omitempty"`
+	HashALGO        string    `json:"hash-algo"`
+	CompressionALGO string    `json:"compression-algo"`
+	Agent           string    `json:"agent"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Reference: find reference by name, HEAD resolves to Header.HEAD.
+func (h *Header) Reference(name plumbing.ReferenceName) (*Reference, bool) {
+	if name == plumbing.HEAD {
+		name = h.HEAD
+	}
+	for _, r := range h.References {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+type countingWriter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return n, err
+}
+
+// Writer: write the bundle in order, header, metadata stream, blobs, then Close writes the trailer.
+type Writer struct {
+	w              io.Writer
+	cw             *countingWriter
+	sha            hash.Hash
+	metadataOffset int64
+	blobsOffset    int64
+	metadata       int64
+	blobs          int64
+}
+
+func NewWriter(w io.Writer, h *Header) (*Writer, error) {
+	sha := sha256.New()
+	bw := &Writer{w: w, cw: &countingWriter{w: io.MultiWriter(w, sha)}, sha: sha}
+	h.Version = Version
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if err := binary.Write(bw.cw, Magic[:], Version, reserved[:]); err != nil {
+		return nil, err
+	}
+	if err := binary.WriteUint32(bw.cw, uint32(len(b))); err != nil {
+		return nil, err
+	}
+	if _, err := bw.cw.Write(b); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+// readMetadata: read the metadata stream, fn receives each object, the crc64 checksum is verified.
+func readMetadata(r io.Reader, fn func(oid plumbing.Hash, r io.Reader, size int64) error) (int64, error) {
+	cr := crc.NewCrc64Reader(r)
+	var magic [4]byte
+	var version uint32
+	var rs [16]byte
+	if err := binary.Read(cr, &magic, &version, &rs); err != nil {
+		return 0, fmt.Errorf("read metadata stream header error: %w", err)
+	}
+	if !bytes.Equal(magic[:], metadataMagic[:]) {
+		return 0, fmt.Errorf("unexpected metadata magic '%c' '%c' '%c' '%c'", magic[0], magic[1], magic[2], magic[3])
+	}
+	var oidBytes [plumbing.HASH_HEX_SIZE]byte
+	var count int64
+	for {
+		length, err := binary.ReadUint32(cr)
+		if err != nil {
+			return count, fmt.Errorf("read metadata length error: %w", err)
+		}
+		if length == 0 {
+			break
+		}
+		if length < plumbing.HASH_HEX_SIZE {
+			return count, fmt.Errorf("bad metadata length: %d", length)
+		}
+		if _, err := io.ReadFull(cr, oidBytes[:]); err != nil {
+			return count, fmt.Errorf("read metadata hash error: %w", err)
+		}
+		size := int64(length - plumbing.HASH_HEX_SIZE)
+		lr := io.LimitReader(cr, size)
+		if err := fn(plumbing.NewHash(string(oidBytes[:])), lr, size); err != nil {
+			return count, err
+		}
+		if _, err := io.Copy(io.Discard, lr); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cr.Verify(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// WriteMetadata: copy the metadata stream into the bundle, the stream is verified while copying.
+func (w *Writer) WriteMetadata(r io.Reader) error {
+	w.metadataOffset = w.cw.offset
+	count, err := readMetadata(io.TeeReader(r, w.cw), func(plumbing.Hash, io.Reader, int64) error { return nil })
+	if err != nil {
+		return err
+	}
+	w.metadata = count
+	w.blobsOffset = w.cw.offset
+	return nil
+}
+
+func entryChecksum(length uint64, oid plumbing.Hash) hash.Hash32 {
+	h := crc32.NewIEEE()
+	_ = binary.WriteUint64(h, length)
+	_, _ = h.Write([]byte(oid.String()))
+	return h
+}
+
+// WriteBlob: append a blob, must be called after WriteMetadata.
+func (w *Writer) WriteBlob(oid plumbing.Hash, r io.Reader, size int64) error {
+	if w.blobsOffset == 0 {
+		return errors.New("bundle metadata not written")
+	}
+	length := uint64(size + plumbing.HASH_HEX_SIZE)
+	if err := binary.WriteUint64(w.cw, length); err != nil {
+		return err
+	}
+	if err := binary.Write(w.cw, []byte(oid.String())); err != nil {
+		return err
+	}
+	h := entryChecksum(length, oid)
+	n, err := io.Copy(io.MultiWriter(w.cw, h), r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("blob %s: expected to write %d bytes, actually wrote %d bytes", oid, size, n)
+	}
+	if err := binary.WriteUint32(w.cw, h.Sum32()); err != nil {
+		return err
+	}
+	w.blobs++
+	return nil
+}
+
+// Close: finish the blobs and write the trailer, does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.blobsOffset == 0 {
+		return errors.New("bundle metadata not written")
+	}
+	if err := binary.WriteUint64(w.cw, 0); err != nil {
+		return err
+	}
+	if err := binary.Write(w.cw, uint64(w.metadataOffset), uint64(w.blobsOffset), uint64(w.metadata), uint64(w.blobs)); err != nil {
+		return err
+	}
+	// the checksum is not part of itself
+	_, err := w.w.Write(w.sha.Sum(nil))
+	return err
+}
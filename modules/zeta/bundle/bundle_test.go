@@ -0,0 +1,81 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func metadataStream(t *testing.T, objects map[plumbing.Hash]string) []byte {
+	var b bytes.Buffer
+	cw := crc.NewCrc64Writer(&b)
+	require.NoError(t, binary.Write(cw, metadataMagic[:], uint32(1), reserved[:]))
+	for oid, content := range objects {
+		require.NoError(t, binary.WriteUint32(cw, uint32(len(content)+plumbing.HASH_HEX_SIZE)))
+		require.NoError(t, binary.Write(cw, []byte(oid.String()), []byte(content)))
+	}
+	require.NoError(t, binary.WriteUint32(cw, 0))
+	_, err := cw.Finish()
+	require.NoError(t, err)
+	return b.Bytes()
+}
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+	commit := plumbing.NewHash(strings.Repeat("1", 64))
+	blob := plumbing.NewHash(strings.Repeat("2", 64))
+	name := filepath.Join(t.TempDir(), "repo"+Extension)
+	fd, err := os.Create(name)
+	require.NoError(t, err)
+	w, err := NewWriter(fd, &Header{
+		HEAD:       "refs/heads/main",
+		References: []*Reference{{Name: "refs/heads/main", Hash: commit.String()}},
+		Blobs:      true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMetadata(bytes.NewReader(metadataStream(t, map[plumbing.Hash]string{commit: "commit"}))))
+	require.NoError(t, w.WriteBlob(blob, strings.NewReader("hello world"), 11))
+	require.NoError(t, w.Close())
+	require.NoError(t, fd.Close())
+
+	require.True(t, IsBundle(name))
+	b, err := Open(name)
+	require.NoError(t, err)
+	defer b.Close()
+	require.NoError(t, b.Verify(ctx))
+	ref, ok := b.Reference(plumbing.HEAD)
+	require.True(t, ok)
+	require.Equal(t, commit.String(), ref.Hash)
+	require.Equal(t, int64(1), b.Metadata)
+	require.Equal(t, int64(1), b.Blobs)
+	e, err := b.Lookup(blob)
+	require.NoError(t, err)
+	var content bytes.Buffer
+	_, err = content.ReadFrom(b.Open(e, 6))
+	require.NoError(t, err)
+	require.Equal(t, "world", content.String())
+	_, err = b.Lookup(commit)
+	require.True(t, plumbing.IsNoSuchObject(err))
+
+	var push bytes.Buffer
+	require.NoError(t, b.PushTo(ctx, &push))
+	require.Equal(t, pushMagic[:], push.Bytes()[:4])
+
+	// corrupted bundle
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	data[len(data)-trailerSize-10] ^= 0xff
+	require.NoError(t, os.WriteFile(name, data, 0644))
+	bad, err := Open(name)
+	require.NoError(t, err)
+	defer bad.Close()
+	require.Error(t, bad.Verify(ctx))
+}
@@ -0,0 +1,366 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+// Entry: blob of the bundle
+type Entry struct {
+	Hash   plumbing.Hash
+	Size   int64
+	offset int64 // offset of the content
+}
+
+// Bundle: random access to the bundle file.
+type Bundle struct {
+	*Header
+	f              *os.File
+	size           int64
+	metadataOffset int64
+	blobsOffset    int64
+	Metadata       int64 // count of metadata objects
+	Blobs          int64 // count of blobs
+	once           sync.Once
+	entries        []*Entry
+	index          map[plumbing.Hash]*Entry
+	indexErr       error
+}
+
+// IsBundle: returns true if the file starts with the bundle magic.
+func IsBundle(name string) bool {
+	fd, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer fd.Close()
+	var magic [4]byte
+	if _, err := io.ReadFull(fd, magic[:]); err != nil {
+		return false
+	}
+	return magic == Magic
+}
+
+// Open: open the bundle and read its header and trailer, checksums are not verified, see Verify.
+func Open(name string) (*Bundle, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newBundle(fd)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func newBundle(fd *os.File) (*Bundle, error) {
+	si, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{f: fd, size: si.Size()}
+	if b.size < prefixSize+4+trailerSize {
+		return nil, ErrNotBundle
+	}
+	var magic [4]byte
+	var version, headerSize uint32
+	var rs [16]byte
+	if err := binary.Read(io.NewSectionReader(fd, 0, prefixSize+4), &magic, &version, &rs, &headerSize); err != nil {
+		return nil, err
+	}
+	if magic != Magic {
+		return nil, ErrNotBundle
+	}
+	if version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d", version)
+	}
+	if headerSize > maxHeaderSize || int64(headerSize) > b.size {
+		return nil, fmt.Errorf("bad bundle header size %d", headerSize)
+	}
+	hb := make([]byte, headerSize)
+	if _, err := fd.ReadAt(hb, prefixSize+4); err != nil {
+		return nil, fmt.Errorf("read bundle header error: %w", err)
+	}
+	b.Header = &Header{}
+	if err := json.Unmarshal(hb, b.Header); err != nil {
+		return nil, fmt.Errorf("decode bundle header error: %w", err)
+	}
+	var metadataOffset, blobsOffset, metadata, blobs uint64
+	if err := binary.Read(io.NewSectionReader(fd, b.size-trailerSize, 8*4), &metadataOffset, &blobsOffset, &metadata, &blobs); err != nil {
+		return nil, fmt.Errorf("read bundle trailer error: %w", err)
+	}
+	headerEnd := uint64(prefixSize + 4 + headerSize)
+	if metadataOffset != headerEnd || blobsOffset < metadataOffset || blobsOffset > uint64(b.size-trailerSize) || metadata > math.MaxInt64 || blobs > math.MaxInt64 {
+		return nil, fmt.Errorf("bad bundle trailer, the bundle may be truncated")
+	}
+	b.metadataOffset = int64(metadataOffset)
+	b.blobsOffset = int64(blobsOffset)
+	b.Metadata = int64(metadata)
+	b.Blobs = int64(blobs)
+	return b, nil
+}
+
+func (b *Bundle) Close() error {
+	return b.f.Close()
+}
+
+// Size: size of the bundle file
+func (b *Bundle) Size() int64 {
+	return b.size
+}
+
+// MetadataReader: the metadata stream, same as the metadata transport.
+func (b *Bundle) MetadataReader() io.Reader {
+	return io.NewSectionReader(b.f, b.metadataOffset, b.blobsOffset-b.metadataOffset)
+}
+
+// scanEntries: read blob entries in order, fn receives the reader of the content.
+func (b *Bundle) scanEntries(fn func(e *Entry, length uint64, content io.Reader) error) error {
+	end := b.size - trailerSize
+	offset := b.blobsOffset
+	var oidBytes [plumbing.HASH_HEX_SIZE]byte
+	for {
+		if offset+8 > end {
+			return fmt.Errorf("bad blob entry at %d, the bundle may be truncated", offset)
+		}
+		length, err := binary.ReadUint64(io.NewSectionReader(b.f, offset, 8))
+		if err != nil {
+			return err
+		}
+		offset += 8
+		if length == 0 {
+			break
+		}
+		if length < plumbing.HASH_HEX_SIZE || length+4 > uint64(end-offset) {
+			return fmt.Errorf("bad blob entry length %d at %d", length, offset-8)
+		}
+		if _, err := b.f.ReadAt(oidBytes[:], offset); err != nil {
+			return err
+		}
+		e := &Entry{Hash: plumbing.NewHash(string(oidBytes[:])), Size: int64(length) - plumbing.HASH_HEX_SIZE, offset: offset + plumbing.HASH_HEX_SIZE}
+		if err := fn(e, length, io.NewSectionReader(b.f, e.offset, e.Size)); err != nil {
+			return err
+		}
+		offset = e.offset + e.Size + 4
+	}
+	if offset != end {
+		return fmt.Errorf("unexpected data after blobs at %d", offset)
+	}
+	return nil
+}
+
+func (b *Bundle) buildIndex() {
+	b.index = make(map[plumbing.Hash]*Entry)
+	b.indexErr = b.scanEntries(func(e *Entry, _ uint64, _ io.Reader) error {
+		b.entries = append(b.entries, e)
+		b.index[e.Hash] = e
+		return nil
+	})
+}
+
+// Entries: blobs of the bundle in order.
+func (b *Bundle) Entries() ([]*Entry, error) {
+	b.once.Do(b.buildIndex)
+	return b.entries, b.indexErr
+}
+
+// Lookup: find the blob, returns plumbing.NoSuchObject error if the bundle does not contain it.
+func (b *Bundle) Lookup(oid plumbing.Hash) (*Entry, error) {
+	b.once.Do(b.buildIndex)
+	if b.indexErr != nil {
+		return nil, b.indexErr
+	}
+	if e, ok := b.index[oid]; ok {
+		return e, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+// Open: reader of the blob content from the offset.
+func (b *Bundle) Open(e *Entry, from int64) io.Reader {
+	return io.NewSectionReader(b.f, e.offset+from, e.Size-from)
+}
+
+// ForEachMetadata: read metadata objects in order, the metadata stream checksum is verified.
+func (b *Bundle) ForEachMetadata(fn func(oid plumbing.Hash, r io.Reader, size int64) error) error {
+	_, err := readMetadata(b.MetadataReader(), fn)
+	return err
+}
+
+// Verify: verify the checksum of the bundle file, the metadata stream and each blob.
+func (b *Bundle) Verify(ctx context.Context) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(b.f, 0, b.size-sha256.Size)); err != nil {
+		return err
+	}
+	want := make([]byte, sha256.Size)
+	if _, err := b.f.ReadAt(want, b.size-sha256.Size); err != nil {
+		return err
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("bundle checksum mismatch: got %x want %x", got, want)
+	}
+	metadata, err := readMetadata(b.MetadataReader(), func(plumbing.Hash, io.Reader, int64) error {
+		return ctx.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("verify metadata error: %w", err)
+	}
+	if metadata != b.Metadata {
+		return fmt.Errorf("metadata count mismatch: got %d want %d", metadata, b.Metadata)
+	}
+	var blobs int64
+	if err := b.scanEntries(func(e *Entry, length uint64, content io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h := entryChecksum(length, e.Hash)
+		if _, err := io.Copy(h, content); err != nil {
+			return err
+		}
+		sum, err := binary.ReadUint32(io.NewSectionReader(b.f, e.offset+e.Size, 4))
+		if err != nil {
+			return err
+		}
+		if h.Sum32() != sum {
+			return fmt.Errorf("blob %s checksum mismatch", e.Hash)
+		}
+		blobs++
+		return nil
+	}); err != nil {
+		return fmt.Errorf("verify blobs error: %w", err)
+	}
+	if blobs != b.Blobs {
+		return fmt.Errorf("blobs count mismatch: got %d want %d", blobs, b.Blobs)
+	}
+	return nil
+}
+
+var (
+	batchMagic          = [4]byte{'Z', 'B', '\x00', '\x02'}
+	batchVersion uint32 = 2
+	pushMagic           = [4]byte{'Z', 'P', '\x00', '\x01'}
+	pushVersion  uint32 = 1
+	// maxStreamBlobSize: length of blobs in batch and push streams is uint32
+	maxStreamBlobSize = int64(math.MaxUint32 - plumbing.HASH_HEX_SIZE)
+)
+
+// WriteBatch: write the blobs as a batch objects stream with per object checksum, like 'resumable-batch' of
+// zeta-serve, blobs not in the bundle and blobs that do not fit the stream are skipped.
+func (b *Bundle) WriteBatch(ctx context.Context, w io.Writer, oids []plumbing.Hash) error {
+	cw := crc.NewCrc64Writer(w)
+	if err := binary.Write(cw, batchMagic[:], batchVersion, reserved[:]); err != nil {
+		return err
+	}
+	for _, oid := range oids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e, err := b.Lookup(oid)
+		if plumbing.IsNoSuchObject(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if e.Size > maxStreamBlobSize {
+			continue
+		}
+		length := uint32(e.Size + plumbing.HASH_HEX_SIZE)
+		h := crc32.NewIEEE()
+		_ = binary.WriteUint32(h, length)
+		_, _ = h.Write([]byte(oid.String()))
+		if err := binary.WriteUint32(cw, length); err != nil {
+			return err
+		}
+		if err := binary.Write(cw, []byte(oid.String())); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.MultiWriter(cw, h), b.Open(e, 0)); err != nil {
+			return err
+		}
+		if err := binary.WriteUint32(cw, h.Sum32()); err != nil {
+			return err
+		}
+	}
+	if err := binary.WriteUint32(cw, 0); err != nil {
+		return err
+	}
+	_, err := cw.Finish()
+	return err
+}
+
+func writePushEntry(w io.Writer, oid plumbing.Hash, metadata bool, r io.Reader, size int64) error {
+	length := uint64(size + plumbing.HASH_HEX_SIZE)
+	if metadata {
+		length = uint64(-int64(length))
+	}
+	if err := binary.WriteUint64(w, length); err != nil {
+		return err
+	}
+	if err := binary.Write(w, []byte(oid.String())); err != nil {
+		return err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("expected to write pack %d bytes, actually wrote %d bytes", size, n)
+	}
+	return nil
+}
+
+// PushTo: write metadata and blobs of the bundle as a push stream, so that zeta-serve can ingest the bundle as a push.
+// Blobs that do not fit the push stream are skipped.
+func (b *Bundle) PushTo(ctx context.Context, w io.Writer) error {
+	cw := crc.NewCrc64Writer(w)
+	if err := binary.Write(cw, pushMagic[:], pushVersion, reserved[:]); err != nil {
+		return err
+	}
+	if err := b.ForEachMetadata(func(oid plumbing.Hash, r io.Reader, size int64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return writePushEntry(cw, oid, true, r, size)
+	}); err != nil {
+		return err
+	}
+	entries, err := b.Entries()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.Size > maxStreamBlobSize {
+			continue
+		}
+		if err := writePushEntry(cw, e.Hash, false, b.Open(e, 0), e.Size); err != nil {
+			return err
+		}
+	}
+	if err := binary.WriteUint64(cw, 0); err != nil {
+		return err
+	}
+	_, err = cw.Finish()
+	return err
+}
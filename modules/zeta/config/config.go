@@ -6,6 +6,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/antgroup/hugescm/modules/strengthen"
 )
@@ -165,12 +168,160 @@ func (t *Transport) Overwrite(o *Transport) {
 	}
 }
 
+// Cache: shared blob cache, see core.sharingRoot
+type Cache struct {
+	MaxSize Size `toml:"maxSize,omitempty"` // zeta config cache.maxSize 20G OR ZETA_CACHE_MAX_SIZE=20G
+}
+
+func (c *Cache) Overwrite(o *Cache) {
+	if o.MaxSize.Size > 0 {
+		c.MaxSize.Size = o.MaxSize.Size
+	}
+}
+
+const (
+	// DefaultRemote: core.remote is the URL of the implicit 'origin' remote
+	DefaultRemote = "origin"
+)
+
+// Remote: named remote, zeta config remote.<name>.url URL
+type Remote struct {
+	URL     string `toml:"url,omitempty"`
+	PushURL string `toml:"pushurl,omitempty"` // zeta config remote.<name>.pushurl URL, defaults to url
+}
+
+func (r *Remote) Overwrite(o *Remote) {
+	r.URL = overwrite(r.URL, o.URL)
+	r.PushURL = overwrite(r.PushURL, o.PushURL)
+}
+
+// PushTarget: push URL of the remote
+func (r *Remote) PushTarget() string {
+	return overwrite(r.URL, r.PushURL)
+}
+
+// Credential: external credential helpers, zeta config credential.helper 'cache --timeout=900',
+// helpers scoped to a URL: zeta config 'credential.https://zeta.io.helper' '!sso-token'
+type Credential struct {
+	Helper  StringArray            `toml:"helper,omitempty"`
+	Keyring string                 `toml:"keyring,omitempty"` // zeta config credential.keyring file OR ZETA_KEYRING_BACKEND=file
+	URLs    map[string]StringArray `toml:"-"`                 // [credential."https://zeta.io"] helper = "..."
+}
+
+func (c *Credential) UnmarshalTOML(data any) error {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", data)
+	}
+	for k, v := range m {
+		switch k {
+		case "helper":
+			if err := c.Helper.UnmarshalTOML(v); err != nil {
+				return err
+			}
+			continue
+		case "keyring":
+			if s, ok := v.(string); ok {
+				c.Keyring = s
+			}
+			continue
+		}
+		sub, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		h, ok := sub["helper"]
+		if !ok {
+			continue
+		}
+		var helpers StringArray
+		if err := helpers.UnmarshalTOML(h); err != nil {
+			return err
+		}
+		if c.URLs == nil {
+			c.URLs = make(map[string]StringArray)
+		}
+		c.URLs[k] = helpers
+	}
+	return nil
+}
+
+func (c *Credential) Overwrite(o *Credential) {
+	c.Helper = append(c.Helper, o.Helper...)
+	c.Keyring = overwrite(c.Keyring, o.Keyring)
+	for u, helpers := range o.URLs {
+		if c.URLs == nil {
+			c.URLs = make(map[string]StringArray)
+		}
+		c.URLs[u] = append(c.URLs[u], helpers...)
+	}
+}
+
+func matchCredentialURL(pattern, target *url.URL) bool {
+	if !strings.EqualFold(pattern.Scheme, target.Scheme) || !strings.EqualFold(pattern.Host, target.Host) {
+		return false
+	}
+	if len(pattern.User.Username()) != 0 && pattern.User.Username() != target.User.Username() {
+		return false
+	}
+	p := strings.Trim(pattern.Path, "/")
+	if len(p) == 0 {
+		return true
+	}
+	t := strings.Trim(target.Path, "/")
+	return t == p || strings.HasPrefix(t, p+"/")
+}
+
+// HelpersFor: helpers of the URL, helpers of the most specific matching URL come first, generic helpers last.
+// An empty helper in the list discards the less specific helpers.
+func (c *Credential) HelpersFor(rawURL string) []string {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	patterns := make([]string, 0, len(c.URLs))
+	for p := range c.URLs {
+		if u, err := url.Parse(p); err == nil && matchCredentialURL(u, target) {
+			patterns = append(patterns, p)
+		}
+	}
+	slices.SortFunc(patterns, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	lists := make([]StringArray, 0, len(patterns)+1)
+	for _, p := range patterns {
+		lists = append(lists, c.URLs[p])
+	}
+	lists = append(lists, c.Helper)
+	helpers := make([]string, 0, len(c.Helper))
+	for _, list := range lists {
+		var reset bool
+		for _, h := range list {
+			if len(h) == 0 {
+				reset = true
+				continue
+			}
+			helpers = append(helpers, h)
+		}
+		if reset {
+			break
+		}
+	}
+	return helpers
+}
+
 type Config struct {
-	Core      Core      `toml:"core,omitempty"`
-	User      User      `toml:"user,omitempty"`
-	Fragment  Fragment  `toml:"fragment,omitempty"`
-	HTTP      HTTP      `toml:"http,omitempty"`
-	Transport Transport `toml:"transport,omitempty"`
+	Core       Core               `toml:"core,omitempty"`
+	User       User               `toml:"user,omitempty"`
+	Fragment   Fragment           `toml:"fragment,omitempty"`
+	HTTP       HTTP               `toml:"http,omitempty"`
+	Transport  Transport          `toml:"transport,omitempty"`
+	Cache      Cache              `toml:"cache,omitempty"`
+	Remotes    map[string]*Remote `toml:"remote,omitempty"`
+	Credential Credential         `toml:"credential,omitempty"`
 }
 
 // Overwrite: use local config overwrite config
@@ -180,4 +331,48 @@ func (c *Config) Overwrite(co *Config) {
 	c.Fragment.Overwrite(&co.Fragment)
 	c.HTTP.Overwrite(&co.HTTP)
 	c.Transport.Overwrite(&co.Transport)
+	c.Cache.Overwrite(&co.Cache)
+	c.Credential.Overwrite(&co.Credential)
+	for name, o := range co.Remotes {
+		if o == nil {
+			continue
+		}
+		if c.Remotes == nil {
+			c.Remotes = make(map[string]*Remote)
+		}
+		if r, ok := c.Remotes[name]; ok {
+			r.Overwrite(o)
+			continue
+		}
+		c.Remotes[name] = &Remote{URL: o.URL, PushURL: o.PushURL}
+	}
+}
+
+// LookupRemote: find the remote by name, core.remote is the URL of 'origin' unless remote.origin.url is set.
+func (c *Config) LookupRemote(name string) (*Remote, bool) {
+	if r, ok := c.Remotes[name]; ok && r != nil {
+		if len(r.URL) == 0 && name == DefaultRemote {
+			return &Remote{URL: c.Core.Remote, PushURL: r.PushURL}, len(c.Core.Remote) != 0
+		}
+		return r, len(r.URL) != 0
+	}
+	if name == DefaultRemote && len(c.Core.Remote) != 0 {
+		return &Remote{URL: c.Core.Remote}, true
+	}
+	return nil, false
+}
+
+// RemoteNames: sorted names of the configured remotes, including the implicit 'origin'.
+func (c *Config) RemoteNames() []string {
+	names := make([]string, 0, len(c.Remotes)+1)
+	for name := range c.Remotes {
+		if _, ok := c.LookupRemote(name); ok {
+			names = append(names, name)
+		}
+	}
+	if _, ok := c.Remotes[DefaultRemote]; !ok && len(c.Core.Remote) != 0 {
+		names = append(names, DefaultRemote)
+	}
+	slices.Sort(names)
+	return names
 }
@@ -104,3 +104,27 @@ func TestLoad(t *testing.T) {
 
 	fmt.Fprintf(os.Stderr, "%v\n", rc)
 }
+
+func TestCredentialHelpersFor(t *testing.T) {
+	var cc Config
+	if _, err := toml.Decode(`[credential]
+helper = "cache --timeout=900"
+[credential."https://zeta.io"]
+helper = "!sso-token"
+[credential."https://zeta.io/group"]
+helper = ["", "!group-token"]
+`, &cc); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	for u, want := range map[string][]string{
+		"https://zeta.io/other/repo":  {"!sso-token", "cache --timeout=900"},
+		"https://zeta.io/group/repo":  {"!group-token"},
+		"https://zeta.io/groupx/repo": {"!sso-token", "cache --timeout=900"},
+		"https://example.io/repo":     {"cache --timeout=900"},
+	} {
+		got := cc.Credential.HelpersFor(u)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("HelpersFor(%s) = %v, want %v", u, got, want)
+		}
+	}
+}
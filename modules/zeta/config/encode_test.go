@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -121,3 +122,47 @@ func TestUpdateNot2(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "encode error: %v\n", err)
 	}
 }
+
+func TestUpdateRemote(t *testing.T) {
+	zetaDir := t.TempDir()
+	if err := UpdateLocal(zetaDir, &UpdateOptions{Values: map[string]any{
+		"core.remote":              "https://zeta.io/group/mono",
+		"remote.vendor.url":        "https://vendor.io/group/mono",
+		"remote.vendor.pushurl":    "https://vendor.io/drop/mono",
+		"remote.eu.mirror.url":     "https://eu.zeta.io/group/mono",
+		"remote.eu.mirror.pushurl": "",
+	}}); err != nil {
+		t.Fatalf("update config error: %v", err)
+	}
+	if err := UnsetLocal(zetaDir, "remote.eu.mirror.pushurl"); err != nil {
+		t.Fatalf("unset config error: %v", err)
+	}
+	cfg, err := Load(zetaDir)
+	if err != nil {
+		t.Fatalf("load config error: %v", err)
+	}
+	if names := cfg.RemoteNames(); len(names) != 3 || names[0] != "eu.mirror" || names[1] != "origin" || names[2] != "vendor" {
+		t.Fatalf("unexpected remotes: %v", names)
+	}
+	origin, ok := cfg.LookupRemote(DefaultRemote)
+	if !ok || origin.URL != "https://zeta.io/group/mono" || origin.PushTarget() != origin.URL {
+		t.Fatalf("unexpected origin: %v", origin)
+	}
+	vendor, ok := cfg.LookupRemote("vendor")
+	if !ok || vendor.PushTarget() != "https://vendor.io/drop/mono" {
+		t.Fatalf("unexpected vendor: %v", vendor)
+	}
+	if _, ok := cfg.LookupRemote("missing"); ok {
+		t.Fatalf("unexpected remote 'missing'")
+	}
+	if err := UnsetLocal(zetaDir, "remote.vendor"); err != nil {
+		t.Fatalf("unset config error: %v", err)
+	}
+	var b strings.Builder
+	if err := DisplayLocal(&DisplayOptions{Writer: &b}, zetaDir); err != nil {
+		t.Fatalf("display config error: %v", err)
+	}
+	if out := b.String(); strings.Contains(out, "vendor") || !strings.Contains(out, "remote.eu.mirror.url=https://eu.zeta.io/group/mono\n") {
+		t.Fatalf("unexpected config:\n%s", out)
+	}
+}
@@ -156,8 +156,34 @@ type Display interface {
 	Show(a any, keys ...string) error
 }
 
+// subsection: key 'remote.vendor.url' is 'url' of the subsection 'vendor' in the section 'remote', the subsection name
+// may contain dots, so the last component is the key.
+func (s Section) subsection(subKey string, create bool) (Section, string, bool) {
+	i := strings.LastIndexByte(subKey, '.')
+	if i == -1 {
+		return s, subKey, true
+	}
+	name, key := subKey[:i], subKey[i+1:]
+	v, exists := s[name]
+	if m, ok := v.(map[string]any); ok {
+		return Section(m), key, true
+	}
+	if !create || exists {
+		return nil, key, false
+	}
+	m := make(map[string]any)
+	s[name] = m
+	return Section(m), key, true
+}
+
 func (s Section) dispayTo(d Display, sectionKey string) error {
 	for subKey, v := range s {
+		if m, ok := v.(map[string]any); ok {
+			if err := Section(m).dispayTo(d, sectionKey+"."+subKey); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := d.Show(v, sectionKey, subKey); err != nil {
 			return err
 		}
@@ -217,7 +243,9 @@ func (ss Sections) filter(key string) (any, error) {
 		return nil, &ErrBadConfigKey{key: key}
 	}
 	if s, ok := ss[sectionKey]; ok {
-		return s.filter(subKey)
+		if s, subKey, ok := s.subsection(subKey, false); ok {
+			return s.filter(subKey)
+		}
 	}
 	return nil, ErrKeyNotFound
 }
@@ -228,7 +256,9 @@ func (ss Sections) filterAll(key string) ([]any, error) {
 		return nil, &ErrBadConfigKey{key: key}
 	}
 	if s, ok := ss[sectionKey]; ok {
-		return s.filterAll(subKey)
+		if s, subKey, ok := s.subsection(subKey, false); ok {
+			return s.filterAll(subKey)
+		}
 	}
 	return nil, ErrKeyNotFound
 }
@@ -244,8 +274,19 @@ func (ss Sections) deleteKey(key string) (bool, error) {
 	}
 	var deleted bool
 	if _, ok := s[subKey]; ok {
+		// key or whole subsection
 		delete(s, subKey)
 		deleted = true
+	} else if i := strings.LastIndexByte(subKey, '.'); i != -1 {
+		if sub, key, ok := s.subsection(subKey, false); ok {
+			if _, ok := sub[key]; ok {
+				delete(sub, key)
+				deleted = true
+			}
+			if len(sub) == 0 {
+				delete(s, subKey[:i])
+			}
+		}
 	}
 	if len(s) == 0 {
 		delete(ss, sectionKey)
@@ -367,9 +408,14 @@ func (ss Sections) updateKey(key string, val any, append bool) (bool, error) {
 	}
 	s, ok := ss[sectionKey]
 	if !ok {
-		newSection := make(Section)
-		newSection[subKey] = val
-		ss[sectionKey] = newSection
+		s = make(Section)
+		ss[sectionKey] = s
+	}
+	if s, subKey, ok = s.subsection(subKey, true); !ok {
+		return false, &ErrBadConfigKey{key: key}
+	}
+	if _, exists := s[subKey]; !exists {
+		s[subKey] = val
 		return true, nil
 	}
 	if raw, ok := s[subKey]; ok && append {
@@ -14,6 +14,7 @@ import (
 type Branch struct {
 	ShowCurrent bool     `name:"show-current" help:"Show current branch name"`
 	List        bool     `name:"list" short:"l" help:"List branches. With optional <pattern>..."`
+	Remotes     bool     `name:"remotes" short:"r" help:"List remote branches. With optional <pattern>..."`
 	Copy        bool     `name:"copy" short:"c" help:"Copy a branch and its reflog"`
 	ForceCopy   bool     `name:":force-copy" short:"C" help:"Copy a branch, even if target exists"`
 	Delete      bool     `name:"delete" short:"d" help:"Delete fully merged branch"`
@@ -21,12 +22,14 @@ type Branch struct {
 	Move        bool     `name:"move" short:"m" help:"Move/rename a branch and its reflog"`
 	ForceMove   bool     `name:":force-move" short:"M" help:"Move/rename a branch, even if target exists"`
 	Force       bool     `name:"force" short:"f" help:"Force creation, move/rename, deletion"`
+	Remote      string   `name:"remote" help:"List branches of the remote named <name> with '-r', default: origin" placeholder:"<name>"`
 	Args        []string `arg:"" optional:"" name:"args" help:"Branch args: <branchname>, <pattern>, <start-point>"`
 }
 
 const (
 	branchSumaryFormat = `%szeta branch [<options>] [-f] <branchname> [<start-point>]
 %szeta branch [<options>] [-l] [<pattern>...]
+%szeta branch -r [<pattern>...]
 %szeta branch [<options>] (-d | -D) <branchname>...
 %szeta branch [<options>] (-m | -M) [<old-branch>] <new-branch>
 %szeta branch [<options>] (-c | -C) [<old-branch>] <new-branch>
@@ -35,7 +38,7 @@ const (
 
 func (b *Branch) Summary() string {
 	or := W("   or: ")
-	return fmt.Sprintf(branchSumaryFormat, W("Usage: "), or, or, or, or, or)
+	return fmt.Sprintf(branchSumaryFormat, W("Usage: "), or, or, or, or, or, or)
 }
 
 func (b *Branch) IsMove() bool {
@@ -62,6 +65,7 @@ func (b *Branch) Run(g *Globals) error {
 	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
 		Worktree: g.CWD,
 		Values:   g.Values,
+		Remote:   b.Remote,
 		Verbose:  g.Verbose,
 	})
 	if err != nil {
@@ -71,6 +75,9 @@ func (b *Branch) Run(g *Globals) error {
 	if b.ShowCurrent {
 		return r.ShowCurrent(os.Stdout)
 	}
+	if b.Remotes {
+		return r.ListRemoteBranch(context.Background(), b.Args)
+	}
 	if b.List {
 		return r.ListBranch(context.Background(), b.Args)
 	}
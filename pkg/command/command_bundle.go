@@ -0,0 +1,87 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Bundle struct {
+	Create CreateBundle `cmd:"create" help:"Create a bundle file of the reference for offline transfer"`
+	Verify VerifyBundle `cmd:"verify" help:"Check that the bundle file is valid and can be applied"`
+	Push   PushBundle   `cmd:"push" help:"Push the bundle file to the remote"`
+}
+
+type CreateBundle struct {
+	File         string   `arg:"" name:"file" help:"Path of the bundle file"`
+	RevRange     string   `arg:"" optional:"" name:"rev-range" help:"Branch or tag to bundle, '<base>..<tip>' excludes history reachable from base, default: HEAD"`
+	Sparse       []string `name:"sparse" short:"s" help:"Only include trees and blobs of these directories" type:"string"`
+	IncludeBlobs bool     `name:"include-blobs" help:"Include blobs of the reference, only metadata is included by default"`
+	MaxBlobSize  int64    `name:"max-blob-size" help:"Include blobs no larger than n bytes or units, implies --include-blobs. supported units: KB,MB,GB,K,M,G" default:"0" type:"size"`
+}
+
+func (c *CreateBundle) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.BundleCreate(context.Background(), &zeta.BundleCreateOptions{
+		File:         c.File,
+		RevRange:     c.RevRange,
+		Sparses:      c.Sparse,
+		IncludeBlobs: c.IncludeBlobs,
+		MaxBlobSize:  c.MaxBlobSize,
+	})
+}
+
+type VerifyBundle struct {
+	File string `arg:"" name:"file" help:"Path of the bundle file"`
+}
+
+func (c *VerifyBundle) Run(g *Globals) error {
+	// prerequisites are checked only inside a repository
+	if _, _, err := zeta.FindZetaDir(g.CWD); err != nil {
+		if !zeta.IsErrNotZetaDir(err) {
+			return err
+		}
+		return zeta.VerifyBundle(context.Background(), nil, c.File)
+	}
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return zeta.VerifyBundle(context.Background(), r, c.File)
+}
+
+type PushBundle struct {
+	File   string `arg:"" name:"file" help:"Path of the bundle file"`
+	Remote string `name:"remote" help:"Push to the remote named <name>, default: origin" placeholder:"<name>"`
+	Force  bool   `name:"force" short:"f" help:"force updates"`
+}
+
+func (c *PushBundle) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+		Remote:   c.Remote,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.PushBundle(context.Background(), c.File, c.Force)
+}
@@ -0,0 +1,56 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"os"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// Cache: manage the blob cache shared by repositories through core.sharingRoot
+type Cache struct {
+	Status CacheStatus `cmd:"status" help:"Show usage of the shared blob cache" default:"1"`
+	Prune  CachePrune  `cmd:"prune" help:"Evict least recently used blobs not referenced by any checkout"`
+	Verify CacheVerify `cmd:"verify" help:"Verify the integrity of blobs in the shared blob cache"`
+}
+
+type CacheStatus struct {
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *CacheStatus) Run(g *Globals) error {
+	cc, err := zeta.OpenCache(&zeta.CacheOptions{Worktree: g.CWD, Values: g.Values, Verbose: g.Verbose})
+	if err != nil {
+		return err
+	}
+	return cc.Status(context.Background(), os.Stdout, c.JSON)
+}
+
+type CachePrune struct {
+	MaxSize int64 `name:"max-size" help:"Evict blobs until the cache fits in the size, overwrite cache.maxSize. supported units: KB,MB,GB,K,M,G" default:"-1" type:"size"`
+	DryRun  bool  `name:"dry-run" short:"n" help:"Do not remove anything; just show what would be evicted"`
+	Quiet   bool  `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
+}
+
+func (c *CachePrune) Run(g *Globals) error {
+	cc, err := zeta.OpenCache(&zeta.CacheOptions{Worktree: g.CWD, Values: g.Values, Verbose: g.Verbose})
+	if err != nil {
+		return err
+	}
+	return cc.Prune(context.Background(), &zeta.CachePruneOptions{MaxSize: c.MaxSize, DryRun: c.DryRun, Quiet: c.Quiet})
+}
+
+type CacheVerify struct {
+	Fix bool `name:"fix" help:"Remove corrupted blobs, they will be downloaded again on demand"`
+}
+
+func (c *CacheVerify) Run(g *Globals) error {
+	cc, err := zeta.OpenCache(&zeta.CacheOptions{Worktree: g.CWD, Values: g.Values, Verbose: g.Verbose})
+	if err != nil {
+		return err
+	}
+	return cc.Verify(context.Background(), c.Fix)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/local"
 	"github.com/antgroup/hugescm/pkg/zeta"
 )
 
@@ -33,6 +34,7 @@ type Checkout struct {
 	Depth           int      `name:"depth" help:"Create a shallow clone with a history truncated to the specified number of commits" default:"1"`
 	One             bool     `name:"one" help:"Checkout large files one after another"`
 	Quiet           bool     `name:"quiet" help:"Operate quietly. Progress is not reported to the standard error stream"`
+	Remote          string   `name:"remote" help:"Name of the remote to checkout from, default: origin" placeholder:"<name>"`
 	passthroughArgs []string `kong:"-"`
 }
 
@@ -57,6 +59,7 @@ func (c *Checkout) doRemote(g *Globals, remote, destination string) error {
 	}
 	r, err := zeta.New(context.Background(), &zeta.NewOptions{
 		Remote:      remote,
+		RemoteName:  c.Remote,
 		Branch:      c.Branch,
 		TagName:     c.TagName,
 		Commit:      c.Commit,
@@ -165,12 +168,13 @@ func (c *Checkout) runCompatibleCheckout(g *Globals, r *zeta.Repository) error {
 }
 
 func (c *Checkout) Run(g *Globals) error {
-	if len(c.UnresolvedArgs) > 0 && transport.IsRemoteEndpoint(c.UnresolvedArgs[0]) {
+	if len(c.UnresolvedArgs) > 0 && (transport.IsRemoteEndpoint(c.UnresolvedArgs[0]) || local.IsRepository(c.UnresolvedArgs[0])) {
 		return c.doRemote(g, c.UnresolvedArgs[0], c.destination())
 	}
 	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
 		Worktree: g.CWD,
 		Verbose:  g.Verbose,
+		Remote:   c.Remote,
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,39 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Credential struct {
+	List  ListCredential  `cmd:"list" aliases:"ls" help:"List URLs and usernames stored in the keyring"`
+	Erase EraseCredential `cmd:"erase" help:"Erase stored credentials of the URLs"`
+}
+
+type ListCredential struct {
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *ListCredential) Run(g *Globals) error {
+	return zeta.ListCredentials(context.Background(), &zeta.CredentialOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	}, c.JSON)
+}
+
+type EraseCredential struct {
+	URLs []string `arg:"" name:"url" help:"URL of the remote, e.g. https://zeta.io"`
+}
+
+func (c *EraseCredential) Run(g *Globals) error {
+	return zeta.EraseCredentials(context.Background(), &zeta.CredentialOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	}, c.URLs)
+}
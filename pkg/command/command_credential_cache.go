@@ -0,0 +1,61 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/antgroup/hugescm/pkg/transport/credential"
+)
+
+// CredCache: built-in credential helper, credentials are kept in memory by a daemon,
+// zeta config --global credential.helper 'cache --timeout=900'
+type CredCache struct {
+	Action  string `arg:"" name:"action" enum:"get,store,erase,exit" help:"Credential helper action: get, store, erase, exit"`
+	Timeout int    `name:"timeout" help:"Number of seconds to cache credentials" default:"900"`
+	Socket  string `name:"socket" help:"Use <path> to contact a running cache daemon" placeholder:"<path>"`
+}
+
+func (c *CredCache) Run(g *Globals) error {
+	socket := c.Socket
+	if len(socket) == 0 {
+		socket = credential.DefaultCacheSocket()
+	}
+	var cred *credential.Credential
+	if c.Action != credential.ActionExit {
+		var err error
+		if cred, err = credential.Decode(os.Stdin); err != nil {
+			diev("zeta credential-cache: %v", err)
+			return err
+		}
+	}
+	answer, err := credential.CacheDo(socket, c.Action, c.Timeout, cred)
+	if err != nil {
+		diev("zeta credential-cache %s: %v", c.Action, err)
+		return err
+	}
+	if c.Action == credential.ActionGet && answer != nil {
+		return answer.Encode(os.Stdout)
+	}
+	return nil
+}
+
+type CredDaemon struct {
+	Socket string `arg:"" name:"socket" help:"Path of the daemon socket"`
+}
+
+func (c *CredDaemon) Run(g *Globals) error {
+	d, err := credential.NewCacheDaemon(c.Socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "zeta credential-cache--daemon: %v\n", err)
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return d.Serve(ctx)
+}
@@ -0,0 +1,62 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Du struct {
+	MaxDepth int    `name:"max-depth" short:"d" help:"Print the total for a directory only if it is N or fewer levels below the path" default:"-1"`
+	JSON     bool   `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Revision string `arg:"" optional:"" name:"revision" help:"Revision to be analyzed, default HEAD"`
+	Path     string `arg:"" optional:"" name:"path" help:"Only count the directory"`
+}
+
+func (c *Du) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.Du(context.Background(), os.Stdout, &zeta.DuOptions{
+		Revision: c.Revision,
+		Path:     filepath.ToSlash(c.Path),
+		MaxDepth: c.MaxDepth,
+		JSON:     c.JSON,
+	})
+}
+
+type SizeReport struct {
+	History  bool   `name:"history" help:"Report the largest blobs ever added in the history of the revision, default all references"`
+	Limit    int    `name:"limit" short:"n" help:"Number of blobs to report" default:"20"`
+	JSON     bool   `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Revision string `arg:"" optional:"" name:"revision" help:"Revision to be analyzed, default HEAD"`
+}
+
+func (c *SizeReport) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.SizeReport(context.Background(), os.Stdout, &zeta.SizeReportOptions{
+		Revision: c.Revision,
+		History:  c.History,
+		Limit:    c.Limit,
+		JSON:     c.JSON,
+	})
+}
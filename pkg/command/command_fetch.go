@@ -6,7 +6,10 @@ package command
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
 	"github.com/antgroup/hugescm/pkg/zeta"
 )
 
@@ -14,15 +17,16 @@ import (
 // when implementing the fetch feature, it's important to adhere to the principle that zeta fetch will not support fetching all data at once,
 // but will only support fetching specific reference metadata and particular objects.
 type Fetch struct {
-	Name      string `arg:"" optional:"" name:"name" help:"Reference or commit to be downloaded"`
+	Name      string `arg:"" optional:"" name:"name" help:"Reference or commit to be downloaded, or a bundle file to import"`
 	Unshallow bool   `name:"unshallow" help:"Get complete history"`
 	Tag       bool   `name:"tag" short:"t" help:"Download tags instead of branches only when refname is incomplete"` //
 	Limit     int64  `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. supported units: KB,MB,GB,K,M,G" default:"-1" type:"size"`
 	Force     bool   `name:"force" short:"f" help:"Override reference update check"`
+	Remote    string `name:"remote" help:"Fetch from the remote named <name>, default: origin" placeholder:"<name>"`
 }
 
 const (
-	fetchSummaryFormat = `%szeta fetch [reference] [--unshallow] [--tag] [--skip-larges]`
+	fetchSummaryFormat = `%szeta fetch [reference|bundle] [--unshallow] [--tag] [--skip-larges] [--remote <name>]`
 )
 
 func (c *Fetch) Summary() string {
@@ -34,11 +38,18 @@ func (c *Fetch) Run(g *Globals) error {
 		Worktree: g.CWD,
 		Values:   g.Values,
 		Verbose:  g.Verbose,
+		Remote:   c.Remote,
 	})
 	if err != nil {
 		return err
 	}
 	defer r.Close()
+	if name, ok := bundleName(g.CWD, c.Name); ok {
+		return r.FetchBundle(context.Background(), name, &zeta.DoFetchOptions{
+			Limit: c.Limit,
+			Force: c.Force,
+		})
+	}
 	_, err = r.DoFetch(context.Background(), &zeta.DoFetchOptions{
 		Name:        c.Name,
 		Unshallow:   c.Unshallow,
@@ -48,3 +59,15 @@ func (c *Fetch) Run(g *Globals) error {
 	})
 	return err
 }
+
+// bundleName: 'bundle://path' or path of an existing bundle file, relative paths are resolved from cwd
+func bundleName(cwd, name string) (string, bool) {
+	p, ok := strings.CutPrefix(name, "bundle://")
+	if len(p) == 0 {
+		return "", false
+	}
+	if !filepath.IsAbs(p) && len(cwd) != 0 {
+		p = filepath.Join(cwd, p)
+	}
+	return p, ok || bundle.IsBundle(p)
+}
@@ -0,0 +1,56 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+type Hydrate struct {
+	PathSpec []string `arg:"" optional:"" name:"pathspec" help:"Path specification, similar to Git path matching mode"`
+	Remote   string   `name:"remote" help:"Download from the remote named <name>, default: origin" placeholder:"<name>"`
+}
+
+func (c *Hydrate) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Remote:   c.Remote,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w := r.Worktree()
+	pathSpec := c.PathSpec
+	if len(pathSpec) == 0 {
+		pathSpec = []string{"."}
+	}
+	return w.Hydrate(context.Background(), pathSpec)
+}
+
+type Dehydrate struct {
+	PathSpec []string `arg:"" name:"pathspec" help:"Path specification, similar to Git path matching mode"`
+	Force    bool     `name:"force" short:"f" help:"Dehydrate files not found in the upstream branch, they may not be downloaded again"`
+	DryRun   bool     `name:"dry-run" short:"n" help:"Do not remove anything; just show what would be dehydrated"`
+	Remote   string   `name:"remote" help:"Check files against the branch of the remote named <name>, default: origin" placeholder:"<name>"`
+}
+
+func (c *Dehydrate) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Remote:   c.Remote,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w := r.Worktree()
+	return w.Dehydrate(context.Background(), &zeta.DehydrateOptions{PathSpec: c.PathSpec, Force: c.Force, DryRun: c.DryRun})
+}
@@ -0,0 +1,38 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+
+	"github.com/antgroup/hugescm/pkg/zeta"
+)
+
+// List references in the remote repository
+type LsRemote struct {
+	Heads    bool     `name:"heads" help:"Limit to only refs/heads"`
+	Tags     bool     `name:"tags" short:"t" help:"Limit to only refs/tags"`
+	JSON     bool     `name:"json" short:"j" help:"Data will be returned in JSON format"`
+	Remote   string   `name:"remote" help:"List references of the remote named <name>, default: origin" placeholder:"<name>"`
+	Patterns []string `arg:"" optional:"" name:"pattern" help:"If given, only refs matching at least one pattern are shown"`
+}
+
+func (c *LsRemote) Run(g *Globals) error {
+	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Remote:   c.Remote,
+		Verbose:  g.Verbose,
+	})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.LsRemote(context.Background(), &zeta.LsRemoteOptions{
+		Heads:    c.Heads,
+		Tags:     c.Tags,
+		Patterns: c.Patterns,
+		JSON:     c.JSON,
+	})
+}
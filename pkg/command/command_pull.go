@@ -10,13 +10,14 @@ import (
 )
 
 type Pull struct {
-	FF        bool  `name:"ff" negatable:"" help:"Allow fast-forward" default:"true"`
-	FFOnly    bool  `name:"ff-only" help:"Abort if fast-forward is not possible"`
-	Rebase    bool  `name:"rebase" help:"Incorporate changes by rebasing rather than merging"`
-	Squash    bool  `name:"squash" help:"Create a single commit instead of doing a merge"`
-	Unshallow bool  `name:"unshallow" help:"Get complete history"`
-	One       bool  `name:"one" help:"Checkout large files one after another"`
-	Limit     int64 `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. supported units: KB,MB,GB,K,M,G" default:"-1" type:"size"`
+	FF        bool   `name:"ff" negatable:"" help:"Allow fast-forward" default:"true"`
+	FFOnly    bool   `name:"ff-only" help:"Abort if fast-forward is not possible"`
+	Rebase    bool   `name:"rebase" help:"Incorporate changes by rebasing rather than merging"`
+	Squash    bool   `name:"squash" help:"Create a single commit instead of doing a merge"`
+	Unshallow bool   `name:"unshallow" help:"Get complete history"`
+	One       bool   `name:"one" help:"Checkout large files one after another"`
+	Limit     int64  `name:"limit" short:"L" help:"Omits blobs larger than n bytes or units. n may be zero. supported units: KB,MB,GB,K,M,G" default:"-1" type:"size"`
+	Remote    string `name:"remote" help:"Pull from the remote named <name>, default: origin" placeholder:"<name>"`
 }
 
 func (c *Pull) Run(g *Globals) error {
@@ -28,6 +29,7 @@ func (c *Pull) Run(g *Globals) error {
 		Worktree: g.CWD,
 		Values:   g.Values,
 		Verbose:  g.Verbose,
+		Remote:   c.Remote,
 	})
 	if err != nil {
 		return err
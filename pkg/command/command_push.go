@@ -11,14 +11,24 @@ import (
 )
 
 type Push struct {
-	Refspec     string   `arg:"" optional:"" name:"refspec" default:"" help:"Specify what destination ref to update with what source object"`
+	Refspecs    []string `arg:"" optional:"" name:"refspec" help:"Specify what destination ref to update with what source object"`
 	PushOptions []string `name:"push-option" short:"o" help:"Option to transmit"`
 	Tag         bool     `name:"tag" short:"t" help:"Update remote tag reference"`
 	Force       bool     `name:"force" short:"f" help:"force updates"`
+	Remote      string   `name:"remote" help:"Push to the remote named <name>, default: origin" placeholder:"<name>"`
+	Atomic      bool     `name:"atomic" help:"Request atomic transaction on remote side, either all refs are updated, or none"`
 }
 
 func (c *Push) Run(g *Globals) error {
-	if len(c.Refspec) == 0 && c.Tag {
+	if len(c.Refspecs) > 1 && !c.Atomic {
+		diev("multiple refspecs require --atomic")
+		return errors.New("flags incompatible")
+	}
+	var refspec string
+	if len(c.Refspecs) != 0 {
+		refspec = c.Refspecs[0]
+	}
+	if len(refspec) == 0 && c.Tag {
 		diev("--tag is not compatible with blank refspec")
 		return errors.New("flags incompatible")
 	}
@@ -26,16 +36,19 @@ func (c *Push) Run(g *Globals) error {
 		Worktree: g.CWD,
 		Values:   g.Values,
 		Verbose:  g.Verbose,
+		Remote:   c.Remote,
 	})
 	if err != nil {
 		return err
 	}
 	defer r.Close()
 	if err := r.Push(context.Background(), &zeta.PushOptions{
-		Refspec:     c.Refspec,
+		Refspec:     refspec,
+		Refspecs:    c.Refspecs,
 		PushObjects: c.PushOptions,
 		Tag:         c.Tag,
 		Force:       c.Force,
+		Atomic:      c.Atomic,
 	}); err != nil {
 		return err
 	}
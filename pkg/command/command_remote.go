@@ -10,13 +10,25 @@ import (
 	"os"
 
 	"github.com/antgroup/hugescm/modules/zeta/config"
-	"github.com/antgroup/hugescm/pkg/transport"
 	"github.com/antgroup/hugescm/pkg/zeta"
 )
 
 type Remote struct {
-	Show ShowRemote `cmd:"show" help:"Gives some information about the remote" default:"1"`
-	Set  SetRemote  `cmd:"set" help:"Set URL for the remote"`
+	Show   ShowRemote   `cmd:"show" help:"Gives some information about the remote" default:"1"`
+	Set    SetRemote    `cmd:"set" help:"Set URL for the remote"`
+	Add    AddRemote    `cmd:"add" help:"Add a remote named <name> for the repository at <url>"`
+	Remove RemoveRemote `cmd:"remove" aliases:"rm" help:"Remove the remote named <name> and its remote-tracking references"`
+	Rename RenameRemote `cmd:"rename" help:"Rename the remote named <old> to <new>"`
+	SetURL SetRemoteURL `cmd:"set-url" help:"Change URL for the remote"`
+	List   ListRemote   `cmd:"list" aliases:"ls" help:"List remotes"`
+}
+
+func openRemoteRepository(g *Globals) (*zeta.Repository, error) {
+	return zeta.Open(context.Background(), &zeta.OpenOptions{
+		Worktree: g.CWD,
+		Values:   g.Values,
+		Verbose:  g.Verbose,
+	})
 }
 
 type ShowRemote struct {
@@ -24,23 +36,27 @@ type ShowRemote struct {
 }
 
 func (c *ShowRemote) Run(g *Globals) error {
-	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
-		Worktree: g.CWD,
-		Values:   g.Values,
-		Verbose:  g.Verbose,
-	})
+	r, err := openRemoteRepository(g)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	remote := r.Config.Core.Remote
+	rc, ok := r.LookupRemote(config.DefaultRemote)
+	if !ok {
+		// repository without origin
+		rc = &config.Remote{}
+	}
 	if c.JSON {
 		m := map[string]string{
-			"remote": remote,
+			"remote":  rc.URL,
+			"pushurl": rc.PushTarget(),
 		}
 		return json.NewEncoder(os.Stdout).Encode(m)
 	}
-	fmt.Fprintf(os.Stdout, "remote: %s\n", remote)
+	fmt.Fprintf(os.Stdout, "remote: %s\n", rc.URL)
+	if len(rc.PushURL) != 0 {
+		fmt.Fprintf(os.Stdout, "pushurl: %s\n", rc.PushURL)
+	}
 	return nil
 }
 
@@ -50,24 +66,23 @@ type SetRemote struct {
 }
 
 func (c *SetRemote) Run(g *Globals) error {
-	r, err := zeta.Open(context.Background(), &zeta.OpenOptions{
-		Worktree: g.CWD,
-		Values:   g.Values,
-		Verbose:  g.Verbose,
-	})
+	r, err := openRemoteRepository(g)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	e, err := transport.NewEndpoint(c.URL, nil)
+	newRemote, err := zeta.RemoteURL(c.URL)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "zeta remote set remote to '%s' error: %v\n", c.URL, err)
 		return err
 	}
-	newRemote := e.String()
+	key := "core.remote"
+	if rc, ok := r.Remotes[config.DefaultRemote]; ok && rc != nil && len(rc.URL) != 0 {
+		key = "remote." + config.DefaultRemote + ".url"
+	}
 	if err := config.UpdateLocal(r.ZetaDir(), &config.UpdateOptions{
 		Values: map[string]any{
-			"core.remote": newRemote,
+			key: newRemote,
 		},
 	}); err != nil {
 		fmt.Fprintf(os.Stderr, "zeta remote set remote to '%s' error: %v\n", newRemote, err)
@@ -76,3 +91,74 @@ func (c *SetRemote) Run(g *Globals) error {
 	fmt.Fprintf(os.Stdout, "remote: %s\n", newRemote)
 	return nil
 }
+
+type AddRemote struct {
+	Name    string `arg:"" name:"name" help:"Name of the remote"`
+	URL     string `arg:"" name:"url" help:"URL for the remote"`
+	PushURL string `name:"push-url" help:"URL used for pushing, default: <url>"`
+}
+
+func (c *AddRemote) Run(g *Globals) error {
+	r, err := openRemoteRepository(g)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.AddRemote(c.Name, c.URL, c.PushURL)
+}
+
+type RemoveRemote struct {
+	Name string `arg:"" name:"name" help:"Name of the remote"`
+}
+
+func (c *RemoveRemote) Run(g *Globals) error {
+	r, err := openRemoteRepository(g)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.RemoveRemote(c.Name)
+}
+
+type RenameRemote struct {
+	Old string `arg:"" name:"old" help:"Current name of the remote"`
+	New string `arg:"" name:"new" help:"New name of the remote"`
+}
+
+func (c *RenameRemote) Run(g *Globals) error {
+	r, err := openRemoteRepository(g)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.RenameRemote(c.Old, c.New)
+}
+
+type SetRemoteURL struct {
+	Name string `arg:"" name:"name" help:"Name of the remote"`
+	URL  string `arg:"" name:"url" help:"URL for the remote"`
+	Push bool   `name:"push" help:"Manipulate push URL instead of fetch URL"`
+}
+
+func (c *SetRemoteURL) Run(g *Globals) error {
+	r, err := openRemoteRepository(g)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.SetRemoteURL(c.Name, c.URL, c.Push)
+}
+
+type ListRemote struct {
+	Long bool `name:"long" short:"l" help:"Show remote URLs after name"`
+	JSON bool `name:"json" short:"j" help:"Data will be returned in JSON format"`
+}
+
+func (c *ListRemote) Run(g *Globals) error {
+	r, err := openRemoteRepository(g)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.ListRemotes(c.Long, c.JSON)
+}
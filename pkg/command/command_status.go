@@ -47,7 +47,7 @@ func (s *Status) Run(g *Globals) error {
 		w.ShowStatus(status, true, s.Z)
 		return nil
 	}
-	if status.IsClean() {
+	if status.IsClean() && !status.HasDehydrated() {
 		fmt.Fprintln(os.Stderr, W("nothing to commit, working tree clean"))
 		return nil
 	}
@@ -4,13 +4,19 @@
 package serve
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/database"
 	"github.com/go-sql-driver/mysql"
 )
 
@@ -28,12 +34,30 @@ func (d *Duration) UnmarshalText(text []byte) error {
 	return err
 }
 
+// Size: size in bytes, eg: "4g", "512m"
+type Size struct {
+	Size int64
+}
+
+func (s *Size) UnmarshalText(text []byte) error {
+	var err error
+	s.Size, err = strengthen.ParseSize(string(text))
+	return err
+}
+
+// Database: the metadata database, driver is mysql (default, MySQL or OceanBase), sqlite or postgres.
+//
+//	[database]
+//	driver = "sqlite"
+//	name = "/var/lib/zeta/zeta.db"
 type Database struct {
-	Name    string   `toml:"name"`
+	Driver  string   `toml:"driver,omitempty"`
+	Name    string   `toml:"name"` // database name, path of the database file for sqlite
 	User    string   `toml:"user"`
 	Host    string   `toml:"host"`
 	Port    int      `toml:"port"`
 	Passwd  string   `toml:"passwd"`
+	SSLMode string   `toml:"sslmode,omitempty"` // sslmode of postgres, default disable
 	Timeout Duration `toml:"timeout,omitempty"`
 }
 
@@ -72,14 +96,72 @@ func (d *Database) MakeConfig() (*mysql.Config, error) {
 	return cfg, nil
 }
 
+// DSN: driver and data source name of the database
+func (d *Database) DSN() (string, string, error) {
+	if d.Timeout.Duration == 0 {
+		d.Timeout.Duration = 30 * time.Second
+	}
+	switch d.Driver {
+	case "", database.MySQL:
+		cfg, err := d.MakeConfig()
+		if err != nil {
+			return "", "", err
+		}
+		return database.MySQL, cfg.FormatDSN(), nil
+	case database.SQLite:
+		if len(d.Name) == 0 {
+			return "", "", errors.New("sqlite database file is not set")
+		}
+		q := url.Values{}
+		q.Add("_pragma", "busy_timeout("+strconv.FormatInt(d.Timeout.Milliseconds(), 10)+")")
+		q.Add("_pragma", "journal_mode(WAL)")
+		q.Add("_pragma", "foreign_keys(1)")
+		q.Add("_pragma", "case_sensitive_like(1)")
+		q.Set("_time_format", "sqlite")
+		q.Set("_txlock", "immediate")
+		return database.SQLite, "file:" + d.Name + "?" + q.Encode(), nil
+	case database.PostgreSQL:
+		port := d.Port
+		if port == 0 {
+			port = 5432
+		}
+		sslMode := d.SSLMode
+		if len(sslMode) == 0 {
+			sslMode = "disable"
+		}
+		q := url.Values{}
+		q.Set("sslmode", sslMode)
+		q.Set("connect_timeout", strconv.Itoa(max(int(d.Timeout.Seconds()), 1)))
+		u := &url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(d.User, d.Passwd),
+			Host:     net.JoinHostPort(d.Host, strconv.Itoa(port)),
+			Path:     "/" + d.Name,
+			RawQuery: q.Encode(),
+		}
+		return database.PostgreSQL, u.String(), nil
+	}
+	return "", "", fmt.Errorf("unsupported database driver '%s'", d.Driver)
+}
+
+// OSS: bucket of large objects. When local is set, objects are stored in the directory and shared_endpoint is the
+// URL of zeta-serve httpd which serves the signed download URLs, access_key_secret is the HMAC key of the URLs.
+//
+//	[oss]
+//	local = "/var/lib/zeta/oss"
+//	shared_endpoint = "https://zeta.example.io"
+//	access_key_secret = "..."
+//	upload_expiration = "168h"
 type OSS struct {
-	Endpoint        string `toml:"endpoint,omitempty"`
-	SharedEndpoint  string `toml:"shared_endpoint,omitempty"`
-	Bucket          string `toml:"bucket"`
-	AccessKeyID     string `toml:"access_key_id"`
-	AccessKeySecret string `toml:"access_key_secret"`
-	Product         string `toml:"product,omitempty"`
-	Region          string `toml:"region,omitempty"`
+	Local            string   `toml:"local,omitempty"`
+	Endpoint         string   `toml:"endpoint,omitempty"`
+	SharedEndpoint   string   `toml:"shared_endpoint,omitempty"`
+	Bucket           string   `toml:"bucket"`
+	AccessKeyID      string   `toml:"access_key_id"`
+	AccessKeySecret  string   `toml:"access_key_secret"`
+	Product          string   `toml:"product,omitempty"`
+	Region           string   `toml:"region,omitempty"`
+	UploadExpiration Duration `toml:"upload_expiration,omitempty"` // unfinished resumable uploads created earlier are aborted, default 7 days
 }
 
 func (o *OSS) Decrypt(decryptedKey string) {
@@ -94,6 +176,92 @@ func (o *OSS) Decrypt(decryptedKey string) {
 	}
 }
 
+// PushOptions: push options allowed by the server, built-in options like 'ci.skip' are always allowed.
+//
+//	[push_options]
+//	allowed = ["ci.variable", "deploy.*"]
+type PushOptions struct {
+	Allowed  []string `toml:"allowed,omitempty"`   // patterns of option keys, see path.Match
+	MaxCount int      `toml:"max_count,omitempty"` // max count of push options, default 32
+}
+
+// Hook: server-side hook, an executable or an HTTP callout, the payload is sent as JSON on stdin or as POST body.
+type Hook struct {
+	Command string   `toml:"command,omitempty"` // path of the executable
+	Args    []string `toml:"args,omitempty"`
+	URL     string   `toml:"url,omitempty"`   // HTTP callout, used when command is empty
+	Token   string   `toml:"token,omitempty"` // bearer token of the HTTP callout
+	Env     []string `toml:"env,omitempty"`   // extra environment of the executable, 'KEY=VALUE'
+	Timeout Duration `toml:"timeout,omitempty"`
+}
+
+// Hooks: hooks run on reference updates, pre-receive and update hooks can reject the push.
+//
+//	[hooks]
+//	timeout = "30s"
+//	[hooks.pre_receive]
+//	command = "/etc/zeta/hooks/pre-receive"
+//	[hooks.post_receive]
+//	url = "https://ci.example.io/zeta/post-receive"
+type Hooks struct {
+	Timeout     Duration `toml:"timeout,omitempty"` // default timeout of hooks, default 30s
+	PreReceive  *Hook    `toml:"pre_receive,omitempty"`
+	Update      *Hook    `toml:"update,omitempty"`
+	PostReceive *Hook    `toml:"post_receive,omitempty"`
+}
+
+func (h *Hooks) Decrypt(decryptedKey string) {
+	if h == nil || len(decryptedKey) == 0 {
+		return
+	}
+	for _, hk := range []*Hook{h.PreReceive, h.Update, h.PostReceive} {
+		if hk == nil || len(hk.Token) == 0 {
+			continue
+		}
+		if token, err := Decrypt(hk.Token, decryptedKey); err == nil {
+			hk.Token = token
+		}
+	}
+}
+
+// Webhooks: delivery of webhooks registered through the management API, deliveries are queued in the database and
+// sent by every server which is not disabled.
+//
+//	[webhooks]
+//	timeout = "10s"
+//	max_attempts = 8
+type Webhooks struct {
+	Disabled    bool     `toml:"disabled,omitempty"`     // don't send deliveries from this server, they are still queued
+	Timeout     Duration `toml:"timeout,omitempty"`      // timeout of each attempt, default 10s
+	Interval    Duration `toml:"interval,omitempty"`     // interval of polling the queue, default 5s
+	MaxAttempts int      `toml:"max_attempts,omitempty"` // attempts before the delivery fails, default 8
+	Workers     int      `toml:"workers,omitempty"`      // concurrent deliveries, default 4
+}
+
+// RateLimit: token bucket of requests and max concurrent heavy operations (metadata fetch, batch objects, push),
+// zero disables the limit.
+type RateLimit struct {
+	Rate        float64 `toml:"rate,omitempty"`        // requests per second
+	Burst       int     `toml:"burst,omitempty"`       // default max(rate, 1)
+	Concurrency int     `toml:"concurrency,omitempty"` // max concurrent heavy operations
+}
+
+// RateLimits: limits keyed by user, deploy key, IP and repository, each server process counts on its own.
+//
+//	[rate_limits.user]
+//	rate = 10
+//	burst = 50
+//	concurrency = 8
+//	[rate_limits.repository]
+//	concurrency = 64
+type RateLimits struct {
+	User       *RateLimit `toml:"user,omitempty"`
+	DeployKey  *RateLimit `toml:"deploy_key,omitempty"`
+	IP         *RateLimit `toml:"ip,omitempty"`
+	Repository *RateLimit `toml:"repository,omitempty"`
+	RetryAfter Duration   `toml:"retry_after,omitempty"` // Retry-After when the concurrency is exceeded, default 5s
+}
+
 type Cache struct {
 	NumCounters int64 `toml:"num_counters"`
 	MaxCost     int64 `toml:"max_cost"`
@@ -11,6 +11,8 @@ const (
 	DevAccess      AccessLevel = 30
 	MasterAccess   AccessLevel = 40
 	OwnerAccess    AccessLevel = 50
+	// NoOneAccess: required level of operations that no one, including administrators, is allowed
+	NoOneAccess AccessLevel = 60
 )
 
 func (accessLevel AccessLevel) Writeable() bool {
@@ -0,0 +1,137 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+// ErrReferenceUpdate: the reference update that failed in DoReferenceUpdates, all updates are rolled back.
+type ErrReferenceUpdate struct {
+	Reference plumbing.ReferenceName
+	Err       error
+}
+
+func (e *ErrReferenceUpdate) Error() string {
+	return fmt.Sprintf("update '%s': %v", e.Reference, e.Err)
+}
+
+func (e *ErrReferenceUpdate) Unwrap() error {
+	return e.Err
+}
+
+func IsErrReferenceUpdate(err error) bool {
+	_, ok := err.(*ErrReferenceUpdate)
+	return ok
+}
+
+// checkAffected: the compare-and-swap of the reference failed when no rows are affected.
+func checkAffected(result sql.Result, refname plumbing.ReferenceName) error {
+	a, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if a == 0 {
+		return &ErrAlreadyLocked{Reference: string(refname)}
+	}
+	return nil
+}
+
+func txBranchUpdate(ctx context.Context, tx *Tx, cmd *Command, now time.Time) (*Reference, error) {
+	branchName := cmd.ReferenceName.BranchName()
+	ref := &Reference{Name: cmd.ReferenceName, RID: cmd.RID, Hash: cmd.NewRev, CreatedAt: now, UpdatedAt: now}
+	switch {
+	case cmd.OldRev == plumbing.ZERO_OID:
+		id, err := tx.InsertContext(ctx, "insert into branches(name, rid, hash, protection_level, created_at, updated_at) values(?,?,?,?,?,?)", branchName, cmd.RID, cmd.NewRev, 0, now, now)
+		if IsDupEntry(err) {
+			return nil, &ErrAlreadyLocked{Reference: string(cmd.ReferenceName)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		ref.ID = id
+		return ref, nil
+	case cmd.NewRev == plumbing.ZERO_OID:
+		result, err := tx.ExecContext(ctx, "delete from branches where rid = ? and name = ? and hash = ?", cmd.RID, branchName, cmd.OldRev)
+		if err != nil {
+			return nil, err
+		}
+		ref.Hash = cmd.OldRev
+		return ref, checkAffected(result, cmd.ReferenceName)
+	}
+	result, err := tx.ExecContext(ctx, "update branches set hash = ?, updated_at = ? where rid = ? and name = ? and hash = ?", cmd.NewRev, now, cmd.RID, branchName, cmd.OldRev)
+	if err != nil {
+		return nil, err
+	}
+	return ref, checkAffected(result, cmd.ReferenceName)
+}
+
+func txTagUpdate(ctx context.Context, tx *Tx, cmd *Command, now time.Time) (*Reference, error) {
+	tagName := cmd.ReferenceName.TagName()
+	ref := &Reference{Name: cmd.ReferenceName, RID: cmd.RID, Hash: cmd.NewRev, CreatedAt: now, UpdatedAt: now}
+	switch {
+	case cmd.OldRev == plumbing.ZERO_OID:
+		result, err := tx.ExecContext(ctx, "insert into tags(name, rid, uid, hash, subject, description, created_at, updated_at) values(?,?,?,?,?,?,?,?)",
+			tagName, cmd.RID, cmd.UID, cmd.NewRev, cmd.Subject, cmd.Description, now, now)
+		if IsDupEntry(err) {
+			return nil, &ErrAlreadyLocked{Reference: string(cmd.ReferenceName)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ref, checkAffected(result, cmd.ReferenceName)
+	case cmd.NewRev == plumbing.ZERO_OID:
+		result, err := tx.ExecContext(ctx, "delete from tags where rid = ? and name = ? and hash = ?", cmd.RID, tagName, cmd.OldRev)
+		if err != nil {
+			return nil, err
+		}
+		ref.Hash = cmd.OldRev
+		return ref, checkAffected(result, cmd.ReferenceName)
+	}
+	result, err := tx.ExecContext(ctx, "update tags set hash = ?, subject = ?, description = ?, updated_at = ? where rid = ? and name = ? and hash = ?",
+		cmd.NewRev, cmd.Subject, cmd.Description, now, cmd.RID, tagName, cmd.OldRev)
+	if err != nil {
+		return nil, err
+	}
+	return ref, checkAffected(result, cmd.ReferenceName)
+}
+
+// DoReferenceUpdates: update branches and tags in one transaction, each update compares the old rev,
+// either all references are updated or none of them. Updates are recorded in the audit log in the same transaction.
+func (d *database) DoReferenceUpdates(ctx context.Context, cmds []*Command) ([]*Reference, error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new tx error: %v", err)
+	}
+	now := time.Now()
+	refs := make([]*Reference, 0, len(cmds))
+	for _, cmd := range cmds {
+		var ref *Reference
+		switch {
+		case cmd.ReferenceName.IsBranch():
+			ref, err = txBranchUpdate(ctx, tx, cmd, now)
+		case cmd.ReferenceName.IsTag():
+			ref, err = txTagUpdate(ctx, tx, cmd, now)
+		default:
+			err = ErrReferenceNotAllowed
+		}
+		if err == nil {
+			err = insertAuditEvent(ctx, tx, referenceAuditEvent(cmd, now))
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, &ErrReferenceUpdate{Reference: cmd.ReferenceName, Err: err}
+		}
+		refs = append(refs, ref)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,200 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+const (
+	AuditReferenceCreate = "reference.create"
+	AuditReferenceUpdate = "reference.update"
+	AuditReferenceDelete = "reference.delete"
+)
+
+// AuditEvent: an entry of the append-only audit log. Reference updates are recorded in the transaction of the update,
+// administrative actions are recorded after they succeed.
+type AuditEvent struct {
+	ID            int64                  `json:"id"`
+	RID           int64                  `json:"rid"` // 0 for actions not related to a repository
+	UID           int64                  `json:"uid"`
+	KeyID         int64                  `json:"key_id,omitempty"`
+	Action        string                 `json:"action"`           // reference.create, reference.update, reference.delete or the name of the administrative action
+	Target        string                 `json:"target,omitempty"` // path of the management API request or arguments of the command
+	ReferenceName plumbing.ReferenceName `json:"reference_name,omitempty"`
+	OldRev        string                 `json:"old_rev,omitempty"`
+	NewRev        string                 `json:"new_rev,omitempty"`
+	Force         bool                   `json:"force,omitempty"`
+	Protocol      string                 `json:"protocol,omitempty"` // http, ssh, api or cli
+	ClientIP      string                 `json:"client_ip,omitempty"`
+	PushOptions   []string               `json:"push_options,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// AuditFilter: filters of the audit log, zero values match all events.
+type AuditFilter struct {
+	RID           int64
+	UID           int64
+	ReferenceName plumbing.ReferenceName
+	Action        string    // action or the prefix of actions ending with '.', eg: reference.
+	Since         time.Time // events created at or after since
+	Until         time.Time // events created before until
+}
+
+// referenceAuditEvent: the audit event of the reference update
+func referenceAuditEvent(cmd *Command, now time.Time) *AuditEvent {
+	action := AuditReferenceUpdate
+	switch {
+	case cmd.OldRev == plumbing.ZERO_OID:
+		action = AuditReferenceCreate
+	case cmd.NewRev == plumbing.ZERO_OID:
+		action = AuditReferenceDelete
+	}
+	return &AuditEvent{
+		RID:           cmd.RID,
+		UID:           cmd.UID,
+		KeyID:         cmd.KeyID,
+		Action:        action,
+		ReferenceName: cmd.ReferenceName,
+		OldRev:        cmd.OldRev,
+		NewRev:        cmd.NewRev,
+		Force:         cmd.Force,
+		Protocol:      cmd.Protocol,
+		ClientIP:      cmd.ClientIP,
+		PushOptions:   cmd.PushOptions,
+		CreatedAt:     now,
+	}
+}
+
+type inserter interface {
+	InsertContext(ctx context.Context, query string, args ...any) (int64, error)
+}
+
+func insertAuditEvent(ctx context.Context, c inserter, e *AuditEvent) error {
+	pushOptions := "[]"
+	if len(e.PushOptions) != 0 {
+		b, err := json.Marshal(e.PushOptions)
+		if err != nil {
+			return err
+		}
+		pushOptions = string(b)
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	id, err := c.InsertContext(ctx, "insert into audit_events(rid, uid, key_id, action, target, reference_name, old_rev, new_rev, forced, protocol, client_ip, push_options, created_at) values(?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		e.RID, e.UID, e.KeyID, e.Action, e.Target, string(e.ReferenceName), e.OldRev, e.NewRev, e.Force, e.Protocol, e.ClientIP, pushOptions, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+// NewAuditEvent: append the event to the audit log
+func (d *database) NewAuditEvent(ctx context.Context, e *AuditEvent) error {
+	return insertAuditEvent(ctx, d.Conn, e)
+}
+
+// ReferenceVersion: the last audit event of the repository. Reference updates are recorded in the audit log in the same
+// transaction, so the version is changed by every update.
+func (d *database) ReferenceVersion(ctx context.Context, rid int64) (int64, error) {
+	var version int64
+	if err := d.QueryRowContext(ctx, "select coalesce(max(id), 0) from audit_events where rid = ?", rid).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+const (
+	auditColumns = "id, rid, uid, key_id, action, target, reference_name, old_rev, new_rev, forced, protocol, client_ip, push_options, created_at"
+)
+
+func scanAuditEvent(scan func(dest ...any) error) (*AuditEvent, error) {
+	var e AuditEvent
+	var refname, pushOptions string
+	if err := scan(&e.ID, &e.RID, &e.UID, &e.KeyID, &e.Action, &e.Target, &refname, &e.OldRev, &e.NewRev, &e.Force, &e.Protocol, &e.ClientIP, &pushOptions, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.ReferenceName = plumbing.ReferenceName(refname)
+	e.OldRev = strings.TrimSpace(e.OldRev)
+	e.NewRev = strings.TrimSpace(e.NewRev)
+	if err := json.Unmarshal([]byte(pushOptions), &e.PushOptions); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// FindAuditEvent: find the event by id, returns sql.ErrNoRows if it does not exist
+func (d *database) FindAuditEvent(ctx context.Context, id int64) (*AuditEvent, error) {
+	return scanAuditEvent(d.QueryRowContext(ctx, "select "+auditColumns+" from audit_events where id = ?", id).Scan)
+}
+
+// ListAuditEvents: list events matching the filter, newest first. cursor is the next of the previous page, 0 for the
+// first page.
+func (d *database) ListAuditEvents(ctx context.Context, f *AuditFilter, cursor int64, limit int) ([]*AuditEvent, int64, error) {
+	limit = listLimit(limit)
+	conditions := []string{"id < ?"}
+	if cursor <= 0 {
+		cursor = 1<<63 - 1
+	}
+	args := []any{cursor}
+	if f.RID != 0 {
+		conditions = append(conditions, "rid = ?")
+		args = append(args, f.RID)
+	}
+	if f.UID != 0 {
+		conditions = append(conditions, "uid = ?")
+		args = append(args, f.UID)
+	}
+	if len(f.ReferenceName) != 0 {
+		conditions = append(conditions, "reference_name = ?")
+		args = append(args, string(f.ReferenceName))
+	}
+	switch {
+	case strings.HasSuffix(f.Action, "."):
+		conditions = append(conditions, "action like ? escape '!'")
+		args = append(args, likeEscaper.Replace(f.Action)+"%")
+	case len(f.Action) != 0:
+		conditions = append(conditions, "action = ?")
+		args = append(args, f.Action)
+	}
+	if !f.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.Since)
+	}
+	if !f.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.Until)
+	}
+	// one more to know whether there is a next page
+	args = append(args, limit+1)
+	rows, err := d.QueryContext(ctx, "select "+auditColumns+" from audit_events where "+strings.Join(conditions, " and ")+" order by id desc limit ?", args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	events := make([]*AuditEvent, 0, limit)
+	for rows.Next() {
+		e, err := scanAuditEvent(rows.Scan)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(events) > limit {
+		events = events[:limit]
+		next = events[limit-1].ID
+	}
+	return events, next, nil
+}
@@ -0,0 +1,183 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	MySQL      = "mysql" // MySQL or OceanBase
+	SQLite     = "sqlite"
+	PostgreSQL = "postgres"
+)
+
+// Conn: *sql.DB of the driver, queries are written with '?' placeholders and MySQL compatible syntax, Conn rebinds
+// them for the driver.
+type Conn struct {
+	*sql.DB
+	Driver string
+}
+
+// Tx: transaction started by Conn.BeginTx
+type Tx struct {
+	*sql.Tx
+	driver string
+}
+
+// rebind: replace '?' placeholders with '$1', '$2'... for PostgreSQL, quoted strings are kept.
+func rebind(driver string, query string) string {
+	if driver != PostgreSQL || strings.IndexByte(query, '?') == -1 {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	var quote byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// bindArgs: SQLite stores time as text, times are written in UTC so that they can be compared as strings.
+func bindArgs(driver string, args []any) []any {
+	if driver != SQLite {
+		return args
+	}
+	bound := make([]any, len(args))
+	for i, a := range args {
+		switch v := a.(type) {
+		case time.Time:
+			bound[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				bound[i] = v.UTC()
+			}
+		case sql.NullTime:
+			if v.Valid {
+				bound[i] = sql.NullTime{Time: v.Time.UTC(), Valid: true}
+			} else {
+				bound[i] = v
+			}
+		default:
+			bound[i] = a
+		}
+	}
+	return bound
+}
+
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.DB.ExecContext(ctx, rebind(c.Driver, query), bindArgs(c.Driver, args)...)
+}
+
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.DB.QueryContext(ctx, rebind(c.Driver, query), bindArgs(c.Driver, args)...)
+}
+
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.DB.QueryRowContext(ctx, rebind(c.Driver, query), bindArgs(c.Driver, args)...)
+}
+
+// InsertContext: execute the insert statement and return the ID of the new row.
+func (c *Conn) InsertContext(ctx context.Context, query string, args ...any) (int64, error) {
+	if c.Driver == PostgreSQL {
+		var id int64
+		err := c.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := c.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := c.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, driver: c.Driver}, nil
+}
+
+// IgnoreDuplicate: clause of the insert statement which skips rows violating unique keys, column is any column of
+// the table.
+func (c *Conn) IgnoreDuplicate(column string) string {
+	if c.Driver == MySQL {
+		return " ON DUPLICATE KEY UPDATE " + column + " = " + column
+	}
+	return " ON CONFLICT DO NOTHING"
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, rebind(tx.driver, query), bindArgs(tx.driver, args)...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, rebind(tx.driver, query), bindArgs(tx.driver, args)...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, rebind(tx.driver, query), bindArgs(tx.driver, args)...)
+}
+
+// InsertContext: execute the insert statement and return the ID of the new row.
+func (tx *Tx) InsertContext(ctx context.Context, query string, args ...any) (int64, error) {
+	if tx.driver == PostgreSQL {
+		var id int64
+		err := tx.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// OpenConn: open the database of the driver, dsn is the data source name of the driver.
+func OpenConn(driver string, dsn string) (*Conn, error) {
+	switch driver {
+	case MySQL, SQLite, PostgreSQL:
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%s'", driver)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == SQLite {
+		// a single writer, transactions take the write lock when they begin
+		db.SetMaxOpenConns(8)
+	} else {
+		db.SetMaxIdleConns(25)
+		db.SetMaxOpenConns(50)
+	}
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &Conn{DB: db, Driver: driver}, nil
+}
@@ -5,44 +5,83 @@ package database
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"time"
-
-	"github.com/go-sql-driver/mysql"
 )
 
 type DB interface {
-	Database() *sql.DB
+	Database() *Conn
 	FindUser(ctx context.Context, uid int64) (*User, error)
 	SearchUser(ctx context.Context, emailOrName string) (*User, error)
 	SearchKey(ctx context.Context, fingerprint string) (*Key, error)
 	NewUser(ctx context.Context, u *User) (*User, error)
+	ListUsers(ctx context.Context, cursor int64, limit int) ([]*User, int64, error)
+	UpdateUser(ctx context.Context, u *User) error
+	DeleteUser(ctx context.Context, uid int64) error
 	AddMember(ctx context.Context, m *Member) error
+	ListMembers(ctx context.Context, sourceType MemberType, sourceID int64, cursor int64, limit int) ([]*Member, int64, error)
+	UpdateMember(ctx context.Context, m *Member) error
+	RemoveMember(ctx context.Context, sourceType MemberType, sourceID int64, uid int64) error
 	FindKey(ctx context.Context, id int64) (*Key, error)
 	AddKey(ctx context.Context, k *Key) (*Key, error)
+	ListKeys(ctx context.Context, uid int64, cursor int64, limit int) ([]*Key, int64, error)
+	DeleteKey(ctx context.Context, id int64) error
 	IsDeployKeyEnabled(ctx context.Context, rid int64, kid int64) (bool, error)
+	ListDeployKeys(ctx context.Context, rid int64) ([]*Key, error)
+	EnableDeployKey(ctx context.Context, rid int64, kid int64) error
+	DisableDeployKey(ctx context.Context, rid int64, kid int64) error
 	FindNamespaceByID(ctx context.Context, namespaceID int64) (*Namespace, error)
 	FindNamespaceByPath(ctx context.Context, namespacePath string) (*Namespace, error)
+	NewGroupNamespace(ctx context.Context, ns *Namespace) (*Namespace, error)
+	ListNamespaces(ctx context.Context, owner int64, cursor int64, limit int) ([]*Namespace, int64, error)
+	UpdateNamespace(ctx context.Context, ns *Namespace) error
+	DeleteNamespace(ctx context.Context, namespaceID int64) error
+	GroupAccessLevel(ctx context.Context, namespaceID int64, u *User) (AccessLevel, error)
 	FindRepositoryByID(ctx context.Context, rid int) (*Namespace, *Repository, error)
 	FindRepositoryByPath(ctx context.Context, namespacePath, repoPath string) (*Namespace, *Repository, error)
 	NewRepository(ctx context.Context, r *Repository) (*Repository, error)
+	ListRepositories(ctx context.Context, namespaceID int64, cursor int64, limit int) ([]*Repository, int64, error)
+	UpdateRepository(ctx context.Context, r *Repository) error
+	DeleteRepository(ctx context.Context, rid int64) error
 	RepoAccessLevel(ctx context.Context, r *Repository, u *User) (AccessLevel, AccessLevel, error)
 	FindBranchForPrefix(ctx context.Context, rid int64, prefix string) (*Branch, error)
 	FindTagForPrefix(ctx context.Context, rid int64, prefix string) (*Tag, error)
 	FindBranch(ctx context.Context, rid int64, branchName string) (*Branch, error)
 	FindTag(ctx context.Context, rid int64, tagName string) (*Tag, error)
-	DoBranchUpdate(ctx context.Context, cmd *Command) (*Branch, error)
 	DoReferenceUpdate(ctx context.Context, cmd *Command) (*Reference, error)
+	DoReferenceUpdates(ctx context.Context, cmds []*Command) ([]*Reference, error)
+	ListReferences(ctx context.Context, rid int64, prefix, cursor string, limit int) ([]*Reference, string, error)
+	NewWebhook(ctx context.Context, w *Webhook) (*Webhook, error)
+	FindWebhook(ctx context.Context, id int64) (*Webhook, error)
+	ListWebhooks(ctx context.Context, sourceType MemberType, sourceID int64) ([]*Webhook, error)
+	MatchWebhooks(ctx context.Context, rid int64) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	NewWebhookDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, w *WebhookDelivery) error
+	RetryWebhookDelivery(ctx context.Context, id int64) error
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, cursor int64, limit int) ([]*WebhookDelivery, int64, error)
+	NewProtectionRule(ctx context.Context, p *ProtectionRule) (*ProtectionRule, error)
+	ListProtectionRules(ctx context.Context, rid int64) ([]*ProtectionRule, error)
+	UpdateProtectionRule(ctx context.Context, p *ProtectionRule) error
+	DeleteProtectionRule(ctx context.Context, rid int64, id int64) error
+	NewPathACL(ctx context.Context, a *PathACL) (*PathACL, error)
+	ListPathACLs(ctx context.Context, rid int64) ([]*PathACL, error)
+	DeletePathACL(ctx context.Context, rid int64, id int64) error
+	UserGroups(ctx context.Context, uid int64) ([]int64, error)
+	NewAuditEvent(ctx context.Context, e *AuditEvent) error
+	FindAuditEvent(ctx context.Context, id int64) (*AuditEvent, error)
+	ListAuditEvents(ctx context.Context, f *AuditFilter, cursor int64, limit int) ([]*AuditEvent, int64, error)
+	ReferenceVersion(ctx context.Context, rid int64) (int64, error)
 	Close() error
 }
 
 type database struct {
-	*sql.DB
+	*Conn
 }
 
-func (d *database) Database() *sql.DB {
-	return d.DB
+func (d *database) Database() *Conn {
+	return d.Conn
 }
 
 func (d *database) Close() error {
@@ -53,15 +92,11 @@ var (
 	_ DB = &database{}
 )
 
-func NewDB(cfg *mysql.Config) (DB, error) {
-	connector, err := mysql.NewConnector(cfg)
+// NewDB: open the database, driver is one of mysql, sqlite and postgres.
+func NewDB(driver string, dsn string) (DB, error) {
+	conn, err := OpenConn(driver, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("new connector: %w", err)
+		return nil, fmt.Errorf("open database: %w", err)
 	}
-
-	db := sql.OpenDB(connector)
-	db.SetMaxIdleConns(25)
-	db.SetMaxOpenConns(50)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	return &database{DB: db}, nil
+	return &database{Conn: conn}, nil
 }
@@ -9,6 +9,9 @@ import (
 	"fmt"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 const (
@@ -16,6 +19,10 @@ const (
 	ER_DUP_ENTRY           = 1062
 )
 
+const (
+	pqUniqueViolation = "23505"
+)
+
 var (
 	ErrReferenceNotAllowed = errors.New("reference types not allowed")
 	ErrUserNotGiven        = errors.New("user not given")
@@ -55,8 +62,20 @@ func IsNotFound(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
 
+// IsDupEntry: the statement violates a unique key, the error of MySQL, SQLite and PostgreSQL are supported.
 func IsDupEntry(err error) bool {
-	return IsErrorCode(err, ER_DUP_ENTRY)
+	if IsErrorCode(err, ER_DUP_ENTRY) {
+		return true
+	}
+	var perr *pq.Error
+	if errors.As(err, &perr) {
+		return perr.Code == pqUniqueViolation
+	}
+	var serr *sqlite.Error
+	if errors.As(err, &serr) {
+		return serr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || serr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
 }
 
 type ErrAlreadyLocked struct {
@@ -106,3 +125,20 @@ func IsErrExist(err error) bool {
 	_, ok := err.(*ErrExist)
 	return ok
 }
+
+// ErrInvalidArgument: the input does not pass the validation
+type ErrInvalidArgument struct {
+	message string
+}
+
+func (e *ErrInvalidArgument) Error() string {
+	return e.message
+}
+
+func IsErrInvalidArgument(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(*ErrInvalidArgument)
+	return ok
+}
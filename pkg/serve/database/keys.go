@@ -51,3 +51,106 @@ func (d *database) IsDeployKeyEnabled(ctx context.Context, rid int64, kid int64)
 	}
 	return true, nil
 }
+
+const (
+	keyColumns = "id, uid, content, title, type, fingerprint, created_at, updated_at"
+)
+
+func scanKeys(rows *sql.Rows) ([]*Key, error) {
+	defer rows.Close()
+	keys := make([]*Key, 0, 8)
+	for rows.Next() {
+		var k Key
+		if err := rows.Scan(&k.ID, &k.UID, &k.Content, &k.Title, &k.Type, &k.Fingerprint, &k.CreatedAt, &k.UpdatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+// ListKeys: SSH and deploy keys added by the user ordered by id, cursor is the id of the last key of the previous
+// page, 0 for the first page, the returned cursor is 0 when there are no more keys.
+func (d *database) ListKeys(ctx context.Context, uid int64, cursor int64, limit int) ([]*Key, int64, error) {
+	limit = listLimit(limit)
+	rows, err := d.QueryContext(ctx, "select "+keyColumns+" from ssh_keys where uid = ? and id > ? order by id limit ?", uid, cursor, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	keys, err := scanKeys(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1].ID
+	}
+	return keys, next, nil
+}
+
+// DeleteKey: remove the key, deploy keys are also disabled for all repositories.
+func (d *database) DeleteKey(ctx context.Context, id int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	result, err := tx.ExecContext(ctx, "delete from ssh_keys where id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, "delete from deploy_keys_repositories where kid = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListDeployKeys: deploy keys enabled for the repository
+func (d *database) ListDeployKeys(ctx context.Context, rid int64) ([]*Key, error) {
+	rows, err := d.QueryContext(ctx, `select k.id, k.uid, k.content, k.title, k.type, k.fingerprint, k.created_at, k.updated_at
+from ssh_keys as k inner join deploy_keys_repositories as dk on dk.kid = k.id
+where dk.rid = ? order by k.id`, rid)
+	if err != nil {
+		return nil, err
+	}
+	return scanKeys(rows)
+}
+
+// EnableDeployKey: allow the deploy key to access the repository, enabling it again is not an error.
+func (d *database) EnableDeployKey(ctx context.Context, rid int64, kid int64) error {
+	now := time.Now()
+	_, err := d.ExecContext(ctx, "insert into deploy_keys_repositories(kid, rid, created_at, updated_at) values(?,?,?,?)"+d.IgnoreDuplicate("rid"),
+		kid, rid, now, now)
+	return err
+}
+
+// DisableDeployKey: revoke the access of the deploy key to the repository, the key is removed when no repository
+// uses it anymore.
+func (d *database) DisableDeployKey(ctx context.Context, rid int64, kid int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	result, err := tx.ExecContext(ctx, "delete from deploy_keys_repositories where rid = ? and kid = ?", rid, kid)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	var repos int
+	if err := tx.QueryRowContext(ctx, "select count(*) from deploy_keys_repositories where kid = ?", kid).Scan(&repos); err != nil {
+		return err
+	}
+	if repos == 0 {
+		if _, err := tx.ExecContext(ctx, "delete from ssh_keys where id = ? and type = ?", kid, DeployKey); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -70,13 +70,81 @@ const (
 	sqlNewMember = `INSERT    INTO members (rid, uid, access_level, source_type, expires_at, created_at, updated_at)
 VALUES    (?, ?, ?, ?, ?, ?, ?)
 ON        DUPLICATE KEY UPDATE expires_at = VALUES(expires_at)`
+	// SQLite and PostgreSQL
+	sqlNewMemberOnConflict = `INSERT    INTO members (rid, uid, access_level, source_type, expires_at, created_at, updated_at)
+VALUES    (?, ?, ?, ?, ?, ?, ?)
+ON        CONFLICT (source_type, rid, uid) DO UPDATE SET expires_at = excluded.expires_at`
 )
 
 func (d *database) AddMember(ctx context.Context, m *Member) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
 	now := time.Now()
-	_, err := d.ExecContext(ctx, sqlNewMember, &m.SourceID, &m.UID, &m.AccessLevel, &m.SourceType, &m.ExpiresAt, now, now)
+	query := sqlNewMember
+	if d.Driver != MySQL {
+		query = sqlNewMemberOnConflict
+	}
+	_, err := d.ExecContext(ctx, query, &m.SourceID, &m.UID, &m.AccessLevel, &m.SourceType, &m.ExpiresAt, now, now)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListMembers: members of the repository or group ordered by id, cursor is the id of the last member of the previous
+// page, 0 for the first page, the returned cursor is 0 when there are no more members.
+func (d *database) ListMembers(ctx context.Context, sourceType MemberType, sourceID int64, cursor int64, limit int) ([]*Member, int64, error) {
+	limit = listLimit(limit)
+	rows, err := d.QueryContext(ctx, `select m.id, m.uid, u.username, m.access_level, m.rid, m.source_type, m.expires_at, m.created_at, m.updated_at
+from members as m inner join users as u on m.uid = u.id
+where m.source_type = ? and m.rid = ? and m.id > ? order by m.id limit ?`, sourceType, sourceID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	members := make([]*Member, 0, limit)
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.UID, &m.UserName, &m.AccessLevel, &m.SourceID, &m.SourceType, &m.ExpiresAt, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(members) > limit {
+		members = members[:limit]
+		next = members[limit-1].ID
+	}
+	return members, next, nil
+}
+
+// UpdateMember: update access level and expiration of the member, sql.ErrNoRows when the user is not a member.
+func (d *database) UpdateMember(ctx context.Context, m *Member) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	result, err := d.ExecContext(ctx, "update members set access_level = ?, expires_at = ?, updated_at = ? where source_type = ? and rid = ? and uid = ?",
+		m.AccessLevel, m.ExpiresAt, time.Now(), m.SourceType, m.SourceID, m.UID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (d *database) RemoveMember(ctx context.Context, sourceType MemberType, sourceID int64, uid int64) error {
+	result, err := d.ExecContext(ctx, "delete from members where source_type = ? and rid = ? and uid = ?", sourceType, sourceID, uid)
 	if err != nil {
 		return err
 	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
@@ -0,0 +1,157 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations: schema migrations of each driver, migrations/<driver>/<version>_<name>.sql. Applied migrations are
+// recorded in the schema_migrations table.
+//
+//go:embed migrations
+var migrations embed.FS
+
+const (
+	sqlNewSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	file    string
+}
+
+// Migrations: schema migrations of the driver ordered by version
+func Migrations(driver string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrations, path.Join("migrations", driver))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported database driver '%s'", driver)
+	}
+	ms := make([]*Migration, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".sql")
+		if e.IsDir() || !ok {
+			continue
+		}
+		versionText, name, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad migration '%s': %w", e.Name(), err)
+		}
+		ms = append(ms, &Migration{Version: version, Name: name, file: path.Join("migrations", driver, e.Name())})
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Version < ms[j].Version
+	})
+	return ms, nil
+}
+
+// splitStatements: split the script into statements, statements end with ';' at the end of line.
+func splitStatements(script string) []string {
+	statements := make([]string, 0, 32)
+	var b strings.Builder
+	appendStatement := func() {
+		statement := strings.TrimSpace(b.String())
+		b.Reset()
+		for _, line := range strings.Split(statement, "\n") {
+			if line = strings.TrimSpace(line); len(line) != 0 && !strings.HasPrefix(line, "--") {
+				statements = append(statements, statement)
+				return
+			}
+		}
+	}
+	for _, line := range strings.SplitAfter(script, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasSuffix(trimmed, ";") {
+			b.WriteString(strings.TrimSuffix(trimmed, ";"))
+			appendStatement()
+			continue
+		}
+		b.WriteString(line)
+	}
+	appendStatement()
+	return statements
+}
+
+// PendingMigrations: migrations not yet applied to the database
+func PendingMigrations(ctx context.Context, c *Conn) ([]*Migration, error) {
+	ms, err := Migrations(c.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.ExecContext(ctx, sqlNewSchemaMigrations); err != nil {
+		return nil, fmt.Errorf("create schema_migrations error: %w", err)
+	}
+	rows, err := c.QueryContext(ctx, "select version from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	pending := make([]*Migration, 0, len(ms))
+	for _, m := range ms {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func (c *Conn) applyMigration(ctx context.Context, m *Migration) error {
+	script, err := migrations.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
+	// DDL of MySQL is committed implicitly, statements are idempotent so that a failed migration can be run again.
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	for _, statement := range splitStatements(string(script)) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "insert into schema_migrations(version, name, applied_at) values(?,?,?)", m.Version, m.Name, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Migrate: create or upgrade the schema of the database, returns the applied migrations.
+func Migrate(ctx context.Context, c *Conn) ([]*Migration, error) {
+	pending, err := PendingMigrations(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range pending {
+		if err := c.applyMigration(ctx, m); err != nil {
+			return pending[:i], fmt.Errorf("apply migration %04d_%s error: %w", m.Version, m.Name, err)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,22 @@
+-- Append-only audit log of reference updates and administrative actions.
+CREATE TABLE IF NOT EXISTS
+    `audit_events` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '存储库 ID，与存储库无关的操作为 0',
+        `uid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '操作用户 ID',
+        `key_id` bigint (20) unsigned NOT NULL DEFAULT '0' comment 'SSH 密钥或部署密钥 ID',
+        `action` varchar(64) NOT NULL comment '操作，例如 reference.update、user.create',
+        `target` varchar(1024) NOT NULL DEFAULT '' comment '操作对象，管理 API 为请求路径',
+        `reference_name` varchar(4096) NOT NULL DEFAULT '' comment '引用名',
+        `old_rev` char(64) NOT NULL DEFAULT '' comment '更新前的提交',
+        `new_rev` char(64) NOT NULL DEFAULT '' comment '更新后的提交',
+        `forced` tinyint (4) NOT NULL DEFAULT '0' comment '强制推送',
+        `protocol` varchar(16) NOT NULL DEFAULT '' comment '协议，http、ssh、api 或 cli',
+        `client_ip` varchar(64) NOT NULL DEFAULT '' comment '客户端 IP',
+        `push_options` text NOT NULL comment '推送选项，JSON 数组',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_audit_events_rid_reference_name` (`rid`, `reference_name`(512)),
+        KEY `idx_audit_events_uid` (`uid`),
+        KEY `idx_audit_events_created_at` (`created_at`)
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '审计日志，只追加';
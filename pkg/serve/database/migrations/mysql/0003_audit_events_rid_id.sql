@@ -0,0 +1,2 @@
+-- The reference version of the repository is the last audit event of it, see ReferenceVersion.
+CREATE INDEX `idx_audit_events_rid_id` ON `audit_events` (`rid`, `id`);
@@ -0,0 +1,2 @@
+-- require_signed_commits only checks the signature header, the signature is not verified.
+ALTER TABLE `protected_references` CHANGE COLUMN `require_signed_commits` `require_signature_header` tinyint (4) NOT NULL DEFAULT '0' comment '要求提交带有签名头，不验证签名';
@@ -0,0 +1,13 @@
+-- Unfinished multipart uploads of large objects, sessions not finished in time are aborted by the sweeper.
+CREATE TABLE IF NOT EXISTS
+    `upload_sessions` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `oid` char(64) NOT NULL comment '对象 ID',
+        `upload_id` varchar(255) NOT NULL comment '分片上传 ID',
+        `size` bigint (20) NOT NULL DEFAULT '0' comment '对象大小',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_upload_sessions_rid_upload_id` (`rid`, `upload_id`),
+        KEY `idx_upload_sessions_created_at` (`created_at`)
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '未完成的分片上传';
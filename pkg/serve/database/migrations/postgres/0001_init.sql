@@ -0,0 +1,211 @@
+-- Initial schema of PostgreSQL, see zeta.sql for the description of columns.
+CREATE TABLE IF NOT EXISTS branches (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(4096) NOT NULL DEFAULT '',
+    rid BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    protection_level INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_branches_rid_name ON branches (rid, name);
+
+CREATE TABLE IF NOT EXISTS objects (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    bindata TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_objects_rid_hash ON objects (rid, hash);
+
+CREATE TABLE IF NOT EXISTS commits (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    author VARCHAR(512) NOT NULL DEFAULT '',
+    committer VARCHAR(512) NOT NULL DEFAULT '',
+    bindata TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_commits_rid_hash ON commits (rid, hash);
+CREATE INDEX IF NOT EXISTS idx_commits_author ON commits (author);
+CREATE INDEX IF NOT EXISTS idx_commits_committer ON commits (committer);
+
+CREATE TABLE IF NOT EXISTS trees (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    bindata TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_trees_rid_hash ON trees (rid, hash);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    uid BIGINT NOT NULL DEFAULT 0,
+    name VARCHAR(4096) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    subject VARCHAR(1024) NOT NULL DEFAULT '',
+    description TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_tags_rid_name ON tags (rid, name);
+
+CREATE TABLE IF NOT EXISTS namespaces (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(256) NOT NULL,
+    path VARCHAR(256) NOT NULL,
+    description VARCHAR(512) NOT NULL,
+    type SMALLINT NOT NULL DEFAULT 0,
+    owner_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_namespaces_path ON namespaces (path);
+CREATE INDEX IF NOT EXISTS idx_namespaces_type_owner_id ON namespaces (type, owner_id);
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    admin BOOLEAN NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    type SMALLINT NOT NULL DEFAULT 0,
+    password VARCHAR(512) NOT NULL DEFAULT '',
+    signature_token VARCHAR(255) NOT NULL DEFAULT '',
+    locked_at TIMESTAMPTZ NULL DEFAULT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_on_name ON users (name);
+
+CREATE TABLE IF NOT EXISTS repositories (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(256) NOT NULL,
+    path VARCHAR(256) NOT NULL,
+    namespace_id BIGINT NOT NULL,
+    description TEXT NOT NULL,
+    default_branch VARCHAR(4096) NOT NULL,
+    hash_algo VARCHAR(64) NOT NULL DEFAULT 'BLAKE3',
+    compression_algo VARCHAR(64) NOT NULL DEFAULT 'zstd',
+    visible_level INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_repositories_namespace_path ON repositories (namespace_id, path);
+
+CREATE TABLE IF NOT EXISTS members (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    uid BIGINT NOT NULL,
+    access_level INTEGER NOT NULL,
+    source_type SMALLINT NOT NULL DEFAULT 2,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_members_source_type_rid_uid ON members (source_type, rid, uid);
+CREATE INDEX IF NOT EXISTS idx_members_rid ON members (rid);
+CREATE INDEX IF NOT EXISTS idx_members_uid ON members (uid);
+
+CREATE TABLE IF NOT EXISTS emails (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    uid BIGINT NOT NULL,
+    confirmation_token VARCHAR(64) NOT NULL,
+    confirmation_sent_at TIMESTAMPTZ NULL DEFAULT NULL,
+    confirmed_at TIMESTAMPTZ NULL DEFAULT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_emails_email ON emails (email);
+CREATE INDEX IF NOT EXISTS idx_emails_uid ON emails (uid);
+
+CREATE TABLE IF NOT EXISTS ssh_keys (
+    id BIGSERIAL PRIMARY KEY,
+    uid BIGINT NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    type SMALLINT NOT NULL DEFAULT 0,
+    fingerprint VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_fingerprint ON ssh_keys (fingerprint);
+CREATE INDEX IF NOT EXISTS idx_keys_uid ON ssh_keys (uid);
+
+CREATE TABLE IF NOT EXISTS deploy_keys_repositories (
+    id BIGSERIAL PRIMARY KEY,
+    kid BIGINT NOT NULL,
+    rid BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_deploy_keys_repositories_kid_and_rid ON deploy_keys_repositories (kid, rid);
+CREATE INDEX IF NOT EXISTS idx_deploy_keys_repositories_rid ON deploy_keys_repositories (rid);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    source_id BIGINT NOT NULL,
+    source_type SMALLINT NOT NULL DEFAULT 2,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(512) NOT NULL DEFAULT '',
+    events VARCHAR(1024) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_source_type_source_id ON webhooks (source_type, source_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL,
+    rid BIGINT NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error VARCHAR(1024) NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMPTZ NULL DEFAULT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+
+CREATE TABLE IF NOT EXISTS protected_references (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    pattern VARCHAR(4096) NOT NULL,
+    push_access_level INTEGER NOT NULL DEFAULT 30,
+    force_push_access_level INTEGER NOT NULL DEFAULT 60,
+    delete_access_level INTEGER NOT NULL DEFAULT 60,
+    require_linear_history BOOLEAN NOT NULL DEFAULT FALSE,
+    require_signed_commits BOOLEAN NOT NULL DEFAULT FALSE,
+    max_blob_size BIGINT NOT NULL DEFAULT 0,
+    forbidden_paths VARCHAR(4096) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_protected_references_rid ON protected_references (rid);
+
+CREATE TABLE IF NOT EXISTS path_acls (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    path VARCHAR(4096) NOT NULL,
+    namespace_id BIGINT NOT NULL,
+    access_level INTEGER NOT NULL DEFAULT 20,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_path_acls_rid ON path_acls (rid);
@@ -0,0 +1,20 @@
+-- Append-only audit log of reference updates and administrative actions, see zeta.sql for the description of columns.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL DEFAULT 0,
+    uid BIGINT NOT NULL DEFAULT 0,
+    key_id BIGINT NOT NULL DEFAULT 0,
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(1024) NOT NULL DEFAULT '',
+    reference_name VARCHAR(4096) NOT NULL DEFAULT '',
+    old_rev CHAR(64) NOT NULL DEFAULT '',
+    new_rev CHAR(64) NOT NULL DEFAULT '',
+    forced BOOLEAN NOT NULL DEFAULT FALSE,
+    protocol VARCHAR(16) NOT NULL DEFAULT '',
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    push_options TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_rid_reference_name ON audit_events (rid, reference_name);
+CREATE INDEX IF NOT EXISTS idx_audit_events_uid ON audit_events (uid);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
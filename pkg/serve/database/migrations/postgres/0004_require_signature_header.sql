@@ -0,0 +1,2 @@
+-- require_signed_commits only checks the signature header, the signature is not verified.
+ALTER TABLE protected_references RENAME COLUMN require_signed_commits TO require_signature_header;
@@ -0,0 +1,11 @@
+-- Unfinished multipart uploads of large objects, see zeta.sql for the description of columns.
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    rid BIGINT NOT NULL,
+    oid CHAR(64) NOT NULL,
+    upload_id VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_rid_upload_id ON upload_sessions (rid, upload_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_created_at ON upload_sessions (created_at);
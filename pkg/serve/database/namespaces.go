@@ -5,12 +5,18 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"time"
 )
 
+const (
+	namespaceColumns = "id, path, name, owner_id, type, description, created_at, updated_at"
+)
+
 func (d *database) FindNamespaceByID(ctx context.Context, namespaceID int64) (*Namespace, error) {
 	var n Namespace
-	if err := d.QueryRowContext(ctx, "select id, path, name, owner_id, type, description, created_at, updated_at from namespaces where id = ?", namespaceID).
+	if err := d.QueryRowContext(ctx, "select "+namespaceColumns+" from namespaces where id = ?", namespaceID).
 		Scan(&n.ID, &n.Path, &n.Name, &n.Owner, &n.Type, &n.Description, &n.CreatedAt, &n.UpdatedAt); err != nil {
 		return nil, err
 	}
@@ -19,17 +25,21 @@ func (d *database) FindNamespaceByID(ctx context.Context, namespaceID int64) (*N
 
 func (d *database) FindNamespaceByPath(ctx context.Context, namespacePath string) (*Namespace, error) {
 	var n Namespace
-	if err := d.QueryRowContext(ctx, "select id, path, name, owner_id, type, description, created_at, updated_at from namespaces where name = ?", namespacePath).
+	if err := d.QueryRowContext(ctx, "select "+namespaceColumns+" from namespaces where path = ?", namespacePath).
 		Scan(&n.ID, &n.Path, &n.Name, &n.Owner, &n.Type, &n.Description, &n.CreatedAt, &n.UpdatedAt); err != nil {
 		return nil, err
 	}
 	return &n, nil
 }
 
+// NewGroupNamespace: create a group, members of the group have access to all repositories of the group.
 func (d *database) NewGroupNamespace(ctx context.Context, ns *Namespace) (*Namespace, error) {
+	if err := ns.Validate(); err != nil {
+		return nil, err
+	}
 	now := time.Now()
 	_, err := d.ExecContext(ctx, "insert into namespaces(path, name, owner_id, type, description, created_at, updated_at) values(?,?,?,?,?,?,?)",
-		ns.Path, ns.Name, ns.Owner, 1, ns.Description, now, now)
+		ns.Path, ns.Name, ns.Owner, GroupNamespace, ns.Description, now, now)
 	if IsDupEntry(err) {
 		return nil, &ErrExist{message: "namespace already exists"}
 	}
@@ -38,3 +48,85 @@ func (d *database) NewGroupNamespace(ctx context.Context, ns *Namespace) (*Names
 	}
 	return d.FindNamespaceByPath(ctx, ns.Path)
 }
+
+// ListNamespaces: namespaces ordered by id, all namespaces when owner is 0. cursor is the id of the last namespace of
+// the previous page, 0 for the first page, the returned cursor is 0 when there are no more namespaces.
+func (d *database) ListNamespaces(ctx context.Context, owner int64, cursor int64, limit int) ([]*Namespace, int64, error) {
+	limit = listLimit(limit)
+	query, args := "select "+namespaceColumns+" from namespaces where id > ? order by id limit ?", []any{cursor, limit + 1}
+	if owner != 0 {
+		query, args = "select "+namespaceColumns+" from namespaces where owner_id = ? and id > ? order by id limit ?", []any{owner, cursor, limit + 1}
+	}
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	namespaces := make([]*Namespace, 0, limit)
+	for rows.Next() {
+		var n Namespace
+		if err := rows.Scan(&n.ID, &n.Path, &n.Name, &n.Owner, &n.Type, &n.Description, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		namespaces = append(namespaces, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(namespaces) > limit {
+		namespaces = namespaces[:limit]
+		next = namespaces[limit-1].ID
+	}
+	return namespaces, next, nil
+}
+
+// UpdateNamespace: update name, description and owner of the namespace, the path cannot be changed.
+func (d *database) UpdateNamespace(ctx context.Context, ns *Namespace) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	result, err := d.ExecContext(ctx, "update namespaces set name = ?, description = ?, owner_id = ?, updated_at = ? where id = ?",
+		ns.Name, ns.Description, ns.Owner, time.Now(), ns.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteNamespace: remove the group with its members, webhooks and path ACLs granted to it. Groups which still have
+// repositories cannot be removed.
+func (d *database) DeleteNamespace(ctx context.Context, namespaceID int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	var repos int
+	if err := tx.QueryRowContext(ctx, "select count(*) from repositories where namespace_id = ?", namespaceID).Scan(&repos); err != nil {
+		return err
+	}
+	if repos != 0 {
+		return &ErrExist{message: fmt.Sprintf("namespace still has %d repositories", repos)}
+	}
+	result, err := tx.ExecContext(ctx, "delete from namespaces where id = ?", namespaceID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	for _, query := range []string{
+		"delete from members where source_type = 3 and rid = ?",
+		"delete from webhooks where source_type = 3 and source_id = ?",
+		"delete from path_acls where namespace_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, namespaceID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,99 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"strings"
+	"time"
+)
+
+// PathACL: members of the group NamespaceID are granted AccessLevel (ReporterAccess: read, DevAccess: read and write)
+// to the files under Path. Paths covered by ACL rules are restricted to the granted groups.
+type PathACL struct {
+	ID          int64       `json:"id"`
+	RID         int64       `json:"rid"`
+	Path        string      `json:"path"` // directory prefix, eg: secret/
+	NamespaceID int64       `json:"namespace_id"`
+	AccessLevel AccessLevel `json:"access_level"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// CleanACLPath: directory prefix of the ACL rule with a trailing slash, eg: 'a/./b' --> 'a/b/'. Returns false if the
+// path is the root of the repository.
+func CleanACLPath(p string) (string, bool) {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if len(p) == 0 {
+		return "", false
+	}
+	return p + "/", true
+}
+
+const (
+	pathACLColumns = "id, rid, path, namespace_id, access_level, created_at, updated_at"
+)
+
+func (d *database) NewPathACL(ctx context.Context, a *PathACL) (*PathACL, error) {
+	now := time.Now()
+	id, err := d.InsertContext(ctx, "insert into path_acls(rid, path, namespace_id, access_level, created_at, updated_at) values(?,?,?,?,?,?)",
+		a.RID, a.Path, a.NamespaceID, a.AccessLevel, now, now)
+	if err != nil {
+		return nil, err
+	}
+	newACL := *a
+	newACL.ID = id
+	newACL.CreatedAt = now
+	newACL.UpdatedAt = now
+	return &newACL, nil
+}
+
+// ListPathACLs: path ACL rules of the repository
+func (d *database) ListPathACLs(ctx context.Context, rid int64) ([]*PathACL, error) {
+	rows, err := d.QueryContext(ctx, "select "+pathACLColumns+" from path_acls where rid = ? order by id", rid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	acls := make([]*PathACL, 0, 4)
+	for rows.Next() {
+		var a PathACL
+		if err := rows.Scan(&a.ID, &a.RID, &a.Path, &a.NamespaceID, &a.AccessLevel, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		acls = append(acls, &a)
+	}
+	return acls, rows.Err()
+}
+
+func (d *database) DeletePathACL(ctx context.Context, rid int64, id int64) error {
+	result, err := d.ExecContext(ctx, "delete from path_acls where id = ? and rid = ?", id, rid)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UserGroups: ID of the groups the user is a member of
+func (d *database) UserGroups(ctx context.Context, uid int64) ([]int64, error) {
+	rows, err := d.QueryContext(ctx, "select rid from members where uid = ? and source_type = ? and access_level >= ?", uid, GroupMember, ReporterAccess)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := make([]int64, 0, 8)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		groups = append(groups, id)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,185 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+)
+
+// ProtectionRule: protection of references matched by Pattern, see path.Match. Access levels are the minimum
+// access level of the user, NoOneAccess forbids the operation.
+type ProtectionRule struct {
+	ID                     int64       `json:"id"`
+	RID                    int64       `json:"rid"`
+	Pattern                string      `json:"pattern"` // full reference name, eg: refs/heads/release/*
+	PushAccessLevel        AccessLevel `json:"push_access_level"`
+	ForcePushAccessLevel   AccessLevel `json:"force_push_access_level"`
+	DeleteAccessLevel      AccessLevel `json:"delete_access_level"`
+	RequireLinearHistory   bool        `json:"require_linear_history"`
+	RequireSignatureHeader bool        `json:"require_signature_header"`
+	MaxBlobSize            int64       `json:"max_blob_size"` // 0: unlimited
+	ForbiddenPaths         []string    `json:"forbidden_paths,omitempty"`
+	CreatedAt              time.Time   `json:"created_at"`
+	UpdatedAt              time.Time   `json:"updated_at"`
+}
+
+// Validate: the pattern is a full reference name pattern and access levels are known.
+func (p *ProtectionRule) Validate() error {
+	if !strings.HasPrefix(p.Pattern, plumbing.ReferencePrefix) {
+		return &ErrInvalidArgument{message: fmt.Sprintf("bad pattern '%s', full reference name required, eg: refs/heads/main", p.Pattern)}
+	}
+	if _, err := path.Match(p.Pattern, ""); err != nil {
+		return &ErrInvalidArgument{message: fmt.Sprintf("bad pattern '%s': %v", p.Pattern, err)}
+	}
+	for _, level := range []AccessLevel{p.PushAccessLevel, p.ForcePushAccessLevel, p.DeleteAccessLevel} {
+		switch level {
+		case NoneAccess, ReporterAccess, DevAccess, MasterAccess, OwnerAccess, NoOneAccess:
+		default:
+			return &ErrInvalidArgument{message: fmt.Sprintf("bad access level %d, supported: 0, 20, 30, 40, 50, 60", level)}
+		}
+	}
+	for _, pattern := range p.ForbiddenPaths {
+		if len(pattern) == 0 || strings.Contains(pattern, ",") {
+			return &ErrInvalidArgument{message: fmt.Sprintf("bad forbidden path '%s'", pattern)}
+		}
+	}
+	if p.MaxBlobSize < 0 {
+		return &ErrInvalidArgument{message: "max_blob_size must not be negative"}
+	}
+	return nil
+}
+
+func (p *ProtectionRule) Match(refname plumbing.ReferenceName) bool {
+	matched, err := path.Match(p.Pattern, string(refname))
+	return err == nil && matched
+}
+
+// MatchProtectionRules: merge rules matching the reference, the most restrictive setting of the rules wins.
+// Returns nil if the reference is not protected.
+func MatchProtectionRules(rules []*ProtectionRule, refname plumbing.ReferenceName) *ProtectionRule {
+	var merged *ProtectionRule
+	for _, p := range rules {
+		if !p.Match(refname) {
+			continue
+		}
+		if merged == nil {
+			merged = &ProtectionRule{Pattern: p.Pattern, RID: p.RID}
+		}
+		merged.PushAccessLevel = max(merged.PushAccessLevel, p.PushAccessLevel)
+		merged.ForcePushAccessLevel = max(merged.ForcePushAccessLevel, p.ForcePushAccessLevel)
+		merged.DeleteAccessLevel = max(merged.DeleteAccessLevel, p.DeleteAccessLevel)
+		merged.RequireLinearHistory = merged.RequireLinearHistory || p.RequireLinearHistory
+		merged.RequireSignatureHeader = merged.RequireSignatureHeader || p.RequireSignatureHeader
+		if p.MaxBlobSize > 0 && (merged.MaxBlobSize == 0 || p.MaxBlobSize < merged.MaxBlobSize) {
+			merged.MaxBlobSize = p.MaxBlobSize
+		}
+		for _, pattern := range p.ForbiddenPaths {
+			if !slices.Contains(merged.ForbiddenPaths, pattern) {
+				merged.ForbiddenPaths = append(merged.ForbiddenPaths, pattern)
+			}
+		}
+	}
+	return merged
+}
+
+const (
+	protectionColumns = `id, rid, pattern, push_access_level, force_push_access_level, delete_access_level, require_linear_history,
+          require_signature_header, max_blob_size, forbidden_paths, created_at, updated_at`
+
+	sqlNewProtectionRule = `INSERT    INTO protected_references (
+          rid,
+          pattern,
+          push_access_level,
+          force_push_access_level,
+          delete_access_level,
+          require_linear_history,
+          require_signature_header,
+          max_blob_size,
+          forbidden_paths,
+          created_at,
+          updated_at
+          )
+VALUES    (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+)
+
+func scanProtectionRules(rows *sql.Rows) ([]*ProtectionRule, error) {
+	defer rows.Close()
+	rules := make([]*ProtectionRule, 0, 4)
+	for rows.Next() {
+		var p ProtectionRule
+		var forbiddenPaths string
+		if err := rows.Scan(&p.ID, &p.RID, &p.Pattern, &p.PushAccessLevel, &p.ForcePushAccessLevel, &p.DeleteAccessLevel, &p.RequireLinearHistory,
+			&p.RequireSignatureHeader, &p.MaxBlobSize, &forbiddenPaths, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if len(forbiddenPaths) != 0 {
+			p.ForbiddenPaths = strings.Split(forbiddenPaths, ",")
+		}
+		rules = append(rules, &p)
+	}
+	return rules, rows.Err()
+}
+
+func (d *database) NewProtectionRule(ctx context.Context, p *ProtectionRule) (*ProtectionRule, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id, err := d.InsertContext(ctx, sqlNewProtectionRule, p.RID, p.Pattern, p.PushAccessLevel, p.ForcePushAccessLevel, p.DeleteAccessLevel,
+		p.RequireLinearHistory, p.RequireSignatureHeader, p.MaxBlobSize, strings.Join(p.ForbiddenPaths, ","), now, now)
+	if err != nil {
+		return nil, err
+	}
+	newRule := *p
+	newRule.ID = id
+	newRule.CreatedAt = now
+	newRule.UpdatedAt = now
+	return &newRule, nil
+}
+
+// ListProtectionRules: protection rules of the repository
+func (d *database) ListProtectionRules(ctx context.Context, rid int64) ([]*ProtectionRule, error) {
+	rows, err := d.QueryContext(ctx, "select "+protectionColumns+" from protected_references where rid = ? order by id", rid)
+	if err != nil {
+		return nil, err
+	}
+	return scanProtectionRules(rows)
+}
+
+// UpdateProtectionRule: update the rule, the repository of the rule cannot be changed.
+func (d *database) UpdateProtectionRule(ctx context.Context, p *ProtectionRule) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	result, err := d.ExecContext(ctx, `update protected_references set pattern = ?, push_access_level = ?, force_push_access_level = ?,
+delete_access_level = ?, require_linear_history = ?, require_signature_header = ?, max_blob_size = ?, forbidden_paths = ?, updated_at = ?
+where id = ? and rid = ?`, p.Pattern, p.PushAccessLevel, p.ForcePushAccessLevel, p.DeleteAccessLevel, p.RequireLinearHistory,
+		p.RequireSignatureHeader, p.MaxBlobSize, strings.Join(p.ForbiddenPaths, ","), time.Now(), p.ID, p.RID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (d *database) DeleteProtectionRule(ctx context.Context, rid int64, id int64) error {
+	result, err := d.ExecContext(ctx, "delete from protected_references where id = ? and rid = ?", id, rid)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
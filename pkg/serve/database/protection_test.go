@@ -0,0 +1,33 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchProtectionRules(t *testing.T) {
+	rules := []*ProtectionRule{
+		{Pattern: "refs/heads/release/*", PushAccessLevel: MasterAccess, DeleteAccessLevel: NoOneAccess, MaxBlobSize: 1024, ForbiddenPaths: []string{"*.exe"}},
+		{Pattern: "refs/heads/release/v*", PushAccessLevel: DevAccess, ForcePushAccessLevel: NoOneAccess, RequireLinearHistory: true, MaxBlobSize: 4096, ForbiddenPaths: []string{"*.exe", "vendor/"}},
+		{Pattern: "refs/heads/*", PushAccessLevel: DevAccess},
+	}
+	require.Nil(t, MatchProtectionRules(rules, plumbing.NewTagReferenceName("v1.0")))
+	require.Nil(t, MatchProtectionRules(rules, plumbing.NewBranchReferenceName("release/v1/fix")))
+
+	p := MatchProtectionRules(rules, plumbing.NewBranchReferenceName("main"))
+	require.NotNil(t, p)
+	require.Equal(t, DevAccess, p.PushAccessLevel)
+	require.Equal(t, NoneAccess, p.DeleteAccessLevel)
+	require.False(t, p.RequireLinearHistory)
+
+	p = MatchProtectionRules(rules, plumbing.NewBranchReferenceName("release/v1"))
+	require.NotNil(t, p)
+	require.Equal(t, MasterAccess, p.PushAccessLevel)
+	require.Equal(t, NoOneAccess, p.ForcePushAccessLevel)
+	require.Equal(t, NoOneAccess, p.DeleteAccessLevel)
+	require.True(t, p.RequireLinearHistory)
+	require.Equal(t, int64(1024), p.MaxBlobSize)
+	require.Equal(t, []string{"*.exe", "vendor/"}, p.ForbiddenPaths)
+}
@@ -5,6 +5,7 @@ package database
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -20,33 +21,118 @@ type Reference struct {
 	UpdatedAt       time.Time              `json:"updated_at"`
 }
 
+// DoReferenceUpdate: update the branch or tag if it is not changed since the old rev, the update is recorded in the
+// audit log. ErrAlreadyLocked is returned when the reference is changed by others.
 func (d *database) DoReferenceUpdate(ctx context.Context, cmd *Command) (*Reference, error) {
+	refs, err := d.DoReferenceUpdates(ctx, []*Command{cmd})
+	if e, ok := err.(*ErrReferenceUpdate); ok {
+		return nil, e.Err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return refs[0], nil
+}
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// listLimit: limit of the list, DefaultListLimit when not given and at most MaxListLimit
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultListLimit
+	}
+	return min(limit, MaxListLimit)
+}
+
+var (
+	// likeEscaper: '!' is the escape character of LIKE, backslash is not an escape character of SQLite and string
+	// literals of MySQL and PostgreSQL differ in backslashes.
+	likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+)
+
+// listRange: the name range of the table for references prefix and cursor, ok is false when the table is out of range.
+func listRange(refPrefix, prefix, cursor string) (namePrefix, after string, ok bool) {
 	switch {
-	case cmd.ReferenceName.IsBranch():
-		b, err := d.DoBranchUpdate(ctx, cmd)
-		if err != nil {
+	case strings.HasPrefix(prefix, refPrefix):
+		namePrefix = prefix[len(refPrefix):]
+	case !strings.HasPrefix(refPrefix, prefix):
+		return "", "", false
+	}
+	switch {
+	case strings.HasPrefix(cursor, refPrefix):
+		after = cursor[len(refPrefix):]
+	case cursor > refPrefix:
+		return "", "", false
+	}
+	return namePrefix, after, true
+}
+
+func (d *database) listNames(ctx context.Context, query string, rid int64, namePrefix, after string, limit int) ([]*Reference, error) {
+	rows, err := d.QueryContext(ctx, query, rid, likeEscaper.Replace(namePrefix)+"%", after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	refs := make([]*Reference, 0, limit)
+	for rows.Next() {
+		r := &Reference{RID: rid}
+		var name string
+		if err := rows.Scan(&r.ID, &name, &r.Hash, &r.ProtectionLevel, &r.CreatedAt, &r.UpdatedAt); err != nil {
 			return nil, err
 		}
-		return &Reference{
-			ID:              b.ID,
-			Name:            cmd.ReferenceName,
-			RID:             b.RID,
-			Hash:            b.Hash,
-			ProtectionLevel: b.ProtectionLevel,
-			CreatedAt:       b.CreatedAt,
-			UpdatedAt:       b.UpdatedAt}, nil
-	case cmd.ReferenceName.IsTag():
-		t, err := d.doTagUpdate(ctx, cmd)
+		r.Name = plumbing.ReferenceName(name)
+		r.CreatedAt = r.CreatedAt.Local()
+		r.UpdatedAt = r.UpdatedAt.Local()
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// ListReferences: list branches and tags ordered by name, prefix is a full reference prefix like 'refs/heads/dev',
+// cursor is the last reference name of the previous page. The returned cursor is empty on the last page.
+func (d *database) ListReferences(ctx context.Context, rid int64, prefix, cursor string, limit int) ([]*Reference, string, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	tables := []struct {
+		refPrefix string
+		query     string
+	}{
+		{
+			refPrefix: plumbing.ReferencePrefix + "heads/",
+			query:     "select id, name, hash, protection_level, created_at, updated_at from branches where rid = ? and name like ? escape '!' and name > ? order by name limit ?",
+		},
+		{
+			refPrefix: plumbing.ReferencePrefix + "tags/",
+			query:     "select id, name, hash, 0, created_at, updated_at from tags where rid = ? and name like ? escape '!' and name > ? order by name limit ?",
+		},
+	}
+	refs := make([]*Reference, 0, limit)
+	for _, t := range tables {
+		namePrefix, after, ok := listRange(t.refPrefix, prefix, cursor)
+		if !ok {
+			continue
+		}
+		// one more to know whether there is a next page
+		items, err := d.listNames(ctx, t.query, rid, namePrefix, after, limit+1-len(refs))
 		if err != nil {
-			return nil, err
+			return nil, "", err
+		}
+		for _, r := range items {
+			r.Name = plumbing.ReferenceName(t.refPrefix + string(r.Name))
 		}
-		return &Reference{
-			Name:      cmd.ReferenceName,
-			RID:       t.RID,
-			Hash:      t.Hash,
-			CreatedAt: t.CreatedAt,
-			UpdatedAt: t.UpdatedAt,
-		}, nil
-	}
-	return nil, ErrReferenceNotAllowed
+		refs = append(refs, items...)
+		if len(refs) > limit {
+			break
+		}
+	}
+	if len(refs) > limit {
+		refs = refs[:limit]
+		return refs, string(refs[limit-1].Name), nil
+	}
+	return refs, "", nil
 }
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRange(t *testing.T) {
+	for _, c := range []struct {
+		refPrefix, prefix, cursor string
+		namePrefix, after         string
+		ok                        bool
+	}{
+		{"refs/heads/", "", "", "", "", true},
+		{"refs/heads/", "refs/", "", "", "", true},
+		{"refs/heads/", "refs/heads/dev", "", "dev", "", true},
+		{"refs/tags/", "refs/heads/dev", "", "", "", false},
+		{"refs/heads/", "refs/heads/", "refs/heads/dev", "", "dev", true},
+		{"refs/tags/", "", "refs/heads/dev", "", "", true},
+		{"refs/heads/", "", "refs/tags/v1", "", "", false},
+		{"refs/tags/", "", "refs/tags/v1", "", "v1", true},
+	} {
+		namePrefix, after, ok := listRange(c.refPrefix, c.prefix, c.cursor)
+		require.Equal(t, c.ok, ok, "%v", c)
+		require.Equal(t, c.namePrefix, namePrefix, "%v", c)
+		require.Equal(t, c.after, after, "%v", c)
+	}
+}
@@ -111,17 +111,13 @@ func (d *database) NewRepository(ctx context.Context, r *Repository) (*Repositor
 		return nil, err
 	}
 	now := time.Now()
-	result, err := d.ExecContext(ctx, sqlNewRepository, r.Name, r.Path, r.Description, r.VisibleLevel, r.DefaultBranch, r.HashAlgo, r.CompressionAlgo, r.NamespaceID, now, now)
+	rid, err := d.InsertContext(ctx, sqlNewRepository, r.Name, r.Path, r.Description, r.VisibleLevel, r.DefaultBranch, r.HashAlgo, r.CompressionAlgo, r.NamespaceID, now, now)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, sql.ErrNoRows) || IsDupEntry(err) {
 			return nil, &ErrExist{message: "repository already exists"}
 		}
 		return nil, err
 	}
-	rid, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
 	return &Repository{
 		ID:              rid,
 		Name:            r.Name,
@@ -135,3 +131,92 @@ func (d *database) NewRepository(ctx context.Context, r *Repository) (*Repositor
 		CreatedAt:       now,
 	}, nil
 }
+
+const (
+	repositoryColumns = "id, namespace_id, name, path, description, visible_level, default_branch, hash_algo, compression_algo, created_at, updated_at"
+)
+
+// ListRepositories: repositories of the namespace ordered by id, cursor is the id of the last repository of the
+// previous page, 0 for the first page, the returned cursor is 0 when there are no more repositories.
+func (d *database) ListRepositories(ctx context.Context, namespaceID int64, cursor int64, limit int) ([]*Repository, int64, error) {
+	limit = listLimit(limit)
+	rows, err := d.QueryContext(ctx, "select "+repositoryColumns+" from repositories where namespace_id = ? and id > ? order by id limit ?",
+		namespaceID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	repos := make([]*Repository, 0, limit)
+	for rows.Next() {
+		var r Repository
+		if err := rows.Scan(&r.ID, &r.NamespaceID, &r.Name, &r.Path, &r.Description, &r.VisibleLevel, &r.DefaultBranch, &r.HashAlgo,
+			&r.CompressionAlgo, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		repos = append(repos, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(repos) > limit {
+		repos = repos[:limit]
+		next = repos[limit-1].ID
+	}
+	return repos, next, nil
+}
+
+// UpdateRepository: update name, description, visible level and default branch of the repository, the path, hash
+// and compression algorithm cannot be changed.
+func (d *database) UpdateRepository(ctx context.Context, r *Repository) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	result, err := d.ExecContext(ctx, "update repositories set name = ?, description = ?, visible_level = ?, default_branch = ?, updated_at = ? where id = ?",
+		r.Name, r.Description, r.VisibleLevel, r.DefaultBranch, time.Now(), r.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteRepository: remove the repository with its references, metadata, members and settings, pending webhook
+// deliveries are marked failed and kept in the delivery log.
+func (d *database) DeleteRepository(ctx context.Context, rid int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	result, err := tx.ExecContext(ctx, "delete from repositories where id = ?", rid)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	for _, query := range []string{
+		"delete from branches where rid = ?",
+		"delete from tags where rid = ?",
+		"delete from objects where rid = ?",
+		"delete from commits where rid = ?",
+		"delete from trees where rid = ?",
+		"delete from members where source_type = 2 and rid = ?",
+		"delete from deploy_keys_repositories where rid = ?",
+		"delete from protected_references where rid = ?",
+		"delete from path_acls where rid = ?",
+		"delete from webhooks where source_type = 2 and source_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, rid); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "update webhook_deliveries set status = ?, last_error = ?, updated_at = ? where rid = ? and status = ?",
+		DeliveryFailed, "repository deleted", time.Now(), rid, DeliveryPending); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
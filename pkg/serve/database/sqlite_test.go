@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebind(t *testing.T) {
+	require.Equal(t, "select id from branches where rid = $1 and name = $2 and hash <> '?'",
+		rebind(PostgreSQL, "select id from branches where rid = ? and name = ? and hash <> '?'"))
+	require.Equal(t, "select id from branches where rid = ?", rebind(SQLite, "select id from branches where rid = ?"))
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE a (\n    id INTEGER -- id; of a\n);\nCREATE INDEX idx_a ON a (id);\n\n-- end\n")
+	require.Equal(t, []string{"-- comment\nCREATE TABLE a (\n    id INTEGER -- id; of a\n)", "CREATE INDEX idx_a ON a (id)"}, statements)
+}
+
+func TestMigrations(t *testing.T) {
+	for _, driver := range []string{MySQL, SQLite, PostgreSQL} {
+		ms, err := Migrations(driver)
+		require.NoError(t, err)
+		require.NotEmpty(t, ms)
+		require.Equal(t, int64(1), ms[0].Version)
+	}
+	_, err := Migrations("oracle")
+	require.Error(t, err)
+}
+
+// TestMigrationColumnTypes: only SQLite migrations are applied by the tests, typos in the column types of other
+// drivers are found here.
+func TestMigrationColumnTypes(t *testing.T) {
+	columnTypes := map[string][]string{
+		MySQL:      {"bigint", "int", "tinyint", "char", "varchar", "text", "mediumtext", "mediumblob", "timestamp"},
+		PostgreSQL: {"bigserial", "bigint", "integer", "smallint", "boolean", "char", "varchar", "text", "timestamptz"},
+		SQLite:     {"integer", "bigint", "smallint", "boolean", "char", "varchar", "text", "timestamp"},
+	}
+	for driver, types := range columnTypes {
+		ms, err := Migrations(driver)
+		require.NoError(t, err)
+		for _, m := range ms {
+			script, err := migrations.ReadFile(m.file)
+			require.NoError(t, err)
+			for _, statement := range splitStatements(string(script)) {
+				var columns bool
+				for _, line := range strings.Split(statement, "\n") {
+					line = strings.TrimSpace(line)
+					if !columns {
+						if strings.HasPrefix(line, "CREATE TABLE") || strings.HasPrefix(line, "CREATE INDEX") || strings.HasPrefix(line, "CREATE UNIQUE INDEX") {
+							columns = strings.HasPrefix(line, "CREATE TABLE") && strings.HasSuffix(line, "(")
+							continue
+						}
+						if strings.HasPrefix(line, "ALTER TABLE") {
+							// only columns are renamed, CHANGE COLUMN of MySQL has the column type
+							fields := strings.Fields(line)
+							i := slices.Index(fields, "COLUMN")
+							require.True(t, i > 1 && (fields[i-1] == "RENAME" || fields[i-1] == "CHANGE"), "%s: unexpected statement: %s", m.file, line)
+							if fields[i-1] == "CHANGE" {
+								require.Greater(t, len(fields), i+3, "%s: no column type: %s", m.file, line)
+								typ, _, _ := strings.Cut(strings.ToLower(fields[i+3]), "(")
+								require.Contains(t, types, typ, "%s: bad column type: %s", m.file, line)
+							}
+							continue
+						}
+						if strings.HasPrefix(line, "`") && strings.HasSuffix(line, "(") {
+							// table name of MySQL
+							columns = true
+							continue
+						}
+						require.True(t, len(line) == 0 || strings.HasPrefix(line, "--"), "%s: unexpected statement: %s", m.file, line)
+						continue
+					}
+					fields := strings.Fields(line)
+					if len(fields) < 2 || strings.HasPrefix(line, ")") || strings.HasPrefix(line, "--") {
+						continue
+					}
+					switch strings.ToUpper(fields[0]) {
+					case "PRIMARY", "UNIQUE", "KEY", "INDEX", "CONSTRAINT":
+						continue
+					}
+					typ, _, _ := strings.Cut(strings.ToLower(strings.TrimSuffix(fields[1], ",")), "(")
+					require.Contains(t, types, typ, "%s: bad column type: %s", m.file, line)
+				}
+				require.True(t, columns || !strings.Contains(statement, "CREATE TABLE"), "%s: no columns: %s", m.file, statement)
+			}
+		}
+	}
+}
+
+func openSQLite(t *testing.T) *database {
+	dsn := "file:" + filepath.Join(t.TempDir(), "zeta.db") +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=case_sensitive_like(1)&_time_format=sqlite&_txlock=immediate"
+	conn, err := OpenConn(SQLite, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	applied, err := Migrate(context.Background(), conn)
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+	return &database{Conn: conn}
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	d := openSQLite(t)
+	applied, err := Migrate(ctx, d.Conn)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	u, err := d.NewUser(ctx, &User{UserName: "zeta", Name: "Zeta", Email: "zeta@example.io", Administrator: true})
+	require.NoError(t, err)
+	u, err = d.FindUser(ctx, u.ID)
+	require.NoError(t, err)
+	require.True(t, u.Administrator)
+	_, err = d.NewUser(ctx, &User{UserName: "zeta", Name: "Zeta", Email: "zeta@example.io"})
+	require.True(t, IsErrExist(err))
+
+	ns, err := d.FindNamespaceByPath(ctx, "zeta")
+	require.NoError(t, err)
+	r, err := d.NewRepository(ctx, &Repository{Name: "demo", Path: "demo", NamespaceID: ns.ID})
+	require.NoError(t, err)
+	for range 2 {
+		require.NoError(t, d.AddMember(ctx, &Member{UID: u.ID, SourceID: r.ID, SourceType: ProjectMember, AccessLevel: DevAccess, ExpiresAt: time.Now().Add(time.Hour)}))
+	}
+
+	newRev := plumbing.NewHash("4d1ad2bc0e3c3c7c1c5e3b1d6fb0a1d3c9d8e4c7a1f2b3c4d5e6f708192a3b4c").String()
+	cmds := []*Command{
+		{ReferenceName: "refs/heads/a_b", OldRev: plumbing.ZERO_OID, NewRev: newRev, RID: r.ID, UID: u.ID},
+		{ReferenceName: "refs/heads/axb", OldRev: plumbing.ZERO_OID, NewRev: newRev, RID: r.ID, UID: u.ID},
+	}
+	refs, err := d.DoReferenceUpdates(ctx, cmds)
+	require.NoError(t, err)
+	require.NotZero(t, refs[0].ID)
+	_, err = d.DoReferenceUpdates(ctx, cmds[:1])
+	require.Error(t, err)
+	refs, _, err = d.ListReferences(ctx, r.ID, "refs/heads/a_", "", 10)
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	require.Equal(t, plumbing.ReferenceName("refs/heads/a_b"), refs[0].Name)
+
+	w, err := d.NewWebhook(ctx, &Webhook{SourceID: r.ID, SourceType: ProjectMember, URL: "https://ci.example.io/hook", Active: true})
+	require.NoError(t, err)
+	webhooks, err := d.MatchWebhooks(ctx, r.ID)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.NoError(t, d.NewWebhookDeliveries(ctx, []*WebhookDelivery{{WebhookID: w.ID, RID: r.ID, Event: "push", Payload: "{}"}}))
+	claimed, err := d.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	claimed, err = d.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+}
+
+func TestSQLiteManagement(t *testing.T) {
+	ctx := context.Background()
+	d := openSQLite(t)
+	_, err := d.NewUser(ctx, &User{UserName: "bad name"})
+	require.True(t, IsErrNamingRule(err))
+	_, err = d.NewUser(ctx, &User{UserName: "zeta", Email: "not an email"})
+	require.True(t, IsErrInvalidArgument(err))
+	users := make([]*User, 0, 3)
+	for _, name := range []string{"zeta", "alice", "bob"} {
+		u, err := d.NewUser(ctx, &User{UserName: name, Email: name + "@example.io"})
+		require.NoError(t, err)
+		users = append(users, u)
+	}
+	page, next, err := d.ListUsers(ctx, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, users[1].ID, next)
+	page, next, err = d.ListUsers(ctx, next, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Zero(t, next)
+	require.Equal(t, "bob", page[0].UserName)
+
+	bob := users[2]
+	bob.Name = "Bob"
+	bob.LockedAt = time.Now()
+	require.NoError(t, d.UpdateUser(ctx, bob))
+	bob, err = d.FindUser(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Bob", bob.Name)
+	require.False(t, bob.LockedAt.IsZero())
+	require.True(t, IsNotFound(d.UpdateUser(ctx, &User{ID: 1000, UserName: "nobody"})))
+
+	g, err := d.NewGroupNamespace(ctx, &Namespace{Path: "team", Owner: users[1].ID})
+	require.NoError(t, err)
+	require.Equal(t, GroupNamespace, g.Type)
+	g, err = d.FindNamespaceByPath(ctx, "team")
+	require.NoError(t, err)
+	require.Equal(t, "team", g.Name)
+	g.Description = "The team"
+	require.NoError(t, d.UpdateNamespace(ctx, g))
+	namespaces, _, err := d.ListNamespaces(ctx, users[1].ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, namespaces, 2)
+
+	require.True(t, IsErrInvalidArgument(d.AddMember(ctx, &Member{UID: bob.ID, SourceID: g.ID, SourceType: GroupMember, AccessLevel: 60})))
+	require.NoError(t, d.AddMember(ctx, &Member{UID: bob.ID, SourceID: g.ID, SourceType: GroupMember, AccessLevel: ReporterAccess}))
+	require.NoError(t, d.UpdateMember(ctx, &Member{UID: bob.ID, SourceID: g.ID, SourceType: GroupMember, AccessLevel: DevAccess}))
+	members, _, err := d.ListMembers(ctx, GroupMember, g.ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	require.Equal(t, "bob", members[0].UserName)
+	require.Equal(t, DevAccess, members[0].AccessLevel)
+
+	r, err := d.NewRepository(ctx, &Repository{Name: "demo", Path: "demo", NamespaceID: g.ID, DefaultBranch: "mainline"})
+	require.NoError(t, err)
+	require.True(t, IsErrExist(d.DeleteNamespace(ctx, g.ID)))
+	r.VisibleLevel = 15
+	require.True(t, IsErrInvalidArgument(d.UpdateRepository(ctx, r)))
+	r.VisibleLevel = 20
+	r.Description = "Demo"
+	require.NoError(t, d.UpdateRepository(ctx, r))
+	repos, _, err := d.ListRepositories(ctx, g.ID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	require.Equal(t, "Demo", repos[0].Description)
+
+	k, err := d.AddKey(ctx, &Key{UID: bob.ID, Title: "deploy", Content: "ssh-ed25519 AAAA", Type: DeployKey, Fingerprint: "SHA256:deploy"})
+	require.NoError(t, err)
+	require.NoError(t, d.EnableDeployKey(ctx, r.ID, k.ID))
+	require.NoError(t, d.EnableDeployKey(ctx, r.ID, k.ID))
+	keys, err := d.ListDeployKeys(ctx, r.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	p, err := d.NewProtectionRule(ctx, &ProtectionRule{RID: r.ID, Pattern: "refs/heads/mainline", PushAccessLevel: DevAccess, ForcePushAccessLevel: NoOneAccess, DeleteAccessLevel: NoOneAccess})
+	require.NoError(t, err)
+	p.Pattern = "mainline"
+	require.True(t, IsErrInvalidArgument(d.UpdateProtectionRule(ctx, p)))
+	p.Pattern = "refs/heads/release/*"
+	require.NoError(t, d.UpdateProtectionRule(ctx, p))
+
+	require.NoError(t, d.DeleteRepository(ctx, r.ID))
+	_, _, err = d.FindRepositoryByPath(ctx, "team", "demo")
+	require.True(t, IsNotFound(err))
+	keys, err = d.ListDeployKeys(ctx, r.ID)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+	rules, err := d.ListProtectionRules(ctx, r.ID)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+
+	require.True(t, IsErrExist(d.DeleteUser(ctx, users[1].ID)))
+	require.NoError(t, d.DeleteNamespace(ctx, g.ID))
+	require.NoError(t, d.DeleteUser(ctx, bob.ID))
+	_, err = d.FindUser(ctx, bob.ID)
+	require.True(t, IsNotFound(err))
+	_, err = d.FindKey(ctx, k.ID)
+	require.True(t, IsNotFound(err))
+}
+
+func TestSQLiteAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	d := openSQLite(t)
+	u, err := d.NewUser(ctx, &User{UserName: "zeta", Email: "zeta@example.io"})
+	require.NoError(t, err)
+	ns, err := d.FindNamespaceByPath(ctx, "zeta")
+	require.NoError(t, err)
+	r, err := d.NewRepository(ctx, &Repository{Name: "demo", Path: "demo", NamespaceID: ns.ID})
+	require.NoError(t, err)
+
+	rev1 := plumbing.NewHash("4d1ad2bc0e3c3c7c1c5e3b1d6fb0a1d3c9d8e4c7a1f2b3c4d5e6f708192a3b4c").String()
+	rev2 := plumbing.NewHash("5e2be3cd1f4d4d8d2d6f4c2e70c1b2e4dae9f5d8b2a3c4d5e6f708192a3b4c5d").String()
+	branch := plumbing.NewBranchReferenceName("main")
+	_, err = d.DoReferenceUpdate(ctx, &Command{ReferenceName: branch, OldRev: plumbing.ZERO_OID, NewRev: rev1, RID: r.ID, UID: u.ID,
+		Protocol: "ssh", KeyID: 7, ClientIP: "10.0.0.1", PushOptions: []string{"ci.skip"}})
+	require.NoError(t, err)
+	_, err = d.DoReferenceUpdate(ctx, &Command{ReferenceName: branch, OldRev: rev1, NewRev: rev2, RID: r.ID, UID: u.ID, Protocol: "http", Force: true})
+	require.NoError(t, err)
+	// the failed update is rolled back together with its audit event
+	_, err = d.DoReferenceUpdate(ctx, &Command{ReferenceName: branch, OldRev: rev1, NewRev: rev2, RID: r.ID, UID: u.ID})
+	require.True(t, IsErrAlreadyLocked(err))
+	_, err = d.DoReferenceUpdate(ctx, &Command{ReferenceName: plumbing.NewTagReferenceName("v1"), OldRev: plumbing.ZERO_OID, NewRev: rev1, RID: r.ID, UID: u.ID})
+	require.NoError(t, err)
+	require.NoError(t, d.NewAuditEvent(ctx, &AuditEvent{UID: u.ID, Action: "user.update", Target: "/api/v1/users/zeta", Protocol: "api"}))
+
+	events, next, err := d.ListAuditEvents(ctx, &AuditFilter{RID: r.ID, ReferenceName: branch}, 0, 10)
+	require.NoError(t, err)
+	require.Zero(t, next)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditReferenceUpdate, events[0].Action)
+	require.Equal(t, rev1, events[0].OldRev)
+	require.Equal(t, rev2, events[0].NewRev)
+	require.True(t, events[0].Force)
+	require.Equal(t, AuditReferenceCreate, events[1].Action)
+	require.Equal(t, int64(7), events[1].KeyID)
+	require.Equal(t, "10.0.0.1", events[1].ClientIP)
+	require.Equal(t, []string{"ci.skip"}, events[1].PushOptions)
+
+	events, next, err = d.ListAuditEvents(ctx, &AuditFilter{Action: "reference."}, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.NotZero(t, next)
+	events, next, err = d.ListAuditEvents(ctx, &AuditFilter{Action: "reference."}, next, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Zero(t, next)
+
+	events, _, err = d.ListAuditEvents(ctx, &AuditFilter{UID: u.ID, Action: "user.update"}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	e, err := d.FindAuditEvent(ctx, events[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/users/zeta", e.Target)
+	require.Empty(t, e.PushOptions)
+	_, err = d.FindAuditEvent(ctx, e.ID+100)
+	require.True(t, IsNotFound(err))
+
+	events, _, err = d.ListAuditEvents(ctx, &AuditFilter{Since: time.Now().Add(time.Hour)}, 0, 10)
+	require.NoError(t, err)
+	require.Empty(t, events)
+	events, _, err = d.ListAuditEvents(ctx, &AuditFilter{Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour)}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	// the reference version is changed by every reference update of the repository
+	version, err := d.ReferenceVersion(ctx, r.ID)
+	require.NoError(t, err)
+	require.Equal(t, events[1].ID, version)
+	_, err = d.DoReferenceUpdate(ctx, &Command{ReferenceName: branch, OldRev: rev2, NewRev: rev1, RID: r.ID, UID: u.ID})
+	require.NoError(t, err)
+	next, err = d.ReferenceVersion(ctx, r.ID)
+	require.NoError(t, err)
+	require.Greater(t, next, version)
+	version, err = d.ReferenceVersion(ctx, r.ID+1)
+	require.NoError(t, err)
+	require.Zero(t, version)
+}
@@ -4,6 +4,8 @@
 package database
 
 import (
+	"fmt"
+	"net/mail"
 	"regexp"
 	"strings"
 	"time"
@@ -53,6 +55,29 @@ func (u *User) Guard() {
 	u.Password = ""
 }
 
+const (
+	maxNameLength = 255
+)
+
+// Validate: the username complies with the naming rules, name defaults to the username.
+func (u *User) Validate() error {
+	if len(u.UserName) == 0 || len(u.UserName) > maxNameLength || !validatePath(u.UserName) {
+		return &ErrNamingRule{name: u.UserName}
+	}
+	if len(u.Name) == 0 {
+		u.Name = u.UserName
+	}
+	if len(u.Name) > maxNameLength {
+		return &ErrInvalidArgument{message: fmt.Sprintf("name is longer than %d bytes", maxNameLength)}
+	}
+	if len(u.Email) != 0 {
+		if a, err := mail.ParseAddress(u.Email); err != nil || a.Address != u.Email || len(u.Email) > maxNameLength {
+			return &ErrInvalidArgument{message: fmt.Sprintf("bad email '%s'", u.Email)}
+		}
+	}
+	return nil
+}
+
 type Branch struct {
 	Name            string    `json:"name"`
 	ID              int64     `json:"id"`
@@ -82,6 +107,11 @@ type Command struct {
 	Description   string                 `json:"description"`
 	RID           int64                  `json:"rid"`
 	UID           int64                  `json:"uid"`
+	PushOptions   []string               `json:"push_options,omitempty"`
+	KeyID         int64                  `json:"key_id,omitempty"`    // SSH key or deploy key of the push
+	Protocol      string                 `json:"protocol,omitempty"`  // http, ssh, api or cli
+	ClientIP      string                 `json:"client_ip,omitempty"` // IP of the client
+	Force         bool                   `json:"force,omitempty"`     // the new rev is not a descendant of the old rev
 }
 
 // ^[a-zA-Z][a-zA-Z-_.]*((?<!.zeta)(?<!.deleted))$ start alpha
@@ -103,14 +133,28 @@ const (
 )
 
 type Namespace struct {
-	ID          int64
-	Path        string
-	Name        string
-	Owner       int64
-	Type        int // 1-personal , 2-normal
-	Description string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID          int64     `json:"id"`
+	Path        string    `json:"path"`
+	Name        string    `json:"name"`
+	Owner       int64     `json:"owner_id"`
+	Type        int       `json:"type"` // 2-group, others are personal namespaces
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate: the path complies with the naming rules, name defaults to the path.
+func (n *Namespace) Validate() error {
+	if len(n.Path) == 0 || len(n.Path) > maxNameLength || !validatePath(n.Path) {
+		return &ErrNamingRule{name: n.Path}
+	}
+	if len(n.Name) == 0 {
+		n.Name = n.Path
+	}
+	if len(n.Name) > maxNameLength {
+		return &ErrInvalidArgument{message: fmt.Sprintf("name is longer than %d bytes", maxNameLength)}
+	}
+	return nil
 }
 
 const (
@@ -143,15 +187,23 @@ func (r *Repository) IsInternal() bool {
 }
 
 func (r *Repository) Validate() error {
-	if !validatePath(r.Path) {
+	if len(r.Path) == 0 || len(r.Path) > maxNameLength || !validatePath(r.Path) {
 		return &ErrNamingRule{name: r.Path}
 	}
 	if len(r.Name) == 0 {
 		r.Name = r.Path
 	}
+	switch r.VisibleLevel {
+	case PrivateRepository, InternalRepository, PublicRepository, AnonymousRepository:
+	default:
+		return &ErrInvalidArgument{message: fmt.Sprintf("bad visible_level %d, supported: 0, 10, 20, 30", r.VisibleLevel)}
+	}
 	if len(r.DefaultBranch) == 0 {
 		r.DefaultBranch = DefaultBranch
 	}
+	if !plumbing.ValidateBranchName([]byte(r.DefaultBranch)) {
+		return &ErrNamingRule{name: r.DefaultBranch}
+	}
 	if len(r.CompressionAlgo) == 0 {
 		r.CompressionAlgo = DefaultCompressionALGO
 	}
@@ -205,4 +257,14 @@ type Member struct {
 	ExpiresAt   time.Time   `json:"expires_at"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
+	UserName    string      `json:"username,omitempty"` // filled by ListMembers
+}
+
+// Validate: the access level is one of reporter, developer, master and owner.
+func (m *Member) Validate() error {
+	switch m.AccessLevel {
+	case ReporterAccess, DevAccess, MasterAccess, OwnerAccess:
+		return nil
+	}
+	return &ErrInvalidArgument{message: fmt.Sprintf("bad access_level %d, supported: 20, 30, 40, 50", m.AccessLevel)}
 }
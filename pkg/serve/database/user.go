@@ -93,12 +93,15 @@ func (d *database) SearchUser(ctx context.Context, emailOrName string) (*User, e
 }
 
 func (d *database) NewUser(ctx context.Context, u *User) (*User, error) {
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
 	now := time.Now()
 	tx, err := d.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("new tx error: %v", err)
 	}
-	result, err := tx.ExecContext(ctx, "insert into users(username,name,admin,email,type,password,signature_token,created_at,updated_at) values(?,?,?,?,?,?,?,?,?)",
+	uid, err := tx.InsertContext(ctx, "insert into users(username,name,admin,email,type,password,signature_token,created_at,updated_at) values(?,?,?,?,?,?,?,?,?)",
 		u.UserName, u.Name, u.Administrator, u.Email, u.Type, u.Password, u.SignatureToken, now, now)
 	if IsDupEntry(err) {
 		_ = tx.Rollback()
@@ -108,11 +111,6 @@ func (d *database) NewUser(ctx context.Context, u *User) (*User, error) {
 		_ = tx.Rollback()
 		return nil, err
 	}
-	uid, err := result.LastInsertId()
-	if err != nil {
-		_ = tx.Rollback()
-		return nil, err
-	}
 	_, err = tx.ExecContext(ctx, "insert into namespaces(path, name, owner_id, type, description, created_at, updated_at) values(?,?,?,?,?,?,?)",
 		u.UserName, u.UserName, uid, 0, "", now, now)
 	if IsDupEntry(err) {
@@ -128,3 +126,96 @@ func (d *database) NewUser(ctx context.Context, u *User) (*User, error) {
 	}
 	return d.FindUser(ctx, uid)
 }
+
+const (
+	userColumns = "id, username, name, admin, email, type, locked_at, created_at, updated_at"
+)
+
+// ListUsers: users ordered by id, cursor is the id of the last user of the previous page, 0 for the first page, the
+// returned cursor is 0 when there are no more users.
+func (d *database) ListUsers(ctx context.Context, cursor int64, limit int) ([]*User, int64, error) {
+	limit = listLimit(limit)
+	rows, err := d.QueryContext(ctx, "select "+userColumns+" from users where id > ? order by id limit ?", cursor, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	users := make([]*User, 0, limit)
+	for rows.Next() {
+		var u User
+		var lockedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.UserName, &u.Name, &u.Administrator, &u.Email, &u.Type, &lockedAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		u.LockedAt = lockedAt.Time
+		users = append(users, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(users) > limit {
+		users = users[:limit]
+		next = users[limit-1].ID
+	}
+	return users, next, nil
+}
+
+// UpdateUser: update name, email, administrator, password and lock state of the user, the username cannot be changed.
+func (d *database) UpdateUser(ctx context.Context, u *User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	lockedAt := sql.NullTime{Time: u.LockedAt, Valid: !u.LockedAt.IsZero()}
+	result, err := d.ExecContext(ctx, "update users set name = ?, email = ?, admin = ?, password = ?, locked_at = ?, updated_at = ? where id = ?",
+		u.Name, u.Email, u.Administrator, u.Password, lockedAt, time.Now(), u.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUser: remove the user with the personal namespace, keys, emails and memberships. Users who still have
+// repositories in the personal namespace or own groups cannot be removed.
+func (d *database) DeleteUser(ctx context.Context, uid int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	var userName string
+	if err := tx.QueryRowContext(ctx, "select username from users where id = ?", uid).Scan(&userName); err != nil {
+		return err
+	}
+	var owned int
+	if err := tx.QueryRowContext(ctx, "select count(*) from namespaces where owner_id = ? and path <> ?", uid, userName).Scan(&owned); err != nil {
+		return err
+	}
+	if owned != 0 {
+		return &ErrExist{message: fmt.Sprintf("user '%s' still owns %d groups", userName, owned)}
+	}
+	var repos int
+	if err := tx.QueryRowContext(ctx, "select count(*) from repositories as r inner join namespaces as n on r.namespace_id = n.id where n.owner_id = ? and n.path = ?",
+		uid, userName).Scan(&repos); err != nil {
+		return err
+	}
+	if repos != 0 {
+		return &ErrExist{message: fmt.Sprintf("user '%s' still has %d repositories", userName, repos)}
+	}
+	for _, query := range []string{
+		"delete from deploy_keys_repositories where kid in (select id from ssh_keys where uid = ?)",
+		"delete from ssh_keys where uid = ?",
+		"delete from emails where uid = ?",
+		"delete from members where uid = ?",
+		"delete from namespaces where owner_id = ?",
+		"delete from users where id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, uid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,294 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Webhook struct {
+	ID         int64      `json:"id"`
+	SourceID   int64      `json:"source_id"`
+	SourceType MemberType `json:"source_type"` // 2-Project, 3-Namespace
+	URL        string     `json:"url"`
+	Secret     string     `json:"-"`
+	Events     []string   `json:"events"` // empty: all events
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Subscribed: whether the webhook receives the event
+func (w *Webhook) Subscribed(event string) bool {
+	return w.Active && (len(w.Events) == 0 || slices.Contains(w.Events, event))
+}
+
+type DeliveryStatus int
+
+const (
+	DeliveryPending   DeliveryStatus = 0
+	DeliverySucceeded DeliveryStatus = 1
+	DeliveryFailed    DeliveryStatus = 2
+)
+
+type WebhookDelivery struct {
+	ID            int64          `json:"id"`
+	WebhookID     int64          `json:"webhook_id"`
+	RID           int64          `json:"rid"`
+	Event         string         `json:"event"`
+	Payload       string         `json:"payload"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	ResponseCode  int            `json:"response_code"`
+	LastError     string         `json:"last_error"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	// URL and Secret of the webhook, filled by ClaimWebhookDeliveries
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+const (
+	webhookColumns = "id, source_id, source_type, url, secret, events, active, created_at, updated_at"
+)
+
+func scanWebhooks(rows *sql.Rows) ([]*Webhook, error) {
+	defer rows.Close()
+	webhooks := make([]*Webhook, 0, 8)
+	for rows.Next() {
+		var w Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.SourceID, &w.SourceType, &w.URL, &w.Secret, &events, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if len(events) != 0 {
+			w.Events = strings.Split(events, ",")
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (d *database) NewWebhook(ctx context.Context, w *Webhook) (*Webhook, error) {
+	now := time.Now()
+	id, err := d.InsertContext(ctx, "insert into webhooks(source_id, source_type, url, secret, events, active, created_at, updated_at) values(?,?,?,?,?,?,?,?)",
+		w.SourceID, w.SourceType, w.URL, w.Secret, strings.Join(w.Events, ","), w.Active, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return d.FindWebhook(ctx, id)
+}
+
+func (d *database) FindWebhook(ctx context.Context, id int64) (*Webhook, error) {
+	rows, err := d.QueryContext(ctx, "select "+webhookColumns+" from webhooks where id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return webhooks[0], nil
+}
+
+func (d *database) ListWebhooks(ctx context.Context, sourceType MemberType, sourceID int64) ([]*Webhook, error) {
+	rows, err := d.QueryContext(ctx, "select "+webhookColumns+" from webhooks where source_type = ? and source_id = ? order by id", sourceType, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhooks(rows)
+}
+
+const (
+	sqlMatchWebhooks = `SELECT    ` + webhookColumns + `
+FROM      webhooks
+WHERE     active = ?
+          AND (
+          (source_type = 2 AND source_id = ?)
+          OR (source_type = 3 AND source_id = (SELECT namespace_id FROM repositories WHERE id = ?))
+          )
+ORDER BY  id`
+)
+
+// MatchWebhooks: active webhooks of the repository and its namespace
+func (d *database) MatchWebhooks(ctx context.Context, rid int64) ([]*Webhook, error) {
+	rows, err := d.QueryContext(ctx, sqlMatchWebhooks, true, rid, rid)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhooks(rows)
+}
+
+// DeleteWebhook: remove the webhook, pending deliveries are marked failed and kept in the delivery log.
+func (d *database) DeleteWebhook(ctx context.Context, id int64) error {
+	tx, err := d.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint
+	result, err := tx.ExecContext(ctx, "delete from webhooks where id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, "update webhook_deliveries set status = ?, last_error = ?, updated_at = ? where webhook_id = ? and status = ?",
+		DeliveryFailed, "webhook deleted", time.Now(), id, DeliveryPending); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NewWebhookDeliveries: enqueue deliveries, they are sent by the dispatcher which claims them with ClaimWebhookDeliveries.
+func (d *database) NewWebhookDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("insert into webhook_deliveries(webhook_id, rid, event, payload, status, next_attempt_at, created_at, updated_at) values")
+	args := make([]any, 0, len(deliveries)*8)
+	for i, w := range deliveries {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("(?,?,?,?,?,?,?,?)")
+		args = append(args, w.WebhookID, w.RID, w.Event, w.Payload, DeliveryPending, now, now, now)
+	}
+	_, err := d.ExecContext(ctx, b.String(), args...)
+	return err
+}
+
+const (
+	deliveryColumns = "d.id, d.webhook_id, d.rid, d.event, d.payload, d.status, d.attempts, d.response_code, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at, d.updated_at"
+
+	sqlDueDeliveries = `SELECT    ` + deliveryColumns + `, w.url, w.secret
+FROM      webhook_deliveries d
+JOIN      webhooks w ON w.id = d.webhook_id
+WHERE     d.status = 0
+          AND d.next_attempt_at <= ?
+ORDER BY  d.next_attempt_at
+LIMIT     ?`
+)
+
+func scanDelivery(rows *sql.Rows, withWebhook bool) (*WebhookDelivery, error) {
+	var w WebhookDelivery
+	var deliveredAt sql.NullTime
+	dest := []any{&w.ID, &w.WebhookID, &w.RID, &w.Event, &w.Payload, &w.Status, &w.Attempts, &w.ResponseCode, &w.LastError, &w.NextAttemptAt, &deliveredAt, &w.CreatedAt, &w.UpdatedAt}
+	if withWebhook {
+		dest = append(dest, &w.URL, &w.Secret)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		w.DeliveredAt = &deliveredAt.Time
+	}
+	return &w, nil
+}
+
+// ClaimWebhookDeliveries: claim due deliveries for lease, a delivery claimed by a dispatcher that dies before it is
+// updated becomes due again when the lease expires.
+func (d *database) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	now := time.Now()
+	rows, err := d.QueryContext(ctx, sqlDueDeliveries, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	due := make([]*WebhookDelivery, 0, limit)
+	for rows.Next() {
+		w, err := scanDelivery(rows, true)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		due = append(due, w)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	claimed := make([]*WebhookDelivery, 0, len(due))
+	leaseEnd := now.Add(lease)
+	for _, w := range due {
+		// other dispatchers may claim the same delivery, only one of them updates the row
+		result, err := d.ExecContext(ctx, "update webhook_deliveries set next_attempt_at = ? where id = ? and status = ? and next_attempt_at = ?",
+			leaseEnd, w.ID, DeliveryPending, w.NextAttemptAt)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 1 {
+			claimed = append(claimed, w)
+		}
+	}
+	return claimed, nil
+}
+
+// UpdateWebhookDelivery: record the result of an attempt
+func (d *database) UpdateWebhookDelivery(ctx context.Context, w *WebhookDelivery) error {
+	var deliveredAt sql.NullTime
+	if w.DeliveredAt != nil {
+		deliveredAt = sql.NullTime{Time: *w.DeliveredAt, Valid: true}
+	}
+	_, err := d.ExecContext(ctx, "update webhook_deliveries set status = ?, attempts = ?, response_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?, updated_at = ? where id = ?",
+		w.Status, w.Attempts, w.ResponseCode, w.LastError, w.NextAttemptAt, deliveredAt, time.Now(), w.ID)
+	return err
+}
+
+// RetryWebhookDelivery: deliver again as soon as possible, attempts are kept.
+func (d *database) RetryWebhookDelivery(ctx context.Context, id int64) error {
+	now := time.Now()
+	result, err := d.ExecContext(ctx, "update webhook_deliveries set status = ?, next_attempt_at = ?, updated_at = ? where id = ?", DeliveryPending, now, now, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListWebhookDeliveries: deliveries of the webhook, newest first. cursor is the id of the last delivery of the previous
+// page, 0 for the first page, the returned cursor is 0 when there are no more deliveries.
+func (d *database) ListWebhookDeliveries(ctx context.Context, webhookID int64, cursor int64, limit int) ([]*WebhookDelivery, int64, error) {
+	if limit <= 0 || limit > MaxListLimit {
+		limit = DefaultListLimit
+	}
+	if cursor <= 0 {
+		cursor = 1<<63 - 1
+	}
+	rows, err := d.QueryContext(ctx, "select "+deliveryColumns+" from webhook_deliveries d where d.webhook_id = ? and d.id < ? order by d.id desc limit ?",
+		webhookID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	deliveries := make([]*WebhookDelivery, 0, limit)
+	for rows.Next() {
+		w, err := scanDelivery(rows, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		next = deliveries[limit-1].ID
+	}
+	return deliveries, next, nil
+}
@@ -180,4 +180,105 @@ CREATE TABLE
         PRIMARY KEY (`id`),
         UNIQUE KEY `uk_deploy_keys_repositories_kid_and_rid` (`kid`, `rid`) LOCAL,
         KEY `idx_deploy_keys_repositories_rid` (`rid`) LOCAL
-    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '部署公钥开启项目';
\ No newline at end of file
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '部署公钥开启项目';
+CREATE TABLE
+    `webhooks` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `source_id` bigint (20) unsigned NOT NULL comment '资源 ID（存储库 ID 或群组 ID）',
+        `source_type` tinyint (4) NOT NULL DEFAULT '2' comment '所属主体, 2-Project, 3-Namespace',
+        `url` varchar(2048) NOT NULL comment '投递地址',
+        `secret` varchar(512) NOT NULL DEFAULT '' comment 'HMAC-SHA256 签名密钥',
+        `events` varchar(1024) NOT NULL DEFAULT '' comment '订阅的事件，逗号分隔，空为全部事件',
+        `active` tinyint (4) NOT NULL DEFAULT '1' comment '是否启用',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_webhooks_source_type_source_id` (`source_type`, `source_id`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = 'Webhook 表';
+
+CREATE TABLE
+    `webhook_deliveries` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `webhook_id` bigint (20) unsigned NOT NULL comment 'Webhook ID',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `event` varchar(64) NOT NULL comment '事件',
+        `payload` mediumtext NOT NULL comment 'JSON 负载',
+        `status` tinyint (4) NOT NULL DEFAULT '0' comment '投递状态，0 等待投递，1 成功，2 失败',
+        `attempts` int (11) NOT NULL DEFAULT '0' comment '已投递次数',
+        `response_code` int (11) NOT NULL DEFAULT '0' comment '最近一次投递的 HTTP 状态码',
+        `last_error` varchar(1024) NOT NULL DEFAULT '' comment '最近一次投递的错误信息',
+        `next_attempt_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '下次投递时间，投递中时为租约到期时间',
+        `delivered_at` timestamp NULL DEFAULT NULL comment '投递成功时间',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_webhook_deliveries_status_next_attempt_at` (`status`, `next_attempt_at`) LOCAL,
+        KEY `idx_webhook_deliveries_webhook_id` (`webhook_id`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = 'Webhook 投递队列与日志';
+
+CREATE TABLE
+    `protected_references` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `pattern` varchar(4096) NOT NULL comment '引用名通配符，例如 refs/heads/release/*',
+        `push_access_level` int (11) NOT NULL DEFAULT '30' comment '推送所需的访问级别，60 禁止任何人',
+        `force_push_access_level` int (11) NOT NULL DEFAULT '60' comment '强制推送所需的访问级别',
+        `delete_access_level` int (11) NOT NULL DEFAULT '60' comment '删除所需的访问级别',
+        `require_linear_history` tinyint (4) NOT NULL DEFAULT '0' comment '禁止合并提交',
+        `require_signature_header` tinyint (4) NOT NULL DEFAULT '0' comment '要求提交带有签名头，不验证签名',
+        `max_blob_size` bigint (20) NOT NULL DEFAULT '0' comment '文件大小上限，0 不限制',
+        `forbidden_paths` varchar(4096) NOT NULL DEFAULT '' comment '禁止修改的路径通配符，逗号分隔',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_protected_references_rid` (`rid`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '引用保护规则';
+
+CREATE TABLE
+    `path_acls` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `path` varchar(4096) NOT NULL comment '目录前缀，例如 secret/',
+        `namespace_id` bigint (20) unsigned NOT NULL comment '授权的群组 ID',
+        `access_level` int (11) NOT NULL DEFAULT '20' comment '授予的访问级别，20 读取，30 读写',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP comment '修改时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_path_acls_rid` (`rid`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '目录访问控制规则';
+
+CREATE TABLE
+    `audit_events` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '存储库 ID，与存储库无关的操作为 0',
+        `uid` bigint (20) unsigned NOT NULL DEFAULT '0' comment '操作用户 ID',
+        `key_id` bigint (20) unsigned NOT NULL DEFAULT '0' comment 'SSH 密钥或部署密钥 ID',
+        `action` varchar(64) NOT NULL comment '操作，例如 reference.update、user.create',
+        `target` varchar(1024) NOT NULL DEFAULT '' comment '操作对象，管理 API 为请求路径',
+        `reference_name` varchar(4096) NOT NULL DEFAULT '' comment '引用名',
+        `old_rev` char(64) NOT NULL DEFAULT '' comment '更新前的提交',
+        `new_rev` char(64) NOT NULL DEFAULT '' comment '更新后的提交',
+        `forced` tinyint (4) NOT NULL DEFAULT '0' comment '强制推送',
+        `protocol` varchar(16) NOT NULL DEFAULT '' comment '协议，http、ssh、api 或 cli',
+        `client_ip` varchar(64) NOT NULL DEFAULT '' comment '客户端 IP',
+        `push_options` text NOT NULL comment '推送选项，JSON 数组',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_audit_events_rid_reference_name` (`rid`, `reference_name`(512)) LOCAL,
+        KEY `idx_audit_events_uid` (`uid`) LOCAL,
+        KEY `idx_audit_events_created_at` (`created_at`) LOCAL,
+        KEY `idx_audit_events_rid_id` (`rid`, `id`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '审计日志，只追加';
+
+CREATE TABLE
+    `upload_sessions` (
+        `id` bigint (20) unsigned NOT NULL AUTO_INCREMENT comment '主键',
+        `rid` bigint (20) unsigned NOT NULL comment '存储库 ID',
+        `oid` char(64) NOT NULL comment '对象 ID',
+        `upload_id` varchar(255) NOT NULL comment '分片上传 ID',
+        `size` bigint (20) NOT NULL DEFAULT '0' comment '对象大小',
+        `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP comment '创建时间',
+        PRIMARY KEY (`id`),
+        KEY `idx_upload_sessions_rid_upload_id` (`rid`, `upload_id`) LOCAL,
+        KEY `idx_upload_sessions_created_at` (`created_at`) LOCAL
+    ) DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci COMMENT = '未完成的分片上传';
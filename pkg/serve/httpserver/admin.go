@@ -0,0 +1,450 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/serve/argon2id"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/ratelimit"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// The management API: users are authenticated by basic auth with the password. Administrators manage everything,
+// owners of namespaces and repositories manage their members, keys, references and settings. The API is described
+// by openapi.yaml which is served at /api/v1/openapi.yaml.
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+func (s *Server) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPI)
+}
+
+// OnManagement: authenticate the user of the management API, handlers check the access to the resources.
+func (s *Server) OnManagement(fn HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, err := s.authenticate(w, r, r.Header.Get(AUTHORIZATION))
+		if err != nil {
+			return
+		}
+		release, err := s.limiter.Enter([]ratelimit.Key{{Kind: ratelimit.IP, ID: parseRemoteAddress(r)}, ratelimit.NewKey(ratelimit.User, u.ID)}, false)
+		if err != nil {
+			renderLimited(w, r, err)
+			return
+		}
+		defer release()
+		req := &Request{Request: r, U: u}
+		fn(w, req)
+		s.recordAction(w, req)
+	}
+}
+
+// recordAction: append the succeeded management action to the audit log, the action is the name of the route.
+// Request bodies are not recorded, they may contain passwords.
+func (s *Server) recordAction(w http.ResponseWriter, r *Request) {
+	route := mux.CurrentRoute(r.Request)
+	if route == nil || len(route.GetName()) == 0 {
+		return
+	}
+	if hw, ok := w.(*ResponseWriter); ok && hw.StatusCode() >= http.StatusBadRequest {
+		return
+	}
+	e := &database.AuditEvent{
+		UID:      r.U.ID,
+		Action:   route.GetName(),
+		Target:   r.URL.RequestURI(),
+		Protocol: "api",
+		ClientIP: parseRemoteAddress(r.Request),
+	}
+	if r.R != nil {
+		e.RID = r.R.ID
+	}
+	if err := s.db.NewAuditEvent(context.WithoutCancel(r.Context()), e); err != nil {
+		logrus.Errorf("record action %s of %s error: %v", e.Action, r.U.UserName, err)
+	}
+}
+
+// OnAdmin: OnManagement of handlers only administrators are allowed
+func (s *Server) OnAdmin(fn http.HandlerFunc) http.HandlerFunc {
+	return s.OnManagement(func(w http.ResponseWriter, r *Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+		fn(w, r.Request)
+	})
+}
+
+func requireAdmin(w http.ResponseWriter, r *Request) bool {
+	if r.U.Administrator {
+		return true
+	}
+	renderFailureFormat(w, r.Request, http.StatusForbidden, "access denied, administrator required, current user: %s", r.U.UserName)
+	return false
+}
+
+// decodeBody: decode the JSON body, unknown fields are rejected
+func decodeBody(w http.ResponseWriter, r *Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "input body error: %v", err)
+		return false
+	}
+	return true
+}
+
+// parsePage: cursor and limit of list APIs, ?cursor=<next>&limit=<n>
+func parsePage(w http.ResponseWriter, r *Request) (int64, int, bool) {
+	q := r.URL.Query()
+	var cursor int64
+	var limit int
+	var err error
+	if v := q.Get("cursor"); len(v) != 0 {
+		if cursor, err = strconv.ParseInt(v, 10, 64); err != nil || cursor < 0 {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad cursor '%s'", v)
+			return 0, 0, false
+		}
+	}
+	if v := q.Get("limit"); len(v) != 0 {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > database.MaxListLimit {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad limit '%s', range: 1-%d", v, database.MaxListLimit)
+			return 0, 0, false
+		}
+	}
+	return cursor, limit, true
+}
+
+// findUser: the user of the path, only administrators and the user self are allowed
+func (s *Server) findUser(w http.ResponseWriter, r *Request) (*database.User, bool) {
+	userName := mux.Vars(r.Request)["username"]
+	if !r.U.Administrator && r.U.UserName != userName {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, "access denied, current user: %s", r.U.UserName)
+		return nil, false
+	}
+	u, err := s.db.SearchUser(r.Context(), userName)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return nil, false
+	}
+	return u, true
+}
+
+// findUserByName: the user granted or transferred to, not the user of the request
+func (s *Server) findUserByName(w http.ResponseWriter, r *Request, userName string) (*database.User, bool) {
+	if len(userName) == 0 {
+		renderFailure(w, r.Request, http.StatusBadRequest, "username not given")
+		return nil, false
+	}
+	u, err := s.db.SearchUser(r.Context(), userName)
+	if err != nil {
+		if database.IsNotFound(err) {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "user '%s' not found", userName)
+			return nil, false
+		}
+		s.renderErrorRaw(w, r.Request, err)
+		return nil, false
+	}
+	return u, true
+}
+
+type Users struct {
+	Users []*database.User `json:"users"`
+	Next  int64            `json:"next,omitempty"` // cursor of the next page
+}
+
+func (s *Server) ListUsers(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	users, next, err := s.db.ListUsers(r.Context(), cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Users{Users: users, Next: next})
+}
+
+type NewUser struct {
+	UserName      string `json:"username"`
+	Name          string `json:"name,omitempty"`
+	Administrator bool   `json:"administrator"`
+	Email         string `json:"email"`
+	Password      string `json:"password"`
+}
+
+func (s *Server) NewUser(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var newUser NewUser
+	if !decodeBody(w, r, &newUser) {
+		return
+	}
+	if len(newUser.UserName) == 0 || len(newUser.Password) == 0 {
+		renderFailure(w, r.Request, http.StatusBadRequest, "username or password is empty")
+		return
+	}
+	passwd, err := argon2id.CreateHash(newUser.Password, argon2id.DefaultParams)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusInternalServerError, "gen salt password error: %v", err)
+		return
+	}
+	u, err := s.db.NewUser(r.Context(), &database.User{
+		UserName:       newUser.UserName,
+		Name:           newUser.Name,
+		Administrator:  newUser.Administrator,
+		Email:          newUser.Email,
+		Password:       passwd,
+		SignatureToken: strengthen.NewRID(),
+	})
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	u.Guard()
+	JsonEncode(w, u)
+}
+
+func (s *Server) GetUser(w http.ResponseWriter, r *Request) {
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	u.Guard()
+	JsonEncode(w, u)
+}
+
+// UpdateUser: fields not given are not changed
+type UpdateUser struct {
+	Name          *string `json:"name,omitempty"`
+	Email         *string `json:"email,omitempty"`
+	Password      *string `json:"password,omitempty"`
+	Administrator *bool   `json:"administrator,omitempty"` // administrator only
+	Locked        *bool   `json:"locked,omitempty"`        // administrator only
+}
+
+func (s *Server) UpdateUser(w http.ResponseWriter, r *Request) {
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	var updateUser UpdateUser
+	if !decodeBody(w, r, &updateUser) {
+		return
+	}
+	if (updateUser.Administrator != nil || updateUser.Locked != nil) && !requireAdmin(w, r) {
+		return
+	}
+	if u.ID == r.U.ID && ((updateUser.Administrator != nil && !*updateUser.Administrator) || (updateUser.Locked != nil && *updateUser.Locked)) {
+		renderFailure(w, r.Request, http.StatusBadRequest, "administrators cannot revoke or lock themselves")
+		return
+	}
+	if updateUser.Name != nil {
+		u.Name = *updateUser.Name
+	}
+	if updateUser.Email != nil {
+		u.Email = *updateUser.Email
+	}
+	if updateUser.Password != nil {
+		if len(*updateUser.Password) == 0 {
+			renderFailure(w, r.Request, http.StatusBadRequest, "password is empty")
+			return
+		}
+		passwd, err := argon2id.CreateHash(*updateUser.Password, argon2id.DefaultParams)
+		if err != nil {
+			renderFailureFormat(w, r.Request, http.StatusInternalServerError, "gen salt password error: %v", err)
+			return
+		}
+		u.Password = passwd
+	}
+	if updateUser.Administrator != nil {
+		u.Administrator = *updateUser.Administrator
+	}
+	if updateUser.Locked != nil {
+		switch {
+		case !*updateUser.Locked:
+			u.LockedAt = time.Time{}
+		case u.LockedAt.IsZero():
+			u.LockedAt = time.Now()
+		}
+	}
+	if err := s.db.UpdateUser(r.Context(), u); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	u.Guard()
+	JsonEncode(w, u)
+}
+
+func (s *Server) DeleteUser(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	if u.ID == r.U.ID {
+		renderFailure(w, r.Request, http.StatusBadRequest, "administrators cannot delete themselves")
+		return
+	}
+	if err := s.db.DeleteUser(r.Context(), u.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type Keys struct {
+	Keys []*database.Key `json:"keys"`
+	Next int64           `json:"next,omitempty"` // cursor of the next page
+}
+
+func (s *Server) ListKeys(w http.ResponseWriter, r *Request) {
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	keys, next, err := s.db.ListKeys(r.Context(), u.ID, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Keys{Keys: keys, Next: next})
+}
+
+type NewKey struct {
+	UserName string `json:"username,omitempty"`
+	UID      int64  `json:"uid,omitempty"`
+	Title    string `json:"title"` // default: comment of the key
+	Content  string `json:"content"`
+}
+
+// parseKey: the key of the authorized_keys line
+func parseKey(w http.ResponseWriter, r *Request, newKey *NewKey, keyType database.KeyType) (*database.Key, bool) {
+	pk, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(newKey.Content))
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad public key: %v", err)
+		return nil, false
+	}
+	title := newKey.Title
+	if len(title) == 0 {
+		title = comment
+	}
+	if len(title) == 0 || len(title) > 255 {
+		renderFailure(w, r.Request, http.StatusBadRequest, "title is empty or longer than 255 bytes")
+		return nil, false
+	}
+	return &database.Key{
+		Content:     newKey.Content,
+		Title:       title,
+		Type:        keyType,
+		Fingerprint: ssh.FingerprintSHA256(pk),
+	}, true
+}
+
+func (s *Server) NewUserKey(w http.ResponseWriter, r *Request) {
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	var newKey NewKey
+	if !decodeBody(w, r, &newKey) {
+		return
+	}
+	k, ok := parseKey(w, r, &newKey, database.BasicKey)
+	if !ok {
+		return
+	}
+	k.UID = u.ID
+	k, err := s.db.AddKey(r.Context(), k)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, k)
+}
+
+// NewKey: add key of the user given by username or uid
+func (s *Server) NewKey(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var newKey NewKey
+	if !decodeBody(w, r, &newKey) {
+		return
+	}
+	var u *database.User
+	var err error
+	switch {
+	case len(newKey.UserName) != 0:
+		if u, err = s.db.SearchUser(r.Context(), newKey.UserName); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	case newKey.UID != 0:
+		if u, err = s.db.FindUser(r.Context(), newKey.UID); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	default:
+		renderFailure(w, r.Request, http.StatusBadRequest, "username or uid not given")
+		return
+	}
+	k, ok := parseKey(w, r, &newKey, database.BasicKey)
+	if !ok {
+		return
+	}
+	k.UID = u.ID
+	if k, err = s.db.AddKey(r.Context(), k); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, k)
+}
+
+func (s *Server) DeleteUserKey(w http.ResponseWriter, r *Request) {
+	u, ok := s.findUser(w, r)
+	if !ok {
+		return
+	}
+	id, ok := parseID(w, r.Request)
+	if !ok {
+		return
+	}
+	k, err := s.db.FindKey(r.Context(), id)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if k.UID != u.ID {
+		renderFailureFormat(w, r.Request, http.StatusNotFound, "key %d of user '%s' not found", id, u.UserName)
+		return
+	}
+	if err := s.db.DeleteKey(r.Context(), id); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,796 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/gorilla/mux"
+)
+
+// ownNamespace: the namespace of the path, only administrators and owners of the namespace are allowed
+func (s *Server) ownNamespace(w http.ResponseWriter, r *Request) bool {
+	n, err := s.db.FindNamespaceByPath(r.Context(), mux.Vars(r.Request)["namespace"])
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return false
+	}
+	return s.checkNamespaceOwner(w, r, n)
+}
+
+func (s *Server) checkNamespaceOwner(w http.ResponseWriter, r *Request, n *database.Namespace) bool {
+	if !r.U.Administrator && n.Owner != r.U.ID {
+		accessLevel := database.NoneAccess
+		if n.Type == database.GroupNamespace {
+			var err error
+			if accessLevel, err = s.db.GroupAccessLevel(r.Context(), n.ID, r.U); err != nil {
+				s.renderErrorRaw(w, r.Request, err)
+				return false
+			}
+		}
+		if accessLevel < database.OwnerAccess {
+			renderFailureFormat(w, r.Request, http.StatusForbidden, "access denied, owner of '%s' required, current user: %s", n.Path, r.U.UserName)
+			return false
+		}
+	}
+	r.N = n
+	return true
+}
+
+// ownRepo: the repository of the path, only administrators and owners of the repository or its namespace are allowed
+func (s *Server) ownRepo(w http.ResponseWriter, r *Request) bool {
+	mv := mux.Vars(r.Request)
+	n, repo, err := s.db.FindRepositoryByPath(r.Context(), mv["namespace"], mv["repo"])
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return false
+	}
+	if !r.U.Administrator && n.Owner != r.U.ID {
+		_, accessLevel, err := s.db.RepoAccessLevel(r.Context(), repo, r.U)
+		if err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return false
+		}
+		if accessLevel < database.OwnerAccess {
+			renderFailureFormat(w, r.Request, http.StatusForbidden, "access denied, owner of '%s/%s' required, current user: %s", n.Path, repo.Path, r.U.UserName)
+			return false
+		}
+	}
+	r.N, r.R, r.AccessLevel = n, repo, database.OwnerAccess
+	return true
+}
+
+// pushRepo: the user is allowed to update references of the repository, the access level of the user is kept so that
+// protection rules are checked as they are for pushes.
+func (s *Server) pushRepo(w http.ResponseWriter, r *Request) bool {
+	mv := mux.Vars(r.Request)
+	n, repo, err := s.db.FindRepositoryByPath(r.Context(), mv["namespace"], mv["repo"])
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return false
+	}
+	accessLevel := database.OwnerAccess
+	if n.Owner != r.U.ID {
+		if _, accessLevel, err = s.db.RepoAccessLevel(r.Context(), repo, r.U); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return false
+		}
+	}
+	if accessLevel < database.DevAccess {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, "access denied, developer of '%s/%s' required, current user: %s", n.Path, repo.Path, r.U.UserName)
+		return false
+	}
+	r.N, r.R, r.AccessLevel = n, repo, accessLevel
+	return true
+}
+
+type Namespaces struct {
+	Namespaces []*database.Namespace `json:"namespaces"`
+	Next       int64                 `json:"next,omitempty"` // cursor of the next page
+}
+
+// ListNamespaces: all namespaces for administrators, namespaces owned by the user for others
+func (s *Server) ListNamespaces(w http.ResponseWriter, r *Request) {
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	var owner int64
+	if !r.U.Administrator {
+		owner = r.U.ID
+	}
+	namespaces, next, err := s.db.ListNamespaces(r.Context(), owner, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Namespaces{Namespaces: namespaces, Next: next})
+}
+
+type NewNamespace struct {
+	Path        string `json:"path"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"` // username of the owner, default: current user
+}
+
+// NewNamespace: create a group, the owner becomes an owner member of the group.
+func (s *Server) NewNamespace(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	var newNamespace NewNamespace
+	if !decodeBody(w, r, &newNamespace) {
+		return
+	}
+	owner := r.U
+	if len(newNamespace.Owner) != 0 {
+		var ok bool
+		if owner, ok = s.findUserByName(w, r, newNamespace.Owner); !ok {
+			return
+		}
+	}
+	n, err := s.db.NewGroupNamespace(r.Context(), &database.Namespace{
+		Path:        newNamespace.Path,
+		Name:        newNamespace.Name,
+		Description: newNamespace.Description,
+		Owner:       owner.ID,
+	})
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if err := s.grantOwner(r, database.GroupMember, n.ID, owner.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, n)
+}
+
+// grantOwner: add the user as an owner member, the access level of existing members is raised.
+func (s *Server) grantOwner(r *Request, sourceType database.MemberType, sourceID int64, uid int64) error {
+	m := &database.Member{UID: uid, SourceID: sourceID, SourceType: sourceType, AccessLevel: database.OwnerAccess}
+	if err := s.db.UpdateMember(r.Context(), m); !database.IsNotFound(err) {
+		return err
+	}
+	return s.db.AddMember(r.Context(), m)
+}
+
+func (s *Server) GetNamespace(w http.ResponseWriter, r *Request) {
+	if !s.ownNamespace(w, r) {
+		return
+	}
+	JsonEncode(w, r.N)
+}
+
+// UpdateNamespace: fields not given are not changed
+type UpdateNamespace struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Owner       *string `json:"owner,omitempty"` // username of the new owner, groups only
+}
+
+func (s *Server) UpdateNamespace(w http.ResponseWriter, r *Request) {
+	if !s.ownNamespace(w, r) {
+		return
+	}
+	var updateNamespace UpdateNamespace
+	if !decodeBody(w, r, &updateNamespace) {
+		return
+	}
+	n := r.N
+	if updateNamespace.Name != nil {
+		n.Name = *updateNamespace.Name
+	}
+	if updateNamespace.Description != nil {
+		n.Description = *updateNamespace.Description
+	}
+	if updateNamespace.Owner != nil {
+		if n.Type != database.GroupNamespace {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' is a personal namespace, the owner cannot be changed", n.Path)
+			return
+		}
+		owner, ok := s.findUserByName(w, r, *updateNamespace.Owner)
+		if !ok {
+			return
+		}
+		n.Owner = owner.ID
+	}
+	if err := s.db.UpdateNamespace(r.Context(), n); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if updateNamespace.Owner != nil {
+		if err := s.grantOwner(r, database.GroupMember, n.ID, n.Owner); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	}
+	JsonEncode(w, n)
+}
+
+func (s *Server) DeleteNamespace(w http.ResponseWriter, r *Request) {
+	if !s.ownNamespace(w, r) {
+		return
+	}
+	if r.N.Type != database.GroupNamespace {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' is a personal namespace, it is removed with the user", r.N.Path)
+		return
+	}
+	if err := s.db.DeleteNamespace(r.Context(), r.N.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type Members struct {
+	Members []*database.Member `json:"members"`
+	Next    int64              `json:"next,omitempty"` // cursor of the next page
+}
+
+func (s *Server) listMembers(w http.ResponseWriter, r *Request, sourceType database.MemberType, sourceID int64) {
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	members, next, err := s.db.ListMembers(r.Context(), sourceType, sourceID, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Members{Members: members, Next: next})
+}
+
+type SetMember struct {
+	AccessLevel database.AccessLevel `json:"access_level"` // 20: reporter, 30: developer, 40: master, 50: owner
+	ExpiresAt   time.Time            `json:"expires_at,omitempty"`
+}
+
+// setMember: add the member or change its access level
+func (s *Server) setMember(w http.ResponseWriter, r *Request, sourceType database.MemberType, sourceID int64) {
+	u, ok := s.findUserByName(w, r, mux.Vars(r.Request)["username"])
+	if !ok {
+		return
+	}
+	var setMember SetMember
+	if !decodeBody(w, r, &setMember) {
+		return
+	}
+	m := &database.Member{UID: u.ID, SourceID: sourceID, SourceType: sourceType, AccessLevel: setMember.AccessLevel, ExpiresAt: setMember.ExpiresAt}
+	err := s.db.UpdateMember(r.Context(), m)
+	if database.IsNotFound(err) {
+		err = s.db.AddMember(r.Context(), m)
+	}
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	m.UserName = u.UserName
+	JsonEncode(w, m)
+}
+
+func (s *Server) removeMember(w http.ResponseWriter, r *Request, sourceType database.MemberType, sourceID int64) {
+	u, ok := s.findUserByName(w, r, mux.Vars(r.Request)["username"])
+	if !ok {
+		return
+	}
+	if sourceType == database.GroupMember && r.N.Owner == u.ID {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' is the owner of '%s', transfer the group first", u.UserName, r.N.Path)
+		return
+	}
+	if err := s.db.RemoveMember(r.Context(), sourceType, sourceID, u.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownGroup: ownNamespace of groups, personal namespaces have no members
+func (s *Server) ownGroup(w http.ResponseWriter, r *Request) bool {
+	if !s.ownNamespace(w, r) {
+		return false
+	}
+	if r.N.Type != database.GroupNamespace {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' is not a group", r.N.Path)
+		return false
+	}
+	return true
+}
+
+func (s *Server) ListNamespaceMembers(w http.ResponseWriter, r *Request) {
+	if s.ownGroup(w, r) {
+		s.listMembers(w, r, database.GroupMember, r.N.ID)
+	}
+}
+
+func (s *Server) SetNamespaceMember(w http.ResponseWriter, r *Request) {
+	if s.ownGroup(w, r) {
+		s.setMember(w, r, database.GroupMember, r.N.ID)
+	}
+}
+
+func (s *Server) RemoveNamespaceMember(w http.ResponseWriter, r *Request) {
+	if s.ownGroup(w, r) {
+		s.removeMember(w, r, database.GroupMember, r.N.ID)
+	}
+}
+
+type Repositories struct {
+	Repositories []*database.Repository `json:"repositories"`
+	Next         int64                  `json:"next,omitempty"` // cursor of the next page
+}
+
+func (s *Server) ListRepos(w http.ResponseWriter, r *Request) {
+	if !s.ownNamespace(w, r) {
+		return
+	}
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	repos, next, err := s.db.ListRepositories(r.Context(), r.N.ID, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Repositories{Repositories: repos, Next: next})
+}
+
+type NewRepo struct {
+	Name          string `json:"name,omitempty"`
+	Path          string `json:"path"`
+	Description   string `json:"description"`
+	VisibleLevel  int    `json:"visible_level,omitempty"`
+	DefaultBranch string `json:"default_branch,omitempty"` // current branch
+	UserName      string `json:"username,omitempty"`       // owner of the repository, default: current user
+	UID           int64  `json:"uid,omitempty"`
+	NamespacePath string `json:"namespace_path,omitempty"`
+	NamespaceID   int64  `json:"namespace_id,omitempty"`
+	Empty         bool   `json:"empty,omitempty"`
+}
+
+// NewRepo: create a repository in the namespace owned by the current user, administrators may create repositories
+// owned by others.
+func (s *Server) NewRepo(w http.ResponseWriter, r *Request) {
+	var newRepo NewRepo
+	if !decodeBody(w, r, &newRepo) {
+		return
+	}
+	u := r.U
+	var err error
+	switch {
+	case len(newRepo.UserName) != 0 && newRepo.UserName != r.U.UserName:
+		if !requireAdmin(w, r) {
+			return
+		}
+		if u, err = s.db.SearchUser(r.Context(), newRepo.UserName); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	case newRepo.UID != 0 && newRepo.UID != r.U.ID:
+		if !requireAdmin(w, r) {
+			return
+		}
+		if u, err = s.db.FindUser(r.Context(), newRepo.UID); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	}
+	var n *database.Namespace
+	switch {
+	case len(newRepo.NamespacePath) != 0:
+		n, err = s.db.FindNamespaceByPath(r.Context(), newRepo.NamespacePath)
+	case newRepo.NamespaceID != 0:
+		n, err = s.db.FindNamespaceByID(r.Context(), newRepo.NamespaceID)
+	default:
+		renderFailure(w, r.Request, http.StatusBadRequest, "namespace_path or namespace_id not given")
+		return
+	}
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if !s.checkNamespaceOwner(w, r, n) {
+		return
+	}
+	repo, err := s.hub.New(r.Context(), &database.Repository{
+		NamespaceID:   n.ID,
+		Name:          newRepo.Name,
+		Path:          newRepo.Path,
+		Description:   newRepo.Description,
+		VisibleLevel:  newRepo.VisibleLevel,
+		DefaultBranch: newRepo.DefaultBranch,
+	}, u, newRepo.Empty)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, repo)
+}
+
+func (s *Server) GetRepo(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	JsonEncode(w, r.R)
+}
+
+// UpdateRepo: fields not given are not changed
+type UpdateRepo struct {
+	Name          *string `json:"name,omitempty"`
+	Description   *string `json:"description,omitempty"`
+	VisibleLevel  *int    `json:"visible_level,omitempty"`
+	DefaultBranch *string `json:"default_branch,omitempty"` // the branch must exist
+}
+
+func (s *Server) UpdateRepo(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	var updateRepo UpdateRepo
+	if !decodeBody(w, r, &updateRepo) {
+		return
+	}
+	repo := r.R
+	if updateRepo.Name != nil {
+		repo.Name = *updateRepo.Name
+	}
+	if updateRepo.Description != nil {
+		repo.Description = *updateRepo.Description
+	}
+	if updateRepo.VisibleLevel != nil {
+		repo.VisibleLevel = *updateRepo.VisibleLevel
+	}
+	if updateRepo.DefaultBranch != nil && *updateRepo.DefaultBranch != repo.DefaultBranch {
+		if _, err := s.db.FindBranch(r.Context(), repo.ID, *updateRepo.DefaultBranch); err != nil {
+			if database.IsNotFound(err) {
+				renderFailureFormat(w, r.Request, http.StatusBadRequest, "branch '%s' not found", *updateRepo.DefaultBranch)
+				return
+			}
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+		repo.DefaultBranch = *updateRepo.DefaultBranch
+	}
+	if err := s.db.UpdateRepository(r.Context(), repo); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, repo)
+}
+
+func (s *Server) DeleteRepo(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	if err := s.hub.Delete(r.Context(), r.R.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListRepoMembers(w http.ResponseWriter, r *Request) {
+	if s.ownRepo(w, r) {
+		s.listMembers(w, r, database.ProjectMember, r.R.ID)
+	}
+}
+
+func (s *Server) SetRepoMember(w http.ResponseWriter, r *Request) {
+	if s.ownRepo(w, r) {
+		s.setMember(w, r, database.ProjectMember, r.R.ID)
+	}
+}
+
+func (s *Server) RemoveRepoMember(w http.ResponseWriter, r *Request) {
+	if s.ownRepo(w, r) {
+		s.removeMember(w, r, database.ProjectMember, r.R.ID)
+	}
+}
+
+func (s *Server) ListDeployKeys(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	keys, err := s.db.ListDeployKeys(r.Context(), r.R.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &Keys{Keys: keys})
+}
+
+// NewDeployKey: enable the deploy key for the repository, the key is added when it does not exist yet.
+func (s *Server) NewDeployKey(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	var newKey NewKey
+	if !decodeBody(w, r, &newKey) {
+		return
+	}
+	k, ok := parseKey(w, r, &newKey, database.DeployKey)
+	if !ok {
+		return
+	}
+	existing, err := s.db.SearchKey(r.Context(), k.Fingerprint)
+	switch {
+	case err == nil:
+		if existing.Type != database.DeployKey {
+			renderFailure(w, r.Request, http.StatusConflict, "key is already used by a user")
+			return
+		}
+		k = existing
+	case database.IsNotFound(err):
+		k.UID = r.U.ID
+		if k, err = s.db.AddKey(r.Context(), k); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	default:
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if err := s.db.EnableDeployKey(r.Context(), r.R.ID, k.ID); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, k)
+}
+
+func (s *Server) DeleteDeployKey(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	id, ok := parseID(w, r.Request)
+	if !ok {
+		return
+	}
+	if err := s.db.DisableDeployKey(r.Context(), r.R.ID, id); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReferenceInfo: branch or tag with the protection applied to it
+type ReferenceInfo struct {
+	*database.Reference
+	Default    bool                     `json:"default,omitempty"`    // the default branch of the repository
+	Protection *database.ProtectionRule `json:"protection,omitempty"` // merged rules matching the reference
+}
+
+type References struct {
+	References []*ReferenceInfo `json:"references"`
+	Next       string           `json:"next,omitempty"` // cursor of the next page
+}
+
+// listReferences: references of the prefix paged by ?cursor=<next>&limit=<n>, ?prefix= filters the names.
+func (s *Server) listReferences(w http.ResponseWriter, r *Request, refPrefix string) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	var limit int
+	if v := q.Get("limit"); len(v) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > database.MaxListLimit {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad limit '%s', range: 1-%d", v, database.MaxListLimit)
+			return
+		}
+	}
+	var cursor string
+	if v := q.Get("cursor"); len(v) != 0 {
+		cursor = refPrefix + v
+	}
+	rules, err := s.db.ListProtectionRules(r.Context(), r.R.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	refs, next, err := s.db.ListReferences(r.Context(), r.R.ID, refPrefix+q.Get("prefix"), cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	items := make([]*ReferenceInfo, 0, len(refs))
+	for _, ref := range refs {
+		items = append(items, &ReferenceInfo{
+			Reference:  ref,
+			Default:    ref.Name == plumbing.NewBranchReferenceName(r.R.DefaultBranch),
+			Protection: database.MatchProtectionRules(rules, ref.Name),
+		})
+	}
+	JsonEncode(w, &References{References: items, Next: strings.TrimPrefix(next, refPrefix)})
+}
+
+// reference: the branch or tag of the path with the protection applied to it
+func (s *Server) reference(w http.ResponseWriter, r *Request, refname plumbing.ReferenceName) (*ReferenceInfo, bool) {
+	if !s.ownRepo(w, r) {
+		return nil, false
+	}
+	ref := &database.Reference{Name: refname, RID: r.R.ID}
+	if refname.IsBranch() {
+		b, err := s.db.FindBranch(r.Context(), r.R.ID, refname.BranchName())
+		if err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return nil, false
+		}
+		ref.ID, ref.Hash, ref.ProtectionLevel, ref.CreatedAt, ref.UpdatedAt = b.ID, b.Hash, b.ProtectionLevel, b.CreatedAt, b.UpdatedAt
+	} else {
+		t, err := s.db.FindTag(r.Context(), r.R.ID, refname.TagName())
+		if err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return nil, false
+		}
+		ref.Hash, ref.CreatedAt, ref.UpdatedAt = t.Hash, t.CreatedAt, t.UpdatedAt
+	}
+	rules, err := s.db.ListProtectionRules(r.Context(), r.R.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return nil, false
+	}
+	return &ReferenceInfo{
+		Reference:  ref,
+		Default:    refname == plumbing.NewBranchReferenceName(r.R.DefaultBranch),
+		Protection: database.MatchProtectionRules(rules, refname),
+	}, true
+}
+
+func refnameFromPath(w http.ResponseWriter, r *Request, name string, fn func(string) plumbing.ReferenceName) (plumbing.ReferenceName, bool) {
+	unescaped, err := url.PathUnescape(mux.Vars(r.Request)[name])
+	if err != nil || len(unescaped) == 0 {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad %s name '%s'", name, mux.Vars(r.Request)[name])
+		return "", false
+	}
+	return fn(unescaped), true
+}
+
+func (s *Server) getReference(w http.ResponseWriter, r *Request, name string, fn func(string) plumbing.ReferenceName) {
+	refname, ok := refnameFromPath(w, r, name, fn)
+	if !ok {
+		return
+	}
+	if ref, ok := s.reference(w, r, refname); ok {
+		JsonEncode(w, ref)
+	}
+}
+
+// deleteReference: remove the branch or tag, the default branch and references protected from deletion are refused.
+func (s *Server) deleteReference(w http.ResponseWriter, r *Request, name string, fn func(string) plumbing.ReferenceName) {
+	refname, ok := refnameFromPath(w, r, name, fn)
+	if !ok {
+		return
+	}
+	ref, ok := s.reference(w, r, refname)
+	if !ok {
+		return
+	}
+	if ref.Default {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' is the default branch, it cannot be deleted", refname.BranchName())
+		return
+	}
+	if ref.Protection != nil && ref.Protection.DeleteAccessLevel > r.AccessLevel {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, "'%s' is protected by '%s', deletion is not allowed", refname, ref.Protection.Pattern)
+		return
+	}
+	if _, err := s.db.DoReferenceUpdate(r.Context(), &database.Command{
+		ReferenceName: refname,
+		OldRev:        ref.Hash,
+		NewRev:        plumbing.ZERO_OID,
+		RID:           r.R.ID,
+		UID:           r.U.ID,
+		Protocol:      "api",
+		ClientIP:      parseRemoteAddress(r.Request),
+	}); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListBranches(w http.ResponseWriter, r *Request) {
+	s.listReferences(w, r, plumbing.ReferencePrefix+"heads/")
+}
+
+func (s *Server) GetBranch(w http.ResponseWriter, r *Request) {
+	s.getReference(w, r, "branch", plumbing.NewBranchReferenceName)
+}
+
+func (s *Server) DeleteBranch(w http.ResponseWriter, r *Request) {
+	s.deleteReference(w, r, "branch", plumbing.NewBranchReferenceName)
+}
+
+func (s *Server) ListTags(w http.ResponseWriter, r *Request) {
+	s.listReferences(w, r, plumbing.ReferencePrefix+"tags/")
+}
+
+func (s *Server) GetTag(w http.ResponseWriter, r *Request) {
+	s.getReference(w, r, "tag", plumbing.NewTagReferenceName)
+}
+
+func (s *Server) DeleteTag(w http.ResponseWriter, r *Request) {
+	s.deleteReference(w, r, "tag", plumbing.NewTagReferenceName)
+}
+
+func (s *Server) ListRepoProtections(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	rules, err := s.db.ListProtectionRules(r.Context(), r.R.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, rules)
+}
+
+func (s *Server) NewRepoProtection(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	var newProtection NewProtection
+	if !decodeBody(w, r, &newProtection) {
+		return
+	}
+	p, err := s.db.NewProtectionRule(r.Context(), newProtection.rule(r.R.ID))
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, p)
+}
+
+// UpdateRepoProtection: replace the rule, settings not given are reset to defaults.
+func (s *Server) UpdateRepoProtection(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	id, ok := parseID(w, r.Request)
+	if !ok {
+		return
+	}
+	var newProtection NewProtection
+	if !decodeBody(w, r, &newProtection) {
+		return
+	}
+	p := newProtection.rule(r.R.ID)
+	p.ID = id
+	if err := s.db.UpdateProtectionRule(r.Context(), p); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	rules, err := s.db.ListProtectionRules(r.Context(), r.R.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if i := slices.IndexFunc(rules, func(rule *database.ProtectionRule) bool { return rule.ID == id }); i != -1 {
+		p = rules[i]
+	}
+	JsonEncode(w, p)
+}
+
+func (s *Server) DeleteRepoProtection(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	id, ok := parseID(w, r.Request)
+	if !ok {
+		return
+	}
+	if err := s.db.DeleteProtectionRule(r.Context(), r.R.ID, id); err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
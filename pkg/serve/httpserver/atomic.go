@@ -0,0 +1,171 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+)
+
+// currentRev: current rev of the reference, ZERO_OID if the reference does not exist. Protection rules are
+// checked for branches, returns the reason if the reference cannot be updated.
+func (s *Server) currentRev(r *Request, refname plumbing.ReferenceName) (string, string, error) {
+	if refname.IsBranch() {
+		branch, err := s.branchCanUpdate(r.Context(), r, refname.BranchName())
+		if err != nil {
+			if e, ok := err.(*zeta.ErrStatusCode); ok {
+				return "", e.Message, nil
+			}
+			return "", "", err
+		}
+		if branch == nil {
+			return plumbing.ZERO_OID, "", nil
+		}
+		return branch.Hash, "", nil
+	}
+	tag, err := s.db.FindTag(r.Context(), r.R.ID, refname.TagName())
+	if err != nil && !database.IsErrRevisionNotFound(err) {
+		return "", "", err
+	}
+	if tag == nil {
+		return plumbing.ZERO_OID, "", nil
+	}
+	return tag.Hash, "", nil
+}
+
+// checkAtomicCommand: protection rules and the old rev of each reference are checked before objects are received,
+// returns the reason if the reference cannot be updated.
+func (s *Server) checkAtomicCommand(r *Request, cmd *repo.Command) (string, error) {
+	rev, reason, err := s.currentRev(r, cmd.ReferenceName)
+	if err != nil || len(reason) != 0 {
+		return reason, err
+	}
+	if rev == cmd.OldRev {
+		return s.checkProtection(r, cmd)
+	}
+	if cmd.ReferenceName.IsBranch() {
+		return r.W("branch is updated, please update and try again"), nil
+	}
+	return r.W("tag is updated, please update and try again"), nil
+}
+
+// POST /{namespace}/{repo}/references
+//
+// Atomic push: the body starts with pktline commands '<old-rev> <new-rev> <refname>' ended by a flush-pkt,
+// followed by the objects of all references.
+func (s *Server) AtomicPush(w http.ResponseWriter, r *Request) {
+	cmds, err := repo.ReadAtomicCommands(r.Body)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "read commands error: %v", err)
+		return
+	}
+	pushOptions, ok := s.parsePushOptions(w, r)
+	if !ok {
+		return
+	}
+	for _, cmd := range cmds {
+		cmd.RID = r.R.ID
+		cmd.UID = r.U.ID
+		cmd.KeyID = r.KID
+		cmd.Terminal = r.Header.Get(ZETA_TERMINAL)
+		cmd.Language = serve.Language(r.Request)
+		cmd.Protocol = "http"
+		cmd.ClientIP = parseRemoteAddress(r.Request)
+		cmd.PushOptions = pushOptions
+		cmd.UpdateStats(r.Header.Get(ZETA_OBJECTS_STATS))
+	}
+	w.Header().Set("Content-Type", ZETA_MIME_REPORT_RESULT)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, cmd := range cmds {
+		reason, err := s.checkAtomicCommand(r, cmd)
+		if err != nil {
+			renderFailureFormat(w, r.Request, http.StatusInternalServerError, r.W("internal server error: %v"), err)
+			return
+		}
+		if len(reason) != 0 {
+			repo.ReportAtomicRejected(w, cmds, cmd, "%s", reason)
+			return
+		}
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	defer rr.Close()
+	if err = rr.DoAtomicPush(r.Context(), cmds, r.Body, w); err != nil {
+		if es, ok := err.(*zeta.ErrStatusCode); ok {
+			renderFailure(w, r.Request, es.Code, es.Message)
+		}
+		return
+	}
+}
+
+// POST /{namespace}/{repo}/bundle
+//
+// Bundle push: the body is a bundle file, all references of the bundle are updated atomically. The old revs are
+// recorded in the bundle, non-fast-forward updates are rejected without 'force=1'.
+func (s *Server) BundlePush(w http.ResponseWriter, r *Request) {
+	limit := s.MaxBundleSize.Size
+	if r.ContentLength > limit {
+		renderFailureFormat(w, r.Request, http.StatusRequestEntityTooLarge, "bad bundle: %v", &repo.ErrBundleTooLarge{Limit: limit})
+		return
+	}
+	b, err := repo.ReceiveBundle(r.Context(), r.Body, limit)
+	if repo.IsErrBundleTooLarge(err) {
+		renderFailureFormat(w, r.Request, http.StatusRequestEntityTooLarge, "bad bundle: %v", err)
+		return
+	}
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad bundle: %v", err)
+		return
+	}
+	defer b.Close()
+	cmds, err := b.Commands(r.URL.Query().Get(Force) == "1")
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad bundle: %v", err)
+		return
+	}
+	for _, cmd := range cmds {
+		cmd.RID = r.R.ID
+		cmd.UID = r.U.ID
+		cmd.KeyID = r.KID
+		cmd.Terminal = r.Header.Get(ZETA_TERMINAL)
+		cmd.Language = serve.Language(r.Request)
+		cmd.Protocol = "http"
+		cmd.ClientIP = parseRemoteAddress(r.Request)
+	}
+	w.Header().Set("Content-Type", ZETA_MIME_REPORT_RESULT)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, cmd := range cmds {
+		reason, err := s.checkAtomicCommand(r, cmd)
+		if err != nil {
+			renderFailureFormat(w, r.Request, http.StatusInternalServerError, r.W("internal server error: %v"), err)
+			return
+		}
+		if len(reason) != 0 {
+			repo.ReportAtomicRejected(w, cmds, cmd, "%s", reason)
+			return
+		}
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	defer rr.Close()
+	reader := b.NewReader(r.Context())
+	defer reader.Close()
+	if err = rr.DoAtomicPush(r.Context(), cmds, reader, w); err != nil {
+		if es, ok := err.(*zeta.ErrStatusCode); ok {
+			renderFailure(w, r.Request, es.Code, es.Message)
+		}
+		return
+	}
+}
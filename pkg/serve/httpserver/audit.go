@@ -0,0 +1,195 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
+	"github.com/gorilla/mux"
+)
+
+type AuditEvents struct {
+	Events []*database.AuditEvent `json:"events"`
+	Next   int64                  `json:"next,omitempty"` // cursor of the next page
+}
+
+// parseAuditFilter: ?ref=&username=&action=&since=&until=, times are RFC3339, ref is a full reference name or a branch name
+func (s *Server) parseAuditFilter(w http.ResponseWriter, r *Request) (*database.AuditFilter, bool) {
+	q := r.URL.Query()
+	f := &database.AuditFilter{Action: q.Get("action")}
+	if ref := q.Get("ref"); len(ref) != 0 {
+		if !strings.HasPrefix(ref, plumbing.ReferencePrefix) {
+			ref = string(plumbing.NewBranchReferenceName(ref))
+		}
+		f.ReferenceName = plumbing.ReferenceName(ref)
+	}
+	if userName := q.Get("username"); len(userName) != 0 {
+		u, ok := s.findUserByName(w, r, userName)
+		if !ok {
+			return nil, false
+		}
+		f.UID = u.ID
+	}
+	for _, t := range []struct {
+		name string
+		v    *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		v := q.Get(t.name)
+		if len(v) == 0 {
+			continue
+		}
+		var err error
+		if *t.v, err = time.Parse(time.RFC3339, v); err != nil {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad %s '%s', RFC3339 time required", t.name, v)
+			return nil, false
+		}
+	}
+	return f, true
+}
+
+func (s *Server) listAuditEvents(w http.ResponseWriter, r *Request, f *database.AuditFilter) {
+	cursor, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	events, next, err := s.db.ListAuditEvents(r.Context(), f, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	JsonEncode(w, &AuditEvents{Events: events, Next: next})
+}
+
+// ListAuditEvents: the audit log of all repositories and actions, administrators only. ?namespace=&repo= or ?rid=
+// filters the repository.
+func (s *Server) ListAuditEvents(w http.ResponseWriter, r *Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	f, ok := s.parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case len(q.Get("namespace")) != 0 || len(q.Get("repo")) != 0:
+		_, repo, err := s.db.FindRepositoryByPath(r.Context(), q.Get("namespace"), q.Get("repo"))
+		if err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+		f.RID = repo.ID
+	case len(q.Get("rid")) != 0:
+		rid, err := strconv.ParseInt(q.Get("rid"), 10, 64)
+		if err != nil || rid <= 0 {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad rid '%s'", q.Get("rid"))
+			return
+		}
+		f.RID = rid
+	}
+	s.listAuditEvents(w, r, f)
+}
+
+// ListRefLog: updates of branches and tags of the repository, newest first
+func (s *Server) ListRefLog(w http.ResponseWriter, r *Request) {
+	if !s.ownRepo(w, r) {
+		return
+	}
+	f, ok := s.parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+	f.RID, f.Action = r.R.ID, "reference."
+	s.listAuditEvents(w, r, f)
+}
+
+// RestoreReference: reset the reference to the old rev of the ref log entry, which undoes the update of the entry.
+// The reference is recreated if it has been deleted since. The restore is checked like a push with the access level
+// of the user: protection rules (force push included when the old rev is not a descendant), path ACL and hooks.
+func (s *Server) RestoreReference(w http.ResponseWriter, r *Request) {
+	if !s.pushRepo(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r.Request)["id"], 10, 64)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad id '%s'", mux.Vars(r.Request)["id"])
+		return
+	}
+	e, err := s.db.FindAuditEvent(r.Context(), id)
+	if err != nil && !database.IsNotFound(err) {
+		s.renderErrorRaw(w, r.Request, err)
+		return
+	}
+	if e == nil || e.RID != r.R.ID || !strings.HasPrefix(e.Action, "reference.") {
+		renderFailureFormat(w, r.Request, http.StatusNotFound, "ref log entry '%d' not found", id)
+		return
+	}
+	if e.OldRev == plumbing.ZERO_OID {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' was created by the entry, delete it instead", e.ReferenceName)
+		return
+	}
+	current := plumbing.ZERO_OID
+	if e.ReferenceName.IsBranch() {
+		b, err := s.checkBranchCanUpdate(r.Context(), w, r, e.ReferenceName.BranchName())
+		if err != nil {
+			return
+		}
+		if b != nil {
+			current = b.Hash
+		}
+	} else {
+		t, err := s.db.FindTag(r.Context(), r.R.ID, e.ReferenceName.TagName())
+		if err != nil && !database.IsNotFound(err) {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+		if err == nil {
+			current = t.Hash
+		}
+	}
+	if current != e.OldRev {
+		cmd := &repo.Command{
+			RID:           r.R.ID,
+			UID:           r.U.ID,
+			KeyID:         r.KID,
+			ReferenceName: e.ReferenceName,
+			OldRev:        current,
+			NewRev:        e.OldRev,
+			Language:      serve.Language(r.Request),
+			Protocol:      "api",
+			ClientIP:      parseRemoteAddress(r.Request),
+		}
+		if !s.renderProtection(w, r, cmd) {
+			return
+		}
+		rr, err := s.open(w, r)
+		if err != nil {
+			return
+		}
+		defer rr.Close()
+		// the reference must not point to objects missing from the repository
+		if _, err := rr.ODB().Objects(r.Context(), plumbing.NewHash(e.OldRev)); err != nil {
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+		if err := rr.DoRestore(r.Context(), cmd); err != nil {
+			if repo.IsErrRejected(err) {
+				renderFailure(w, r.Request, http.StatusForbidden, err.Error())
+				return
+			}
+			s.renderErrorRaw(w, r.Request, err)
+			return
+		}
+	}
+	if ref, ok := s.reference(w, r, e.ReferenceName); ok {
+		JsonEncode(w, ref)
+	}
+}
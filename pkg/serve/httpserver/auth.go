@@ -9,11 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/antgroup/hugescm/pkg/serve/argon2id"
 	"github.com/antgroup/hugescm/pkg/serve/database"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/ratelimit"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -77,7 +79,8 @@ var (
 	}
 )
 
-func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation protocol.Operation, cred string) (*Request, error) {
+// authenticate: the user of basic auth credential, the password is verified and the user must not be locked
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, cred string) (*database.User, error) {
 	user, password, ok := parseBasicAuth(cred)
 	if !ok {
 		renderFailure(w, r, http.StatusUnauthorized, "missing credential")
@@ -112,6 +115,14 @@ func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation pro
 	}
 	// cleanup
 	u.Guard()
+	return u, nil
+}
+
+func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation protocol.Operation, cred string) (*Request, error) {
+	u, err := s.authenticate(w, r, cred)
+	if err != nil {
+		return nil, err
+	}
 	mv := mux.Vars(r)
 	namespacePath, repoPath := mv["namespace"], mv["repo"]
 	ns, repo, err := s.db.FindRepositoryByPath(r.Context(), namespacePath, repoPath)
@@ -123,14 +134,16 @@ func (s *Server) basicAuth(w http.ResponseWriter, r *http.Request, operation pro
 		renderFailureFormat(w, r, http.StatusInternalServerError, "search repo '%s/%s' error: %v", namespacePath, repoPath, err)
 		return nil, ErrStop
 	}
-	if _, err = s.checkAccess(w, r, operation, repo, u); err != nil {
+	accessLevel, err := s.checkAccess(w, r, operation, repo, u)
+	if err != nil {
 		return nil, err
 	}
 	return &Request{
-		Request: r,
-		U:       u,
-		N:       ns,
-		R:       repo,
+		Request:     r,
+		U:           u,
+		N:           ns,
+		R:           repo,
+		AccessLevel: accessLevel,
 	}, nil
 }
 
@@ -159,27 +172,66 @@ func (s *Server) doAuth(w http.ResponseWriter, r *http.Request, operation protoc
 		renderFailureFormat(w, r, http.StatusInternalServerError, "search repo '%s/%s' error: %v", namespacePath, repoPath, err)
 		return nil, ErrStop
 	}
-	if _, err = s.checkAccess(w, r, operation, repo, u); err != nil {
+	accessLevel, err := s.checkAccess(w, r, operation, repo, u)
+	if err != nil {
 		return nil, err
 	}
 	return &Request{
-		Request: r,
-		U:       u,
-		N:       ns,
-		R:       repo,
+		Request:     r,
+		U:           u,
+		N:           ns,
+		R:           repo,
+		AccessLevel: accessLevel,
+		KID:         m.KID,
 	}, nil
 }
 
 func (s *Server) OnFunc(fn HandlerFunc, operation protocol.Operation) http.HandlerFunc {
+	return s.onFunc(fn, operation, false)
+}
+
+// OnHeavyFunc: OnFunc of heavy operations (metadata fetch, batch objects, push), they are also limited by the
+// concurrency quotas.
+func (s *Server) OnHeavyFunc(fn HandlerFunc, operation protocol.Operation) http.HandlerFunc {
+	return s.onFunc(fn, operation, true)
+}
+
+func (s *Server) onFunc(fn HandlerFunc, operation protocol.Operation, heavy bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		req, err := s.doAuth(w, r, operation)
 		if err != nil {
 			return
 		}
+		release, err := s.limiter.Enter(limitKeys(req), heavy)
+		if err != nil {
+			renderLimited(w, r, err)
+			return
+		}
+		defer release()
 		fn(w, req)
 	}
 }
 
+func limitKeys(r *Request) []ratelimit.Key {
+	keys := []ratelimit.Key{
+		{Kind: ratelimit.IP, ID: parseRemoteAddress(r.Request)},
+		ratelimit.NewKey(ratelimit.Repository, r.R.ID),
+	}
+	if r.U != nil && r.U.ID != 0 {
+		keys = append(keys, ratelimit.NewKey(ratelimit.User, r.U.ID))
+	}
+	return keys
+}
+
+// renderLimited: 429 with Retry-After, clients retry after that
+func renderLimited(w http.ResponseWriter, r *http.Request, err error) {
+	var e *ratelimit.ErrLimited
+	if errors.As(err, &e) {
+		w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfterSeconds()))
+	}
+	renderFailure(w, r, http.StatusTooManyRequests, err.Error())
+}
+
 func checkRepoReadable(u *database.User, repo *database.Repository, accessLevel database.AccessLevel) bool {
 	if accessLevel.Readable() {
 		return true
@@ -21,6 +21,7 @@ const (
 type BearerMD struct {
 	UID                  int64              `json:"uid,string"`
 	RID                  int64              `json:"rid,string"`
+	KID                  int64              `json:"kid,string,omitempty"` // key of the credential, recorded in the audit log
 	Operation            protocol.Operation `json:"operation"`
 	jwt.RegisteredClaims                    // v5 new
 }
@@ -38,11 +39,12 @@ func (t *BearerMD) Match(op protocol.Operation) bool {
 	return false
 }
 
-func GenerateJWT(u *database.User, rid int64, op protocol.Operation, expiresAt time.Time) (string, error) {
+func GenerateJWT(u *database.User, kid int64, rid int64, op protocol.Operation, expiresAt time.Time) (string, error) {
 	now := time.Now()
 	claims := BearerMD{
 		UID:       u.ID,
 		RID:       rid,
+		KID:       kid,
 		Operation: op,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt), // expiresAt
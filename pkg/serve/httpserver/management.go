@@ -3,182 +3,406 @@
 
 package httpserver
 
-// WARING: The management API is mainly used for testing and adding users. Do not use it in a production environment.
-
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/antgroup/hugescm/modules/strengthen"
-	"github.com/antgroup/hugescm/pkg/serve/argon2id"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/gorilla/mux"
-	"golang.org/x/crypto/ssh"
 )
 
-type NewUser struct {
-	UserName      string `json:"username"`
-	Name          string `json:"name,omitempty"`
-	Administrator bool   `json:"administrator"`
-	Email         string `json:"email"`
-	Password      string `json:"password"`
+type NewWebhook struct {
+	NamespacePath string   `json:"namespace_path"`
+	RepoPath      string   `json:"repo_path,omitempty"` // namespace webhook when empty
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret,omitempty"`
+	Events        []string `json:"events,omitempty"` // all events when empty
+	Disabled      bool     `json:"disabled,omitempty"`
 }
 
-func (s *Server) NewUser(w http.ResponseWriter, r *http.Request) {
-	var newUser NewUser
-	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil {
-		fmt.Println(err)
+// webhookSource: the repository or namespace of the webhook
+func (s *Server) webhookSource(ctx context.Context, namespacePath, repoPath string) (database.MemberType, int64, error) {
+	if len(repoPath) != 0 {
+		_, repo, err := s.db.FindRepositoryByPath(ctx, namespacePath, repoPath)
+		if err != nil {
+			return 0, 0, err
+		}
+		return database.ProjectMember, repo.ID, nil
+	}
+	n, err := s.db.FindNamespaceByPath(ctx, namespacePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	return database.GroupMember, n.ID, nil
+}
+
+func (s *Server) NewWebhook(w http.ResponseWriter, r *http.Request) {
+	var newWebhook NewWebhook
+	if err := json.NewDecoder(r.Body).Decode(&newWebhook); err != nil {
 		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
 		return
 	}
-	if len(newUser.UserName) == 0 || len(newUser.Password) == 0 {
-		renderFailure(w, r, http.StatusBadRequest, "username or password is empty")
+	if len(newWebhook.NamespacePath) == 0 {
+		renderFailure(w, r, http.StatusBadRequest, "namespace_path not given")
 		return
 	}
-	if len(newUser.Name) == 0 {
-		newUser.Name = newUser.UserName
+	if u, err := url.Parse(newWebhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad webhook url '%s'", newWebhook.URL)
+		return
 	}
-	passwd, err := argon2id.CreateHash(newUser.Password, argon2id.DefaultParams)
+	for _, event := range newWebhook.Events {
+		if !slices.Contains(repo.WebhookEvents, event) {
+			renderFailureFormat(w, r, http.StatusBadRequest, "unsupported event '%s', supported: %s", event, strings.Join(repo.WebhookEvents, ", "))
+			return
+		}
+	}
+	sourceType, sourceID, err := s.webhookSource(r.Context(), newWebhook.NamespacePath, newWebhook.RepoPath)
 	if err != nil {
-		renderFailureFormat(w, r, http.StatusInternalServerError, "gen salt password error: %v", err)
+		s.renderErrorRaw(w, r, err)
 		return
 	}
-	u, err := s.db.NewUser(r.Context(), &database.User{
-		UserName:       newUser.UserName,
-		Name:           newUser.Name,
-		Administrator:  newUser.Administrator,
-		Email:          newUser.Email,
-		Password:       passwd,
-		SignatureToken: strengthen.NewRID(),
+	wh, err := s.db.NewWebhook(r.Context(), &database.Webhook{
+		SourceID:   sourceID,
+		SourceType: sourceType,
+		URL:        newWebhook.URL,
+		Secret:     newWebhook.Secret,
+		Events:     newWebhook.Events,
+		Active:     !newWebhook.Disabled,
 	})
 	if err != nil {
 		s.renderErrorRaw(w, r, err)
 		return
 	}
-	JsonEncode(w, u)
+	JsonEncode(w, wh)
 }
 
-type NewRepo struct {
-	Name          string `json:"name,omitempty"`
-	Path          string `json:"path"`
-	Description   string `json:"description"`
-	VisibleLevel  int    `json:"visible_level,omitempty"`
-	DefaultBranch string `json:"default_branch,omitempty"` // current branch
-	UserName      string `json:"username,omitempty"`
-	UID           int64  `json:"uid,omitempty"`
-	NamespacePath string `json:"namespace_path,omitempty"`
-	NamespaceID   int64  `json:"namespace_id,omitempty"`
-	Empty         bool   `json:"empty,omitempty"`
+func (s *Server) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	namespacePath := q.Get("namespace_path")
+	if len(namespacePath) == 0 {
+		renderFailure(w, r, http.StatusBadRequest, "namespace_path not given")
+		return
+	}
+	sourceType, sourceID, err := s.webhookSource(r.Context(), namespacePath, q.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	webhooks, err := s.db.ListWebhooks(r.Context(), sourceType, sourceID)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, webhooks)
+}
+
+func parseID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad id: %v", err)
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.DeleteWebhook(r.Context(), id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Server) NewRepo(w http.ResponseWriter, r *http.Request) {
-	var newRepo NewRepo
-	if err := json.NewDecoder(r.Body).Decode(&newRepo); err != nil {
-		fmt.Println(err)
+type WebhookDeliveries struct {
+	Deliveries []*database.WebhookDelivery `json:"deliveries"`
+	Next       int64                       `json:"next,omitempty"` // cursor of the next page
+}
+
+// ListWebhookDeliveries: delivery log of the webhook, newest first, paged by ?cursor=<next>&limit=<n>.
+func (s *Server) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.db.FindWebhook(r.Context(), id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	cursor, _ := strconv.ParseInt(q.Get("cursor"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	deliveries, next, err := s.db.ListWebhookDeliveries(r.Context(), id, cursor, limit)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, &WebhookDeliveries{Deliveries: deliveries, Next: next})
+}
+
+func (s *Server) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.RetryWebhookDelivery(r.Context(), id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type NewProtection struct {
+	NamespacePath          string                `json:"namespace_path"`
+	RepoPath               string                `json:"repo_path"`
+	Pattern                string                `json:"pattern"`                           // full reference name, eg: refs/heads/release/*
+	PushAccessLevel        *database.AccessLevel `json:"push_access_level,omitempty"`       // default: developer
+	ForcePushAccessLevel   *database.AccessLevel `json:"force_push_access_level,omitempty"` // default: no one
+	DeleteAccessLevel      *database.AccessLevel `json:"delete_access_level,omitempty"`     // default: no one
+	RequireLinearHistory   bool                  `json:"require_linear_history,omitempty"`
+	RequireSignatureHeader bool                  `json:"require_signature_header,omitempty"`
+	MaxBlobSize            int64                 `json:"max_blob_size,omitempty"`
+	ForbiddenPaths         []string              `json:"forbidden_paths,omitempty"`
+}
+
+func accessLevelOr(level *database.AccessLevel, d database.AccessLevel) database.AccessLevel {
+	if level == nil {
+		return d
+	}
+	return *level
+}
+
+// rule: the protection rule of the repository, validated by the database
+func (p *NewProtection) rule(rid int64) *database.ProtectionRule {
+	return &database.ProtectionRule{
+		RID:                    rid,
+		Pattern:                p.Pattern,
+		PushAccessLevel:        accessLevelOr(p.PushAccessLevel, database.DevAccess),
+		ForcePushAccessLevel:   accessLevelOr(p.ForcePushAccessLevel, database.NoOneAccess),
+		DeleteAccessLevel:      accessLevelOr(p.DeleteAccessLevel, database.NoOneAccess),
+		RequireLinearHistory:   p.RequireLinearHistory,
+		RequireSignatureHeader: p.RequireSignatureHeader,
+		MaxBlobSize:            p.MaxBlobSize,
+		ForbiddenPaths:         p.ForbiddenPaths,
+	}
+}
+
+func (s *Server) NewProtection(w http.ResponseWriter, r *http.Request) {
+	var newProtection NewProtection
+	if err := json.NewDecoder(r.Body).Decode(&newProtection); err != nil {
 		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
 		return
 	}
-	var u *database.User
-	var err error
-	switch {
-	case len(newRepo.UserName) != 0:
-		if u, err = s.db.SearchUser(r.Context(), newRepo.UserName); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	case newRepo.UID != 0:
-		if u, err = s.db.FindUser(r.Context(), newRepo.UID); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	default:
-		renderFailure(w, r, http.StatusBadRequest, "username or uid not given")
+	if len(newProtection.NamespacePath) == 0 || len(newProtection.RepoPath) == 0 {
+		renderFailure(w, r, http.StatusBadRequest, "namespace_path or repo_path not given")
 		return
 	}
-	var n *database.Namespace
-	switch {
-	case len(newRepo.NamespacePath) != 0:
-		if n, err = s.db.FindNamespaceByPath(r.Context(), newRepo.NamespacePath); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	case newRepo.NamespaceID != 0:
-		if n, err = s.db.FindNamespaceByID(r.Context(), newRepo.UID); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	default:
-		renderFailure(w, r, http.StatusBadRequest, "namespace_path or namespace_id not given")
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), newProtection.NamespacePath, newProtection.RepoPath)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	p, err := s.db.NewProtectionRule(r.Context(), newProtection.rule(repo.ID))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, p)
+}
+
+func (s *Server) ListProtections(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), q.Get("namespace_path"), q.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
 		return
 	}
-	repo, err := s.hub.New(r.Context(), &database.Repository{
-		NamespaceID:   n.ID,
-		Name:          newRepo.Name,
-		Path:          newRepo.Path,
-		Description:   newRepo.Description,
-		VisibleLevel:  newRepo.VisibleLevel,
-		DefaultBranch: newRepo.DefaultBranch,
-	}, u, newRepo.Empty)
+	rules, err := s.db.ListProtectionRules(r.Context(), repo.ID)
 	if err != nil {
 		s.renderErrorRaw(w, r, err)
 		return
 	}
-	JsonEncode(w, repo)
+	JsonEncode(w, rules)
 }
 
-type NewKey struct {
-	UserName string `json:"username,omitempty"`
-	UID      int64  `json:"uid,omitempty"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
+func (s *Server) DeleteProtection(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), q.Get("namespace_path"), q.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	if err := s.db.DeleteProtectionRule(r.Context(), repo.ID, id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type NewPathACL struct {
+	NamespacePath string               `json:"namespace_path"`
+	RepoPath      string               `json:"repo_path"`
+	Path          string               `json:"path"`         // directory prefix, eg: secret/
+	GroupPath     string               `json:"group_path"`   // path of the granted group namespace
+	AccessLevel   database.AccessLevel `json:"access_level"` // 20: read, 30: read and write, default: read
 }
 
-func (s *Server) NewKey(w http.ResponseWriter, r *http.Request) {
-	var newKey NewKey
-	if err := json.NewDecoder(r.Body).Decode(&newKey); err != nil {
-		fmt.Println(err)
+func (s *Server) NewPathACL(w http.ResponseWriter, r *http.Request) {
+	var newACL NewPathACL
+	if err := json.NewDecoder(r.Body).Decode(&newACL); err != nil {
 		renderFailureFormat(w, r, http.StatusBadRequest, "input body error: %v", err)
 		return
 	}
-	var u *database.User
-	var err error
-	switch {
-	case len(newKey.UserName) != 0:
-		if u, err = s.db.SearchUser(r.Context(), newKey.UserName); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	case newKey.UID != 0:
-		if u, err = s.db.FindUser(r.Context(), newKey.UID); err != nil {
-			s.renderErrorRaw(w, r, err)
-			return
-		}
-	default:
-		renderFailure(w, r, http.StatusBadRequest, "username or uid not given")
+	if len(newACL.NamespacePath) == 0 || len(newACL.RepoPath) == 0 {
+		renderFailure(w, r, http.StatusBadRequest, "namespace_path or repo_path not given")
+		return
+	}
+	p, ok := database.CleanACLPath(newACL.Path)
+	if !ok {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad path '%s', the root of the repository is not allowed", newACL.Path)
+		return
+	}
+	if newACL.AccessLevel == database.NoneAccess {
+		newACL.AccessLevel = database.ReporterAccess
+	}
+	if newACL.AccessLevel != database.ReporterAccess && newACL.AccessLevel != database.DevAccess {
+		renderFailureFormat(w, r, http.StatusBadRequest, "bad access_level %d, supported: 20 (read), 30 (read and write)", newACL.AccessLevel)
+		return
+	}
+	g, err := s.db.FindNamespaceByPath(r.Context(), newACL.GroupPath)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
 		return
 	}
-	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(newKey.Content))
+	if g.Type != database.GroupNamespace {
+		renderFailureFormat(w, r, http.StatusBadRequest, "'%s' is not a group", newACL.GroupPath)
+		return
+	}
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), newACL.NamespacePath, newACL.RepoPath)
 	if err != nil {
-		renderFailureFormat(w, r, http.StatusBadRequest, "bad public key: %v", err)
+		s.renderErrorRaw(w, r, err)
 		return
 	}
-	k, err := s.db.AddKey(r.Context(), &database.Key{
-		UID:         u.ID,
-		Content:     newKey.Content,
-		Title:       newKey.Title,
-		Type:        database.BasicKey,
-		Fingerprint: ssh.FingerprintSHA256(pk),
+	a, err := s.db.NewPathACL(r.Context(), &database.PathACL{
+		RID:         repo.ID,
+		Path:        p,
+		NamespaceID: g.ID,
+		AccessLevel: newACL.AccessLevel,
 	})
 	if err != nil {
 		s.renderErrorRaw(w, r, err)
 		return
 	}
-	JsonEncode(w, k)
+	JsonEncode(w, a)
+}
+
+func (s *Server) ListPathACLs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), q.Get("namespace_path"), q.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	acls, err := s.db.ListPathACLs(r.Context(), repo.ID)
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	JsonEncode(w, acls)
+}
+
+func (s *Server) DeletePathACL(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	_, repo, err := s.db.FindRepositoryByPath(r.Context(), q.Get("namespace_path"), q.Get("repo_path"))
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	if err := s.db.DeletePathACL(r.Context(), repo.ID, id); err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// ManagementRouter: routes of the management API, succeeded requests of named routes are recorded in the audit log
+// with the name as the action. Deleting branches and tags is not named, reference updates are recorded by the database.
 func (s *Server) ManagementRouter(r *mux.Router) {
-	r.HandleFunc("/api/v1/user", s.NewUser).Methods("POST")
-	r.HandleFunc("/api/v1/key", s.NewKey).Methods("POST")
-	r.HandleFunc("/api/v1/repo", s.NewRepo).Methods("POST")
+	r.HandleFunc("/api/v1/openapi.yaml", s.OpenAPI).Methods("GET")
+	// users and their SSH keys
+	r.HandleFunc("/api/v1/users", s.OnManagement(s.ListUsers)).Methods("GET")
+	r.HandleFunc("/api/v1/users", s.OnManagement(s.NewUser)).Methods("POST").Name("user.create")
+	r.HandleFunc("/api/v1/users/{username}", s.OnManagement(s.GetUser)).Methods("GET")
+	r.HandleFunc("/api/v1/users/{username}", s.OnManagement(s.UpdateUser)).Methods("PATCH").Name("user.update")
+	r.HandleFunc("/api/v1/users/{username}", s.OnManagement(s.DeleteUser)).Methods("DELETE").Name("user.delete")
+	r.HandleFunc("/api/v1/users/{username}/keys", s.OnManagement(s.ListKeys)).Methods("GET")
+	r.HandleFunc("/api/v1/users/{username}/keys", s.OnManagement(s.NewUserKey)).Methods("POST").Name("user.key.create")
+	r.HandleFunc("/api/v1/users/{username}/keys/{id:[0-9]+}", s.OnManagement(s.DeleteUserKey)).Methods("DELETE").Name("user.key.delete")
+	// namespaces
+	r.HandleFunc("/api/v1/namespaces", s.OnManagement(s.ListNamespaces)).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces", s.OnManagement(s.NewNamespace)).Methods("POST").Name("namespace.create")
+	r.HandleFunc("/api/v1/namespaces/{namespace}", s.OnManagement(s.GetNamespace)).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}", s.OnManagement(s.UpdateNamespace)).Methods("PATCH").Name("namespace.update")
+	r.HandleFunc("/api/v1/namespaces/{namespace}", s.OnManagement(s.DeleteNamespace)).Methods("DELETE").Name("namespace.delete")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/members", s.OnManagement(s.ListNamespaceMembers)).Methods("GET")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/members/{username}", s.OnManagement(s.SetNamespaceMember)).Methods("PUT").Name("namespace.member.set")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/members/{username}", s.OnManagement(s.RemoveNamespaceMember)).Methods("DELETE").Name("namespace.member.remove")
+	r.HandleFunc("/api/v1/namespaces/{namespace}/repos", s.OnManagement(s.ListRepos)).Methods("GET")
+	// repositories
+	r.HandleFunc("/api/v1/repos", s.OnManagement(s.NewRepo)).Methods("POST").Name("repo.create")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}", s.OnManagement(s.GetRepo)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}", s.OnManagement(s.UpdateRepo)).Methods("PATCH").Name("repo.update")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}", s.OnManagement(s.DeleteRepo)).Methods("DELETE").Name("repo.delete")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/members", s.OnManagement(s.ListRepoMembers)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/members/{username}", s.OnManagement(s.SetRepoMember)).Methods("PUT").Name("repo.member.set")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/members/{username}", s.OnManagement(s.RemoveRepoMember)).Methods("DELETE").Name("repo.member.remove")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/deploy-keys", s.OnManagement(s.ListDeployKeys)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/deploy-keys", s.OnManagement(s.NewDeployKey)).Methods("POST").Name("repo.deploy_key.create")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/deploy-keys/{id:[0-9]+}", s.OnManagement(s.DeleteDeployKey)).Methods("DELETE").Name("repo.deploy_key.delete")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/branches", s.OnManagement(s.ListBranches)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/branches/{branch:.+}", s.OnManagement(s.GetBranch)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/branches/{branch:.+}", s.OnManagement(s.DeleteBranch)).Methods("DELETE")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/tags", s.OnManagement(s.ListTags)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/tags/{tag:.+}", s.OnManagement(s.GetTag)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/tags/{tag:.+}", s.OnManagement(s.DeleteTag)).Methods("DELETE")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/reflog", s.OnManagement(s.ListRefLog)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/reflog/{id:[0-9]+}/restore", s.OnManagement(s.RestoreReference)).Methods("POST").Name("reference.restore")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/protections", s.OnManagement(s.ListRepoProtections)).Methods("GET")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/protections", s.OnManagement(s.NewRepoProtection)).Methods("POST").Name("repo.protection.create")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/protections/{id:[0-9]+}", s.OnManagement(s.UpdateRepoProtection)).Methods("PUT").Name("repo.protection.update")
+	r.HandleFunc("/api/v1/repos/{namespace}/{repo}/protections/{id:[0-9]+}", s.OnManagement(s.DeleteRepoProtection)).Methods("DELETE").Name("repo.protection.delete")
+	// audit log
+	r.HandleFunc("/api/v1/audit-events", s.OnManagement(s.ListAuditEvents)).Methods("GET")
+	// routes of earlier versions, webhooks, protections and path ACLs addressed by query are administrator only
+	r.HandleFunc("/api/v1/user", s.OnManagement(s.NewUser)).Methods("POST").Name("user.create")
+	r.HandleFunc("/api/v1/key", s.OnManagement(s.NewKey)).Methods("POST").Name("user.key.create")
+	r.HandleFunc("/api/v1/repo", s.OnManagement(s.NewRepo)).Methods("POST").Name("repo.create")
+	r.HandleFunc("/api/v1/webhooks", s.OnAdmin(s.NewWebhook)).Methods("POST").Name("webhook.create")
+	r.HandleFunc("/api/v1/webhooks", s.OnAdmin(s.ListWebhooks)).Methods("GET")
+	r.HandleFunc("/api/v1/webhooks/{id:[0-9]+}", s.OnAdmin(s.DeleteWebhook)).Methods("DELETE").Name("webhook.delete")
+	r.HandleFunc("/api/v1/webhooks/{id:[0-9]+}/deliveries", s.OnAdmin(s.ListWebhookDeliveries)).Methods("GET")
+	r.HandleFunc("/api/v1/webhooks/deliveries/{id:[0-9]+}/retry", s.OnAdmin(s.RetryWebhookDelivery)).Methods("POST").Name("webhook.delivery.retry")
+	r.HandleFunc("/api/v1/protections", s.OnAdmin(s.NewProtection)).Methods("POST").Name("protection.create")
+	r.HandleFunc("/api/v1/protections", s.OnAdmin(s.ListProtections)).Methods("GET")
+	r.HandleFunc("/api/v1/protections/{id:[0-9]+}", s.OnAdmin(s.DeleteProtection)).Methods("DELETE").Name("protection.delete")
+	r.HandleFunc("/api/v1/path-acls", s.OnAdmin(s.NewPathACL)).Methods("POST").Name("path_acl.create")
+	r.HandleFunc("/api/v1/path-acls", s.OnAdmin(s.ListPathACLs)).Methods("GET")
+	r.HandleFunc("/api/v1/path-acls/{id:[0-9]+}", s.OnAdmin(s.DeletePathACL)).Methods("DELETE").Name("path_acl.delete")
 }
@@ -19,6 +19,10 @@ const (
 	DeepenFrom = "deepen-from" // shallow base
 	Deepen     = "deepen"      // deepen <depth>
 	Have       = "have"        // local have
+	Want       = "want"        // want commit
+	Resumable  = "resumable"   // checked batch objects stream
+	SizeLimit  = "size-limit"  // batch objects larger than the limit are skipped
+	Force      = "force"       // bundle push allows non-fast-forward updates
 )
 
 // checkDeepen: check deepen and deepen-from, if deepen-from is set, ignore deepen
@@ -70,7 +74,7 @@ func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 	if err != nil {
 		return
 	}
-	deepen, have, deepenFrom, err := s.checkDeepen(w, r)
+	deepen, deepenFrom, have, err := s.checkDeepen(w, r)
 	if err != nil {
 		return
 	}
@@ -80,6 +84,11 @@ func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
 	ro, err := rr.ParseRev(r.Context(), rev)
 	if err != nil {
 		s.renderError(w, r, err)
@@ -95,6 +104,22 @@ func (s *Server) FetchMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), nil)
+	}
+	if !have.IsZero() {
+		haves, err := rr.ODB().Reachable(r.Context(), have)
+		if err != nil {
+			logrus.Warnf("resolve objects reachable from have '%s' error: %v", have, err)
+		}
+		p.Exclude(haves)
+		// the client has checked out the whole tree of have, unless it is restricted or limited by depth
+		if acl == nil && depth == -1 {
+			if err := p.ExcludeCheckout(r.Context(), have); err != nil && !plumbing.IsNoSuchObject(err) {
+				logrus.Warnf("resolve checkout of have '%s' error: %v", have, err)
+			}
+		}
+	}
 	for oid, o := range ro.Objects {
 		if err := p.WriteAny(r.Context(), o, oid); err != nil {
 			logrus.Errorf("write objects error %v", err)
@@ -133,6 +158,17 @@ func (s *Server) GetSparseMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	for _, p := range paths {
+		if acl != nil && !acl.CanRead(p) {
+			renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("access to '%s' is denied"), p)
+			return
+		}
+	}
 
 	rev, _ := url.PathUnescape(mux.Vars(r.Request)["revision"])
 	ro, err := rr.ParseRev(r.Context(), rev)
@@ -151,6 +187,16 @@ func (s *Server) GetSparseMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), nil)
+	}
+	if !have.IsZero() {
+		haves, err := rr.ODB().Reachable(r.Context(), have)
+		if err != nil {
+			logrus.Warnf("resolve objects reachable from have '%s' error: %v", have, err)
+		}
+		p.Exclude(haves)
+	}
 	for oid, o := range ro.Objects {
 		if err := p.WriteAny(r.Context(), o, oid); err != nil {
 			logrus.Errorf("write objects error %v", err)
@@ -182,6 +228,25 @@ func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	hidden, err := rr.HiddenObjects(r.Context(), acl)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	refused, err := hidden.Refused(r.Context(), oids)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	for oid := range refused {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("access to object '%s' is denied"), oid)
+		return
+	}
 	odb := rr.ODB()
 	objects := make([]any, 0, len(oids))
 	for _, oid := range oids {
@@ -198,6 +263,9 @@ func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), hidden.Contains)
+	}
 	for _, a := range objects {
 		switch v := a.(type) {
 		case *object.Commit:
@@ -205,7 +273,7 @@ func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
 				logrus.Errorf("write commit error %v", err)
 				return
 			}
-			if err := p.WriteTree(r.Context(), v.Tree, 0); err != nil {
+			if err := p.WriteRootTree(r.Context(), v.Tree); err != nil {
 				logrus.Errorf("write tree error %v", err)
 				return
 			}
@@ -235,7 +303,7 @@ func (s *Server) BatchMetadata(w http.ResponseWriter, r *Request) {
 				logrus.Errorf("write fragments error %v", err)
 				return
 			}
-			if err := p.WriteTree(r.Context(), target.Tree, 0); err != nil {
+			if err := p.WriteRootTree(r.Context(), target.Tree); err != nil {
 				logrus.Errorf("write tree error %v", err)
 				return
 			}
@@ -0,0 +1,42 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/oss"
+)
+
+// GET /_oss/{key}?expires={unix}&signature={hmac}: download the object of the local bucket by the URL signed by Sharing,
+// ranges are supported.
+func (s *Server) LocalDownload(w http.ResponseWriter, r *http.Request) {
+	bucket, ok := s.hub.Bucket().(*oss.LocalBucket)
+	if !ok {
+		renderFailure(w, r, http.StatusNotFound, "local bucket not configured")
+		return
+	}
+	resourcePath := strings.TrimPrefix(r.URL.Path, oss.LocalDownloadPrefix)
+	q := r.URL.Query()
+	fd, err := bucket.OpenShared(resourcePath, q.Get("expires"), q.Get("signature"))
+	switch {
+	case errors.Is(err, oss.ErrSignatureMismatch), errors.Is(err, oss.ErrSignatureExpired), errors.Is(err, oss.ErrBadResourcePath):
+		renderFailure(w, r, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	defer fd.Close()
+	si, err := fd.Stat()
+	if err != nil {
+		s.renderErrorRaw(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, path.Base(resourcePath), si.ModTime(), fd)
+}
@@ -16,6 +16,10 @@ type Request struct {
 	U *database.User
 	N *database.Namespace
 	R *database.Repository
+	// AccessLevel: access level of the user to the repository
+	AccessLevel database.AccessLevel
+	// KID: SSH key or deploy key the bearer token was issued to, 0 for password credentials
+	KID int64
 }
 
 func (r *Request) W(message string) string {
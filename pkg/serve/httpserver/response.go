@@ -33,6 +33,7 @@ type ResponseWriter struct {
 	written    int64
 	statusCode int
 	remoteAddr string
+	route      string
 }
 
 // NewResponseWriter bind ResponseWriter
@@ -63,6 +64,14 @@ func (w *ResponseWriter) Written() int64 {
 	return w.written
 }
 
+// Route return the path template of the matched route, "unmatched" when no route matched
+func (w *ResponseWriter) Route() string {
+	if len(w.route) == 0 {
+		return "unmatched"
+	}
+	return w.route
+}
+
 func (w *ResponseWriter) F1RemoteAddr() string {
 	return w.remoteAddr
 }
@@ -135,8 +144,10 @@ func (s *Server) renderErrorRaw(w http.ResponseWriter, r *http.Request, err erro
 		renderFailure(w, r, http.StatusNotFound, err.Error())
 	case os.IsNotExist(err), database.IsNotFound(err), object.IsErrDirectoryNotFound(err), object.IsErrEntryNotFound(err):
 		renderFailureFormat(w, r, http.StatusNotFound, "resource not found: %v", err)
-	case backend.IsErrMismatchedObjectType(err), database.IsErrExist(err), errors.Is(err, fs.ErrExist):
+	case backend.IsErrMismatchedObjectType(err), database.IsErrExist(err), errors.Is(err, fs.ErrExist), database.IsErrAlreadyLocked(err):
 		renderFailure(w, r, http.StatusConflict, err.Error())
+	case database.IsErrNamingRule(err), database.IsErrInvalidArgument(err):
+		renderFailure(w, r, http.StatusBadRequest, err.Error())
 	default:
 		renderFailure(w, r, http.StatusInternalServerError, "internal server error")
 		r.Header.Set(ErrorMessageKey, err.Error())
@@ -10,11 +10,15 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
+	"github.com/antgroup/hugescm/modules/oss"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/metrics"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/ratelimit"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
@@ -28,6 +32,8 @@ type Server struct {
 	r          *mux.Router
 	db         database.DB
 	hub        repo.Repositories
+	limiter    *ratelimit.Limiter // nil when rate_limits is not set
+	metrics    *metrics.Server    // nil when metrics_listen is not set
 	serverName string
 }
 
@@ -38,23 +44,45 @@ func Z1Matcher(r *http.Request, m *mux.RouteMatch) bool {
 func (s *Server) ProtocolZ1Router(r *mux.Router) {
 	r.HandleFunc("/{namespace}/{repo}/authorization", s.ShareAuthorization).Methods("POST").MatcherFunc(Z1Matcher) // AUTH: shard siganture auth
 	// Zeta Protocol: FETCH APIs
-	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}", s.OnFunc(s.LsReference, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)        // CHECKOUT: fetch reference
-	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnFunc(s.FetchMetadata, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)      // CHECKOUT: download commit and tree/subtrees metadata ...
-	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnFunc(s.GetSparseMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // CHECKOUT: sparse checkout
-	r.HandleFunc("/{namespace}/{repo}/metadata/batch", s.OnFunc(s.BatchMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)             // CHECKOUT: batch metadata for FUSE
-	r.HandleFunc("/{namespace}/{repo}/objects/batch", s.OnFunc(s.BatchObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)               // ENHANCED: batch objects Required to migrate from zeta to git
-	r.HandleFunc("/{namespace}/{repo}/objects/share", s.OnFunc(s.ShareObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)               // CHECKOUT: shared signed oss urls
-	r.HandleFunc("/{namespace}/{repo}/objects/{oid}", s.OnFunc(s.GetObject, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                   // ENHANCED: download object Required to migrate from zeta to git
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}", s.OnFunc(s.LsReference, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)             // CHECKOUT: fetch reference
+	r.HandleFunc("/{namespace}/{repo}/references", s.OnFunc(s.ListReferences, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                      // LS-REMOTE: list references
+	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnHeavyFunc(s.FetchMetadata, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)      // CHECKOUT: download commit and tree/subtrees metadata ...
+	r.HandleFunc("/{namespace}/{repo}/metadata/{revision:.*}", s.OnHeavyFunc(s.GetSparseMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // CHECKOUT: sparse checkout
+	r.HandleFunc("/{namespace}/{repo}/metadata/batch", s.OnHeavyFunc(s.BatchMetadata, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)             // CHECKOUT: batch metadata for FUSE
+	r.HandleFunc("/{namespace}/{repo}/objects/batch", s.OnHeavyFunc(s.BatchObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)               // ENHANCED: batch objects Required to migrate from zeta to git
+	r.HandleFunc("/{namespace}/{repo}/objects/share", s.OnFunc(s.ShareObjects, protocol.DOWNLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                    // CHECKOUT: shared signed oss urls
+	r.HandleFunc("/{namespace}/{repo}/objects/{oid}", s.OnFunc(s.GetObject, protocol.DOWNLOAD)).Methods("GET").MatcherFunc(Z1Matcher)                        // ENHANCED: download object Required to migrate from zeta to git
 	// Zeta Protocol: PUSH APIs
-	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/batch", s.OnFunc(s.BatchCheck, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // PUSH: batch check large objects
-	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}", s.OnFunc(s.PutObject, protocol.UPLOAD)).Methods("PUT").MatcherFunc(Z1Matcher)   // PUSH: PUT one large object
-	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}", s.OnFunc(s.Push, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                     // PUSH: push local commit to zeta server
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/batch", s.OnFunc(s.BatchCheck, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                 // PUSH: batch check large objects
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}", s.OnFunc(s.PutObject, protocol.UPLOAD)).Methods("PUT").MatcherFunc(Z1Matcher)                   // PUSH: PUT one large object
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload", s.OnFunc(s.NewUpload, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)           // PUSH: create resumable upload session
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload/status", s.OnFunc(s.UploadStatus, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // PUSH: query committed offset of upload session
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload", s.OnFunc(s.UploadChunk, protocol.UPLOAD)).Methods("PUT").MatcherFunc(Z1Matcher)          // PUSH: PUT one chunk at offset
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload/finish", s.OnFunc(s.FinishUpload, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher) // PUSH: finish upload session and verify hash
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload", s.OnFunc(s.AbortUpload, protocol.UPLOAD)).Methods("DELETE").MatcherFunc(Z1Matcher)       // PUSH: abort upload session
+	r.HandleFunc("/{namespace}/{repo}/reference/{refname:.*}", s.OnHeavyFunc(s.Push, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                                // PUSH: push local commit to zeta server
+	r.HandleFunc("/{namespace}/{repo}/references", s.OnHeavyFunc(s.AtomicPush, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                                      // PUSH: update multiple references atomically
+	r.HandleFunc("/{namespace}/{repo}/bundle", s.OnHeavyFunc(s.BundlePush, protocol.UPLOAD)).Methods("POST").MatcherFunc(Z1Matcher)                                          // PUSH: push a bundle file
+}
+
+// routeMiddleware: record the path template of the matched route, requests are reported to metrics by route.
+func routeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hw, ok := w.(*ResponseWriter); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				hw.route, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (s *Server) initialize() error {
 	r := mux.NewRouter().UseEncodedPath()
 	s.ProtocolZ1Router(r)
 	s.ManagementRouter(r)
+	r.PathPrefix(oss.LocalDownloadPrefix).HandlerFunc(s.LocalDownload).Methods("GET", "HEAD") // shared URLs of the local bucket
+	r.Use(routeMiddleware)
 	s.r = r
 	s.srv.Handler = s
 	return nil
@@ -73,19 +101,20 @@ func NewServer(sc *ServerConfig) (*Server, error) {
 			IdleTimeout:  sc.IdleTimeout.Duration,
 			WriteTimeout: sc.WriteTimeout.Duration,
 		},
+		limiter:    ratelimit.NewLimiter(sc.RateLimits),
 		serverName: sc.BannerVersion,
 	}
 	if err := srv.initialize(); err != nil {
 		return nil, err
 	}
-	cfg, err := sc.DB.MakeConfig()
+	driver, dsn, err := sc.DB.DSN()
 	if err != nil {
 		return nil, err
 	}
-	if srv.db, err = database.NewDB(cfg); err != nil {
+	if srv.db, err = database.NewDB(driver, dsn); err != nil {
 		return nil, err
 	}
-	if srv.hub, err = repo.NewRepositories(sc.Repositories, sc.ZetaOSS, sc.Cache, srv.db); err != nil {
+	if srv.hub, err = repo.NewRepositories(sc.Repositories, sc.ZetaOSS, sc.Cache, sc.Hooks, sc.Webhooks, srv.db); err != nil {
 		_ = srv.db.Close()
 		return nil, err
 	}
@@ -96,6 +125,10 @@ func (s *Server) ListenAndServe() error {
 	if err := serve.RegisterLanguageMatcher(); err != nil {
 		logrus.Errorf("register languages matcher error: %v", err)
 	}
+	if len(s.MetricsListen) != 0 {
+		s.metrics = metrics.NewServer(s.MetricsListen)
+		s.metrics.Start()
+	}
 	return s.srv.ListenAndServe()
 }
 
@@ -137,9 +170,20 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.Body = tr
 	now := time.Now()
 	hw := NewResponseWriter(w, r)
+	metrics.HTTPActiveRequests.Inc()
+	defer metrics.HTTPActiveRequests.Dec()
 	s.r.ServeHTTP(hw, r)
 	spent := time.Since(now)
 	logResponse(hw, r, tr, spent)
+	observeResponse(hw, r, tr, spent)
+}
+
+func observeResponse(hw *ResponseWriter, r *http.Request, tr *trackedReader, spent time.Duration) {
+	route, method, code := hw.Route(), metrics.HTTPMethod(r.Method), strconv.Itoa(hw.StatusCode())
+	metrics.HTTPRequests.WithLabelValues(route, method, code).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(route, method, code).Observe(spent.Seconds())
+	metrics.HTTPReceivedBytes.WithLabelValues(route).Add(float64(tr.received))
+	metrics.HTTPWrittenBytes.WithLabelValues(route).Add(float64(hw.Written()))
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
@@ -149,6 +193,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.srv.Shutdown(ctx); err != nil {
 		logrus.Errorf("shutdown ssh server %v", err)
 	}
+	if err := s.metrics.Shutdown(ctx); err != nil {
+		logrus.Errorf("shutdown metrics server %v", err)
+	}
+	if s.hub != nil {
+		_ = s.hub.Close()
+	}
 	if s.db != nil {
 		_ = s.db.Close()
 	}
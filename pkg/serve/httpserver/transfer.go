@@ -42,6 +42,11 @@ const (
 	ZETA_TERMINAL        = "X-Zeta-Terminal"
 	ZETA_OBJECTS_STATS   = "X-Zeta-Objects-Stats"
 	ZETA_COMPRESSED_SIZE = "X-Zeta-Compressed-Size"
+	ZETA_UPLOAD_ID       = "X-Zeta-Upload-Id"
+	ZETA_UPLOAD_OFFSET   = "X-Zeta-Upload-Offset"
+	// push options: X-Zeta-Push-Option-Count and X-Zeta-Push-Option-<i>
+	ZETA_PUSH_OPTION_COUNT  = "X-Zeta-Push-Option-Count"
+	ZETA_PUSH_OPTION_PREFIX = "X-Zeta-Push-Option-"
 	// ZETA Protocol Content Type
 	ZETA_MIME_BLOB          = "application/x-zeta-blob"
 	ZETA_MIME_BLOBS         = "application/x-zeta-blobs"
@@ -65,7 +70,7 @@ func (s *Server) ShareAuthorization(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	expiresAt := time.Now().Add(time.Hour * 2) // default 24h
-	token, err := GenerateJWT(req.U, req.R.ID, sa.Operation, expiresAt)
+	token, err := GenerateJWT(req.U, req.KID, req.R.ID, sa.Operation, expiresAt)
 	if err != nil {
 		renderFailureFormat(w, r, http.StatusInternalServerError, "new token error: %v", err)
 		return
@@ -97,6 +102,7 @@ func (s *Server) LsBranchReference(w http.ResponseWriter, r *Request, branchName
 		Agent:           s.serverName,
 		HashAlgo:        r.R.HashAlgo,
 		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    s.hub.Capabilities(),
 	}
 	ZetaEncodeVND(w, branch)
 }
@@ -122,6 +128,7 @@ func (s *Server) LsTagReference(w http.ResponseWriter, r *Request, tagName strin
 		Agent:           s.serverName,
 		HashAlgo:        r.R.HashAlgo,
 		CompressionAlgo: r.R.CompressionAlgo,
+		Capabilities:    s.hub.Capabilities(),
 	}
 	ZetaEncodeVND(w, branch)
 }
@@ -153,8 +160,73 @@ func (s *Server) LsReference(w http.ResponseWriter, r *Request) {
 	s.LsBranchReference(w, r, refname)
 }
 
+// GET /{namespace}/{repo}/references?prefix=${prefix}&cursor=${cursor}&limit=${limit}
+func (s *Server) ListReferences(w http.ResponseWriter, r *Request) {
+	q := r.URL.Query()
+	var limit int
+	if ls := q.Get("limit"); len(ls) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(ls); err != nil || limit < 0 {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad limit '%s'", ls)
+			return
+		}
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	entries, next, err := rr.ListReferences(r.Context(), q.Get("prefix"), q.Get("cursor"), limit)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	ZetaEncodeVND(w, &protocol.References{
+		Remote:     r.makeRemoteURL(),
+		HEAD:       protocol.BRANCH_PREFIX + r.R.DefaultBranch,
+		References: entries,
+		NextCursor: next,
+	})
+}
+
+// checkWantHave: want and have commits, when want is set, the server resolves the missing objects itself.
+func (s *Server) checkWantHave(w http.ResponseWriter, r *Request) (want, have plumbing.Hash, err error) {
+	q := r.URL.Query()
+	if ws := q.Get(Want); len(ws) != 0 {
+		if !plumbing.ValidateHashHex(ws) {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad want '%s'", ws)
+			return want, have, ErrStop
+		}
+		want = plumbing.NewHash(ws)
+	}
+	if hs := q.Get(Have); len(hs) != 0 {
+		if !plumbing.ValidateHashHex(hs) {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad have '%s'", hs)
+			return want, have, ErrStop
+		}
+		have = plumbing.NewHash(hs)
+	}
+	return want, have, nil
+}
+
 // POST /{namespace}/{repo}/objects/batch
+//
+//	POST /{namespace}/{repo}/objects/batch?want=${want}&have=${have}: objects reachable from want but not from have are appended
+//	POST /{namespace}/{repo}/objects/batch?size-limit=${size}: objects larger than size are skipped
+//	POST /{namespace}/{repo}/objects/batch?resumable=1: checked stream, see 'resumable-batch' capability
 func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
+	want, have, err := s.checkWantHave(w, r)
+	if err != nil {
+		return
+	}
+	var sizeLimit int64
+	if v := r.URL.Query().Get(SizeLimit); len(v) != 0 {
+		if sizeLimit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "bad size-limit '%s'", v)
+			return
+		}
+	}
+	sizeLimit = protocol.BatchSizeLimit(sizeLimit)
 	oids, err := protocol.ReadInputOIDs(r.Body)
 	if err != nil {
 		renderFailureFormat(w, r.Request, http.StatusBadRequest, "batch-oids: %v", err)
@@ -165,6 +237,39 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(r)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	if !want.IsZero() {
+		var missing []plumbing.Hash
+		if acl != nil {
+			missing, err = acl.MissingObjects(r.Context(), rr.ODB(), want, have)
+		} else {
+			missing, err = rr.ODB().MissingObjects(r.Context(), want, have)
+		}
+		if errors.Is(err, odb.ErrBitmapIndexNotReady) {
+			w.Header().Set("Retry-After", "10")
+			renderFailure(w, r.Request, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if err != nil {
+			s.renderError(w, r, err)
+			return
+		}
+		oids = protocol.MergeOIDs(oids, missing)
+	}
+	hidden, err := rr.HiddenObjects(r.Context(), acl)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	refused, err := hidden.Refused(r.Context(), oids)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
 	w.Header().Set("Content-Type", ZETA_MIME_BLOBS)
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("X-Accel-Buffering", "no")
@@ -175,12 +280,20 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 		streamio.PutBufferWriter(buffedWriter)
 	}()
 	cw := crc.NewCrc64Writer(buffedWriter)
-	if err := protocol.WriteBatchObjectsHeader(cw); err != nil {
+	version, writeItem := protocol.PROTOCOL_VERSION, protocol.WriteObjectsItem
+	if r.URL.Query().Get(Resumable) == "1" {
+		version, writeItem = protocol.RESUMABLE_BATCH_VERSION, protocol.WriteCheckedObjectsItem
+	}
+	if err := protocol.WriteBatchObjectsHeader(cw, version); err != nil {
 		logrus.Errorf("write blob header error: %v", err)
 		return
 	}
 	o := rr.ODB()
 	writeFunc := func(oid plumbing.Hash) error {
+		if refused[oid] {
+			// same as missing objects
+			return nil
+		}
 		sr, err := o.Open(r.Context(), oid, 0)
 		if plumbing.IsNoSuchObject(err) {
 			return nil
@@ -188,12 +301,12 @@ func (s *Server) BatchObjects(w http.ResponseWriter, r *Request) {
 		if err != nil {
 			return err
 		}
-		if sr.Size() > protocol.MAX_BATCH_BLOB_SIZE {
+		if sr.Size() > sizeLimit {
 			sr.Close()
 			return nil
 		}
 		defer sr.Close()
-		return protocol.WriteObjectsItem(cw, sr, oid.String(), sr.Size())
+		return writeItem(cw, sr, oid.String(), sr.Size())
 	}
 	for _, oid := range oids {
 		if err := writeFunc(oid); err != nil {
@@ -220,6 +333,23 @@ func (s *Server) ShareObjects(w http.ResponseWriter, r *Request) {
 	}
 	defer rr.Close()
 
+	oids := make([]plumbing.Hash, 0, len(request.Objects))
+	for _, o := range request.Objects {
+		if o == nil {
+			renderFailureFormat(w, r.Request, http.StatusBadRequest, "require object is nil")
+			return
+		}
+		oids = append(oids, plumbing.NewHash(o.OID))
+	}
+	refused, err := s.refusedObjects(r, rr, oids)
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	for oid := range refused {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("access to object '%s' is denied"), oid)
+		return
+	}
 	response := &protocol.BatchSharedsResponse{
 		Objects: make([]*protocol.Representation, 0, len(request.Objects)),
 	}
@@ -266,8 +396,18 @@ func (s *Server) GetObject(w http.ResponseWriter, r *Request) {
 		return
 	}
 	defer repo.Close()
+	oid := plumbing.NewHash(sid)
+	refused, err := s.refusedObjects(r, repo, []plumbing.Hash{oid})
+	if err != nil {
+		s.renderError(w, r, err)
+		return
+	}
+	if refused[oid] {
+		renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("access to object '%s' is denied"), oid)
+		return
+	}
 	o := repo.ODB()
-	sr, err := o.Open(r.Context(), plumbing.NewHash(sid), rg.Start)
+	sr, err := o.Open(r.Context(), oid, rg.Start)
 	if err != nil {
 		s.renderError(w, r, err)
 		return
@@ -302,7 +442,7 @@ func (s *Server) updateBranchDryRun(w http.ResponseWriter, r *Request, branchNam
 		renderFailureFormat(w, r.Request, http.StatusInternalServerError, "internal server error: %v", err)
 		return false
 	}
-	return true
+	return s.renderProtection(w, r, &repo.Command{ReferenceName: plumbing.NewBranchReferenceName(branchName), Language: serve.Language(r.Request)})
 }
 
 func (s *Server) updateReferenceDryRun(w http.ResponseWriter, r *Request) bool {
@@ -322,8 +462,7 @@ func (s *Server) updateReferenceDryRun(w http.ResponseWriter, r *Request) bool {
 	case refname.IsBranch():
 		return s.updateBranchDryRun(w, r, refname.BranchName())
 	case refname.IsTag():
-		//return s.updateTagDryRun(w, r, refname.TagName())
-		return true
+		return s.renderProtection(w, r, &repo.Command{ReferenceName: refname, Language: serve.Language(r.Request)})
 	case !strings.HasPrefix(unescapeRefname, plumbing.ReferencePrefix):
 		return s.updateBranchDryRun(w, r, string(refname))
 	}
@@ -418,6 +557,147 @@ func (s *Server) PutObject(w http.ResponseWriter, r *Request) {
 	ZetaEncodeVND(w, &protocol.ErrorCode{Code: 200, Message: "OK"})
 }
 
+func newUploadSession(oid plumbing.Hash, u *odb.Upload) *protocol.UploadSession {
+	return &protocol.UploadSession{ID: u.ID, OID: oid.String(), Size: u.Size, ChunkSize: u.ChunkSize, Offset: u.Offset}
+}
+
+func parseInt64Header(w http.ResponseWriter, r *Request, name string) (int64, bool) {
+	v := r.Header.Get(name)
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "'%s' value not valid number: '%s'", strings.ToLower(name), v)
+		return 0, false
+	}
+	return n, true
+}
+
+// upload session: oid, upload id and object size are required by every request after the session was created
+func (s *Server) parseUploadSession(w http.ResponseWriter, r *Request, requireID bool) (oid plumbing.Hash, uploadID string, size int64, ok bool) {
+	sid := mux.Vars(r.Request)["oid"]
+	if !plumbing.ValidateHashHex(sid) {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "invalid hash string: %s", sid)
+		return
+	}
+	if size, ok = parseInt64Header(w, r, ZETA_COMPRESSED_SIZE); !ok {
+		return
+	}
+	if uploadID = r.Header.Get(ZETA_UPLOAD_ID); requireID && len(uploadID) == 0 {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "missing upload id")
+		return oid, uploadID, size, false
+	}
+	return plumbing.NewHash(sid), uploadID, size, s.updateReferenceDryRun(w, r)
+}
+
+func renderUploadFailure(w http.ResponseWriter, r *Request, oid plumbing.Hash, err error) {
+	if err == odb.ErrUploadNotFound {
+		renderFailureFormat(w, r.Request, http.StatusNotFound, "upload object '%s' error: %v", oid, err)
+		return
+	}
+	renderFailureFormat(w, r.Request, http.StatusConflict, "upload object '%s' error: %v", oid, err)
+}
+
+// POST /{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload
+func (s *Server) NewUpload(w http.ResponseWriter, r *Request) {
+	oid, _, size, ok := s.parseUploadSession(w, r, false)
+	if !ok {
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	u, err := rr.ODB().NewUpload(r.Context(), oid, size)
+	if err != nil {
+		renderUploadFailure(w, r, oid, err)
+		return
+	}
+	ZetaEncodeVND(w, newUploadSession(oid, u))
+}
+
+// POST /{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload/status
+func (s *Server) UploadStatus(w http.ResponseWriter, r *Request) {
+	oid, uploadID, size, ok := s.parseUploadSession(w, r, true)
+	if !ok {
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	u, err := rr.ODB().UploadStatus(r.Context(), oid, uploadID, size)
+	if err != nil {
+		renderUploadFailure(w, r, oid, err)
+		return
+	}
+	ZetaEncodeVND(w, newUploadSession(oid, u))
+}
+
+// PUT /{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload
+func (s *Server) UploadChunk(w http.ResponseWriter, r *Request) {
+	oid, uploadID, size, ok := s.parseUploadSession(w, r, true)
+	if !ok {
+		return
+	}
+	offset, ok := parseInt64Header(w, r, ZETA_UPLOAD_OFFSET)
+	if !ok {
+		return
+	}
+	if r.ContentLength < 0 {
+		renderFailureFormat(w, r.Request, http.StatusLengthRequired, "chunk upload requires content-length")
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	u, err := rr.ODB().UploadChunk(r.Context(), oid, uploadID, size, offset, r.Body, r.ContentLength)
+	if err != nil {
+		renderUploadFailure(w, r, oid, err)
+		return
+	}
+	ZetaEncodeVND(w, newUploadSession(oid, u))
+}
+
+// POST /{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload/finish
+func (s *Server) FinishUpload(w http.ResponseWriter, r *Request) {
+	oid, uploadID, size, ok := s.parseUploadSession(w, r, true)
+	if !ok {
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	if size, err = rr.ODB().FinishUpload(r.Context(), oid, uploadID, size); err != nil {
+		renderUploadFailure(w, r, oid, err)
+		return
+	}
+	logrus.Infof("%s upload large object %s [size: %s] to %s [refname: %s] success", checkName(r), oid, strengthen.HumanateSize(size), r.makeRemoteURL(), mux.Vars(r.Request)["refname"])
+	ZetaEncodeVND(w, &protocol.ErrorCode{Code: 200, Message: "OK"})
+}
+
+// DELETE /{namespace}/{repo}/reference/{refname:.*}/objects/{oid}/upload
+func (s *Server) AbortUpload(w http.ResponseWriter, r *Request) {
+	oid, uploadID, _, ok := s.parseUploadSession(w, r, true)
+	if !ok {
+		return
+	}
+	rr, err := s.open(w, r)
+	if err != nil {
+		return
+	}
+	defer rr.Close()
+	if err := rr.ODB().AbortUpload(r.Context(), oid, uploadID); err != nil {
+		renderUploadFailure(w, r, oid, err)
+		return
+	}
+	ZetaEncodeVND(w, &protocol.ErrorCode{Code: 200, Message: "OK"})
+}
+
 const (
 	GeneralBranch      = 0
 	ProtectedBranch    = 10
@@ -425,30 +705,26 @@ const (
 	ConfidentialBranch = 30
 )
 
-func (s *Server) checkBranchCanUpdate(ctx context.Context, w http.ResponseWriter, r *Request, branchName string) (*database.Branch, error) {
+// branchCanUpdate: returns *zeta.ErrStatusCode if the branch is not allowed to be updated
+func (s *Server) branchCanUpdate(ctx context.Context, r *Request, branchName string) (*database.Branch, error) {
 	if !plumbing.ValidateBranchName([]byte(branchName)) {
-		renderFailureFormat(w, r.Request, http.StatusBadRequest, r.W("'%s' is not a valid branch name"), branchName)
-		return nil, ErrStop
+		return nil, zeta.NewErrStatusCode(http.StatusBadRequest, r.W("'%s' is not a valid branch name"), branchName)
 	}
 	branch, err := s.db.FindBranch(ctx, r.R.ID, branchName)
 	if database.IsNotFound(err) {
 		return nil, nil
 	}
 	if err != nil {
-		renderFailureFormat(w, r.Request, http.StatusInternalServerError, r.W("internal server error: %v"), err)
-		return nil, ErrStop
+		return nil, zeta.NewErrStatusCode(http.StatusInternalServerError, r.W("internal server error: %v"), err)
 	}
 	switch branch.ProtectionLevel {
 	case ConfidentialBranch:
-		renderFailureFormat(w, r.Request, http.StatusNotFound, r.W("'%s' is archived, cannot be modified"), branchName)
-		return nil, ErrStop
+		return nil, zeta.NewErrStatusCode(http.StatusNotFound, r.W("'%s' is archived, cannot be modified"), branchName)
 	case ArchivedBranch:
-		renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("'%s' is archived, cannot be modified"), branchName)
-		return nil, ErrStop
+		return nil, zeta.NewErrStatusCode(http.StatusForbidden, r.W("'%s' is archived, cannot be modified"), branchName)
 	case ProtectedBranch:
 		if !r.U.Administrator {
-			renderFailureFormat(w, r.Request, http.StatusForbidden, r.W("'%s' is protected branch, cannot be modified"), branchName)
-			return nil, ErrStop
+			return nil, zeta.NewErrStatusCode(http.StatusForbidden, r.W("'%s' is protected branch, cannot be modified"), branchName)
 		}
 		return branch, nil
 	default:
@@ -456,6 +732,88 @@ func (s *Server) checkBranchCanUpdate(ctx context.Context, w http.ResponseWriter
 	return branch, nil
 }
 
+func (s *Server) checkBranchCanUpdate(ctx context.Context, w http.ResponseWriter, r *Request, branchName string) (*database.Branch, error) {
+	branch, err := s.branchCanUpdate(ctx, r, branchName)
+	if err != nil {
+		if e, ok := err.(*zeta.ErrStatusCode); ok {
+			renderFailure(w, r.Request, e.Code, e.Message)
+		}
+		return nil, ErrStop
+	}
+	return branch, nil
+}
+
+// checkProtection: fill the protection rule, the access level and the path ACL of the command, returns the reason if
+// the user is not allowed to update the reference.
+func (s *Server) checkProtection(r *Request, cmd *repo.Command) (string, error) {
+	rules, err := s.db.ListProtectionRules(r.Context(), r.R.ID)
+	if err != nil {
+		return "", err
+	}
+	cmd.Protection = database.MatchProtectionRules(rules, cmd.ReferenceName)
+	cmd.AccessLevel = r.AccessLevel
+	if cmd.PathACL, err = s.pathACL(r); err != nil {
+		return "", err
+	}
+	return cmd.ProtectionReason(), nil
+}
+
+// pathACL: path access control of the user, nil if the user is allowed to access all paths of the repository
+func (s *Server) pathACL(r *Request) (*repo.PathACL, error) {
+	acls, err := s.db.ListPathACLs(r.Context(), r.R.ID)
+	if err != nil || len(acls) == 0 {
+		return nil, err
+	}
+	var groups []int64
+	if r.U != nil {
+		if groups, err = s.db.UserGroups(r.Context(), r.U.ID); err != nil {
+			return nil, err
+		}
+	}
+	// the repository is readable after the permission check, eg: public repository
+	return repo.NewPathACL(acls, groups, max(r.AccessLevel, database.ReporterAccess)), nil
+}
+
+// refusedObjects: objects under the paths the user is not allowed to read, see repo.HiddenObjects
+func (s *Server) refusedObjects(r *Request, rr repo.Repository, oids []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	acl, err := s.pathACL(r)
+	if err != nil || acl == nil {
+		return nil, err
+	}
+	hidden, err := rr.HiddenObjects(r.Context(), acl)
+	if err != nil {
+		return nil, err
+	}
+	return hidden.Refused(r.Context(), oids)
+}
+
+func (s *Server) renderProtection(w http.ResponseWriter, r *Request, cmd *repo.Command) bool {
+	reason, err := s.checkProtection(r, cmd)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusInternalServerError, r.W("internal server error: %v"), err)
+		return false
+	}
+	if len(reason) != 0 {
+		renderFailure(w, r.Request, http.StatusForbidden, reason)
+		return false
+	}
+	return true
+}
+
+func (s *Server) parsePushOptions(w http.ResponseWriter, r *Request) (*repo.PushOptions, bool) {
+	options, err := repo.ReadPushOptions(r.Header.Get, ZETA_PUSH_OPTION_COUNT, ZETA_PUSH_OPTION_PREFIX)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "%v", err)
+		return nil, false
+	}
+	o, err := repo.ParsePushOptions(options, s.PushOptions)
+	if err != nil {
+		renderFailureFormat(w, r.Request, http.StatusBadRequest, "%v", err)
+		return nil, false
+	}
+	return o, true
+}
+
 // POST /{namespace}/{repo}/reference/{refname:.*}
 func (s *Server) Push(w http.ResponseWriter, r *Request) {
 	escapedRefname := mux.Vars(r.Request)["refname"]
@@ -500,11 +858,14 @@ func (s *Server) TagPush(w http.ResponseWriter, r *Request, tagName string) {
 	command := &repo.Command{
 		RID:           r.R.ID,
 		UID:           r.U.ID,
+		KeyID:         r.KID,
 		ReferenceName: plumbing.NewTagReferenceName(tagName),
 		OldRev:        r.Header.Get("X-Zeta-Command-OldRev"),
 		NewRev:        r.Header.Get("X-Zeta-Command-NewRev"),
 		Terminal:      r.Header.Get("X-Zeta-Termainl"),
 		Language:      serve.Language(r.Request),
+		Protocol:      "http",
+		ClientIP:      parseRemoteAddress(r.Request),
 	}
 	if !plumbing.ValidateHashHex(command.NewRev) {
 		renderFailureFormat(w, r.Request, http.StatusBadRequest, "NewRev '%s' is bad commit", command.NewRev)
@@ -518,7 +879,14 @@ func (s *Server) TagPush(w http.ResponseWriter, r *Request, tagName string) {
 		renderFailure(w, r.Request, http.StatusConflict, r.W("tag is updated, please update and try again"))
 		return
 	}
+	if !s.renderProtection(w, r, command) {
+		return
+	}
 	command.UpdateStats(r.Header.Get("X-Zeta-Objects-Stats"))
+	var ok bool
+	if command.PushOptions, ok = s.parsePushOptions(w, r); !ok {
+		return
+	}
 	rr, err := s.open(w, r)
 	if err != nil {
 		s.renderError(w, r, err)
@@ -544,11 +912,14 @@ func (s *Server) BranchPush(w http.ResponseWriter, r *Request, branchName string
 	command := &repo.Command{
 		RID:           r.R.ID,
 		UID:           r.U.ID,
+		KeyID:         r.KID,
 		ReferenceName: plumbing.NewBranchReferenceName(branchName),
 		OldRev:        r.Header.Get("X-Zeta-Command-OldRev"),
 		NewRev:        r.Header.Get("X-Zeta-Command-NewRev"),
 		Terminal:      r.Header.Get("X-Zeta-Termainl"),
 		Language:      serve.Language(r.Request),
+		Protocol:      "http",
+		ClientIP:      parseRemoteAddress(r.Request),
 	}
 	if !plumbing.ValidateHashHex(command.NewRev) {
 		renderFailureFormat(w, r.Request, http.StatusBadRequest, "NewRev '%s' is bad commit", command.NewRev)
@@ -562,7 +933,14 @@ func (s *Server) BranchPush(w http.ResponseWriter, r *Request, branchName string
 		renderFailure(w, r.Request, http.StatusConflict, r.W("branch is updated, please update and try again"))
 		return
 	}
+	if !s.renderProtection(w, r, command) {
+		return
+	}
 	command.UpdateStats(r.Header.Get("X-Zeta-Objects-Stats"))
+	var ok bool
+	if command.PushOptions, ok = s.parsePushOptions(w, r); !ok {
+		return
+	}
 	rr, err := s.open(w, r)
 	if err != nil {
 		s.renderError(w, r, err)
@@ -0,0 +1,97 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+)
+
+// observedBucket: record the latency and errors of each Bucket method
+type observedBucket struct {
+	oss.Bucket
+}
+
+// ObserveBucket: wrap the bucket so that its operations are reported
+func ObserveBucket(b oss.Bucket) oss.Bucket {
+	return &observedBucket{Bucket: b}
+}
+
+func observeOSS(method string, start time.Time, err error) {
+	OSSRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		OSSErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (b *observedBucket) Stat(ctx context.Context, resourcePath string) (st *oss.Stat, err error) {
+	defer func(start time.Time) { observeOSS("Stat", start, err) }(time.Now())
+	return b.Bucket.Stat(ctx, resourcePath)
+}
+
+// Open: latency until the object is opened, reading the body is not included.
+func (b *observedBucket) Open(ctx context.Context, resourcePath string, start, length int64) (rr oss.RangeReader, err error) {
+	defer func(start time.Time) { observeOSS("Open", start, err) }(time.Now())
+	return b.Bucket.Open(ctx, resourcePath, start, length)
+}
+
+func (b *observedBucket) Delete(ctx context.Context, resourcePath string) (err error) {
+	defer func(start time.Time) { observeOSS("Delete", start, err) }(time.Now())
+	return b.Bucket.Delete(ctx, resourcePath)
+}
+
+func (b *observedBucket) Put(ctx context.Context, resourcePath string, r io.Reader, mime string) (err error) {
+	defer func(start time.Time) { observeOSS("Put", start, err) }(time.Now())
+	return b.Bucket.Put(ctx, resourcePath, r, mime)
+}
+
+func (b *observedBucket) StartUpload(ctx context.Context, resourcePath, filePath string, mime string) (err error) {
+	defer func(start time.Time) { observeOSS("StartUpload", start, err) }(time.Now())
+	return b.Bucket.StartUpload(ctx, resourcePath, filePath, mime)
+}
+
+func (b *observedBucket) LinearUpload(ctx context.Context, resourcePath string, r io.Reader, size int64, mime string) (err error) {
+	defer func(start time.Time) { observeOSS("LinearUpload", start, err) }(time.Now())
+	return b.Bucket.LinearUpload(ctx, resourcePath, r, size, mime)
+}
+
+func (b *observedBucket) InitiateUpload(ctx context.Context, resourcePath string, mime string) (uploadID string, err error) {
+	defer func(start time.Time) { observeOSS("InitiateUpload", start, err) }(time.Now())
+	return b.Bucket.InitiateUpload(ctx, resourcePath, mime)
+}
+
+func (b *observedBucket) UploadPart(ctx context.Context, resourcePath, uploadID string, partNumber int, r io.Reader, size int64) (err error) {
+	defer func(start time.Time) { observeOSS("UploadPart", start, err) }(time.Now())
+	return b.Bucket.UploadPart(ctx, resourcePath, uploadID, partNumber, r, size)
+}
+
+func (b *observedBucket) ListParts(ctx context.Context, resourcePath, uploadID string) (parts []*oss.Part, err error) {
+	defer func(start time.Time) { observeOSS("ListParts", start, err) }(time.Now())
+	return b.Bucket.ListParts(ctx, resourcePath, uploadID)
+}
+
+func (b *observedBucket) CompleteUpload(ctx context.Context, resourcePath, uploadID string, parts []*oss.Part) (err error) {
+	defer func(start time.Time) { observeOSS("CompleteUpload", start, err) }(time.Now())
+	return b.Bucket.CompleteUpload(ctx, resourcePath, uploadID, parts)
+}
+
+func (b *observedBucket) AbortUpload(ctx context.Context, resourcePath, uploadID string) (err error) {
+	defer func(start time.Time) { observeOSS("AbortUpload", start, err) }(time.Now())
+	return b.Bucket.AbortUpload(ctx, resourcePath, uploadID)
+}
+
+func (b *observedBucket) DeleteMultipleObjects(ctx context.Context, objectKeys []string) (err error) {
+	defer func(start time.Time) { observeOSS("DeleteMultipleObjects", start, err) }(time.Now())
+	return b.Bucket.DeleteMultipleObjects(ctx, objectKeys)
+}
+
+func (b *observedBucket) ListObjects(ctx context.Context, prefix, continuationToken string) (objects []*oss.Object, next string, err error) {
+	defer func(start time.Time) { observeOSS("ListObjects", start, err) }(time.Now())
+	return b.Bucket.ListObjects(ctx, prefix, continuationToken)
+}
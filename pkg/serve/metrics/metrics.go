@@ -0,0 +1,173 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	namespace = "zeta"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+)
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 1800},
+	}, []string{"route", "method", "code"})
+	HTTPReceivedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "received_bytes_total",
+		Help:      "Bytes of HTTP request bodies by route.",
+	}, []string{"route"})
+	HTTPWrittenBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "written_bytes_total",
+		Help:      "Bytes of HTTP response bodies by route.",
+	}, []string{"route"})
+	HTTPActiveRequests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "active_requests",
+		Help:      "Number of HTTP requests being served.",
+	})
+	SSHActiveSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ssh",
+		Name:      "active_sessions",
+		Help:      "Number of SSH sessions being served.",
+	})
+	SSHCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ssh",
+		Name:      "commands_total",
+		Help:      "Number of SSH commands by command and exit code.",
+	}, []string{"command", "exit_code"})
+	SSHCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ssh",
+		Name:      "command_duration_seconds",
+		Help:      "Latency of SSH commands by command.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 1800},
+	}, []string{"command"})
+	PushDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "push",
+		Name:      "duration_seconds",
+		Help:      "Duration of pushes by kind (push or atomic) and result (ok or failed).",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 1800},
+	}, []string{"kind", "result"})
+	PushFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "push",
+		Name:      "failures_total",
+		Help:      "Number of failed pushes by reason.",
+	}, []string{"reason"})
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by rate limits and concurrency quotas by key kind.",
+	}, []string{"kind"})
+	OSSRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "oss",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of OSS operations by Bucket method.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method"})
+	OSSErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "oss",
+		Name:      "errors_total",
+		Help:      "Number of failed OSS operations by Bucket method, objects not found are not counted.",
+	}, []string{"method"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPReceivedBytes,
+		HTTPWrittenBytes,
+		HTTPActiveRequests,
+		SSHActiveSessions,
+		SSHCommands,
+		SSHCommandDuration,
+		PushDuration,
+		PushFailures,
+		RateLimited,
+		OSSRequestDuration,
+		OSSErrors,
+		cacheStats,
+		dbStats,
+	)
+}
+
+// HTTPMethod: the method label of HTTP metrics, methods not in RFC 9110 are counted as "other", so that a client
+// can't add label values by sending arbitrary methods.
+func HTTPMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// Handler: the /metrics handler of the registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Server: serve /metrics on a separate listen address, so that it is not exposed with the repositories.
+type Server struct {
+	srv *http.Server
+}
+
+func NewServer(listen string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return &Server{srv: &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+}
+
+// Start: listen and serve in the background, errors are logged.
+func (s *Server) Start() {
+	go func() {
+		logrus.Infof("Zeta metrics listen: %v", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("metrics listen server error: %v", err)
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCacheStats struct{}
+
+func (fakeCacheStats) Hits() uint64        { return 3 }
+func (fakeCacheStats) Misses() uint64      { return 1 }
+func (fakeCacheStats) KeysAdded() uint64   { return 4 }
+func (fakeCacheStats) KeysEvicted() uint64 { return 0 }
+
+func TestObserveBucket(t *testing.T) {
+	ctx := context.Background()
+	local, err := oss.NewLocalBucket(&oss.NewLocalBucketOptions{Root: t.TempDir(), SharedEndpoint: "http://zeta.example.io", Secret: "secret"})
+	require.NoError(t, err)
+	b := ObserveBucket(local)
+	require.NoError(t, b.Put(ctx, "zeta/001/1/ab/cd/abcd", strings.NewReader("abcd"), ""))
+	_, err = b.Stat(ctx, "zeta/001/1/ab/cd/none")
+	require.Equal(t, os.ErrNotExist, err)
+	require.Error(t, b.Put(ctx, "../escape", strings.NewReader("x"), ""))
+
+	require.Equal(t, 0.0, testutil.ToFloat64(OSSErrors.WithLabelValues("Stat")))
+	require.Equal(t, 1.0, testutil.ToFloat64(OSSErrors.WithLabelValues("Put")))
+	require.Equal(t, 2, testutil.CollectAndCount(OSSRequestDuration))
+}
+
+func TestHTTPMethod(t *testing.T) {
+	require.Equal(t, "GET", HTTPMethod("GET"))
+	require.Equal(t, "DELETE", HTTPMethod("DELETE"))
+	require.Equal(t, "other", HTTPMethod("get"))
+	require.Equal(t, "other", HTTPMethod("PROPFIND"))
+}
+
+func TestHandler(t *testing.T) {
+	SetCacheStats(fakeCacheStats{})
+	HTTPRequests.WithLabelValues("/{namespace}/{repo}/references", "GET", "200").Inc()
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `zeta_http_requests_total{code="200",method="GET",route="/{namespace}/{repo}/references"} 1`)
+	require.Contains(t, string(body), "zeta_cache_hits_total 3")
+	require.Contains(t, string(body), "go_goroutines")
+	require.NotContains(t, string(body), "zeta_db_open_connections")
+}
@@ -0,0 +1,123 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheStats: statistics of the object cache, satisfied by *ristretto.Metrics
+type CacheStats interface {
+	Hits() uint64
+	Misses() uint64
+	KeysAdded() uint64
+	KeysEvicted() uint64
+}
+
+type cacheCollector struct {
+	stats       *atomic.Pointer[CacheStats]
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	keysAdded   *prometheus.Desc
+	keysEvicted *prometheus.Desc
+}
+
+var (
+	cacheStats = &cacheCollector{
+		stats:       &atomic.Pointer[CacheStats]{},
+		hits:        prometheus.NewDesc(namespace+"_cache_hits_total", "Number of object cache hits.", nil, nil),
+		misses:      prometheus.NewDesc(namespace+"_cache_misses_total", "Number of object cache misses.", nil, nil),
+		keysAdded:   prometheus.NewDesc(namespace+"_cache_keys_added_total", "Number of objects added to the object cache.", nil, nil),
+		keysEvicted: prometheus.NewDesc(namespace+"_cache_keys_evicted_total", "Number of objects evicted from the object cache.", nil, nil),
+	}
+)
+
+// SetCacheStats: report the statistics of the object cache
+func SetCacheStats(stats CacheStats) {
+	cacheStats.stats.Store(&stats)
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.keysAdded
+	ch <- c.keysEvicted
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats.Load()
+	if stats == nil || *stats == nil {
+		return
+	}
+	s := *stats
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits()))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses()))
+	ch <- prometheus.MustNewConstMetric(c.keysAdded, prometheus.CounterValue, float64(s.KeysAdded()))
+	ch <- prometheus.MustNewConstMetric(c.keysEvicted, prometheus.CounterValue, float64(s.KeysEvicted()))
+}
+
+type dbCollector struct {
+	db                *atomic.Pointer[sql.DB]
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+func newDBDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(namespace+"_db_"+name, help, nil, nil)
+}
+
+var (
+	dbStats = &dbCollector{
+		db:                &atomic.Pointer[sql.DB]{},
+		maxOpen:           newDBDesc("max_open_connections", "Maximum number of open connections of the database pool."),
+		open:              newDBDesc("open_connections", "Number of established connections of the database pool."),
+		inUse:             newDBDesc("in_use_connections", "Number of connections in use."),
+		idle:              newDBDesc("idle_connections", "Number of idle connections."),
+		waitCount:         newDBDesc("wait_count_total", "Number of connections waited for."),
+		waitDuration:      newDBDesc("wait_duration_seconds_total", "Time blocked waiting for a new connection."),
+		maxIdleClosed:     newDBDesc("max_idle_closed_total", "Number of connections closed due to the idle limit."),
+		maxLifetimeClosed: newDBDesc("max_lifetime_closed_total", "Number of connections closed due to the lifetime limit."),
+	}
+)
+
+// SetDB: report the pool statistics of the metadata database
+func SetDB(db *sql.DB) {
+	dbStats.db.Store(db)
+}
+
+func (c *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	db := c.db.Load()
+	if db == nil {
+		return
+	}
+	s := db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
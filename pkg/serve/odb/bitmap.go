@@ -0,0 +1,637 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package odb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/bitmap"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bitmapIndexName = "reachability.bitmap"
+	// bitmapInterval: besides reference tips, a bitmap is kept for every N commits walked.
+	bitmapInterval = 100
+	// staleBitmapLockTimeout: lock files of the index older than this are left by crashed processes.
+	staleBitmapLockTimeout = 30 * time.Minute
+)
+
+var (
+	bitmapIndexMagic = [4]byte{'Z', 'R', '\x00', '\x01'}
+)
+
+const (
+	bitmapCommit uint8 = iota + 1
+	bitmapTree
+	bitmapFragments
+	bitmapBlob
+)
+
+// BitmapIndex: reachability bitmaps over a per-repository object ordering.
+//
+// Every object (commit, tree, fragments, blob) gets a stable position the first time it is seen,
+// a commit bitmap has the positions of all objects reachable from this commit set.
+//
+// The index is shared by the requests of the repository, it is only extended by the background builder, see merge.
+type BitmapIndex struct {
+	mu        sync.RWMutex
+	objects   []plumbing.Hash
+	types     []uint8
+	positions map[plumbing.Hash]int64
+	bitmaps   map[plumbing.Hash]*bitmap.Bitmap
+}
+
+func newBitmapIndex() *BitmapIndex {
+	return &BitmapIndex{
+		positions: make(map[plumbing.Hash]int64),
+		bitmaps:   make(map[plumbing.Hash]*bitmap.Bitmap),
+	}
+}
+
+// Objects: number of objects in ordering
+func (x *BitmapIndex) Objects() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.objects)
+}
+
+// Bitmaps: number of commits with bitmap
+func (x *BitmapIndex) Bitmaps() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.bitmaps)
+}
+
+// union: objects reachable from the commits, false when some commits have no bitmap yet. The returned bitmap is a
+// copy owned by the caller.
+func (x *BitmapIndex) union(oids []plumbing.Hash) (*bitmap.Bitmap, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	b := bitmap.New()
+	for _, oid := range oids {
+		cb, ok := x.bitmaps[oid]
+		if !ok {
+			return nil, false
+		}
+		b = b.Or(cb)
+	}
+	return b, true
+}
+
+// bitmapUpdate: objects and bitmaps computed on top of an index without modifying it, merged at once.
+type bitmapUpdate struct {
+	x         *BitmapIndex
+	objects   []plumbing.Hash
+	types     []uint8
+	positions map[plumbing.Hash]int64
+	bitmaps   map[plumbing.Hash]*bitmap.Bitmap
+	unknown   []plumbing.Hash // tips not found in the repository
+}
+
+func newBitmapUpdate(x *BitmapIndex) *bitmapUpdate {
+	return &bitmapUpdate{
+		x:         x,
+		positions: make(map[plumbing.Hash]int64),
+		bitmaps:   make(map[plumbing.Hash]*bitmap.Bitmap),
+	}
+}
+
+// the builder is the only writer of the index, reading it without lock is safe here.
+func (u *bitmapUpdate) add(oid plumbing.Hash, t uint8) int64 {
+	if pos, ok := u.x.positions[oid]; ok {
+		return pos
+	}
+	if pos, ok := u.positions[oid]; ok {
+		return pos
+	}
+	pos := int64(len(u.x.objects) + len(u.objects))
+	u.objects = append(u.objects, oid)
+	u.types = append(u.types, t)
+	u.positions[oid] = pos
+	return pos
+}
+
+func (u *bitmapUpdate) bitmap(oid plumbing.Hash) *bitmap.Bitmap {
+	if b, ok := u.x.bitmaps[oid]; ok {
+		return b
+	}
+	return u.bitmaps[oid]
+}
+
+// merge: publish the update to the readers of the index.
+func (x *BitmapIndex) merge(u *bitmapUpdate) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.objects = append(x.objects, u.objects...)
+	x.types = append(x.types, u.types...)
+	for oid, pos := range u.positions {
+		x.positions[oid] = pos
+	}
+	for oid, b := range u.bitmaps {
+		x.bitmaps[oid] = b
+	}
+}
+
+func newBitmapFromPositions(positions []int64) (*bitmap.Bitmap, error) {
+	slices.Sort(positions)
+	b := bitmap.New()
+	for i, pos := range positions {
+		if i > 0 && positions[i-1] == pos {
+			continue
+		}
+		if err := b.Set(pos); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// topoOrder: commits reachable from tips that have no bitmap yet, parents first.
+func (u *bitmapUpdate) topoOrder(ctx context.Context, db DB, tips []plumbing.Hash) ([]*object.Commit, error) {
+	type frame struct {
+		cc   *object.Commit
+		next int
+	}
+	order := make([]*object.Commit, 0, 100)
+	visited := make(map[plumbing.Hash]bool)
+	for _, tip := range tips {
+		if visited[tip] || u.bitmap(tip) != nil {
+			continue
+		}
+		visited[tip] = true
+		cc, err := db.Commit(ctx, tip)
+		if plumbing.IsNoSuchObject(err) {
+			// unknown to server, eg: client local commit
+			u.unknown = append(u.unknown, tip)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stack := []*frame{{cc: cc}}
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.next < len(top.cc.Parents) {
+				p := top.cc.Parents[top.next]
+				top.next++
+				if visited[p] || u.bitmap(p) != nil {
+					continue
+				}
+				visited[p] = true
+				pc, err := db.Commit(ctx, p)
+				if plumbing.IsNoSuchObject(err) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, &frame{cc: pc})
+				continue
+			}
+			order = append(order, top.cc)
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return order, nil
+}
+
+type bitmapWalker struct {
+	u         *bitmapUpdate
+	db        DB
+	base      *bitmap.Bitmap
+	seen      map[int64]bool
+	positions []int64
+}
+
+func (w *bitmapWalker) mark(oid plumbing.Hash, t uint8) bool {
+	pos := w.u.add(oid, t)
+	if w.seen[pos] || w.base.Get(pos) {
+		return false
+	}
+	w.seen[pos] = true
+	w.positions = append(w.positions, pos)
+	return true
+}
+
+func (w *bitmapWalker) walkTree(ctx context.Context, oid plumbing.Hash) error {
+	if !w.mark(oid, bitmapTree) {
+		return nil
+	}
+	tree, err := w.db.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := w.walkTree(ctx, e.Hash); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if !w.mark(e.Hash, bitmapFragments) {
+				continue
+			}
+			ff, err := w.db.Fragments(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			for _, fe := range ff.Entries {
+				w.mark(fe.Hash, bitmapBlob)
+			}
+		case object.BlobObject:
+			if e.Hash.IsZero() || e.Hash == backend.BLANK_BLOB_HASH {
+				continue
+			}
+			w.mark(e.Hash, bitmapBlob)
+		}
+	}
+	return nil
+}
+
+// extend: compute bitmaps of commits reachable from tips which have no bitmap yet, keep the bitmaps of tips and of
+// periodic points of the newly walked history.
+func (u *bitmapUpdate) extend(ctx context.Context, db DB, tips []plumbing.Hash) error {
+	order, err := u.topoOrder(ctx, db, tips)
+	if err != nil {
+		return err
+	}
+	computed := make(map[plumbing.Hash]*bitmap.Bitmap, len(order))
+	lookup := func(oid plumbing.Hash) *bitmap.Bitmap {
+		if b := u.bitmap(oid); b != nil {
+			return b
+		}
+		return computed[oid]
+	}
+	for _, cc := range order {
+		base := bitmap.New()
+		for _, p := range cc.Parents {
+			if pb := lookup(p); pb != nil {
+				base = base.Or(pb)
+			}
+		}
+		w := &bitmapWalker{u: u, db: db, base: base, seen: make(map[int64]bool)}
+		w.mark(cc.Hash, bitmapCommit)
+		if err := w.walkTree(ctx, cc.Tree); err != nil {
+			return err
+		}
+		b, err := newBitmapFromPositions(w.positions)
+		if err != nil {
+			return err
+		}
+		computed[cc.Hash] = base.Or(b)
+	}
+	for i, cc := range order {
+		if i%bitmapInterval == 0 {
+			u.bitmaps[cc.Hash] = computed[cc.Hash]
+		}
+	}
+	for _, tip := range tips {
+		if b, ok := computed[tip]; ok {
+			u.bitmaps[tip] = b
+		}
+	}
+	return nil
+}
+
+// Reachability: objects reachable from a set of commits.
+type Reachability struct {
+	index  *BitmapIndex
+	bitmap *bitmap.Bitmap
+}
+
+// Contains: returns true when oid is reachable. Safe to call on nil.
+func (r *Reachability) Contains(oid plumbing.Hash) bool {
+	if r == nil {
+		return false
+	}
+	r.index.mu.RLock()
+	pos, ok := r.index.positions[oid]
+	r.index.mu.RUnlock()
+	return ok && r.bitmap.Get(pos)
+}
+
+// Count: number of reachable objects.
+func (r *Reachability) Count() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.bitmap.Count()
+}
+
+func decodeBitmapIndex(r io.Reader) (*BitmapIndex, error) {
+	cr := crc.NewCrc64Reader(r)
+	var magic [4]byte
+	if _, err := io.ReadFull(cr, magic[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic[:], bitmapIndexMagic[:]) {
+		return nil, fmt.Errorf("unexpected bitmap index magic '%c' '%c' '%c' '%c'", magic[0], magic[1], magic[2], magic[3])
+	}
+	var objects uint32
+	if err := binary.Read(cr, binary.BigEndian, &objects); err != nil {
+		return nil, err
+	}
+	x := newBitmapIndex()
+	x.objects = make([]plumbing.Hash, objects)
+	x.types = make([]uint8, objects)
+	for i := range x.objects {
+		if _, err := io.ReadFull(cr, x.objects[i][:]); err != nil {
+			return nil, err
+		}
+		x.positions[x.objects[i]] = int64(i)
+	}
+	if _, err := io.ReadFull(cr, x.types); err != nil {
+		return nil, err
+	}
+	var count uint32
+	if err := binary.Read(cr, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		var oid plumbing.Hash
+		if _, err := io.ReadFull(cr, oid[:]); err != nil {
+			return nil, err
+		}
+		b, err := bitmap.FromReader(cr, binary.BigEndian)
+		if err != nil {
+			return nil, err
+		}
+		x.bitmaps[oid] = b
+	}
+	if err := cr.Verify(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *BitmapIndex) encode(w io.Writer) error {
+	cw := crc.NewCrc64Writer(w)
+	if _, err := cw.Write(bitmapIndexMagic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(cw, binary.BigEndian, uint32(len(x.objects))); err != nil {
+		return err
+	}
+	for _, oid := range x.objects {
+		if _, err := cw.Write(oid[:]); err != nil {
+			return err
+		}
+	}
+	if _, err := cw.Write(x.types); err != nil {
+		return err
+	}
+	if err := binary.Write(cw, binary.BigEndian, uint32(len(x.bitmaps))); err != nil {
+		return err
+	}
+	for oid, b := range x.bitmaps {
+		if _, err := cw.Write(oid[:]); err != nil {
+			return err
+		}
+		if _, err := b.Write(cw, binary.BigEndian); err != nil {
+			return err
+		}
+	}
+	_, err := cw.Finish()
+	return err
+}
+
+var (
+	// ErrBitmapIndexNotReady: the reachability bitmaps of the commits are being built in the background, retry later.
+	ErrBitmapIndexNotReady = errors.New("reachability bitmaps are not ready, retry later")
+)
+
+// bitmapIndexes: *bitmapState of repositories, keyed by the path of the index file.
+var bitmapIndexes sync.Map
+
+// bitmapState: the cached index of a repository and its background builder.
+type bitmapState struct {
+	mu       sync.Mutex
+	index    *BitmapIndex // nil until loaded by the builder
+	file     os.FileInfo  // the index file when loaded or saved, saving replaces the file
+	unknown  map[plumbing.Hash]bool
+	pending  []plumbing.Hash
+	building bool
+}
+
+func (o *ODB) bitmapIndexPath() string {
+	return filepath.Join(o.odb.Root(), bitmapIndexName)
+}
+
+func (o *ODB) bitmapState() *bitmapState {
+	s, _ := bitmapIndexes.LoadOrStore(o.bitmapIndexPath(), &bitmapState{unknown: make(map[plumbing.Hash]bool)})
+	return s.(*bitmapState)
+}
+
+// resolve: the cached index and the commits unknown to the repository.
+func (s *bitmapState) resolve(oids []plumbing.Hash) (*BitmapIndex, []plumbing.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := make([]plumbing.Hash, 0, len(oids))
+	for _, oid := range oids {
+		if !s.unknown[oid] {
+			known = append(known, oid)
+		}
+	}
+	return s.index, known
+}
+
+// UpdateBitmapIndex: extend reachability bitmaps with commits reachable from tips in the background.
+func (o *ODB) UpdateBitmapIndex(tips ...plumbing.Hash) {
+	if len(tips) == 0 {
+		return
+	}
+	s := o.bitmapState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, tips...)
+	if s.building {
+		return
+	}
+	// the odb of the request is closed when the request is done, the builder uses its own.
+	bo, err := NewODB(o.rid, o.odb.Root(), o.odb.CompressionALGO(), o.cdb, o.mdb, o.bucket)
+	if err != nil {
+		logrus.Warnf("repo %d: open odb for bitmap index error: %v", o.rid, err)
+		return
+	}
+	s.building = true
+	go func() {
+		defer bo.Close()
+		for {
+			s.mu.Lock()
+			tips := s.pending
+			s.pending = nil
+			if len(tips) == 0 {
+				s.building = false
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+			if err := buildBitmapIndex(context.Background(), bo, bo.bitmapIndexPath(), s, tips); err != nil {
+				logrus.Warnf("repo %d: update bitmap index error: %v", bo.rid, err)
+			}
+		}
+	}()
+}
+
+// lockBitmapIndex: exclusive lock file of the index, other servers sharing the repository storage update the index
+// one after another. Stale locks left by crashed processes are removed.
+func lockBitmapIndex(indexPath string) (func(), error) {
+	lockPath := indexPath + ".lock"
+	for range 2 {
+		fd, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = fd.Close()
+			return func() {
+				_ = os.Remove(lockPath)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		si, err := os.Stat(lockPath)
+		if err != nil || time.Since(si.ModTime()) < staleBitmapLockTimeout {
+			break
+		}
+		_ = os.Remove(lockPath)
+	}
+	return nil, fmt.Errorf("bitmap index is locked by '%s'", lockPath)
+}
+
+// buildBitmapIndex: extend the index with tips, the index file is reloaded first when it was saved by others since.
+func buildBitmapIndex(ctx context.Context, db DB, indexPath string, s *bitmapState, tips []plumbing.Hash) error {
+	unlock, err := lockBitmapIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s.mu.Lock()
+	x, file := s.index, s.file
+	s.mu.Unlock()
+	if si, err := os.Stat(indexPath); err == nil && (x == nil || file == nil || !os.SameFile(si, file)) {
+		if x, err = loadBitmapIndex(indexPath); err != nil {
+			logrus.Warnf("discard broken bitmap index '%s': %v", indexPath, err)
+			x = newBitmapIndex()
+		}
+		file = si
+	} else if x == nil {
+		x = newBitmapIndex()
+	}
+	u := newBitmapUpdate(x)
+	if err := u.extend(ctx, db, tips); err != nil {
+		return err
+	}
+	x.merge(u)
+	if len(u.objects) != 0 || len(u.bitmaps) != 0 {
+		if err := saveBitmapIndex(indexPath, x); err != nil {
+			return err
+		}
+		if si, err := os.Stat(indexPath); err == nil {
+			file = si
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index, s.file = x, file
+	for _, oid := range u.unknown {
+		s.unknown[oid] = true
+	}
+	return nil
+}
+
+func loadBitmapIndex(indexPath string) (*BitmapIndex, error) {
+	fd, err := os.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return decodeBitmapIndex(bufio.NewReader(fd))
+}
+
+func saveBitmapIndex(indexPath string, x *BitmapIndex) error {
+	fd, err := os.CreateTemp(filepath.Dir(indexPath), "reachability-*.tmp")
+	if err != nil {
+		return err
+	}
+	tempName := fd.Name()
+	w := bufio.NewWriter(fd)
+	if err := x.encode(w); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = fd.Close()
+		_ = os.Remove(tempName)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(tempName)
+		return err
+	}
+	return os.Rename(tempName, indexPath)
+}
+
+// Reachable: objects reachable from the given commits, resolved with reachability bitmaps. Returns nil when the
+// bitmaps of the commits are not built yet, they are built in the background.
+func (o *ODB) Reachable(ctx context.Context, oids ...plumbing.Hash) (*Reachability, error) {
+	x, known := o.bitmapState().resolve(oids)
+	if x != nil {
+		if b, ok := x.union(known); ok {
+			return &Reachability{index: x, bitmap: b}, nil
+		}
+	}
+	o.UpdateBitmapIndex(known...)
+	return nil, nil
+}
+
+// MissingObjects: blobs reachable from want but not from have. Returns ErrBitmapIndexNotReady when the bitmaps are
+// not built yet, they are built in the background.
+func (o *ODB) MissingObjects(ctx context.Context, want, have plumbing.Hash) ([]plumbing.Hash, error) {
+	oids := []plumbing.Hash{want}
+	if !have.IsZero() {
+		oids = append(oids, have)
+	}
+	x, known := o.bitmapState().resolve(oids)
+	if len(known) == 0 || known[0] != want {
+		return nil, plumbing.NoSuchObject(want)
+	}
+	var missing, haves *bitmap.Bitmap
+	var ok bool
+	if x != nil {
+		if missing, ok = x.union(known[:1]); ok {
+			haves, ok = x.union(known[1:])
+		}
+	}
+	if !ok {
+		o.UpdateBitmapIndex(known...)
+		return nil, ErrBitmapIndexNotReady
+	}
+	missing = missing.AndNot(haves)
+	blobs := make([]plumbing.Hash, 0, 100)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	missing.ForEach(func(pos int64) bool {
+		if x.types[pos] == bitmapBlob {
+			blobs = append(blobs, x.objects[pos])
+		}
+		return true
+	})
+	return blobs, nil
+}
@@ -0,0 +1,98 @@
+package odb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryDB struct {
+	DB
+	commits map[plumbing.Hash]*object.Commit
+	trees   map[plumbing.Hash]*object.Tree
+}
+
+func (m *memoryDB) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	if cc, ok := m.commits[oid]; ok {
+		return cc, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (m *memoryDB) Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	if t, ok := m.trees[oid]; ok {
+		return t, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func testHash(s string) plumbing.Hash {
+	return plumbing.NewHash(s + "0000000000000000000000000000000000000000000000000000000000000000"[len(s):])
+}
+
+func TestBuildBitmapIndex(t *testing.T) {
+	m := &memoryDB{commits: make(map[plumbing.Hash]*object.Commit), trees: make(map[plumbing.Hash]*object.Tree)}
+	// c1 <- c2 <- c3, every commit adds a blob
+	var parent plumbing.Hash
+	var entries []*object.TreeEntry
+	for i, name := range []string{"1", "2", "3"} {
+		entries = append(entries, &object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: testHash("b" + name)})
+		tree := &object.Tree{Hash: testHash("e" + name), Entries: append([]*object.TreeEntry(nil), entries...)}
+		m.trees[tree.Hash] = tree
+		cc := &object.Commit{Hash: testHash("c" + name), Tree: tree.Hash}
+		if i > 0 {
+			cc.Parents = []plumbing.Hash{parent}
+		}
+		m.commits[cc.Hash] = cc
+		parent = cc.Hash
+	}
+	ctx := context.Background()
+	indexPath := filepath.Join(t.TempDir(), bitmapIndexName)
+	s := &bitmapState{unknown: make(map[plumbing.Hash]bool)}
+	require.NoError(t, buildBitmapIndex(ctx, m, indexPath, s, []plumbing.Hash{testHash("c2"), testHash("ff")}))
+	x, known := s.resolve([]plumbing.Hash{testHash("c2"), testHash("ff")})
+	require.Equal(t, []plumbing.Hash{testHash("c2")}, known)
+	b, ok := x.union(known)
+	require.True(t, ok)
+	r := &Reachability{index: x, bitmap: b}
+	require.True(t, r.Contains(testHash("b2")))
+	require.False(t, r.Contains(testHash("b3")))
+	_, ok = x.union([]plumbing.Hash{testHash("c3")})
+	require.False(t, ok)
+
+	// the index saved by another process is reloaded before extending
+	other := &bitmapState{unknown: make(map[plumbing.Hash]bool)}
+	require.NoError(t, buildBitmapIndex(ctx, m, indexPath, other, []plumbing.Hash{testHash("c3")}))
+	require.NoError(t, buildBitmapIndex(ctx, m, indexPath, s, []plumbing.Hash{testHash("c1")}))
+	x, _ = s.resolve(nil)
+	for _, oid := range []string{"c1", "c2", "c3"} {
+		_, ok = x.union([]plumbing.Hash{testHash(oid)})
+		require.True(t, ok, oid)
+	}
+	b3, _ := x.union([]plumbing.Hash{testHash("c3")})
+	b1, _ := x.union([]plumbing.Hash{testHash("c1")})
+	// c2, c3, e2, e3, b2, b3
+	require.Equal(t, int64(6), b3.AndNot(b1).Count())
+}
+
+func TestBitmapIndexLocked(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), bitmapIndexName)
+	unlock, err := lockBitmapIndex(indexPath)
+	require.NoError(t, err)
+	_, err = lockBitmapIndex(indexPath)
+	require.Error(t, err)
+	s := &bitmapState{unknown: make(map[plumbing.Hash]bool)}
+	require.Error(t, buildBitmapIndex(context.Background(), &memoryDB{}, indexPath, s, []plumbing.Hash{testHash("c1")}))
+	unlock()
+	_, err = os.Stat(indexPath + ".lock")
+	require.True(t, os.IsNotExist(err))
+	unlock, err = lockBitmapIndex(indexPath)
+	require.NoError(t, err)
+	unlock()
+}
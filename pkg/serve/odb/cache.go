@@ -27,6 +27,8 @@ type CacheDB interface {
 	Store(ctx context.Context, rid int64, a any) error
 	Mark(rid int64, oid plumbing.Hash)
 	Exist(rid int64, oid plumbing.Hash) bool
+	// Stats: hits, misses and evictions of the cache
+	Stats() *ristretto.Metrics
 }
 
 type cacheDB struct {
@@ -38,6 +40,7 @@ func NewCacheDB(numCounters int64, maxCost int64, bufferItems int64) (CacheDB, e
 		NumCounters: numCounters,
 		MaxCost:     maxCost << 30,
 		BufferItems: bufferItems,
+		Metrics:     true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("unable initialize memory cache, error: %w", err)
@@ -45,6 +48,10 @@ func NewCacheDB(numCounters int64, maxCost int64, bufferItems int64) (CacheDB, e
 	return &cacheDB{Cache: c}, nil
 }
 
+func (d *cacheDB) Stats() *ristretto.Metrics {
+	return d.Metrics
+}
+
 func (d *cacheDB) Object(ctx context.Context, rid int64, oid plumbing.Hash) (any, error) {
 	if o, ok := d.Get(cacheKey(rid, oid)); ok {
 		return o, nil
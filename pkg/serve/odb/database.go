@@ -9,19 +9,21 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
 	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
 )
 
 type MetadataDB struct {
-	*sql.DB
+	*database.Conn
 	rid int64
 }
 
-func NewMetadataDB(db *sql.DB, rid int64) *MetadataDB {
-	return &MetadataDB{DB: db, rid: rid}
+func NewMetadataDB(conn *database.Conn, rid int64) *MetadataDB {
+	return &MetadataDB{Conn: conn, rid: rid}
 }
 
 func (d *MetadataDB) DecodeCommit(ctx context.Context, oid plumbing.Hash, b object.Backend) (*object.Commit, error) {
@@ -42,7 +44,7 @@ func (d *MetadataDB) EncodeCommit(ctx context.Context, cc *object.Commit) error
 	if err != nil {
 		return err
 	}
-	_, err = d.ExecContext(ctx, "insert into commits(rid, hash, author, committer, bindata, created_at, updated_at) values(?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE rid = rid",
+	_, err = d.ExecContext(ctx, "insert into commits(rid, hash, author, committer, bindata, created_at, updated_at) values(?, ?, ?, ?, ?, ?, ?)"+d.IgnoreDuplicate("rid"),
 		d.rid, cc.Hash.String(), cc.Author.Email, cc.Committer.Email, bindata, cc.Author.When, cc.Committer.When)
 	return err
 }
@@ -64,7 +66,7 @@ func (d *MetadataDB) BatchEncodeCommit(ctx context.Context, commits []*object.Co
 		sb := strings.Builder{}
 		sb.WriteString("insert into commits(rid, hash, author, committer, bindata, created_at, updated_at) values(?, ?, ?, ?, ?, ?, ?)")
 		sb.WriteString(strings.Repeat(", (?, ?, ?, ?, ?, ?, ?)", len(cs)-1))
-		sb.WriteString(" ON DUPLICATE KEY UPDATE rid = rid")
+		sb.WriteString(d.IgnoreDuplicate("rid"))
 		_, err := d.ExecContext(ctx, sb.String(), args...)
 		return err
 	}
@@ -95,7 +97,7 @@ func (d *MetadataDB) EncodeTree(ctx context.Context, t *object.Tree) error {
 	if err != nil {
 		return err
 	}
-	_, err = d.ExecContext(ctx, "insert into trees(rid, hash, bindata) values(?, ?, ?) ON DUPLICATE KEY UPDATE rid = rid",
+	_, err = d.ExecContext(ctx, "insert into trees(rid, hash, bindata) values(?, ?, ?)"+d.IgnoreDuplicate("rid"),
 		d.rid, t.Hash.String(), bindata)
 	return err
 }
@@ -117,7 +119,7 @@ func (d *MetadataDB) BatchEncodeTree(ctx context.Context, trees []*object.Tree)
 		sb := strings.Builder{}
 		sb.WriteString("insert into trees(rid, hash, bindata) values(?, ?, ?)")
 		sb.WriteString(strings.Repeat(", (?, ?, ?)", len(ts)-1))
-		sb.WriteString(" ON DUPLICATE KEY UPDATE rid = rid")
+		sb.WriteString(d.IgnoreDuplicate("rid"))
 		_, err := d.ExecContext(ctx, sb.String(), args...)
 		return err
 	}
@@ -169,7 +171,7 @@ func (d *MetadataDB) Encode(ctx context.Context, oid plumbing.Hash, e object.Enc
 	if err != nil {
 		return err
 	}
-	if _, err := d.ExecContext(ctx, "insert into objects(rid, hash, bindata) values(?, ?, ?) ON DUPLICATE KEY UPDATE rid = rid", d.rid, oid.String(), bindata); err != nil {
+	if _, err := d.ExecContext(ctx, "insert into objects(rid, hash, bindata) values(?, ?, ?)"+d.IgnoreDuplicate("rid"), d.rid, oid.String(), bindata); err != nil {
 		return fmt.Errorf("enocde object %s error: %w", oid, err)
 	}
 	return nil
@@ -192,7 +194,7 @@ func (d *MetadataDB) BatchEncodeFragments(ctx context.Context, fss []*object.Fra
 		sb := strings.Builder{}
 		sb.WriteString("insert into objects(rid, hash, bindata) values(?, ?, ?)")
 		sb.WriteString(strings.Repeat(", (?, ?, ?)", len(fs)-1))
-		sb.WriteString(" ON DUPLICATE KEY UPDATE rid = rid")
+		sb.WriteString(d.IgnoreDuplicate("rid"))
 		_, err := d.ExecContext(ctx, sb.String(), args...)
 		return err
 	}
@@ -223,7 +225,7 @@ func (d *MetadataDB) BatchEncodeTags(ctx context.Context, tags []*object.Tag) er
 		sb := strings.Builder{}
 		sb.WriteString("insert into objects(rid, hash, bindata) values(?, ?, ?)")
 		sb.WriteString(strings.Repeat(", (?, ?, ?)", len(ts)-1))
-		sb.WriteString(" ON DUPLICATE KEY UPDATE rid = rid")
+		sb.WriteString(d.IgnoreDuplicate("rid"))
 		_, err := d.ExecContext(ctx, sb.String(), args...)
 		return err
 	}
@@ -237,6 +239,46 @@ func (d *MetadataDB) BatchEncodeTags(ctx context.Context, tags []*object.Tag) er
 	return nil
 }
 
+// NewUploadSession: record the multipart upload, sessions not finished in time are aborted by AbortExpiredUploads.
+func (d *MetadataDB) NewUploadSession(ctx context.Context, oid plumbing.Hash, uploadID string, size int64) error {
+	_, err := d.ExecContext(ctx, "insert into upload_sessions(rid, oid, upload_id, size, created_at) values(?, ?, ?, ?, ?)",
+		d.rid, oid.String(), uploadID, size, time.Now())
+	return err
+}
+
+// DeleteUploadSession: the multipart upload was completed or aborted.
+func (d *MetadataDB) DeleteUploadSession(ctx context.Context, uploadID string) error {
+	_, err := d.ExecContext(ctx, "delete from upload_sessions where rid = ? and upload_id = ?", d.rid, uploadID)
+	return err
+}
+
+type uploadSession struct {
+	id       int64
+	rid      int64
+	oid      plumbing.Hash
+	uploadID string
+}
+
+// expiredUploadSessions: upload sessions of all repositories created before the deadline, ordered by id after cursor.
+func expiredUploadSessions(ctx context.Context, conn *database.Conn, deadline time.Time, cursor int64, limit int) ([]*uploadSession, error) {
+	rows, err := conn.QueryContext(ctx, "select id, rid, oid, upload_id from upload_sessions where created_at < ? and id > ? order by id limit ?", deadline, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := make([]*uploadSession, 0, limit)
+	for rows.Next() {
+		var s uploadSession
+		var oid string
+		if err := rows.Scan(&s.id, &s.rid, &oid, &s.uploadID); err != nil {
+			return nil, err
+		}
+		s.oid = plumbing.NewHash(oid)
+		sessions = append(sessions, &s)
+	}
+	return sessions, rows.Err()
+}
+
 func (o *ODB) batchCommits(ctx context.Context, oids []plumbing.Hash) error {
 	commits := make([]*object.Commit, 0, len(oids))
 	for _, oid := range oids {
@@ -24,8 +24,15 @@ type DB interface {
 	Blob(ctx context.Context, oid plumbing.Hash) (b *object.Blob, err error)
 	Push(ctx context.Context, oid plumbing.Hash) error // Push object to OSS
 	WriteDirect(ctx context.Context, oid plumbing.Hash, r io.Reader, size int64) (int64, error)
+	NewUpload(ctx context.Context, oid plumbing.Hash, size int64) (*Upload, error)
+	UploadStatus(ctx context.Context, oid plumbing.Hash, uploadID string, size int64) (*Upload, error)
+	UploadChunk(ctx context.Context, oid plumbing.Hash, uploadID string, size, offset int64, r io.Reader, length int64) (*Upload, error)
+	FinishUpload(ctx context.Context, oid plumbing.Hash, uploadID string, size int64) (int64, error)
+	AbortUpload(ctx context.Context, oid plumbing.Hash, uploadID string) error
 	Stat(ctx context.Context, oid plumbing.Hash) (*oss.Stat, error)
 	Sharing(ctx context.Context, oid plumbing.Hash, expiresAt int64) (*Representation, error)
+	Reachable(ctx context.Context, oids ...plumbing.Hash) (*Reachability, error)
+	MissingObjects(ctx context.Context, want, have plumbing.Hash) ([]plumbing.Hash, error)
 }
 
 type ODB struct {
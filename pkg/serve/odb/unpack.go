@@ -47,18 +47,40 @@ type Objects struct {
 
 type Validator func(ctx context.Context, quarantineDir string, o *Objects) error
 
+// newQuarantineDir: objects of the push are kept in the quarantine under 'incoming' until they are verified.
+func (o *ODB) newQuarantineDir() (string, error) {
+	incoming := filepath.Join(o.odb.Root(), "incoming")
+	if err := os.MkdirAll(incoming, 0755); err != nil {
+		return "", zeta.NewErrStatusCode(http.StatusInternalServerError, "create quarantine dir error: %v", err)
+	}
+	quarantineDir, err := os.MkdirTemp(incoming, "quarantine-")
+	if err != nil {
+		return "", zeta.NewErrStatusCode(http.StatusInternalServerError, "create quarantine dir error: %v", err)
+	}
+	return quarantineDir, nil
+}
+
+// Validate: run the validator with an empty quarantine, reference updates without objects (eg: restore from the ref
+// log) are checked like pushes.
+func (o *ODB) Validate(ctx context.Context, validator Validator) error {
+	quarantineDir, err := o.newQuarantineDir()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(quarantineDir)
+	}()
+	return validator(ctx, quarantineDir, &Objects{})
+}
+
 // Unpack:
 //
 //	FIXME: CRC64 verification has been temporarily stopped and may need to be restored later.
 func (o *ODB) Unpack(ctx context.Context, r io.Reader, ss *OStats, validator Validator) (*Objects, error) {
 	now := time.Now()
-	incoming := filepath.Join(o.odb.Root(), "incoming")
-	if err := os.MkdirAll(incoming, 0755); err != nil {
-		return nil, zeta.NewErrStatusCode(http.StatusInternalServerError, "create quarantine dir error: %v", err)
-	}
-	quarantineDir, err := os.MkdirTemp(incoming, "quarantine-")
+	quarantineDir, err := o.newQuarantineDir()
 	if err != nil {
-		return nil, zeta.NewErrStatusCode(http.StatusInternalServerError, "create quarantine dir error: %v", err)
+		return nil, err
 	}
 	defer func() {
 		_ = os.RemoveAll(quarantineDir)
@@ -0,0 +1,203 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package odb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	minUploadChunkSize = 64 * MiByte
+	maxUploadChunks    = 10000
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload session not found")
+)
+
+// Upload: resumable upload session of a large object, chunks are staged as parts of a bucket multipart upload.
+//
+// The chunk size is derived from the object size and the committed offset from the uploaded parts, the session is
+// only recorded in the database so that sessions not finished in time are aborted, see AbortExpiredUploads.
+type Upload struct {
+	ID        string
+	Size      int64
+	ChunkSize int64
+	Offset    int64
+}
+
+// UploadChunkSize: at least 64M, large enough to keep the number of parts within the bucket limit.
+func UploadChunkSize(size int64) int64 {
+	chunkSize := minUploadChunkSize
+	if n := (size + maxUploadChunks - 1) / maxUploadChunks; n > chunkSize {
+		chunkSize = (n + MiByte - 1) / MiByte * MiByte
+	}
+	return chunkSize
+}
+
+// committedOffset: parts are committed while they are contiguous and full sized, a trailing gap is uploaded again.
+func committedOffset(parts []*oss.Part, chunkSize, size int64) (int64, []*oss.Part) {
+	var offset int64
+	for i, p := range parts {
+		if p.Number != i+1 || p.Size != min(chunkSize, size-offset) {
+			return offset, parts[:i]
+		}
+		offset += p.Size
+	}
+	return offset, parts
+}
+
+func (o *ODB) NewUpload(ctx context.Context, oid plumbing.Hash, size int64) (*Upload, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("bad upload size: %d", size)
+	}
+	resourcePath := ossJoin(o.rid, oid)
+	si, err := o.bucket.Stat(ctx, resourcePath)
+	if err == nil {
+		// object exists, nothing to upload
+		return &Upload{Size: si.Size, ChunkSize: UploadChunkSize(si.Size), Offset: si.Size}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	uploadID, err := o.bucket.InitiateUpload(ctx, resourcePath, zetaBlobMIME)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.mdb.NewUploadSession(ctx, oid, uploadID, size); err != nil {
+		_ = o.bucket.AbortUpload(ctx, resourcePath, uploadID)
+		return nil, err
+	}
+	return &Upload{ID: uploadID, Size: size, ChunkSize: UploadChunkSize(size)}, nil
+}
+
+func (o *ODB) listParts(ctx context.Context, resourcePath, uploadID string) ([]*oss.Part, error) {
+	parts, err := o.bucket.ListParts(ctx, resourcePath, uploadID)
+	if os.IsNotExist(err) {
+		return nil, ErrUploadNotFound
+	}
+	return parts, err
+}
+
+func (o *ODB) UploadStatus(ctx context.Context, oid plumbing.Hash, uploadID string, size int64) (*Upload, error) {
+	parts, err := o.listParts(ctx, ossJoin(o.rid, oid), uploadID)
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := UploadChunkSize(size)
+	offset, _ := committedOffset(parts, chunkSize, size)
+	return &Upload{ID: uploadID, Size: size, ChunkSize: chunkSize, Offset: offset}, nil
+}
+
+// UploadChunk: write the chunk at offset, offset must be aligned to the chunk size and only the last chunk may be shorter.
+func (o *ODB) UploadChunk(ctx context.Context, oid plumbing.Hash, uploadID string, size, offset int64, r io.Reader, length int64) (*Upload, error) {
+	chunkSize := UploadChunkSize(size)
+	if offset < 0 || offset >= size || offset%chunkSize != 0 {
+		return nil, fmt.Errorf("offset %d is not aligned to chunk size %d", offset, chunkSize)
+	}
+	if want := min(chunkSize, size-offset); length != want {
+		return nil, fmt.Errorf("bad chunk length %d at offset %d, want %d", length, offset, want)
+	}
+	if err := o.bucket.UploadPart(ctx, ossJoin(o.rid, oid), uploadID, int(offset/chunkSize)+1, r, length); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &Upload{ID: uploadID, Size: size, ChunkSize: chunkSize, Offset: offset + length}, nil
+}
+
+// FinishUpload: complete the multipart upload and verify the object hash, a mismatched object is removed and the
+// session is aborted.
+//
+// Finishing a session that was already completed succeeds as long as the object exists.
+func (o *ODB) FinishUpload(ctx context.Context, oid plumbing.Hash, uploadID string, size int64) (int64, error) {
+	resourcePath := ossJoin(o.rid, oid)
+	parts, err := o.listParts(ctx, resourcePath, uploadID)
+	if err == ErrUploadNotFound {
+		if si, err := o.bucket.Stat(ctx, resourcePath); err == nil {
+			return si.Size, nil
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	offset, parts := committedOffset(parts, UploadChunkSize(size), size)
+	if offset != size {
+		return 0, fmt.Errorf("upload incomplete: committed %d of %d bytes", offset, size)
+	}
+	if err := o.bucket.CompleteUpload(ctx, resourcePath, uploadID, parts); err != nil {
+		return 0, fmt.Errorf("complete upload error: %w", err)
+	}
+	got, err := o.hashObject(ctx, resourcePath)
+	if err == nil && got == oid {
+		if err := o.mdb.DeleteUploadSession(ctx, uploadID); err != nil {
+			logrus.Errorf("delete upload session %s of %s error: %v", uploadID, oid, err)
+		}
+		return size, nil
+	}
+	cleanupCtx, cancelCtx := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelCtx()
+	_ = o.bucket.Delete(cleanupCtx, resourcePath)
+	if abortErr := o.AbortUpload(cleanupCtx, oid, uploadID); abortErr != nil {
+		logrus.Errorf("abort upload %s of %s error: %v", uploadID, oid, abortErr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("unexpected blob oid got '%s' want '%s'", got, oid)
+}
+
+// AbortUpload: abort the multipart upload and remove its staged parts, aborting a session that is already gone succeeds.
+func (o *ODB) AbortUpload(ctx context.Context, oid plumbing.Hash, uploadID string) error {
+	if err := o.bucket.AbortUpload(ctx, ossJoin(o.rid, oid), uploadID); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return o.mdb.DeleteUploadSession(ctx, uploadID)
+}
+
+// AbortExpiredUploads: abort the multipart uploads of all repositories created before the deadline, returns the number
+// of aborted sessions. A session failed to abort is kept and retried by the next call.
+func AbortExpiredUploads(ctx context.Context, conn *database.Conn, bucket oss.Bucket, deadline time.Time) (int, error) {
+	var cursor int64
+	var aborted int
+	for {
+		sessions, err := expiredUploadSessions(ctx, conn, deadline, cursor, 100)
+		if err != nil {
+			return aborted, err
+		}
+		for _, s := range sessions {
+			cursor = s.id
+			o := &ODB{mdb: NewMetadataDB(conn, s.rid), bucket: bucket, rid: s.rid}
+			if err := o.AbortUpload(ctx, s.oid, s.uploadID); err != nil {
+				logrus.Errorf("abort expired upload %s of %s [rid: %d] error: %v", s.uploadID, s.oid, s.rid, err)
+				continue
+			}
+			aborted++
+		}
+		if len(sessions) < 100 {
+			return aborted, nil
+		}
+	}
+}
+
+func (o *ODB) hashObject(ctx context.Context, resourcePath string) (plumbing.Hash, error) {
+	rr, err := o.bucket.Open(ctx, resourcePath, 0, -1)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer rr.Close()
+	return object.HashFrom(rr)
+}
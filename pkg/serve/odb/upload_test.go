@@ -0,0 +1,122 @@
+package odb
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+)
+
+func TestUploadChunkSize(t *testing.T) {
+	if got := UploadChunkSize(1024); got != minUploadChunkSize {
+		t.Fatalf("chunk size of small object: %d", got)
+	}
+	size := int64(maxUploadChunks) * 100 * MiByte
+	if got := UploadChunkSize(size); got != 100*MiByte {
+		t.Fatalf("chunk size of large object: %d", got)
+	}
+	if n := (size + UploadChunkSize(size+1) - 1) / UploadChunkSize(size+1); n > maxUploadChunks {
+		t.Fatalf("too many chunks: %d", n)
+	}
+}
+
+func TestCommittedOffset(t *testing.T) {
+	chunkSize := minUploadChunkSize
+	size := 2*chunkSize + 10
+	offset, parts := committedOffset([]*oss.Part{{Number: 1, Size: chunkSize}, {Number: 2, Size: chunkSize}, {Number: 3, Size: 10}}, chunkSize, size)
+	if offset != size || len(parts) != 3 {
+		t.Fatalf("complete upload: offset %d parts %d", offset, len(parts))
+	}
+	// part 2 is missing, part 3 must be uploaded again after it
+	offset, parts = committedOffset([]*oss.Part{{Number: 1, Size: chunkSize}, {Number: 3, Size: 10}}, chunkSize, size)
+	if offset != chunkSize || len(parts) != 1 {
+		t.Fatalf("gap: offset %d parts %d", offset, len(parts))
+	}
+	// truncated part is not committed
+	offset, _ = committedOffset([]*oss.Part{{Number: 1, Size: chunkSize - 1}}, chunkSize, size)
+	if offset != 0 {
+		t.Fatalf("truncated part: offset %d", offset)
+	}
+}
+
+func newTestUploadODB(t *testing.T) (*ODB, *database.Conn) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "zeta.db") + "?_pragma=busy_timeout(5000)&_time_format=sqlite"
+	conn, err := database.OpenConn(database.SQLite, dsn)
+	if err != nil {
+		t.Fatalf("open database error: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if _, err := database.Migrate(context.Background(), conn); err != nil {
+		t.Fatalf("migrate error: %v", err)
+	}
+	bucket, err := oss.NewLocalBucket(&oss.NewLocalBucketOptions{Root: t.TempDir(), SharedEndpoint: "http://zeta.example.io", Secret: "secret"})
+	if err != nil {
+		t.Fatalf("new local bucket error: %v", err)
+	}
+	return &ODB{mdb: NewMetadataDB(conn, 1), bucket: bucket, rid: 1}, conn
+}
+
+func countUploadSessions(t *testing.T, conn *database.Conn) int {
+	var n int
+	if err := conn.QueryRowContext(context.Background(), "select count(*) from upload_sessions").Scan(&n); err != nil {
+		t.Fatalf("count upload sessions error: %v", err)
+	}
+	return n
+}
+
+func TestAbortUpload(t *testing.T) {
+	ctx := context.Background()
+	o, conn := newTestUploadODB(t)
+	oid := plumbing.NewHash("4d1ad2bc0e3c3c7c1c5e3b1d6fb0a1d3c9d8e4c7a1f2b3c4d5e6f708192a3b4c")
+	u, err := o.NewUpload(ctx, oid, 5)
+	if err != nil {
+		t.Fatalf("new upload error: %v", err)
+	}
+	if n := countUploadSessions(t, conn); n != 1 {
+		t.Fatalf("upload sessions: %d", n)
+	}
+	// hash mismatch aborts the session
+	if _, err := o.UploadChunk(ctx, oid, u.ID, 5, 0, strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("upload chunk error: %v", err)
+	}
+	if _, err := o.FinishUpload(ctx, oid, u.ID, 5); err == nil {
+		t.Fatalf("mismatched object accepted")
+	}
+	if n := countUploadSessions(t, conn); n != 0 {
+		t.Fatalf("upload sessions after mismatch: %d", n)
+	}
+	// explicit abort
+	if u, err = o.NewUpload(ctx, oid, 5); err != nil {
+		t.Fatalf("new upload error: %v", err)
+	}
+	if err := o.AbortUpload(ctx, oid, u.ID); err != nil {
+		t.Fatalf("abort upload error: %v", err)
+	}
+	if _, err := o.UploadStatus(ctx, oid, u.ID, 5); err != ErrUploadNotFound {
+		t.Fatalf("upload status of aborted session: %v", err)
+	}
+	if err := o.AbortUpload(ctx, oid, u.ID); err != nil {
+		t.Fatalf("abort aborted upload error: %v", err)
+	}
+	// expired sessions
+	if u, err = o.NewUpload(ctx, oid, 5); err != nil {
+		t.Fatalf("new upload error: %v", err)
+	}
+	if n, err := AbortExpiredUploads(ctx, conn, o.bucket, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("abort unexpired uploads: %d %v", n, err)
+	}
+	if n, err := AbortExpiredUploads(ctx, conn, o.bucket, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("abort expired uploads: %d %v", n, err)
+	}
+	if _, err := o.UploadStatus(ctx, oid, u.ID, 5); err != ErrUploadNotFound {
+		t.Fatalf("upload status of expired session: %v", err)
+	}
+	if n := countUploadSessions(t, conn); n != 0 {
+		t.Fatalf("upload sessions after sweep: %d", n)
+	}
+}
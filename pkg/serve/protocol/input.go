@@ -54,3 +54,19 @@ func ReadInputOIDs(r io.Reader) ([]plumbing.Hash, error) {
 	}
 	return oids, nil
 }
+
+// MergeOIDs: append oids not in a yet
+func MergeOIDs(a []plumbing.Hash, oids []plumbing.Hash) []plumbing.Hash {
+	seen := make(map[plumbing.Hash]bool, len(a))
+	for _, oid := range a {
+		seen[oid] = true
+	}
+	for _, oid := range oids {
+		if seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		a = append(a, oid)
+	}
+	return a
+}
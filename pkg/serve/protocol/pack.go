@@ -6,6 +6,7 @@ package protocol
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math"
 	"net/http"
@@ -52,8 +53,9 @@ func writeMetadatItem(w io.Writer, e object.Encoder, oid string) error {
 	return nil
 }
 
-func WriteBatchObjectsHeader(w io.Writer) error {
-	if err := binary.Write(w, objectsTransportMagic[:], PROTOCOL_VERSION, reserved[:]); err != nil {
+// WriteBatchObjectsHeader: version RESUMABLE_BATCH_VERSION items are written by WriteCheckedObjectsItem.
+func WriteBatchObjectsHeader(w io.Writer, version uint32) error {
+	if err := binary.Write(w, objectsTransportMagic[:], version, reserved[:]); err != nil {
 		return fmt.Errorf("write batch-blobs error: %v", err)
 	}
 	return nil
@@ -81,6 +83,19 @@ func WriteObjectsItem(w io.Writer, r io.Reader, oid string, size int64) error {
 	return nil
 }
 
+// WriteCheckedObjectsItem: the item is followed by its crc32 (length, hash and contents), the client keeps the objects
+// received before an interruption and requests only the rest.
+func WriteCheckedObjectsItem(w io.Writer, r io.Reader, oid string, size int64) error {
+	if r == nil {
+		return WriteObjectsItem(w, nil, "", 0)
+	}
+	h := crc32.NewIEEE()
+	if err := WriteObjectsItem(io.MultiWriter(w, h), r, oid, size); err != nil {
+		return err
+	}
+	return binary.WriteUint32(w, h.Sum32())
+}
+
 func WriteSingleObjectsHeader(w io.Writer, contentLength, compressedSize int64) error {
 	if err := binary.Write(w, objectsTransportMagic[:], PROTOCOL_VERSION, contentLength, compressedSize); err != nil {
 		return fmt.Errorf("write batch-blobs error: %v", err)
@@ -127,6 +142,15 @@ func NewSparseTreeMatcher(dirs []string) SparseMatcher {
 	return root
 }
 
+// PathMatcher: path access control of the user, see Packer.Restrict.
+type PathMatcher interface {
+	// Match: matcher of the subdirectory, nil if everything under the subdirectory is readable, false if nothing
+	// under the subdirectory is readable.
+	Match(name string) (PathMatcher, bool)
+	// Readable: whether the file of the directory is readable
+	Readable(name string) bool
+}
+
 type Packer struct {
 	odb.DB
 	crc.Finisher
@@ -134,6 +158,10 @@ type Packer struct {
 	count        int
 	treeMaxDepth int
 	seen         map[plumbing.Hash]bool
+	haves        *odb.Reachability
+	checkout     plumbing.Hash // root tree of the client's have commit
+	acl          PathMatcher
+	hidden       func(oid plumbing.Hash) bool
 	closeFn      func() error
 }
 
@@ -206,6 +234,47 @@ func (p *Packer) Close() error {
 	return nil
 }
 
+// Exclude: skip commits reachable from the client's have commits. Trees and fragments are not skipped, shallow and
+// sparse clients do not have the trees of the commits they have, see ExcludeCheckout.
+func (p *Packer) Exclude(haves *odb.Reachability) {
+	p.haves = haves
+}
+
+// ExcludeCheckout: skip the trees and fragments identical to the ones at the same paths of the tree of the client's
+// have commit. Only for clients known to have the whole tree of the have commit, eg: full fetch without depth limit.
+func (p *Packer) ExcludeCheckout(ctx context.Context, have plumbing.Hash) error {
+	cc, err := p.Commit(ctx, have)
+	if err != nil {
+		return err
+	}
+	p.checkout = cc.Tree
+	return nil
+}
+
+// Restrict: write only the trees and fragments the user is allowed to read. acl is applied to the trees of commits,
+// hidden objects are skipped when writing trees without their paths, eg: the trees requested by batch metadata.
+func (p *Packer) Restrict(acl PathMatcher, hidden func(oid plumbing.Hash) bool) {
+	p.acl = acl
+	p.hidden = hidden
+}
+
+func (p *Packer) isHidden(oid plumbing.Hash) bool {
+	return p.hidden != nil && p.hidden(oid)
+}
+
+func (p *Packer) newCommitIter(current *object.Commit) object.CommitIter {
+	if p.haves == nil {
+		return object.NewCommitIterBSF(current, nil, nil)
+	}
+	isValid := object.CommitFilter(func(cc *object.Commit) bool {
+		return !p.haves.Contains(cc.Hash)
+	})
+	isLimit := object.CommitFilter(func(cc *object.Commit) bool {
+		return p.haves.Contains(cc.Hash)
+	})
+	return object.NewFilterCommitIter(current, &isValid, &isLimit)
+}
+
 func (p *Packer) Done() (err error) {
 	if err = writeMetadatItem(p.w, nil, ""); err != nil {
 		return err
@@ -244,11 +313,14 @@ func (p *Packer) WriteTree(ctx context.Context, oid plumbing.Hash, depth int) er
 	for _, e := range tree.Entries {
 		switch e.Type() {
 		case object.TreeObject:
+			if p.isHidden(e.Hash) {
+				continue
+			}
 			if err := p.WriteTree(ctx, e.Hash, depth+1); err != nil {
 				return err
 			}
 		case object.FragmentsObject:
-			if !p.seen[e.Hash] {
+			if !p.seen[e.Hash] && !p.isHidden(e.Hash) {
 				ff, err := p.Fragments(ctx, e.Hash)
 				if err != nil {
 					return err
@@ -313,11 +385,147 @@ func (p *Packer) WriteSparseTree(ctx context.Context, oid plumbing.Hash, m Spars
 	return nil
 }
 
+// writeRestrictedTree: write the tree matched by the sparse matcher m and readable by acl, m is nil for all paths.
+func (p *Packer) writeRestrictedTree(ctx context.Context, oid plumbing.Hash, m SparseMatcher, acl PathMatcher, depth int) error {
+	if acl == nil {
+		if m == nil {
+			return p.WriteTree(ctx, oid, depth)
+		}
+		return p.WriteSparseTree(ctx, oid, m, depth)
+	}
+	if depth > p.treeMaxDepth {
+		return nil
+	}
+	if p.seen[oid] {
+		return nil
+	}
+	tree, err := p.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if err := writeMetadatItem(p.w, tree, oid.String()); err != nil {
+		return err
+	}
+	p.count++
+	for _, e := range tree.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			var sub SparseMatcher
+			if m != nil && m.Len() > 0 {
+				var ok bool
+				if sub, ok = m.Match(e.Name); !ok {
+					continue
+				}
+			}
+			subACL, ok := acl.Match(e.Name)
+			if !ok {
+				continue
+			}
+			if err := p.writeRestrictedTree(ctx, e.Hash, sub, subACL, depth+1); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if !acl.Readable(e.Name) {
+				continue
+			}
+			if !p.seen[e.Hash] {
+				ff, err := p.Fragments(ctx, e.Hash)
+				if err != nil {
+					return err
+				}
+				if err := writeMetadatItem(p.w, ff, ff.Hash.String()); err != nil {
+					return err
+				}
+				p.count++
+				p.seen[e.Hash] = true
+			}
+		default:
+			// nothing
+		}
+	}
+	// not marked as seen, the same tree may be fully readable at another path
+	return nil
+}
+
+// writeTreeExcept: write the tree except the subtrees and fragments equal to the entries of the same names in the
+// checkout tree, which is the tree at the same path of the client's checkout.
+func (p *Packer) writeTreeExcept(ctx context.Context, oid, checkout plumbing.Hash, depth int) error {
+	if checkout.IsZero() {
+		return p.WriteTree(ctx, oid, depth)
+	}
+	if depth > p.treeMaxDepth {
+		return nil
+	}
+	if p.seen[oid] || oid == checkout {
+		return nil
+	}
+	ct, err := p.Tree(ctx, checkout)
+	if err != nil {
+		return p.WriteTree(ctx, oid, depth)
+	}
+	tree, err := p.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if err := writeMetadatItem(p.w, tree, oid.String()); err != nil {
+		return err
+	}
+	p.count++
+	checkoutEntries := make(map[string]*object.TreeEntry, len(ct.Entries))
+	for _, e := range ct.Entries {
+		checkoutEntries[e.Name] = e
+	}
+	for _, e := range tree.Entries {
+		ce := checkoutEntries[e.Name]
+		if ce != nil && ce.Hash == e.Hash {
+			continue
+		}
+		switch e.Type() {
+		case object.TreeObject:
+			if p.isHidden(e.Hash) {
+				continue
+			}
+			var sub plumbing.Hash
+			if ce != nil && ce.Type() == object.TreeObject {
+				sub = ce.Hash
+			}
+			if err := p.writeTreeExcept(ctx, e.Hash, sub, depth+1); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if !p.seen[e.Hash] && !p.isHidden(e.Hash) {
+				ff, err := p.Fragments(ctx, e.Hash)
+				if err != nil {
+					return err
+				}
+				if err := writeMetadatItem(p.w, ff, ff.Hash.String()); err != nil {
+					return err
+				}
+				p.count++
+				p.seen[e.Hash] = true
+			}
+		default:
+			// nothing
+		}
+	}
+	// the skipped entries are in the client's checkout, the tree is complete for the client
+	p.seen[oid] = true
+	return nil
+}
+
+// WriteRootTree: write the root tree of the commit, restricted by the acl, see Restrict.
+func (p *Packer) WriteRootTree(ctx context.Context, oid plumbing.Hash) error {
+	if p.acl == nil && !p.checkout.IsZero() {
+		return p.writeTreeExcept(ctx, oid, p.checkout, 0)
+	}
+	return p.writeRestrictedTree(ctx, oid, nil, p.acl, 0)
+}
+
 func (p *Packer) WriteDeepenMetadata(ctx context.Context, current *object.Commit, deepenFrom, have plumbing.Hash, deepen int) error {
 	if deepen == -1 {
 		deepen = math.MaxInt
 	}
-	iter := object.NewCommitIterBSF(current, nil, nil)
+	iter := p.newCommitIter(current)
 	defer iter.Close()
 	for i := 0; i < deepen; i++ {
 		cc, err := iter.Next(ctx)
@@ -334,7 +542,7 @@ func (p *Packer) WriteDeepenMetadata(ctx context.Context, current *object.Commit
 		if err := writeMetadatItem(p.w, cc, oid.String()); err != nil {
 			return err
 		}
-		if err := p.WriteTree(ctx, cc.Tree, 0); err != nil {
+		if err := p.WriteRootTree(ctx, cc.Tree); err != nil {
 			return err
 		}
 	}
@@ -346,7 +554,7 @@ func (p *Packer) WriteDeepenSparseMetadata(ctx context.Context, current *object.
 		deepen = math.MaxInt
 	}
 	m := NewSparseTreeMatcher(paths)
-	iter := object.NewCommitIterBSF(current, nil, nil)
+	iter := p.newCommitIter(current)
 	defer iter.Close()
 	for i := 0; i < deepen; i++ {
 		cc, err := iter.Next(ctx)
@@ -364,7 +572,7 @@ func (p *Packer) WriteDeepenSparseMetadata(ctx context.Context, current *object.
 			return err
 		}
 
-		if err := p.WriteSparseTree(ctx, cc.Tree, m, 0); err != nil {
+		if err := p.writeRestrictedTree(ctx, cc.Tree, m, p.acl, 0); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryDB struct {
+	odb.DB
+	commits   map[plumbing.Hash]*object.Commit
+	trees     map[plumbing.Hash]*object.Tree
+	fragments map[plumbing.Hash]*object.Fragments
+	loaded    map[plumbing.Hash]bool
+}
+
+func (m *memoryDB) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	if cc, ok := m.commits[oid]; ok {
+		return cc, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (m *memoryDB) Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	m.loaded[oid] = true
+	if t, ok := m.trees[oid]; ok {
+		return t, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (m *memoryDB) Fragments(ctx context.Context, oid plumbing.Hash) (*object.Fragments, error) {
+	m.loaded[oid] = true
+	if ff, ok := m.fragments[oid]; ok {
+		return ff, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func TestPackerExcludeCheckout(t *testing.T) {
+	hash := func(s string) plumbing.Hash {
+		return plumbing.NewHash(s + "0000000000000000000000000000000000000000000000000000000000000000"[len(s):])
+	}
+	m := &memoryDB{
+		commits:   make(map[plumbing.Hash]*object.Commit),
+		trees:     make(map[plumbing.Hash]*object.Tree),
+		fragments: make(map[plumbing.Hash]*object.Fragments),
+		loaded:    make(map[plumbing.Hash]bool),
+	}
+	addTree := func(oid plumbing.Hash, entries ...*object.TreeEntry) {
+		m.trees[oid] = &object.Tree{Hash: oid, Entries: entries}
+	}
+	addTree(hash("a1"), &object.TreeEntry{Name: "a.go", Mode: filemode.Regular, Hash: hash("b1")})
+	addTree(hash("a2"), &object.TreeEntry{Name: "a.go", Mode: filemode.Regular, Hash: hash("b2")})
+	addTree(hash("c1"), &object.TreeEntry{Name: "c.go", Mode: filemode.Regular, Hash: hash("b3")})
+	m.fragments[hash("f1")] = &object.Fragments{Hash: hash("f1")}
+	m.fragments[hash("f2")] = &object.Fragments{Hash: hash("f2")}
+	// have: lib=c1 src=a1 big=f1, new: lib=c1 src=a2 big=f1 video=f2
+	addTree(hash("e1"),
+		&object.TreeEntry{Name: "big", Mode: filemode.Regular | filemode.Fragments, Hash: hash("f1")},
+		&object.TreeEntry{Name: "lib", Mode: filemode.Dir, Hash: hash("c1")},
+		&object.TreeEntry{Name: "src", Mode: filemode.Dir, Hash: hash("a1")})
+	addTree(hash("e2"),
+		&object.TreeEntry{Name: "big", Mode: filemode.Regular | filemode.Fragments, Hash: hash("f1")},
+		&object.TreeEntry{Name: "lib", Mode: filemode.Dir, Hash: hash("c1")},
+		&object.TreeEntry{Name: "src", Mode: filemode.Dir, Hash: hash("a2")},
+		&object.TreeEntry{Name: "video", Mode: filemode.Regular | filemode.Fragments, Hash: hash("f2")})
+	m.commits[hash("d1")] = &object.Commit{Hash: hash("d1"), Tree: hash("e1")}
+
+	ctx := context.Background()
+	p, err := NewPipePacker(m, &bytes.Buffer{}, -1)
+	require.NoError(t, err)
+	require.NoError(t, p.ExcludeCheckout(ctx, hash("d1")))
+	require.NoError(t, p.WriteRootTree(ctx, hash("e2")))
+	require.True(t, m.loaded[hash("a2")])
+	require.True(t, m.loaded[hash("f2")])
+	require.False(t, m.loaded[hash("c1")], "unchanged tree must be skipped")
+	require.False(t, m.loaded[hash("f1")], "unchanged fragments must be skipped")
+	require.Equal(t, 3, p.count)
+
+	// without the checkout, e.g. shallow or sparse clients, every tree is written
+	clear(m.loaded)
+	p, err = NewPipePacker(m, &bytes.Buffer{}, -1)
+	require.NoError(t, err)
+	require.NoError(t, p.WriteRootTree(ctx, hash("e2")))
+	require.True(t, m.loaded[hash("c1")])
+	require.True(t, m.loaded[hash("f1")])
+	require.Equal(t, 5, p.count)
+}
@@ -6,11 +6,14 @@ package protocol
 import (
 	"math"
 	"time"
+
+	"github.com/antgroup/hugescm/pkg/transport"
 )
 
 const (
-	PROTOCOL_Z1             = "Z1"
-	PROTOCOL_VERSION uint32 = 1
+	PROTOCOL_Z1                    = "Z1"
+	PROTOCOL_VERSION        uint32 = 1
+	RESUMABLE_BATCH_VERSION uint32 = 2 // batch objects stream with per object checksum
 	// references prefix
 	REF_PREFIX    = "refs/"
 	BRANCH_PREFIX = "refs/heads/" // branch prefix
@@ -29,6 +32,35 @@ var (
 	reserved              [16]byte // reserved zero fill
 )
 
+// BatchSizeLimit: objects larger than the size limit of the batch request are skipped, the client downloads them
+// one by one. limit <= 0 means MAX_BATCH_BLOB_SIZE.
+func BatchSizeLimit(limit int64) int64 {
+	if limit <= 0 || limit > MAX_BATCH_BLOB_SIZE {
+		return MAX_BATCH_BLOB_SIZE
+	}
+	return limit
+}
+
+// Capabilities: capabilities advertised in reference discovery, features that the server does not support are not
+// listed, so clients can detect them without bumping the protocol version. The names are shared with the client, see
+// transport.Capabilities. hooks is listed only when hooks are configured.
+func Capabilities(hooks bool) []string {
+	capabilities := []string{
+		transport.CapabilityResumableBatch,
+		transport.CapabilityResumableUpload,
+		transport.CapabilityMetadataZstd,
+		transport.CapabilityLsRefs,
+		transport.CapabilityPushOptions,
+		transport.CapabilityAtomicPush,
+		transport.CapabilityBundlePush,
+		transport.CapabilityBatchWantHave,
+	}
+	if hooks {
+		capabilities = append(capabilities, transport.CapabilityHooks)
+	}
+	return capabilities
+}
+
 type Operation string
 
 const (
@@ -75,6 +107,21 @@ type Reference struct {
 	Capabilities    []string `json:"capabilities"`
 }
 
+// ReferenceEntry: item of the reference listing, annotated tags are peeled.
+type ReferenceEntry struct {
+	Name   string `json:"name"`
+	Hash   string `json:"hash"`
+	Peeled string `json:"peeled,omitempty"`
+}
+
+// References: paginated reference listing, pass NextCursor as cursor to get the next page, it is empty on the last page.
+type References struct {
+	Remote     string            `json:"remote"`
+	HEAD       string            `json:"head"`
+	References []*ReferenceEntry `json:"references"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
 type Branch struct {
 	Remote          string   `json:"remote"`
 	Branch          string   `json:"branch"`
@@ -132,3 +179,13 @@ type BatchRequest struct {
 type BatchResponse struct {
 	Objects []*HaveObject `json:"objects"`
 }
+
+// UploadSession: resumable upload session of a large object, chunks are uploaded at offsets aligned to ChunkSize.
+// Offset is the committed offset, an empty ID means the object already exists.
+type UploadSession struct {
+	ID        string `json:"id,omitempty"`
+	OID       string `json:"oid"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Offset    int64  `json:"offset"`
+}
@@ -0,0 +1,29 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCapabilities: the capabilities checked by the client are advertised by the server
+func TestCapabilities(t *testing.T) {
+	for _, hooks := range []bool{false, true} {
+		caps := transport.Capabilities(Capabilities(hooks))
+		for _, name := range []string{
+			transport.CapabilityResumableBatch,
+			transport.CapabilityResumableUpload,
+			transport.CapabilityMetadataZstd,
+			transport.CapabilityLsRefs,
+			transport.CapabilityPushOptions,
+			transport.CapabilityAtomicPush,
+			transport.CapabilityBundlePush,
+			transport.CapabilityBatchWantHave,
+		} {
+			require.True(t, caps.Has(name), "%s is not advertised", name)
+		}
+		require.Equal(t, hooks, caps.Has(transport.CapabilityHooks))
+		require.NoError(t, caps.Require("origin", transport.CapabilityAtomicPush, transport.CapabilityPushOptions))
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/metrics"
+	"golang.org/x/time/rate"
+)
+
+type Kind string
+
+const (
+	User       Kind = "user"
+	DeployKey  Kind = "deploy_key"
+	IP         Kind = "ip"
+	Repository Kind = "repository"
+)
+
+const (
+	defaultRetryAfter = 5 * time.Second
+	sweepInterval     = time.Minute
+)
+
+type Key struct {
+	Kind Kind
+	ID   string
+}
+
+func NewKey(kind Kind, id int64) Key {
+	return Key{Kind: kind, ID: strconv.FormatInt(id, 10)}
+}
+
+type ErrLimited struct {
+	Key         Key
+	RetryAfter  time.Duration
+	Concurrency bool // too many concurrent heavy operations, otherwise the rate is exceeded
+}
+
+func (e *ErrLimited) Error() string {
+	if e.Concurrency {
+		return fmt.Sprintf("too many concurrent operations of %s '%s', retry after %v", e.Key.Kind, e.Key.ID, e.RetryAfter)
+	}
+	return fmt.Sprintf("rate limit of %s '%s' exceeded, retry after %v", e.Key.Kind, e.Key.ID, e.RetryAfter)
+}
+
+// RetryAfterSeconds: value of the Retry-After header, at least 1 second
+func (e *ErrLimited) RetryAfterSeconds() int {
+	return max(int(math.Ceil(e.RetryAfter.Seconds())), 1)
+}
+
+func IsErrLimited(err error) bool {
+	var e *ErrLimited
+	return errors.As(err, &e)
+}
+
+type entry struct {
+	limiter *rate.Limiter // nil when the rate is not limited
+	active  int
+}
+
+// Limiter: token buckets and counters of concurrent heavy operations, a nil Limiter limits nothing.
+type Limiter struct {
+	rules      map[Kind]*serve.RateLimit
+	retryAfter time.Duration
+	mu         sync.Mutex
+	entries    map[Key]*entry
+	lastSweep  time.Time
+}
+
+// NewLimiter: returns nil when no limit is configured
+func NewLimiter(cfg *serve.RateLimits) *Limiter {
+	if cfg == nil {
+		return nil
+	}
+	rules := make(map[Kind]*serve.RateLimit)
+	for kind, rule := range map[Kind]*serve.RateLimit{User: cfg.User, DeployKey: cfg.DeployKey, IP: cfg.IP, Repository: cfg.Repository} {
+		if rule != nil && (rule.Rate > 0 || rule.Concurrency > 0) {
+			rules[kind] = rule
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	retryAfter := cfg.RetryAfter.Duration
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	return &Limiter{rules: rules, retryAfter: retryAfter, entries: make(map[Key]*entry), lastSweep: time.Now()}
+}
+
+func (l *Limiter) entry(k Key, rule *serve.RateLimit) *entry {
+	if e, ok := l.entries[k]; ok {
+		return e
+	}
+	e := &entry{}
+	if rule.Rate > 0 {
+		burst := rule.Burst
+		if burst <= 0 {
+			burst = max(int(math.Ceil(rule.Rate)), 1)
+		}
+		e.limiter = rate.NewLimiter(rate.Limit(rule.Rate), burst)
+	}
+	l.entries[k] = e
+	return e
+}
+
+// sweep: forget idle keys whose token buckets are full again
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for k, e := range l.entries {
+		if e.active == 0 && (e.limiter == nil || e.limiter.TokensAt(now) >= float64(e.limiter.Burst())) {
+			delete(l.entries, k)
+		}
+	}
+}
+
+func limited(e *ErrLimited) error {
+	metrics.RateLimited.WithLabelValues(string(e.Key.Kind)).Inc()
+	return e
+}
+
+// Enter: take a token of each key, heavy operations also hold a slot of each key until release is called.
+// Nothing is taken when the request is limited, the error is *ErrLimited.
+func (l *Limiter) Enter(keys []Key, heavy bool) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	var holds []*entry
+	if heavy {
+		for _, k := range keys {
+			if rule, ok := l.rules[k.Kind]; ok && rule.Concurrency > 0 {
+				e := l.entry(k, rule)
+				if e.active >= rule.Concurrency {
+					return nil, limited(&ErrLimited{Key: k, RetryAfter: l.retryAfter, Concurrency: true})
+				}
+				holds = append(holds, e)
+			}
+		}
+	}
+	reservations := make([]*rate.Reservation, 0, len(keys))
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	for _, k := range keys {
+		rule, ok := l.rules[k.Kind]
+		if !ok || rule.Rate <= 0 {
+			continue
+		}
+		r := l.entry(k, rule).limiter.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			return nil, limited(&ErrLimited{Key: k, RetryAfter: l.retryAfter})
+		}
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			cancel()
+			return nil, limited(&ErrLimited{Key: k, RetryAfter: delay})
+		}
+		reservations = append(reservations, r)
+	}
+	for _, e := range holds {
+		e.active++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, e := range holds {
+				e.active--
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilLimiter(t *testing.T) {
+	require.Nil(t, NewLimiter(nil))
+	require.Nil(t, NewLimiter(&serve.RateLimits{User: &serve.RateLimit{}}))
+	var l *Limiter
+	release, err := l.Enter([]Key{NewKey(User, 1)}, true)
+	require.NoError(t, err)
+	release()
+}
+
+func TestRate(t *testing.T) {
+	l := NewLimiter(&serve.RateLimits{User: &serve.RateLimit{Rate: 1, Burst: 2}})
+	keys := []Key{NewKey(User, 1), NewKey(Repository, 1)}
+	for range 2 {
+		_, err := l.Enter(keys, false)
+		require.NoError(t, err)
+	}
+	_, err := l.Enter(keys, false)
+	var e *ErrLimited
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, User, e.Key.Kind)
+	require.False(t, e.Concurrency)
+	require.Greater(t, e.RetryAfter, time.Duration(0))
+	require.Equal(t, 1, e.RetryAfterSeconds())
+	// other users are not limited
+	_, err = l.Enter([]Key{NewKey(User, 2)}, false)
+	require.NoError(t, err)
+}
+
+func TestRateAllOrNothing(t *testing.T) {
+	l := NewLimiter(&serve.RateLimits{
+		User:       &serve.RateLimit{Rate: 0.001, Burst: 1},
+		Repository: &serve.RateLimit{Rate: 0.001, Burst: 1},
+	})
+	_, err := l.Enter([]Key{NewKey(Repository, 1)}, false)
+	require.NoError(t, err)
+	// the repository is limited, the token of the user is given back
+	_, err = l.Enter([]Key{NewKey(User, 1), NewKey(Repository, 1)}, false)
+	require.True(t, IsErrLimited(err))
+	_, err = l.Enter([]Key{NewKey(User, 1)}, false)
+	require.NoError(t, err)
+}
+
+func TestConcurrency(t *testing.T) {
+	l := NewLimiter(&serve.RateLimits{
+		IP:         &serve.RateLimit{Concurrency: 2},
+		RetryAfter: serve.Duration{Duration: 3 * time.Second},
+	})
+	keys := []Key{{Kind: IP, ID: "10.0.0.1"}}
+	release1, err := l.Enter(keys, true)
+	require.NoError(t, err)
+	release2, err := l.Enter(keys, true)
+	require.NoError(t, err)
+	// light operations are not counted
+	_, err = l.Enter(keys, false)
+	require.NoError(t, err)
+	_, err = l.Enter(keys, true)
+	var e *ErrLimited
+	require.ErrorAs(t, err, &e)
+	require.True(t, e.Concurrency)
+	require.Equal(t, 3, e.RetryAfterSeconds())
+	release1()
+	release1()
+	release3, err := l.Enter(keys, true)
+	require.NoError(t, err)
+	_, err = l.Enter(keys, true)
+	require.Error(t, err)
+	release2()
+	release3()
+}
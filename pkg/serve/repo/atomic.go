@@ -0,0 +1,222 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/pktline"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxAtomicCommands: max references updated by one atomic push
+	MaxAtomicCommands = 1000
+)
+
+// ReadAtomicCommands: the commands of an atomic push precede the objects stream, one pktline for each
+// reference '<old-rev> <new-rev> <refname>', ended by a flush-pkt.
+func ReadAtomicCommands(r io.Reader) ([]*Command, error) {
+	scanner := pktline.NewScanner(r)
+	cmds := make([]*Command, 0, 4)
+	seen := make(map[plumbing.ReferenceName]bool)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			// flush-pkt
+			if len(cmds) == 0 {
+				return nil, errors.New("atomic push without commands")
+			}
+			return cmds, nil
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("bad command line: %s", line)
+		}
+		if !plumbing.ValidateHashHex(fields[0]) {
+			return nil, fmt.Errorf("old-rev is invalid hash: %s", fields[0])
+		}
+		if !plumbing.ValidateHashHex(fields[1]) {
+			return nil, fmt.Errorf("new-rev is invalid hash: %s", fields[1])
+		}
+		refname := plumbing.ReferenceName(fields[2])
+		if !plumbing.ValidateReferenceName([]byte(refname)) || (!refname.IsBranch() && !refname.IsTag()) {
+			return nil, fmt.Errorf("'%s' is not a valid branch or tag name", refname)
+		}
+		if seen[refname] {
+			return nil, fmt.Errorf("duplicate reference '%s'", refname)
+		}
+		seen[refname] = true
+		if len(cmds) >= MaxAtomicCommands {
+			return nil, fmt.Errorf("too many references, limit %d", MaxAtomicCommands)
+		}
+		cmds = append(cmds, &Command{ReferenceName: refname, OldRev: fields[0], NewRev: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// rejectOthers: the push is atomic, references other than the failed one are rejected too.
+func rejectOthers(ro *reporter, cmds []*Command, failed *Command) {
+	for _, cmd := range cmds {
+		if cmd != failed {
+			_ = ro.ng(cmd, "%s", cmd.W("atomic push failed"))
+		}
+	}
+	_ = ro.close()
+}
+
+// ReportAtomicRejected: reject all references of the atomic push because the failed reference was rejected.
+func ReportAtomicRejected(w io.Writer, cmds []*Command, failed *Command, format string, a ...any) {
+	ro := newReporter(w)
+	_ = ro.ng(failed, format, a...)
+	rejectOthers(ro, cmds, failed)
+}
+
+// DoAtomicPush: update all references of the push or none of them. Objects of all references are received
+// in one stream, each reference is checked, then all references are updated in one database transaction.
+// The objects stats and push options of the first command are used for the whole push.
+func (r *repository) DoAtomicPush(ctx context.Context, cmds []*Command, reader io.Reader, w io.Writer) (err error) {
+	ro := newReporter(w)
+	defer func(start time.Time) { observePush("atomic", ro, start, err) }(time.Now())
+	var updates int
+	for _, cmd := range cmds {
+		if cmd.NewRev != plumbing.ZERO_OID {
+			updates++
+			continue
+		}
+		if cmd.ReferenceName.IsBranch() && cmd.ReferenceName.BranchName() == r.defaultBranch {
+			ro.fail(pushFailedDefaultBranch)
+			_ = ro.ng(cmd, "\x1b[31merror\x1b[0m: %s%s", cmd.W("refusing to delete the current branch: "), cmd.ReferenceName)
+			rejectOthers(ro, cmds, cmd)
+			return ErrReportStarted
+		}
+	}
+	defer ro.close()
+	commits := make(map[*Command][]plumbing.Hash, len(cmds))
+	if updates != 0 {
+		first := cmds[0]
+		recvObjs, err := r.odb.Unpack(ctx, reader, &odb.OStats{M: first.M, B: first.B}, func(ctx context.Context, quarantineDir string, o *odb.Objects) error {
+			if err := ro.EncodeString("unpack ok"); err != nil {
+				ro.fail(pushFailedDisconnected)
+				return ErrReportStarted
+			}
+			qr, err := NewQR(r.odb, quarantineDir)
+			if err != nil {
+				ro.fail(pushFailedIntegrity)
+				_ = ro.ng(first, first.W("check integrity error: %v"), err)
+				rejectOthers(ro, cmds, first)
+				return ErrReportStarted
+			}
+			defer qr.Close()
+			for _, cmd := range cmds {
+				ic, err := qr.checkIntegrity(ctx, cmd, ro)
+				if err != nil {
+					ro.fail(pushFailedIntegrity)
+					rejectOthers(ro, cmds, cmd)
+					return ErrReportStarted
+				}
+				commits[cmd] = ic.commits
+				if ic.forcePush && cmd.FastForwardOnly && cmd.OldRev != plumbing.ZERO_OID {
+					ro.fail(pushFailedProtected)
+					_ = ro.ng(cmd, cmd.W("non-fast-forward update of '%s' is rejected, use '--force' to update it"), cmd.ReferenceName)
+					rejectOthers(ro, cmds, cmd)
+					return ErrReportStarted
+				}
+				if err := qr.checkProtection(ctx, cmd, ro, ic); err != nil {
+					ro.fail(pushFailedProtected)
+					rejectOthers(ro, cmds, cmd)
+					return ErrReportStarted
+				}
+				if err := qr.checkPathACL(ctx, cmd, ro, commits[cmd]); err != nil {
+					ro.fail(pushFailedPathACL)
+					rejectOthers(ro, cmds, cmd)
+					return ErrReportStarted
+				}
+				if ic.forcePush && cmd.OldRev != plumbing.ZERO_OID && cmd.NewRev != plumbing.ZERO_OID {
+					cmd.force = true
+					logrus.Infof("Force push %s, oldRev %s --> newRev %s", cmd.ReferenceName, cmd.OldRev, cmd.NewRev)
+				}
+			}
+			if failed, err := r.hooks.receiveHooks(ctx, qr, quarantineDir, cmds, commits); err != nil {
+				ro.fail(pushFailedHook)
+				reportHookError(ro, failed, err)
+				rejectOthers(ro, cmds, failed)
+				return ErrReportStarted
+			}
+			return nil
+		})
+		if err != nil {
+			ro.fail(pushFailedUnpack)
+			return err
+		}
+		if err := r.odb.Reload(); err != nil {
+			ro.fail(pushFailedStorage)
+			_ = ro.ng(first, "reload odb error: %v", err)
+			rejectOthers(ro, cmds, first)
+			return err
+		}
+		if err := r.storeObjects(ctx, recvObjs); err != nil {
+			ro.fail(pushFailedStorage)
+			_ = ro.ng(first, "store object error: %v", err)
+			rejectOthers(ro, cmds, first)
+			return ErrReportStarted
+		}
+		_ = ro.status(first.W("objects verified")) //nolint:govet
+	} else if failed, err := r.hooks.receiveHooks(ctx, nil, "", cmds, nil); err != nil {
+		// only deletions, no object is received
+		ro.fail(pushFailedHook)
+		reportHookError(ro, failed, err)
+		rejectOthers(ro, cmds, failed)
+		return ErrReportStarted
+	}
+	changes := make([]*database.Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		changes = append(changes, r.databaseCommand(ctx, cmd))
+	}
+	newReferences, err := r.mdb.DoReferenceUpdates(ctx, changes)
+	if err != nil {
+		failed := cmds[0]
+		var e *database.ErrReferenceUpdate
+		if errors.As(err, &e) {
+			for _, cmd := range cmds {
+				if cmd.ReferenceName == e.Reference {
+					failed = cmd
+				}
+			}
+			err = e.Err
+		}
+		if database.IsErrAlreadyLocked(err) {
+			ro.fail(pushFailedLocked)
+			_ = ro.ng(failed, failed.W("reference is already locked: %s"), failed.ReferenceName)
+		} else {
+			ro.fail(pushFailedDatabase)
+			_ = ro.ng(failed, failed.W("update reference error: %v"), err)
+		}
+		rejectOthers(ro, cmds, failed)
+		return ErrReportStarted
+	}
+	for i, cmd := range cmds {
+		_ = ro.ok(cmd, newReferences[i].Hash)
+		auditReferenceUpdate(cmd)
+	}
+	r.hooks.postReceive(cmds)
+	r.notifyPush(ctx, cmds, commits)
+	for _, cmd := range cmds {
+		if cmd.ReferenceName.IsBranch() && cmd.NewRev != plumbing.ZERO_OID {
+			r.odb.UpdateBitmapIndex(plumbing.NewHash(cmd.NewRev))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/pktline"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeAtomicCommands(t *testing.T, lines ...string) *bytes.Buffer {
+	var b bytes.Buffer
+	e := pktline.NewEncoder(&b)
+	for _, line := range lines {
+		require.NoError(t, e.EncodeString(line))
+	}
+	require.NoError(t, e.Flush())
+	return &b
+}
+
+func TestReadAtomicCommands(t *testing.T) {
+	newRev := strings.Repeat("a", 64)
+	b := encodeAtomicCommands(t, plumbing.ZERO_OID+" "+newRev+" refs/heads/dev", newRev+" "+plumbing.ZERO_OID+" refs/tags/v1.0")
+	b.WriteString("objects")
+	cmds, err := ReadAtomicCommands(b)
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	require.Equal(t, plumbing.ReferenceName("refs/heads/dev"), cmds[0].ReferenceName)
+	require.Equal(t, newRev, cmds[0].NewRev)
+	require.Equal(t, plumbing.ZERO_OID, cmds[1].NewRev)
+	// objects stream follows the commands
+	require.Equal(t, "objects", b.String())
+
+	for _, bad := range [][]string{
+		nil,
+		{"bad line"},
+		{plumbing.ZERO_OID + " xyz refs/heads/dev"},
+		{plumbing.ZERO_OID + " " + newRev + " refs/notes/dev"},
+		{plumbing.ZERO_OID + " " + newRev + " refs/heads/dev", plumbing.ZERO_OID + " " + newRev + " refs/heads/dev"},
+	} {
+		_, err = ReadAtomicCommands(encodeAtomicCommands(t, bad...))
+		require.Error(t, err, "%q", bad)
+	}
+	_, err = ReadAtomicCommands(strings.NewReader(""))
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
+)
+
+// IncomingBundle: bundle pushed by the client, spooled to a temporary file which is removed by Close.
+type IncomingBundle struct {
+	*bundle.Bundle
+	name string
+}
+
+// ErrBundleTooLarge: the pushed bundle exceeds the size limit of the server
+type ErrBundleTooLarge struct {
+	Limit int64
+}
+
+func (e *ErrBundleTooLarge) Error() string {
+	return fmt.Sprintf("bundle exceeds the size limit %s", strengthen.HumanateSize(e.Limit))
+}
+
+func IsErrBundleTooLarge(err error) bool {
+	_, ok := err.(*ErrBundleTooLarge)
+	return ok
+}
+
+// ReceiveBundle: spool the bundle to a temporary file and verify its checksums before any reference is checked,
+// bundles larger than limit are rejected with ErrBundleTooLarge.
+func ReceiveBundle(ctx context.Context, r io.Reader, limit int64) (*IncomingBundle, error) {
+	fd, err := os.CreateTemp("", "zeta-bundle-*"+bundle.Extension)
+	if err != nil {
+		return nil, err
+	}
+	name := fd.Name()
+	n, err := io.Copy(fd, io.LimitReader(r, limit+1))
+	if err != nil {
+		_ = fd.Close()
+		_ = os.Remove(name)
+		return nil, fmt.Errorf("receive bundle error: %w", err)
+	}
+	if n > limit {
+		_ = fd.Close()
+		_ = os.Remove(name)
+		return nil, &ErrBundleTooLarge{Limit: limit}
+	}
+	if err := fd.Close(); err != nil {
+		_ = os.Remove(name)
+		return nil, err
+	}
+	b, err := bundle.Open(name)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, err
+	}
+	if err := b.Verify(ctx); err != nil {
+		_ = b.Close()
+		_ = os.Remove(name)
+		return nil, err
+	}
+	return &IncomingBundle{Bundle: b, name: name}, nil
+}
+
+func (b *IncomingBundle) Close() error {
+	err := b.Bundle.Close()
+	_ = os.Remove(b.name)
+	return err
+}
+
+// Commands: one command for each reference of the bundle, the old rev is the value of the reference expected by the
+// bundle, zero if the reference is created. Non-fast-forward updates are rejected unless force is set.
+func (b *IncomingBundle) Commands(force bool) ([]*Command, error) {
+	if len(b.References) == 0 {
+		return nil, errors.New("bundle without references")
+	}
+	if len(b.References) > MaxAtomicCommands {
+		return nil, fmt.Errorf("too many references, limit %d", MaxAtomicCommands)
+	}
+	cmds := make([]*Command, 0, len(b.References))
+	seen := make(map[plumbing.ReferenceName]bool)
+	for _, ref := range b.References {
+		if !plumbing.ValidateReferenceName([]byte(ref.Name)) || (!ref.Name.IsBranch() && !ref.Name.IsTag()) {
+			return nil, fmt.Errorf("'%s' is not a valid branch or tag name", ref.Name)
+		}
+		if !plumbing.ValidateHashHex(ref.Hash) || ref.Hash == plumbing.ZERO_OID {
+			return nil, fmt.Errorf("reference '%s' has invalid hash: %s", ref.Name, ref.Hash)
+		}
+		oldRev := plumbing.ZERO_OID
+		if len(ref.Old) != 0 {
+			if !plumbing.ValidateHashHex(ref.Old) {
+				return nil, fmt.Errorf("reference '%s' has invalid old hash: %s", ref.Name, ref.Old)
+			}
+			oldRev = ref.Old
+		}
+		if seen[ref.Name] {
+			return nil, fmt.Errorf("duplicate reference '%s'", ref.Name)
+		}
+		seen[ref.Name] = true
+		cmds = append(cmds, &Command{
+			ReferenceName:   ref.Name,
+			OldRev:          oldRev,
+			NewRev:          ref.Hash,
+			M:               int(b.Metadata),
+			B:               int(b.Blobs),
+			FastForwardOnly: !force,
+		})
+	}
+	return cmds, nil
+}
+
+// NewReader: objects of the bundle as push stream, see DoAtomicPush.
+func (b *IncomingBundle) NewReader(ctx context.Context) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(b.PushTo(ctx, pw))
+	}()
+	return pr
+}
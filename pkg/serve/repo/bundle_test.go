@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiveBundleLimit(t *testing.T) {
+	_, err := ReceiveBundle(context.Background(), bytes.NewReader(make([]byte, 1025)), 1024)
+	require.True(t, IsErrBundleTooLarge(err))
+	// within the limit but not a bundle
+	_, err = ReceiveBundle(context.Background(), bytes.NewReader(make([]byte, 1024)), 1024)
+	require.Error(t, err)
+	require.False(t, IsErrBundleTooLarge(err))
+}
+
+func TestIncomingBundleCommands(t *testing.T) {
+	oldRev := strings.Repeat("a", 64)
+	newRev := strings.Repeat("b", 64)
+	b := &IncomingBundle{Bundle: &bundle.Bundle{Header: &bundle.Header{References: []*bundle.Reference{
+		{Name: "refs/heads/dev", Hash: newRev, Old: oldRev},
+		{Name: "refs/tags/v1.0", Hash: newRev},
+	}}}}
+	cmds, err := b.Commands(false)
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	require.Equal(t, oldRev, cmds[0].OldRev)
+	require.Equal(t, plumbing.ZERO_OID, cmds[1].OldRev)
+	require.True(t, cmds[0].FastForwardOnly)
+	cmds, err = b.Commands(true)
+	require.NoError(t, err)
+	require.False(t, cmds[0].FastForwardOnly)
+
+	b.References[0].Old = "xyz"
+	_, err = b.Commands(false)
+	require.Error(t, err)
+}
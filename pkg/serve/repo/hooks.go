@@ -0,0 +1,320 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HookPreReceive  = "pre-receive"
+	HookUpdate      = "update"
+	HookPostReceive = "post-receive"
+	// defaultHookTimeout: used when neither the hook nor [hooks] sets a timeout
+	defaultHookTimeout = 30 * time.Second
+	// maxHookOutput: output of executables and bodies of callouts are truncated, they are shown to the user
+	maxHookOutput = 64 << 10
+	// maxHookCommits: new commits listed in the payload, the remaining are omitted
+	maxHookCommits = 1000
+	// sandboxPath: PATH of hook executables, the environment of the server is not inherited
+	sandboxPath = "/usr/local/bin:/usr/bin:/bin"
+)
+
+// HookReference: reference update passed to hooks
+type HookReference struct {
+	ReferenceName plumbing.ReferenceName `json:"reference_name"`
+	OldRev        string                 `json:"old_rev"`
+	NewRev        string                 `json:"new_rev"`
+}
+
+// HookCommit: commit received by the push, read from the quarantine
+type HookCommit struct {
+	Hash      string           `json:"hash"`
+	Author    object.Signature `json:"author"`
+	Committer object.Signature `json:"committer"`
+	Message   string           `json:"message"`
+}
+
+// HookPayload: sent as JSON on stdin of executables and as body of HTTP callouts.
+type HookPayload struct {
+	Hook        string           `json:"hook"`
+	RID         int64            `json:"rid"`
+	UID         int64            `json:"uid"`
+	References  []*HookReference `json:"references"`
+	Commits     []*HookCommit    `json:"commits,omitempty"` // pre-receive and update only
+	Truncated   bool             `json:"truncated,omitempty"`
+	PushOptions []string         `json:"push_options,omitempty"`
+}
+
+// ErrHookDeclined: the hook rejected the push, the message is shown to the user.
+type ErrHookDeclined struct {
+	Hook    string
+	Message string
+}
+
+func (e *ErrHookDeclined) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("%s hook declined", e.Hook)
+	}
+	return fmt.Sprintf("%s hook declined: %s", e.Hook, e.Message)
+}
+
+func IsErrHookDeclined(err error) bool {
+	_, ok := err.(*ErrHookDeclined)
+	return ok
+}
+
+// hookOutput: keeps the first maxHookOutput bytes, the rest is discarded
+type hookOutput struct {
+	bytes.Buffer
+}
+
+func (o *hookOutput) Write(p []byte) (int, error) {
+	if remaining := maxHookOutput - o.Len(); remaining > 0 {
+		_, _ = o.Buffer.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func (o *hookOutput) message() string {
+	return strings.TrimSpace(o.String())
+}
+
+// hookRunner: runs the configured hooks, a nil runner runs nothing.
+type hookRunner struct {
+	cfg    *serve.Hooks
+	client *http.Client
+}
+
+// newHookRunner: returns nil if no hook is configured
+func newHookRunner(cfg *serve.Hooks) *hookRunner {
+	if cfg == nil {
+		return nil
+	}
+	h := &hookRunner{cfg: cfg, client: &http.Client{}}
+	if h.lookup(HookPreReceive) == nil && h.lookup(HookUpdate) == nil && h.lookup(HookPostReceive) == nil {
+		return nil
+	}
+	return h
+}
+
+func (h *hookRunner) lookup(name string) *serve.Hook {
+	if h == nil {
+		return nil
+	}
+	var hk *serve.Hook
+	switch name {
+	case HookPreReceive:
+		hk = h.cfg.PreReceive
+	case HookUpdate:
+		hk = h.cfg.Update
+	case HookPostReceive:
+		hk = h.cfg.PostReceive
+	}
+	if hk == nil || (len(hk.Command) == 0 && len(hk.URL) == 0) {
+		return nil
+	}
+	return hk
+}
+
+func (h *hookRunner) timeout(hk *serve.Hook) time.Duration {
+	if hk.Timeout.Duration > 0 {
+		return hk.Timeout.Duration
+	}
+	if h.cfg.Timeout.Duration > 0 {
+		return h.cfg.Timeout.Duration
+	}
+	return defaultHookTimeout
+}
+
+// sandboxEnv: hooks only see these variables, secrets of the server are never passed.
+func sandboxEnv(hk *serve.Hook, p *HookPayload, cmd *Command, quarantineDir string) []string {
+	env := []string{
+		"PATH=" + sandboxPath,
+		"HOME=" + os.TempDir(),
+		"ZETA_HOOK=" + p.Hook,
+		"ZETA_RID=" + strconv.FormatInt(p.RID, 10),
+		"ZETA_UID=" + strconv.FormatInt(p.UID, 10),
+	}
+	if len(cmd.Language) != 0 {
+		env = append(env, "LANG="+cmd.Language)
+	}
+	if len(quarantineDir) != 0 {
+		env = append(env, "ZETA_QUARANTINE_DIR="+quarantineDir)
+	}
+	env = append(env, cmd.PushOptions.Environ()...)
+	return append(env, hk.Env...)
+}
+
+func (h *hookRunner) exec(ctx context.Context, hk *serve.Hook, p *HookPayload, body []byte, cmd *Command, quarantineDir string) error {
+	c := exec.CommandContext(ctx, hk.Command, hk.Args...)
+	c.Dir = os.TempDir()
+	c.Env = sandboxEnv(hk, p, cmd, quarantineDir)
+	c.Stdin = bytes.NewReader(body)
+	var out hookOutput
+	c.Stdout = &out
+	c.Stderr = &out
+	// don't wait for pipes held by orphaned children after the hook is killed
+	c.WaitDelay = time.Second
+	err := c.Run()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if ctx.Err() == nil && !errors.As(err, &exitErr) {
+		return err
+	}
+	return &ErrHookDeclined{Hook: p.Hook, Message: out.message()}
+}
+
+func (h *hookRunner) callout(ctx context.Context, hk *serve.Hook, p *HookPayload, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", hk.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Zeta-Hook", p.Hook)
+	if len(hk.Token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+hk.Token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &ErrHookDeclined{Hook: p.Hook}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var out hookOutput
+	_, _ = io.Copy(&out, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	message := out.message()
+	var m struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(out.Bytes(), &m) == nil && len(m.Message) != 0 {
+		message = m.Message
+	}
+	return &ErrHookDeclined{Hook: p.Hook, Message: message}
+}
+
+// run: run the hook, returns ErrHookDeclined if the hook rejected the push or timed out, cmd provides the language and push options of the push.
+func (h *hookRunner) run(ctx context.Context, hk *serve.Hook, p *HookPayload, cmd *Command, quarantineDir string) error {
+	timeout := h.timeout(hk)
+	newCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if len(hk.Command) != 0 {
+		err = h.exec(newCtx, hk, p, body, cmd, quarantineDir)
+	} else {
+		err = h.callout(newCtx, hk, p, body)
+	}
+	if e, ok := err.(*ErrHookDeclined); ok && len(e.Message) == 0 && newCtx.Err() == context.DeadlineExceeded {
+		e.Message = fmt.Sprintf("timed out after %v", timeout)
+	}
+	return err
+}
+
+func newHookPayload(name string, cmds []*Command) *HookPayload {
+	p := &HookPayload{Hook: name, References: make([]*HookReference, 0, len(cmds))}
+	for _, cmd := range cmds {
+		p.References = append(p.References, &HookReference{ReferenceName: cmd.ReferenceName, OldRev: cmd.OldRev, NewRev: cmd.NewRev})
+	}
+	if len(cmds) != 0 {
+		p.RID = cmds[0].RID
+		p.UID = cmds[0].UID
+		p.PushOptions = cmds[0].pushOptions()
+	}
+	return p
+}
+
+// hookCommits: commits received by the push, qr is nil when no object is received.
+func hookCommits(ctx context.Context, qr *QR, oids []plumbing.Hash) ([]*HookCommit, bool) {
+	if qr == nil {
+		return nil, false
+	}
+	commits := make([]*HookCommit, 0, min(len(oids), maxHookCommits))
+	for _, oid := range oids {
+		if len(commits) == maxHookCommits {
+			return commits, true
+		}
+		cc, err := qr.Commit(ctx, oid)
+		if err != nil {
+			logrus.Errorf("hooks: read commit %s error: %v", oid, err)
+			continue
+		}
+		commits = append(commits, &HookCommit{Hash: oid.String(), Author: cc.Author, Committer: cc.Committer, Message: cc.Message})
+	}
+	return commits, false
+}
+
+// receiveHooks: run the pre-receive hook with all updates, then the update hook for each reference. commits are
+// the new commits of each command, returns the command rejected by the hook.
+func (h *hookRunner) receiveHooks(ctx context.Context, qr *QR, quarantineDir string, cmds []*Command, commits map[*Command][]plumbing.Hash) (*Command, error) {
+	if hk := h.lookup(HookPreReceive); hk != nil {
+		p := newHookPayload(HookPreReceive, cmds)
+		all := make([]plumbing.Hash, 0, 16)
+		for _, cmd := range cmds {
+			all = append(all, commits[cmd]...)
+		}
+		p.Commits, p.Truncated = hookCommits(ctx, qr, all)
+		if err := h.run(ctx, hk, p, cmds[0], quarantineDir); err != nil {
+			return cmds[0], err
+		}
+	}
+	if hk := h.lookup(HookUpdate); hk != nil {
+		for _, cmd := range cmds {
+			p := newHookPayload(HookUpdate, []*Command{cmd})
+			p.Commits, p.Truncated = hookCommits(ctx, qr, commits[cmd])
+			if err := h.run(ctx, hk, p, cmd, quarantineDir); err != nil {
+				return cmd, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// postReceive: run the post-receive hook asynchronously after the references are updated, failures are logged.
+func (h *hookRunner) postReceive(cmds []*Command) {
+	hk := h.lookup(HookPostReceive)
+	if hk == nil {
+		return
+	}
+	p := newHookPayload(HookPostReceive, cmds)
+	go func() {
+		if err := h.run(context.Background(), hk, p, cmds[0], ""); err != nil {
+			logrus.Errorf("hooks: %v", err)
+		}
+	}()
+}
+
+// reportHookError: the reason shown to the user
+func reportHookError(ro *reporter, cmd *Command, err error) {
+	if e, ok := err.(*ErrHookDeclined); ok {
+		_ = ro.ng(cmd, "%s", e.Error())
+		return
+	}
+	_ = ro.ng(cmd, cmd.W("run hook error: %v"), err)
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func newHookCommand() *Command {
+	return &Command{
+		RID:           1,
+		UID:           2,
+		ReferenceName: plumbing.NewBranchReferenceName("mainline"),
+		OldRev:        plumbing.ZERO_OID,
+		NewRev:        "0b94a4d2bd0dcd1cd2e4e58d3a7e2d2f2de7d5e5b12a0b7c86bfa8c0c44bf9d5",
+		PushOptions:   &PushOptions{Options: []string{"ci.skip"}},
+	}
+}
+
+func TestHookExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts require sh")
+	}
+	h := newHookRunner(&serve.Hooks{
+		PreReceive: &serve.Hook{Command: "sh", Args: []string{"-c", `cat >/dev/null; echo "$ZETA_HOOK $ZETA_RID $ZETA_PUSH_OPTION_0 ${SECRET:-none}"; exit 1`}},
+		Update:     &serve.Hook{Command: "sh", Args: []string{"-c", "sleep 5"}, Timeout: serve.Duration{Duration: 100 * time.Millisecond}},
+	})
+	t.Setenv("SECRET", "leaked")
+	cmd := newHookCommand()
+	rejected, err := h.receiveHooks(context.Background(), nil, "", []*Command{cmd}, nil)
+	require.Equal(t, cmd, rejected)
+	require.True(t, IsErrHookDeclined(err))
+	require.Equal(t, "pre-receive 1 ci.skip none", err.(*ErrHookDeclined).Message)
+
+	h.cfg.PreReceive = nil
+	start := time.Now()
+	_, err = h.receiveHooks(context.Background(), nil, "", []*Command{cmd}, nil)
+	require.True(t, IsErrHookDeclined(err))
+	require.Contains(t, err.Error(), "timed out")
+	require.Less(t, time.Since(start), 3*time.Second)
+
+	var none *hookRunner
+	rejected, err = none.receiveHooks(context.Background(), nil, "", []*Command{cmd}, nil)
+	require.NoError(t, err)
+	require.Nil(t, rejected)
+}
+
+func TestHookCallout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p HookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if strings.HasSuffix(string(p.References[0].ReferenceName), "mainline") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"mainline is frozen"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	h := newHookRunner(&serve.Hooks{Update: &serve.Hook{URL: srv.URL, Token: "secret"}})
+	cmd := newHookCommand()
+	_, err := h.receiveHooks(context.Background(), nil, "", []*Command{cmd}, nil)
+	require.Equal(t, &ErrHookDeclined{Hook: HookUpdate, Message: "mainline is frozen"}, err)
+
+	cmd.ReferenceName = plumbing.NewBranchReferenceName("dev")
+	_, err = h.receiveHooks(context.Background(), nil, "", []*Command{cmd}, nil)
+	require.NoError(t, err)
+}
+
+func TestHookRunnerCapabilities(t *testing.T) {
+	for _, cfg := range []*serve.Hooks{nil, {}, {PreReceive: &serve.Hook{}}} {
+		require.Nil(t, newHookRunner(cfg))
+		require.NotContains(t, (&repositories{hooks: newHookRunner(cfg)}).Capabilities(), transport.CapabilityHooks)
+	}
+	r := &repositories{hooks: newHookRunner(&serve.Hooks{PostReceive: &serve.Hook{URL: "http://127.0.0.1/hooks"}})}
+	require.Contains(t, r.Capabilities(), transport.CapabilityHooks)
+}
@@ -0,0 +1,596 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+type aclNode struct {
+	entries  map[string]*aclNode
+	rule     bool
+	level    database.AccessLevel // access level of the user to the directory
+	readable bool                 // the directory or one of its subdirectories is readable
+}
+
+func (n *aclNode) resolve(parent database.AccessLevel) bool {
+	if !n.rule {
+		n.level = parent
+	}
+	n.readable = n.level.Readable()
+	for _, e := range n.entries {
+		if e.resolve(n.level) {
+			n.readable = true
+		}
+	}
+	return n.readable
+}
+
+func (n *aclNode) Match(name string) (protocol.PathMatcher, bool) {
+	e, ok := n.entries[name]
+	if !ok {
+		return nil, n.level.Readable()
+	}
+	if len(e.entries) == 0 {
+		return nil, e.level.Readable()
+	}
+	return e, e.readable
+}
+
+func (n *aclNode) Readable(name string) bool {
+	if e, ok := n.entries[name]; ok {
+		return e.level.Readable()
+	}
+	return n.level.Readable()
+}
+
+// writable: whether everything under the directory is writable
+func (n *aclNode) writable() bool {
+	if !n.level.Writeable() {
+		return false
+	}
+	for _, e := range n.entries {
+		if !e.writable() {
+			return false
+		}
+	}
+	return true
+}
+
+// PathACL: access of the user to the paths of the repository. Paths covered by ACL rules are accessible to the
+// members of the granted groups, the most specific rule of the path wins, other paths follow the access level of
+// the user to the repository. nil means all paths are accessible.
+type PathACL struct {
+	root *aclNode
+	key  string // resolved access levels of the paths, users with the same key have the same access
+}
+
+// NewPathACL: groups are the groups of the user, returns nil if the repository has no ACL rules or the user is the
+// owner of the repository.
+func NewPathACL(acls []*database.PathACL, groups []int64, accessLevel database.AccessLevel) *PathACL {
+	if accessLevel >= database.OwnerAccess {
+		return nil
+	}
+	root := &aclNode{entries: make(map[string]*aclNode), level: accessLevel}
+	for _, a := range acls {
+		p, ok := database.CleanACLPath(a.Path)
+		if !ok {
+			continue
+		}
+		current := root
+		for _, d := range strengthen.StrSplitSkipEmpty(p, '/', 10) {
+			e, ok := current.entries[d]
+			if !ok {
+				e = &aclNode{entries: make(map[string]*aclNode)}
+				current.entries[d] = e
+			}
+			current = e
+		}
+		current.rule = true
+		if slices.Contains(groups, a.NamespaceID) {
+			// granted access never exceeds the access to the repository
+			current.level = max(current.level, min(a.AccessLevel, accessLevel))
+		}
+	}
+	if len(root.entries) == 0 {
+		return nil
+	}
+	root.resolve(accessLevel)
+	var b strings.Builder
+	root.writeKey(&b, "")
+	return &PathACL{root: root, key: b.String()}
+}
+
+func (n *aclNode) writeKey(b *strings.Builder, name string) {
+	fmt.Fprintf(b, "%s:%d;", name, n.level)
+	names := make([]string, 0, len(n.entries))
+	for d := range n.entries {
+		names = append(names, d)
+	}
+	slices.Sort(names)
+	for _, d := range names {
+		n.entries[d].writeKey(b, name+"/"+d)
+	}
+}
+
+// lookup: the deepest node of the path and whether the node is the path itself
+func (a *PathACL) lookup(name string) (*aclNode, bool) {
+	current := a.root
+	for _, d := range strengthen.StrSplitSkipEmpty(name, '/', 10) {
+		e, ok := current.entries[d]
+		if !ok {
+			return current, false
+		}
+		current = e
+	}
+	return current, true
+}
+
+// Level: access level of the user to the file or directory
+func (a *PathACL) Level(name string) database.AccessLevel {
+	n, _ := a.lookup(name)
+	return n.level
+}
+
+// CanRead: whether the user is allowed to read the directory or one of its subdirectories
+func (a *PathACL) CanRead(dir string) bool {
+	n, exact := a.lookup(dir)
+	if exact {
+		return n.readable
+	}
+	return n.level.Readable()
+}
+
+// CanWrite: whether the user is allowed to modify or remove the file or directory, including everything under it
+func (a *PathACL) CanWrite(name string) bool {
+	n, exact := a.lookup(name)
+	if exact {
+		return n.writable()
+	}
+	return n.level.Writeable()
+}
+
+func (a *PathACL) Matcher() protocol.PathMatcher {
+	return a.root
+}
+
+// walkReadable: call fn with each readable object under the tree, including the tree itself, the fragments of large
+// files and their blobs. stop is called before each tree to finish the walk early.
+func walkReadable(ctx context.Context, o odb.DB, oid plumbing.Hash, m protocol.PathMatcher, seen map[plumbing.Hash]bool, fn func(oid plumbing.Hash, blob bool), stop func() bool) error {
+	if stop() {
+		return nil
+	}
+	if m == nil {
+		if seen[oid] {
+			return nil
+		}
+		seen[oid] = true
+	}
+	tree, err := o.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	fn(oid, false)
+	for _, e := range tree.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			var sub protocol.PathMatcher
+			if m != nil {
+				var ok bool
+				if sub, ok = m.Match(e.Name); !ok {
+					continue
+				}
+			}
+			if err := walkReadable(ctx, o, e.Hash, sub, seen, fn, stop); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if m != nil && !m.Readable(e.Name) {
+				continue
+			}
+			fn(e.Hash, false)
+			ff, err := o.Fragments(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			for _, f := range ff.Entries {
+				fn(f.Hash, true)
+			}
+		default:
+			if m != nil && !m.Readable(e.Name) {
+				continue
+			}
+			fn(e.Hash, true)
+		}
+	}
+	return nil
+}
+
+// MissingObjects: blobs under the readable paths of want but not of have, replaces odb.MissingObjects for users with
+// restricted paths because blobs reachable from want may be under unreadable paths. The bitmap index is not used, it
+// falls back to a full walk of the readable trees of want and have on every request.
+func (a *PathACL) MissingObjects(ctx context.Context, o odb.DB, want, have plumbing.Hash) ([]plumbing.Hash, error) {
+	readableBlobs := func(oid plumbing.Hash) (map[plumbing.Hash]bool, error) {
+		cc, err := o.Commit(ctx, oid)
+		if err != nil {
+			return nil, err
+		}
+		blobs := make(map[plumbing.Hash]bool)
+		if err := walkReadable(ctx, o, cc.Tree, a.root, make(map[plumbing.Hash]bool), func(oid plumbing.Hash, blob bool) {
+			if blob {
+				blobs[oid] = true
+			}
+		}, func() bool { return false }); err != nil {
+			return nil, err
+		}
+		return blobs, nil
+	}
+	blobs, err := readableBlobs(want)
+	if err != nil {
+		return nil, err
+	}
+	if !have.IsZero() {
+		haves, err := readableBlobs(have)
+		if err != nil {
+			return nil, err
+		}
+		for oid := range haves {
+			delete(blobs, oid)
+		}
+	}
+	oids := make([]plumbing.Hash, 0, len(blobs))
+	for oid := range blobs {
+		oids = append(oids, oid)
+	}
+	return oids, nil
+}
+
+// hiddenSet: objects under the unreadable paths of the history of the references, cached by the repository and the
+// ACL. A cached set is never modified, it is extended to a new set with the new commits when the reference version
+// of the repository is changed.
+type hiddenSet struct {
+	acl     *PathACL
+	version int64
+	roots   []plumbing.Hash        // trees of the tips
+	commits map[plumbing.Hash]bool // walked commits
+	walked  map[walkedTree]bool    // walked restricted directories
+	seen    map[plumbing.Hash]bool // walked hidden trees
+	hidden  map[plumbing.Hash]bool
+	mu      sync.Mutex
+	// allowed: hidden objects which are also under the readable paths of the tips, nil until walked
+	allowed map[plumbing.Hash]bool
+}
+
+type walkedTree struct {
+	oid plumbing.Hash
+	n   *aclNode
+}
+
+// HiddenObjects: objects under the unreadable paths of the reference tips and their history, an object removed from a
+// restricted directory is still hidden.
+type HiddenObjects struct {
+	*hiddenSet
+	o odb.DB
+}
+
+const (
+	// hiddenCacheCost: max objects and commits of the cached hidden sets
+	hiddenCacheCost = 1 << 24
+	hiddenCacheTTL  = time.Hour
+)
+
+// hiddenSets: *hiddenSet keyed by the repository and the ACL, replaced when the reference version is changed.
+var hiddenSets = func() *ristretto.Cache[string, *hiddenSet] {
+	c, err := ristretto.NewCache(&ristretto.Config[string, *hiddenSet]{
+		NumCounters: 100000,
+		MaxCost:     hiddenCacheCost,
+		BufferItems: 64,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// resolveTips: commits of the references and their trees
+func (r *repository) resolveTips(ctx context.Context) ([]plumbing.Hash, []plumbing.Hash, error) {
+	tips := make([]plumbing.Hash, 0, 16)
+	roots := make([]plumbing.Hash, 0, 16)
+	var cursor string
+	for {
+		refs, next, err := r.mdb.ListReferences(ctx, r.rid, "", cursor, database.MaxListLimit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ref := range refs {
+			oid := plumbing.NewHash(ref.Hash)
+			if ref.Name.IsTag() {
+				oid = peelTag(ctx, r.odb, oid)
+			}
+			cc, err := r.odb.Commit(ctx, oid)
+			if err != nil {
+				continue
+			}
+			if !slices.Contains(tips, cc.Hash) {
+				tips = append(tips, cc.Hash)
+			}
+			if !slices.Contains(roots, cc.Tree) {
+				roots = append(roots, cc.Tree)
+			}
+		}
+		if len(next) == 0 {
+			return tips, roots, nil
+		}
+		cursor = next
+	}
+}
+
+// HiddenObjects: returns nil if acl is nil. The references are resolved only when the reference version of the
+// repository is changed, the commits walked before are skipped.
+func (r *repository) HiddenObjects(ctx context.Context, acl *PathACL) (*HiddenObjects, error) {
+	if acl == nil {
+		return nil, nil
+	}
+	version, err := r.mdb.ReferenceVersion(ctx, r.rid)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d/%s", r.rid, acl.key)
+	h, ok := hiddenSets.Get(key)
+	if ok && h.version == version {
+		return &HiddenObjects{hiddenSet: h, o: r.odb}, nil
+	}
+	if !ok {
+		h = newHiddenSet(acl)
+	}
+	tips, roots, err := r.resolveTips(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if h, err = h.extend(ctx, r.odb, version, tips, roots); err != nil {
+		return nil, err
+	}
+	hiddenSets.SetWithTTL(key, h, int64(len(h.hidden)+len(h.commits))+1, hiddenCacheTTL)
+	return &HiddenObjects{hiddenSet: h, o: r.odb}, nil
+}
+
+func newHiddenSet(acl *PathACL) *hiddenSet {
+	return &hiddenSet{
+		acl:     acl,
+		commits: make(map[plumbing.Hash]bool),
+		walked:  make(map[walkedTree]bool),
+		seen:    make(map[plumbing.Hash]bool),
+		hidden:  make(map[plumbing.Hash]bool),
+	}
+}
+
+// extend: returns a new set with the commits reachable from the tips, only restricted directories of the commits not
+// walked by h are walked.
+func (h *hiddenSet) extend(ctx context.Context, o odb.DB, version int64, tips, roots []plumbing.Hash) (*hiddenSet, error) {
+	n := &hiddenSet{
+		acl:     h.acl,
+		version: version,
+		roots:   roots,
+		commits: maps.Clone(h.commits),
+		walked:  maps.Clone(h.walked),
+		seen:    maps.Clone(h.seen),
+		hidden:  maps.Clone(h.hidden),
+	}
+	pending := slices.Clone(tips)
+	for len(pending) != 0 {
+		oid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if n.commits[oid] {
+			continue
+		}
+		n.commits[oid] = true
+		cc, err := o.Commit(ctx, oid)
+		if plumbing.IsNoSuchObject(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := n.collect(ctx, o, cc.Tree, n.acl.root); err != nil {
+			return nil, err
+		}
+		pending = append(pending, cc.Parents...)
+	}
+	return n, nil
+}
+
+// collect: only restricted directories are walked
+func (h *hiddenSet) collect(ctx context.Context, o odb.DB, oid plumbing.Hash, n *aclNode) error {
+	if h.walked[walkedTree{oid: oid, n: n}] {
+		return nil
+	}
+	h.walked[walkedTree{oid: oid, n: n}] = true
+	tree, err := o.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		sub, ok := n.entries[e.Name]
+		level := n.level
+		if ok {
+			level = sub.level
+		}
+		if e.Type() == object.TreeObject && ok && len(sub.entries) != 0 {
+			if err := h.collect(ctx, o, e.Hash, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		if level.Readable() {
+			continue
+		}
+		if err := h.hideAll(ctx, o, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *hiddenSet) hideAll(ctx context.Context, o odb.DB, e *object.TreeEntry) error {
+	h.hidden[e.Hash] = true
+	switch e.Type() {
+	case object.TreeObject:
+		if h.seen[e.Hash] {
+			return nil
+		}
+		h.seen[e.Hash] = true
+		tree, err := o.Tree(ctx, e.Hash)
+		if err != nil {
+			return err
+		}
+		for _, sub := range tree.Entries {
+			if err := h.hideAll(ctx, o, sub); err != nil {
+				return err
+			}
+		}
+	case object.FragmentsObject:
+		ff, err := o.Fragments(ctx, e.Hash)
+		if err != nil {
+			return err
+		}
+		for _, f := range ff.Entries {
+			h.hidden[f.Hash] = true
+		}
+	}
+	return nil
+}
+
+// Contains: whether the object is under unreadable paths, the object may also be under readable paths, see Refused.
+func (h *HiddenObjects) Contains(oid plumbing.Hash) bool {
+	return h != nil && h.hidden[oid]
+}
+
+// resolveAllowed: walk the readable paths of the tips once to find the hidden objects which are also readable.
+func (h *HiddenObjects) resolveAllowed(ctx context.Context) (map[plumbing.Hash]bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.allowed != nil {
+		return h.allowed, nil
+	}
+	allowed := make(map[plumbing.Hash]bool)
+	seen := make(map[plumbing.Hash]bool)
+	for _, root := range h.roots {
+		if err := walkReadable(ctx, h.o, root, h.acl.root, seen, func(oid plumbing.Hash, _ bool) {
+			if h.hidden[oid] {
+				allowed[oid] = true
+			}
+		}, func() bool { return false }); err != nil {
+			return nil, err
+		}
+	}
+	h.allowed = allowed
+	return allowed, nil
+}
+
+// Refused: objects the user is not allowed to read, hidden objects which are also under the readable paths of the
+// reference tips (eg: empty files) are allowed. A hidden object which is readable only in the history is refused.
+func (h *HiddenObjects) Refused(ctx context.Context, oids []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	if h == nil {
+		return nil, nil
+	}
+	refused := make(map[plumbing.Hash]bool)
+	for _, oid := range oids {
+		if h.hidden[oid] {
+			refused[oid] = true
+		}
+	}
+	if len(refused) == 0 {
+		return nil, nil
+	}
+	allowed, err := h.resolveAllowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for oid := range refused {
+		if allowed[oid] {
+			delete(refused, oid)
+		}
+	}
+	return refused, nil
+}
+
+// checkPathACL: check the files modified by the new commits after the integrity check, a change of a merge commit is
+// ignored if one of the other parents has the same entry. The rejection is reported to the user.
+func (r *QR) checkPathACL(ctx context.Context, cmd *Command, rr *reporter, commits []plumbing.Hash) error {
+	acl := cmd.PathACL
+	if acl == nil || cmd.NewRev == plumbing.ZERO_OID {
+		return nil
+	}
+	seen := make(map[[2]plumbing.Hash]bool)
+	for _, oid := range commits {
+		cc, err := r.Commit(ctx, oid)
+		if err != nil {
+			_ = rr.ng(cmd, "resolve commit '%s' error: %v", oid, err)
+			return err
+		}
+		var parentTree plumbing.Hash
+		others := make([]*object.Tree, 0, len(cc.Parents))
+		for i, p := range cc.Parents {
+			parent, err := r.Commit(ctx, p)
+			if err != nil {
+				_ = rr.ng(cmd, "resolve commit '%s' error: %v", p, err)
+				return err
+			}
+			if i == 0 {
+				parentTree = parent.Tree
+				continue
+			}
+			if t, err := r.Tree(ctx, parent.Tree); err == nil {
+				others = append(others, t)
+			}
+		}
+		if err := r.changedEntries(ctx, parentTree, cc.Tree, "", seen, func(name string, e *object.TreeEntry) error {
+			if acl.CanWrite(name) {
+				return nil
+			}
+			for _, t := range others {
+				o, err := t.FindEntry(ctx, name)
+				if (e == nil && err != nil) || (e != nil && err == nil && o.Hash == e.Hash) {
+					return nil
+				}
+			}
+			_ = rr.ng(cmd, cmd.W("you are not allowed to modify '%s' in '%s'"), name, oid)
+			return &ErrPathDenied{Path: name}
+		}); err != nil {
+			if !IsErrPathDenied(err) {
+				_ = rr.ng(cmd, "check tree of '%s' error: %v", oid, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrPathDenied: the user is not allowed to access the path
+type ErrPathDenied struct {
+	Path string
+}
+
+func (e *ErrPathDenied) Error() string {
+	return fmt.Sprintf("access to '%s' is denied", strings.TrimSuffix(e.Path, "/"))
+}
+
+func IsErrPathDenied(err error) bool {
+	_, ok := err.(*ErrPathDenied)
+	return ok
+}
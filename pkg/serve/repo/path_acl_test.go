@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathACL(t *testing.T) {
+	acls := []*database.PathACL{
+		{Path: "secret", NamespaceID: 1, AccessLevel: database.DevAccess},
+		{Path: "secret/public/", NamespaceID: 2, AccessLevel: database.ReporterAccess},
+		{Path: "/docs/internal/./", NamespaceID: 1, AccessLevel: database.ReporterAccess},
+	}
+	require.Nil(t, NewPathACL(nil, nil, database.DevAccess))
+	require.Nil(t, NewPathACL(acls, nil, database.OwnerAccess))
+
+	// member of group 1
+	a := NewPathACL(acls, []int64{1}, database.DevAccess)
+	require.True(t, a.CanWrite("secret/a.go"))
+	require.False(t, a.CanRead("secret/public"))
+	require.False(t, a.CanWrite("secret"))
+	require.True(t, a.CanRead("docs"))
+	require.True(t, a.CanRead("docs/internal/x"))
+	require.False(t, a.CanWrite("docs/internal/x.md"))
+	require.True(t, a.CanWrite("src/main.go"))
+
+	// member of group 2 with read access to the repository
+	a = NewPathACL(acls, []int64{2}, database.ReporterAccess)
+	require.True(t, a.CanRead("secret"))
+	require.Equal(t, database.NoneAccess, a.Level("secret/a.go"))
+	require.Equal(t, database.ReporterAccess, a.Level("secret/public/a.go"))
+	require.False(t, a.CanRead("docs/internal"))
+	require.False(t, a.CanWrite("src/main.go"))
+
+	m := a.Matcher()
+	sub, ok := m.Match("src")
+	require.True(t, ok)
+	require.Nil(t, sub)
+	_, ok = m.Match("docs")
+	require.True(t, ok)
+	sub, ok = m.Match("secret")
+	require.True(t, ok)
+	require.False(t, sub.Readable("a.go"))
+	_, ok = sub.Match("private")
+	require.False(t, ok)
+	leaf, ok := sub.Match("public")
+	require.True(t, ok)
+	require.Nil(t, leaf)
+}
+
+type treeDB struct {
+	odb.DB
+	trees   map[plumbing.Hash]*object.Tree
+	commits map[plumbing.Hash]*object.Commit
+}
+
+func (d *treeDB) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	if cc, ok := d.commits[oid]; ok {
+		return cc, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (d *treeDB) Tree(ctx context.Context, oid plumbing.Hash) (*object.Tree, error) {
+	if t, ok := d.trees[oid]; ok {
+		return t, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func TestHiddenObjects(t *testing.T) {
+	ctx := context.Background()
+	// empty files are in both src and secret
+	empty := testCommitHash("e0")
+	src := &object.Tree{Hash: testCommitHash("a1"), Entries: []*object.TreeEntry{
+		{Name: "a.go", Mode: filemode.Regular, Hash: testCommitHash("b1")},
+		{Name: "empty", Mode: filemode.Regular, Hash: empty},
+	}}
+	secret := &object.Tree{Hash: testCommitHash("a2"), Entries: []*object.TreeEntry{
+		{Name: "s.go", Mode: filemode.Regular, Hash: testCommitHash("b2")},
+		{Name: "empty", Mode: filemode.Regular, Hash: empty},
+	}}
+	root := &object.Tree{Hash: testCommitHash("a3"), Entries: []*object.TreeEntry{
+		{Name: "secret", Mode: filemode.Dir, Hash: secret.Hash},
+		{Name: "src", Mode: filemode.Dir, Hash: src.Hash},
+	}}
+	// secret/old.go was removed from the tip
+	oldSecret := &object.Tree{Hash: testCommitHash("a4"), Entries: []*object.TreeEntry{
+		{Name: "old.go", Mode: filemode.Regular, Hash: testCommitHash("b3")},
+	}}
+	oldRoot := &object.Tree{Hash: testCommitHash("a5"), Entries: []*object.TreeEntry{
+		{Name: "secret", Mode: filemode.Dir, Hash: oldSecret.Hash},
+	}}
+	c1 := &object.Commit{Hash: testCommitHash("c1"), Tree: oldRoot.Hash}
+	c2 := &object.Commit{Hash: testCommitHash("c2"), Tree: root.Hash, Parents: []plumbing.Hash{c1.Hash}}
+	d := &treeDB{
+		trees:   map[plumbing.Hash]*object.Tree{src.Hash: src, secret.Hash: secret, root.Hash: root, oldSecret.Hash: oldSecret, oldRoot.Hash: oldRoot},
+		commits: map[plumbing.Hash]*object.Commit{c1.Hash: c1, c2.Hash: c2},
+	}
+	acls := []*database.PathACL{{Path: "secret/", NamespaceID: 1, AccessLevel: database.DevAccess}}
+	acl := NewPathACL(acls, nil, database.DevAccess)
+	require.Equal(t, acl.key, NewPathACL(acls, []int64{2}, database.DevAccess).key)
+	require.NotEqual(t, acl.key, NewPathACL(acls, []int64{1}, database.DevAccess).key)
+
+	hs, err := newHiddenSet(acl).extend(ctx, d, 1, []plumbing.Hash{c2.Hash}, []plumbing.Hash{root.Hash})
+	require.NoError(t, err)
+	h := &HiddenObjects{hiddenSet: hs, o: d}
+	require.True(t, h.Contains(secret.Hash))
+	require.True(t, h.Contains(empty))
+	require.True(t, h.Contains(testCommitHash("b3")))
+	require.False(t, h.Contains(src.Hash))
+	refused, err := h.Refused(ctx, []plumbing.Hash{testCommitHash("b1"), testCommitHash("b2"), testCommitHash("b3"), empty})
+	require.NoError(t, err)
+	require.Equal(t, map[plumbing.Hash]bool{testCommitHash("b2"): true, testCommitHash("b3"): true}, refused)
+	// the readable paths are walked once
+	delete(d.trees, src.Hash)
+	refused, err = h.Refused(ctx, []plumbing.Hash{empty, secret.Hash})
+	require.NoError(t, err)
+	require.Equal(t, map[plumbing.Hash]bool{secret.Hash: true}, refused)
+
+	// only the new commits are walked when the set is extended, the cached set is not modified
+	newSecret := &object.Tree{Hash: testCommitHash("a6"), Entries: []*object.TreeEntry{
+		{Name: "new.go", Mode: filemode.Regular, Hash: testCommitHash("b4")},
+	}}
+	newRoot := &object.Tree{Hash: testCommitHash("a7"), Entries: []*object.TreeEntry{
+		{Name: "secret", Mode: filemode.Dir, Hash: newSecret.Hash},
+	}}
+	c3 := &object.Commit{Hash: testCommitHash("c3"), Tree: newRoot.Hash, Parents: []plumbing.Hash{c2.Hash}}
+	d.trees[newSecret.Hash] = newSecret
+	d.trees[newRoot.Hash] = newRoot
+	d.commits[c3.Hash] = c3
+	delete(d.trees, oldRoot.Hash)
+	extended, err := hs.extend(ctx, d, 2, []plumbing.Hash{c3.Hash}, []plumbing.Hash{newRoot.Hash})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), extended.version)
+	require.True(t, extended.hidden[testCommitHash("b3")])
+	require.True(t, extended.hidden[testCommitHash("b4")])
+	require.False(t, h.Contains(testCommitHash("b4")))
+}
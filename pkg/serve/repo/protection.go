@@ -0,0 +1,317 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/emirpasic/gods/trees/binaryheap"
+)
+
+// ProtectionReason: reason if the user is not allowed to update or delete the protected reference, checked before
+// objects are received. Rules about the content are checked by checkProtection.
+func (c *Command) ProtectionReason() string {
+	p := c.Protection
+	if p == nil {
+		return ""
+	}
+	if c.NewRev == plumbing.ZERO_OID {
+		if c.AccessLevel < p.DeleteAccessLevel {
+			return fmt.Sprintf(c.W("'%s' is protected, you are not allowed to delete it"), c.ReferenceName)
+		}
+		return ""
+	}
+	if c.AccessLevel < p.PushAccessLevel {
+		return fmt.Sprintf(c.W("'%s' is protected, you are not allowed to push to it"), c.ReferenceName)
+	}
+	return ""
+}
+
+// MatchForbiddenPath: pattern ending with '/' matches everything in the directory, pattern without '/' matches the
+// file name at any depth, other patterns match the full path, see path.Match.
+func MatchForbiddenPath(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		var matched bool
+		switch {
+		case strings.HasSuffix(pattern, "/"):
+			matched = strings.HasPrefix(name, pattern)
+		case !strings.Contains(pattern, "/"):
+			matched, _ = path.Match(pattern, path.Base(name))
+		default:
+			matched, _ = path.Match(pattern, name)
+		}
+		if matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// hasSignatureHeader: the commit has a gpgsig header, the signature is not verified, the server has no keys of the
+// committers.
+func hasSignatureHeader(cc *object.Commit) bool {
+	for _, h := range cc.ExtraHeaders {
+		if h.K == "gpgsig" || h.K == "gpgsig-sha256" {
+			return true
+		}
+	}
+	return false
+}
+
+// changedEntries: call fn with the path of each file of newTree which is added or modified compared to oldTree, and
+// with a nil entry for each file or directory of oldTree which is removed. oldTree is zero for root commits.
+// Unchanged subtrees are skipped.
+func (r *QR) changedEntries(ctx context.Context, oldTree, newTree plumbing.Hash, prefix string, seen map[[2]plumbing.Hash]bool, fn func(name string, e *object.TreeEntry) error) error {
+	if oldTree == newTree || seen[[2]plumbing.Hash{oldTree, newTree}] {
+		return nil
+	}
+	seen[[2]plumbing.Hash{oldTree, newTree}] = true
+	nt, err := r.Tree(ctx, newTree)
+	if err != nil {
+		return err
+	}
+	old := make(map[string]*object.TreeEntry)
+	if !oldTree.IsZero() {
+		ot, err := r.Tree(ctx, oldTree)
+		if err != nil {
+			return err
+		}
+		for _, e := range ot.Entries {
+			old[e.Name] = e
+		}
+	}
+	for _, e := range nt.Entries {
+		name := path.Join(prefix, e.Name)
+		o := old[e.Name]
+		delete(old, e.Name)
+		if o != nil && o.Hash == e.Hash && o.Mode == e.Mode {
+			continue
+		}
+		if o != nil && o.Type() == object.TreeObject && e.Type() != object.TreeObject {
+			if err := fn(name, nil); err != nil {
+				return err
+			}
+		}
+		if e.Type() == object.TreeObject {
+			var oldSub plumbing.Hash
+			if o != nil && o.Type() == object.TreeObject {
+				oldSub = o.Hash
+			}
+			if err := r.changedEntries(ctx, oldSub, e.Hash, name, seen, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(name, e); err != nil {
+			return err
+		}
+	}
+	for _, o := range old {
+		if err := fn(path.Join(prefix, o.Name), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitReader: commits and tags of the repository, objects of the push included
+type commitReader interface {
+	Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error)
+	Tag(ctx context.Context, oid plumbing.Hash) (*object.Tag, error)
+}
+
+const (
+	fromNewRev = 1 << iota
+	fromOldRev
+)
+
+// rangeCommits: commits reachable from newRev but not from oldRev like 'git rev-list oldRev..newRev', newest first.
+// The history is walked by committer time and the walk stops when only commits reachable from oldRev are queued,
+// so the history behind oldRev is not walked. oldRev is zero for new references, all commits of newRev are returned.
+// Clock skew may only add commits reachable from oldRev, commits of the range are never skipped.
+func rangeCommits(ctx context.Context, o commitReader, oldRev, newRev plumbing.Hash) ([]*object.Commit, error) {
+	peel := func(oid plumbing.Hash) (*object.Commit, error) {
+		for {
+			tag, err := o.Tag(ctx, oid)
+			if err != nil {
+				break
+			}
+			oid = tag.Object
+		}
+		return o.Commit(ctx, oid)
+	}
+	flags := make(map[plumbing.Hash]int)
+	queued := make(map[plumbing.Hash]bool)
+	commits := make(map[plumbing.Hash]*object.Commit)
+	queue := binaryheap.NewWith(func(a, b any) int {
+		if a.(*object.Commit).Committer.When.Before(b.(*object.Commit).Committer.When) {
+			return 1
+		}
+		return -1
+	})
+	walked := make([]plumbing.Hash, 0, 16)
+	walkedSet := make(map[plumbing.Hash]bool)
+	// pending: queued commits which are not reachable from oldRev, rewalk: queued commits which are walked again
+	var pending, rewalk int
+	mark := func(cc *object.Commit, flag int) {
+		old, ok := flags[cc.Hash]
+		if ok && old|flag == old {
+			return
+		}
+		flags[cc.Hash] = old | flag
+		commits[cc.Hash] = cc
+		if queued[cc.Hash] {
+			if old&fromOldRev == 0 && flag&fromOldRev != 0 {
+				pending--
+			}
+			return
+		}
+		// commits reached by oldRev after they were walked (clock skew) are walked again to mark their parents
+		queued[cc.Hash] = true
+		queue.Push(cc)
+		switch {
+		case walkedSet[cc.Hash]:
+			rewalk++
+		case (old|flag)&fromOldRev == 0:
+			pending++
+		}
+	}
+	tip, err := peel(newRev)
+	if err != nil {
+		return nil, err
+	}
+	mark(tip, fromNewRev)
+	if !oldRev.IsZero() {
+		base, err := peel(oldRev)
+		if err != nil {
+			return nil, err
+		}
+		mark(base, fromOldRev)
+	}
+	for pending > 0 || rewalk > 0 {
+		v, ok := queue.Pop()
+		if !ok {
+			break
+		}
+		cc := v.(*object.Commit)
+		queued[cc.Hash] = false
+		flag := flags[cc.Hash]
+		switch {
+		case walkedSet[cc.Hash]:
+			rewalk--
+		case flag&fromOldRev == 0:
+			pending--
+			walkedSet[cc.Hash] = true
+			walked = append(walked, cc.Hash)
+		}
+		for _, p := range cc.Parents {
+			pc, ok := commits[p]
+			if !ok {
+				if pc, err = o.Commit(ctx, p); err != nil {
+					if plumbing.IsNoSuchObject(err) {
+						// shallow history
+						continue
+					}
+					return nil, err
+				}
+			}
+			mark(pc, flag)
+		}
+	}
+	result := make([]*object.Commit, 0, len(walked))
+	for _, oid := range walked {
+		if flags[oid]&fromOldRev == 0 {
+			result = append(result, commits[oid])
+		}
+	}
+	return result, nil
+}
+
+// checkProtection: check all commits of OldRev..NewRev of the protected reference after the integrity check, commits
+// which already exist on the server are checked too, the rejection is reported to the user.
+func (r *QR) checkProtection(ctx context.Context, cmd *Command, rr *reporter, ic *integrity) error {
+	p := cmd.Protection
+	if p == nil || cmd.NewRev == plumbing.ZERO_OID {
+		return nil
+	}
+	if ic.forcePush && cmd.OldRev != plumbing.ZERO_OID && cmd.AccessLevel < p.ForcePushAccessLevel {
+		_ = rr.ng(cmd, cmd.W("'%s' is protected, force push is not allowed"), cmd.ReferenceName)
+		return &ErrProtected{Reference: cmd.ReferenceName}
+	}
+	checkFiles := p.MaxBlobSize > 0 || len(p.ForbiddenPaths) != 0
+	if !p.RequireLinearHistory && !p.RequireSignatureHeader && !checkFiles {
+		return nil
+	}
+	commits, err := rangeCommits(ctx, r, plumbing.NewHash(cmd.OldRev), plumbing.NewHash(cmd.NewRev))
+	if err != nil {
+		_ = rr.ng(cmd, "walk commits '%s..%s' error: %v", cmd.OldRev, cmd.NewRev, err)
+		return err
+	}
+	seen := make(map[[2]plumbing.Hash]bool)
+	for _, cc := range commits {
+		oid := cc.Hash
+		if p.RequireLinearHistory && len(cc.Parents) > 1 {
+			_ = rr.ng(cmd, cmd.W("'%s' is protected, linear history is required but '%s' is a merge commit"), cmd.ReferenceName, oid)
+			return &ErrProtected{Reference: cmd.ReferenceName}
+		}
+		if p.RequireSignatureHeader && !hasSignatureHeader(cc) {
+			_ = rr.ng(cmd, cmd.W("'%s' is protected, a signature header is required but '%s' has none"), cmd.ReferenceName, oid)
+			return &ErrProtected{Reference: cmd.ReferenceName}
+		}
+		if !checkFiles {
+			continue
+		}
+		var parentTree plumbing.Hash
+		if len(cc.Parents) != 0 {
+			parent, err := r.Commit(ctx, cc.Parents[0])
+			if err != nil {
+				_ = rr.ng(cmd, "resolve commit '%s' error: %v", cc.Parents[0], err)
+				return err
+			}
+			parentTree = parent.Tree
+		}
+		if err := r.changedEntries(ctx, parentTree, cc.Tree, "", seen, func(name string, e *object.TreeEntry) error {
+			if e == nil {
+				// removed
+				return nil
+			}
+			if pattern, ok := MatchForbiddenPath(p.ForbiddenPaths, name); ok {
+				_ = rr.ng(cmd, cmd.W("'%s' is protected, '%s' modified by '%s' matches forbidden path '%s'"), cmd.ReferenceName, name, oid, pattern)
+				return &ErrProtected{Reference: cmd.ReferenceName}
+			}
+			if p.MaxBlobSize > 0 && e.Size > p.MaxBlobSize {
+				_ = rr.ng(cmd, cmd.W("'%s' is protected, '%s' (%s) in '%s' exceeds the file size limit %s"), cmd.ReferenceName, name,
+					strengthen.HumanateSize(e.Size), oid, strengthen.HumanateSize(p.MaxBlobSize))
+				return &ErrProtected{Reference: cmd.ReferenceName}
+			}
+			return nil
+		}); err != nil {
+			if !IsErrProtected(err) {
+				_ = rr.ng(cmd, "check tree of '%s' error: %v", oid, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrProtected: the push violates the protection rule of the reference, the reason has been reported.
+type ErrProtected struct {
+	Reference plumbing.ReferenceName
+}
+
+func (e *ErrProtected) Error() string {
+	return fmt.Sprintf("'%s' is protected", e.Reference)
+}
+
+func IsErrProtected(err error) bool {
+	_, ok := err.(*ErrProtected)
+	return ok
+}
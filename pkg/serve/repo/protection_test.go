@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchForbiddenPath(t *testing.T) {
+	patterns := []string{"vendor/", "*.exe", "config/*.key"}
+	for _, c := range []struct {
+		name    string
+		pattern string
+	}{
+		{"vendor/a/b.go", "vendor/"},
+		{"src/vendor/b.go", ""},
+		{"bin/tool.exe", "*.exe"},
+		{"tool.exe", "*.exe"},
+		{"config/server.key", "config/*.key"},
+		{"config/tls/server.key", ""},
+		{"main.go", ""},
+	} {
+		pattern, ok := MatchForbiddenPath(patterns, c.name)
+		require.Equal(t, c.pattern, pattern, "%v", c)
+		require.Equal(t, len(c.pattern) != 0, ok, "%v", c)
+	}
+}
+
+func TestProtectionReason(t *testing.T) {
+	rev := "0b94a4d2bd0dcd1cd2e4e58d3a7e2d2f2de7d5e5b12a0b7c86bfa8c0c44bf9d5"
+	p := &database.ProtectionRule{PushAccessLevel: database.MasterAccess, DeleteAccessLevel: database.NoOneAccess}
+	for _, c := range []struct {
+		protection *database.ProtectionRule
+		level      database.AccessLevel
+		newRev     string
+		rejected   bool
+	}{
+		{nil, database.ReporterAccess, rev, false},
+		{p, database.DevAccess, rev, true},
+		{p, database.MasterAccess, rev, false},
+		{p, database.OwnerAccess, plumbing.ZERO_OID, true},
+	} {
+		cmd := &Command{ReferenceName: plumbing.NewBranchReferenceName("main"), OldRev: rev, NewRev: c.newRev, Protection: c.protection, AccessLevel: c.level}
+		require.Equal(t, c.rejected, len(cmd.ProtectionReason()) != 0, "%v", c)
+	}
+}
+
+type commitDB struct {
+	commits map[plumbing.Hash]*object.Commit
+}
+
+func (d *commitDB) Commit(ctx context.Context, oid plumbing.Hash) (*object.Commit, error) {
+	if cc, ok := d.commits[oid]; ok {
+		return cc, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (d *commitDB) Tag(ctx context.Context, oid plumbing.Hash) (*object.Tag, error) {
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func (d *commitDB) add(name string, when int64, parents ...string) {
+	cc := &object.Commit{Hash: testCommitHash(name), Committer: object.Signature{When: time.Unix(when, 0)}}
+	for _, p := range parents {
+		cc.Parents = append(cc.Parents, testCommitHash(p))
+	}
+	d.commits[cc.Hash] = cc
+}
+
+func testCommitHash(s string) plumbing.Hash {
+	return plumbing.NewHash(s + "0000000000000000000000000000000000000000000000000000000000000000"[len(s):])
+}
+
+func TestRangeCommits(t *testing.T) {
+	ctx := context.Background()
+	d := &commitDB{commits: make(map[plumbing.Hash]*object.Commit)}
+	// c1 <- c2 <- c3 <- c4 <- c5, c1 <- a1 <- c5 (merge)
+	d.add("c1", 10)
+	d.add("c2", 20, "c1")
+	d.add("c3", 30, "c2")
+	d.add("c4", 40, "c3")
+	d.add("a1", 15, "c1")
+	d.add("c5", 50, "c4", "a1")
+	names := func(commits []*object.Commit) []plumbing.Hash {
+		oids := make([]plumbing.Hash, 0, len(commits))
+		for _, cc := range commits {
+			oids = append(oids, cc.Hash)
+		}
+		return oids
+	}
+	commits, err := rangeCommits(ctx, d, testCommitHash("c2"), testCommitHash("c5"))
+	require.NoError(t, err)
+	// a1 already exists on the server but is new to the reference
+	require.Equal(t, []plumbing.Hash{testCommitHash("c5"), testCommitHash("c4"), testCommitHash("c3"), testCommitHash("a1")}, names(commits))
+	commits, err = rangeCommits(ctx, d, plumbing.ZeroHash, testCommitHash("c3"))
+	require.NoError(t, err)
+	require.Equal(t, []plumbing.Hash{testCommitHash("c3"), testCommitHash("c2"), testCommitHash("c1")}, names(commits))
+
+	// b1 <- b2 <- b3, b2 <- e1: e1 is older than its parent
+	d.add("b1", 40)
+	d.add("b2", 50, "b1")
+	d.add("b3", 100, "b2")
+	d.add("e1", 45, "b2")
+	commits, err = rangeCommits(ctx, d, testCommitHash("e1"), testCommitHash("b3"))
+	require.NoError(t, err)
+	require.Equal(t, []plumbing.Hash{testCommitHash("b3")}, names(commits))
+	_, err = rangeCommits(ctx, d, testCommitHash("ff"), testCommitHash("b3"))
+	require.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/plumbing/format/pktline"
@@ -19,6 +20,7 @@ import (
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/metrics"
 	"github.com/antgroup/hugescm/pkg/serve/odb"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -54,12 +56,33 @@ type Command struct {
 	Terminal      string                 // term
 	M             int
 	B             int
+	PushOptions   *PushOptions `json:"push_options,omitempty"` // validated push options, nil if there are none
+	// Protection: merged protection rules of the reference, nil if the reference is not protected
+	Protection  *database.ProtectionRule `json:"-"`
+	AccessLevel database.AccessLevel     `json:"-"` // access level of the user
+	// PathACL: path access control of the user, nil if all paths are writable
+	PathACL *PathACL `json:"-"`
+	// KeyID, Protocol and ClientIP: the source of the push recorded in the audit log
+	KeyID    int64  `json:"-"`
+	Protocol string `json:"-"`
+	ClientIP string `json:"-"`
+	// FastForwardOnly: non-fast-forward updates are rejected, set for bundle pushes without force, other pushes
+	// are checked by the client
+	FastForwardOnly bool `json:"-"`
+	force           bool // the new rev is not a descendant of the old rev
 }
 
 func (c *Command) W(message string) string {
 	return serve.T(c.Language, message)
 }
 
+func (c *Command) pushOptions() []string {
+	if c.PushOptions == nil {
+		return nil
+	}
+	return c.PushOptions.Options
+}
+
 func (c *Command) UpdateStats(s string) {
 	kv := strengthen.StrSplitSkipEmpty(s, ';', 2)
 	for _, k := range kv {
@@ -81,6 +104,46 @@ func (c *Command) UpdateStats(s string) {
 
 type reporter struct {
 	pktline.Encoder
+	// reason: why the push was rejected, reported to metrics
+	reason string
+	// message: the first message reported by ng, returned by APIs without the report stream
+	message string
+}
+
+const (
+	pushFailedDefaultBranch = "default_branch"
+	pushFailedDisconnected  = "disconnected"
+	pushFailedUnpack        = "unpack"
+	pushFailedIntegrity     = "integrity"
+	pushFailedProtected     = "protected"
+	pushFailedPathACL       = "path_acl"
+	pushFailedHook          = "hook"
+	pushFailedStorage       = "storage"
+	pushFailedLocked        = "locked"
+	pushFailedDatabase      = "database"
+	pushFailedInternal      = "internal"
+)
+
+// fail: record the reason of the rejection, the first reason wins.
+func (r *reporter) fail(reason string) {
+	if len(r.reason) == 0 {
+		r.reason = reason
+	}
+}
+
+// observePush: report the duration of the push, and the reason when it failed.
+func observePush(kind string, ro *reporter, start time.Time, err error) {
+	spent := time.Since(start).Seconds()
+	if err == nil && len(ro.reason) == 0 {
+		metrics.PushDuration.WithLabelValues(kind, "ok").Observe(spent)
+		return
+	}
+	reason := ro.reason
+	if len(reason) == 0 {
+		reason = pushFailedInternal
+	}
+	metrics.PushDuration.WithLabelValues(kind, "failed").Observe(spent)
+	metrics.PushFailures.WithLabelValues(reason).Inc()
 }
 
 func newReporter(w io.Writer) *reporter {
@@ -108,14 +171,15 @@ func (r *reporter) ok(cmd *Command, newRev string) error {
 func (r *reporter) ng(cmd *Command, format string, a ...any) error {
 	message := fmt.Sprintf(format, a...)
 	logrus.Errorf("[%s] %s", cmd.ReferenceName, message)
+	if len(r.message) == 0 {
+		r.message = message
+	}
 	return r.Encodef("ng %s %s", cmd.ReferenceName, message)
 }
 
 type QR struct {
 	*odb.QuarantineDB
-	seen      map[plumbing.Hash]bool
-	commits   []plumbing.Hash
-	forcePush bool
+	trees map[plumbing.Hash]bool // checked trees, the result does not depend on the command
 }
 
 func NewQR(o *odb.ODB, quarantineDir string) (*QR, error) {
@@ -123,7 +187,7 @@ func NewQR(o *odb.ODB, quarantineDir string) (*QR, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &QR{QuarantineDB: d, seen: make(map[plumbing.Hash]bool), forcePush: true}, nil
+	return &QR{QuarantineDB: d, trees: make(map[plumbing.Hash]bool)}, nil
 }
 
 func (r *QR) Close() error {
@@ -133,8 +197,16 @@ func (r *QR) Close() error {
 	return nil
 }
 
+// integrity: the integrity check of one command, commands of an atomic push are walked separately, otherwise a
+// commit reached by another command would hide the old-rev of this command and its new commits.
+type integrity struct {
+	seen      map[plumbing.Hash]bool
+	commits   []plumbing.Hash // new commits received by the push
+	forcePush bool            // old-rev is not reachable from new-rev
+}
+
 func (r *QR) checkTreeIntegrity(ctx context.Context, cmd *Command, rr *reporter, oid plumbing.Hash) error {
-	if r.seen[oid] {
+	if r.trees[oid] {
 		// checked
 		return nil
 	}
@@ -170,11 +242,11 @@ func (r *QR) checkTreeIntegrity(ctx context.Context, cmd *Command, rr *reporter,
 			return fmt.Errorf("unsupported object type %v", e.Type())
 		}
 	}
-	r.seen[oid] = true
+	r.trees[oid] = true
 	return nil
 }
 
-func (r *QR) checkCommitIntegrity(ctx context.Context, cmd *Command, rr *reporter, oid plumbing.Hash) error {
+func (r *QR) checkCommitIntegrity(ctx context.Context, cmd *Command, rr *reporter, ic *integrity, oid plumbing.Hash) error {
 	cc, isolated, err := r.ParseRev(ctx, oid)
 	if err != nil {
 		_ = rr.ng(cmd, "peeled object '%s' error: %v", oid, err)
@@ -182,10 +254,10 @@ func (r *QR) checkCommitIntegrity(ctx context.Context, cmd *Command, rr *reporte
 	}
 	_ = rr.rate("check '%s' integrity", oid)
 	if oid.String() == cmd.OldRev {
-		r.forcePush = false
+		ic.forcePush = false
 		return nil
 	}
-	if r.seen[oid] {
+	if ic.seen[oid] {
 		return nil
 	}
 	// The commit already exists on the server, so we don't need to continue with the integrity check.
@@ -193,136 +265,201 @@ func (r *QR) checkCommitIntegrity(ctx context.Context, cmd *Command, rr *reporte
 		if err := r.checkTreeIntegrity(ctx, cmd, rr, cc.Tree); err != nil {
 			return err
 		}
-		r.commits = append(r.commits, oid)
+		ic.commits = append(ic.commits, oid)
 	}
-	r.seen[oid] = true
+	ic.seen[oid] = true
 	for _, p := range cc.Parents {
-		if err := r.checkCommitIntegrity(ctx, cmd, rr, p); err != nil {
+		if err := r.checkCommitIntegrity(ctx, cmd, rr, ic, p); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *QR) checkIntegrity(ctx context.Context, cmd *Command, rr *reporter) error {
+func (r *QR) checkIntegrity(ctx context.Context, cmd *Command, rr *reporter) (*integrity, error) {
+	ic := &integrity{seen: make(map[plumbing.Hash]bool), forcePush: true}
 	if cmd.NewRev == plumbing.ZERO_OID {
-		return nil
+		return ic, nil
+	}
+	if err := r.checkCommitIntegrity(ctx, cmd, rr, ic, plumbing.NewHash(cmd.NewRev)); err != nil {
+		return nil, err
+	}
+	return ic, nil
+}
+
+// auditReferenceUpdate: record the reference update together with its push options.
+func auditReferenceUpdate(cmd *Command) {
+	logrus.Infof("reference updated [rid: %d uid: %d] %s %s -> %s push-options: %q", cmd.RID, cmd.UID, cmd.ReferenceName, cmd.OldRev, cmd.NewRev, cmd.pushOptions())
+}
+
+// databaseCommand: the subject and description of annotated tags are stored with the tag.
+func (r *repository) databaseCommand(ctx context.Context, cmd *Command) *database.Command {
+	change := &database.Command{
+		ReferenceName: cmd.ReferenceName,
+		NewRev:        cmd.NewRev,
+		OldRev:        cmd.OldRev,
+		RID:           cmd.RID,
+		UID:           cmd.UID,
+		PushOptions:   cmd.pushOptions(),
+		KeyID:         cmd.KeyID,
+		Protocol:      cmd.Protocol,
+		ClientIP:      cmd.ClientIP,
+		Force:         cmd.force,
+	}
+	if cmd.ReferenceName.IsTag() && cmd.NewRev != plumbing.ZERO_OID {
+		if to, err := r.odb.Tag(ctx, plumbing.NewHash(cmd.NewRev)); err == nil {
+			message, _ := to.Extract()
+			change.Subject, change.Description = messageSplit(message)
+		}
 	}
-	return r.checkCommitIntegrity(ctx, cmd, rr, plumbing.NewHash(cmd.NewRev))
+	return change
 }
 
-func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader, w io.Writer) error {
+// storeObjects: move received objects from the quarantine to the repository.
+func (r *repository) storeObjects(ctx context.Context, recvObjs *odb.Objects) error {
+	var g errgroup.Group
+	g.Go(func() error {
+		if err := r.odb.Batch(ctx, recvObjs.Blobs, 50); err != nil {
+			logrus.Errorf("batch upload blobs error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := r.odb.BatchObjects(ctx, recvObjs.Objects); err != nil {
+			logrus.Errorf("batch encode objects error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := r.odb.BatchTrees(ctx, recvObjs.Trees); err != nil {
+			logrus.Errorf("batch encode trees error: %v", err)
+			return err
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := r.odb.BatchCommits(ctx, recvObjs.Commits); err != nil {
+			logrus.Errorf("batch encode commits error: %v", err)
+			return err
+		}
+		return nil
+	})
+	return g.Wait()
+}
+
+func (r *repository) DoPush(ctx context.Context, cmd *Command, reader io.Reader, w io.Writer) (err error) {
 	ro := newReporter(w)
+	defer func(start time.Time) { observePush("push", ro, start, err) }(time.Now())
 	// remove branch or tag
 	if cmd.NewRev == plumbing.ZERO_OID {
 		if cmd.ReferenceName.IsBranch() && cmd.ReferenceName.BranchName() == r.defaultBranch {
+			ro.fail(pushFailedDefaultBranch)
 			_ = ro.ng(cmd, "\x1b[31merror\x1b[0m: %s%s", cmd.W("refusing to delete the current branch: "), cmd.ReferenceName)
 			return ErrReportStarted
 		}
-		newReference, err := r.mdb.DoReferenceUpdate(ctx, &database.Command{
-			ReferenceName: cmd.ReferenceName,
-			NewRev:        cmd.NewRev,
-			OldRev:        cmd.OldRev,
-			RID:           cmd.RID,
-			UID:           cmd.UID,
-		})
+		if failed, err := r.hooks.receiveHooks(ctx, nil, "", []*Command{cmd}, nil); err != nil {
+			ro.fail(pushFailedHook)
+			reportHookError(ro, failed, err)
+			return ErrReportStarted
+		}
+		newReference, err := r.mdb.DoReferenceUpdate(ctx, r.databaseCommand(ctx, cmd))
 		if database.IsErrAlreadyLocked(err) {
+			ro.fail(pushFailedLocked)
 			_ = ro.ng(cmd, cmd.W("reference is already locked: %s"), cmd.ReferenceName)
 			return ErrReportStarted
 		}
 		if err != nil {
+			ro.fail(pushFailedDatabase)
 			_ = ro.ng(cmd, cmd.W("update reference error: %v"), err)
 			return ErrReportStarted
 		}
 		_ = ro.ok(cmd, newReference.Hash)
+		auditReferenceUpdate(cmd)
+		r.hooks.postReceive([]*Command{cmd})
+		r.notifyPush(ctx, []*Command{cmd}, nil)
 		return nil
 	}
+	var newCommits []plumbing.Hash
 	recvObjs, err := r.odb.Unpack(ctx, reader, &odb.OStats{M: cmd.M, B: cmd.B}, func(ctx context.Context, quarantineDir string, o *odb.Objects) error {
 		if err := ro.EncodeString("unpack ok"); err != nil {
+			ro.fail(pushFailedDisconnected)
 			ro.close()
 			return ErrReportStarted
 		}
 		qr, err := NewQR(r.odb, quarantineDir)
 		if err != nil {
+			ro.fail(pushFailedIntegrity)
 			_ = ro.ng(cmd, cmd.W("check integrity error: %v"), err)
 			return err
 		}
 		defer qr.Close()
 
-		if err = qr.checkIntegrity(ctx, cmd, ro); err != nil {
+		ic, err := qr.checkIntegrity(ctx, cmd, ro)
+		if err != nil {
+			ro.fail(pushFailedIntegrity)
+			ro.close()
+			return ErrReportStarted
+		}
+		if err = qr.checkProtection(ctx, cmd, ro, ic); err != nil {
+			ro.fail(pushFailedProtected)
+			ro.close()
+			return ErrReportStarted
+		}
+		if err = qr.checkPathACL(ctx, cmd, ro, ic.commits); err != nil {
+			ro.fail(pushFailedPathACL)
 			ro.close()
 			return ErrReportStarted
 		}
-		if qr.forcePush && cmd.OldRev != plumbing.ZERO_OID {
+		if failed, err := r.hooks.receiveHooks(ctx, qr, quarantineDir, []*Command{cmd}, map[*Command][]plumbing.Hash{cmd: ic.commits}); err != nil {
+			ro.fail(pushFailedHook)
+			reportHookError(ro, failed, err)
+			ro.close()
+			return ErrReportStarted
+		}
+		if ic.forcePush && cmd.OldRev != plumbing.ZERO_OID {
+			cmd.force = true
 			logrus.Infof("Force push, oldRev %s --> newRev %s", cmd.OldRev, cmd.NewRev)
 		}
+		newCommits = ic.commits
 		return nil
 	})
 	if err != nil {
+		ro.fail(pushFailedUnpack)
 		return err
 	}
 	logrus.Infof("objects %d", len(recvObjs.Commits))
 	defer ro.close()
 	if err := r.odb.Reload(); err != nil {
+		ro.fail(pushFailedStorage)
 		_ = ro.ng(cmd, "reload odb error: %v", err)
 		return err
 	}
-	var g errgroup.Group
-	g.Go(func() error {
-		if err := r.odb.Batch(ctx, recvObjs.Blobs, 50); err != nil {
-			logrus.Errorf("batch upload blobs error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchObjects(ctx, recvObjs.Objects); err != nil {
-			logrus.Errorf("batch encode objects error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchTrees(ctx, recvObjs.Trees); err != nil {
-			logrus.Errorf("batch encode trees error: %v", err)
-			return err
-		}
-		return nil
-	})
-	g.Go(func() error {
-		if err := r.odb.BatchCommits(ctx, recvObjs.Commits); err != nil {
-			logrus.Errorf("batch encode commits error: %v", err)
-			return err
-		}
-		return nil
-	})
-	if err := g.Wait(); err != nil {
+	if err := r.storeObjects(ctx, recvObjs); err != nil {
+		ro.fail(pushFailedStorage)
 		_ = ro.ng(cmd, "store object error: %v", err)
 		return ErrReportStarted
 	}
 	_ = ro.status(cmd.W("objects verified")) //nolint:govet
-	change := &database.Command{
-		ReferenceName: cmd.ReferenceName,
-		NewRev:        cmd.NewRev,
-		OldRev:        cmd.OldRev,
-		RID:           cmd.RID,
-		UID:           cmd.UID,
-	}
-	if cmd.ReferenceName.IsTag() {
-		if to, err := r.odb.Tag(ctx, plumbing.NewHash(cmd.NewRev)); err == nil {
-			message, _ := to.Extract()
-			change.Subject, change.Description = messageSplit(message)
-		}
-	}
-	newReference, err := r.mdb.DoReferenceUpdate(ctx, change)
+	newReference, err := r.mdb.DoReferenceUpdate(ctx, r.databaseCommand(ctx, cmd))
 	if database.IsErrAlreadyLocked(err) {
+		ro.fail(pushFailedLocked)
 		_ = ro.ng(cmd, cmd.W("reference is already locked: %s"), cmd.ReferenceName)
 		return ErrReportStarted
 	}
 	if err != nil {
+		ro.fail(pushFailedDatabase)
 		_ = ro.ng(cmd, cmd.W("update reference error: %v"), err)
 		return ErrReportStarted
 	}
 	_ = ro.ok(cmd, newReference.Hash)
+	auditReferenceUpdate(cmd)
+	r.hooks.postReceive([]*Command{cmd})
+	r.notifyPush(ctx, []*Command{cmd}, map[*Command][]plumbing.Hash{cmd: newCommits})
+	if cmd.ReferenceName.IsBranch() {
+		// keep reachability bitmaps of branch tips up to date, fetches and pushes reuse them.
+		r.odb.UpdateBitmapIndex(plumbing.NewHash(newReference.Hash))
+	}
 	return nil
 }
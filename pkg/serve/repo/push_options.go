@@ -0,0 +1,183 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve"
+)
+
+// built-in push options, always allowed
+const (
+	PushOptionCISkip             = "ci.skip"                     // skip CI pipelines of the push
+	PushOptionMergeRequestCreate = "merge_request.create"        // create a merge request from the pushed branch
+	PushOptionMergeRequestTarget = "merge_request.target"        // merge_request.target=<branch>, defaults to the default branch
+	PushOptionMergeRequestTitle  = "merge_request.title"         // merge_request.title=<title>
+	PushOptionNotify             = "notify"                      // notify=<user,user...>, can be repeated
+	DefaultMaxPushOptions        = 32                            // max count of push options
+	DefaultMaxPushOptionSize     = 1024                          // max bytes of one push option
+	maxReadPushOptions           = 256                           // hard limit of push options sent by the client
+	pushOptionEnvPrefix          = "ZETA_PUSH_OPTION_"           // env of hooks, like git GIT_PUSH_OPTION_<i>
+	pushOptionCountEnv           = pushOptionEnvPrefix + "COUNT" // count of push options
+)
+
+type MergeRequest struct {
+	Target string `json:"target,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// PushOptions: validated push options of a reference update, see 'zeta push --push-option'.
+type PushOptions struct {
+	Options      []string      `json:"options,omitempty"` // options in the order they were sent
+	CISkip       bool          `json:"ci_skip,omitempty"`
+	MergeRequest *MergeRequest `json:"merge_request,omitempty"`
+	Notify       []string      `json:"notify,omitempty"`
+	// Custom: options allowed by the server config, key to value, options without value have an empty value
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
+type ErrPushOption struct {
+	Option string
+	Reason string
+}
+
+func (e *ErrPushOption) Error() string {
+	return fmt.Sprintf("push option '%s' %s", e.Option, e.Reason)
+}
+
+func IsErrPushOption(err error) bool {
+	_, ok := err.(*ErrPushOption)
+	return ok
+}
+
+func isAllowedPushOption(key string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if ok, err := path.Match(pattern, key); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func splitNotify(value string) []string {
+	users := make([]string, 0, 2)
+	for _, u := range strings.Split(value, ",") {
+		if u = strings.TrimSpace(u); len(u) != 0 {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// ParsePushOptions: validate push options against the built-in options and the server allowlist,
+// returns nil if there are no push options.
+func ParsePushOptions(options []string, cfg *serve.PushOptions) (*PushOptions, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	maxCount := DefaultMaxPushOptions
+	var allowed []string
+	if cfg != nil {
+		if cfg.MaxCount > 0 {
+			maxCount = cfg.MaxCount
+		}
+		allowed = cfg.Allowed
+	}
+	if len(options) > maxCount {
+		return nil, fmt.Errorf("too many push options: %d, limit %d", len(options), maxCount)
+	}
+	o := &PushOptions{Options: options}
+	var mergeRequest MergeRequest
+	var hasMergeRequestOptions bool
+	for _, option := range options {
+		if len(option) > DefaultMaxPushOptionSize {
+			return nil, &ErrPushOption{Option: option[:32] + "...", Reason: "is too long"}
+		}
+		if strings.ContainsAny(option, "\x00\r\n") {
+			return nil, &ErrPushOption{Option: strconv.Quote(option), Reason: "contains control characters"}
+		}
+		key, value, hasValue := strings.Cut(option, "=")
+		switch key {
+		case PushOptionCISkip:
+			if hasValue {
+				return nil, &ErrPushOption{Option: option, Reason: "does not accept a value"}
+			}
+			o.CISkip = true
+		case PushOptionMergeRequestCreate:
+			if hasValue {
+				return nil, &ErrPushOption{Option: option, Reason: "does not accept a value"}
+			}
+			o.MergeRequest = &mergeRequest
+		case PushOptionMergeRequestTarget:
+			if !plumbing.ValidateBranchName([]byte(value)) {
+				return nil, &ErrPushOption{Option: option, Reason: "requires a valid target branch"}
+			}
+			mergeRequest.Target = value
+			hasMergeRequestOptions = true
+		case PushOptionMergeRequestTitle:
+			if len(value) == 0 {
+				return nil, &ErrPushOption{Option: option, Reason: "requires a title"}
+			}
+			mergeRequest.Title = value
+			hasMergeRequestOptions = true
+		case PushOptionNotify:
+			users := splitNotify(value)
+			if len(users) == 0 {
+				return nil, &ErrPushOption{Option: option, Reason: "requires a list of users"}
+			}
+			o.Notify = append(o.Notify, users...)
+		default:
+			if len(key) == 0 || !isAllowedPushOption(key, allowed) {
+				return nil, &ErrPushOption{Option: option, Reason: "is not allowed by the server"}
+			}
+			if o.Custom == nil {
+				o.Custom = make(map[string]string)
+			}
+			o.Custom[key] = value
+		}
+	}
+	if hasMergeRequestOptions && o.MergeRequest == nil {
+		return nil, &ErrPushOption{Option: PushOptionMergeRequestTarget, Reason: "requires '" + PushOptionMergeRequestCreate + "'"}
+	}
+	return o, nil
+}
+
+// Environ: push options for hooks, ZETA_PUSH_OPTION_COUNT and ZETA_PUSH_OPTION_<i> like git.
+func (o *PushOptions) Environ() []string {
+	if o == nil || len(o.Options) == 0 {
+		return nil
+	}
+	environ := make([]string, 0, len(o.Options)+1)
+	environ = append(environ, pushOptionCountEnv+"="+strconv.Itoa(len(o.Options)))
+	for i, option := range o.Options {
+		environ = append(environ, pushOptionEnvPrefix+strconv.Itoa(i)+"="+option)
+	}
+	return environ
+}
+
+// ReadPushOptions: read push options sent as count and indexed values, like HTTP headers 'X-Zeta-Push-Option-Count'
+// and 'X-Zeta-Push-Option-<i>', or SSH env 'ZETA_PUSH_OPTION_COUNT' and 'ZETA_PUSH_OPTION_<i>'.
+func ReadPushOptions(getter func(string) string, countKey, prefix string) ([]string, error) {
+	s := getter(countKey)
+	if len(s) == 0 {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(s)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("bad push option count '%s'", s)
+	}
+	if count > maxReadPushOptions {
+		return nil, fmt.Errorf("too many push options: %d", count)
+	}
+	options := make([]string, 0, count)
+	for i := range count {
+		options = append(options, getter(prefix+strconv.Itoa(i)))
+	}
+	return options, nil
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePushOptions(t *testing.T) {
+	o, err := ParsePushOptions(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, o)
+
+	cfg := &serve.PushOptions{Allowed: []string{"deploy.*"}}
+	o, err = ParsePushOptions([]string{"ci.skip", "merge_request.create", "merge_request.target=release", "notify=alice, bob", "notify=carol", "deploy.env=staging"}, cfg)
+	require.NoError(t, err)
+	require.True(t, o.CISkip)
+	require.Equal(t, &MergeRequest{Target: "release"}, o.MergeRequest)
+	require.Equal(t, []string{"alice", "bob", "carol"}, o.Notify)
+	require.Equal(t, map[string]string{"deploy.env": "staging"}, o.Custom)
+	require.Equal(t, []string{"ZETA_PUSH_OPTION_COUNT=6", "ZETA_PUSH_OPTION_0=ci.skip"}, o.Environ()[:2])
+
+	for _, bad := range [][]string{
+		{"ci.skip=1"},
+		{"merge_request.target=release"},
+		{"merge_request.create", "merge_request.target=.."},
+		{"notify="},
+		{"deploy.env=staging"},
+		{"=value"},
+		{"ci.skip\nfoo"},
+	} {
+		_, err = ParsePushOptions(bad, nil)
+		require.True(t, IsErrPushOption(err), "%q", bad)
+	}
+	_, err = ParsePushOptions(make([]string, 3), &serve.PushOptions{MaxCount: 2})
+	require.Error(t, err)
+}
+
+func TestReadPushOptions(t *testing.T) {
+	env := map[string]string{"ZETA_PUSH_OPTION_COUNT": "2", "ZETA_PUSH_OPTION_0": "ci.skip", "ZETA_PUSH_OPTION_1": "notify=alice"}
+	getter := func(k string) string { return env[k] }
+	options, err := ReadPushOptions(getter, "ZETA_PUSH_OPTION_COUNT", "ZETA_PUSH_OPTION_")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ci.skip", "notify=alice"}, options)
+	env["ZETA_PUSH_OPTION_COUNT"] = "-1"
+	_, err = ReadPushOptions(getter, "ZETA_PUSH_OPTION_COUNT", "ZETA_PUSH_OPTION_")
+	require.Error(t, err)
+}
@@ -19,12 +19,22 @@ import (
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/metrics"
 	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/sirupsen/logrus"
 )
 
 type Repositories interface {
 	Open(ctx context.Context, rid int64, compressionAlgo, defaultBranch string) (Repository, error)
 	New(ctx context.Context, newRepo *database.Repository, u *database.User, empty bool) (*database.Repository, error)
+	// Delete: remove the repository from the database and the disk, large objects are kept in the bucket
+	Delete(ctx context.Context, rid int64) error
+	// Bucket: bucket of large objects, *oss.LocalBucket when the local bucket is configured
+	Bucket() oss.Bucket
+	// Capabilities: capabilities of this server advertised in reference discovery
+	Capabilities() []string
+	Close() error
 }
 
 var (
@@ -36,28 +46,79 @@ type repositories struct {
 	cdb    odb.CacheDB
 	mdb    database.DB
 	bucket oss.Bucket
+	// sharedBucket: the bucket not observed, the local bucket serves its shared URLs
+	sharedBucket oss.Bucket
+	hooks        *hookRunner
+	// webhooks: nil when deliveries are disabled on this server
+	webhooks *webhookDispatcher
+	uploads  *uploadSweeper
 }
 
-func NewRepositories(root string, ossConfig *serve.OSS, cacheConfig *serve.Cache, mdb database.DB) (Repositories, error) {
+func NewRepositories(root string, ossConfig *serve.OSS, cacheConfig *serve.Cache, hooksConfig *serve.Hooks, webhooksConfig *serve.Webhooks, mdb database.DB) (Repositories, error) {
 	if err := os.MkdirAll(root, 0755); err != nil {
 		return nil, err
 	}
 
-	bucket, err := oss.NewBucket(&oss.NewBucketOptions{
+	bucket, err := newBucket(ossConfig)
+	if err != nil {
+		return nil, err
+	}
+	cdb, err := odb.NewCacheDB(cacheConfig.NumCounters, cacheConfig.MaxCost, cacheConfig.BufferItems)
+	if err != nil {
+		return nil, err
+	}
+	metrics.SetCacheStats(cdb.Stats())
+	metrics.SetDB(mdb.Database().DB)
+	r := &repositories{root: root, cdb: cdb, mdb: mdb, bucket: metrics.ObserveBucket(bucket), sharedBucket: bucket, hooks: newHookRunner(hooksConfig)}
+	if webhooksConfig == nil || !webhooksConfig.Disabled {
+		r.webhooks = newWebhookDispatcher(mdb, webhooksConfig)
+		r.webhooks.start()
+	}
+	r.uploads = newUploadSweeper(mdb.Database(), r.bucket, ossConfig.UploadExpiration.Duration)
+	r.uploads.start()
+	return r, nil
+}
+
+func newBucket(ossConfig *serve.OSS) (oss.Bucket, error) {
+	if len(ossConfig.Local) != 0 {
+		return oss.NewLocalBucket(&oss.NewLocalBucketOptions{
+			Root:           ossConfig.Local,
+			SharedEndpoint: ossConfig.SharedEndpoint,
+			Secret:         ossConfig.AccessKeySecret,
+		})
+	}
+	return oss.NewBucket(&oss.NewBucketOptions{
 		Endpoint:        ossConfig.Endpoint,
 		SharedEndpoint:  ossConfig.SharedEndpoint,
 		AccessKeyID:     ossConfig.AccessKeyID,
 		AccessKeySecret: ossConfig.AccessKeySecret,
 		Bucket:          ossConfig.Bucket,
 	})
-	if err != nil {
-		return nil, err
+}
+
+func (r *repositories) Bucket() oss.Bucket {
+	return r.sharedBucket
+}
+
+func (r *repositories) Capabilities() []string {
+	return protocol.Capabilities(r.hooks != nil)
+}
+
+// Close: stop sending webhook deliveries and aborting expired uploads, deliveries being sent are finished.
+func (r *repositories) Close() error {
+	r.webhooks.stop()
+	r.uploads.stop()
+	return nil
+}
+
+func (r *repositories) Delete(ctx context.Context, rid int64) error {
+	if err := r.mdb.DeleteRepository(ctx, rid); err != nil {
+		return err
 	}
-	cdb, err := odb.NewCacheDB(cacheConfig.NumCounters, cacheConfig.MaxCost, cacheConfig.BufferItems)
-	if err != nil {
-		return nil, err
+	if err := os.RemoveAll(r.zetaJoin(rid)); err != nil {
+		logrus.Errorf("remove repository %d error: %v", rid, err)
 	}
-	return &repositories{root: root, cdb: cdb, mdb: mdb, bucket: bucket}, nil
+	return nil
 }
 
 func (r *repositories) zetaJoin(rid int64) string {
@@ -70,7 +131,7 @@ func (r *repositories) Open(ctx context.Context, rid int64, compressionAlgo, def
 	if err != nil {
 		return nil, err
 	}
-	return &repository{odb: o, mdb: r.mdb, rid: rid, defaultBranch: defaultBranch}, nil
+	return &repository{odb: o, mdb: r.mdb, rid: rid, defaultBranch: defaultBranch, hooks: r.hooks}, nil
 }
 
 func (r *repositories) New(ctx context.Context, newRepo *database.Repository, u *database.User, empty bool) (*database.Repository, error) {
@@ -86,16 +147,20 @@ func (r *repositories) New(ctx context.Context, newRepo *database.Repository, u
 	}); err != nil {
 		return nil, err
 	}
-	if empty {
-		return repo, nil
-	}
-	rr, err := r.Open(ctx, repo.ID, repo.CompressionAlgo, repo.DefaultBranch)
-	if err != nil {
-		return nil, err
+	if !empty {
+		rr, err := r.Open(ctx, repo.ID, repo.CompressionAlgo, repo.DefaultBranch)
+		if err != nil {
+			return nil, err
+		}
+		defer rr.Close()
+		if err := rr.Initialize(ctx, u, repo.DefaultBranch); err != nil {
+			return nil, err
+		}
 	}
-	defer rr.Close()
-	if err := rr.Initialize(ctx, u, repo.DefaultBranch); err != nil {
-		return nil, err
+	if ns, err := r.mdb.FindNamespaceByID(ctx, newRepo.NamespaceID); err == nil {
+		r.notifyCreated(ctx, ns, repo, u)
+	} else {
+		logrus.Errorf("webhooks: find namespace %d error: %v", newRepo.NamespaceID, err)
 	}
 	return repo, nil
 }
@@ -103,8 +168,12 @@ func (r *repositories) New(ctx context.Context, newRepo *database.Repository, u
 type Repository interface {
 	Initialize(ctx context.Context, u *database.User, initBranch string) error
 	LsTag(ctx context.Context, tagName string) (string, string, error)
+	ListReferences(ctx context.Context, prefix, cursor string, limit int) ([]*protocol.ReferenceEntry, string, error)
 	ParseRev(ctx context.Context, rev string) (*RevObjects, error)
 	DoPush(ctx context.Context, cmd *Command, reader io.Reader, w io.Writer) error
+	DoAtomicPush(ctx context.Context, cmds []*Command, reader io.Reader, w io.Writer) error
+	DoRestore(ctx context.Context, cmd *Command) error
+	HiddenObjects(ctx context.Context, acl *PathACL) (*HiddenObjects, error)
 	ODB() odb.DB
 	Close() error
 }
@@ -114,6 +183,7 @@ type repository struct {
 	odb           *odb.ODB
 	rid           int64
 	defaultBranch string
+	hooks         *hookRunner
 }
 
 func (r *repository) Close() error {
@@ -129,12 +199,44 @@ func (r *repository) LsTag(ctx context.Context, tagName string) (string, string,
 		return "", "", err
 	}
 	oid := plumbing.NewHash(tag.Hash)
-	if to, err := r.odb.Tag(ctx, oid); err == nil {
-		return tag.Hash, to.Object.String(), nil
+	if peeled := peelTag(ctx, r.odb, oid); peeled != oid {
+		return tag.Hash, peeled.String(), nil
 	}
 	return tag.Hash, "", nil
 }
 
+// peelTag: annotated tags may point to other tags, peel until a non-tag object.
+func peelTag(ctx context.Context, o odb.DB, oid plumbing.Hash) plumbing.Hash {
+	peeled := oid
+	for {
+		to, err := o.Tag(ctx, peeled)
+		if err != nil {
+			return peeled
+		}
+		peeled = to.Object
+	}
+}
+
+// ListReferences: list branches and tags ordered by name, annotated tags are peeled.
+func (r *repository) ListReferences(ctx context.Context, prefix, cursor string, limit int) ([]*protocol.ReferenceEntry, string, error) {
+	refs, next, err := r.mdb.ListReferences(ctx, r.rid, prefix, cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := make([]*protocol.ReferenceEntry, 0, len(refs))
+	for _, ref := range refs {
+		e := &protocol.ReferenceEntry{Name: string(ref.Name), Hash: ref.Hash}
+		if ref.Name.IsTag() {
+			oid := plumbing.NewHash(ref.Hash)
+			if peeled := peelTag(ctx, r.odb, oid); peeled != oid {
+				e.Peeled = peeled.String()
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, next, nil
+}
+
 func (r *repository) ODB() odb.DB {
 	return r.odb
 }
@@ -229,11 +331,13 @@ func (r *repository) Initialize(ctx context.Context, u *database.User, initBranc
 		return err
 	}
 	// create default branch mainline
-	if _, err := r.mdb.DoBranchUpdate(ctx, &database.Command{
+	if _, err := r.mdb.DoReferenceUpdate(ctx, &database.Command{
 		ReferenceName: plumbing.NewBranchReferenceName(initBranch),
 		OldRev:        plumbing.ZERO_OID,
 		NewRev:        commitOID.String(),
 		RID:           r.rid,
+		UID:           u.ID,
+		Protocol:      "api",
 	}); err != nil {
 		return err
 	}
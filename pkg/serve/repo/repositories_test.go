@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/stretchr/testify/require"
+)
+
+type tagDB struct {
+	odb.DB
+	tags map[plumbing.Hash]*object.Tag
+}
+
+func (d *tagDB) Tag(ctx context.Context, oid plumbing.Hash) (*object.Tag, error) {
+	if t, ok := d.tags[oid]; ok {
+		return t, nil
+	}
+	return nil, plumbing.NoSuchObject(oid)
+}
+
+func TestPeelTag(t *testing.T) {
+	hash := func(s string) plumbing.Hash {
+		return plumbing.NewHash(s + "0000000000000000000000000000000000000000000000000000000000000000"[len(s):])
+	}
+	// t2 -> t1 -> c1
+	d := &tagDB{tags: map[plumbing.Hash]*object.Tag{
+		hash("a1"): {Hash: hash("a1"), Object: hash("c1"), ObjectType: object.CommitObject},
+		hash("a2"): {Hash: hash("a2"), Object: hash("a1"), ObjectType: object.TagObject},
+	}}
+	ctx := context.Background()
+	require.Equal(t, hash("c1"), peelTag(ctx, d, hash("a2")))
+	require.Equal(t, hash("c1"), peelTag(ctx, d, hash("a1")))
+	require.Equal(t, hash("c1"), peelTag(ctx, d, hash("c1")))
+}
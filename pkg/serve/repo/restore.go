@@ -0,0 +1,84 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"io"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+)
+
+// ErrRejected: the reference update is rejected by the checks of the push, the reason is the message reported to
+// the user.
+type ErrRejected struct {
+	Reference plumbing.ReferenceName
+	Reason    string
+}
+
+func (e *ErrRejected) Error() string {
+	return e.Reason
+}
+
+func IsErrRejected(err error) bool {
+	_, ok := err.(*ErrRejected)
+	return ok
+}
+
+// DoRestore: point the reference to commits which already exist in the repository, eg: restore from the ref log.
+// The update is checked like a push without objects: the force push and commit rules of the protection, the path
+// ACL of the commits brought back and the receive hooks.
+func (r *repository) DoRestore(ctx context.Context, cmd *Command) error {
+	ro := newReporter(io.Discard)
+	if err := r.odb.Validate(ctx, func(ctx context.Context, quarantineDir string, _ *odb.Objects) error {
+		qr, err := NewQR(r.odb, quarantineDir)
+		if err != nil {
+			return err
+		}
+		defer qr.Close()
+		ic, err := qr.checkIntegrity(ctx, cmd, ro)
+		if err != nil {
+			return err
+		}
+		if err := qr.checkProtection(ctx, cmd, ro, ic); err != nil {
+			return err
+		}
+		var commits []plumbing.Hash
+		if cmd.PathACL != nil {
+			cs, err := rangeCommits(ctx, qr, plumbing.NewHash(cmd.OldRev), plumbing.NewHash(cmd.NewRev))
+			if err != nil {
+				return err
+			}
+			for _, cc := range cs {
+				commits = append(commits, cc.Hash)
+			}
+		}
+		if err := qr.checkPathACL(ctx, cmd, ro, commits); err != nil {
+			return err
+		}
+		if failed, err := r.hooks.receiveHooks(ctx, qr, quarantineDir, []*Command{cmd}, map[*Command][]plumbing.Hash{cmd: commits}); err != nil {
+			reportHookError(ro, failed, err)
+			return err
+		}
+		cmd.force = ic.forcePush && cmd.OldRev != plumbing.ZERO_OID
+		return nil
+	}); err != nil {
+		if len(ro.message) != 0 {
+			return &ErrRejected{Reference: cmd.ReferenceName, Reason: ro.message}
+		}
+		return err
+	}
+	newReference, err := r.mdb.DoReferenceUpdate(ctx, r.databaseCommand(ctx, cmd))
+	if err != nil {
+		return err
+	}
+	auditReferenceUpdate(cmd)
+	r.hooks.postReceive([]*Command{cmd})
+	r.notifyPush(ctx, []*Command{cmd}, nil)
+	if cmd.ReferenceName.IsBranch() {
+		r.odb.UpdateBitmapIndex(plumbing.NewHash(newReference.Hash))
+	}
+	return nil
+}
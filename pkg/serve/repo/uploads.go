@@ -0,0 +1,71 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/oss"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUploadExpiration = 7 * 24 * time.Hour
+	uploadSweepInterval     = time.Hour
+)
+
+// uploadSweeper: abort resumable uploads which are not finished before the expiration, every server sweeps, aborting
+// a session that another server has aborted succeeds.
+type uploadSweeper struct {
+	conn       *database.Conn
+	bucket     oss.Bucket
+	expiration time.Duration
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func newUploadSweeper(conn *database.Conn, bucket oss.Bucket, expiration time.Duration) *uploadSweeper {
+	if expiration <= 0 {
+		expiration = defaultUploadExpiration
+	}
+	return &uploadSweeper{conn: conn, bucket: bucket, expiration: expiration}
+}
+
+func (s *uploadSweeper) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+}
+
+func (s *uploadSweeper) stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+func (s *uploadSweeper) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		aborted, err := odb.AbortExpiredUploads(ctx, s.conn, s.bucket, time.Now().Add(-s.expiration))
+		if err != nil {
+			logrus.Errorf("uploads: abort expired uploads error: %v", err)
+		}
+		if aborted != 0 {
+			logrus.Infof("uploads: %d expired uploads aborted", aborted)
+		}
+	}
+}
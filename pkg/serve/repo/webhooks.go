@@ -0,0 +1,349 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package repo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EventPush             = "push"
+	EventBranchCreate     = "branch_create"
+	EventBranchDelete     = "branch_delete"
+	EventTagCreate        = "tag_create"
+	EventTagDelete        = "tag_delete"
+	EventRepositoryCreate = "repository_create"
+)
+
+const (
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookInterval    = 5 * time.Second
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookWorkers     = 4
+	// webhookBackoff: delay before the second attempt, doubled for each further attempt up to maxWebhookBackoff
+	webhookBackoff    = 10 * time.Second
+	maxWebhookBackoff = time.Hour
+	// maxWebhookCommits: commit summaries of each reference in the payload
+	maxWebhookCommits = 20
+	// maxWebhookError: length of last_error kept in the delivery log
+	maxWebhookError = 1024
+)
+
+var (
+	WebhookEvents = []string{EventPush, EventBranchCreate, EventBranchDelete, EventTagCreate, EventTagDelete, EventRepositoryCreate}
+)
+
+// WebhookRepository: repository of the event
+type WebhookRepository struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Path          string `json:"path"` // namespace/repo
+	DefaultBranch string `json:"default_branch"`
+}
+
+// WebhookCommit: summary of a commit pushed to the reference
+type WebhookCommit struct {
+	Hash    string           `json:"hash"`
+	Summary string           `json:"summary"`
+	Author  object.Signature `json:"author"`
+}
+
+// WebhookReference: reference update and its new commits, newest first
+type WebhookReference struct {
+	ReferenceName plumbing.ReferenceName `json:"reference_name"`
+	OldRev        string                 `json:"old_rev"`
+	NewRev        string                 `json:"new_rev"`
+	Commits       []*WebhookCommit       `json:"commits,omitempty"`
+	Truncated     bool                   `json:"truncated,omitempty"`
+}
+
+// WebhookPayload: JSON body of deliveries, signed with the secret of the webhook in the X-Zeta-Signature-256 header.
+type WebhookPayload struct {
+	Event       string              `json:"event"`
+	Repository  *WebhookRepository  `json:"repository"`
+	UID         int64               `json:"uid,omitempty"`
+	References  []*WebhookReference `json:"references,omitempty"`
+	PushOptions []string            `json:"push_options,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// SignWebhookPayload: value of X-Zeta-Signature-256, 'sha256=' followed by the hex encoded HMAC-SHA256 of the body.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// referenceEvent: create or delete event of the reference, empty for updates.
+func referenceEvent(cmd *Command) string {
+	switch {
+	case cmd.OldRev == plumbing.ZERO_OID && cmd.ReferenceName.IsBranch():
+		return EventBranchCreate
+	case cmd.NewRev == plumbing.ZERO_OID && cmd.ReferenceName.IsBranch():
+		return EventBranchDelete
+	case cmd.OldRev == plumbing.ZERO_OID && cmd.ReferenceName.IsTag():
+		return EventTagCreate
+	case cmd.NewRev == plumbing.ZERO_OID && cmd.ReferenceName.IsTag():
+		return EventTagDelete
+	}
+	return ""
+}
+
+func enqueueWebhooks(ctx context.Context, mdb database.DB, rid int64, payloads []*WebhookPayload) error {
+	webhooks, err := mdb.MatchWebhooks(ctx, rid)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+	deliveries := make([]*database.WebhookDelivery, 0, len(webhooks)*len(payloads))
+	for _, p := range payloads {
+		var body []byte
+		for _, w := range webhooks {
+			if !w.Subscribed(p.Event) {
+				continue
+			}
+			if p.Repository == nil {
+				ns, repo, err := mdb.FindRepositoryByID(ctx, int(rid))
+				if err != nil {
+					return err
+				}
+				p.Repository = &WebhookRepository{ID: repo.ID, Name: repo.Name, Path: ns.Path + "/" + repo.Path, DefaultBranch: repo.DefaultBranch}
+			}
+			if body == nil {
+				if body, err = json.Marshal(p); err != nil {
+					return err
+				}
+			}
+			deliveries = append(deliveries, &database.WebhookDelivery{WebhookID: w.ID, RID: rid, Event: p.Event, Payload: string(body)})
+		}
+	}
+	return mdb.NewWebhookDeliveries(ctx, deliveries)
+}
+
+// notifyPush: queue webhook deliveries of the push, commits are the new commits of each command.
+func (r *repository) notifyPush(ctx context.Context, cmds []*Command, commits map[*Command][]plumbing.Hash) {
+	// the client may disconnect once the references are updated
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	push := &WebhookPayload{Event: EventPush, UID: cmds[0].UID, PushOptions: cmds[0].pushOptions(), CreatedAt: now}
+	payloads := []*WebhookPayload{push}
+	for _, cmd := range cmds {
+		ref := &WebhookReference{ReferenceName: cmd.ReferenceName, OldRev: cmd.OldRev, NewRev: cmd.NewRev}
+		ref.Commits, ref.Truncated = r.webhookCommits(ctx, commits[cmd])
+		push.References = append(push.References, ref)
+		if event := referenceEvent(cmd); len(event) != 0 {
+			payloads = append(payloads, &WebhookPayload{Event: event, UID: cmd.UID, References: []*WebhookReference{ref}, CreatedAt: now})
+		}
+	}
+	if err := enqueueWebhooks(ctx, r.mdb, r.rid, payloads); err != nil {
+		logrus.Errorf("webhooks: enqueue deliveries of repo %d error: %v", r.rid, err)
+	}
+}
+
+func (r *repository) webhookCommits(ctx context.Context, oids []plumbing.Hash) ([]*WebhookCommit, bool) {
+	commits := make([]*WebhookCommit, 0, min(len(oids), maxWebhookCommits))
+	for _, oid := range oids {
+		if len(commits) == maxWebhookCommits {
+			return commits, true
+		}
+		cc, err := r.odb.Commit(ctx, oid)
+		if err != nil {
+			logrus.Errorf("webhooks: read commit %s error: %v", oid, err)
+			continue
+		}
+		commits = append(commits, &WebhookCommit{Hash: oid.String(), Summary: cc.Subject(), Author: cc.Author})
+	}
+	return commits, false
+}
+
+// notifyCreated: queue webhook deliveries of the new repository, only namespace webhooks can match.
+func (r *repositories) notifyCreated(ctx context.Context, ns *database.Namespace, repo *database.Repository, u *database.User) {
+	p := &WebhookPayload{
+		Event:      EventRepositoryCreate,
+		Repository: &WebhookRepository{ID: repo.ID, Name: repo.Name, Path: ns.Path + "/" + repo.Path, DefaultBranch: repo.DefaultBranch},
+		UID:        u.ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := enqueueWebhooks(context.WithoutCancel(ctx), r.mdb, repo.ID, []*WebhookPayload{p}); err != nil {
+		logrus.Errorf("webhooks: enqueue deliveries of repo %d error: %v", repo.ID, err)
+	}
+}
+
+// webhookDispatcher: sends queued deliveries, retries failed attempts with exponential backoff.
+type webhookDispatcher struct {
+	mdb         database.DB
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	workers     int
+	lease       time.Duration
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+func newWebhookDispatcher(mdb database.DB, cfg *serve.Webhooks) *webhookDispatcher {
+	if cfg == nil {
+		cfg = &serve.Webhooks{}
+	}
+	d := &webhookDispatcher{
+		mdb:         mdb,
+		interval:    cfg.Interval.Duration,
+		maxAttempts: cfg.MaxAttempts,
+		workers:     cfg.Workers,
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	if d.interval <= 0 {
+		d.interval = defaultWebhookInterval
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultWebhookMaxAttempts
+	}
+	if d.workers <= 0 {
+		d.workers = defaultWebhookWorkers
+	}
+	d.client = &http.Client{Timeout: timeout}
+	// deliveries claimed by a dispatcher which stopped are retried when the lease expires
+	d.lease = timeout + time.Minute
+	return d
+}
+
+func (d *webhookDispatcher) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go d.run(ctx)
+}
+
+func (d *webhookDispatcher) stop() {
+	if d == nil || d.cancel == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// drain the queue before waiting for the next tick
+		for ctx.Err() == nil {
+			deliveries, err := d.mdb.ClaimWebhookDeliveries(ctx, d.workers*4, d.lease)
+			if err != nil {
+				logrus.Errorf("webhooks: claim deliveries error: %v", err)
+				break
+			}
+			if len(deliveries) == 0 {
+				break
+			}
+			d.dispatch(ctx, deliveries)
+		}
+	}
+}
+
+func (d *webhookDispatcher) dispatch(ctx context.Context, deliveries []*database.WebhookDelivery) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, d.workers)
+	for _, w := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.attempt(ctx, w)
+			// record the result even if the dispatcher is stopping
+			if err := d.mdb.UpdateWebhookDelivery(context.WithoutCancel(ctx), w); err != nil {
+				logrus.Errorf("webhooks: update delivery %d error: %v", w.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func webhookBackoffAt(attempts int) time.Duration {
+	backoff := webhookBackoff
+	for i := 1; i < attempts && backoff < maxWebhookBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxWebhookBackoff)
+}
+
+// attempt: send the delivery once and update its status, attempts and the time of the next attempt.
+func (d *webhookDispatcher) attempt(ctx context.Context, w *database.WebhookDelivery) {
+	w.Attempts++
+	w.ResponseCode = 0
+	w.LastError = ""
+	err := d.send(ctx, w)
+	now := time.Now()
+	if err == nil {
+		w.Status = database.DeliverySucceeded
+		w.DeliveredAt = &now
+		return
+	}
+	if w.LastError = err.Error(); len(w.LastError) > maxWebhookError {
+		w.LastError = w.LastError[:maxWebhookError]
+	}
+	if w.Attempts >= d.maxAttempts {
+		w.Status = database.DeliveryFailed
+		logrus.Warnf("webhooks: delivery %d to %s failed after %d attempts: %v", w.ID, w.URL, w.Attempts, err)
+		return
+	}
+	w.Status = database.DeliveryPending
+	w.NextAttemptAt = now.Add(webhookBackoffAt(w.Attempts))
+}
+
+func (d *webhookDispatcher) send(ctx context.Context, w *database.WebhookDelivery) error {
+	body := []byte(w.Payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Zeta-Webhook")
+	req.Header.Set("X-Zeta-Event", w.Event)
+	req.Header.Set("X-Zeta-Delivery", strconv.FormatInt(w.ID, 10))
+	if len(w.Secret) != 0 {
+		req.Header.Set("X-Zeta-Signature-256", SignWebhookPayload(w.Secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	w.ResponseCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHookOutput))
+		return nil
+	}
+	var out hookOutput
+	_, _ = io.Copy(&out, resp.Body)
+	return fmt.Errorf("status %d: %s", resp.StatusCode, out.message())
+}
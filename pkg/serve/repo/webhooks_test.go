@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/serve"
+	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceEvent(t *testing.T) {
+	rev := "0b94a4d2bd0dcd1cd2e4e58d3a7e2d2f2de7d5e5b12a0b7c86bfa8c0c44bf9d5"
+	for _, c := range []struct {
+		name           plumbing.ReferenceName
+		oldRev, newRev string
+		event          string
+	}{
+		{plumbing.NewBranchReferenceName("dev"), plumbing.ZERO_OID, rev, EventBranchCreate},
+		{plumbing.NewBranchReferenceName("dev"), rev, plumbing.ZERO_OID, EventBranchDelete},
+		{plumbing.NewBranchReferenceName("dev"), rev, rev, ""},
+		{plumbing.NewTagReferenceName("v1.0"), plumbing.ZERO_OID, rev, EventTagCreate},
+		{plumbing.NewTagReferenceName("v1.0"), rev, plumbing.ZERO_OID, EventTagDelete},
+	} {
+		require.Equal(t, c.event, referenceEvent(&Command{ReferenceName: c.name, OldRev: c.oldRev, NewRev: c.newRev}), "%v", c)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	require.Equal(t, 10*time.Second, webhookBackoffAt(1))
+	require.Equal(t, 20*time.Second, webhookBackoffAt(2))
+	require.Equal(t, 80*time.Second, webhookBackoffAt(4))
+	require.Equal(t, time.Hour, webhookBackoffAt(100))
+}
+
+func TestWebhookAttempt(t *testing.T) {
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !hmac.Equal([]byte(r.Header.Get("X-Zeta-Signature-256")), []byte(SignWebhookPayload("secret", body))) || r.Header.Get("X-Zeta-Event") != EventPush {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("try later"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	d := newWebhookDispatcher(nil, &serve.Webhooks{MaxAttempts: 2})
+	w := &database.WebhookDelivery{ID: 1, Event: EventPush, Payload: `{"event":"push"}`, URL: srv.URL, Secret: "secret"}
+	start := time.Now()
+	d.attempt(context.Background(), w)
+	require.Equal(t, database.DeliveryPending, w.Status)
+	require.Equal(t, http.StatusServiceUnavailable, w.ResponseCode)
+	require.Equal(t, "status 503: try later", w.LastError)
+	require.WithinDuration(t, start.Add(webhookBackoff), w.NextAttemptAt, 5*time.Second)
+
+	fail = false
+	d.attempt(context.Background(), w)
+	require.Equal(t, database.DeliverySucceeded, w.Status)
+	require.Equal(t, 2, w.Attempts)
+	require.NotNil(t, w.DeliveredAt)
+
+	// attempts exhausted
+	w = &database.WebhookDelivery{ID: 2, Event: EventPush, Payload: `{}`, URL: srv.URL, Secret: "wrong", Attempts: 1}
+	d.attempt(context.Background(), w)
+	require.Equal(t, database.DeliveryFailed, w.Status)
+	require.Equal(t, http.StatusBadRequest, w.ResponseCode)
+}
@@ -7,9 +7,12 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/pkg/serve/database"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
+	"github.com/antgroup/hugescm/pkg/serve/ratelimit"
+	"github.com/antgroup/hugescm/pkg/serve/repo"
 )
 
 func (s *Server) checkAccessForDelopyKey(e *Session, repoPath string, operation protocol.Operation) int {
@@ -38,7 +41,42 @@ func checkRepoReadable(u *database.User, repo *database.Repository, accessLevel
 	return repo.IsPublic() || (repo.IsInternal() && u.Type != database.UserTypeRemoteUser)
 }
 
+// doPermissionCheck: check the access of the session to the repository, then enter the rate limits.
 func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protocol.Operation) int {
+	if exitCode := s.checkPermission(e, repoPath, operation); exitCode != 0 {
+		return exitCode
+	}
+	return s.enterLimits(e)
+}
+
+func (s *Server) limitKeys(e *Session) []ratelimit.Key {
+	keys := []ratelimit.Key{
+		ratelimit.NewKey(ratelimit.Repository, e.RID),
+	}
+	if e.RemoteAddress != nil && len(e.RemoteAddress.IP) != 0 {
+		keys = append(keys, ratelimit.Key{Kind: ratelimit.IP, ID: e.RemoteAddress.IP})
+	}
+	if e.IsDeployKey {
+		return append(keys, ratelimit.NewKey(ratelimit.DeployKey, e.KID))
+	}
+	if e.UID != 0 {
+		keys = append(keys, ratelimit.NewKey(ratelimit.User, e.UID))
+	}
+	return keys
+}
+
+// enterLimits: the slot of heavy commands is held until the session is finished.
+func (s *Server) enterLimits(e *Session) int {
+	release, err := s.limiter.Enter(s.limitKeys(e), e.heavy)
+	if err != nil {
+		e.WriteError("%v\n", err)
+		return 429
+	}
+	e.release = release
+	return 0
+}
+
+func (s *Server) checkPermission(e *Session, repoPath string, operation protocol.Operation) int {
 	repoParts := strengthen.SplitPath(repoPath)
 	if len(repoParts) < 2 {
 		e.WriteError("bad repo relative path '%s'", repoPath)
@@ -78,6 +116,7 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 	}
 	e.IsAdministrator = u.Administrator
 	if u.Administrator {
+		e.AccessLevel = database.OwnerAccess
 		return 0
 	}
 	_, accessLevel, err := s.db.RepoAccessLevel(e.Context(), repo, u)
@@ -85,6 +124,7 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 		e.WriteError("check user's access for repository error: %v", err)
 		return 500
 	}
+	e.AccessLevel = accessLevel
 	switch operation {
 	case protocol.DOWNLOAD:
 		if !checkRepoReadable(u, repo, accessLevel) {
@@ -102,3 +142,32 @@ func (s *Server) doPermissionCheck(e *Session, repoPath string, operation protoc
 	}
 	return 0
 }
+
+// pathACL: path access control of the user, nil if the user is allowed to access all paths of the repository
+func (s *Server) pathACL(e *Session) (*repo.PathACL, error) {
+	acls, err := s.db.ListPathACLs(e.Context(), e.RID)
+	if err != nil || len(acls) == 0 {
+		return nil, err
+	}
+	var groups []int64
+	if !e.IsDeployKey {
+		if groups, err = s.db.UserGroups(e.Context(), e.UID); err != nil {
+			return nil, err
+		}
+	}
+	// the repository is readable after the permission check, eg: public repository
+	return repo.NewPathACL(acls, groups, max(e.AccessLevel, database.ReporterAccess)), nil
+}
+
+// refusedObjects: objects under the paths the user is not allowed to read, see repo.HiddenObjects
+func (s *Server) refusedObjects(e *Session, rr repo.Repository, oids []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	acl, err := s.pathACL(e)
+	if err != nil || acl == nil {
+		return nil, err
+	}
+	hidden, err := rr.HiddenObjects(e.Context(), acl)
+	if err != nil {
+		return nil, err
+	}
+	return hidden.Refused(e.Context(), oids)
+}
@@ -58,6 +58,19 @@ func NewCommand(args []string) (Command, error) {
 	return cmd, nil
 }
 
+// isHeavy: metadata fetch, batch objects and pushes are limited by the concurrency quotas
+func isHeavy(cmd Command) bool {
+	switch c := cmd.(type) {
+	case *Metadata:
+		return true
+	case *Objects:
+		return c.Batch
+	case *Push:
+		return !c.BatchCheck && c.OID.IsZero()
+	}
+	return false
+}
+
 func ZetaEncodeVND(w io.Writer, a any) {
 	if err := json.NewEncoder(w).Encode(a); err != nil {
 		logrus.Errorf("encode response error: %v", err)
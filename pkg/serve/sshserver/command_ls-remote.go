@@ -4,6 +4,8 @@
 package sshserver
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/antgroup/hugescm/pkg/serve/database"
@@ -11,18 +13,40 @@ import (
 )
 
 // zeta-serve ls-remote "group/mono-zeta" --reference "${REFNAME}"
+//
+// zeta-serve ls-remote "group/mono-zeta" --list [--prefix=refs/heads/] [--cursor=${CURSOR}] [--limit=N]
 type LsRemote struct {
 	Path      string
 	Reference string
+	List      bool
+	Prefix    string
+	Cursor    string
+	Limit     int
 }
 
 func (c *LsRemote) ParseArgs(args []string) error {
 	var p ParseArgs
-	p.Add("reference", REQUIRED, 'R')
+	p.Add("reference", REQUIRED, 'R').
+		Add("list", NOARG, 'L').
+		Add("prefix", REQUIRED, 'P').
+		Add("cursor", REQUIRED, 'C').
+		Add("limit", REQUIRED, 'N')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'R':
 			c.Reference = nextArg
+		case 'L':
+			c.List = true
+		case 'P':
+			c.Prefix = nextArg
+		case 'C':
+			c.Cursor = nextArg
+		case 'N':
+			limit, err := strconv.Atoi(nextArg)
+			if err != nil || limit < 0 {
+				return fmt.Errorf("bad limit '%s'", nextArg)
+			}
+			c.Limit = limit
 		}
 		return nil
 	}); err != nil {
@@ -36,9 +60,34 @@ func (c *LsRemote) ParseArgs(args []string) error {
 }
 
 func (c *LsRemote) Exec(ctx *RunCtx) int {
+	if c.List {
+		return ctx.S.ListReferences(ctx.Session, c)
+	}
 	return ctx.S.LsRemote(ctx.Session, c.Path, c.Reference)
 }
 
+func (s *Server) ListReferences(e *Session, c *LsRemote) int {
+	if exitCode := s.doPermissionCheck(e, c.Path, protocol.DOWNLOAD); exitCode != 0 {
+		return exitCode
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close()
+	entries, next, err := rr.ListReferences(e.Context(), c.Prefix, c.Cursor, c.Limit)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	ZetaEncodeVND(e, &protocol.References{
+		Remote:     e.makeRemoteURL(s.Endpoint),
+		HEAD:       protocol.BRANCH_PREFIX + e.DefaultBranch,
+		References: entries,
+		NextCursor: next,
+	})
+	return 0
+}
+
 func (s *Server) LsRemote(e *Session, repoPath, refname string) int {
 	if exitCode := s.doPermissionCheck(e, repoPath, protocol.DOWNLOAD); exitCode != 0 {
 		return exitCode
@@ -79,6 +128,7 @@ func (s *Server) LsBranchReference(e *Session, branchName string) int {
 		Agent:           s.serverName,
 		HashAlgo:        e.HashAlgo,
 		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    s.hub.Capabilities(),
 	}
 	ZetaEncodeVND(e, branch)
 	return 0
@@ -104,6 +154,7 @@ func (s *Server) LsTagReference(e *Session, tagName string) int {
 		Agent:           s.serverName,
 		HashAlgo:        e.HashAlgo,
 		CompressionAlgo: e.CompressionAlgo,
+		Capabilities:    s.hub.Capabilities(),
 	}
 	ZetaEncodeVND(e, branch)
 	return 0
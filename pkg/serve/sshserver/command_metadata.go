@@ -103,6 +103,10 @@ func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 		return e.ExitError(err)
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
 	ro, err := rr.ParseRev(e.Context(), c.Revision)
 	if err != nil {
 		return e.ExitError(err)
@@ -116,6 +120,22 @@ func (s *Server) FetchMetadata(e *Session, c *Metadata) int {
 		return e.ExitError(err)
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), nil)
+	}
+	if !c.Have.IsZero() {
+		haves, err := rr.ODB().Reachable(e.Context(), c.Have)
+		if err != nil {
+			logrus.Warnf("resolve objects reachable from have '%s' error: %v", c.Have, err)
+		}
+		p.Exclude(haves)
+		// the client has checked out the whole tree of have, unless it is restricted or limited by depth
+		if acl == nil && c.Depth == -1 {
+			if err := p.ExcludeCheckout(e.Context(), c.Have); err != nil && !plumbing.IsNoSuchObject(err) {
+				logrus.Warnf("resolve checkout of have '%s' error: %v", c.Have, err)
+			}
+		}
+	}
 	for oid, o := range ro.Objects {
 		if err := p.WriteAny(e.Context(), o, oid); err != nil {
 			logrus.Errorf("write objects error %v", err)
@@ -144,6 +164,15 @@ func (s *Server) GetSparseMetadata(e *Session, c *Metadata) int {
 		return e.ExitError(err)
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	for _, p := range paths {
+		if acl != nil && !acl.CanRead(p) {
+			return e.ExitFormat(403, e.W("access to '%s' is denied"), p)
+		}
+	}
 
 	ro, err := rr.ParseRev(e.Context(), c.Revision)
 	if err != nil {
@@ -159,6 +188,16 @@ func (s *Server) GetSparseMetadata(e *Session, c *Metadata) int {
 		return e.ExitError(err)
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), nil)
+	}
+	if !c.Have.IsZero() {
+		haves, err := rr.ODB().Reachable(e.Context(), c.Have)
+		if err != nil {
+			logrus.Warnf("resolve objects reachable from have '%s' error: %v", c.Have, err)
+		}
+		p.Exclude(haves)
+	}
 	for oid, o := range ro.Objects {
 		if err := p.WriteAny(e.Context(), o, oid); err != nil {
 			logrus.Errorf("write objects error %v", err)
@@ -187,6 +226,21 @@ func (s *Server) BatchMetadata(e *Session, depth int) int {
 		return e.ExitError(err)
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	hidden, err := rr.HiddenObjects(e.Context(), acl)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	refused, err := hidden.Refused(e.Context(), oids)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	for oid := range refused {
+		return e.ExitFormat(403, e.W("access to object '%s' is denied"), oid)
+	}
 	odb := rr.ODB()
 	objects := make([]any, 0, len(oids))
 	for _, oid := range oids {
@@ -202,6 +256,9 @@ func (s *Server) BatchMetadata(e *Session, depth int) int {
 		return e.ExitError(err)
 	}
 	defer p.Close()
+	if acl != nil {
+		p.Restrict(acl.Matcher(), hidden.Contains)
+	}
 	for _, a := range objects {
 		switch v := a.(type) {
 		case *object.Commit:
@@ -209,7 +266,7 @@ func (s *Server) BatchMetadata(e *Session, depth int) int {
 				logrus.Errorf("write commit error %v", err)
 				return e.ExitError(err)
 			}
-			if err := p.WriteTree(e.Context(), v.Tree, 0); err != nil {
+			if err := p.WriteRootTree(e.Context(), v.Tree); err != nil {
 				logrus.Errorf("write tree error %v", err)
 				return e.ExitError(err)
 			}
@@ -238,7 +295,7 @@ func (s *Server) BatchMetadata(e *Session, depth int) int {
 				logrus.Errorf("write fragments error %v", err)
 				return e.ExitError(err)
 			}
-			if err := p.WriteTree(e.Context(), target.Tree, 0); err != nil {
+			if err := p.WriteRootTree(e.Context(), target.Tree); err != nil {
 				logrus.Errorf("write tree error %v", err)
 				return e.ExitError(err)
 			}
@@ -22,14 +22,22 @@ import (
 
 // zeta-serve objects "group/mono-zeta" --batch
 
+// zeta-serve objects "group/mono-zeta" --batch --want=${want} --have=${have}
+
+// zeta-serve objects "group/mono-zeta" --batch --resumable: checked stream, see 'resumable-batch' capability
+
 // zeta-serve objects "group/mono-zeta" --share
 
 type Objects struct {
-	Path   string
-	OID    plumbing.Hash
-	Offset int64
-	Batch  bool
-	Share  bool
+	Path      string
+	OID       plumbing.Hash
+	Offset    int64
+	Want      plumbing.Hash
+	Have      plumbing.Hash
+	Batch     bool
+	Share     bool
+	Resumable bool
+	SizeLimit int64
 }
 
 func (c *Objects) ParseArgs(args []string) error {
@@ -37,7 +45,11 @@ func (c *Objects) ParseArgs(args []string) error {
 	p.Add("oid", REQUIRED, 'O').
 		Add("offset", REQUIRED, 'o').
 		Add("share", NOARG, 'S').
-		Add("batch", NOARG, 'B')
+		Add("want", REQUIRED, 'W').
+		Add("have", REQUIRED, 'H').
+		Add("batch", NOARG, 'B').
+		Add("resumable", NOARG, 'R').
+		Add("size-limit", REQUIRED, 'L')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'O':
@@ -54,12 +66,28 @@ func (c *Objects) ParseArgs(args []string) error {
 				return errors.New("--offset cannot be less than 0")
 			}
 			c.Offset = offset
+		case 'W':
+			if !plumbing.ValidateHashHex(nextArg) {
+				return fmt.Errorf("want is invalid hash: %s", nextArg)
+			}
+			c.Want = plumbing.NewHash(nextArg)
+		case 'H':
+			if !plumbing.ValidateHashHex(nextArg) {
+				return fmt.Errorf("have is invalid hash: %s", nextArg)
+			}
+			c.Have = plumbing.NewHash(nextArg)
 		case 'B':
 			c.Batch = true
+		case 'R':
+			c.Resumable = true
 		case 'S':
 			c.Share = true
 		case 'L':
-
+			sizeLimit, err := strconv.ParseInt(nextArg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse '--size-limit': %s error: %s", nextArg, err)
+			}
+			c.SizeLimit = sizeLimit
 		}
 		return nil
 	}); err != nil {
@@ -77,7 +105,7 @@ func (c *Objects) Exec(ctx *RunCtx) int {
 		return exitCode
 	}
 	if c.Batch {
-		return ctx.S.BatchObjects(ctx.Session)
+		return ctx.S.BatchObjects(ctx.Session, c)
 	}
 	if c.Share {
 		return ctx.S.ShareObjects(ctx.Session)
@@ -89,7 +117,7 @@ func (c *Objects) Exec(ctx *RunCtx) int {
 	return ctx.S.GetObject(ctx.Session, c.OID, c.Offset)
 }
 
-func (s *Server) BatchObjects(e *Session) int {
+func (s *Server) BatchObjects(e *Session, c *Objects) int {
 	oids, err := protocol.ReadInputOIDs(e)
 	if err != nil {
 		return e.ExitFormat(400, "batch-oids: %v", err)
@@ -99,18 +127,54 @@ func (s *Server) BatchObjects(e *Session) int {
 		return e.ExitError(err)
 	}
 	defer rr.Close()
+	acl, err := s.pathACL(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	if !c.Want.IsZero() {
+		var missing []plumbing.Hash
+		if acl != nil {
+			missing, err = acl.MissingObjects(e.Context(), rr.ODB(), c.Want, c.Have)
+		} else {
+			missing, err = rr.ODB().MissingObjects(e.Context(), c.Want, c.Have)
+		}
+		if errors.Is(err, odb.ErrBitmapIndexNotReady) {
+			return e.ExitFormat(503, "%v", err)
+		}
+		if err != nil {
+			return e.ExitError(err)
+		}
+		oids = protocol.MergeOIDs(oids, missing)
+	}
+	hidden, err := rr.HiddenObjects(e.Context(), acl)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	refused, err := hidden.Refused(e.Context(), oids)
+	if err != nil {
+		return e.ExitError(err)
+	}
 	buffedWriter := streamio.GetBufferWriter(e)
 	defer func() {
 		_ = buffedWriter.Flush()
 		streamio.PutBufferWriter(buffedWriter)
 	}()
 	cw := crc.NewCrc64Writer(buffedWriter)
-	if err := protocol.WriteBatchObjectsHeader(cw); err != nil {
+	version, writeItem := protocol.PROTOCOL_VERSION, protocol.WriteObjectsItem
+	if c.Resumable {
+		version, writeItem = protocol.RESUMABLE_BATCH_VERSION, protocol.WriteCheckedObjectsItem
+	}
+	if err := protocol.WriteBatchObjectsHeader(cw, version); err != nil {
 		logrus.Errorf("write blob header error: %v", err)
 		return e.ExitError(err)
 	}
 	o := rr.ODB()
+	sizeLimit := protocol.BatchSizeLimit(c.SizeLimit)
 	writeFunc := func(oid plumbing.Hash) error {
+		if refused[oid] {
+			// same as missing objects
+			return nil
+		}
 		sr, err := o.Open(e.Context(), oid, 0)
 		if plumbing.IsNoSuchObject(err) {
 			return nil
@@ -118,12 +182,12 @@ func (s *Server) BatchObjects(e *Session) int {
 		if err != nil {
 			return err
 		}
-		if sr.Size() > protocol.MAX_BATCH_BLOB_SIZE {
+		if sr.Size() > sizeLimit {
 			sr.Close()
 			return nil
 		}
 		defer sr.Close()
-		return protocol.WriteObjectsItem(cw, sr, oid.String(), sr.Size())
+		return writeItem(cw, sr, oid.String(), sr.Size())
 	}
 	for _, oid := range oids {
 		if err := writeFunc(oid); err != nil {
@@ -144,6 +208,13 @@ func (s *Server) GetObject(e *Session, oid plumbing.Hash, offset int64) int {
 		return e.ExitError(err)
 	}
 	defer repo.Close()
+	refused, err := s.refusedObjects(e, repo, []plumbing.Hash{oid})
+	if err != nil {
+		return e.ExitError(err)
+	}
+	if refused[oid] {
+		return e.ExitFormat(403, e.W("access to object '%s' is denied"), oid)
+	}
 	o := repo.ODB()
 	sr, err := o.Open(e.Context(), oid, 0)
 	if err != nil {
@@ -170,6 +241,20 @@ func (s *Server) ShareObjects(e *Session) int {
 		return e.ExitError(err)
 	}
 	defer rr.Close()
+	oids := make([]plumbing.Hash, 0, len(request.Objects))
+	for _, o := range request.Objects {
+		if o == nil {
+			return e.ExitFormat(400, "require object is nil")
+		}
+		oids = append(oids, plumbing.NewHash(o.OID))
+	}
+	refused, err := s.refusedObjects(e, rr, oids)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	for oid := range refused {
+		return e.ExitFormat(403, e.W("access to object '%s' is denied"), oid)
+	}
 
 	response := &protocol.BatchSharedsResponse{
 		Objects: make([]*protocol.Representation, 0, len(request.Objects)),
@@ -15,6 +15,7 @@ import (
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/zeta"
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/odb"
 	"github.com/antgroup/hugescm/pkg/serve/protocol"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/sirupsen/logrus"
@@ -24,8 +25,18 @@ import (
 
 // zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}"
 
+// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload new
+
+// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload status|finish --upload-id "$UPLOAD_ID"
+
+// zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload chunk --upload-id "$UPLOAD_ID" --offset "$OFFSET"
+
 // zeta-serve push "group/mono-zeta" --reference "$REFNAME" --old-rev "$OLD_REV" --new-rev "$NEW_REV"
 
+// zeta-serve push "group/mono-zeta" --atomic
+
+// zeta-serve push "group/mono-zeta" --bundle [--force]
+
 type Push struct {
 	Path       string
 	Reference  string
@@ -34,6 +45,12 @@ type Push struct {
 	OldRev     plumbing.Hash
 	NewRev     plumbing.Hash
 	BatchCheck bool
+	Upload     string
+	UploadID   string
+	Offset     int64
+	Atomic     bool
+	Bundle     bool
+	Force      bool
 }
 
 func (c *Push) ParseArgs(args []string) error {
@@ -43,7 +60,13 @@ func (c *Push) ParseArgs(args []string) error {
 		Add("batch-check", NOARG, 'B').
 		Add("size", REQUIRED, 'S').
 		Add("old-rev", REQUIRED, 'o').
-		Add("new-rev", REQUIRED, 'n')
+		Add("new-rev", REQUIRED, 'n').
+		Add("upload", REQUIRED, 'U').
+		Add("upload-id", REQUIRED, 'I').
+		Add("offset", REQUIRED, 'F').
+		Add("atomic", NOARG, 'A').
+		Add("bundle", NOARG, 'K').
+		Add("force", NOARG, 'f')
 	if err := p.Parse(args, func(index rune, nextArg, raw string) error {
 		switch index {
 		case 'R':
@@ -74,6 +97,30 @@ func (c *Push) ParseArgs(args []string) error {
 				return fmt.Errorf("old-rev is invalid hash: %s", nextArg)
 			}
 			c.OldRev = plumbing.NewHash(nextArg)
+		case 'U':
+			switch nextArg {
+			case uploadNew, uploadStatus, uploadChunk, uploadFinish, uploadAbort:
+			default:
+				return fmt.Errorf("unsupported '--upload': %s", nextArg)
+			}
+			c.Upload = nextArg
+		case 'I':
+			c.UploadID = nextArg
+		case 'F':
+			offset, err := strconv.ParseInt(nextArg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse '--offset': %s error: %s", nextArg, err)
+			}
+			if offset < 0 {
+				return errors.New("--offset cannot be less than 0")
+			}
+			c.Offset = offset
+		case 'A':
+			c.Atomic = true
+		case 'K':
+			c.Bundle = true
+		case 'f':
+			c.Force = true
 		}
 		return nil
 	}); err != nil {
@@ -90,12 +137,21 @@ func (c *Push) Exec(ctx *RunCtx) int {
 	if exitCode := ctx.S.doPermissionCheck(ctx.Session, c.Path, protocol.UPLOAD); exitCode != 0 {
 		return exitCode
 	}
+	if c.Atomic {
+		return ctx.S.AtomicPush(ctx.Session)
+	}
+	if c.Bundle {
+		return ctx.S.BundlePush(ctx.Session, c.Force)
+	}
 	if c.BatchCheck {
 		return ctx.S.BatchCheck(ctx.Session, c.Reference)
 	}
 	if c.OID.IsZero() {
 		return ctx.S.Push(ctx.Session, c.Reference, c.OldRev, c.NewRev)
 	}
+	if len(c.Upload) != 0 {
+		return ctx.S.Upload(ctx.Session, c)
+	}
 	return ctx.S.PutObject(ctx.Session, c.Reference, c.OID, c.Size)
 }
 
@@ -162,6 +218,65 @@ func (s *Server) PutObject(e *Session, refname string, oid plumbing.Hash, compre
 	return 0
 }
 
+const (
+	uploadNew    = "new"
+	uploadStatus = "status"
+	uploadChunk  = "chunk"
+	uploadFinish = "finish"
+	uploadAbort  = "abort"
+)
+
+func uploadExitError(e *Session, oid plumbing.Hash, err error) int {
+	if err == odb.ErrUploadNotFound {
+		return e.ExitFormat(404, "upload object '%s' error: %v", oid, err)
+	}
+	return e.ExitFormat(409, "upload object '%s' error: %v", oid, err)
+}
+
+// Upload: resumable upload session, a chunk is read from stdin, its length is derived from the offset and the chunk size.
+func (s *Server) Upload(e *Session, c *Push) int {
+	if c.Upload != uploadNew && len(c.UploadID) == 0 {
+		return e.ExitFormat(400, "missing upload id")
+	}
+	if exitCode := s.updateReferenceDryRun(e, c.Reference); exitCode != 0 {
+		return exitCode
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close()
+	var u *odb.Upload
+	switch c.Upload {
+	case uploadNew:
+		u, err = rr.ODB().NewUpload(e.Context(), c.OID, c.Size)
+	case uploadStatus:
+		u, err = rr.ODB().UploadStatus(e.Context(), c.OID, c.UploadID, c.Size)
+	case uploadChunk:
+		length := min(odb.UploadChunkSize(c.Size), c.Size-c.Offset)
+		u, err = rr.ODB().UploadChunk(e.Context(), c.OID, c.UploadID, c.Size, c.Offset, e, length)
+	case uploadFinish:
+		var size int64
+		if size, err = rr.ODB().FinishUpload(e.Context(), c.OID, c.UploadID, c.Size); err != nil {
+			return uploadExitError(e, c.OID, err)
+		}
+		logrus.Infof("%s upload large object %s [size: %s] to %s [refname: %s] success", e.UserName, c.OID, strengthen.HumanateSize(size), e.makeRemoteURL(s.Endpoint), c.Reference)
+		ZetaEncodeVND(e, &protocol.ErrorCode{Code: 200, Message: "OK"})
+		return 0
+	case uploadAbort:
+		if err = rr.ODB().AbortUpload(e.Context(), c.OID, c.UploadID); err != nil {
+			return uploadExitError(e, c.OID, err)
+		}
+		ZetaEncodeVND(e, &protocol.ErrorCode{Code: 200, Message: "OK"})
+		return 0
+	}
+	if err != nil {
+		return uploadExitError(e, c.OID, err)
+	}
+	ZetaEncodeVND(e, &protocol.UploadSession{ID: u.ID, OID: c.OID.String(), Size: u.Size, ChunkSize: u.ChunkSize, Offset: u.Offset})
+	return 0
+}
+
 func (s *Server) Push(e *Session, referenceName string, oldRev, newRev plumbing.Hash) int {
 	if referenceName == protocol.HEAD {
 		return s.BranchPush(e, e.DefaultBranch, oldRev, newRev)
@@ -181,6 +296,45 @@ func (s *Server) Push(e *Session, referenceName string, oldRev, newRev plumbing.
 	return e.ExitFormat(501, e.W("reference name '%s' is reserved"), referenceName)
 }
 
+// parsePushOptions: push options are sent as env ZETA_PUSH_OPTION_COUNT and ZETA_PUSH_OPTION_<i>
+func (s *Server) parsePushOptions(e *Session) (*repo.PushOptions, int) {
+	options, err := repo.ReadPushOptions(e.Getenv, "ZETA_PUSH_OPTION_COUNT", "ZETA_PUSH_OPTION_")
+	if err != nil {
+		return nil, e.ExitFormat(400, "%v", err)
+	}
+	o, err := repo.ParsePushOptions(options, s.PushOptions)
+	if err != nil {
+		return nil, e.ExitFormat(400, "%v", err)
+	}
+	return o, 0
+}
+
+// checkProtection: fill the protection rule, the access level and the path ACL of the command, returns the reason if
+// the user is not allowed to update the reference.
+func (s *Server) checkProtection(e *Session, cmd *repo.Command) (string, error) {
+	rules, err := s.db.ListProtectionRules(e.Context(), e.RID)
+	if err != nil {
+		return "", err
+	}
+	cmd.Protection = database.MatchProtectionRules(rules, cmd.ReferenceName)
+	cmd.AccessLevel = e.AccessLevel
+	if cmd.PathACL, err = s.pathACL(e); err != nil {
+		return "", err
+	}
+	return cmd.ProtectionReason(), nil
+}
+
+func (s *Server) exitProtection(e *Session, cmd *repo.Command) int {
+	reason, err := s.checkProtection(e, cmd)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	if len(reason) != 0 {
+		return e.ExitFormat(403, "%s", reason)
+	}
+	return 0
+}
+
 func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Hash) int {
 	tag, err := s.db.FindTag(e.Context(), e.RID, tagName)
 	if err != nil && !database.IsErrRevisionNotFound(err) {
@@ -194,11 +348,21 @@ func (s *Server) TagPush(e *Session, tagName string, oldRev, newRev plumbing.Has
 		NewRev:        newRev.String(),
 		Terminal:      e.Getenv("TERM"),
 		Language:      e.Getenv("LANG"),
+		KeyID:         e.KID,
+		Protocol:      "ssh",
+		ClientIP:      e.clientIP(),
 	}
 	if tag != nil && tag.Hash != command.OldRev {
 		return e.ExitFormat(409, e.W("tag is updated, please update and try again")) //nolint:govet
 	}
+	if exitCode := s.exitProtection(e, command); exitCode != 0 {
+		return exitCode
+	}
 	command.UpdateStats(e.Getenv("ZETA_OBJECTS_STATS"))
+	var exitCode int
+	if command.PushOptions, exitCode = s.parsePushOptions(e); exitCode != 0 {
+		return exitCode
+	}
 	rr, err := s.open(e)
 	if err != nil {
 		return e.ExitError(err)
@@ -226,11 +390,20 @@ func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbi
 		NewRev:        newRev.String(),
 		Terminal:      e.Getenv("TERM"),
 		Language:      e.Getenv("LANG"),
+		KeyID:         e.KID,
+		Protocol:      "ssh",
+		ClientIP:      e.clientIP(),
 	}
 	if oldBranch != nil && oldBranch.Hash != command.OldRev {
 		return e.ExitFormat(409, e.W("branch is updated, please update and try again")) //nolint:govet
 	}
+	if exitCode = s.exitProtection(e, command); exitCode != 0 {
+		return exitCode
+	}
 	command.UpdateStats(e.Getenv("ZETA_OBJECTS_STATS"))
+	if command.PushOptions, exitCode = s.parsePushOptions(e); exitCode != 0 {
+		return exitCode
+	}
 	rr, err := s.open(e)
 	if err != nil {
 		return e.ExitError(err)
@@ -245,6 +418,144 @@ func (s *Server) BranchPush(e *Session, branchName string, oldRev, newRev plumbi
 	return 0
 }
 
+// currentRev: current rev of the reference, ZERO_OID if the reference does not exist. Protection rules are
+// checked for branches, returns the reason if the reference cannot be updated.
+func (s *Server) currentRev(e *Session, refname plumbing.ReferenceName) (string, string, error) {
+	if refname.IsBranch() {
+		branch, err := s.branchCanUpdate(e, refname.BranchName())
+		if err != nil {
+			if es, ok := err.(*zeta.ErrStatusCode); ok {
+				return "", es.Message, nil
+			}
+			return "", "", err
+		}
+		if branch == nil {
+			return plumbing.ZERO_OID, "", nil
+		}
+		return branch.Hash, "", nil
+	}
+	tag, err := s.db.FindTag(e.Context(), e.RID, refname.TagName())
+	if err != nil && !database.IsErrRevisionNotFound(err) {
+		return "", "", err
+	}
+	if tag == nil {
+		return plumbing.ZERO_OID, "", nil
+	}
+	return tag.Hash, "", nil
+}
+
+// checkAtomicCommand: protection rules and the old rev of each reference are checked before objects are received,
+// returns the reason if the reference cannot be updated.
+func (s *Server) checkAtomicCommand(e *Session, cmd *repo.Command) (string, error) {
+	rev, reason, err := s.currentRev(e, cmd.ReferenceName)
+	if err != nil || len(reason) != 0 {
+		return reason, err
+	}
+	if rev == cmd.OldRev {
+		return s.checkProtection(e, cmd)
+	}
+	if cmd.ReferenceName.IsBranch() {
+		return e.W("branch is updated, please update and try again"), nil
+	}
+	return e.W("tag is updated, please update and try again"), nil
+}
+
+// AtomicPush: stdin starts with pktline commands '<old-rev> <new-rev> <refname>' ended by a flush-pkt,
+// followed by the objects of all references.
+func (s *Server) AtomicPush(e *Session) int {
+	cmds, err := repo.ReadAtomicCommands(e)
+	if err != nil {
+		return e.ExitFormat(400, "read commands error: %v", err)
+	}
+	pushOptions, exitCode := s.parsePushOptions(e)
+	if exitCode != 0 {
+		return exitCode
+	}
+	for _, cmd := range cmds {
+		cmd.RID = e.RID
+		cmd.UID = e.UID
+		cmd.Terminal = e.Getenv("TERM")
+		cmd.Language = e.Getenv("LANG")
+		cmd.KeyID = e.KID
+		cmd.Protocol = "ssh"
+		cmd.ClientIP = e.clientIP()
+		cmd.PushOptions = pushOptions
+		cmd.UpdateStats(e.Getenv("ZETA_OBJECTS_STATS"))
+	}
+	for _, cmd := range cmds {
+		reason, err := s.checkAtomicCommand(e, cmd)
+		if err != nil {
+			return e.ExitError(err)
+		}
+		if len(reason) != 0 {
+			repo.ReportAtomicRejected(e, cmds, cmd, "%s", reason)
+			return 0
+		}
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close()
+	if err = rr.DoAtomicPush(e.Context(), cmds, e, e); err != nil {
+		if es, ok := err.(*zeta.ErrStatusCode); ok {
+			return e.ExitFormat(es.Code, "reason: %v", err)
+		}
+		return e.ExitError(err)
+	}
+	return 0
+}
+
+// BundlePush: stdin is a bundle file, all references of the bundle are updated atomically. The old revs are recorded
+// in the bundle, non-fast-forward updates are rejected without '--force'.
+func (s *Server) BundlePush(e *Session, force bool) int {
+	b, err := repo.ReceiveBundle(e.Context(), e, s.MaxBundleSize.Size)
+	if repo.IsErrBundleTooLarge(err) {
+		return e.ExitFormat(413, "bad bundle: %v", err)
+	}
+	if err != nil {
+		return e.ExitFormat(400, "bad bundle: %v", err)
+	}
+	defer b.Close()
+	cmds, err := b.Commands(force)
+	if err != nil {
+		return e.ExitFormat(400, "bad bundle: %v", err)
+	}
+	for _, cmd := range cmds {
+		cmd.RID = e.RID
+		cmd.UID = e.UID
+		cmd.Terminal = e.Getenv("TERM")
+		cmd.Language = e.Getenv("LANG")
+		cmd.KeyID = e.KID
+		cmd.Protocol = "ssh"
+		cmd.ClientIP = e.clientIP()
+	}
+	for _, cmd := range cmds {
+		reason, err := s.checkAtomicCommand(e, cmd)
+		if err != nil {
+			return e.ExitError(err)
+		}
+		if len(reason) != 0 {
+			repo.ReportAtomicRejected(e, cmds, cmd, "%s", reason)
+			return 0
+		}
+	}
+	rr, err := s.open(e)
+	if err != nil {
+		return e.ExitError(err)
+	}
+	defer rr.Close()
+	reader := b.NewReader(e.Context())
+	defer reader.Close()
+	if err = rr.DoAtomicPush(e.Context(), cmds, reader, e); err != nil {
+		if es, ok := err.(*zeta.ErrStatusCode); ok {
+			return e.ExitFormat(es.Code, "reason: %v", err)
+		}
+		return e.ExitError(err)
+	}
+	return 0
+}
+
 const (
 	GeneralBranch      = 0
 	ProtectedBranch    = 10
@@ -252,29 +563,41 @@ const (
 	ConfidentialBranch = 30
 )
 
-func (s *Server) checkBranchCanUpdate(e *Session, branchName string) (*database.Branch, int) {
+// branchCanUpdate: returns *zeta.ErrStatusCode if the branch is not allowed to be updated
+func (s *Server) branchCanUpdate(e *Session, branchName string) (*database.Branch, error) {
 	if !plumbing.ValidateBranchName([]byte(branchName)) {
-		return nil, e.ExitFormat(400, e.W("'%s' is not a valid branch name"), branchName)
+		return nil, zeta.NewErrStatusCode(400, e.W("'%s' is not a valid branch name"), branchName)
 	}
 	branch, err := s.db.FindBranch(e.Context(), e.RID, branchName)
 	if database.IsNotFound(err) {
-		return nil, 0
+		return nil, nil
 	}
 	if err != nil {
-		return nil, e.ExitFormat(500, e.W("internal server error: %v"), err)
+		return nil, zeta.NewErrStatusCode(500, e.W("internal server error: %v"), err)
 	}
 	switch branch.ProtectionLevel {
 	case ConfidentialBranch:
-		return nil, e.ExitFormat(404, e.W("'%s' is archived, cannot be modified"), branchName)
+		return nil, zeta.NewErrStatusCode(404, e.W("'%s' is archived, cannot be modified"), branchName)
 	case ArchivedBranch:
-		return nil, e.ExitFormat(403, e.W("'%s' is archived, cannot be modified"), branchName)
+		return nil, zeta.NewErrStatusCode(403, e.W("'%s' is archived, cannot be modified"), branchName)
 	case ProtectedBranch:
 		if !e.IsAdministrator {
-			return nil, e.ExitFormat(403, e.W("'%s' is protected branch, cannot be modified"), branchName)
+			return nil, zeta.NewErrStatusCode(403, e.W("'%s' is protected branch, cannot be modified"), branchName)
 		}
-		return branch, 0
+		return branch, nil
 	default:
 	}
+	return branch, nil
+}
+
+func (s *Server) checkBranchCanUpdate(e *Session, branchName string) (*database.Branch, int) {
+	branch, err := s.branchCanUpdate(e, branchName)
+	if err != nil {
+		if es, ok := err.(*zeta.ErrStatusCode); ok {
+			return nil, e.ExitFormat(es.Code, "%s", es.Message)
+		}
+		return nil, e.ExitError(err)
+	}
 	return branch, 0
 }
 
@@ -282,14 +605,17 @@ func (s *Server) updateReferenceDryRun(e *Session, reference string) int {
 	refname := plumbing.ReferenceName(reference)
 	switch {
 	case refname.IsBranch():
-		_, exitCode := s.checkBranchCanUpdate(e, refname.BranchName())
-		return exitCode
+		if _, exitCode := s.checkBranchCanUpdate(e, refname.BranchName()); exitCode != 0 {
+			return exitCode
+		}
+		return s.exitProtection(e, &repo.Command{ReferenceName: refname, Language: e.Getenv("LANG")})
 	case refname.IsTag():
-		//return s.updateTagDryRun(w, r, refname.TagName())
-		return 0
+		return s.exitProtection(e, &repo.Command{ReferenceName: refname, Language: e.Getenv("LANG")})
 	case !strings.HasPrefix(reference, plumbing.ReferencePrefix):
-		_, exitCode := s.checkBranchCanUpdate(e, string(refname))
-		return exitCode
+		if _, exitCode := s.checkBranchCanUpdate(e, string(refname)); exitCode != 0 {
+			return exitCode
+		}
+		return s.exitProtection(e, &repo.Command{ReferenceName: plumbing.NewBranchReferenceName(reference), Language: e.Getenv("LANG")})
 	}
 	return e.ExitFormat(501, e.W("reference name '%s' is reserved"), refname)
 }
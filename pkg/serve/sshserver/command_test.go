@@ -39,3 +39,28 @@ func TestMetadataCommand(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "parse command: %v\n", err)
 	}
 }
+
+func TestPushUploadCommand(t *testing.T) {
+	oid := "fdd1b6a6a8b4b3aeb3d8a6b0d0e1e2f5a3cbd8a6e06f1a3fd7f3b8ca2b9d2f04"
+	args := []string{"push", "monon/zeta", "--reference=refs/heads/mainline", "--oid", oid, "--size=1024", "--upload=chunk", "--upload-id", "u1", "--offset=512"}
+	cmd, err := NewCommand(args)
+	if err != nil {
+		t.Fatalf("parse command: %v", err)
+	}
+	c, ok := cmd.(*Push)
+	if !ok {
+		t.Fatalf("unexpected command %T", cmd)
+	}
+	if c.Upload != uploadChunk || c.UploadID != "u1" || c.Offset != 512 || c.Size != 1024 {
+		t.Fatalf("unexpected upload args: %+v", c)
+	}
+	if cmd, err = NewCommand([]string{"push", "monon/zeta", "--oid", oid, "--size=1024", "--upload=abort", "--upload-id=u1"}); err != nil {
+		t.Fatalf("parse abort command: %v", err)
+	}
+	if c := cmd.(*Push); c.Upload != uploadAbort || c.UploadID != "u1" {
+		t.Fatalf("unexpected abort args: %+v", c)
+	}
+	if _, err := NewCommand([]string{"push", "monon/zeta", "--oid", oid, "--upload=bad"}); err == nil {
+		t.Fatalf("unsupported upload action accepted")
+	}
+}
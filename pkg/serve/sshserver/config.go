@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/pkg/serve"
 	"github.com/antgroup/hugescm/pkg/version"
 )
@@ -14,20 +15,28 @@ import (
 const (
 	DefaultMaxTimeout  = 2 * time.Hour
 	DefaultIdleTimeout = 5 * time.Minute
+	// DefaultMaxBundleSize: max size of a pushed bundle file
+	DefaultMaxBundleSize = 4 * strengthen.GiByte
 )
 
 type ServerConfig struct {
-	Listen          string          `toml:"listen"`
-	Repositories    string          `toml:"repositories"`
-	Endpoint        string          `toml:"endpoint"`
-	MaxTimeout      serve.Duration  `toml:"max_timeout,omitempty"`
-	IdleTimeout     serve.Duration  `toml:"idle_timeout,omitempty"`
-	BannerVersion   string          `toml:"banner_version,omitempty"`
-	HostPrivateKeys []string        `toml:"host_private_keys"` // private keys
-	DecryptedKey    string          `toml:"decrypted_key,omitempty"`
-	Cache           *serve.Cache    `toml:"cache,omitempty"`
-	DB              *serve.Database `toml:"database,omitempty"`
-	ZetaOSS         *serve.OSS      `toml:"oss,omitempty"`
+	Listen          string             `toml:"listen"`
+	MetricsListen   string             `toml:"metrics_listen,omitempty"` // listen address of /metrics, disabled when empty
+	Repositories    string             `toml:"repositories"`
+	Endpoint        string             `toml:"endpoint"`
+	MaxTimeout      serve.Duration     `toml:"max_timeout,omitempty"`
+	IdleTimeout     serve.Duration     `toml:"idle_timeout,omitempty"`
+	BannerVersion   string             `toml:"banner_version,omitempty"`
+	HostPrivateKeys []string           `toml:"host_private_keys"` // private keys
+	DecryptedKey    string             `toml:"decrypted_key,omitempty"`
+	Cache           *serve.Cache       `toml:"cache,omitempty"`
+	DB              *serve.Database    `toml:"database,omitempty"`
+	ZetaOSS         *serve.OSS         `toml:"oss,omitempty"`
+	PushOptions     *serve.PushOptions `toml:"push_options,omitempty"`
+	Hooks           *serve.Hooks       `toml:"hooks,omitempty"`
+	Webhooks        *serve.Webhooks    `toml:"webhooks,omitempty"`
+	RateLimits      *serve.RateLimits  `toml:"rate_limits,omitempty"`
+	MaxBundleSize   serve.Size         `toml:"max_bundle_size,omitempty"` // max size of a pushed bundle file
 }
 
 func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
@@ -44,6 +53,9 @@ func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
 		MaxTimeout: serve.Duration{
 			Duration: DefaultMaxTimeout,
 		},
+		MaxBundleSize: serve.Size{
+			Size: DefaultMaxBundleSize,
+		},
 		BannerVersion: "ZetaServe-" + version.GetVersion(),
 	}
 	if _, err = toml.NewDecoder(r).Decode(sc); err != nil {
@@ -51,6 +63,7 @@ func NewServerConfig(file string, expandEnv bool) (*ServerConfig, error) {
 	}
 	sc.DB.Decrypt(sc.DecryptedKey)
 	sc.ZetaOSS.Decrypt(sc.DecryptedKey)
+	sc.Hooks.Decrypt(sc.DecryptedKey)
 	if sc.Cache == nil {
 		sc.Cache = &serve.Cache{
 			NumCounters: 1000000000,
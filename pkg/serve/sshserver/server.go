@@ -8,9 +8,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/antgroup/hugescm/pkg/serve/database"
+	"github.com/antgroup/hugescm/pkg/serve/metrics"
+	"github.com/antgroup/hugescm/pkg/serve/ratelimit"
 	"github.com/antgroup/hugescm/pkg/serve/repo"
 	"github.com/antgroup/hugescm/pkg/serve/sshserver/rainbow"
 	"github.com/gliderlabs/ssh"
@@ -25,6 +29,7 @@ const (
 )
 
 //	ls-remote --reference=$REFNAME
+//	ls-remote --list [--prefix=$PREFIX] [--cursor=$CURSOR] [--limit=N]
 //	metadata --commit=$COMMIT [--depth=N] [--deepen-from|--deepen] [--batch]
 //	objects [--oid=$OID|--batch|--share]
 //	push --reference $REFNAME [--oid $OID|--batch-check]
@@ -37,21 +42,24 @@ type Server struct {
 	hub        repo.Repositories
 	serverName string
 	uniqueID   int64
+	limiter    *ratelimit.Limiter // nil when rate_limits is not set
+	metrics    *metrics.Server    // nil when metrics_listen is not set
 }
 
 func NewServer(sc *ServerConfig) (*Server, error) {
 	s := &Server{
 		ServerConfig: sc,
+		limiter:      ratelimit.NewLimiter(sc.RateLimits),
 		serverName:   sc.BannerVersion,
 	}
-	cfg, err := sc.DB.MakeConfig()
+	driver, dsn, err := sc.DB.DSN()
 	if err != nil {
 		return nil, err
 	}
-	if s.db, err = database.NewDB(cfg); err != nil {
+	if s.db, err = database.NewDB(driver, dsn); err != nil {
 		return nil, err
 	}
-	if s.hub, err = repo.NewRepositories(sc.Repositories, sc.ZetaOSS, sc.Cache, s.db); err != nil {
+	if s.hub, err = repo.NewRepositories(sc.Repositories, sc.ZetaOSS, sc.Cache, sc.Hooks, sc.Webhooks, s.db); err != nil {
 		_ = s.db.Close()
 		return nil, err
 	}
@@ -82,6 +90,10 @@ func addHostKeyInternal(srv *ssh.Server, pemBytes []byte) {
 
 func (s *Server) ListenAndServe() error {
 	logrus.Infof("Zeta SSH Server listen: %v", s.Listen)
+	if len(s.MetricsListen) != 0 {
+		s.metrics = metrics.NewServer(s.MetricsListen)
+		s.metrics.Start()
+	}
 	return s.srv.ListenAndServe()
 }
 
@@ -111,6 +123,8 @@ func (s *Server) OnKey(ctx ssh.Context, key ssh.PublicKey) bool {
 }
 
 func (s *Server) OnSession(sess ssh.Session) {
+	metrics.SSHActiveSessions.Inc()
+	defer metrics.SSHActiveSessions.Dec()
 	se, err := s.NewSession(sess)
 	if err != nil {
 		fmt.Fprintf(sess.Stderr(), "bad ssh session")
@@ -178,10 +192,17 @@ func (s *Server) handleSession(e *Session) int {
 		e.WriteError("fatal: \x1b[31m%v\x1b[0m", err)
 		return 1
 	}
-	return cmd.Exec(&RunCtx{
+	e.heavy = isHeavy(cmd)
+	defer e.releaseLimits()
+	start := time.Now()
+	exitCode := cmd.Exec(&RunCtx{
 		S:       s,
 		Session: e,
 	})
+	// sub commands are registered in commandProvider, args[1] is one of them
+	metrics.SSHCommands.WithLabelValues(args[1], strconv.Itoa(exitCode)).Inc()
+	metrics.SSHCommandDuration.WithLabelValues(args[1]).Observe(time.Since(start).Seconds())
+	return exitCode
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
@@ -191,6 +212,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.srv.Shutdown(ctx); err != nil {
 		logrus.Errorf("shutdown ssh server %v", err)
 	}
+	if err := s.metrics.Shutdown(ctx); err != nil {
+		logrus.Errorf("shutdown metrics server %v", err)
+	}
+	if s.hub != nil {
+		_ = s.hub.Close()
+	}
 	if s.db != nil {
 		s.db.Close()
 	}
@@ -73,6 +73,7 @@ type request struct {
 	DefaultBranch   string
 	CompressionAlgo string
 	HashAlgo        string
+	AccessLevel     database.AccessLevel // access level of the user to the repository
 }
 
 type Session struct {
@@ -83,6 +84,16 @@ type Session struct {
 	written  int64
 	received int64
 	start    time.Time
+	heavy    bool   // heavy command, limited by the concurrency quotas
+	release  func() // release the slots taken by enterLimits
+}
+
+// clientIP: the IP address of the client, recorded in the audit log
+func (e *Session) clientIP() string {
+	if e.RemoteAddress == nil {
+		return ""
+	}
+	return e.RemoteAddress.IP
 }
 
 func (s *Server) NewSession(se ssh.Session) (*Session, error) {
@@ -133,6 +144,12 @@ func (e *Session) Getenv(k string) string {
 	return e.env[k]
 }
 
+func (e *Session) releaseLimits() {
+	if e.release != nil {
+		e.release()
+	}
+}
+
 // Read reads up to len(data) bytes from the channel.
 func (e *Session) Read(data []byte) (int, error) {
 	n, err := e.Session.Read(data)
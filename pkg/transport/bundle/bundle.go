@@ -0,0 +1,208 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundle: read-only transport of bundle files, 'bundle:///path/to/repo.zb'.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+var (
+	ErrReadOnly = errors.New("bundle is read-only")
+)
+
+type client struct {
+	*transport.Endpoint
+	b       *bundle.Bundle
+	verbose bool
+}
+
+var (
+	_ transport.Transport = &client{}
+	_ transport.Partial   = &client{}
+)
+
+func NewTransport(ctx context.Context, endpoint *transport.Endpoint, operation transport.Operation, verbose bool) (transport.Transport, error) {
+	if operation != transport.DOWNLOAD {
+		return nil, ErrReadOnly
+	}
+	p := endpoint.Path
+	if runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		// bundle:///C:/path/to/repo.zb
+		p = p[1:]
+	}
+	b, err := bundle.Open(filepath.FromSlash(p))
+	if err != nil {
+		return nil, err
+	}
+	c := &client{Endpoint: endpoint, b: b, verbose: verbose}
+	c.DbgPrint("bundle: %s references: %d metadata: %d blobs: %d", p, len(b.References), b.Metadata, b.Blobs)
+	return c, nil
+}
+
+func (c *client) DbgPrint(format string, args ...any) {
+	if !c.verbose {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	var buffer bytes.Buffer
+	for _, s := range strings.Split(message, "\n") {
+		_, _ = buffer.WriteString("\x1b[38;2;254;225;64m* ")
+		_, _ = buffer.WriteString(s)
+		_, _ = buffer.WriteString("\x1b[0m\n")
+	}
+	_, _ = os.Stderr.Write(buffer.Bytes())
+}
+
+// referenceName: names without 'refs/' prefix are branches.
+func referenceName(refname plumbing.ReferenceName) plumbing.ReferenceName {
+	if refname == plumbing.HEAD || strings.HasPrefix(string(refname), plumbing.ReferencePrefix) {
+		return refname
+	}
+	return plumbing.NewBranchReferenceName(string(refname))
+}
+
+// FetchReference: references recorded in the bundle header
+func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceName) (*transport.Reference, error) {
+	ref, ok := c.b.Reference(referenceName(refname))
+	if !ok {
+		return nil, transport.ErrReferenceNotExist
+	}
+	return &transport.Reference{
+		Remote:          c.String(),
+		Name:            ref.Name,
+		Hash:            ref.Hash,
+		HEAD:            string(c.b.HEAD),
+		Version:         int(bundle.Version),
+		Agent:           c.b.Agent,
+		HashAlgo:        c.b.HashALGO,
+		CompressionALGO: c.b.CompressionALGO,
+		Capabilities:    transport.Capabilities{transport.CapabilityLsRefs},
+	}, nil
+}
+
+// ListReferences: list references of the bundle ordered by name, paginated like 'zeta-serve ls-remote --list'.
+func (c *client) ListReferences(ctx context.Context, opts *transport.ListReferencesOptions) (*transport.References, error) {
+	refs := make([]*bundle.Reference, 0, len(c.b.References))
+	for _, r := range c.b.References {
+		if strings.HasPrefix(string(r.Name), opts.Prefix) && string(r.Name) > opts.Cursor {
+			refs = append(refs, r)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	result := &transport.References{
+		Remote:     c.String(),
+		HEAD:       string(c.b.HEAD),
+		References: make([]*transport.ReferenceEntry, 0, len(refs)),
+	}
+	for _, r := range refs {
+		if opts.Limit > 0 && len(result.References) == opts.Limit {
+			result.NextCursor = string(result.References[opts.Limit-1].Name)
+			break
+		}
+		result.References = append(result.References, &transport.ReferenceEntry{Name: r.Name, Hash: r.Hash})
+	}
+	return result, nil
+}
+
+// session: the bundle is read directly, there is no remote error.
+type session struct {
+	io.Reader
+	closer func() error
+}
+
+func (s *session) Close() error {
+	if s.closer != nil {
+		return s.closer()
+	}
+	return nil
+}
+
+func (s *session) LastError() error {
+	return nil
+}
+
+// FetchMetadata: the bundle contains one metadata stream, the options are decided when the bundle was created.
+func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *transport.MetadataOptions) (transport.SessionReader, error) {
+	return &session{Reader: c.b.MetadataReader()}, nil
+}
+
+// BatchObjects: blobs that are not in the bundle are skipped, they are reported missing by the caller.
+func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(c.b.WriteBatch(ctx, pw, oids))
+	}()
+	return &session{Reader: pr, closer: pr.Close}, nil
+}
+
+type sizeReader struct {
+	io.Reader
+	offset int64
+	size   int64
+}
+
+func (sr *sizeReader) Close() error {
+	return nil
+}
+
+func (sr *sizeReader) Offset() int64 {
+	return sr.offset
+}
+
+func (sr *sizeReader) Size() int64 {
+	return sr.size
+}
+
+func (sr *sizeReader) LastError() error {
+	return nil
+}
+
+// GetObject: get large object from the bundle, support Range feature
+func (c *client) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int64) (transport.SizeReader, error) {
+	e, err := c.b.Lookup(oid)
+	if err != nil {
+		return nil, err
+	}
+	if fromByte < 0 || fromByte > e.Size {
+		return nil, fmt.Errorf("offset %d out of range, object size %d", fromByte, e.Size)
+	}
+	return &sizeReader{Reader: c.b.Open(e, fromByte), offset: fromByte, size: e.Size}, nil
+}
+
+// HasObject: blobs larger than the size limit of the bundle are not included
+func (c *client) HasObject(oid plumbing.Hash) bool {
+	_, err := c.b.Lookup(oid)
+	return err == nil
+}
+
+// Shared: bundle has no accelerator, objects are read directly.
+func (c *client) Shared(ctx context.Context, wantObjects []*transport.WantObject) ([]*transport.Representation, error) {
+	return nil, errors.New("bundle transport does not support shared links")
+}
+
+func (c *client) Push(ctx context.Context, r io.Reader, cmd *transport.Command) (transport.SessionReader, error) {
+	return nil, ErrReadOnly
+}
+
+func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) ([]*transport.HaveObject, error) {
+	return nil, ErrReadOnly
+}
+
+func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error {
+	return ErrReadOnly
+}
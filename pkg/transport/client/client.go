@@ -8,7 +8,9 @@ import (
 	"fmt"
 
 	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/bundle"
 	"github.com/antgroup/hugescm/pkg/transport/http"
+	"github.com/antgroup/hugescm/pkg/transport/local"
 	"github.com/antgroup/hugescm/pkg/transport/ssh"
 )
 
@@ -18,6 +20,10 @@ func NewTransport(ctx context.Context, endpoint *transport.Endpoint, operation t
 		return http.NewTransport(ctx, endpoint, operation, verbose)
 	case "ssh":
 		return ssh.NewTransport(ctx, endpoint, operation, verbose)
+	case "file":
+		return local.NewTransport(ctx, endpoint, operation, verbose)
+	case "bundle":
+		return bundle.NewTransport(ctx, endpoint, operation, verbose)
 	}
 	return nil, fmt.Errorf("unsupport protocol '%s'", endpoint.Protocol)
 }
@@ -0,0 +1,253 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/command"
+)
+
+const (
+	// DefaultCacheTimeout: seconds to cache credentials, same as git-credential-cache
+	DefaultCacheTimeout = 900
+	ActionExit          = "exit"
+)
+
+// DefaultCacheSocket: $XDG_CACHE_HOME/zeta/credential/socket
+func DefaultCacheSocket() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "zeta", "credential", "socket")
+}
+
+type cacheRequest struct {
+	Action     string      `json:"action"`
+	Timeout    int         `json:"timeout,omitempty"`
+	Credential *Credential `json:"credential,omitempty"`
+}
+
+type cacheResponse struct {
+	Credential *Credential `json:"credential,omitempty"`
+}
+
+func cacheDial(socket string) (net.Conn, error) {
+	return net.DialTimeout("unix", socket, 2*time.Second)
+}
+
+func spawnCacheDaemon(socket string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := command.NewFromOptions(context.Background(), &command.RunOpts{
+		Environ:  os.Environ(),
+		Detached: true,
+	}, exe, "credential-cache--daemon", socket)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	for range 40 {
+		time.Sleep(50 * time.Millisecond)
+		if conn, err := cacheDial(socket); err == nil {
+			_ = conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("credential cache daemon not ready on '%s'", socket)
+}
+
+// CacheDo: send the action to the cache daemon, the daemon is started on demand when storing.
+func CacheDo(socket, action string, timeout int, c *Credential) (*Credential, error) {
+	conn, err := cacheDial(socket)
+	if err != nil {
+		if action != ActionStore {
+			// no daemon, nothing cached
+			return nil, nil
+		}
+		if err := spawnCacheDaemon(socket); err != nil {
+			return nil, err
+		}
+		if conn, err = cacheDial(socket); err != nil {
+			return nil, err
+		}
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := json.NewEncoder(conn).Encode(&cacheRequest{Action: action, Timeout: timeout, Credential: c}); err != nil {
+		return nil, err
+	}
+	var resp cacheResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return resp.Credential, nil
+}
+
+type cacheEntry struct {
+	*Credential
+	expiresAt time.Time
+}
+
+// CacheDaemon: keeps credentials in memory, exits when the cache becomes empty.
+type CacheDaemon struct {
+	socket   string
+	listener net.Listener
+	entries  []*cacheEntry
+	mu       sync.Mutex
+}
+
+func NewCacheDaemon(socket string) (*CacheDaemon, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := cacheDial(socket); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("credential cache daemon is already running on '%s'", socket)
+	}
+	// stale socket
+	_ = os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Chmod(socket, 0600)
+	return &CacheDaemon{socket: socket, listener: listener}, nil
+}
+
+func (d *CacheDaemon) Close() error {
+	err := d.listener.Close()
+	_ = os.Remove(d.socket)
+	return err
+}
+
+// prune: remove expired entries, return number of the remaining entries
+func (d *CacheDaemon) prune(now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entries := d.entries[:0]
+	for _, e := range d.entries {
+		if now.Before(e.expiresAt) {
+			entries = append(entries, e)
+		}
+	}
+	d.entries = entries
+	return len(d.entries)
+}
+
+func (d *CacheDaemon) handle(req *cacheRequest) (*cacheResponse, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	switch req.Action {
+	case ActionGet:
+		for _, e := range d.entries {
+			if req.Credential != nil && req.Credential.Match(e.Credential) && now.Before(e.expiresAt) {
+				return &cacheResponse{Credential: e.Credential.Clone()}, false
+			}
+		}
+	case ActionStore:
+		if req.Credential == nil || !req.Credential.Complete() {
+			break
+		}
+		timeout := req.Timeout
+		if timeout <= 0 {
+			timeout = DefaultCacheTimeout
+		}
+		expiresAt := now.Add(time.Duration(timeout) * time.Second)
+		if req.Credential.PasswordExpiry > 0 {
+			// the password expires before the timeout
+			if passwordExpiresAt := time.Unix(req.Credential.PasswordExpiry, 0); passwordExpiresAt.Before(expiresAt) {
+				expiresAt = passwordExpiresAt
+			}
+		}
+		entries := d.entries[:0]
+		for _, e := range d.entries {
+			if !req.Credential.Match(e.Credential) {
+				entries = append(entries, e)
+			}
+		}
+		d.entries = append(entries, &cacheEntry{Credential: req.Credential.Clone(), expiresAt: expiresAt})
+	case ActionErase:
+		if req.Credential == nil {
+			break
+		}
+		entries := d.entries[:0]
+		for _, e := range d.entries {
+			if !req.Credential.Match(e.Credential) {
+				entries = append(entries, e)
+			}
+		}
+		d.entries = entries
+	case ActionExit:
+		return &cacheResponse{}, true
+	}
+	return &cacheResponse{}, false
+}
+
+func (d *CacheDaemon) serveConn(conn net.Conn) bool {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	var req cacheRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return false
+	}
+	resp, exit := d.handle(&req)
+	_ = json.NewEncoder(conn).Encode(resp)
+	return exit
+}
+
+// Serve: serve requests until exit is requested or all cached credentials expire.
+func (d *CacheDaemon) Serve(ctx context.Context) error {
+	defer d.Close()
+	conns := make(chan net.Conn)
+	acceptErr := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := d.listener.Accept()
+			if err != nil {
+				acceptErr <- err
+				return
+			}
+			conns <- conn
+		}
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	idleDeadline := time.Now().Add(30 * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-acceptErr:
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		case conn := <-conns:
+			if d.serveConn(conn) {
+				return nil
+			}
+		case now := <-ticker.C:
+			// started on demand, exits when there is nothing left to cache
+			if d.prune(now) == 0 && now.After(idleDeadline) {
+				return nil
+			}
+		}
+		if d.prune(time.Now()) != 0 {
+			idleDeadline = time.Now().Add(30 * time.Second)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package credential
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Credential: git-credential compatible attributes, see https://git-scm.com/docs/git-credential#IOFMT
+type Credential struct {
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Path     string `json:"path,omitempty"`
+	UserName string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// PasswordExpiry: password_expiry_utc, unix timestamp, zero means the password never expires
+	PasswordExpiry int64 `json:"password_expiry_utc,omitempty"`
+	// Quit: helper asks to stop, no further helpers are consulted
+	Quit bool `json:"-"`
+}
+
+// FromURL: credential description of the URL, the user of the URL becomes the username.
+func FromURL(u *url.URL) *Credential {
+	c := &Credential{
+		Protocol: u.Scheme,
+		Host:     u.Host,
+		Path:     strings.TrimPrefix(u.Path, "/"),
+	}
+	if u.User != nil {
+		c.UserName = u.User.Username()
+	}
+	return c
+}
+
+// Key: protocol://host/path
+func (c *Credential) Key() string {
+	return c.Protocol + "://" + c.Host + "/" + c.Path
+}
+
+// Complete: username and password are both present
+func (c *Credential) Complete() bool {
+	return len(c.UserName) != 0 && len(c.Password) != 0
+}
+
+// Expired: returned password_expiry_utc is in the past
+func (c *Credential) Expired() bool {
+	return c.PasswordExpiry > 0 && time.Now().Unix() >= c.PasswordExpiry
+}
+
+// Match: c describes o, empty attributes of c match anything.
+func (c *Credential) Match(o *Credential) bool {
+	return c.Protocol == o.Protocol &&
+		strings.EqualFold(c.Host, o.Host) &&
+		c.Path == o.Path &&
+		(len(c.UserName) == 0 || c.UserName == o.UserName)
+}
+
+func (c *Credential) Clone() *Credential {
+	nc := *c
+	return &nc
+}
+
+// ErrBadAttribute: the attribute contains a newline or NUL, which would inject attributes into the helper input,
+// eg: a URL path 'repo%0Ahost=other.example' would ask helpers for the credentials of other.example.
+type ErrBadAttribute struct {
+	Key string
+}
+
+func (e *ErrBadAttribute) Error() string {
+	return fmt.Sprintf("credential attribute '%s' contains newline or NUL", e.Key)
+}
+
+func IsErrBadAttribute(err error) bool {
+	_, ok := err.(*ErrBadAttribute)
+	return ok
+}
+
+func badAttribute(s string) bool {
+	return strings.ContainsAny(s, "\n\x00")
+}
+
+// Encode: write attributes as 'key=value' lines followed by a blank line, values containing newline or NUL are
+// rejected.
+func (c *Credential) Encode(w io.Writer) error {
+	var b strings.Builder
+	for _, a := range []struct {
+		key, value string
+	}{
+		{"protocol", c.Protocol},
+		{"host", c.Host},
+		{"path", c.Path},
+		{"username", c.UserName},
+		{"password", c.Password},
+	} {
+		if len(a.value) == 0 {
+			continue
+		}
+		if badAttribute(a.value) {
+			return &ErrBadAttribute{Key: a.key}
+		}
+		fmt.Fprintf(&b, "%s=%s\n", a.key, a.value)
+	}
+	if c.PasswordExpiry > 0 {
+		fmt.Fprintf(&b, "password_expiry_utc=%d\n", c.PasswordExpiry)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Decode: read 'key=value' lines until a blank line or EOF, unknown attributes are ignored, keys containing NUL
+// are rejected.
+func Decode(r io.Reader) (*Credential, error) {
+	c := &Credential{}
+	br := bufio.NewScanner(r)
+	for br.Scan() {
+		line := strings.TrimSuffix(br.Text(), "\r")
+		if len(line) == 0 {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad credential line '%s'", line)
+		}
+		if badAttribute(key) {
+			return nil, &ErrBadAttribute{Key: strings.ReplaceAll(key, "\x00", "\\0")}
+		}
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.UserName = value
+		case "password":
+			c.Password = value
+		case "password_expiry_utc":
+			expiry, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad password_expiry_utc '%s'", value)
+			}
+			c.PasswordExpiry = expiry
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad credential url '%s': %w", value, err)
+			}
+			uc := FromURL(u)
+			c.Protocol, c.Host, c.Path = uc.Protocol, uc.Host, uc.Path
+			if len(uc.UserName) != 0 {
+				c.UserName = uc.UserName
+			}
+		case "quit":
+			c.Quit = value == "1" || strings.EqualFold(value, "true")
+		}
+	}
+	if err := br.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
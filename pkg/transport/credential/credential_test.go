@@ -0,0 +1,126 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	c := &Credential{Protocol: "https", Host: "zeta.io", UserName: "bob", Password: "secret", PasswordExpiry: 1893456000}
+	var b bytes.Buffer
+	require.NoError(t, c.Encode(&b))
+	require.Equal(t, "protocol=https\nhost=zeta.io\nusername=bob\npassword=secret\npassword_expiry_utc=1893456000\n\n", b.String())
+	dc, err := Decode(&b)
+	require.NoError(t, err)
+	require.Equal(t, c, dc)
+
+	dc, err = Decode(strings.NewReader("url=https://alice@zeta.io/group/repo\nquit=1\n"))
+	require.NoError(t, err)
+	require.Equal(t, &Credential{Protocol: "https", Host: "zeta.io", Path: "group/repo", UserName: "alice", Quit: true}, dc)
+
+	_, err = Decode(strings.NewReader("bad line\n"))
+	require.Error(t, err)
+}
+
+func TestEncodeInjection(t *testing.T) {
+	u, err := url.Parse("https://evil.example/repo%0Ahost=good.example")
+	require.NoError(t, err)
+	c := FromURL(u)
+	require.Equal(t, "repo\nhost=good.example", c.Path)
+	var b bytes.Buffer
+	err = c.Encode(&b)
+	require.True(t, IsErrBadAttribute(err))
+	require.Zero(t, b.Len())
+
+	c = &Credential{Protocol: "https", Host: "zeta.io", Password: "secret\x00"}
+	require.True(t, IsErrBadAttribute(c.Encode(&b)))
+
+	// url attribute of helpers is decoded the same way
+	dc, err := Decode(strings.NewReader("url=https://evil.example/repo%0Ahost=good.example\n"))
+	require.NoError(t, err)
+	require.True(t, IsErrBadAttribute(dc.Encode(&b)))
+
+	_, err = Decode(strings.NewReader("ho\x00st=good.example\n"))
+	require.True(t, IsErrBadAttribute(err))
+}
+
+func TestHelperCommand(t *testing.T) {
+	name, args, err := helperCommand("!sso-token --scope zeta", ActionGet)
+	require.NoError(t, err)
+	require.Equal(t, "sh", name)
+	require.Equal(t, []string{"-c", "sso-token --scope zeta get"}, args)
+
+	name, args, err = helperCommand("foo --bar", ActionStore)
+	require.NoError(t, err)
+	require.Equal(t, "zeta-credential-foo", name)
+	require.Equal(t, []string{"--bar", "store"}, args)
+
+	_, args, err = helperCommand("cache --timeout=60", ActionErase)
+	require.NoError(t, err)
+	require.Equal(t, []string{"credential-cache", "--timeout=60", "erase"}, args)
+}
+
+func TestHelpersFill(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Unix()
+	h := Helpers{
+		"!f() { echo quit=0; }; f",
+		"!f() { echo username=bob; echo password=token; echo password_expiry_utc=" + strconv.FormatInt(expiry, 10) + "; }; f",
+	}
+	c, err := h.Fill(context.Background(), &Credential{Protocol: "https", Host: "fill.zeta.io"})
+	require.NoError(t, err)
+	require.Equal(t, "bob", c.UserName)
+	require.Equal(t, "token", c.Password)
+	require.Equal(t, expiry, c.PasswordExpiry)
+	// cached until expiry, helpers are not called again
+	c, err = Helpers{"!false"}.Fill(context.Background(), &Credential{Protocol: "https", Host: "fill.zeta.io"})
+	require.NoError(t, err)
+	require.Equal(t, "token", c.Password)
+	Helpers{}.Reject(context.Background(), c)
+	_, err = Helpers{"!true"}.Fill(context.Background(), &Credential{Protocol: "https", Host: "fill.zeta.io"})
+	require.Error(t, err)
+}
+
+func TestCacheDaemon(t *testing.T) {
+	dir, err := os.MkdirTemp("", "zc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "socket")
+	d, err := NewCacheDaemon(socket)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Serve(context.Background())
+	}()
+	c := &Credential{Protocol: "https", Host: "zeta.io", UserName: "bob", Password: "secret"}
+	_, err = CacheDo(socket, ActionStore, 60, c)
+	require.NoError(t, err)
+	got, err := CacheDo(socket, ActionGet, 0, &Credential{Protocol: "https", Host: "zeta.io"})
+	require.NoError(t, err)
+	require.Equal(t, c, got)
+	got, err = CacheDo(socket, ActionGet, 0, &Credential{Protocol: "https", Host: "zeta.io", UserName: "alice"})
+	require.NoError(t, err)
+	require.Nil(t, got)
+	// password expires before the timeout
+	_, err = CacheDo(socket, ActionStore, 60, &Credential{Protocol: "https", Host: "old.zeta.io", UserName: "bob", Password: "x", PasswordExpiry: time.Now().Unix() - 1})
+	require.NoError(t, err)
+	got, err = CacheDo(socket, ActionGet, 0, &Credential{Protocol: "https", Host: "old.zeta.io"})
+	require.NoError(t, err)
+	require.Nil(t, got)
+	_, err = CacheDo(socket, ActionErase, 0, &Credential{Protocol: "https", Host: "zeta.io"})
+	require.NoError(t, err)
+	got, err = CacheDo(socket, ActionGet, 0, &Credential{Protocol: "https", Host: "zeta.io"})
+	require.NoError(t, err)
+	require.Nil(t, got)
+	_, err = CacheDo(socket, ActionExit, 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+}
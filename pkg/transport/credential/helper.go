@@ -0,0 +1,173 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package credential
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/antgroup/hugescm/modules/command"
+	"github.com/antgroup/hugescm/modules/shlex"
+)
+
+const (
+	ActionGet   = "get"
+	ActionStore = "store"
+	ActionErase = "erase"
+)
+
+var (
+	// filled: credentials returned by helpers are reused by the process until they expire,
+	// tokens minted by SSO helpers are not requested again for every connection.
+	filled   = make(map[string]*Credential)
+	filledMu sync.Mutex
+)
+
+func lookupFilled(c *Credential) (*Credential, bool) {
+	filledMu.Lock()
+	defer filledMu.Unlock()
+	fc, ok := filled[c.Key()]
+	if !ok {
+		return nil, false
+	}
+	if fc.Expired() || (len(c.UserName) != 0 && c.UserName != fc.UserName) {
+		delete(filled, c.Key())
+		return nil, false
+	}
+	return fc.Clone(), true
+}
+
+func updateFilled(c *Credential) {
+	filledMu.Lock()
+	defer filledMu.Unlock()
+	filled[c.Key()] = c.Clone()
+}
+
+func removeFilled(c *Credential) {
+	filledMu.Lock()
+	defer filledMu.Unlock()
+	delete(filled, c.Key())
+}
+
+// Helpers: credential.helper values, like git:
+//
+//	cache --timeout=900  -->  zeta credential-cache --timeout=900 <action>
+//	foo --bar            -->  zeta-credential-foo --bar <action>
+//	/path/to/helper      -->  /path/to/helper <action>
+//	!sso-token --scope   -->  sh -c 'sso-token --scope <action>'
+type Helpers []string
+
+// Fill: ask helpers for username and password, the first complete and unexpired answer wins.
+func (h Helpers) Fill(ctx context.Context, c *Credential) (*Credential, error) {
+	if len(h) == 0 {
+		return nil, os.ErrNotExist
+	}
+	// like git, refuse the URL instead of trying the next helper
+	if err := c.Encode(io.Discard); err != nil {
+		return nil, err
+	}
+	if fc, ok := lookupFilled(c); ok {
+		return fc, nil
+	}
+	for _, helper := range h {
+		answer, err := runHelper(ctx, helper, ActionGet, c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "credential helper '%s' get error: %v\n", helper, err)
+			continue
+		}
+		if answer.Quit {
+			return nil, fmt.Errorf("credential helper '%s' told us to quit", helper)
+		}
+		if len(answer.UserName) == 0 {
+			answer.UserName = c.UserName
+		}
+		if !answer.Complete() || answer.Expired() {
+			continue
+		}
+		fc := c.Clone()
+		fc.UserName, fc.Password, fc.PasswordExpiry = answer.UserName, answer.Password, answer.PasswordExpiry
+		updateFilled(fc)
+		return fc, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// Approve: the credential was accepted by the remote, ask helpers to store it.
+func (h Helpers) Approve(ctx context.Context, c *Credential) {
+	if !c.Complete() || c.Expired() {
+		return
+	}
+	updateFilled(c)
+	for _, helper := range h {
+		if _, err := runHelper(ctx, helper, ActionStore, c); err != nil {
+			fmt.Fprintf(os.Stderr, "credential helper '%s' store error: %v\n", helper, err)
+		}
+	}
+}
+
+// Reject: the credential was rejected by the remote, ask helpers to erase it.
+func (h Helpers) Reject(ctx context.Context, c *Credential) {
+	removeFilled(c)
+	for _, helper := range h {
+		if _, err := runHelper(ctx, helper, ActionErase, c); err != nil {
+			fmt.Fprintf(os.Stderr, "credential helper '%s' erase error: %v\n", helper, err)
+		}
+	}
+}
+
+func helperCommand(helper, action string) (string, []string, error) {
+	if shellCmd, ok := strings.CutPrefix(helper, "!"); ok {
+		return "sh", []string{"-c", shellCmd + " " + action}, nil
+	}
+	cmdArgs, err := shlex.Split(helper, true)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(cmdArgs) == 0 {
+		return "", nil, fmt.Errorf("empty credential helper")
+	}
+	args := append(cmdArgs[1:], action)
+	if filepath.IsAbs(cmdArgs[0]) {
+		return cmdArgs[0], args, nil
+	}
+	if cmdArgs[0] == "cache" {
+		exe, err := os.Executable()
+		if err != nil {
+			return "", nil, err
+		}
+		return exe, append([]string{"credential-cache"}, args...), nil
+	}
+	return "zeta-credential-" + cmdArgs[0], args, nil
+}
+
+func runHelper(ctx context.Context, helper, action string, c *Credential) (*Credential, error) {
+	name, args, err := helperCommand(helper, action)
+	if err != nil {
+		return nil, err
+	}
+	var stdin, stdout bytes.Buffer
+	if err := c.Encode(&stdin); err != nil {
+		return nil, err
+	}
+	cmd := command.NewFromOptions(ctx, &command.RunOpts{
+		Environ:   os.Environ(),
+		Stdin:     &stdin,
+		Stdout:    &stdout,
+		Stderr:    os.Stderr,
+		NoSetpgid: true,
+	}, name, args...)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	if action != ActionGet {
+		return nil, nil
+	}
+	return Decode(&stdout)
+}
@@ -7,10 +7,13 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
 )
 
 var (
@@ -87,6 +90,45 @@ func parseSCPLike(endpoint string) (*Endpoint, bool) {
 	}, true
 }
 
+func parseFile(endpoint string) (*Endpoint, bool) {
+	if len(endpoint) == 0 || MatchesScheme(endpoint) {
+		return nil, false
+	}
+	path, err := filepath.Abs(strengthen.ExpandPath(endpoint))
+	if err != nil {
+		return nil, false
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// windows: C:/path/to/repo --> file:///C:/path/to/repo
+		path = "/" + path
+	}
+	return &Endpoint{
+		Protocol: "file",
+		Path:     path,
+	}, true
+}
+
+// parseBundle: bundle://path/to/file, relative paths are resolved from the current directory.
+func parseBundle(endpoint string) (*Endpoint, bool) {
+	p, ok := strings.CutPrefix(endpoint, "bundle://")
+	if !ok || len(p) == 0 {
+		return nil, false
+	}
+	path, err := filepath.Abs(strengthen.ExpandPath(p))
+	if err != nil {
+		return nil, false
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return &Endpoint{
+		Protocol: "bundle",
+		Path:     path,
+	}, true
+}
+
 // Endpoint represents a Git URL in any supported protocol.
 type Endpoint struct {
 	// Protocol is the protocol of the endpoint (e.g. git, https, file).
@@ -108,11 +150,14 @@ type Endpoint struct {
 	InsecureSkipTLS bool
 	// ExtraHeader extra header
 	ExtraHeader map[string]string
+	// CredentialHelpers credential helpers of the URL, only http/https
+	CredentialHelpers []string
 }
 
 type Options struct {
-	InsecureSkipTLS bool
-	ExtraHeader     []string
+	InsecureSkipTLS   bool
+	ExtraHeader       []string
+	CredentialHelpers []string
 }
 
 func (opts *Options) parseExtraHeader() map[string]string {
@@ -132,9 +177,13 @@ func NewEndpoint(endpoint string, opts *Options) (*Endpoint, error) {
 		return e, nil
 	}
 
-	// if e, ok := parseFile(endpoint); ok {
-	// 	return e, nil
-	// }
+	if e, ok := parseFile(endpoint); ok {
+		return e, nil
+	}
+
+	if e, ok := parseBundle(endpoint); ok {
+		return e, nil
+	}
 
 	return parseURL(endpoint, opts)
 }
@@ -200,6 +249,7 @@ func parseURL(endpoint string, opts *Options) (*Endpoint, error) {
 	if opts != nil {
 		e.InsecureSkipTLS = opts.InsecureSkipTLS
 		e.ExtraHeader = opts.parseExtraHeader()
+		e.CredentialHelpers = opts.CredentialHelpers
 	}
 	return e, nil
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/antgroup/hugescm/modules/keyring"
 	"github.com/antgroup/hugescm/modules/survey"
 	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/credential"
 	"github.com/antgroup/hugescm/pkg/version"
 )
 
@@ -34,6 +35,7 @@ var (
 type Credentials struct {
 	UserName string
 	Password string
+	expiry   int64 // password_expiry_utc returned by credential helpers
 }
 
 // See 2 (end of page 4) https://www.ietf.org/rfc/rfc2617.txt
@@ -86,9 +88,52 @@ func (c *client) readCredentials0(ctx context.Context) (*Credentials, error) {
 }
 
 func (c *client) storeCredentials(ctx context.Context, cred *Credentials) error {
+	if len(c.helpers) != 0 {
+		c.helpers.Approve(ctx, c.helperCredential(cred))
+		return nil
+	}
 	return keyring.Store(ctx, c.baseCredentailsURL(), &keyring.Cred{UserName: cred.UserName, Password: cred.Password})
 }
 
+// helperCredential: credential description passed to credential helpers
+func (c *client) helperCredential(cred *Credentials) *credential.Credential {
+	hc := credential.FromURL(c.baseURL)
+	hc.Path = "" // like git, credentials are scoped to the host unless a helper is configured for the URL
+	if cred != nil {
+		hc.UserName, hc.Password, hc.PasswordExpiry = cred.UserName, cred.Password, cred.expiry
+	}
+	return hc
+}
+
+// authorizeWithHelpers: credential helpers are tried before the keyring, accepted credentials are stored back to the helpers,
+// rejected credentials are erased.
+func (c *client) authorizeWithHelpers(ctx context.Context, operation transport.Operation) (bool, error) {
+	if len(c.helpers) == 0 {
+		return false, nil
+	}
+	if u := c.baseURL.User; u != nil {
+		if _, ok := u.Password(); ok {
+			return false, nil
+		}
+	}
+	hc, err := c.helpers.Fill(ctx, c.helperCredential(nil))
+	if err != nil {
+		return false, nil
+	}
+	cred := &Credentials{UserName: hc.UserName, Password: hc.Password, expiry: hc.PasswordExpiry}
+	ok, err := c.checkAuth(ctx, cred, operation)
+	if ok {
+		c.helpers.Approve(ctx, hc)
+		c.credentials = cred.BasicAuth()
+		return true, nil
+	}
+	if !checkUnauthorized(err) {
+		return false, err
+	}
+	c.helpers.Reject(ctx, hc)
+	return false, nil
+}
+
 func (c *client) credentialAskOne() (*Credentials, error) {
 	if !env.ZETA_TERMINAL_PROMPT.SimpleAtob(true) {
 		return nil, errors.New("terminal prompts disabled")
@@ -124,6 +169,9 @@ func (c *client) readCredentials(ctx context.Context) (*Credentials, error) {
 }
 
 func (c *client) authorize(ctx context.Context, operation transport.Operation) error {
+	if ok, err := c.authorizeWithHelpers(ctx, operation); ok || err != nil {
+		return err
+	}
 	cred, err := c.readCredentials(ctx)
 	if err == nil {
 		ok, err := c.checkAuth(ctx, cred, operation)
@@ -21,6 +21,7 @@ import (
 	"github.com/antgroup/hugescm/modules/streamio"
 	"github.com/antgroup/hugescm/pkg/tr"
 	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/credential"
 	"github.com/antgroup/hugescm/pkg/transport/proxy"
 	"github.com/antgroup/hugescm/pkg/version"
 )
@@ -38,6 +39,8 @@ const (
 	ZETA_COMPRESSED_SIZE    = "X-Zeta-Compressed-Size"
 	ZETA_PUSH_OPTION_COUNT  = "X-Zeta-Push-Option-Count"
 	ZETA_PUSH_OPTION_PREFIX = "X-Zeta-Push-Option-"
+	ZETA_UPLOAD_ID          = "X-Zeta-Upload-Id"
+	ZETA_UPLOAD_OFFSET      = "X-Zeta-Upload-Offset"
 	// ZETA Protocol Content Type
 	ZETA_MIME_BLOB              = "application/x-zeta-blob"
 	ZETA_MIME_BLOBS             = "application/x-zeta-blobs"
@@ -60,6 +63,7 @@ type client struct {
 	*http.Client
 	baseURL      *url.URL
 	extraHeader  map[string]string
+	helpers      credential.Helpers
 	credentials  string // User Credentials
 	tokenPayload *transport.SASPayload
 	userAgent    string
@@ -98,16 +102,18 @@ func newClient(ctx context.Context, endpoint *transport.Endpoint, operation tran
 	base := cloneURL(endpoint.Base)
 	c := &client{
 		Client: &http.Client{
-			Transport: &http.Transport{
-				Proxy:                 proxy.ProxyFromEnvironment,
-				DialContext:           dialer.DialContext,
-				ForceAttemptHTTP2:     true,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: endpoint.InsecureSkipTLS,
+			Transport: &retryTransport{
+				base: &http.Transport{
+					Proxy:                 proxy.ProxyFromEnvironment,
+					DialContext:           dialer.DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       90 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+					TLSClientConfig: &tls.Config{
+						InsecureSkipVerify: endpoint.InsecureSkipTLS,
+					},
 				},
 			},
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -116,6 +122,7 @@ func newClient(ctx context.Context, endpoint *transport.Endpoint, operation tran
 		},
 		baseURL:     base,
 		extraHeader: endpoint.ExtraHeader,
+		helpers:     endpoint.CredentialHelpers,
 		userAgent:   "Zeta/" + version.GetVersion(),
 		language:    tr.Language(),
 		termEnv:     os.Getenv("TERM"),
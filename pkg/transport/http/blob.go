@@ -12,8 +12,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
@@ -21,6 +23,23 @@ import (
 )
 
 func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	return c.batchObjects(ctx, oids, nil)
+}
+
+// ResumableBatchObjects: POST /{namespace}/{repo}/objects/batch?resumable=1
+func (c *client) ResumableBatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	return c.batchObjects(ctx, oids, url.Values{"resumable": {"1"}})
+}
+
+// WantHaveObjects: POST /{namespace}/{repo}/objects/batch?want=${want}&have=${have}&size-limit=N
+func (c *client) WantHaveObjects(ctx context.Context, want, have plumbing.Hash, sizeLimit int64) (transport.SessionReader, error) {
+	u := c.baseURL.JoinPath("objects", "batch")
+	u.RawQuery = url.Values{"want": {want.String()}, "have": {have.String()}, "size-limit": {strconv.FormatInt(sizeLimit, 10)}}.Encode()
+	// no object in the body, the request can be sent again when the bitmap index of the remote is not ready
+	return c.postBatch(ctx, u.String(), strings.NewReader("\n"))
+}
+
+func (c *client) batchObjects(ctx context.Context, oids []plumbing.Hash, query url.Values) (transport.SessionReader, error) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	pr, pw := io.Pipe()
@@ -36,8 +55,13 @@ func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transp
 		_ = buf.WriteByte('\n')
 	}()
 
-	batchURL := c.baseURL.JoinPath("objects", "batch").String()
-	req, err := c.newRequest(ctx, "POST", batchURL, pr)
+	u := c.baseURL.JoinPath("objects", "batch")
+	u.RawQuery = query.Encode()
+	return c.postBatch(ctx, u.String(), pr)
+}
+
+func (c *client) postBatch(ctx context.Context, u string, body io.Reader) (transport.SessionReader, error) {
+	req, err := c.newRequest(ctx, "POST", u, body)
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +50,72 @@ func (c *client) Push(ctx context.Context, r io.Reader, cmd *transport.Command)
 	}, nil
 }
 
+// PushAtomic: POST {baseURL}/references
+func (c *client) PushAtomic(ctx context.Context, r io.Reader, cmd *transport.AtomicCommand) (transport.SessionReader, error) {
+	body, err := transport.NewAtomicReader(r, cmd)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "POST", c.baseURL.JoinPath("references").String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("new request error: %v", err)
+	}
+	req.Header.Set(ZETA_OBJECTS_STATS, fmt.Sprintf("m-%d;b-%d", cmd.Metadata, cmd.Objects))
+	req.Header.Set("Accept", ZETA_MIME_REPORT_RESULT)
+	if len(cmd.PushOptions) != 0 {
+		req.Header.Set(ZETA_PUSH_OPTION_COUNT, strconv.Itoa(len(cmd.PushOptions)))
+		for i, o := range cmd.PushOptions {
+			req.Header.Set(fmt.Sprintf("%s%d", ZETA_PUSH_OPTION_PREFIX, i), o)
+		}
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, parseError(resp)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != ZETA_MIME_REPORT_RESULT {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unsupported content-type: %s", contentType)
+	}
+	return &sessionReader{
+		Reader: resp.Body,
+		Closer: resp.Body,
+	}, nil
+}
+
+// PushBundle: POST /{namespace}/{repo}/bundle?force=1, the body is the bundle file
+func (c *client) PushBundle(ctx context.Context, r io.Reader, size int64, force bool) (transport.SessionReader, error) {
+	u := c.baseURL.JoinPath("bundle")
+	if force {
+		u.RawQuery = "force=1"
+	}
+	req, err := c.newRequest(ctx, "POST", u.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("new request error: %v", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Accept", ZETA_MIME_REPORT_RESULT)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, parseError(resp)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != ZETA_MIME_REPORT_RESULT {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unsupported content-type: %s", contentType)
+	}
+	return &sessionReader{
+		Reader: resp.Body,
+		Closer: resp.Body,
+	}, nil
+}
+
 func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) ([]*transport.HaveObject, error) {
 	c.DbgPrint("check %d large objects", len(haveObjects))
 	var b bytes.Buffer
@@ -98,3 +164,84 @@ func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName,
 	}
 	return nil
 }
+
+// newUploadRequest: {baseURL}/reference/{refname}/objects/{oid}/upload[/status|/finish]
+func (c *client) newUploadRequest(ctx context.Context, method string, refname plumbing.ReferenceName, us *transport.UploadSession, action string, body io.Reader) (*http.Request, error) {
+	uploadURL := c.baseURL.JoinPath("reference", string(refname), "objects", us.OID, "upload")
+	if len(action) != 0 {
+		uploadURL = uploadURL.JoinPath(action)
+	}
+	req, err := c.newRequest(ctx, method, uploadURL.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("new request error: %v", err)
+	}
+	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	req.Header.Set(ZETA_COMPRESSED_SIZE, strconv.FormatInt(us.Size, 10))
+	if len(us.ID) != 0 {
+		req.Header.Set(ZETA_UPLOAD_ID, us.ID)
+	}
+	return req, nil
+}
+
+func (c *client) doUpload(req *http.Request) (*transport.UploadSession, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && len(req.Header.Get(ZETA_UPLOAD_ID)) != 0 {
+		return nil, transport.ErrUploadNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseError(resp)
+	}
+	var session transport.UploadSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *client) NewUpload(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, size int64) (*transport.UploadSession, error) {
+	req, err := c.newUploadRequest(ctx, "POST", refname, &transport.UploadSession{OID: oid.String(), Size: size}, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.doUpload(req)
+}
+
+func (c *client) UploadStatus(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) (*transport.UploadSession, error) {
+	req, err := c.newUploadRequest(ctx, "POST", refname, us, "status", nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.doUpload(req)
+}
+
+func (c *client) UploadChunk(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession, offset int64, r io.Reader, length int64) (*transport.UploadSession, error) {
+	req, err := c.newUploadRequest(ctx, "PUT", refname, us, "", io.LimitReader(r, length))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	req.Header.Set(ZETA_UPLOAD_OFFSET, strconv.FormatInt(offset, 10))
+	return c.doUpload(req)
+}
+
+func (c *client) FinishUpload(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) error {
+	req, err := c.newUploadRequest(ctx, "POST", refname, us, "finish", nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.doUpload(req)
+	return err
+}
+
+func (c *client) AbortUpload(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) error {
+	req, err := c.newUploadRequest(ctx, "DELETE", refname, us, "", nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.doUpload(req)
+	return err
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
 	"github.com/antgroup/hugescm/pkg/transport"
@@ -41,3 +42,36 @@ func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceN
 	}
 	return &ref, nil
 }
+
+func (c *client) ListReferences(ctx context.Context, opts *transport.ListReferencesOptions) (*transport.References, error) {
+	listURL := c.baseURL.JoinPath("references")
+	q := listURL.Query()
+	if len(opts.Prefix) != 0 {
+		q.Set("prefix", opts.Prefix)
+	}
+	if len(opts.Cursor) != 0 {
+		q.Set("cursor", opts.Cursor)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	listURL.RawQuery = q.Encode()
+	req, err := c.newRequest(ctx, "GET", listURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ZETA_MIME_JSON_METADATA)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseError(resp)
+	}
+	var refs transport.References
+	if err := json.NewDecoder(resp.Body).Decode(&refs); err != nil {
+		return nil, fmt.Errorf("decode references response error: %w", err)
+	}
+	return &refs, nil
+}
@@ -0,0 +1,99 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRetries    = 5
+	maxRetryAfter = 2 * time.Minute
+	maxBackoff    = 30 * time.Second
+)
+
+// retryTransport: retry requests limited by the server (429 Too Many Requests) after Retry-After, or with exponential
+// backoff when it is missing. 503 Service Unavailable is retried only with Retry-After, eg: the bitmap index is not
+// ready. Requests whose body can't be rewound are not retried.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// parseRetryAfter: Retry-After is delay-seconds or an HTTP-date
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if len(v) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryDelay: Retry-After with a little jitter so that limited clients don't come back at once
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		return d + time.Duration(rand.Int64N(int64(d/10)+1))
+	}
+	d := min(time.Second<<attempt, maxBackoff)
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+func retryable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		_, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return ok
+	}
+	return false
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || !retryable(resp) || attempt >= maxRetries {
+			return resp, err
+		}
+		delay := retryDelay(resp, attempt)
+		if delay > maxRetryAfter || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, nil
+		}
+		retryReq := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			retryReq.Body = body
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		message := W("rate limited by the server, retry after")
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			message = W("server is unavailable, retry after")
+		}
+		fmt.Fprintf(os.Stderr, "\x1b[33m%s %v\x1b[0m\n", message, delay.Round(time.Second))
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = retryReq
+	}
+}
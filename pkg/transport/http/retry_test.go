@@ -0,0 +1,95 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter("3", now)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, d)
+	d, ok = parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Equal(t, 10*time.Second, d)
+	_, ok = parseRetryAfter("soon", now)
+	require.False(t, ok)
+	_, ok = parseRetryAfter("", now)
+	require.False(t, ok)
+}
+
+func TestRetryTransport(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if requests.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: &retryTransport{base: http.DefaultTransport}}
+
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "hello", string(body))
+	require.Equal(t, int32(3), requests.Load())
+
+	// streaming bodies can't be sent again
+	requests.Store(0)
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("stream"))
+		_ = pw.Close()
+	}()
+	resp, err = c.Post(srv.URL, "text/plain", pr)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryTransportUnavailable(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			// eg: the bitmap index is not ready
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: &retryTransport{base: http.DefaultTransport}}
+
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("\n"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), requests.Load())
+
+	// 503 without Retry-After is not retried
+	resp, err = c.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, int32(3), requests.Load())
+}
@@ -0,0 +1,125 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/tr"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+var (
+	W = tr.W
+)
+
+var (
+	ErrUnsupportedShared = errors.New("local transport does not support shared links")
+)
+
+type client struct {
+	*transport.Endpoint
+	zetaDir string
+	// bare: the endpoint points to the '.zeta' directory itself, there is no worktree to protect.
+	bare    bool
+	verbose bool
+}
+
+// resolveRepository: the path is a worktree containing '.zeta', or the '.zeta' directory itself.
+func resolveRepository(p string) (zetaDir string, bare bool, err error) {
+	isZetaDir := func(dir string) bool {
+		if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+			return false
+		}
+		si, err := os.Stat(filepath.Join(dir, "metadata"))
+		return err == nil && si.IsDir()
+	}
+	if zetaDir = filepath.Join(p, ".zeta"); isZetaDir(zetaDir) {
+		return zetaDir, false, nil
+	}
+	if isZetaDir(p) {
+		return p, true, nil
+	}
+	return "", false, transport.ErrRepositoryNotFound
+}
+
+// IsRepository: returns true if the path looks like a local path and contains a zeta repository.
+// Bare names such as 'dev' are not treated as paths, they may be revisions.
+func IsRepository(p string) bool {
+	if !filepath.IsAbs(p) && !strings.HasPrefix(p, "./") && !strings.HasPrefix(p, "../") && !strings.HasPrefix(p, "~") &&
+		!strings.HasPrefix(p, "."+string(filepath.Separator)) && !strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return false
+	}
+	_, _, err := resolveRepository(strengthen.ExpandPath(p))
+	return err == nil
+}
+
+func NewTransport(ctx context.Context, endpoint *transport.Endpoint, operation transport.Operation, verbose bool) (transport.Transport, error) {
+	p := endpoint.Path
+	if len(p) == 0 {
+		return nil, fmt.Errorf("invalid local endpoint: %s", endpoint)
+	}
+	if runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		// file:///C:/path/to/repo
+		p = p[1:]
+	}
+	zetaDir, bare, err := resolveRepository(filepath.FromSlash(p))
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		Endpoint: endpoint,
+		zetaDir:  zetaDir,
+		bare:     bare,
+		verbose:  verbose,
+	}
+	c.DbgPrint("local repository: %s", zetaDir)
+	return c, nil
+}
+
+// open: objects database of the local repository, honoring its compression algorithm and sharing root.
+func (c *client) open() (*backend.Database, error) {
+	cfg, err := config.Load(c.zetaDir)
+	if err != nil {
+		return nil, err
+	}
+	opts := []backend.Option{backend.WithCompressionALGO(cfg.Core.CompressionALGO)}
+	if len(cfg.Core.SharingRoot) != 0 && filepath.IsAbs(cfg.Core.SharingRoot) {
+		opts = append(opts, backend.WithSharingRoot(cfg.Core.SharingRoot))
+	}
+	return backend.NewDatabase(c.zetaDir, opts...)
+}
+
+func (c *client) references() refs.Backend {
+	return refs.NewBackend(c.zetaDir)
+}
+
+var (
+	_ transport.Transport = &client{}
+)
+
+func (c *client) DbgPrint(format string, args ...any) {
+	if !c.verbose {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	var buffer bytes.Buffer
+	for _, s := range strings.Split(message, "\n") {
+		_, _ = buffer.WriteString("\x1b[38;2;254;225;64m* ")
+		_, _ = buffer.WriteString(s)
+		_, _ = buffer.WriteString("\x1b[0m\n")
+	}
+	_, _ = os.Stderr.Write(buffer.Bytes())
+}
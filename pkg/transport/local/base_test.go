@@ -0,0 +1,76 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRepository(t *testing.T) {
+	root := t.TempDir()
+	zetaDir := filepath.Join(root, "repo", ".zeta")
+	require.NoError(t, os.MkdirAll(filepath.Join(zetaDir, "metadata"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(zetaDir, "HEAD"), []byte("ref: refs/heads/mainline\n"), 0644))
+
+	dir, bare, err := resolveRepository(filepath.Join(root, "repo"))
+	require.NoError(t, err)
+	require.Equal(t, zetaDir, dir)
+	require.False(t, bare)
+
+	dir, bare, err = resolveRepository(zetaDir)
+	require.NoError(t, err)
+	require.Equal(t, zetaDir, dir)
+	require.True(t, bare)
+
+	_, _, err = resolveRepository(root)
+	require.ErrorIs(t, err, transport.ErrRepositoryNotFound)
+
+	require.True(t, IsRepository(filepath.Join(root, "repo")))
+	require.False(t, IsRepository("repo"))
+}
+
+func TestReferenceName(t *testing.T) {
+	require.Equal(t, plumbing.HEAD, referenceName(plumbing.HEAD))
+	require.Equal(t, plumbing.NewBranchReferenceName("dev"), referenceName("dev"))
+	require.Equal(t, plumbing.NewTagReferenceName("v1"), referenceName(plumbing.NewTagReferenceName("v1")))
+}
+
+func TestListReferences(t *testing.T) {
+	root := t.TempDir()
+	zetaDir := filepath.Join(root, "repo", ".zeta")
+	require.NoError(t, os.MkdirAll(filepath.Join(zetaDir, "refs", "heads", "feature"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(zetaDir, "HEAD"), []byte("ref: refs/heads/mainline\n"), 0644))
+	oid := "6a4c4c1d29b8ee2f4e2cc8b8cbb3be1ec1c1ad8e04de16b4a2e26aaf9ba4a5d5"
+	for _, name := range []string{"mainline", "dev", "feature/a", "feature/b"} {
+		require.NoError(t, os.WriteFile(filepath.Join(zetaDir, "refs", "heads", filepath.FromSlash(name)), []byte(oid+"\n"), 0644))
+	}
+	endpoint, err := transport.NewEndpoint(filepath.Join(root, "repo"), nil)
+	require.NoError(t, err)
+	c := &client{Endpoint: endpoint, zetaDir: zetaDir}
+
+	var names []plumbing.ReferenceName
+	opts := &transport.ListReferencesOptions{Limit: 3}
+	for {
+		refs, err := c.ListReferences(context.Background(), opts)
+		require.NoError(t, err)
+		require.Equal(t, "refs/heads/mainline", refs.HEAD)
+		for _, e := range refs.References {
+			names = append(names, e.Name)
+		}
+		if len(refs.NextCursor) == 0 {
+			break
+		}
+		opts.Cursor = refs.NextCursor
+	}
+	require.Equal(t, []plumbing.ReferenceName{"refs/heads/dev", "refs/heads/feature/a", "refs/heads/feature/b", "refs/heads/mainline"}, names)
+
+	refs, err := c.ListReferences(context.Background(), &transport.ListReferencesOptions{Prefix: "refs/heads/feature/"})
+	require.NoError(t, err)
+	require.Len(t, refs.References, 2)
+	require.Empty(t, refs.NextCursor)
+}
@@ -0,0 +1,333 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/streamio"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/version"
+)
+
+const (
+	protocolVersion uint32 = 1
+)
+
+var (
+	metadataTransportMagic = [4]byte{'Z', 'M', '\x00', '\x01'}
+	reserved               [16]byte
+)
+
+// referenceName: names without 'refs/' prefix are branches, like 'zeta-serve ls-remote'.
+func referenceName(refname plumbing.ReferenceName) plumbing.ReferenceName {
+	if refname == plumbing.HEAD || strings.HasPrefix(string(refname), plumbing.ReferencePrefix) {
+		return refname
+	}
+	return plumbing.NewBranchReferenceName(string(refname))
+}
+
+// peel: resolve tag objects until the commit.
+func peel(ctx context.Context, db *backend.Database, oid plumbing.Hash) (*object.Commit, []*object.Tag, error) {
+	var tags []*object.Tag
+	for range 10 {
+		a, err := db.Object(ctx, oid)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch v := a.(type) {
+		case *object.Commit:
+			return v, tags, nil
+		case *object.Tag:
+			tags = append(tags, v)
+			oid = v.Object
+		default:
+			return nil, nil, fmt.Errorf("revision %s target not commit", oid)
+		}
+	}
+	return nil, nil, fmt.Errorf("revision %s: too many nested tags", oid)
+}
+
+// FetchReference: discover reference of the local repository
+func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceName) (*transport.Reference, error) {
+	rdb := c.references()
+	head, err := rdb.HEAD()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := refs.ReferenceResolve(rdb, referenceName(refname))
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, transport.ErrReferenceNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	r := &transport.Reference{
+		Remote:          c.String(),
+		Name:            ref.Name(),
+		Hash:            ref.Hash().String(),
+		HEAD:            head.Target().String(),
+		Version:         int(protocolVersion),
+		Agent:           "zeta/" + version.GetVersion(),
+		HashAlgo:        backend.DefaultHashALGO,
+		CompressionALGO: db.CompressionALGO(),
+		Capabilities:    transport.Capabilities{transport.CapabilityLsRefs},
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		// detached HEAD
+		r.HEAD = string(plumbing.HEAD)
+	}
+	if ref.Name().IsTag() {
+		cc, _, err := peel(ctx, db, ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if cc.Hash != ref.Hash() {
+			r.Peeled = cc.Hash.String()
+		}
+	}
+	return r, nil
+}
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// ListReferences: list branches and tags of the local repository, paginated like 'zeta-serve ls-remote --list'.
+func (c *client) ListReferences(ctx context.Context, opts *transport.ListReferencesOptions) (*transport.References, error) {
+	rdb, err := c.references().References()
+	if err != nil {
+		return nil, err
+	}
+	rdb.Sort()
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+	result := &transport.References{
+		Remote:     c.String(),
+		References: make([]*transport.ReferenceEntry, 0, limit),
+	}
+	if head := rdb.HEAD(); head != nil && head.Type() == plumbing.SymbolicReference {
+		result.HEAD = head.Target().String()
+	}
+	var db *backend.Database
+	for _, ref := range rdb.References() {
+		name := ref.Name()
+		if (!name.IsBranch() && !name.IsTag()) || ref.Type() != plumbing.HashReference {
+			continue
+		}
+		if !strings.HasPrefix(string(name), opts.Prefix) || string(name) <= opts.Cursor {
+			continue
+		}
+		if len(result.References) == limit {
+			result.NextCursor = string(result.References[limit-1].Name)
+			break
+		}
+		e := &transport.ReferenceEntry{Name: name, Hash: ref.Hash().String()}
+		if name.IsTag() {
+			if db == nil {
+				if db, err = c.open(); err != nil {
+					return nil, err
+				}
+				defer db.Close()
+			}
+			if cc, _, err := peel(ctx, db, ref.Hash()); err == nil && cc.Hash != ref.Hash() {
+				e.Peeled = cc.Hash.String()
+			}
+		}
+		result.References = append(result.References, e)
+	}
+	return result, nil
+}
+
+type sparseMatcher struct {
+	entries map[string]*sparseMatcher
+}
+
+func (m *sparseMatcher) insert(p string) {
+	current := m
+	for _, d := range strengthen.StrSplitSkipEmpty(p, '/', 10) {
+		e, ok := current.entries[d]
+		if !ok {
+			e = &sparseMatcher{entries: make(map[string]*sparseMatcher)}
+			current.entries[d] = e
+		}
+		current = e
+	}
+}
+
+func newSparseMatcher(dirs []string) *sparseMatcher {
+	root := &sparseMatcher{entries: make(map[string]*sparseMatcher)}
+	for _, d := range dirs {
+		root.insert(d)
+	}
+	return root
+}
+
+// packer: write metadata in the same order as the zeta-serve metadata packer.
+type packer struct {
+	db           *backend.Database
+	w            io.Writer
+	treeMaxDepth int
+	seen         map[plumbing.Hash]bool
+}
+
+func (p *packer) write(e object.Encoder, oid plumbing.Hash) error {
+	if e == nil {
+		return binary.WriteUint32(p.w, 0)
+	}
+	b := streamio.GetBytesBuffer()
+	defer streamio.PutBytesBuffer(b)
+	if err := e.Encode(b); err != nil {
+		return err
+	}
+	if err := binary.WriteUint32(p.w, uint32(b.Len()+plumbing.HASH_HEX_SIZE)); err != nil {
+		return err
+	}
+	if err := binary.Write(p.w, []byte(oid.String())); err != nil {
+		return err
+	}
+	_, err := p.w.Write(b.Bytes())
+	return err
+}
+
+func (p *packer) writeTree(ctx context.Context, oid plumbing.Hash, m *sparseMatcher, depth int) error {
+	if depth > p.treeMaxDepth || p.seen[oid] {
+		return nil
+	}
+	tree, err := p.db.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if err := p.write(tree, oid); err != nil {
+		return err
+	}
+	for _, e := range tree.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if m == nil || len(m.entries) == 0 {
+				if err := p.writeTree(ctx, e.Hash, nil, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			if sub, ok := m.entries[e.Name]; ok {
+				if err := p.writeTree(ctx, e.Hash, sub, depth+1); err != nil {
+					return err
+				}
+			}
+		case object.FragmentsObject:
+			if p.seen[e.Hash] {
+				continue
+			}
+			ff, err := p.db.Fragments(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			if err := p.write(ff, e.Hash); err != nil {
+				return err
+			}
+			p.seen[e.Hash] = true
+		}
+	}
+	p.seen[oid] = true
+	return nil
+}
+
+func (p *packer) writeDeepen(ctx context.Context, current *object.Commit, opts *transport.MetadataOptions) error {
+	// same defaults as 'zeta-serve metadata': the last commit only, or all commits after --deepen-from
+	deepen := 1
+	if !opts.DeepenFrom.IsZero() {
+		deepen = -1
+	}
+	if opts.Deepen != 0 {
+		deepen = opts.Deepen
+	}
+	if deepen == -1 {
+		deepen = math.MaxInt
+	}
+	var m *sparseMatcher
+	if len(opts.Sparses) != 0 {
+		m = newSparseMatcher(opts.Sparses)
+	}
+	iter := object.NewCommitIterBSF(current, nil, nil)
+	defer iter.Close()
+	for range deepen {
+		cc, err := iter.Next(ctx)
+		if err == io.EOF || plumbing.IsNoSuchObject(err) {
+			// the local repository may be shallow
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if cc.Hash == opts.DeepenFrom || cc.Hash == opts.Have {
+			break
+		}
+		if err := p.write(cc, cc.Hash); err != nil {
+			return err
+		}
+		if err := p.writeTree(ctx, cc.Tree, m, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchMetadata: support base metadata and sparses metadata.
+func (c *client) FetchMetadata(ctx context.Context, target plumbing.Hash, opts *transport.MetadataOptions) (transport.SessionReader, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	cc, tags, err := peel(ctx, db, target)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return newSession(func(w io.Writer) error {
+		defer db.Close()
+		cw := crc.NewCrc64Writer(w)
+		if err := binary.Write(cw, metadataTransportMagic[:], protocolVersion, reserved[:]); err != nil {
+			return err
+		}
+		treeMaxDepth := opts.Depth
+		if treeMaxDepth == 0 || treeMaxDepth == -1 {
+			treeMaxDepth = math.MaxInt
+		}
+		p := &packer{db: db, w: cw, treeMaxDepth: treeMaxDepth, seen: make(map[plumbing.Hash]bool)}
+		for _, t := range tags {
+			if err := p.write(t, t.Hash); err != nil {
+				return err
+			}
+		}
+		if err := p.writeDeepen(ctx, cc, opts); err != nil {
+			return err
+		}
+		if err := p.write(nil, plumbing.ZeroHash); err != nil {
+			return err
+		}
+		_, err := cw.Finish()
+		return err
+	}), nil
+}
@@ -0,0 +1,140 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+const (
+	maxBatchBlobSize = math.MaxUint32 - plumbing.HASH_HEX_SIZE
+)
+
+var (
+	objectsTransportMagic = [4]byte{'Z', 'B', '\x00', '\x02'}
+)
+
+// BatchObjects: batch download objects AKA blobs, missing and large objects are skipped like zeta-serve.
+func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	return newSession(func(w io.Writer) error {
+		defer db.Close()
+		cw := crc.NewCrc64Writer(w)
+		if err := binary.Write(cw, objectsTransportMagic[:], protocolVersion, reserved[:]); err != nil {
+			return err
+		}
+		writeFunc := func(oid plumbing.Hash) error {
+			sr, err := db.SizeReader(oid, false)
+			if plumbing.IsNoSuchObject(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			defer sr.Close()
+			if sr.Size() > maxBatchBlobSize {
+				return nil
+			}
+			if err := binary.WriteUint32(cw, uint32(sr.Size()+plumbing.HASH_HEX_SIZE)); err != nil {
+				return err
+			}
+			if err := binary.Write(cw, []byte(oid.String())); err != nil {
+				return err
+			}
+			n, err := io.Copy(cw, sr)
+			if err != nil {
+				return err
+			}
+			if n != sr.Size() {
+				return fmt.Errorf("failed to write data, tried to write %d bytes, actual %d bytes", sr.Size(), n)
+			}
+			return nil
+		}
+		for _, oid := range oids {
+			if oid == backend.BLANK_BLOB_HASH {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := writeFunc(oid); err != nil {
+				return fmt.Errorf("batch-oids write blob %s error: %w", oid, err)
+			}
+		}
+		if err := binary.WriteUint32(cw, 0); err != nil {
+			return err
+		}
+		_, err := cw.Finish()
+		return err
+	}), nil
+}
+
+type sizeReader struct {
+	backend.SizeReader
+	closer io.Closer
+	offset int64
+	size   int64
+}
+
+func (sr *sizeReader) Close() error {
+	err := sr.SizeReader.Close()
+	_ = sr.closer.Close()
+	return err
+}
+
+func (sr *sizeReader) Offset() int64 {
+	return sr.offset
+}
+
+func (sr *sizeReader) Size() int64 {
+	return sr.size
+}
+
+func (sr *sizeReader) LastError() error {
+	return nil
+}
+
+// GetObject: get large object, support Range feature
+func (c *client) GetObject(ctx context.Context, oid plumbing.Hash, fromByte int64) (transport.SizeReader, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	sr, err := db.SizeReader(oid, false)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	size := sr.Size()
+	if fromByte < 0 || fromByte > size {
+		_ = sr.Close()
+		_ = db.Close()
+		return nil, fmt.Errorf("offset %d out of range, object size %d", fromByte, size)
+	}
+	if fromByte > 0 {
+		if _, err := io.CopyN(io.Discard, sr, fromByte); err != nil {
+			_ = sr.Close()
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return &sizeReader{SizeReader: sr, closer: db, offset: fromByte, size: size}, nil
+}
+
+// Shared: local repository has no accelerator, objects are read directly.
+func (c *client) Shared(ctx context.Context, wantObjects []*transport.WantObject) ([]*transport.Representation, error) {
+	return nil, ErrUnsupportedShared
+}
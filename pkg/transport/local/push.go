@@ -0,0 +1,259 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/modules/binary"
+	"github.com/antgroup/hugescm/modules/crc"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/pktline"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+var (
+	pushTransportMagic = [4]byte{'Z', 'P', '\x00', '\x01'}
+)
+
+type reporter struct {
+	*pktline.Encoder
+	refname plumbing.ReferenceName
+}
+
+func (r *reporter) ok(newRev plumbing.Hash) error {
+	return r.Encodef("ok %s %s", r.refname, newRev)
+}
+
+func (r *reporter) ng(format string, a ...any) error {
+	message := fmt.Sprintf(format, a...)
+	return r.Encodef("ng %s %s", r.refname, message)
+}
+
+// unpackers: metadata and blobs are stored in different locations.
+type unpackers struct {
+	db       *backend.Database
+	metadata *backend.Unpacker
+	blobs    *backend.Unpacker
+}
+
+func (u *unpackers) write(oid plumbing.Hash, size uint32, r io.Reader, metadata bool) (err error) {
+	ur := &u.blobs
+	if metadata {
+		ur = &u.metadata
+	}
+	if *ur == nil {
+		if *ur, err = u.db.NewUnpacker(0, metadata); err != nil {
+			return err
+		}
+	}
+	return (*ur).Write(oid, size, r, 0)
+}
+
+func (u *unpackers) preserve() error {
+	for _, ur := range []*backend.Unpacker{u.blobs, u.metadata} {
+		if ur == nil {
+			continue
+		}
+		if err := ur.Preserve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *unpackers) close() {
+	for _, ur := range []*backend.Unpacker{u.blobs, u.metadata} {
+		if ur != nil {
+			_ = ur.Close()
+		}
+	}
+}
+
+// unpack: decode the push stream written by 'ODB.PushTo', metadata entries have negative sizes.
+func (c *client) unpack(db *backend.Database, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Join(db.Root(), "incoming"), 0755); err != nil {
+		return err
+	}
+	cr := crc.NewCrc64Reader(r)
+	var magic [4]byte
+	var reserved [16]byte
+	if _, err := io.ReadFull(cr, magic[:]); err != nil {
+		return fmt.Errorf("read magic error: %w", err)
+	}
+	if !bytes.Equal(magic[:], pushTransportMagic[:]) {
+		return fmt.Errorf("bad magic ['\\%x','\\%x','\\%x','\\%x']", magic[0], magic[1], magic[2], magic[3])
+	}
+	version, err := binary.ReadUint32(cr)
+	if err != nil {
+		return fmt.Errorf("read version error: %w", err)
+	}
+	if version != protocolVersion {
+		return fmt.Errorf("unsupported version '%d'", version)
+	}
+	if _, err := io.ReadFull(cr, reserved[:]); err != nil {
+		return fmt.Errorf("read reserved error: %w", err)
+	}
+	u := &unpackers{db: db}
+	defer u.close()
+	var hashBytes [plumbing.HASH_HEX_SIZE]byte
+	for {
+		objectSize, err := binary.ReadUint64(cr)
+		if err != nil {
+			return fmt.Errorf("read object length error: %w", err)
+		}
+		size := int64(objectSize)
+		if size == 0 {
+			break
+		}
+		metadata := size < 0
+		if metadata {
+			size = -size
+		}
+		if size < plumbing.HASH_HEX_SIZE || size-plumbing.HASH_HEX_SIZE > maxBatchBlobSize {
+			return fmt.Errorf("bad chunk size: %d", size)
+		}
+		if _, err := io.ReadFull(cr, hashBytes[:]); err != nil {
+			return fmt.Errorf("read object hash error: %w", err)
+		}
+		currentSize := size - plumbing.HASH_HEX_SIZE
+		if err := u.write(plumbing.NewHash(string(hashBytes[:])), uint32(currentSize), io.LimitReader(cr, currentSize), metadata); err != nil {
+			return fmt.Errorf("decode object error: %w", err)
+		}
+	}
+	if err := cr.Verify(); err != nil {
+		return err
+	}
+	return u.preserve()
+}
+
+// Push: unpack objects into the local repository and update the reference under its lock.
+//
+// Like git's receive.denyCurrentBranch, updating the checked out branch of a repository with a
+// worktree is refused, the worktree would no longer match its HEAD.
+func (c *client) Push(ctx context.Context, r io.Reader, command *transport.Command) (rc transport.SessionReader, err error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	return newSession(func(w io.Writer) error {
+		defer db.Close()
+		ro := &reporter{Encoder: pktline.NewEncoder(w), refname: command.Refname}
+		if err := c.unpack(db, r); err != nil {
+			// drain the stream so that the sender is not blocked
+			_, _ = io.Copy(io.Discard, r)
+			return ro.Encodef("unpack %v", err)
+		}
+		if err := ro.EncodeString("unpack ok"); err != nil {
+			return err
+		}
+		if err := db.Reload(); err != nil {
+			return ro.ng("reload odb error: %v", err)
+		}
+		return c.updateReference(ctx, db, ro, command)
+	}), nil
+}
+
+func (c *client) updateReference(ctx context.Context, db *backend.Database, ro *reporter, command *transport.Command) error {
+	rdb := c.references()
+	head, err := rdb.HEAD()
+	if err != nil {
+		return ro.ng("resolve HEAD error: %v", err)
+	}
+	refname := referenceName(command.Refname)
+	if refname == plumbing.HEAD {
+		if head.Type() != plumbing.SymbolicReference {
+			return ro.ng("HEAD is detached")
+		}
+		refname = head.Target()
+	}
+	if !plumbing.ValidateReferenceName([]byte(refname)) {
+		return ro.ng(W("'%s' is not a valid branch name"), refname)
+	}
+	isCurrent := head.Type() == plumbing.SymbolicReference && head.Target() == refname
+	current, err := rdb.Reference(refname)
+	if err != nil && err != plumbing.ErrReferenceNotFound {
+		return ro.ng("resolve reference error: %v", err)
+	}
+	oldRev := plumbing.NewHash(command.OldRev)
+	newRev := plumbing.NewHash(command.NewRev)
+	if (current == nil && !oldRev.IsZero()) || (current != nil && current.Hash() != oldRev) {
+		return ro.ng(W("reference is updated, please update and try again"))
+	}
+	if newRev.IsZero() {
+		if current == nil {
+			return ro.ng(W("reference '%s' not exist"), refname)
+		}
+		if isCurrent {
+			return ro.ng("\x1b[31merror\x1b[0m: %s%s", W("refusing to delete the current branch: "), refname)
+		}
+		if err := rdb.ReferenceRemove(current); err != nil {
+			return ro.ng("remove reference error: %v", err)
+		}
+		return ro.ok(newRev)
+	}
+	if isCurrent && !c.bare {
+		return ro.ng("\x1b[31merror\x1b[0m: %s%s", W("refusing to update checked out branch: "), refname)
+	}
+	// connectivity: the new revision and the commit it points to must be present after unpacking
+	if refname.IsBranch() {
+		if _, err := db.Commit(ctx, newRev); err != nil {
+			return ro.ng("resolve commit '%s' error: %v", newRev, err)
+		}
+	} else if _, _, err := peel(ctx, db, newRev); err != nil {
+		return ro.ng("peeled object '%s' error: %v", newRev, err)
+	}
+	if err := rdb.ReferenceUpdate(plumbing.NewHashReference(refname, newRev), current); err != nil {
+		if plumbing.IsErrResourceLocked(err) {
+			return ro.ng(W("reference is already locked: %s"), refname)
+		}
+		if errors.Is(err, refs.ErrReferenceHasChanged) {
+			return ro.ng(W("reference is updated, please update and try again"))
+		}
+		return ro.ng("update reference error: %v", err)
+	}
+	return ro.ok(newRev)
+}
+
+// BatchCheck: check large objects exists in the local repository
+func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) ([]*transport.HaveObject, error) {
+	db, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	objects := make([]*transport.HaveObject, 0, len(haveObjects))
+	for _, o := range haveObjects {
+		if o == nil {
+			return nil, errors.New("require object is nil")
+		}
+		if size, err := db.Size(plumbing.NewHash(o.OID), false); err == nil {
+			objects = append(objects, &transport.HaveObject{OID: o.OID, CompressedSize: size, Action: transport.DOWNLOAD})
+			continue
+		}
+		objects = append(objects, &transport.HaveObject{OID: o.OID, CompressedSize: o.CompressedSize, Action: transport.UPLOAD})
+	}
+	return objects, nil
+}
+
+// PutObject: upload large object to the local repository
+func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error {
+	db, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.WriteTo(ctx, oid, r); err != nil {
+		return fmt.Errorf("upload object '%s' error: %w", oid, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package local
+
+import (
+	"io"
+
+	"github.com/antgroup/hugescm/modules/streamio"
+)
+
+// session: the response stream is produced by a goroutine, like the stdout of 'zeta-serve'.
+type session struct {
+	*io.PipeReader
+	done      chan struct{}
+	lastError error
+}
+
+func newSession(fn func(w io.Writer) error) *session {
+	pr, pw := io.Pipe()
+	s := &session{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		bw := streamio.GetBufferWriter(pw)
+		err := fn(bw)
+		if err == nil {
+			err = bw.Flush()
+		}
+		streamio.PutBufferWriter(bw)
+		s.lastError = err
+		_ = pw.CloseWithError(err)
+	}()
+	return s
+}
+
+func (s *session) Close() error {
+	err := s.PipeReader.Close()
+	<-s.done
+	return err
+}
+
+// LastError: error of the producer, valid after Close.
+func (s *session) LastError() error {
+	if s.lastError == io.ErrClosedPipe {
+		return nil
+	}
+	return s.lastError
+}
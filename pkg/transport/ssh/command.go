@@ -23,6 +23,10 @@ type Command struct {
 }
 
 func (c *Command) LastError() error {
+	// avoid returning a typed nil
+	if c.lastError == nil {
+		return nil
+	}
 	return c.lastError
 }
 
@@ -45,6 +45,45 @@ func (c *client) FetchReference(ctx context.Context, refname plumbing.ReferenceN
 	return &r, nil
 }
 
+// ListReferences: zeta-serve ls-remote "group/mono-zeta" --list --prefix=${PREFIX} --cursor=${CURSOR} --limit=N
+func (c *client) ListReferences(ctx context.Context, opts *transport.ListReferencesOptions) (*transport.References, error) {
+	psArgs := []string{"zeta-serve", "ls-remote", fmt.Sprintf("'%s'", c.Path), "--list"}
+	if len(opts.Prefix) != 0 {
+		psArgs = append(psArgs, fmt.Sprintf("--prefix='%s'", opts.Prefix))
+	}
+	if len(opts.Cursor) != 0 {
+		psArgs = append(psArgs, fmt.Sprintf("--cursor='%s'", opts.Cursor))
+	}
+	if opts.Limit > 0 {
+		psArgs = append(psArgs, "--limit="+strconv.Itoa(opts.Limit))
+	}
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(strings.Join(psArgs, " ")); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var refs transport.References
+	if err := json.NewDecoder(stdout).Decode(&refs); err != nil {
+		_ = cmd.Close()
+		if cmd.lastError != nil {
+			return nil, cmd.lastError
+		}
+		return nil, err
+	}
+	if err := cmd.Close(); err != nil {
+		return nil, err
+	}
+	return &refs, nil
+}
+
 func sparsesGenReader(sparses []string) io.Reader {
 	var b strings.Builder
 	var total int
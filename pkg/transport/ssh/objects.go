@@ -24,6 +24,20 @@ var (
 
 // BatchObjects: zeta-serve objects "group/mono-zeta" --batch
 func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	return c.batchObjects(ctx, oids)
+}
+
+// ResumableBatchObjects: zeta-serve objects "group/mono-zeta" --batch --resumable
+func (c *client) ResumableBatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	return c.batchObjects(ctx, oids, "--resumable")
+}
+
+// WantHaveObjects: zeta-serve objects "group/mono-zeta" --batch --want=${want} --have=${have} --size-limit=N
+func (c *client) WantHaveObjects(ctx context.Context, want, have plumbing.Hash, sizeLimit int64) (transport.SessionReader, error) {
+	return c.batchObjects(ctx, nil, "--want="+want.String(), "--have="+have.String(), fmt.Sprintf("--size-limit=%d", sizeLimit))
+}
+
+func (c *client) batchObjects(ctx context.Context, oids []plumbing.Hash, args ...string) (transport.SessionReader, error) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	pr, pw := io.Pipe()
@@ -38,7 +52,7 @@ func (c *client) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transp
 		}
 		_ = buf.WriteByte('\n')
 	}()
-	psArgs := []string{"zeta-serve", "objects", fmt.Sprintf("'%s'", c.Path), "--batch"}
+	psArgs := append([]string{"zeta-serve", "objects", fmt.Sprintf("'%s'", c.Path), "--batch"}, args...)
 	commandArgs := strings.Join(psArgs, " ")
 	cmd, err := c.NewBaseCommand(ctx)
 	if err != nil {
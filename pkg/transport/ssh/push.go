@@ -41,6 +41,58 @@ func (c *client) Push(ctx context.Context, r io.Reader, command *transport.Comma
 	return cmd, nil
 }
 
+// PushAtomic: zeta-serve push "group/mono-zeta" --atomic
+func (c *client) PushAtomic(ctx context.Context, r io.Reader, command *transport.AtomicCommand) (transport.SessionReader, error) {
+	stdin, err := transport.NewAtomicReader(r, command)
+	if err != nil {
+		return nil, err
+	}
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --atomic", c.Path)
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = cmd.Setenv("ZETA_OBJECTS_STATS", fmt.Sprintf("m-%d;b-%d", command.Metadata, command.Objects))
+	if len(command.PushOptions) != 0 {
+		_ = cmd.Setenv("ZETA_PUSH_OPTION_COUNT", strconv.Itoa(len(command.PushOptions)))
+		for i, o := range command.PushOptions {
+			_ = cmd.Setenv(fmt.Sprintf("ZETA_PUSH_OPTION_%d", i), o)
+		}
+	}
+	cmd.Stdin = stdin
+	if cmd.Reader, err = cmd.StdoutPipe(); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// PushBundle: zeta-serve push "group/mono-zeta" --bundle [--force]
+func (c *client) PushBundle(ctx context.Context, r io.Reader, size int64, force bool) (transport.SessionReader, error) {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --bundle", c.Path)
+	if force {
+		commandArgs += " --force"
+	}
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = r
+	if cmd.Reader, err = cmd.StdoutPipe(); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	return cmd, nil
+}
+
 // BatchCheck: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --batch-check
 func (c *client) BatchCheck(ctx context.Context, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) ([]*transport.HaveObject, error) {
 	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --batch-check", c.Path, refname)
@@ -99,3 +151,70 @@ func (c *client) PutObject(ctx context.Context, refname plumbing.ReferenceName,
 	}
 	return cmd.lastError
 }
+
+func (c *client) doUpload(ctx context.Context, commandArgs string, stdin io.Reader, us *transport.UploadSession) (*transport.UploadSession, error) {
+	cmd, err := c.NewBaseCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = stdin
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	if err := cmd.Start(commandArgs); err != nil {
+		_ = cmd.Close()
+		return nil, err
+	}
+	var session transport.UploadSession
+	if err := json.NewDecoder(stdout).Decode(&session); err != nil {
+		_ = cmd.Close()
+		if cmd.lastError == nil {
+			return nil, err
+		}
+		if us != nil && cmd.lastError.Code == 404 {
+			return nil, transport.ErrUploadNotFound
+		}
+		return nil, cmd.lastError
+	}
+	if err := cmd.Close(); err != nil {
+		if cmd.lastError == nil {
+			return nil, err
+		}
+		return nil, cmd.lastError
+	}
+	return &session, nil
+}
+
+// NewUpload: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload new
+func (c *client) NewUpload(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, size int64) (*transport.UploadSession, error) {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --upload=new", c.Path, refname, oid, size)
+	return c.doUpload(ctx, commandArgs, nil, nil)
+}
+
+// UploadStatus: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload status --upload-id "$UPLOAD_ID"
+func (c *client) UploadStatus(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) (*transport.UploadSession, error) {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --upload=status --upload-id='%s'", c.Path, refname, us.OID, us.Size, us.ID)
+	return c.doUpload(ctx, commandArgs, nil, us)
+}
+
+// UploadChunk: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload chunk --upload-id "$UPLOAD_ID" --offset "$OFFSET"
+func (c *client) UploadChunk(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession, offset int64, r io.Reader, length int64) (*transport.UploadSession, error) {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --upload=chunk --upload-id='%s' --offset=%d", c.Path, refname, us.OID, us.Size, us.ID, offset)
+	return c.doUpload(ctx, commandArgs, io.LimitReader(r, length), us)
+}
+
+// FinishUpload: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload finish --upload-id "$UPLOAD_ID"
+func (c *client) FinishUpload(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) error {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --upload=finish --upload-id='%s'", c.Path, refname, us.OID, us.Size, us.ID)
+	_, err := c.doUpload(ctx, commandArgs, nil, us)
+	return err
+}
+
+// AbortUpload: zeta-serve push "group/mono-zeta" --reference "$REFNAME" --oid "$OID" --size "${SIZE}" --upload abort --upload-id "$UPLOAD_ID"
+func (c *client) AbortUpload(ctx context.Context, refname plumbing.ReferenceName, us *transport.UploadSession) error {
+	commandArgs := fmt.Sprintf("zeta-serve push '%s' --reference=%s --oid=%s --size=%d --upload=abort --upload-id='%s'", c.Path, refname, us.OID, us.Size, us.ID)
+	_, err := c.doUpload(ctx, commandArgs, nil, us)
+	return err
+}
@@ -42,7 +42,28 @@ type Reference struct {
 	Agent           string                 `json:"agent"`
 	HashAlgo        string                 `json:"hash-algo"`
 	CompressionALGO string                 `json:"compression-algo"`
-	Capabilities    []string               `json:"capabilities"`
+	Capabilities    Capabilities           `json:"capabilities"`
+}
+
+// ReferenceEntry: item of the reference listing, annotated tags are peeled.
+type ReferenceEntry struct {
+	Name   plumbing.ReferenceName `json:"name"`
+	Hash   string                 `json:"hash"`
+	Peeled string                 `json:"peeled,omitempty"`
+}
+
+// References: one page of the reference listing, NextCursor is empty on the last page.
+type References struct {
+	Remote     string            `json:"remote"`
+	HEAD       string            `json:"head"`
+	References []*ReferenceEntry `json:"references"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type ListReferencesOptions struct {
+	Prefix string // full reference prefix, like 'refs/heads/'
+	Cursor string // NextCursor of the previous page
+	Limit  int    // page size, 0 means the server default
 }
 
 func (r *Reference) Target() plumbing.Hash {
@@ -61,6 +82,14 @@ type Command struct {
 	PushOptions []string               `json:"push_options,omitempty"`
 }
 
+// AtomicCommand: update multiple references in one push, all of them are updated or none of them.
+type AtomicCommand struct {
+	Commands    []*Command `json:"commands"` // Metadata and Objects of the commands are ignored
+	Metadata    int        `json:"metadata"`
+	Objects     int        `json:"objects"`
+	PushOptions []string   `json:"push_options,omitempty"`
+}
+
 type WantObject struct {
 	OID string `json:"oid"`
 }
@@ -108,3 +137,13 @@ type BatchRequest struct {
 type BatchResponse struct {
 	Objects []*HaveObject `json:"objects"`
 }
+
+// UploadSession: resumable upload session of a large object, chunks are uploaded at offsets aligned to ChunkSize.
+// Offset is the committed offset, an empty ID means the object already exists.
+type UploadSession struct {
+	ID        string `json:"id,omitempty"`
+	OID       string `json:"oid"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	Offset    int64  `json:"offset"`
+}
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
@@ -61,3 +62,16 @@ func TestParseEndpoint(t *testing.T) {
 		fmt.Fprintf(os.Stderr, "endpoint: %v protocol: %s\n", e, e.Protocol)
 	}
 }
+
+func TestCapabilities(t *testing.T) {
+	var r Reference
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"refs/heads/main","capabilities":["ls-refs","resumable-upload","future-feature"]}`), &r))
+	require.True(t, r.Capabilities.Has(CapabilityResumableUpload))
+	require.NoError(t, r.Capabilities.Require("https://zeta.io/group/repo", CapabilityLsRefs))
+	err := r.Capabilities.Require("https://zeta.io/group/repo", CapabilityLsRefs, CapabilityAtomicPush)
+	require.True(t, IsErrMissingCapability(err))
+	require.Contains(t, err.Error(), CapabilityAtomicPush)
+	var old Reference
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"refs/heads/main","capabilities":[]}`), &old))
+	require.False(t, old.Capabilities.Has(CapabilityResumableUpload))
+}
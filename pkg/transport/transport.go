@@ -4,18 +4,69 @@
 package transport
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/format/pktline"
+)
+
+const (
+	// capabilities advertised by the remote in reference discovery, see Reference.Capabilities. zeta-serve builds its
+	// list from these names, see protocol.Capabilities.
+	CapabilityResumableBatch  = "resumable-batch"           // checked batch objects stream
+	CapabilityResumableUpload = "resumable-upload"          // chunked upload sessions of large objects
+	CapabilityMetadataZstd    = "metadata-compression=zstd" // metadata stream compressed by zstd
+	CapabilityLsRefs          = "ls-refs"                   // paginated reference listing
+	CapabilityPushOptions     = "push-options"              // push options are honored
+	CapabilityAtomicPush      = "atomic-push"               // multiple references are updated in one transaction
+	CapabilityHooks           = "hooks"                     // server-side hooks run on reference updates
+	CapabilityBundlePush      = "bundle-push"               // bundle files are accepted as push
+	CapabilityBatchWantHave   = "batch-want-have"           // batch objects of want but not of have
 )
 
 var (
 	ErrRepositoryNotFound = errors.New("repository not found")
 	ErrReferenceNotExist  = errors.New("reference not exist")
+	ErrUploadNotFound     = errors.New("upload session not found")
 )
 
+// Capabilities: optional features advertised by the remote, unknown capabilities are ignored.
+type Capabilities []string
+
+func (c Capabilities) Has(name string) bool {
+	return slices.Contains(c, name)
+}
+
+// Require: returns ErrMissingCapability if the remote does not advertise any of the capabilities.
+func (c Capabilities) Require(remote string, names ...string) error {
+	for _, name := range names {
+		if !c.Has(name) {
+			return &ErrMissingCapability{Remote: remote, Capability: name}
+		}
+	}
+	return nil
+}
+
+// ErrMissingCapability: the operation requires a capability that the remote does not advertise.
+type ErrMissingCapability struct {
+	Remote     string
+	Capability string
+}
+
+func (e *ErrMissingCapability) Error() string {
+	return fmt.Sprintf("remote '%s' does not support '%s', the server may need to be upgraded", e.Remote, e.Capability)
+}
+
+func IsErrMissingCapability(err error) bool {
+	var e *ErrMissingCapability
+	return errors.As(err, &e)
+}
+
 type SizeReader interface {
 	io.Reader
 	io.Closer
@@ -41,6 +92,8 @@ type SessionReader interface {
 type Transport interface {
 	// FetchReference: discover reference and remote repo info and caps
 	FetchReference(ctx context.Context, refname plumbing.ReferenceName) (*Reference, error)
+	// ListReferences: list remote branches and tags ordered by name, paginated
+	ListReferences(ctx context.Context, opts *ListReferencesOptions) (*References, error)
 	// FetchMetadata: support base metadata and sparses metadata.
 	//  target: commit or tag
 	FetchMetadata(ctx context.Context, target plumbing.Hash, opts *MetadataOptions) (SessionReader, error)
@@ -57,3 +110,65 @@ type Transport interface {
 	// PutObject: upload large object to remote
 	PutObject(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, r io.Reader, size int64) error
 }
+
+// ResumableBatch: batch download objects with a checksum per object, the verified objects of an interrupted
+// stream are kept so that only the rest is requested again, see CapabilityResumableBatch.
+type ResumableBatch interface {
+	ResumableBatchObjects(ctx context.Context, oids []plumbing.Hash) (SessionReader, error)
+}
+
+// WantHaveBatch: batch download the objects reachable from want but not from have, the remote answers with its bitmap
+// index instead of the client counting the objects, see CapabilityBatchWantHave.
+type WantHaveBatch interface {
+	// WantHaveObjects: objects larger than sizeLimit are skipped, the stream has the same format as BatchObjects
+	WantHaveObjects(ctx context.Context, want, have plumbing.Hash, sizeLimit int64) (SessionReader, error)
+}
+
+// Resumable: resumable chunked upload of large objects, an interrupted upload continues from the committed offset.
+type Resumable interface {
+	// NewUpload: create upload session, the session of an object that already exists in remote has no ID
+	NewUpload(ctx context.Context, refname plumbing.ReferenceName, oid plumbing.Hash, size int64) (*UploadSession, error)
+	// UploadStatus: query committed offset of the upload session, returns ErrUploadNotFound if the session is gone
+	UploadStatus(ctx context.Context, refname plumbing.ReferenceName, s *UploadSession) (*UploadSession, error)
+	// UploadChunk: upload the chunk at offset, offset must be aligned to the chunk size
+	UploadChunk(ctx context.Context, refname plumbing.ReferenceName, s *UploadSession, offset int64, r io.Reader, length int64) (*UploadSession, error)
+	// FinishUpload: finish the upload session, remote verifies the object hash
+	FinishUpload(ctx context.Context, refname plumbing.ReferenceName, s *UploadSession) error
+	// AbortUpload: abort the upload session and remove the uploaded chunks, aborting a session that is gone succeeds
+	AbortUpload(ctx context.Context, refname plumbing.ReferenceName, s *UploadSession) error
+}
+
+// Atomic: update multiple references in one push, see CapabilityAtomicPush.
+type Atomic interface {
+	// PushAtomic: push metadata and blobs of all references, the report contains the status of each reference
+	PushAtomic(ctx context.Context, r io.Reader, cmd *AtomicCommand) (SessionReader, error)
+}
+
+// BundlePusher: push a bundle file as is, the remote verifies the bundle and updates its references atomically,
+// see CapabilityBundlePush.
+type BundlePusher interface {
+	// PushBundle: the report has the same format as PushAtomic, non-fast-forward updates are rejected unless force
+	PushBundle(ctx context.Context, r io.Reader, size int64, force bool) (SessionReader, error)
+}
+
+// Partial: transports that may not contain every blob, such as bundles created with a blob size limit,
+// blobs they do not contain are left missing instead of failing the fetch.
+type Partial interface {
+	HasObject(oid plumbing.Hash) bool
+}
+
+// NewAtomicReader: the commands '<old-rev> <new-rev> <refname>' are sent as pktlines ended by a flush-pkt,
+// followed by the objects stream.
+func NewAtomicReader(r io.Reader, cmd *AtomicCommand) (io.Reader, error) {
+	var b bytes.Buffer
+	e := pktline.NewEncoder(&b)
+	for _, c := range cmd.Commands {
+		if err := e.Encodef("%s %s %s", c.OldRev, c.NewRev, c.Refname); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(&b, r), nil
+}
@@ -0,0 +1,390 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
+	"github.com/antgroup/hugescm/pkg/progress"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/transport/client"
+	"github.com/antgroup/hugescm/pkg/transport/local"
+	"github.com/antgroup/hugescm/pkg/version"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+type BundleCreateOptions struct {
+	File string
+	// RevRange: 'tip' or 'base..tip', the tip must be a branch or tag, commits reachable from base are excluded
+	RevRange     string
+	Sparses      []string
+	IncludeBlobs bool
+	// MaxBlobSize: blobs larger than it are not included, 0 means no limit
+	MaxBlobSize int64
+}
+
+// resolveBundleRange: returns the tip reference and the prerequisite commit, which is zero without base.
+func (r *Repository) resolveBundleRange(ctx context.Context, revRange string) (*bundle.Reference, plumbing.Hash, plumbing.Hash, error) {
+	base, tip, ok := strings.Cut(revRange, "..")
+	if !ok {
+		base, tip = "", revRange
+	}
+	if len(tip) == 0 {
+		tip = string(plumbing.HEAD)
+	}
+	oid, refname, err := r.RevisionEx(ctx, tip)
+	if err != nil {
+		die_error("resolve '%s' error: %v", tip, err)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, err
+	}
+	if !refname.IsBranch() && !refname.IsTag() {
+		die_error("'%s' is not a branch or tag, bundle references must have names", tip)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, fmt.Errorf("'%s' is not a branch or tag", tip)
+	}
+	tipCommit, err := r.odb.ParseRevExhaustive(ctx, oid)
+	if err != nil {
+		die_error("resolve '%s' error: %v", tip, err)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, err
+	}
+	// the reference is created by 'zeta bundle push' without base
+	ref := &bundle.Reference{Name: refname, Hash: oid.String()}
+	if len(base) == 0 {
+		return ref, tipCommit.Hash, plumbing.ZeroHash, nil
+	}
+	baseRev, err := r.Revision(ctx, base)
+	if err != nil {
+		die_error("resolve '%s' error: %v", base, err)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, err
+	}
+	baseCommit, err := r.odb.ParseRevExhaustive(ctx, baseRev)
+	if err != nil {
+		die_error("resolve '%s' error: %v", base, err)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, err
+	}
+	if baseCommit.Hash == tipCommit.Hash {
+		die_error("refusing to create empty bundle, '%s' is '%s'", tip, base)
+		return nil, plumbing.ZeroHash, plumbing.ZeroHash, errors.New("empty bundle")
+	}
+	// the reference is expected at base by 'zeta bundle push'
+	ref.Old = baseCommit.Hash.String()
+	return ref, tipCommit.Hash, baseCommit.Hash, nil
+}
+
+// bundleBlobs: blobs of the tip that are not in the base, limited by size
+func (r *Repository) bundleBlobs(ctx context.Context, tip, base plumbing.Hash, o *BundleCreateOptions) ([]*odb.Entry, error) {
+	excluded := make(map[plumbing.Hash]bool)
+	if !base.IsZero() {
+		if err := r.odb.WalkBlobs(ctx, base, o.Sparses, func(e *odb.Entry) error {
+			excluded[e.Hash] = true
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	entries := make([]*odb.Entry, 0, 100)
+	if err := r.odb.WalkBlobs(ctx, tip, o.Sparses, func(e *odb.Entry) error {
+		if excluded[e.Hash] || e.Hash == backend.BLANK_BLOB_HASH {
+			return nil
+		}
+		if o.MaxBlobSize > 0 && e.Size > o.MaxBlobSize {
+			return nil
+		}
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *Repository) writeBundle(ctx context.Context, name string, h *bundle.Header, tip, base plumbing.Hash, o *BundleCreateOptions) error {
+	t, err := local.NewTransport(ctx, &transport.Endpoint{Protocol: "file", Path: filepath.ToSlash(r.zetaDir)}, transport.DOWNLOAD, r.verbose)
+	if err != nil {
+		return err
+	}
+	rc, err := t.FetchMetadata(ctx, tip, &transport.MetadataOptions{Have: base, Deepen: -1, Sparses: o.Sparses})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	fd, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	w, err := bundle.NewWriter(fd, h)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteMetadata(rc); err != nil {
+		return fmt.Errorf("write metadata error: %w", err)
+	}
+	if h.Blobs {
+		entries, err := r.bundleBlobs(ctx, tip, base, o)
+		if err != nil {
+			return fmt.Errorf("walk blobs error: %w", err)
+		}
+		bar := progress.NewBar("Writing blobs", len(entries), r.quiet)
+		var missing int
+		for _, e := range entries {
+			sr, err := r.odb.SizeReader(e.Hash, false)
+			if plumbing.IsNoSuchObject(err) {
+				// blobs that were never fetched are not included
+				missing++
+				bar.Add(1)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			err = w.WriteBlob(e.Hash, sr, sr.Size())
+			_ = sr.Close()
+			if err != nil {
+				return err
+			}
+			bar.Add(1)
+		}
+		bar.Done()
+		if missing != 0 {
+			warn("%d blobs do not exist locally and are not included in the bundle", missing)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return fd.Sync()
+}
+
+// BundleCreate: write metadata and blobs of the reference into a bundle file, the file is written to
+// '<file>.partial' and renamed when complete.
+func (r *Repository) BundleCreate(ctx context.Context, o *BundleCreateOptions) error {
+	ref, tip, base, err := r.resolveBundleRange(ctx, o.RevRange)
+	if err != nil {
+		return err
+	}
+	h := &bundle.Header{
+		HEAD:            ref.Name,
+		References:      []*bundle.Reference{ref},
+		Sparses:         o.Sparses,
+		Blobs:           o.IncludeBlobs || o.MaxBlobSize > 0,
+		MaxBlobSize:     o.MaxBlobSize,
+		HashALGO:        backend.DefaultHashALGO,
+		CompressionALGO: r.odb.CompressionALGO(),
+		Agent:           version.GetVersion(),
+		CreatedAt:       time.Now(),
+	}
+	if !base.IsZero() {
+		h.Prerequisites = []string{base.String()}
+	}
+	partial := o.File + ".partial"
+	if err := r.writeBundle(ctx, partial, h, tip, base, o); err != nil {
+		_ = os.Remove(partial)
+		die_error("create bundle '%s' error: %v", o.File, err)
+		return err
+	}
+	if err := os.Rename(partial, o.File); err != nil {
+		_ = os.Remove(partial)
+		die_error("create bundle '%s' error: %v", o.File, err)
+		return err
+	}
+	b, err := bundle.Open(o.File)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", o.File, err)
+		return err
+	}
+	defer b.Close()
+	fmt.Fprintf(os.Stderr, "%s: %s %s, %d metadata, %d blobs, %s\n", o.File, ref.Name, shortHash(tip),
+		b.Metadata, b.Blobs, strengthen.HumanateSize(b.Size()))
+	return nil
+}
+
+// missingPrerequisites: prerequisites of the bundle that do not exist in the repository
+func (r *Repository) missingPrerequisites(h *bundle.Header) []string {
+	missing := make([]string, 0, len(h.Prerequisites))
+	for _, p := range h.Prerequisites {
+		if !plumbing.ValidateHashHex(p) || !r.odb.Exists(plumbing.NewHash(p), true) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// VerifyBundle: verify checksums of the bundle and print its contents. Prerequisites are checked when r is
+// not nil.
+func VerifyBundle(ctx context.Context, r *Repository, file string) error {
+	b, err := bundle.Open(file)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", file, err)
+		return err
+	}
+	defer b.Close()
+	if err := b.Verify(ctx); err != nil {
+		die_error("%s is not a valid bundle: %v", file, err)
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "The bundle contains %d references:\n", len(b.References))
+	for _, ref := range b.References {
+		fmt.Fprintf(os.Stdout, "%s %s\n", ref.Hash, ref.Name)
+	}
+	if len(b.Prerequisites) == 0 {
+		fmt.Fprintf(os.Stdout, "The bundle records a complete history.\n")
+	} else {
+		fmt.Fprintf(os.Stdout, "The bundle requires these %d commits:\n", len(b.Prerequisites))
+		for _, p := range b.Prerequisites {
+			fmt.Fprintf(os.Stdout, "%s\n", p)
+		}
+	}
+	if len(b.Sparses) != 0 {
+		fmt.Fprintf(os.Stdout, "The bundle only contains: %s\n", strings.Join(b.Sparses, " "))
+	}
+	fmt.Fprintf(os.Stdout, "Metadata: %d, blobs: %d\n", b.Metadata, b.Blobs)
+	if r != nil {
+		if missing := r.missingPrerequisites(b.Header); len(missing) != 0 {
+			error_red("repository lacks these prerequisite commits:\n%s", strings.Join(missing, "\n"))
+			return errors.New("missing prerequisite commits")
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s is okay\n", file)
+	return nil
+}
+
+// FetchBundle: import all references of the bundle like 'zeta fetch'. Objects that already exist are skipped, so an
+// interrupted import is resumed by running it again.
+func (r *Repository) FetchBundle(ctx context.Context, file string, opts *DoFetchOptions) error {
+	p, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.Open(p)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", file, err)
+		return err
+	}
+	h := b.Header
+	_ = b.Close()
+	if missing := r.missingPrerequisites(h); len(missing) != 0 {
+		error_red("repository lacks these prerequisite commits:\n%s", strings.Join(missing, "\n"))
+		return errors.New("missing prerequisite commits")
+	}
+	endpoint, err := transport.NewEndpoint("bundle://"+p, nil)
+	if err != nil {
+		die_error("bad bundle: %v", err)
+		return err
+	}
+	t, err := client.NewTransport(ctx, endpoint, transport.DOWNLOAD, r.verbose)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", file, err)
+		return err
+	}
+	for _, ref := range h.References {
+		if _, err := r.doFetch(ctx, t, file, &DoFetchOptions{
+			Name:        string(ref.Name),
+			Limit:       opts.Limit,
+			Force:       opts.Force,
+			FetchAlways: true,
+			SkipLarges:  opts.SkipLarges,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushBundle: push the bundle file to the remote, all references of the bundle are updated atomically. The remote
+// rejects non-fast-forward updates unless force.
+func (r *Repository) PushBundle(ctx context.Context, file string, force bool) error {
+	b, err := bundle.Open(file)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", file, err)
+		return err
+	}
+	defer b.Close()
+	if err := b.Verify(ctx); err != nil {
+		die_error("%s is not a valid bundle: %v", file, err)
+		return err
+	}
+	t, err := r.newTransport(ctx, transport.UPLOAD)
+	if err != nil {
+		return err
+	}
+	cleanedRemote := r.cleanedRemote()
+	bp, ok := t.(transport.BundlePusher)
+	if !ok {
+		err := &transport.ErrMissingCapability{Remote: cleanedRemote, Capability: transport.CapabilityBundlePush}
+		die_error("%v", err)
+		return err
+	}
+	if ref, err := t.FetchReference(ctx, plumbing.HEAD); err == nil {
+		if err := ref.Capabilities.Require(cleanedRemote, transport.CapabilityBundlePush); err != nil {
+			die_error("%v", err)
+			return err
+		}
+	}
+	fd, err := os.Open(file)
+	if err != nil {
+		die_error("open bundle '%s' error: %v", file, err)
+		return err
+	}
+	defer fd.Close()
+	rc, err := bp.PushBundle(ctx, fd, b.Size(), force)
+	if err != nil {
+		die_error("Push failed: %v", err)
+		return err
+	}
+	reports, unpackReason, err := r.odb.OnReports(ctx, rc)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			die_error("Push failed: %v", lastErr)
+			return lastErr
+		}
+		die_error("parse report error: %v", err)
+		return err
+	}
+	_ = rc.Close()
+	results := make(map[plumbing.ReferenceName]*odb.Report, len(reports))
+	for _, rp := range reports {
+		results[rp.ReferenceName] = rp
+	}
+	var rejected error
+	fmt.Fprintf(os.Stderr, "To: %s\n", cleanedRemote)
+	for _, ref := range b.References {
+		result, ok := results[ref.Name]
+		if !ok {
+			reason := unpackReason
+			if len(reason) == 0 {
+				reason = "no status reported"
+			}
+			result = &odb.Report{ReferenceName: ref.Name, Rejected: true, Reason: reason}
+		}
+		if result.Rejected {
+			for _, s := range strengthen.StrSplitSkipEmpty(result.Reason, 2, '\n') {
+				fmt.Fprintf(os.Stderr, "remote: %s\n", s)
+			}
+			fmt.Fprintf(os.Stderr, " \x1b[31m! [remote rejected]\x1b[0m %s\n", ref.Name.Short())
+			if rejected == nil {
+				rejected = errors.New(result.Reason)
+			}
+			continue
+		}
+		newRev := plumbing.NewHash(ref.Hash)
+		r.updateTrackingReference(ref.Name, newRev)
+		fmt.Fprintf(os.Stderr, " * %s -> %s\n", shortHash(newRev), shortReferenceName(ref.Name))
+	}
+	if rejected != nil {
+		error_red("failed to push some refs to '%s'", cleanedRemote)
+		return rejected
+	}
+	return nil
+}
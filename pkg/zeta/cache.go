@@ -0,0 +1,332 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/modules/zeta/refs"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+var (
+	ErrSharingRootNotSet = errors.New("core.sharingRoot is not set")
+)
+
+func parseCacheMaxSize(cfg *config.Config, values map[string]StringArray) int64 {
+	if s, ok := getFromValueOrEnv("cache.maxSize", ENV_ZETA_CACHE_MAX_SIZE, values); ok {
+		if size, err := strengthen.ParseSize(s); err == nil && size > 0 {
+			return size
+		}
+	}
+	return cfg.Cache.MaxSize.Size
+}
+
+type CacheOptions struct {
+	Worktree string
+	Values   []string
+	Verbose  bool
+}
+
+// Cache: the shared blob cache configured by core.sharingRoot.
+type Cache struct {
+	*backend.SharingCache
+	maxSize int64 // 0: unlimited
+	verbose bool
+}
+
+// OpenCache: resolve core.sharingRoot from the current repository or the global config.
+func OpenCache(opts *CacheOptions) (*Cache, error) {
+	var cfg *config.Config
+	_, zetaDir, err := FindZetaDir(opts.Worktree)
+	if err == nil {
+		cfg, err = config.Load(zetaDir)
+	} else {
+		cfg, err = config.LoadBaseline()
+	}
+	if err != nil {
+		die_error("%v", err)
+		return nil, err
+	}
+	values := valuesMapArray(opts.Values)
+	sharingRoot, ok := parseSharingRoot(cfg, values)
+	if !ok {
+		die_error("%v", ErrSharingRootNotSet)
+		return nil, ErrSharingRootNotSet
+	}
+	return &Cache{
+		SharingCache: backend.NewSharingCache(sharingRoot),
+		maxSize:      parseCacheMaxSize(cfg, values),
+		verbose:      opts.Verbose,
+	}, nil
+}
+
+func (c *Cache) DbgPrint(format string, args ...any) {
+	if !c.verbose {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	var buffer bytes.Buffer
+	for _, s := range strings.Split(message, "\n") {
+		_, _ = buffer.WriteString("\x1b[33m* ")
+		_, _ = buffer.WriteString(s)
+		_, _ = buffer.WriteString("\x1b[0m\n")
+	}
+	_, _ = os.Stderr.Write(buffer.Bytes())
+}
+
+type cachePinner struct {
+	pinned map[plumbing.Hash]bool
+	seen   map[plumbing.Hash]bool
+}
+
+func (p *cachePinner) pinFragments(ctx context.Context, o *odb.ODB, oid plumbing.Hash) error {
+	ff, err := o.Fragments(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range ff.Entries {
+		p.pinned[e.Hash] = true
+	}
+	return nil
+}
+
+func (p *cachePinner) pinTree(ctx context.Context, o *odb.ODB, oid plumbing.Hash) error {
+	if p.seen[oid] {
+		return nil
+	}
+	p.seen[oid] = true
+	t, err := o.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		return nil // sparse checkout or partial metadata
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := p.pinTree(ctx, o, e.Hash); err != nil {
+				return err
+			}
+		case object.FragmentsObject:
+			if err := p.pinFragments(ctx, o, e.Hash); err != nil && !plumbing.IsNoSuchObject(err) {
+				return err
+			}
+		case object.BlobObject:
+			p.pinned[e.Hash] = true
+		}
+	}
+	return nil
+}
+
+// pinCheckout: pin blobs referenced by the checkout's HEAD and index.
+func (p *cachePinner) pinCheckout(ctx context.Context, sharingRoot, zetaDir string) error {
+	o, err := odb.NewODB(zetaDir, backend.WithSharingRoot(sharingRoot))
+	if err != nil {
+		return err
+	}
+	defer o.Close()
+	if ref, err := refs.ReferenceResolve(refs.NewBackend(zetaDir), plumbing.HEAD); err == nil {
+		cc, err := o.Commit(ctx, ref.Hash())
+		if err != nil && !plumbing.IsNoSuchObject(err) {
+			return err
+		}
+		if cc != nil {
+			if err := p.pinTree(ctx, o, cc.Tree); err != nil {
+				return err
+			}
+		}
+	}
+	idx, err := o.Index()
+	if err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		if !e.Mode.IsFragments() {
+			p.pinned[e.Hash] = true
+			continue
+		}
+		if err := p.pinFragments(ctx, o, e.Hash); err != nil && !plumbing.IsNoSuchObject(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pinned: blobs referenced by the HEAD or index of any registered checkout.
+func (c *Cache) Pinned(ctx context.Context) (map[plumbing.Hash]bool, error) {
+	checkouts, err := c.Checkouts()
+	if err != nil {
+		return nil, err
+	}
+	p := &cachePinner{pinned: make(map[plumbing.Hash]bool), seen: make(map[plumbing.Hash]bool)}
+	for _, zetaDir := range checkouts {
+		if _, err := os.Stat(filepath.Join(zetaDir, "zeta.toml")); err != nil {
+			c.DbgPrint("skip missing checkout %s", zetaDir)
+			continue
+		}
+		c.DbgPrint("pin objects referenced by %s", zetaDir)
+		if err := p.pinCheckout(ctx, c.Root(), zetaDir); err != nil {
+			return nil, fmt.Errorf("resolve objects referenced by '%s' error: %w", zetaDir, err)
+		}
+	}
+	return p.pinned, nil
+}
+
+type CacheStatus struct {
+	Root         string `json:"root"`
+	Checkouts    int    `json:"checkouts"`
+	Missing      int    `json:"missing_checkouts"`
+	LooseObjects int    `json:"loose_objects"`
+	LooseSize    int64  `json:"loose_size"`
+	PackedSize   int64  `json:"packed_size"`
+	Size         int64  `json:"size"`
+	MaxSize      int64  `json:"max_size"`
+	Pinned       int    `json:"pinned_objects"`
+	PinnedSize   int64  `json:"pinned_size"`
+}
+
+func (c *Cache) Status(ctx context.Context, w io.Writer, asJSON bool) error {
+	checkouts, err := c.Checkouts()
+	if err != nil {
+		die_error("read checkouts error: %v", err)
+		return err
+	}
+	u, err := c.Usage(ctx)
+	if err != nil {
+		die_error("scan cache error: %v", err)
+		return err
+	}
+	pinned, err := c.Pinned(ctx)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	st := &CacheStatus{
+		Root:         c.Root(),
+		Checkouts:    len(checkouts),
+		LooseObjects: len(u.Objects),
+		LooseSize:    u.LooseSize,
+		PackedSize:   u.PackedSize,
+		Size:         u.Size(),
+		MaxSize:      c.maxSize,
+	}
+	for _, p := range checkouts {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			st.Missing++
+		}
+	}
+	for _, o := range u.Objects {
+		if pinned[o.Hash] {
+			st.Pinned++
+			st.PinnedSize += o.Size
+		}
+	}
+	if asJSON {
+		return json.NewEncoder(w).Encode(st)
+	}
+	maxSize := W("unlimited")
+	if st.MaxSize > 0 {
+		maxSize = strengthen.HumanateSize(st.MaxSize)
+	}
+	fmt.Fprintf(w, "%s %s\n", W("cache root:"), st.Root)
+	fmt.Fprintf(w, "%s %d (%s %d)\n", W("checkouts:"), st.Checkouts, W("missing"), st.Missing)
+	fmt.Fprintf(w, "%s %d, %s\n", W("loose blobs:"), st.LooseObjects, strengthen.HumanateSize(st.LooseSize))
+	fmt.Fprintf(w, "%s %d, %s\n", W("pinned blobs:"), st.Pinned, strengthen.HumanateSize(st.PinnedSize))
+	fmt.Fprintf(w, "%s %s\n", W("packed blobs:"), strengthen.HumanateSize(st.PackedSize))
+	fmt.Fprintf(w, "%s %s / %s\n", W("size:"), strengthen.HumanateSize(st.Size), maxSize)
+	return nil
+}
+
+type CachePruneOptions struct {
+	MaxSize int64 // overwrite cache.maxSize, -1: use cache.maxSize
+	DryRun  bool
+	Quiet   bool
+}
+
+func (c *Cache) Prune(ctx context.Context, opts *CachePruneOptions) error {
+	// unlimited: only cleanup the checkout registry and the access index
+	maxSize := int64(math.MaxInt64)
+	switch {
+	case opts.MaxSize >= 0:
+		maxSize = opts.MaxSize
+	case c.maxSize > 0:
+		maxSize = c.maxSize
+	}
+	pinned, err := c.Pinned(ctx)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	result, err := c.SharingCache.Prune(ctx, &backend.CachePruneOptions{MaxSize: maxSize, Pinned: pinned, DryRun: opts.DryRun})
+	if err != nil {
+		die_error("prune cache error: %v", err)
+		return err
+	}
+	for _, p := range result.RemovedCheckouts {
+		c.DbgPrint("unregister missing checkout %s", p)
+	}
+	if opts.Quiet {
+		return nil
+	}
+	if opts.DryRun {
+		for _, oid := range result.Evicted {
+			fmt.Fprintf(os.Stdout, "%s %s\n", W("Would evict"), oid)
+		}
+	}
+	fmt.Fprintf(os.Stderr, W("evicted %d blobs, reclaimed %s, cache size %s\n"), len(result.Evicted), strengthen.HumanateSize(result.EvictedSize), strengthen.HumanateSize(result.Size))
+	if result.Size > maxSize {
+		warn("cache size still exceeds %s, %s is pinned by checkouts or was accessed within %v", strengthen.HumanateSize(maxSize), strengthen.HumanateSize(result.PinnedUnevictable), backend.CacheGracePeriod)
+	}
+	return nil
+}
+
+func (c *Cache) Verify(ctx context.Context, fix bool) error {
+	result, err := c.SharingCache.Verify(ctx, fix)
+	if err != nil {
+		die_error("verify cache error: %v", err)
+		return err
+	}
+	for _, oid := range result.Corrupted {
+		fmt.Fprintf(os.Stdout, "%s %s\n", W("corrupted"), oid)
+	}
+	fmt.Fprintf(os.Stderr, W("checked %d blobs, corrupted %d, removed %d\n"), result.Checked, len(result.Corrupted), result.Removed)
+	if len(result.Corrupted) != result.Removed {
+		return backend.ErrCorruptedBlob
+	}
+	return nil
+}
+
+// enforceCacheLimit: evict blobs from the sharing root when this process made it exceed cache.maxSize.
+func (r *Repository) enforceCacheLimit(ctx context.Context) {
+	sc := r.odb.SharingCache()
+	if sc == nil || !r.odb.Grown() {
+		return
+	}
+	maxSize := parseCacheMaxSize(r.Config, r.values)
+	if maxSize <= 0 {
+		return
+	}
+	u, err := sc.Usage(ctx)
+	if err != nil || u.Size() <= maxSize {
+		return
+	}
+	c := &Cache{SharingCache: sc, maxSize: maxSize, verbose: r.verbose}
+	_ = c.Prune(ctx, &CachePruneOptions{MaxSize: -1, Quiet: r.quiet})
+}
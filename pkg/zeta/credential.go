@@ -0,0 +1,134 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/antgroup/hugescm/modules/keyring"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/transport/credential"
+)
+
+// useKeyringBackend: -X credential.keyring=file OR ZETA_KEYRING_BACKEND=file OR zeta config credential.keyring file
+func useKeyringBackend(cfg *config.Config, values map[string]StringArray) error {
+	name, ok := getFromValueOrEnv("credential.keyring", keyring.ENV_ZETA_KEYRING_BACKEND, values)
+	if !ok {
+		name = cfg.Credential.Keyring
+	}
+	return keyring.UseBackend(name)
+}
+
+type CredentialOptions struct {
+	Worktree string
+	Values   []string
+	Verbose  bool
+}
+
+// loadCredentialConfig: credentials can be managed outside of repository, the global config is used.
+func loadCredentialConfig(opts *CredentialOptions) (*config.Config, map[string]StringArray, error) {
+	var zetaDir string
+	if _, dir, err := FindZetaDir(opts.Worktree); err == nil {
+		zetaDir = dir
+	}
+	cfg, err := config.Load(zetaDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := valuesMapArray(opts.Values)
+	if err := useKeyringBackend(cfg, values); err != nil {
+		return nil, nil, err
+	}
+	return cfg, values, nil
+}
+
+type CredentialItem struct {
+	URL      string `json:"url"`
+	UserName string `json:"username"`
+}
+
+// ListCredentials: list URLs and usernames stored in the keyring, passwords are never shown.
+func ListCredentials(ctx context.Context, opts *CredentialOptions, formatJSON bool) error {
+	if _, _, err := loadCredentialConfig(opts); err != nil {
+		die_error("%v", err)
+		return err
+	}
+	targets, err := keyring.List(ctx)
+	if err != nil {
+		die_error("list credentials: %v", err)
+		return err
+	}
+	items := make([]*CredentialItem, 0, len(targets))
+	for _, target := range targets {
+		cred, err := keyring.Find(ctx, target)
+		if err != nil {
+			continue
+		}
+		items = append(items, &CredentialItem{URL: target, UserName: cred.UserName})
+	}
+	if formatJSON {
+		return json.NewEncoder(os.Stdout).Encode(items)
+	}
+	for _, item := range items {
+		fmt.Fprintf(os.Stdout, "%s\t%s\n", item.URL, item.UserName)
+	}
+	return nil
+}
+
+// credentialBaseURL: credentials are stored by scheme and host, see http client baseCredentailsURL
+func credentialBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("'%s' is not a http/https URL", rawURL)
+	}
+	u.Path = ""
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.User = nil
+	return u, nil
+}
+
+// EraseCredentials: erase credentials of the URLs from the keyring and the configured credential helpers.
+func EraseCredentials(ctx context.Context, opts *CredentialOptions, urls []string) error {
+	cfg, values, err := loadCredentialConfig(opts)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	for _, rawURL := range urls {
+		u, err := credentialBaseURL(rawURL)
+		if err != nil {
+			die_error("bad url: %v", err)
+			return err
+		}
+		helpers := credential.Helpers(parseCredentialHelpers(cfg, values, rawURL))
+		hc := credential.FromURL(u)
+		helpers.Reject(ctx, hc)
+		target := u.String()
+		err = keyring.Discard(ctx, target)
+		if errors.Is(err, keyring.ErrNotFound) && len(helpers) == 0 {
+			fmt.Fprintf(os.Stderr, "no stored credentials for '%s'\n", target)
+			continue
+		}
+		if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			if len(helpers) == 0 {
+				die_error("erase '%s': %v", target, err)
+				return err
+			}
+			warn("erase '%s' from keyring: %v", target, err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "erased credentials for '%s'\n", target)
+	}
+	return nil
+}
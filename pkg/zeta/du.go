@@ -0,0 +1,125 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type DuOptions struct {
+	Revision string
+	Path     string
+	MaxDepth int // -1: unlimited
+	JSON     bool
+}
+
+type DuEntry struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Files     int    `json:"files"`
+	Fragments int    `json:"fragments"`
+}
+
+type DuResult struct {
+	Revision     plumbing.Hash `json:"revision"`
+	Entries      []*DuEntry    `json:"entries"`
+	MissingTrees int           `json:"missing_trees"`
+}
+
+type duWalker struct {
+	r        *Repository
+	maxDepth int
+	result   *DuResult
+}
+
+// walk: aggregate sizes of the tree, directories are reported in post-order like du(1).
+func (d *duWalker) walk(ctx context.Context, t *object.Tree, name string, depth int) (*DuEntry, error) {
+	current := &DuEntry{Path: name}
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			st, err := d.r.odb.Tree(ctx, e.Hash)
+			if plumbing.IsNoSuchObject(err) {
+				// sparse checkout or partial metadata
+				d.result.MissingTrees++
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			sub, err := d.walk(ctx, st, path.Join(name, e.Name), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			current.Size += sub.Size
+			current.Files += sub.Files
+			current.Fragments += sub.Fragments
+		case object.FragmentsObject:
+			// tree entry size of fragments is the size of the origin file
+			current.Size += e.Size
+			current.Files++
+			current.Fragments++
+		case object.BlobObject:
+			current.Size += e.Size
+			current.Files++
+		}
+	}
+	if d.maxDepth < 0 || depth <= d.maxDepth {
+		d.result.Entries = append(d.result.Entries, current)
+	}
+	return current, nil
+}
+
+// Du: disk usage of directories at the revision, calculated from tree entries instead of the worktree.
+func (r *Repository) Du(ctx context.Context, w io.Writer, opts *DuOptions) error {
+	rev, p := opts.Revision, strings.TrimSuffix(opts.Path, "/")
+	if len(rev) == 0 {
+		rev = string(plumbing.HEAD)
+	}
+	oid, err := r.Revision(ctx, rev)
+	if err != nil && len(p) == 0 && len(opts.Revision) != 0 {
+		// zeta du <path>
+		r.DbgPrint("'%s' is not a revision, treat it as path", rev)
+		if oid, err = r.Revision(ctx, string(plumbing.HEAD)); err == nil {
+			p = strings.TrimSuffix(opts.Revision, "/")
+		}
+	}
+	if err != nil {
+		die_error("resolve revision '%s': %v", rev, err)
+		return err
+	}
+	t, err := r.readTree(ctx, oid, p)
+	if err != nil {
+		die_error("read tree '%s:%s': %v", rev, p, err)
+		return err
+	}
+	name := "."
+	if len(p) != 0 {
+		name = p
+	}
+	d := &duWalker{r: r, maxDepth: opts.MaxDepth, result: &DuResult{Revision: oid, Entries: make([]*DuEntry, 0, 100)}}
+	if _, err := d.walk(ctx, t, name, 0); err != nil {
+		die_error("walk tree: %v", err)
+		return err
+	}
+	if opts.JSON {
+		return json.NewEncoder(w).Encode(d.result)
+	}
+	for _, e := range d.result.Entries {
+		fmt.Fprintf(w, "%-10s %8d  %s\n", strengthen.HumanateSize(e.Size), e.Files, e.Path)
+	}
+	if d.result.MissingTrees != 0 {
+		warn("%d trees are not present locally (sparse checkout or partial metadata), their sizes are not counted", d.result.MissingTrees)
+	}
+	return nil
+}
@@ -7,10 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/pkg/transport"
 	"github.com/antgroup/hugescm/pkg/zeta/odb"
@@ -38,25 +41,124 @@ type FetchResult struct {
 	FETCH_HEAD plumbing.Hash
 }
 
-func (r *Repository) batch(ctx context.Context, t transport.Transport, oids []plumbing.Hash) error {
-	if len(oids) == 0 {
+const (
+	batchMaxAttempts = 5
+	batchRetryDelay  = time.Second
+	batchMaxDelay    = 16 * time.Second
+)
+
+// batchCapabilities: capabilities of the remote for batch downloads, transports that can't resume skip the discovery.
+func (r *Repository) batchCapabilities(ctx context.Context, t transport.Transport) transport.Capabilities {
+	if _, ok := t.(transport.ResumableBatch); !ok {
 		return nil
 	}
-	rc, err := t.BatchObjects(ctx, oids)
+	ref, err := t.FetchReference(ctx, plumbing.HEAD)
 	if err != nil {
-		return err
+		r.DbgPrint("discover capabilities: %v", err)
+		return nil
 	}
-	if err := r.odb.Unpack(rc, len(oids), r.quiet); err != nil {
-		_ = rc.Close()
+	return ref.Capabilities
+}
+
+func (r *Repository) batchOnce(ctx context.Context, t transport.Transport, caps transport.Capabilities, oids []plumbing.Hash) ([]plumbing.Hash, error) {
+	var rc transport.SessionReader
+	var err error
+	if rt, ok := t.(transport.ResumableBatch); ok && caps.Has(transport.CapabilityResumableBatch) {
+		rc, err = rt.ResumableBatchObjects(ctx, oids)
+	} else {
+		rc, err = t.BatchObjects(ctx, oids)
+	}
+	if err != nil {
+		return nil, err
+	}
+	received, err := r.odb.Unpack(rc, len(oids), r.quiet)
+	_ = rc.Close()
+	if err != nil {
 		if lastErr := rc.LastError(); lastErr != nil {
-			return lastErr
+			return received, lastErr
 		}
-		return err
+		return received, err
+	}
+	return received, nil
+}
+
+// batchRejected: the server answered the batch request with a client error, such as 401, 403 or 404, retrying does
+// not help. Transport errors, interrupted streams and server errors are retried.
+func batchRejected(err error) bool {
+	var status int
+	var se *zeta.ErrStatusCode
+	var ec interface{ Status() int }
+	switch {
+	case errors.As(err, &se):
+		status = se.Code
+	case errors.As(err, &ec):
+		status = ec.Status()
+	}
+	return status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
+
+// batch: download objects in batch, an interrupted stream is retried with exponential backoff and only the objects
+// that have not been written yet are requested again.
+func (r *Repository) batch(ctx context.Context, t transport.Transport, caps transport.Capabilities, oids []plumbing.Hash) error {
+	delay := batchRetryDelay
+	for attempt := 1; len(oids) != 0; attempt++ {
+		received, err := r.batchOnce(ctx, t, caps, oids)
+		if err == nil {
+			return nil
+		}
+		if batchRejected(err) || attempt >= batchMaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if len(received) != 0 {
+			seen := make(map[plumbing.Hash]bool, len(received))
+			for _, o := range received {
+				seen[o] = true
+			}
+			remaining := make([]plumbing.Hash, 0, len(oids)-len(received))
+			for _, o := range oids {
+				if !seen[o] {
+					remaining = append(remaining, o)
+				}
+			}
+			oids = remaining
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v, %s %d %s %v ...\n", W("batch download interrupted"), err, W("retry"), len(oids), W("objects after"), delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, batchMaxDelay)
 	}
-	_ = rc.Close()
 	return nil
 }
 
+// batchWantHave: download the blobs of target which are not in have in one request before the objects of target are
+// counted, the remote finds them with its bitmap index. Sparse checkouts count the objects as before. Objects that are
+// not received, such as the blobs of have that are missing locally, are downloaded by fetchObjects, so errors are
+// ignored.
+func (r *Repository) batchWantHave(ctx context.Context, t transport.Transport, target, have plumbing.Hash, sizeLimit int64) {
+	wt, ok := t.(transport.WantHaveBatch)
+	if !ok || have.IsZero() || len(r.Core.SparseDirs) != 0 || !r.batchCapabilities(ctx, t).Has(transport.CapabilityBatchWantHave) {
+		return
+	}
+	if largeSize := r.largeSize(); sizeLimit < 0 || sizeLimit > largeSize {
+		sizeLimit = largeSize
+	}
+	rc, err := wt.WantHaveObjects(ctx, target, have, sizeLimit)
+	if err != nil {
+		r.DbgPrint("batch want %s have %s: %v", target, have, err)
+		return
+	}
+	if _, err := r.odb.Unpack(rc, -1, r.quiet); err != nil {
+		r.DbgPrint("batch want %s have %s: %v", target, have, err)
+	}
+	_ = rc.Close()
+	if err := r.odb.Reload(); err != nil {
+		r.DbgPrint("reload odb: %v", err)
+	}
+}
+
 func (r *Repository) fetch(ctx context.Context, t transport.Transport, opts *FetchOptions) error {
 	metaOpts := &transport.MetadataOptions{
 		DeepenFrom: opts.DeepenFrom,
@@ -82,6 +184,7 @@ func (r *Repository) fetch(ctx context.Context, t transport.Transport, opts *Fet
 	if err := r.odb.Reload(); err != nil {
 		return err
 	}
+	r.batchWantHave(ctx, t, opts.Target, opts.Have, opts.SizeLimit)
 	return r.fetchObjects(ctx, t, opts.Target, opts.SizeLimit, opts.SkipLarges)
 }
 
@@ -234,11 +337,16 @@ func (r *Repository) prepareFetch(ctx context.Context, current *plumbing.Referen
 
 // DoFetch: Fetch reference or commit
 func (r *Repository) DoFetch(ctx context.Context, opts *DoFetchOptions) (*FetchResult, error) {
-	current, refname, err := r.resolveRef(opts.ReferenceName())
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
 	if err != nil {
 		return nil, err
 	}
-	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	return r.doFetch(ctx, t, r.cleanedRemote(), opts)
+}
+
+// doFetch: fetch the reference from the transport, from is the remote shown to the user.
+func (r *Repository) doFetch(ctx context.Context, t transport.Transport, from string, opts *DoFetchOptions) (*FetchResult, error) {
+	current, refname, err := r.resolveRef(opts.ReferenceName())
 	if err != nil {
 		return nil, err
 	}
@@ -287,10 +395,10 @@ func (r *Repository) DoFetch(ctx context.Context, opts *DoFetchOptions) (*FetchR
 	if opts.Unshallow {
 		_ = r.odb.Unshallow()
 	}
-	fmt.Fprintf(os.Stderr, "From: %s\n", r.cleanedRemote())
+	fmt.Fprintf(os.Stderr, "From: %s\n", from)
 	switch {
 	case refname.IsBranch():
-		originBranch := plumbing.NewRemoteReferenceName(plumbing.Origin, refname.BranchName())
+		originBranch := plumbing.NewRemoteReferenceName(r.remoteName(), refname.BranchName())
 		if err := r.ReferenceUpdate(plumbing.NewHashReference(originBranch, o.Target), nil); err != nil {
 			die_error("update-ref '%s' error: %v", originBranch, err)
 			return nil, err
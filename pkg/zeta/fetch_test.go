@@ -0,0 +1,73 @@
+package zeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+type statusError int
+
+func (e statusError) Error() string {
+	return http.StatusText(int(e))
+}
+
+func (e statusError) Status() int {
+	return int(e)
+}
+
+type batchTransport struct {
+	transport.Transport
+	errs      []error
+	calls     int
+	resumable int
+}
+
+func (t *batchTransport) next() error {
+	err := t.errs[min(t.calls, len(t.errs)-1)]
+	t.calls++
+	return err
+}
+
+func (t *batchTransport) BatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	return nil, t.next()
+}
+
+func (t *batchTransport) ResumableBatchObjects(ctx context.Context, oids []plumbing.Hash) (transport.SessionReader, error) {
+	t.resumable++
+	return nil, t.next()
+}
+
+func TestBatchRejected(t *testing.T) {
+	require.True(t, batchRejected(statusError(http.StatusForbidden)))
+	require.True(t, batchRejected(&zeta.ErrStatusCode{Code: 404}))
+	require.False(t, batchRejected(statusError(http.StatusServiceUnavailable)))
+	require.False(t, batchRejected(statusError(http.StatusTooManyRequests)))
+	require.False(t, batchRejected(&zeta.ErrStatusCode{Code: 500}))
+	require.False(t, batchRejected(errors.New("connection reset by peer")))
+}
+
+func TestBatchRetry(t *testing.T) {
+	r := &Repository{}
+	ctx := context.Background()
+	oids := []plumbing.Hash{plumbing.NewHash("b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1b1")}
+
+	// rejected by the server, the checked stream is not requested without the capability
+	bt := &batchTransport{errs: []error{statusError(http.StatusForbidden)}}
+	require.Error(t, r.batch(ctx, bt, nil, oids))
+	require.Equal(t, 1, bt.calls)
+	require.Equal(t, 0, bt.resumable)
+
+	// transport errors are retried
+	bt = &batchTransport{errs: []error{errors.New("connection reset by peer"), statusError(http.StatusNotFound)}}
+	err := r.batch(ctx, bt, transport.Capabilities{transport.CapabilityResumableBatch}, oids)
+	require.Equal(t, statusError(http.StatusNotFound), err)
+	require.Equal(t, 2, bt.calls)
+	require.Equal(t, 2, bt.resumable)
+}
@@ -0,0 +1,177 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/wildmatch"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+type LsRemoteOptions struct {
+	Heads    bool
+	Tags     bool
+	Patterns []string
+	JSON     bool
+}
+
+// remoteRefMatcher: like 'git ls-remote', a pattern matches the full reference name or its trailing components,
+// 'dev' matches 'refs/heads/dev' and 'refs/tags/dev'.
+type remoteRefMatcher struct {
+	names      []string
+	wildmatchs []*wildmatch.Wildmatch
+}
+
+func newRemoteRefMatcher(patterns []string) *remoteRefMatcher {
+	m := &remoteRefMatcher{}
+	for _, p := range patterns {
+		if len(p) == 0 {
+			continue
+		}
+		if !strings.ContainsAny(p, escapeChars) {
+			m.names = append(m.names, p)
+			continue
+		}
+		m.wildmatchs = append(m.wildmatchs, wildmatch.NewWildmatch(p, wildmatch.SystemCase, wildmatch.Contents))
+	}
+	return m
+}
+
+func (m *remoteRefMatcher) Match(refname string) bool {
+	if len(m.names) == 0 && len(m.wildmatchs) == 0 {
+		return true
+	}
+	for _, n := range m.names {
+		if refname == n || strings.HasSuffix(refname, "/"+n) {
+			return true
+		}
+	}
+	for _, w := range m.wildmatchs {
+		for s := refname; ; {
+			if w.Match(s) {
+				return true
+			}
+			_, next, ok := strings.Cut(s, "/")
+			if !ok {
+				break
+			}
+			s = next
+		}
+	}
+	return false
+}
+
+// listRemoteReferences: all remote references under the prefix, following the pages.
+func (r *Repository) listRemoteReferences(ctx context.Context, t transport.Transport, prefix string) (*transport.References, error) {
+	opts := &transport.ListReferencesOptions{Prefix: prefix}
+	var result *transport.References
+	for {
+		page, err := t.ListReferences(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		r.DbgPrint("list remote references prefix '%s' cursor '%s': %d references", prefix, opts.Cursor, len(page.References))
+		if result == nil {
+			result = page
+		} else {
+			result.References = append(result.References, page.References...)
+		}
+		if len(page.NextCursor) == 0 || page.NextCursor == opts.Cursor {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	result.NextCursor = ""
+	return result, nil
+}
+
+func (r *Repository) LsRemote(ctx context.Context, opts *LsRemoteOptions) error {
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	if err != nil {
+		die_error("new transport error: %v", err)
+		return err
+	}
+	var prefixes []string
+	if opts.Heads {
+		prefixes = append(prefixes, plumbing.ReferencePrefix+"heads/")
+	}
+	if opts.Tags {
+		prefixes = append(prefixes, plumbing.ReferencePrefix+"tags/")
+	}
+	if len(prefixes) == 0 {
+		prefixes = append(prefixes, "")
+	}
+	var result *transport.References
+	for _, prefix := range prefixes {
+		refs, err := r.listRemoteReferences(ctx, t, prefix)
+		if err != nil {
+			die_error("ls-remote '%s' error: %v", r.cleanedRemote(), err)
+			return err
+		}
+		if result == nil {
+			result = refs
+			continue
+		}
+		result.References = append(result.References, refs.References...)
+	}
+	m := newRemoteRefMatcher(opts.Patterns)
+	references := make([]*transport.ReferenceEntry, 0, len(result.References))
+	for _, e := range result.References {
+		if m.Match(string(e.Name)) {
+			references = append(references, e)
+		}
+	}
+	result.References = references
+	if opts.JSON {
+		return json.NewEncoder(os.Stdout).Encode(result)
+	}
+	w := NewPrinter(ctx)
+	defer w.Close()
+	if !opts.Heads && !opts.Tags && len(opts.Patterns) == 0 {
+		for _, e := range references {
+			if string(e.Name) == result.HEAD {
+				fmt.Fprintf(w, "%s\t%s\n", e.Hash, plumbing.HEAD)
+				break
+			}
+		}
+	}
+	for _, e := range references {
+		fmt.Fprintf(w, "%s\t%s\n", e.Hash, e.Name)
+		if len(e.Peeled) != 0 {
+			fmt.Fprintf(w, "%s\t%s^{}\n", e.Peeled, e.Name)
+		}
+	}
+	return nil
+}
+
+// ListRemoteBranch: 'zeta branch -r', remote branches are shown as '<remote>/<branch>'.
+func (r *Repository) ListRemoteBranch(ctx context.Context, pattern []string) error {
+	t, err := r.newTransport(ctx, transport.DOWNLOAD)
+	if err != nil {
+		die_error("new transport error: %v", err)
+		return err
+	}
+	refs, err := r.listRemoteReferences(ctx, t, plumbing.ReferencePrefix+"heads/")
+	if err != nil {
+		die_error("list remote branches error: %v", err)
+		return err
+	}
+	m := NewMatcher(pattern)
+	w := NewPrinter(ctx)
+	defer w.Close()
+	for _, e := range refs.References {
+		branchName := e.Name.BranchName()
+		if !m.Match(branchName) {
+			continue
+		}
+		fmt.Fprintf(w, "  %s/%s\n", r.remoteName(), branchName)
+	}
+	return nil
+}
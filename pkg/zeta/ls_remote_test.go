@@ -0,0 +1,54 @@
+package zeta
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteRefMatcher(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		refname  string
+		want     bool
+	}{
+		{nil, "refs/heads/dev", true},
+		{[]string{"dev"}, "refs/heads/dev", true},
+		{[]string{"dev"}, "refs/tags/dev", true},
+		{[]string{"dev"}, "refs/heads/feature/dev", true},
+		{[]string{"dev"}, "refs/heads/devel", false},
+		{[]string{"refs/heads/dev"}, "refs/heads/dev", true},
+		{[]string{"feature/*"}, "refs/heads/feature/a", true},
+		{[]string{"v1.*"}, "refs/tags/v1.2", true},
+		{[]string{"v1.*"}, "refs/tags/v2.0", false},
+	}
+	for _, c := range cases {
+		if got := newRemoteRefMatcher(c.patterns).Match(c.refname); got != c.want {
+			t.Errorf("patterns %v match %s: got %v want %v", c.patterns, c.refname, got, c.want)
+		}
+	}
+}
+
+func TestListRemoteBranchRemoteName(t *testing.T) {
+	up, _ := newTestRepository(t, map[string][]byte{"a.txt": []byte("hello\n")})
+	r, _ := newTestRepository(t, map[string][]byte{"b.txt": []byte("world\n")})
+	require.NoError(t, r.AddRemote("upstream", up.baseDir, ""))
+	r, err := Open(context.Background(), &OpenOptions{Worktree: r.baseDir, Remote: "upstream", Quiet: true})
+	require.NoError(t, err)
+	defer r.Close()
+
+	stdout := os.Stdout
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = pw
+	err = r.ListRemoteBranch(context.Background(), nil)
+	os.Stdout = stdout
+	_ = pw.Close()
+	require.NoError(t, err)
+	out, err := io.ReadAll(pr)
+	require.NoError(t, err)
+	require.Equal(t, "  upstream/mainline\n", string(out))
+}
@@ -37,6 +37,7 @@ const (
 	ENV_ZETA_SSL_NO_VERIFY             = "ZETA_SSL_NO_VERIFY"
 	ENV_ZETA_TRANSPORT_MAX_ENTRIES     = "ZETA_TRANSPORT_MAX_ENTRIES"
 	ENV_ZETA_TRANSPORT_LARGE_SIZE      = "ZETA_TRANSPORT_LARGE_SIZE"
+	ENV_ZETA_CACHE_MAX_SIZE            = "ZETA_CACHE_MAX_SIZE"
 )
 
 var (
@@ -38,6 +38,12 @@ func (g *entriesGroup) clean() {
 	g.entries = g.entries[:0]
 }
 
+// refused: trees and fragments under the paths the user is not allowed to read are not sent by the server, their
+// files are skipped like the directories outside the sparse checkout.
+func (o *ODB) refused(oid plumbing.Hash) bool {
+	return !o.Exists(oid, true)
+}
+
 func (d *ODB) countingFragments(ctx context.Context, oid plumbing.Hash, g *entriesGroup, maxEntries int, fetcher Fetcher) error {
 	f, err := d.Fragments(ctx, oid)
 	if err != nil {
@@ -70,6 +76,9 @@ func (o *ODB) countingTreeObjects(ctx context.Context, oid plumbing.Hash, g *ent
 	}
 	for _, e := range t.Entries {
 		typ := e.Type()
+		if (typ == object.TreeObject || typ == object.FragmentsObject) && o.refused(e.Hash) {
+			continue
+		}
 		if typ == object.TreeObject {
 			if err := o.countingTreeObjects(ctx, e.Hash, g, maxEntries, fetcher); err != nil {
 				return err
@@ -114,6 +123,9 @@ func (o *ODB) sparseCountingTreeObjects(ctx context.Context, oid plumbing.Hash,
 	}
 	for _, e := range t.Entries {
 		typ := e.Type()
+		if (typ == object.TreeObject || typ == object.FragmentsObject) && o.refused(e.Hash) {
+			continue
+		}
 		if typ == object.FragmentsObject {
 			if err := o.countingFragments(ctx, e.Hash, g, maxEntries, fetcher); err != nil {
 				return err
@@ -235,3 +247,69 @@ func (o *ODB) CountingObjects(ctx context.Context, commit, deepenFrom plumbing.H
 	}
 	return nil
 }
+
+func (o *ODB) walkTreeBlobs(ctx context.Context, oid plumbing.Hash, m noder.Matcher, seen map[plumbing.Hash]bool, fn func(e *Entry) error) error {
+	t, err := o.Tree(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if o.refused(e.Hash) {
+				continue
+			}
+			if m == nil || m.Len() == 0 {
+				if err := o.walkTreeBlobs(ctx, e.Hash, nil, seen, fn); err != nil {
+					return err
+				}
+				continue
+			}
+			if sub, ok := m.Match(e.Name); ok {
+				if err := o.walkTreeBlobs(ctx, e.Hash, sub, seen, fn); err != nil {
+					return err
+				}
+			}
+		case object.FragmentsObject:
+			if o.refused(e.Hash) {
+				continue
+			}
+			f, err := o.Fragments(ctx, e.Hash)
+			if err != nil {
+				return err
+			}
+			for _, fe := range f.Entries {
+				if seen[fe.Hash] {
+					continue
+				}
+				seen[fe.Hash] = true
+				if err := fn(newEntry(fe.Hash, int64(fe.Size))); err != nil {
+					return err
+				}
+			}
+		default:
+			if len(e.Payload) != 0 || seen[e.Hash] {
+				continue
+			}
+			seen[e.Hash] = true
+			if err := fn(newEntry(e.Hash, e.Size)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WalkBlobs: walk blobs of the commit whether they exist or not, fragments are expanded, inline blobs are skipped
+// and each blob is reported once.
+func (o *ODB) WalkBlobs(ctx context.Context, commit plumbing.Hash, sparseDirs []string, fn func(e *Entry) error) error {
+	cc, err := o.ParseRevExhaustive(ctx, commit)
+	if err != nil {
+		return err
+	}
+	var m noder.Matcher
+	if len(sparseDirs) != 0 {
+		m = noder.NewSparseTreeMatcher(sparseDirs)
+	}
+	return o.walkTreeBlobs(ctx, cc.Tree, m, make(map[plumbing.Hash]bool), fn)
+}
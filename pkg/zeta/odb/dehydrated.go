@@ -0,0 +1,69 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package odb
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	dehydratedPath = "dehydrated"
+)
+
+// Dehydrated: paths whose worktree files were replaced by placeholders.
+func (d *ODB) Dehydrated() (map[string]bool, error) {
+	paths := make(map[string]bool)
+	fd, err := os.Open(filepath.Join(d.root, dehydratedPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return paths, nil
+		}
+		return nil, err
+	}
+	defer fd.Close()
+	br := bufio.NewScanner(fd)
+	for br.Scan() {
+		if p := strings.TrimSpace(br.Text()); len(p) != 0 {
+			paths[p] = true
+		}
+	}
+	return paths, br.Err()
+}
+
+func (d *ODB) SetDehydrated(paths map[string]bool) (err error) {
+	p := filepath.Join(d.root, dehydratedPath)
+	if len(paths) == 0 {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fd, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	bw := bufio.NewWriter(fd)
+	defer func() {
+		if e := bw.Flush(); err == nil && e != nil {
+			err = e
+		}
+	}()
+	for _, name := range names {
+		if _, err = bw.WriteString(name + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
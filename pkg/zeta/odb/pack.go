@@ -136,6 +136,61 @@ type Report struct {
 	Reason        string
 }
 
+// OnReports: report of atomic push, contains the status of each reference. When unpack fails, the remote may
+// not report the status of references, the unpack failure reason is returned.
+func (d *ODB) OnReports(ctx context.Context, reader io.Reader) (reports []*Report, unpackReason string, err error) {
+	r := pktline.NewScanner(reader)
+	var newLine bool
+	defer func() {
+		if newLine {
+			fmt.Fprintf(os.Stderr, "\n")
+		}
+	}()
+	for r.Scan() {
+		line := string(r.Bytes())
+		pos := strings.IndexByte(line, ' ')
+		if pos == -1 {
+			return nil, "", fmt.Errorf("bad report line: %s", line)
+		}
+		lab := line[0:pos]
+		substr := line[pos+1:]
+		if lab == "rate" {
+			fmt.Fprintf(os.Stderr, "\x1b[2K\rremote: %s", strings.TrimSpace(substr))
+			newLine = true
+			continue
+		}
+		if newLine {
+			// newLine fill
+			_, _ = os.Stderr.WriteString("\n")
+			newLine = false
+		}
+		switch lab {
+		case "unpack":
+			if substr != "ok" {
+				fmt.Fprintf(os.Stderr, "remote: unpack %s\n", substr)
+				unpackReason = substr
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "remote: unpack success\n")
+		case "ok":
+			refname, newRev, _ := strings.Cut(substr, " ")
+			reports = append(reports, &Report{ReferenceName: plumbing.ReferenceName(refname), NewRev: newRev})
+		case "ng":
+			refname, message, _ := strings.Cut(substr, " ")
+			reports = append(reports, &Report{ReferenceName: plumbing.ReferenceName(refname), Reason: message, Rejected: true})
+		case "status":
+			fmt.Fprintf(os.Stderr, "remote: %s\n", substr)
+		}
+	}
+	if err := r.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(reports) == 0 && len(unpackReason) == 0 {
+		return nil, "", io.ErrUnexpectedEOF
+	}
+	return reports, unpackReason, nil
+}
+
 func (d *ODB) OnReport(ctx context.Context, refname plumbing.ReferenceName, reader io.Reader) (result *Report, err error) {
 	var b strings.Builder
 	r := pktline.NewScanner(io.TeeReader(reader, &b))
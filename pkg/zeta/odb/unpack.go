@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"time"
@@ -24,6 +25,11 @@ var (
 	blobStreamMagic     = [4]byte{'Z', 'B', '\x00', '\x02'}
 )
 
+const (
+	// ResumableBatchVersion: batch objects stream with per object checksum, requested by the 'resumable-batch' capability
+	ResumableBatchVersion uint32 = 2
+)
+
 func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
 	start := time.Now()
 	ur, err := d.NewUnpacker(0, true)
@@ -96,66 +102,103 @@ func (d *ODB) MetadataUnpack(r io.Reader, quiet bool) error {
 	return nil
 }
 
-func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) error {
+// Unpack: unpack the batch objects stream and return the received objects, expected is -1 if the number of objects
+// is unknown.
+//
+// Version 2 streams carry a checksum per object, when such a stream is interrupted the verified objects are
+// preserved and returned with the error, so that the caller can request only the rest.
+func (d *ODB) Unpack(r io.Reader, expected int, quiet bool) ([]plumbing.Hash, error) {
 	start := time.Now()
 	ur, err := d.NewUnpacker(0, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer ur.Close()
 	cr := crc.NewCrc64Reader(r)
-	var magic, version [4]byte
+	var magic [4]byte
 	var reserved [16]byte
 	if _, err := io.ReadFull(cr, magic[:]); err != nil {
 		fmt.Fprintf(os.Stderr, "fail to read blob transport magic, err: %v\n", err)
-		return err
+		return nil, err
 	}
 	if !bytes.Equal(magic[:], blobStreamMagic[:]) {
 		fmt.Fprintf(os.Stderr, "blob transport magic error: %s\n", magic)
-		return fmt.Errorf("blob transport magic error")
+		return nil, fmt.Errorf("blob transport magic error")
 	}
-	if _, err := io.ReadFull(cr, version[:]); err != nil {
+	var version uint32
+	if err := binary.Read(cr, binary.BigEndian, &version); err != nil {
 		fmt.Fprintf(os.Stderr, "unexpected metadata version error: %v\n", err)
-		return err
+		return nil, err
 	}
 	if _, err := io.ReadFull(cr, reserved[:]); err != nil {
 		fmt.Fprintf(os.Stderr, "unexpected reserved, error: %v\n", err)
-		return err
+		return nil, err
+	}
+	checked := version == ResumableBatchVersion
+	received := make([]plumbing.Hash, 0, max(expected, 0))
+	// keep the verified objects of an interrupted stream
+	salvage := func(err error) ([]plumbing.Hash, error) {
+		if !checked || len(received) == 0 {
+			return nil, err
+		}
+		if e := ur.Truncate(len(received)); e != nil {
+			return nil, err
+		}
+		if e := ur.Preserve(); e != nil {
+			return nil, err
+		}
+		return received, err
 	}
 
 	var oidBytes [64]byte
-	var count int
 	var readBytes int64
 	bar := progress.NewBar(tr.W("Batch download files"), expected, quiet)
 	for {
 		var length uint32
 		if err := binary.Read(cr, binary.BigEndian, &length); err != nil {
 			fmt.Fprintf(os.Stderr, "unexpected metadata length, error: %v\n", err)
-			return err
+			return salvage(err)
 		}
 		if length == 0 {
 			break
 		}
-		count++
 		if _, err := io.ReadFull(cr, oidBytes[:]); err != nil {
 			fmt.Fprintf(os.Stderr, "fail to read blob hash, err: %v\n", err)
-			return err
+			return salvage(err)
 		}
+		oid := plumbing.NewHash(string(oidBytes[:]))
 		objectSize := length - plumbing.HASH_HEX_SIZE
-		readBytes += int64(objectSize)
-		if err := ur.Write(plumbing.NewHash(string(oidBytes[:])), objectSize, io.LimitReader(cr, int64(objectSize)), 0); err != nil {
-			return err
+		var contents io.Reader = io.LimitReader(cr, int64(objectSize))
+		h := crc32.NewIEEE()
+		if checked {
+			_ = binary.Write(h, binary.BigEndian, length)
+			_, _ = h.Write(oidBytes[:])
+			contents = io.TeeReader(contents, h)
 		}
+		if err := ur.Write(oid, objectSize, contents, 0); err != nil {
+			return salvage(err)
+		}
+		if checked {
+			var sum uint32
+			if err := binary.Read(cr, binary.BigEndian, &sum); err != nil {
+				return salvage(err)
+			}
+			if sum != h.Sum32() {
+				return salvage(fmt.Errorf("blob %s checksum mismatch", oid))
+			}
+		}
+		readBytes += int64(objectSize)
+		received = append(received, oid)
 		bar.Add(1)
 	}
 	if err := cr.Verify(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return err
+		return salvage(err)
 	}
 	if err := ur.Preserve(); err != nil {
-		return err
+		return nil, err
 	}
 	_ = bar.Done()
-	fmt.Fprintf(os.Stderr, "%s: %d <%s>, %s: %v\n", tr.W("Files download completed, total"), count, strengthen.HumanateSize(readBytes), tr.W("time spent"), time.Since(start).Truncate(time.Millisecond))
-	return nil
+	fmt.Fprintf(os.Stderr, "%s: %d <%s>, %s: %v\n", tr.W("Files download completed, total"), len(received), strengthen.HumanateSize(readBytes), tr.W("time spent"), time.Since(start).Truncate(time.Millisecond))
+	return received, nil
 }
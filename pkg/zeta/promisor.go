@@ -19,8 +19,10 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 		sizeLimit = math.MaxInt64
 	}
 	largeSize := r.largeSize()
+	partial, _ := t.(transport.Partial)
 	larges := make([]*odb.Entry, 0, 100)
 	seen := make(map[plumbing.Hash]bool)
+	caps := r.batchCapabilities(ctx, t)
 	if err := r.odb.CountingSliceObjects(ctx, target, r.Core.SparseDirs, r.maxEntries(), func(ctx context.Context, entries odb.Entries) error {
 		smalls := make([]plumbing.Hash, 0, len(entries))
 		for _, e := range entries {
@@ -31,7 +33,7 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 				continue
 			}
 			if e.Size > largeSize {
-				if seen[e.Hash] {
+				if seen[e.Hash] || (partial != nil && !partial.HasObject(e.Hash)) {
 					continue
 				}
 				larges = append(larges, e)
@@ -40,7 +42,7 @@ func (r *Repository) fetchObjects(ctx context.Context, t transport.Transport, ta
 			}
 			smalls = append(smalls, e.Hash)
 		}
-		if err := r.batch(ctx, t, smalls); err != nil {
+		if err := r.batch(ctx, t, caps, smalls); err != nil {
 			return err
 		}
 		if err := r.odb.Reload(); err != nil {
@@ -114,7 +116,7 @@ func (r *Repository) fetchMissingObjects(ctx context.Context, m *missingFetcher,
 		return err
 	}
 	if len(m.objects) != 0 {
-		if err := r.batch(ctx, t, m.objects); err != nil {
+		if err := r.batch(ctx, t, r.batchCapabilities(ctx, t), m.objects); err != nil {
 			return err
 		}
 		if err := r.odb.Reload(); err != nil {
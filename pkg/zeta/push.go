@@ -25,10 +25,13 @@ type PushOptions struct {
 	//  zeta push :dev     // delete branch or tag
 	//  zeta push rev:dev  // update reference to rev
 	//  zeta push          // update current branch
-	Refspec     string
+	Refspec string
+	// Refspecs: refspecs of atomic push, all references are updated in one request
+	Refspecs    []string
 	PushObjects []string
 	Tag         bool
 	Force       bool
+	Atomic      bool
 }
 
 func (o *PushOptions) Target(name string) plumbing.ReferenceName {
@@ -55,28 +58,37 @@ const (
 		"hint: See the 'Note about fast-forwards' in 'zeta push --help' for details.\x1b[0m\n"
 )
 
-func (r *Repository) putObject(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, oid plumbing.Hash, title string) error {
+func (r *Repository) newUploadBar(size, offset int64, title string) *progressbar.ProgressBar {
+	if r.quiet {
+		return nil
+	}
+	return progressbar.NewOptions64(
+		size,
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetDescription(title),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionWithOffset(offset),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "\x1b[38;2;72;198;239m#\x1b[0m",
+			SaucerHead:    "\x1b[38;2;72;198;239m>\x1b[0m",
+			SaucerPadding: " ",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}))
+}
+
+func (r *Repository) putObject(ctx context.Context, t transport.Transport, caps transport.Capabilities, refname plumbing.ReferenceName, oid plumbing.Hash, title string) error {
 	sr, err := r.odb.SizeReader(oid, false)
 	if err != nil {
 		return err
 	}
 	defer sr.Close()
-	var bar *progressbar.ProgressBar
+	if rt, ok := t.(transport.Resumable); ok && caps.Has(transport.CapabilityResumableUpload) && sr.Size() > resumableUploadThreshold {
+		return r.putObjectResumable(ctx, rt, refname, oid, sr, sr.Size(), title)
+	}
 	var reader io.Reader = sr
-	if !r.quiet {
-		bar = progressbar.NewOptions64(
-			sr.Size(),
-			progressbar.OptionShowBytes(true),
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionSetDescription(title),
-			progressbar.OptionFullWidth(),
-			progressbar.OptionSetTheme(progressbar.Theme{
-				Saucer:        "\x1b[38;2;72;198;239m#\x1b[0m",
-				SaucerHead:    "\x1b[38;2;72;198;239m>\x1b[0m",
-				SaucerPadding: " ",
-				BarStart:      "[",
-				BarEnd:        "]",
-			}))
+	if bar := r.newUploadBar(sr.Size(), 0, title); bar != nil {
 		reader = io.TeeReader(sr, bar)
 		defer bar.Close()
 	}
@@ -86,7 +98,7 @@ func (r *Repository) putObject(ctx context.Context, t transport.Transport, refna
 	return nil
 }
 
-func (r *Repository) putObjects(ctx context.Context, t transport.Transport, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) error {
+func (r *Repository) putObjects(ctx context.Context, t transport.Transport, caps transport.Capabilities, refname plumbing.ReferenceName, haveObjects []*transport.HaveObject) error {
 	objects, err := t.BatchCheck(ctx, refname, haveObjects)
 	if err != nil {
 		return err
@@ -100,13 +112,21 @@ func (r *Repository) putObjects(ctx context.Context, t transport.Transport, refn
 	for i, o := range sendObjects {
 		oid := plumbing.NewHash(o.OID)
 		desc := fmt.Sprintf("%s \x1b[38;2;72;198;239m[%d/%d: %s]\x1b[0m", W("Upload Large files"), i+1, len(sendObjects), shortHash(oid))
-		if err := r.putObject(ctx, t, refname, oid, desc); err != nil {
+		if err := r.putObject(ctx, t, caps, refname, oid, desc); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// checkPushCapabilities: push options require the 'push-options' capability, older servers drop them silently.
+func (r *Repository) checkPushCapabilities(caps transport.Capabilities, o *PushOptions) error {
+	if len(o.PushObjects) == 0 {
+		return nil
+	}
+	return caps.Require(r.cleanedRemote(), transport.CapabilityPushOptions)
+}
+
 func shortReferenceName(name plumbing.ReferenceName) string {
 	if name.IsBranch() {
 		return name.BranchName()
@@ -131,6 +151,10 @@ func (r *Repository) doPushRemove(ctx context.Context, target plumbing.Reference
 		error_red("failed to push some refs to '%s'", cleanedRemote)
 		return err
 	}
+	if err := r.checkPushCapabilities(ref.Capabilities, o); err != nil {
+		die_error("%v", err)
+		return err
+	}
 	pipeReader, pipeWriter := io.Pipe()
 	go func() {
 		defer pipeWriter.Close()
@@ -177,37 +201,50 @@ func (r *Repository) doPushRemove(ctx context.Context, target plumbing.Reference
 		error_red("failed to push some refs to '%s'", cleanedRemote)
 		return errors.New(result.Reason)
 	}
+	r.updateTrackingReference(target, plumbing.ZeroHash)
 	fmt.Fprintf(os.Stderr, "To: %s\n - [deleted] '%s'\n", cleanedRemote, target.Short())
 	return nil
 }
 
-func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName, newRev plumbing.Hash, target plumbing.ReferenceName, o *PushOptions) error {
-	t, err := r.newTransport(ctx, transport.UPLOAD)
-	if err != nil {
-		return err
-	}
-	var shallow plumbing.Hash
-	var ignoreParents []plumbing.Hash
+// shallowParents: parents of the shallow commit are not in the local repository, they are ignored when
+// checking fast-forward.
+func (r *Repository) shallowParents(ctx context.Context) (shallow plumbing.Hash, ignoreParents []plumbing.Hash, err error) {
 	if shallow, err = r.odb.DeepenFrom(); err != nil && !os.IsNotExist(err) {
 		die("cat shallow error: %v", err)
-		return err
+		return
 	}
+	err = nil
 	if !shallow.IsZero() {
 		shallowCommit, err := r.odb.Commit(ctx, shallow)
 		if err != nil {
 			die("read shallow commit %s error: %s", shallow, err)
-			return err
+			return shallow, nil, err
 		}
 		ignoreParents = append(ignoreParents, shallowCommit.Parents...)
 	}
+	return
+}
+
+func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName, newRev plumbing.Hash, target plumbing.ReferenceName, o *PushOptions) error {
+	t, err := r.newTransport(ctx, transport.UPLOAD)
+	if err != nil {
+		return err
+	}
+	shallow, ignoreParents, err := r.shallowParents(ctx)
+	if err != nil {
+		return err
+	}
 	var fasfForward, isNewPush bool
 	var theirs, oldRev plumbing.Hash
+	// discovered: capabilities are unknown when the remote is empty
+	var discovered *transport.Reference
 	ref, err := t.FetchReference(ctx, target)
 	switch {
 	case err == transport.ErrReferenceNotExist:
 		isNewPush = true
 		if current, err := t.FetchReference(ctx, plumbing.HEAD); err == nil {
 			theirs = plumbing.NewHash(current.Hash)
+			discovered = current
 		}
 	case err != nil:
 		die("ls-remote '%s' error: %v", target, err)
@@ -229,6 +266,15 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 			return ErrPushRejected
 		}
 		theirs = ref.Target()
+		discovered = ref
+	}
+	var caps transport.Capabilities
+	if discovered != nil {
+		caps = discovered.Capabilities
+		if err := r.checkPushCapabilities(caps, o); err != nil {
+			die_error("%v", err)
+			return err
+		}
 	}
 
 	po, err := r.odb.Delta(ctx, newRev, shallow, theirs)
@@ -241,7 +287,7 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 		for _, o := range po.LargeObjects {
 			haveObjects = append(haveObjects, &transport.HaveObject{OID: o.Hash.String(), CompressedSize: o.Size})
 		}
-		if err := r.putObjects(ctx, t, target, haveObjects); err != nil {
+		if err := r.putObjects(ctx, t, caps, target, haveObjects); err != nil {
 			die_error("upload large objects error: %v", err)
 			return err
 		}
@@ -291,6 +337,7 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 		error_red("failed to push some refs to '%s'", cleanedRemote)
 		return errors.New(result.Reason)
 	}
+	r.updateTrackingReference(target, newRev)
 	fmt.Fprintf(os.Stderr, "To: %s\n", cleanedRemote)
 	if isNewPush {
 		if target.IsBranch() {
@@ -312,47 +359,68 @@ func (r *Repository) doPush(ctx context.Context, ourName plumbing.ReferenceName,
 	return nil
 }
 
-func (r *Repository) Push(ctx context.Context, o *PushOptions) error {
-	if len(o.Refspec) == 0 || o.Refspec == "HEAD" {
+// pushTarget: resolved refspec, newRev is zero when the target is deleted.
+type pushTarget struct {
+	ours   plumbing.ReferenceName
+	newRev plumbing.Hash
+	target plumbing.ReferenceName
+}
+
+func (r *Repository) resolvePushTarget(ctx context.Context, refspec string, o *PushOptions) (*pushTarget, error) {
+	if len(refspec) == 0 || refspec == "HEAD" {
 		current, err := r.Current()
 		if err != nil {
 			die("resolve HEAD error: %v", err)
-			return err
+			return nil, err
 		}
-		return r.doPush(ctx, current.Name(), current.Hash(), current.Name(), o)
+		return &pushTarget{ours: current.Name(), newRev: current.Hash(), target: current.Name()}, nil
 	}
-	if ours, theirs, ok := strings.Cut(o.Refspec, ":"); ok {
+	if ours, theirs, ok := strings.Cut(refspec, ":"); ok {
 		if len(ours) == 0 {
 			// :target remove branch or tag
-			return r.doPushRemove(ctx, o.Target(theirs), o)
+			return &pushTarget{newRev: plumbing.ZeroHash, target: o.Target(theirs)}, nil
 		}
 		newRev, err := r.Revision(ctx, ours)
 		if err != nil {
 			die("resolve %s error: %v", ours, err)
-			return err
+			return nil, err
 		}
-		return r.doPush(ctx, plumbing.ReferenceName(ours), newRev, o.Target(theirs), o)
+		return &pushTarget{ours: plumbing.ReferenceName(ours), newRev: newRev, target: o.Target(theirs)}, nil
 	}
-	if strings.HasPrefix(o.Refspec, plumbing.ReferencePrefix) {
-		refname := plumbing.ReferenceName(o.Refspec)
+	if strings.HasPrefix(refspec, plumbing.ReferencePrefix) {
+		refname := plumbing.ReferenceName(refspec)
 		ref, err := r.Reference(refname)
 		if err != nil {
-			die("resolve %s error: %v", o.Refspec, err)
-			return err
+			die("resolve %s error: %v", refspec, err)
+			return nil, err
 		}
-		return r.doPush(ctx, refname, ref.Hash(), ref.Name(), o)
+		return &pushTarget{ours: refname, newRev: ref.Hash(), target: ref.Name()}, nil
 	}
-	ref, err := r.Reference(plumbing.NewBranchReferenceName(o.Refspec))
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(refspec))
 	if err == nil {
-		return r.doPush(ctx, ref.Name(), ref.Hash(), ref.Name(), o)
+		return &pushTarget{ours: ref.Name(), newRev: ref.Hash(), target: ref.Name()}, nil
 	}
 	if err != plumbing.ErrReferenceNotFound {
-		die_error("resolve %s error: %v", o.Refspec, err)
-		return err
+		die_error("resolve %s error: %v", refspec, err)
+		return nil, err
+	}
+	if ref, err = r.Reference(plumbing.NewTagReferenceName(refspec)); err != nil {
+		die_error("unable resolve %s error: %v", refspec, err)
+		return nil, err
 	}
-	if ref, err = r.Reference(plumbing.NewTagReferenceName(o.Refspec)); err != nil {
-		die_error("unable resolve %s error: %v", o.Refspec, err)
+	return &pushTarget{ours: ref.Name(), newRev: ref.Hash(), target: ref.Name()}, nil
+}
+
+func (r *Repository) Push(ctx context.Context, o *PushOptions) error {
+	if o.Atomic {
+		return r.doPushAtomic(ctx, o)
+	}
+	pt, err := r.resolvePushTarget(ctx, o.Refspec, o)
+	if err != nil {
 		return err
 	}
-	return r.doPush(ctx, ref.Name(), ref.Hash(), ref.Name(), o)
+	if pt.newRev.IsZero() {
+		return r.doPushRemove(ctx, pt.target, o)
+	}
+	return r.doPush(ctx, pt.ours, pt.newRev, pt.target, o)
 }
@@ -0,0 +1,269 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/pkg/transport"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+// atomicTarget: reference update of atomic push
+type atomicTarget struct {
+	*pushTarget
+	oldRev      plumbing.Hash
+	theirs      plumbing.Hash
+	fastForward bool
+}
+
+func (t *atomicTarget) isDelete() bool {
+	return t.newRev.IsZero()
+}
+
+func (t *atomicTarget) isNew() bool {
+	return t.oldRev.IsZero()
+}
+
+// mergePushObjects: objects of all references are sent in one stream, shared objects are sent once.
+func mergePushObjects(dst, src *odb.PushObjects, seen map[plumbing.Hash]bool) {
+	for _, h := range src.Metadata {
+		if !seen[h] {
+			seen[h] = true
+			dst.Metadata = append(dst.Metadata, h)
+		}
+	}
+	for _, h := range src.Objects {
+		if !seen[h] {
+			seen[h] = true
+			dst.Objects = append(dst.Objects, h)
+		}
+	}
+}
+
+func (r *Repository) printAtomicResult(t *atomicTarget) {
+	ourName := t.ours.Short()
+	switch {
+	case t.isDelete():
+		fmt.Fprintf(os.Stderr, " - [deleted] %s\n", t.target.Short())
+	case t.isNew() && t.target.IsTag():
+		fmt.Fprintf(os.Stderr, " * [new tag] %s -> %s\n", ourName, t.target.TagName())
+	case t.isNew():
+		fmt.Fprintf(os.Stderr, " * [new branch] %s -> %s\n", ourName, shortReferenceName(t.target))
+	case !t.fastForward:
+		fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s (forced update)\n", shortHash(t.oldRev), shortHash(t.newRev), ourName, shortReferenceName(t.target))
+	default:
+		fmt.Fprintf(os.Stderr, " + %s...%s %s -> %s\n", shortHash(t.oldRev), shortHash(t.newRev), ourName, shortReferenceName(t.target))
+	}
+}
+
+// resolveAtomicTargets: resolve refspecs and check the remote references, references that are up-to-date are skipped.
+func (r *Repository) resolveAtomicTargets(ctx context.Context, t transport.Transport, o *PushOptions) ([]*atomicTarget, transport.Capabilities, error) {
+	refspecs := o.Refspecs
+	if len(refspecs) == 0 {
+		refspecs = []string{o.Refspec}
+	}
+	_, ignoreParents, err := r.shallowParents(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanedRemote := r.cleanedRemote()
+	targets := make([]*atomicTarget, 0, len(refspecs))
+	seen := make(map[plumbing.ReferenceName]bool)
+	var caps transport.Capabilities
+	// capabilities are unknown when the remote is empty
+	var discovered bool
+	var head *transport.Reference
+	for _, refspec := range refspecs {
+		pt, err := r.resolvePushTarget(ctx, refspec, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen[pt.target] {
+			die_error("reference '%s' is updated more than once", pt.target)
+			return nil, nil, fmt.Errorf("duplicate reference '%s'", pt.target)
+		}
+		seen[pt.target] = true
+		at := &atomicTarget{pushTarget: pt, fastForward: true}
+		ref, err := t.FetchReference(ctx, pt.target)
+		switch {
+		case err == transport.ErrReferenceNotExist:
+			if at.isDelete() {
+				die_error("unable to delete '%s': remote ref does not exist", shortReferenceName(pt.target))
+				die_error("failed to push some refs to '%s'", cleanedRemote)
+				return nil, nil, err
+			}
+			if head == nil {
+				if head, err = t.FetchReference(ctx, plumbing.HEAD); err == nil {
+					caps = head.Capabilities
+					discovered = true
+				}
+			}
+			if head != nil {
+				at.theirs = plumbing.NewHash(head.Hash)
+			}
+		case err != nil:
+			die("ls-remote '%s' error: %v", pt.target, err)
+			return nil, nil, err
+		default:
+			caps = ref.Capabilities
+			discovered = true
+			at.oldRev = plumbing.NewHash(ref.Hash)
+			at.theirs = ref.Target()
+			if at.newRev == at.oldRev {
+				continue
+			}
+			if !at.isDelete() {
+				if at.fastForward, err = r.isFastForward(ctx, at.oldRev, at.newRev, ignoreParents); err != nil {
+					die("check is fast-forward error: %s", err)
+					return nil, nil, err
+				}
+				if !at.fastForward && !o.Force {
+					fmt.Fprintf(os.Stderr, rejectFormat, cleanedRemote, pt.ours.Short(), ref.Name.Short(), cleanedRemote)
+					return nil, nil, ErrPushRejected
+				}
+			}
+		}
+		targets = append(targets, at)
+	}
+	if discovered {
+		if err := caps.Require(cleanedRemote, transport.CapabilityAtomicPush); err != nil {
+			die_error("%v", err)
+			return nil, nil, err
+		}
+		if err := r.checkPushCapabilities(caps, o); err != nil {
+			die_error("%v", err)
+			return nil, nil, err
+		}
+	}
+	return targets, caps, nil
+}
+
+// doPushAtomic: update all references in one request, the remote updates all of them or none of them.
+func (r *Repository) doPushAtomic(ctx context.Context, o *PushOptions) error {
+	t, err := r.newTransport(ctx, transport.UPLOAD)
+	if err != nil {
+		return err
+	}
+	cleanedRemote := r.cleanedRemote()
+	at, ok := t.(transport.Atomic)
+	if !ok {
+		err := &transport.ErrMissingCapability{Remote: cleanedRemote, Capability: transport.CapabilityAtomicPush}
+		die_error("%v", err)
+		return err
+	}
+	targets, caps, err := r.resolveAtomicTargets(ctx, t, o)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Fprintf(os.Stderr, "Everything up-to-date\n")
+		return nil
+	}
+	shallow, _, err := r.shallowParents(ctx)
+	if err != nil {
+		return err
+	}
+	po := &odb.PushObjects{
+		Metadata:     make([]plumbing.Hash, 0),
+		Objects:      make([]plumbing.Hash, 0),
+		LargeObjects: make([]*odb.HaveObject, 0),
+	}
+	seen := make(map[plumbing.Hash]bool)
+	uploaded := make(map[plumbing.Hash]bool)
+	cmd := &transport.AtomicCommand{PushOptions: o.PushObjects}
+	for _, a := range targets {
+		c := &transport.Command{Refname: a.target, OldRev: a.oldRev.String(), NewRev: a.newRev.String()}
+		cmd.Commands = append(cmd.Commands, c)
+		if a.isDelete() {
+			continue
+		}
+		delta, err := r.odb.Delta(ctx, a.newRev, shallow, a.theirs)
+		if err != nil {
+			die("get objects error: %v", err)
+			return err
+		}
+		mergePushObjects(po, delta, seen)
+		haveObjects := make([]*transport.HaveObject, 0, len(delta.LargeObjects))
+		for _, lo := range delta.LargeObjects {
+			if !uploaded[lo.Hash] {
+				uploaded[lo.Hash] = true
+				haveObjects = append(haveObjects, &transport.HaveObject{OID: lo.Hash.String(), CompressedSize: lo.Size})
+			}
+		}
+		if len(haveObjects) == 0 {
+			continue
+		}
+		// large objects are checked by the target reference
+		if err := r.putObjects(ctx, t, caps, a.target, haveObjects); err != nil {
+			die_error("upload large objects error: %v", err)
+			return err
+		}
+	}
+	cmd.Metadata = len(po.Metadata)
+	cmd.Objects = len(po.Objects)
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer pipeWriter.Close()
+		if err := r.odb.PushTo(ctx, pipeWriter, po, r.quiet); err != nil {
+			return
+		}
+	}()
+	rc, err := at.PushAtomic(ctx, pipeReader, cmd)
+	if err != nil {
+		_ = pipeReader.CloseWithError(err)
+		die_error("Push failed: %v", err)
+		return err
+	}
+	reports, unpackReason, err := r.odb.OnReports(ctx, rc)
+	if err != nil {
+		_ = rc.Close()
+		if lastErr := rc.LastError(); lastErr != nil {
+			die_error("Push failed: %v", lastErr)
+			return lastErr
+		}
+		die_error("parse report error: %v", err)
+		return err
+	}
+	_ = rc.Close()
+	results := make(map[plumbing.ReferenceName]*odb.Report, len(reports))
+	for _, rp := range reports {
+		results[rp.ReferenceName] = rp
+	}
+	var rejected error
+	fmt.Fprintf(os.Stderr, "To: %s\n", cleanedRemote)
+	for _, a := range targets {
+		result, ok := results[a.target]
+		if !ok {
+			reason := unpackReason
+			if len(reason) == 0 {
+				reason = "no status reported"
+			}
+			result = &odb.Report{ReferenceName: a.target, Rejected: true, Reason: reason}
+		}
+		if result.Rejected {
+			for _, s := range strengthen.StrSplitSkipEmpty(result.Reason, 2, '\n') {
+				fmt.Fprintf(os.Stderr, "remote: %s\n", s)
+			}
+			fmt.Fprintf(os.Stderr, " \x1b[31m! [remote rejected]\x1b[0m %s\n", a.target.Short())
+			if rejected == nil {
+				rejected = errors.New(result.Reason)
+			}
+			continue
+		}
+		r.updateTrackingReference(a.target, a.newRev)
+		r.printAtomicResult(a)
+	}
+	if rejected != nil {
+		error_red("failed to push some refs to '%s'", cleanedRemote)
+		return rejected
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/zeta/config"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+type ErrNoSuchRemote struct {
+	name string
+}
+
+func (e *ErrNoSuchRemote) Error() string {
+	return fmt.Sprintf("no such remote '%s'", e.name)
+}
+
+func IsErrNoSuchRemote(err error) bool {
+	_, ok := err.(*ErrNoSuchRemote)
+	return ok
+}
+
+// ValidateRemoteName: remote names are used as 'refs/remotes/<name>/', they cannot contain '/'.
+func ValidateRemoteName(name string) bool {
+	if len(name) == 0 || strings.ContainsAny(name, "/ \t\n\\") || name == "." || name == ".." {
+		return false
+	}
+	return plumbing.ValidateBranchName([]byte(name))
+}
+
+// RemoteURL: normalize the remote URL, like 'zeta remote set'.
+func RemoteURL(rawURL string) (string, error) {
+	e, err := transport.NewEndpoint(rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	return e.String(), nil
+}
+
+// remoteReferences: tracking references of the remote
+func (r *Repository) remoteReferences(name string) ([]*plumbing.Reference, error) {
+	rdb, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	prefix := plumbing.ReferencePrefix + "remotes/" + name + "/"
+	refs := make([]*plumbing.Reference, 0, 10)
+	for _, ref := range rdb.References() {
+		if strings.HasPrefix(string(ref.Name()), prefix) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// updateTrackingReference: after a successful push, refs/remotes/<remote>/<branch> follows the remote branch.
+func (r *Repository) updateTrackingReference(target plumbing.ReferenceName, newRev plumbing.Hash) {
+	if !target.IsBranch() {
+		return
+	}
+	trackingName := plumbing.NewRemoteReferenceName(r.remoteName(), target.BranchName())
+	if newRev.IsZero() {
+		ref, err := r.Reference(trackingName)
+		if err != nil {
+			return
+		}
+		if err := r.ReferenceRemove(ref); err != nil {
+			warn("remove '%s' error: %v", trackingName, err)
+		}
+		return
+	}
+	if err := r.ReferenceUpdate(plumbing.NewHashReference(trackingName, newRev), nil); err != nil {
+		warn("update-ref '%s' error: %v", trackingName, err)
+	}
+}
+
+// hasRemoteURL: remote.<name>.url is set
+func (r *Repository) hasRemoteURL(name string) bool {
+	rc, ok := r.Remotes[name]
+	return ok && rc != nil && len(rc.URL) != 0
+}
+
+// updateRemoteConfig: origin keeps using core.remote unless remote.origin exists, older zeta only knows core.remote.
+func (r *Repository) updateRemoteConfig(name, remoteURL, pushURL string) error {
+	values := make(map[string]any)
+	if name == config.DefaultRemote && !r.hasRemoteURL(name) {
+		values["core.remote"] = remoteURL
+	} else {
+		values["remote."+name+".url"] = remoteURL
+	}
+	if len(pushURL) != 0 {
+		values["remote."+name+".pushurl"] = pushURL
+	}
+	return config.UpdateLocal(r.zetaDir, &config.UpdateOptions{Values: values})
+}
+
+func (r *Repository) unsetRemoteConfig(name string) error {
+	keys := []string{"remote." + name}
+	if name == config.DefaultRemote {
+		keys = append(keys, "core.remote")
+	}
+	return config.UnsetLocal(r.zetaDir, keys...)
+}
+
+func (r *Repository) AddRemote(name, rawURL, rawPushURL string) error {
+	if !ValidateRemoteName(name) {
+		die("'%s' is not a valid remote name", name)
+		return &plumbing.ErrBadReferenceName{Name: name}
+	}
+	if _, ok := r.LookupRemote(name); ok {
+		die("remote %s already exists.", name)
+		return fmt.Errorf("remote %s already exists", name)
+	}
+	remoteURL, err := RemoteURL(rawURL)
+	if err != nil {
+		die("bad remote url '%s': %v", rawURL, err)
+		return err
+	}
+	var pushURL string
+	if len(rawPushURL) != 0 {
+		if pushURL, err = RemoteURL(rawPushURL); err != nil {
+			die("bad remote push url '%s': %v", rawPushURL, err)
+			return err
+		}
+	}
+	if err := r.updateRemoteConfig(name, remoteURL, pushURL); err != nil {
+		die_error("add remote '%s' error: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) RemoveRemote(name string) error {
+	if _, ok := r.LookupRemote(name); !ok {
+		die("no such remote: '%s'", name)
+		return &ErrNoSuchRemote{name: name}
+	}
+	refs, err := r.remoteReferences(name)
+	if err != nil {
+		die_error("resolve references error: %v", err)
+		return err
+	}
+	for _, ref := range refs {
+		if err := r.ReferenceRemove(ref); err != nil {
+			die_error("remove '%s' error: %v", ref.Name(), err)
+			return err
+		}
+	}
+	if err := r.unsetRemoteConfig(name); err != nil {
+		die_error("remove remote '%s' error: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+func (r *Repository) RenameRemote(oldName, newName string) error {
+	rc, ok := r.LookupRemote(oldName)
+	if !ok {
+		die("no such remote: '%s'", oldName)
+		return &ErrNoSuchRemote{name: oldName}
+	}
+	if !ValidateRemoteName(newName) {
+		die("'%s' is not a valid remote name", newName)
+		return &plumbing.ErrBadReferenceName{Name: newName}
+	}
+	if _, ok := r.LookupRemote(newName); ok {
+		die("remote %s already exists.", newName)
+		return fmt.Errorf("remote %s already exists", newName)
+	}
+	refs, err := r.remoteReferences(oldName)
+	if err != nil {
+		die_error("resolve references error: %v", err)
+		return err
+	}
+	if err := r.unsetRemoteConfig(oldName); err != nil {
+		die_error("rename remote '%s' error: %v", oldName, err)
+		return err
+	}
+	delete(r.Remotes, oldName)
+	if err := r.updateRemoteConfig(newName, rc.URL, rc.PushURL); err != nil {
+		die_error("rename remote '%s' error: %v", oldName, err)
+		return err
+	}
+	oldPrefix := plumbing.ReferencePrefix + "remotes/" + oldName + "/"
+	for _, ref := range refs {
+		newRefName := plumbing.ReferenceName(plumbing.ReferencePrefix + "remotes/" + newName + "/" + strings.TrimPrefix(string(ref.Name()), oldPrefix))
+		if err := r.ReferenceUpdate(plumbing.NewHashReference(newRefName, ref.Hash()), nil); err != nil {
+			die_error("update-ref '%s' error: %v", newRefName, err)
+			return err
+		}
+		if err := r.ReferenceRemove(ref); err != nil {
+			die_error("remove '%s' error: %v", ref.Name(), err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) SetRemoteURL(name, rawURL string, push bool) error {
+	if _, ok := r.LookupRemote(name); !ok {
+		die("no such remote: '%s'", name)
+		return &ErrNoSuchRemote{name: name}
+	}
+	remoteURL, err := RemoteURL(rawURL)
+	if err != nil {
+		die("bad remote url '%s': %v", rawURL, err)
+		return err
+	}
+	values := make(map[string]any)
+	switch {
+	case push:
+		values["remote."+name+".pushurl"] = remoteURL
+	case name == config.DefaultRemote && !r.hasRemoteURL(name):
+		values["core.remote"] = remoteURL
+	default:
+		values["remote."+name+".url"] = remoteURL
+	}
+	if err := config.UpdateLocal(r.zetaDir, &config.UpdateOptions{Values: values}); err != nil {
+		die_error("set remote '%s' url error: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+type RemoteItem struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	PushURL string `json:"pushurl"`
+}
+
+func (r *Repository) ListRemotes(verbose bool, formatJSON bool) error {
+	items := make([]*RemoteItem, 0, len(r.Remotes)+1)
+	for _, name := range r.RemoteNames() {
+		rc, _ := r.LookupRemote(name)
+		items = append(items, &RemoteItem{Name: name, URL: rc.URL, PushURL: rc.PushTarget()})
+	}
+	if formatJSON {
+		return json.NewEncoder(os.Stdout).Encode(items)
+	}
+	for _, item := range items {
+		if !verbose {
+			fmt.Fprintln(os.Stdout, item.Name)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s\t%s (fetch)\n%s\t%s (push)\n", item.Name, item.URL, item.Name, item.PushURL)
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/antgroup/hugescm/modules/strengthen"
 	"github.com/antgroup/hugescm/modules/vfs"
 	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/bundle"
 	"github.com/antgroup/hugescm/modules/zeta/config"
 	"github.com/antgroup/hugescm/modules/zeta/object"
 	"github.com/antgroup/hugescm/modules/zeta/reflog"
@@ -98,6 +99,7 @@ func getFromValueOrEnv(k, e string, values map[string]StringArray) (string, bool
 
 type NewOptions struct {
 	Remote      string
+	RemoteName  string // name of the remote, default 'origin'
 	Branch      string
 	TagName     string
 	Commit      string
@@ -147,6 +149,10 @@ func (opts *NewOptions) Validate() error {
 		fmt.Fprintf(os.Stderr, "mistake commit hex string: '%s'\n", opts.Commit)
 		return errors.New("mistake commit hex string")
 	}
+	if len(opts.RemoteName) != 0 && !ValidateRemoteName(opts.RemoteName) {
+		die("'%s' is not a valid remote name", opts.RemoteName)
+		return &plumbing.ErrBadReferenceName{Name: opts.RemoteName}
+	}
 	return nil
 }
 
@@ -159,6 +165,7 @@ type Repository struct {
 	zetaDir           string
 	missingNotFailure bool
 	values            map[string]StringArray
+	remote            string // selected remote, see --remote
 	quiet             bool
 	verbose           bool
 }
@@ -182,6 +189,14 @@ func parseExtraHeader(cfg *config.Config, values map[string]StringArray) []strin
 	return extraHeader
 }
 
+// parseCredentialHelpers: -X credential.helper=... takes precedence over the configured helpers of the remote.
+func parseCredentialHelpers(cfg *config.Config, values map[string]StringArray, remote string) []string {
+	if sa, ok := getStringsFromValues("credential.helper", values); ok {
+		return sa
+	}
+	return cfg.Credential.HelpersFor(remote)
+}
+
 func parseSharingRoot(cfg *config.Config, values map[string]StringArray) (string, bool) {
 	if sharingRoot, ok := getStringFromValues("core.sharingRoot", values); ok && len(sharingRoot) > 0 && filepath.IsAbs(sharingRoot) {
 		return sharingRoot, true
@@ -208,10 +223,15 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		return nil, err
 	}
 	values := valuesMapArray(opts.Values)
+	if err := useKeyringBackend(cfg, values); err != nil {
+		die_error("%v", err)
+		return nil, err
+	}
 	target := plumbing.NewHash(opts.Commit)
 	endpoint, err := transport.NewEndpoint(opts.Remote, &transport.Options{
-		InsecureSkipTLS: parseInsecureSkipTLS(cfg, values),
-		ExtraHeader:     parseExtraHeader(cfg, values),
+		InsecureSkipTLS:   parseInsecureSkipTLS(cfg, values),
+		ExtraHeader:       parseExtraHeader(cfg, values),
+		CredentialHelpers: parseCredentialHelpers(cfg, values, opts.Remote),
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "bad remote: %v\n", err)
@@ -219,6 +239,9 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 	}
 
 	repoName := filepath.Base(strings.TrimSuffix(endpoint.Path, "/"))
+	if endpoint.Protocol == "bundle" {
+		repoName = strings.TrimSuffix(repoName, bundle.Extension)
+	}
 	destination, exists, err := checkDestination(repoName, opts.Destination, true)
 	if err != nil {
 		return nil, err
@@ -291,6 +314,10 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 			CompressionALGO: ref.CompressionALGO,
 		},
 	}
+	if len(opts.RemoteName) != 0 && opts.RemoteName != config.DefaultRemote {
+		newConfig.Core.Remote = ""
+		newConfig.Remotes = map[string]*config.Remote{opts.RemoteName: {URL: endpoint.String()}}
+	}
 	// Flush sharingRoot
 	if sharingSet {
 		newConfig.Core.SharingRoot = sharingRoot
@@ -310,12 +337,16 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 		zetaDir: zetaDir,
 		baseDir: destination,
 		values:  values,
+		remote:  opts.RemoteName,
 		quiet:   opts.Quiet,
 		verbose: opts.Verbose,
 	}
 	if opts.SizeLimit != -1 {
 		r.missingNotFailure = true
 	}
+	if _, ok := tr.(transport.Partial); ok {
+		r.missingNotFailure = true
+	}
 	fetchOpts := &FetchOptions{
 		Target:    target,
 		SizeLimit: opts.SizeLimit,
@@ -354,7 +385,7 @@ func New(ctx context.Context, opts *NewOptions) (*Repository, error) {
 
 	switch {
 	case ref.Name.IsBranch() && target == plumbing.NewHash(ref.Hash):
-		originBranch := plumbing.NewRemoteReferenceName(plumbing.Origin, ref.Name.BranchName())
+		originBranch := plumbing.NewRemoteReferenceName(r.remoteName(), ref.Name.BranchName())
 		if err := r.ReferenceUpdate(plumbing.NewHashReference(originBranch, target), nil); err != nil {
 			fmt.Fprintf(os.Stderr, "update-ref '%s' error: %v\n", originBranch, err)
 			return nil, err
@@ -409,6 +440,7 @@ func (r *Repository) storeShallow(ctx context.Context, commit plumbing.Hash) err
 
 type OpenOptions struct {
 	Worktree string
+	Remote   string // remote name, defaults to 'origin'
 	Quiet    bool
 	Verbose  bool
 	Values   []string
@@ -425,6 +457,12 @@ func Open(ctx context.Context, opts *OpenOptions) (*Repository, error) {
 		die_error("%v", err)
 		return nil, err
 	}
+	if len(opts.Remote) != 0 {
+		if _, ok := cfg.LookupRemote(opts.Remote); !ok {
+			die_error("'%s' does not appear to be a zeta remote", opts.Remote)
+			return nil, &ErrNoSuchRemote{name: opts.Remote}
+		}
+	}
 	odbOpts := make([]backend.Option, 0, 2)
 	odbOpts = append(odbOpts, backend.WithCompressionALGO(cfg.Core.CompressionALGO), backend.WithEnableLRU(true))
 	values := valuesMapArray(opts.Values)
@@ -445,6 +483,7 @@ func Open(ctx context.Context, opts *OpenOptions) (*Repository, error) {
 		Backend: refs.NewBackend(zetaDir),
 		rdb:     reflog.NewDB(zetaDir),
 		values:  values,
+		remote:  opts.Remote,
 		quiet:   opts.Quiet,
 		verbose: opts.Verbose,
 	}
@@ -691,19 +730,50 @@ func (r *Repository) ReferenceResolve(name plumbing.ReferenceName) (ref *plumbin
 	return refs.ReferenceResolve(r.Backend, name)
 }
 
+// remoteName: the selected remote, defaults to 'origin'
+func (r *Repository) remoteName() string {
+	if len(r.remote) != 0 {
+		return r.remote
+	}
+	return config.DefaultRemote
+}
+
+// remoteURL: URL of the selected remote, uploads use its push URL.
+func (r *Repository) remoteURL(operation transport.Operation) string {
+	rc, ok := r.LookupRemote(r.remoteName())
+	if !ok {
+		return ""
+	}
+	if operation == transport.UPLOAD {
+		return rc.PushTarget()
+	}
+	return rc.URL
+}
+
 func (r *Repository) cleanedRemote() string {
-	u, err := url.Parse(r.Core.Remote)
+	remote := r.remoteURL(transport.DOWNLOAD)
+	u, err := url.Parse(remote)
 	if err != nil {
-		return r.Core.Remote
+		return remote
 	}
 	u.User = nil
 	return u.String()
 }
 
 func (r *Repository) newTransport(ctx context.Context, operation transport.Operation) (transport.Transport, error) {
-	endpoint, err := transport.NewEndpoint(r.Core.Remote, &transport.Options{
-		InsecureSkipTLS: parseInsecureSkipTLS(r.Config, r.values),
-		ExtraHeader:     parseExtraHeader(r.Config, r.values),
+	remote := r.remoteURL(operation)
+	if len(remote) == 0 {
+		fmt.Fprintf(os.Stderr, "remote '%s' not configured\n", r.remoteName())
+		return nil, &ErrNoSuchRemote{name: r.remoteName()}
+	}
+	if err := useKeyringBackend(r.Config, r.values); err != nil {
+		die_error("%v", err)
+		return nil, err
+	}
+	endpoint, err := transport.NewEndpoint(remote, &transport.Options{
+		InsecureSkipTLS:   parseInsecureSkipTLS(r.Config, r.values),
+		ExtraHeader:       parseExtraHeader(r.Config, r.values),
+		CredentialHelpers: parseCredentialHelpers(r.Config, r.values, remote),
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "bad remote: %v\n", err)
@@ -721,5 +791,7 @@ func (r *Repository) Close() error {
 	if r.odb == nil {
 		return nil
 	}
-	return r.odb.Close()
+	err := r.odb.Close()
+	r.enforceCacheLimit(context.Background())
+	return err
 }
@@ -121,8 +121,9 @@ func (r *Repository) resolveRevision(ctx context.Context, revision string) (plum
 	if err == nil {
 		return tag.Hash(), nil
 	}
-	if branchRemote, ok := strings.CutPrefix(revision, plumbing.Origin); ok {
-		ref, err := r.Reference(plumbing.NewRemoteReferenceName(plumbing.Origin, branchRemote))
+	// <remote>/<branch> --> refs/remotes/<remote>/<branch>
+	if remoteName, branchName, ok := strings.Cut(revision, "/"); ok && len(remoteName) != 0 && len(branchName) != 0 {
+		ref, err := r.Reference(plumbing.NewRemoteReferenceName(remoteName, branchName))
 		if err == nil {
 			return ref.Hash(), nil
 		}
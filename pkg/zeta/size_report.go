@@ -0,0 +1,185 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+)
+
+type SizeReportOptions struct {
+	Revision string
+	History  bool // scan all commits reachable from the revision, or from references when the revision is empty
+	Limit    int
+	JSON     bool
+}
+
+type SizeReportEntry struct {
+	Hash      plumbing.Hash `json:"hash"`
+	Path      string        `json:"path"`
+	Commit    plumbing.Hash `json:"commit"`
+	Size      int64         `json:"size"`
+	Fragments bool          `json:"fragments"`
+	Present   bool          `json:"present"`
+}
+
+type SizeReport struct {
+	Commits      int                `json:"commits"`
+	Objects      int                `json:"objects"`
+	MissingTrees int                `json:"missing_trees"`
+	Entries      []*SizeReportEntry `json:"entries"`
+}
+
+type sizeReporter struct {
+	r      *Repository
+	seen   map[plumbing.Hash]bool // trees and blobs
+	report *SizeReport
+}
+
+// walk: record blobs not seen before, the first commit that reaches a blob is considered to add it.
+func (s *sizeReporter) walk(ctx context.Context, commit plumbing.Hash, oid plumbing.Hash, parent string) error {
+	if s.seen[oid] {
+		return nil
+	}
+	s.seen[oid] = true
+	t, err := s.r.odb.Tree(ctx, oid)
+	if plumbing.IsNoSuchObject(err) {
+		// sparse checkout or partial metadata
+		s.report.MissingTrees++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		switch e.Type() {
+		case object.TreeObject:
+			if err := s.walk(ctx, commit, e.Hash, path.Join(parent, e.Name)); err != nil {
+				return err
+			}
+		case object.BlobObject, object.FragmentsObject:
+			if s.seen[e.Hash] {
+				continue
+			}
+			s.seen[e.Hash] = true
+			s.report.Entries = append(s.report.Entries, &SizeReportEntry{
+				Hash:      e.Hash,
+				Path:      path.Join(parent, e.Name),
+				Commit:    commit,
+				Size:      e.Size,
+				Fragments: e.Type() == object.FragmentsObject,
+			})
+		}
+	}
+	return nil
+}
+
+func (s *sizeReporter) present(ctx context.Context, e *SizeReportEntry) bool {
+	if !e.Fragments {
+		return s.r.odb.Exists(e.Hash, false)
+	}
+	ff, err := s.r.odb.Fragments(ctx, e.Hash)
+	if err != nil {
+		return false
+	}
+	for _, fe := range ff.Entries {
+		if !s.r.odb.Exists(fe.Hash, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// historyCommits: commits reachable from the revision, or from all references when rev is empty, oldest first.
+func (r *Repository) historyCommits(ctx context.Context, rev string) ([]*object.Commit, error) {
+	var iter object.CommitIter
+	if len(rev) != 0 {
+		cc, err := r.parseRevExhaustive(ctx, rev)
+		if err != nil {
+			return nil, err
+		}
+		iter = commitIterFunc(LogOrderDefault)(cc)
+	} else {
+		var err error
+		if iter, err = r.logAll(ctx, commitIterFunc(LogOrderDefault)); err != nil {
+			return nil, err
+		}
+	}
+	defer iter.Close()
+	commits := make([]*object.Commit, 0, 100)
+	if err := iter.ForEach(ctx, func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	}); err != nil && !plumbing.IsNoSuchObject(err) {
+		return nil, err
+	}
+	slices.SortStableFunc(commits, func(a, b *object.Commit) int {
+		return a.Committer.When.Compare(b.Committer.When)
+	})
+	return commits, nil
+}
+
+// SizeReport: list the largest blobs of the revision, or ever added in history.
+func (r *Repository) SizeReport(ctx context.Context, w io.Writer, opts *SizeReportOptions) error {
+	s := &sizeReporter{r: r, seen: make(map[plumbing.Hash]bool), report: &SizeReport{Entries: make([]*SizeReportEntry, 0, 100)}}
+	var commits []*object.Commit
+	if opts.History {
+		var err error
+		if commits, err = r.historyCommits(ctx, opts.Revision); err != nil {
+			die_error("walk commits: %v", err)
+			return err
+		}
+	} else {
+		rev := opts.Revision
+		if len(rev) == 0 {
+			rev = string(plumbing.HEAD)
+		}
+		cc, err := r.parseRevExhaustive(ctx, rev)
+		if err != nil {
+			die_error("resolve revision '%s': %v", rev, err)
+			return err
+		}
+		commits = append(commits, cc)
+	}
+	for _, c := range commits {
+		if err := s.walk(ctx, c.Hash, c.Tree, ""); err != nil {
+			die_error("walk tree of %s: %v", c.Hash, err)
+			return err
+		}
+	}
+	s.report.Commits = len(commits)
+	s.report.Objects = len(s.report.Entries)
+	slices.SortStableFunc(s.report.Entries, func(a, b *SizeReportEntry) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	if opts.Limit > 0 && len(s.report.Entries) > opts.Limit {
+		s.report.Entries = s.report.Entries[:opts.Limit]
+	}
+	for _, e := range s.report.Entries {
+		e.Present = s.present(ctx, e)
+	}
+	if opts.JSON {
+		return json.NewEncoder(w).Encode(s.report)
+	}
+	for _, e := range s.report.Entries {
+		present := "-"
+		if e.Present {
+			present = "*" // like git lfs ls-files
+		}
+		fmt.Fprintf(w, "%-10s %s %s %s %s\n", strengthen.HumanateSize(e.Size), present, shortHash(e.Hash), shortHash(e.Commit), e.Path)
+	}
+	if s.report.MissingTrees != 0 {
+		warn("%d trees are not present locally (sparse checkout or partial metadata), their blobs are not counted", s.report.MissingTrees)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeReportHistoryRevision(t *testing.T) {
+	ctx := context.Background()
+	r, first := newTestRepository(t, map[string][]byte{"a.txt": []byte("hello\n")})
+	w := r.Worktree()
+	require.NoError(t, os.WriteFile(filepath.Join(w.baseDir, "big.txt"), bytes.Repeat([]byte("x"), 4096), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(w.baseDir, "a.txt"), []byte("hello world\n"), 0644))
+	require.NoError(t, w.Add(ctx, []string{"."}, false))
+	second, err := w.Commit(ctx, &CommitOptions{Message: []string{"add big.txt"}})
+	require.NoError(t, err)
+
+	report := func(opts *SizeReportOptions) *SizeReport {
+		var out bytes.Buffer
+		opts.JSON = true
+		require.NoError(t, r.SizeReport(ctx, &out, opts))
+		var rr SizeReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &rr))
+		return &rr
+	}
+
+	rr := report(&SizeReportOptions{History: true, Revision: first.String()})
+	require.Equal(t, 1, rr.Commits)
+	require.Len(t, rr.Entries, 1)
+	require.Equal(t, "a.txt", rr.Entries[0].Path)
+	require.Equal(t, first, rr.Entries[0].Commit)
+
+	rr = report(&SizeReportOptions{History: true})
+	require.Equal(t, 2, rr.Commits)
+	require.Len(t, rr.Entries, 3)
+	require.Equal(t, "big.txt", rr.Entries[0].Path)
+	require.Equal(t, second, rr.Entries[0].Commit)
+
+	// the tree of the revision only
+	rr = report(&SizeReportOptions{Revision: second.String()})
+	require.Equal(t, 1, rr.Commits)
+	require.Len(t, rr.Entries, 2)
+}
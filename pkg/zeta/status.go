@@ -49,10 +49,26 @@ func (s Status) IsDeleted(path string) bool {
 	return ok && stat.Worktree == Deleted
 }
 
-// IsClean returns true if all the files are in Unmodified status.
+// IsDehydrated checks if file for given path is 'Dehydrated'.
+func (s Status) IsDehydrated(path string) bool {
+	stat, ok := (s)[filepath.ToSlash(path)]
+	return ok && stat.Worktree == Dehydrated
+}
+
+// HasDehydrated returns true if any file is in Dehydrated status.
+func (s Status) HasDehydrated() bool {
+	for _, status := range s {
+		if status.Worktree == Dehydrated {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClean returns true if all the files are in Unmodified or Dehydrated status.
 func (s Status) IsClean() bool {
 	for _, status := range s {
-		if status.Worktree != Unmodified || status.Staging != Unmodified {
+		if (status.Worktree != Unmodified && status.Worktree != Dehydrated) || status.Staging != Unmodified {
 			return false
 		}
 	}
@@ -99,6 +115,8 @@ const (
 	Renamed            StatusCode = 'R'
 	Copied             StatusCode = 'C'
 	UpdatedButUnmerged StatusCode = 'U'
+	// Dehydrated the file content is not resident in the worktree, see 'zeta hydrate'
+	Dehydrated StatusCode = 'H'
 )
 
 type change struct {
@@ -113,21 +131,23 @@ func (c changeOrder) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 func (c changeOrder) Less(i, j int) bool { return c[i].path < c[j].path }
 
 type changes struct {
-	Untracked []change
-	Staging   []change
-	Unstaging []change
-	root      string
-	cwd       string
+	Untracked  []change
+	Staging    []change
+	Unstaging  []change
+	Dehydrated []change
+	root       string
+	cwd        string
 }
 
 func newChanges(status Status, root string) *changes {
 	cwd, _ := os.Getwd()
 	cs := &changes{
-		Untracked: make([]change, 0, 20),
-		Staging:   make([]change, 0, 20),
-		Unstaging: make([]change, 0, 20),
-		root:      root,
-		cwd:       cwd,
+		Untracked:  make([]change, 0, 20),
+		Staging:    make([]change, 0, 20),
+		Unstaging:  make([]change, 0, 20),
+		Dehydrated: make([]change, 0, 20),
+		root:       root,
+		cwd:        cwd,
 	}
 	for p, s := range status {
 		if s.Worktree == Unmodified && s.Staging == Unmodified {
@@ -140,6 +160,10 @@ func newChanges(status Status, root string) *changes {
 		if s.Staging != Unmodified {
 			cs.Staging = append(cs.Staging, change{path: p, FileStatus: s})
 		}
+		if s.Worktree == Dehydrated {
+			cs.Dehydrated = append(cs.Dehydrated, change{path: p, FileStatus: s})
+			continue
+		}
 		if s.Worktree != Unmodified {
 			cs.Unstaging = append(cs.Unstaging, change{path: p, FileStatus: s})
 		}
@@ -147,6 +171,7 @@ func newChanges(status Status, root string) *changes {
 	sort.Sort(changeOrder(cs.Untracked))
 	sort.Sort(changeOrder(cs.Staging))
 	sort.Sort(changeOrder(cs.Unstaging))
+	sort.Sort(changeOrder(cs.Dehydrated))
 	return cs
 }
 
@@ -191,6 +216,17 @@ func (cs *changes) show() {
 			fmt.Fprintf(os.Stdout, "      \x1b[31m%s\x1b[0m\n", cs.makePath(c.path))
 		}
 	}
+	if len(cs.Dehydrated) != 0 {
+		fmt.Fprintf(os.Stdout, "%s:\n", W("Dehydrated files"))
+		fmt.Fprintf(os.Stdout, "  %s\n", W("(use \"zeta hydrate <pathspec>...\" to restore file contents)"))
+		for _, c := range cs.Dehydrated {
+			fmt.Fprintf(os.Stdout, "      \x1b[36m%s\x1b[0m\n", cs.makePath(c.path))
+		}
+	}
+	if len(cs.Staging) == 0 && len(cs.Unstaging) == 0 && len(cs.Untracked) == 0 {
+		fmt.Fprintf(os.Stdout, "%s\n", W("nothing to commit, working tree clean"))
+		return
+	}
 	if len(cs.Staging) == 0 && len(cs.Unstaging) == 0 {
 		fmt.Fprintf(os.Stdout, "%s\n", W("nothing added to commit but untracked files present (use \"zeta add\" to track)"))
 		return
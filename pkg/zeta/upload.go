@@ -0,0 +1,144 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/pkg/transport"
+)
+
+const (
+	// objects larger than one chunk are uploaded in resumable sessions
+	resumableUploadThreshold = 64 << 20
+)
+
+// uploadState: resume state of an interrupted upload, saved as '.zeta/uploads/<oid>.json' and removed once the upload is finished.
+type uploadState struct {
+	Remote  string                   `json:"remote"`
+	Session *transport.UploadSession `json:"session"`
+}
+
+func (r *Repository) uploadStatePath(oid plumbing.Hash) string {
+	return filepath.Join(r.zetaDir, "uploads", oid.String()+".json")
+}
+
+// loadUploadState: state of other remotes is ignored, the session of a different object size is returned as stale.
+func (r *Repository) loadUploadState(oid plumbing.Hash, size int64) (us *transport.UploadSession, stale *transport.UploadSession) {
+	b, err := os.ReadFile(r.uploadStatePath(oid))
+	if err != nil {
+		return nil, nil
+	}
+	var state uploadState
+	if err := json.Unmarshal(b, &state); err != nil {
+		r.DbgPrint("decode upload state of %s error: %v", oid, err)
+		return nil, nil
+	}
+	if us := state.Session; state.Remote == r.cleanedRemote() && us != nil && len(us.ID) != 0 && us.OID == oid.String() {
+		if us.Size != size {
+			return nil, us
+		}
+		return us, nil
+	}
+	return nil, nil
+}
+
+func (r *Repository) saveUploadState(us *transport.UploadSession) error {
+	b, err := json.Marshal(&uploadState{Remote: r.cleanedRemote(), Session: us})
+	if err != nil {
+		return err
+	}
+	statePath := r.uploadStatePath(plumbing.NewHash(us.OID))
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(statePath, b, 0644)
+}
+
+func (r *Repository) removeUploadState(oid plumbing.Hash) {
+	if err := os.Remove(r.uploadStatePath(oid)); err != nil && !os.IsNotExist(err) {
+		r.DbgPrint("remove upload state of %s error: %v", oid, err)
+	}
+}
+
+// resumeUpload: continue the saved upload session, or create a new one if there is none or it is gone.
+func (r *Repository) resumeUpload(ctx context.Context, t transport.Resumable, refname plumbing.ReferenceName, oid plumbing.Hash, size int64) (*transport.UploadSession, error) {
+	us, stale := r.loadUploadState(oid, size)
+	if stale != nil {
+		// the remote aborts it once it expires, abort it now to free the uploaded chunks
+		if err := t.AbortUpload(ctx, refname, stale); err != nil {
+			r.DbgPrint("abort stale upload session of %s error: %v", oid, err)
+		}
+		r.removeUploadState(oid)
+	}
+	if us != nil {
+		current, err := t.UploadStatus(ctx, refname, us)
+		if err == nil {
+			r.DbgPrint("resume upload %s at offset %d", oid, current.Offset)
+			return current, nil
+		}
+		if !errors.Is(err, transport.ErrUploadNotFound) {
+			return nil, err
+		}
+		r.DbgPrint("upload session of %s not found, restart upload", oid)
+		r.removeUploadState(oid)
+	}
+	us, err := t.NewUpload(ctx, refname, oid, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(us.ID) != 0 {
+		if err := r.saveUploadState(us); err != nil {
+			return nil, err
+		}
+	}
+	return us, nil
+}
+
+func (r *Repository) putObjectResumable(ctx context.Context, t transport.Resumable, refname plumbing.ReferenceName, oid plumbing.Hash, sr io.Reader, size int64, title string) error {
+	us, err := r.resumeUpload(ctx, t, refname, oid, size)
+	if err != nil {
+		return err
+	}
+	if len(us.ID) == 0 {
+		// object already exists in remote
+		return nil
+	}
+	if us.ChunkSize <= 0 || us.Offset < 0 || us.Offset > size {
+		return fmt.Errorf("bad upload session: chunk size %d offset %d", us.ChunkSize, us.Offset)
+	}
+	if _, err := io.CopyN(io.Discard, sr, us.Offset); err != nil {
+		return err
+	}
+	reader := sr
+	if bar := r.newUploadBar(size, us.Offset, title); bar != nil {
+		reader = io.TeeReader(sr, bar)
+		defer bar.Close()
+	}
+	for us.Offset < size {
+		length := min(us.ChunkSize, size-us.Offset)
+		if _, err := t.UploadChunk(ctx, refname, us, us.Offset, reader, length); err != nil {
+			return err
+		}
+		us.Offset += length
+		if err := r.saveUploadState(us); err != nil {
+			return err
+		}
+	}
+	if err := t.FinishUpload(ctx, refname, us); err != nil {
+		if errors.Is(err, transport.ErrUploadNotFound) {
+			r.removeUploadState(oid)
+		}
+		return err
+	}
+	r.removeUploadState(oid)
+	return nil
+}
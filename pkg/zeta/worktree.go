@@ -102,6 +102,9 @@ func (w *Worktree) containsUnstagedChanges(ctx context.Context) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if ch, _, err = w.excludeDehydratedChanges(ctx, ch); err != nil {
+		return false, err
+	}
 
 	for _, c := range ch {
 		a, err := c.Action()
@@ -524,6 +527,10 @@ func (w *Worktree) resetWorktree(ctx context.Context, t *object.Tree, bar Progre
 	if err != nil {
 		return err
 	}
+	// dehydrated files stay dehydrated
+	if changes, _, err = w.excludeDehydratedChanges(ctx, changes); err != nil {
+		return err
+	}
 
 	idx, err := w.odb.Index()
 	if err != nil {
@@ -153,6 +153,10 @@ func (w *Worktree) checkoutIgnoreFiles(ctx context.Context, t *object.Tree, doNo
 	if err != nil {
 		return err
 	}
+	// dehydrated files stay dehydrated
+	if changes, _, err = w.excludeDehydratedChanges(ctx, changes); err != nil {
+		return err
+	}
 	changes = rearrangeChanges(changes)
 	for _, ch := range changes {
 		skip, err := w.validChangeIgnore(ch, doNotCheckouts)
@@ -224,7 +228,10 @@ func (w *Worktree) checkout(ctx context.Context, opts *CheckoutOptions, bar Prog
 	if err != nil {
 		return err
 	}
-	worktreeChanges := w.excludeIgnoredChanges(rawChanges)
+	worktreeChanges, _, err := w.excludeDehydratedChanges(ctx, w.excludeIgnoredChanges(rawChanges))
+	if err != nil {
+		return err
+	}
 	if len(indexChanges) == 0 && len(worktreeChanges) == 0 {
 		// no changes: checkout
 		return w.checkoutSlow(ctx, opts, bar)
@@ -529,6 +536,9 @@ func (w *Worktree) unstagedChanges(ctx context.Context) (merkletrie.Changes, err
 	if err != nil {
 		return nil, err
 	}
+	if ch, _, err = w.excludeDehydratedChanges(ctx, ch); err != nil {
+		return nil, err
+	}
 	var changes merkletrie.Changes
 	for _, c := range ch {
 		a, err := c.Action()
@@ -550,6 +560,10 @@ func (w *Worktree) checkoutWorktree(ctx context.Context, t *object.Tree, bar Pro
 	if err != nil {
 		return err
 	}
+	// dehydrated files stay dehydrated
+	if changes, _, err = w.excludeDehydratedChanges(ctx, changes); err != nil {
+		return err
+	}
 
 	idx, err := w.odb.Index()
 	if err != nil {
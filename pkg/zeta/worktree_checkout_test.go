@@ -0,0 +1,57 @@
+package zeta
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutRefusedPaths(t *testing.T) {
+	ctx := context.Background()
+	r, first := newTestRepository(t, map[string][]byte{"README.md": []byte("hello\n")})
+	w := r.Worktree()
+	for name, content := range map[string]string{"public/a.txt": "public\n", "secret/b.txt": "secret\n", "secret/c/d.txt": "secret\n"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(w.baseDir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(w.baseDir, name), []byte(content), 0644))
+	}
+	require.NoError(t, w.Add(ctx, []string{"."}, false))
+	second, err := w.Commit(ctx, &CommitOptions{Message: []string{"add public and secret"}})
+	require.NoError(t, err)
+	require.NoError(t, w.Checkout(ctx, &CheckoutOptions{Hash: first, Force: true}))
+	_, err = os.Stat(filepath.Join(w.baseDir, "secret"))
+	require.True(t, os.IsNotExist(err))
+
+	// the tree and blobs of 'secret' are not sent to a user who is not allowed to read it
+	tree, err := r.odb.ParseRevExhaustive(ctx, second)
+	require.NoError(t, err)
+	root, err := r.odb.Tree(ctx, tree.Tree)
+	require.NoError(t, err)
+	secret, err := root.FindEntry(ctx, "secret")
+	require.NoError(t, err)
+	b, err := root.FindEntry(ctx, "secret/b.txt")
+	require.NoError(t, err)
+	require.NoError(t, r.odb.PruneObject(ctx, secret.Hash, true))
+	require.NoError(t, r.odb.PruneObject(ctx, b.Hash, false))
+
+	var missing odb.Entries
+	require.NoError(t, r.odb.CountingSliceObjects(ctx, second, nil, 0, func(ctx context.Context, entries odb.Entries) error {
+		missing = append(missing, entries...)
+		return nil
+	}))
+	require.Empty(t, missing)
+	require.NoError(t, r.odb.WalkBlobs(ctx, second, nil, func(e *odb.Entry) error {
+		require.NotEqual(t, b.Hash, e.Hash)
+		return nil
+	}))
+
+	require.NoError(t, w.Checkout(ctx, &CheckoutOptions{Hash: second, Force: true}))
+	content, err := os.ReadFile(filepath.Join(w.baseDir, "public/a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "public\n", string(content))
+	_, err = os.Stat(filepath.Join(w.baseDir, "secret"))
+	require.True(t, os.IsNotExist(err))
+}
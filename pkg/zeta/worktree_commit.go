@@ -112,7 +112,7 @@ func (w *Worktree) genMessageTemplate(ctx context.Context, opts *CommitOptions,
 		if !opts.All {
 			continue
 		}
-		if s.Worktree != Unmodified {
+		if s.Worktree != Unmodified && s.Worktree != Dehydrated {
 			fmt.Fprintf(&b, "#    %s\t%s\n", W(StatusName(s.Worktree)), p)
 		}
 	}
@@ -190,6 +190,9 @@ func (w *Worktree) diffWorktree(ctx context.Context, opts *DiffContextOptions, w
 	if err != nil {
 		return err
 	}
+	if changes, _, err = w.excludeDehydratedChanges(ctx, changes); err != nil {
+		return err
+	}
 	if opts.NameOnly || opts.NameStatus {
 		return opts.formatChanges(changes, writer)
 	}
@@ -0,0 +1,330 @@
+// Copyright ©️ Ant Group. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package zeta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/antgroup/hugescm/modules/merkletrie"
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/plumbing/filemode"
+	"github.com/antgroup/hugescm/modules/plumbing/format/index"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/antgroup/hugescm/modules/zeta/backend"
+	"github.com/antgroup/hugescm/modules/zeta/object"
+	"github.com/antgroup/hugescm/pkg/progress"
+	"github.com/antgroup/hugescm/pkg/tr"
+	"github.com/antgroup/hugescm/pkg/zeta/odb"
+)
+
+// dehydratedFilter: a tracked file is dehydrated when its content is not resident in the worktree:
+//
+//  1. the file is an empty placeholder recorded by dehydrate, files emptied by the user are changes even if their
+//     blobs are missing locally;
+//  2. the file does not exist and its blob is missing locally (pseudo index of 'zeta checkout --limit').
+type dehydratedFilter struct {
+	w       *Worktree
+	marked  map[string]bool
+	entries map[string]*index.Entry
+}
+
+func (w *Worktree) newDehydratedFilter() (*dehydratedFilter, error) {
+	marked, err := w.odb.Dehydrated()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := w.odb.Index()
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*index.Entry, len(idx.Entries))
+	for _, e := range idx.Entries {
+		entries[e.Name] = e
+	}
+	return &dehydratedFilter{w: w, marked: marked, entries: entries}, nil
+}
+
+// missing: the blob (or any fragment) of the entry is not in the local object database.
+func (f *dehydratedFilter) missing(ctx context.Context, e *index.Entry) bool {
+	if e.Hash == backend.BLANK_BLOB_HASH {
+		return false
+	}
+	if !e.Mode.IsFragments() {
+		return !f.w.odb.Exists(e.Hash, false)
+	}
+	ff, err := f.w.odb.Fragments(ctx, e.Hash)
+	if err != nil {
+		return true
+	}
+	for _, ee := range ff.Entries {
+		if !f.w.odb.Exists(ee.Hash, false) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *dehydratedFilter) match(ctx context.Context, name string) bool {
+	e, ok := f.entries[name]
+	if !ok || e.Mode == filemode.Symlink || e.Mode == filemode.Submodule {
+		return false
+	}
+	fi, err := f.w.fs.Lstat(name)
+	switch {
+	case os.IsNotExist(err):
+		return f.missing(ctx, e)
+	case err != nil:
+		return false
+	case !fi.Mode().IsRegular() || fi.Size() != 0 || e.Size == 0:
+		return false
+	}
+	return f.marked[name]
+}
+
+// excludeDehydratedChanges: remove changes caused by dehydrated files, which are not changes made by the user.
+func (w *Worktree) excludeDehydratedChanges(ctx context.Context, changes merkletrie.Changes) (merkletrie.Changes, []string, error) {
+	if len(changes) == 0 {
+		return changes, nil, nil
+	}
+	f, err := w.newDehydratedFilter()
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make(merkletrie.Changes, 0, len(changes))
+	var dehydrated []string
+	for _, ch := range changes {
+		// untracked files never match: only index entries are checked
+		if name := nameFromAction(&ch); f.match(ctx, name) {
+			dehydrated = append(dehydrated, name)
+			continue
+		}
+		result = append(result, ch)
+	}
+	return result, dehydrated, nil
+}
+
+func (w *Worktree) matchPathSpec(pathSpec []string) (func(string) bool, error) {
+	patterns, hasDot, err := w.cleanpPatterns(pathSpec)
+	if err != nil {
+		return nil, err
+	}
+	if hasDot || len(patterns) == 0 {
+		return func(string) bool { return true }, nil
+	}
+	return NewMatcher(patterns).Match, nil
+}
+
+// Hydrate: download and checkout dehydrated files matching pathSpec.
+func (w *Worktree) Hydrate(ctx context.Context, pathSpec []string) error {
+	match, err := w.matchPathSpec(pathSpec)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	f, err := w.newDehydratedFilter()
+	if err != nil {
+		die_error("open index: %v", err)
+		return err
+	}
+	entries := make([]*odb.TreeEntry, 0, 100)
+	ci := newMissingFetcher()
+	largeSize := w.largeSize()
+	for name, e := range f.entries {
+		if !match(name) || !f.match(ctx, name) {
+			continue
+		}
+		entries = append(entries, &odb.TreeEntry{Path: name, TreeEntry: &object.TreeEntry{
+			Name: path.Base(name),
+			Size: int64(e.Size),
+			Mode: e.Mode,
+			Hash: e.Hash,
+		}})
+		if !e.Mode.IsFragments() {
+			ci.store(w.odb, e.Hash, int64(e.Size), largeSize)
+			continue
+		}
+		ff, err := w.odb.Fragments(ctx, e.Hash)
+		if err != nil {
+			die_error("open fragments '%s': %v", name, err)
+			return err
+		}
+		for _, ee := range ff.Entries {
+			ci.store(w.odb, ee.Hash, int64(ee.Size), largeSize)
+		}
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", W("no dehydrated files matched"))
+		return nil
+	}
+	w.DbgPrint("hydrate files: %d, missing objects: %d, large objects: %d", len(entries), len(ci.objects), len(ci.larges))
+	if err := w.fetchMissingObjects(ctx, ci, false); err != nil {
+		return err
+	}
+	bar := progress.NewIndicators("Hydrate files", "Hydrate files completed", uint64(len(entries)), w.quiet)
+	newCtx, cancelCtx := context.WithCancelCause(ctx)
+	bar.Run(newCtx)
+	if err := w.resetWorktreeEntries(ctx, entries, bar); err != nil {
+		cancelCtx(err)
+		bar.Wait()
+		die_error("checkout files: %v", err)
+		return err
+	}
+	cancelCtx(nil)
+	bar.Wait()
+	for _, e := range entries {
+		delete(f.marked, e.Path)
+	}
+	if err := w.odb.SetDehydrated(f.marked); err != nil {
+		die_error("update dehydrated paths: %v", err)
+		return err
+	}
+	return nil
+}
+
+type DehydrateOptions struct {
+	PathSpec []string
+	Force    bool // dehydrate files not found in the upstream branch
+	DryRun   bool
+}
+
+// publishedTree: the tree of the current branch of the selected remote, files in it can be downloaded again.
+func (w *Worktree) publishedTree(ctx context.Context) *object.Tree {
+	current, err := w.Current()
+	if err != nil || !current.Name().IsBranch() {
+		return nil
+	}
+	ref, err := w.Reference(plumbing.NewRemoteReferenceName(w.remoteName(), current.Name().BranchName()))
+	if err != nil {
+		return nil
+	}
+	t, err := w.readTree(ctx, ref.Hash(), "")
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+func publishedMatch(ctx context.Context, published *object.Tree, name string, oid plumbing.Hash) bool {
+	if published == nil {
+		return false
+	}
+	e, err := published.FindEntry(ctx, name)
+	return err == nil && e.Hash == oid
+}
+
+// Dehydrate: replace clean files matching pathSpec with empty placeholders and drop their local blobs.
+func (w *Worktree) Dehydrate(ctx context.Context, opts *DehydrateOptions) error {
+	match, err := w.matchPathSpec(opts.PathSpec)
+	if err != nil {
+		die_error("%v", err)
+		return err
+	}
+	status, err := w.Status(ctx, false)
+	if err != nil {
+		die_error("status: %v", err)
+		return err
+	}
+	f, err := w.newDehydratedFilter()
+	if err != nil {
+		die_error("open index: %v", err)
+		return err
+	}
+	var published *object.Tree
+	if !opts.Force {
+		published = w.publishedTree(ctx)
+	}
+	selected := make([]*index.Entry, 0, 100)
+	resident := make(map[plumbing.Hash]bool)
+	// blobs of fragments are shared by files with the same chunks
+	keep := func(e *index.Entry) {
+		resident[e.Hash] = true
+		if !e.Mode.IsFragments() {
+			return
+		}
+		if ff, err := w.odb.Fragments(ctx, e.Hash); err == nil {
+			for _, ee := range ff.Entries {
+				resident[ee.Hash] = true
+			}
+		}
+	}
+	var unpublished int
+	for name, e := range f.entries {
+		s, changed := status[name]
+		if !match(name) || e.Mode == filemode.Symlink || e.Mode == filemode.Submodule || e.Size == 0 ||
+			(changed && (s.Staging != Unmodified || s.Worktree != Unmodified)) {
+			if !changed || s.Worktree != Dehydrated {
+				keep(e)
+			}
+			continue
+		}
+		if !opts.Force {
+			if !publishedMatch(ctx, published, name, e.Hash) {
+				w.DbgPrint("'%s' not found in the upstream branch", name)
+				keep(e)
+				unpublished++
+				continue
+			}
+		}
+		selected = append(selected, e)
+	}
+	if unpublished != 0 {
+		warn("%d files not found in the upstream branch are kept, use '--force' to dehydrate them", unpublished)
+	}
+	if opts.DryRun {
+		for _, e := range selected {
+			fmt.Fprintf(os.Stdout, "%s '%s'\n", W("Would dehydrate"), e.Name)
+		}
+		return nil
+	}
+	blobs := make(map[plumbing.Hash]int64)
+	for _, e := range selected {
+		fd, err := w.fs.OpenFile(e.Name, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			die_error("truncate '%s': %v", e.Name, err)
+			return err
+		}
+		_ = fd.Close()
+		f.marked[e.Name] = true
+		if !e.Mode.IsFragments() {
+			blobs[e.Hash] = int64(e.Size)
+			continue
+		}
+		if ff, err := w.odb.Fragments(ctx, e.Hash); err == nil {
+			for _, ee := range ff.Entries {
+				blobs[ee.Hash] = int64(ee.Size)
+			}
+		}
+	}
+	// drop stale paths which are no longer tracked
+	for name := range f.marked {
+		if _, ok := f.entries[name]; !ok {
+			delete(f.marked, name)
+		}
+	}
+	if err := w.odb.SetDehydrated(f.marked); err != nil {
+		die_error("update dehydrated paths: %v", err)
+		return err
+	}
+	var reclaimed int64
+	// blobs in core.sharingRoot are managed by 'zeta cache prune'
+	if w.odb.SharingCache() == nil {
+		for oid, size := range blobs {
+			if resident[oid] {
+				continue
+			}
+			if err := w.odb.PruneObject(ctx, oid, false); err != nil {
+				if !os.IsNotExist(err) {
+					w.DbgPrint("prune %s: %v", oid, err)
+				}
+				continue
+			}
+			reclaimed += size
+		}
+	}
+	tr.Fprintf(os.Stderr, "dehydrated %d files, reclaimed %s\n", len(selected), strengthen.HumanateSize(reclaimed))
+	return nil
+}
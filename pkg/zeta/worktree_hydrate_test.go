@@ -0,0 +1,101 @@
+package zeta
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/hugescm/modules/plumbing"
+	"github.com/antgroup/hugescm/modules/strengthen"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T, files map[string][]byte) (*Repository, plumbing.Hash) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	ctx := context.Background()
+	r, err := Init(ctx, &InitOptions{Worktree: dir, Branch: "mainline", Quiet: true,
+		Values: []string{"user.name=Zeta", "user.email=zeta@example.com"}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	// path specs are relative to the current directory
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	r.Fragment.ThresholdRaw.Size = strengthen.MiByte
+	r.Fragment.SizeRaw.Size = strengthen.MiByte
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0644))
+	}
+	w := r.Worktree()
+	require.NoError(t, w.Add(ctx, []string{"."}, false))
+	oid, err := w.Commit(ctx, &CommitOptions{Message: []string{"init"}})
+	require.NoError(t, err)
+	return r, oid
+}
+
+func TestDehydratedFilterEmptiedFile(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepository(t, map[string][]byte{"a.txt": []byte("hello\n")})
+	w := r.Worktree()
+	f, err := w.newDehydratedFilter()
+	require.NoError(t, err)
+	e := f.entries["a.txt"]
+	require.NoError(t, os.Truncate(filepath.Join(w.baseDir, "a.txt"), 0))
+	require.NoError(t, w.odb.PruneObject(ctx, e.Hash, false))
+	// emptied by the user, not a placeholder
+	require.False(t, f.match(ctx, "a.txt"))
+	f.marked["a.txt"] = true
+	require.True(t, f.match(ctx, "a.txt"))
+	// removed files with missing blobs come from 'zeta checkout --limit'
+	require.NoError(t, os.Remove(filepath.Join(w.baseDir, "a.txt")))
+	delete(f.marked, "a.txt")
+	require.True(t, f.match(ctx, "a.txt"))
+}
+
+func TestDehydrateSharedFragments(t *testing.T) {
+	ctx := context.Background()
+	head := bytes.Repeat([]byte("z"), int(strengthen.MiByte))
+	r, _ := newTestRepository(t, map[string][]byte{
+		"a.bin": append(append([]byte(nil), head...), bytes.Repeat([]byte("a"), 4096)...),
+		"b.bin": append(append([]byte(nil), head...), bytes.Repeat([]byte("b"), 4096)...),
+	})
+	w := r.Worktree()
+	f, err := w.newDehydratedFilter()
+	require.NoError(t, err)
+	require.True(t, f.entries["a.bin"].Mode.IsFragments())
+	a, err := w.odb.Fragments(ctx, f.entries["a.bin"].Hash)
+	require.NoError(t, err)
+	b, err := w.odb.Fragments(ctx, f.entries["b.bin"].Hash)
+	require.NoError(t, err)
+	require.Equal(t, a.Entries[0].Hash, b.Entries[0].Hash)
+
+	require.NoError(t, w.Dehydrate(ctx, &DehydrateOptions{PathSpec: []string{"a.bin"}, Force: true}))
+	// the chunk shared with the resident b.bin is kept
+	require.True(t, w.odb.Exists(a.Entries[0].Hash, false))
+	require.False(t, w.odb.Exists(a.Entries[1].Hash, false))
+	status, err := w.Status(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, Dehydrated, status["a.bin"].Worktree)
+}
+
+func TestDehydratePublishedRemote(t *testing.T) {
+	ctx := context.Background()
+	r, oid := newTestRepository(t, map[string][]byte{"a.txt": []byte("hello\n")})
+	require.NoError(t, r.ReferenceUpdate(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("upstream", "mainline"), oid), nil))
+	w := r.Worktree()
+	// not published to origin
+	require.NoError(t, w.Dehydrate(ctx, &DehydrateOptions{PathSpec: []string{"."}}))
+	marked, err := r.odb.Dehydrated()
+	require.NoError(t, err)
+	require.Empty(t, marked)
+
+	r.remote = "upstream"
+	require.NoError(t, w.Dehydrate(ctx, &DehydrateOptions{PathSpec: []string{"."}}))
+	marked, err = r.odb.Dehydrated()
+	require.NoError(t, err)
+	require.True(t, marked["a.txt"])
+}
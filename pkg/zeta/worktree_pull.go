@@ -110,7 +110,7 @@ func (w *Worktree) Pull(ctx context.Context, opts *PullOptions) error {
 		fmt.Fprintln(os.Stderr, W("Not possible to fast-forward, aborting."))
 		return ErrNonFastForwardUpdate
 	}
-	remoteRefName := plumbing.NewRemoteReferenceName("origin", branchName)
+	remoteRefName := plumbing.NewRemoteReferenceName(w.remoteName(), branchName)
 	if opts.Rebase {
 		messagePrefix := fmt.Sprintf("Rebase branch '%s of %s' into %s", branchName, w.cleanedRemote(), branchName)
 		newRev, err := w.rebaseInternal(ctx, current.Hash(), fo.FETCH_HEAD, currentName, remoteRefName, false)
@@ -38,6 +38,7 @@ var (
 		Modified:           "modified:",
 		Renamed:            "renamed:",
 		UpdatedButUnmerged: "unmerged:",
+		Dehydrated:         "dehydrated:",
 	}
 )
 
@@ -128,6 +129,17 @@ func (w *Worktree) status(ctx context.Context, commit plumbing.Hash) (Status, er
 	if err != nil {
 		return nil, err
 	}
+	right, dehydrated, err := w.excludeDehydratedChanges(ctx, right)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range dehydrated {
+		fs := s.File(name)
+		if fs.Staging == Untracked {
+			fs.Staging = Unmodified
+		}
+		fs.Worktree = Dehydrated
+	}
 
 	for _, ch := range right {
 		a, err := ch.Action()
@@ -602,7 +614,7 @@ func (w *Worktree) AddGlob(ctx context.Context, pattern string, dryRun bool) err
 // the file added is different from the index.
 // if s status is nil will skip the status check and update the index anyway
 func (w *Worktree) doAddFile(ctx context.Context, idx *index.Index, s Status, path string, ignorePattern []ignore.Pattern, dryRun bool) (added bool, h plumbing.Hash, err error) {
-	if s != nil && s.File(path).Worktree == Unmodified {
+	if s != nil && (s.File(path).Worktree == Unmodified || s.File(path).Worktree == Dehydrated) {
 		return false, h, nil
 	}
 	if len(ignorePattern) > 0 {